@@ -0,0 +1,81 @@
+// Config loads the settings of a service from a Luna file, so they can be
+// computed rather than repeated. The file can read the environment through
+// the host but nothing else.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"luna/interp"
+)
+
+// Config is what the configuration file ends with
+type Config struct {
+	Name    string   `luna:"name"`
+	Port    int      `luna:"port"`
+	Workers int      `luna:"workers"`
+	Debug   bool     `luna:"debug"`
+	Queues  []string `luna:"queues"`
+	Timeout float64  `luna:"timeout"`
+}
+
+const defaultConfig = `
+workers: var = int(setting("WORKERS", "4"))
+config: var = { name: "api", port: 8000 + 80, workers: workers, debug: setting("DEBUG", "") != "" }
+config.queues = ["default", "mail"]
+config.timeout = workers * 2.5
+config
+`
+
+// loadConfig evaluates source sandboxed, with lookup giving setting(name,
+// fallback) its values, and decodes the object it ends with
+func loadConfig(source string, lookup func(string) (string, bool)) (Config, error) {
+	env := interp.NewGlobalEnvironment()
+	luna := interp.NewLuna(env)
+	if err := luna.Sandbox(); err != nil {
+		return Config{}, err
+	}
+	env.DeclareVar("setting", interp.MakeNativeFunction("setting", func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("setting expects a name and a fallback, got %d arguments", len(args))
+		}
+		name, ok := args[0].(*interp.StringValue)
+		if !ok {
+			return nil, fmt.Errorf("setting expects a string name, got %s", args[0].Type())
+		}
+		if value, found := lookup(name.Value); found {
+			return interp.MakeString(value), nil
+		}
+		return args[1], nil
+	}), true)
+
+	result, err := luna.Evaluate(source)
+	if err != nil {
+		return Config{}, err
+	}
+	var config Config
+	if err := interp.FromLunaInto(result, &config); err != nil {
+		return Config{}, fmt.Errorf("configuration: %w", err)
+	}
+	return config, nil
+}
+
+func main() {
+	source := defaultConfig
+	if len(os.Args) > 1 {
+		data, err := os.ReadFile(os.Args[1])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		source = string(data)
+	}
+
+	config, err := loadConfig(source, os.LookupEnv)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	fmt.Printf("%+v\n", config)
+}
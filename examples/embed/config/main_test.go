@@ -0,0 +1,41 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	config, err := loadConfig(defaultConfig, func(string) (string, bool) { return "", false })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Name != "api" || config.Port != 8080 || config.Workers != 4 || config.Debug || config.Timeout != 10 {
+		t.Errorf("got %+v", config)
+	}
+	if !slices.Equal(config.Queues, []string{"default", "mail"}) {
+		t.Errorf("queues %v", config.Queues)
+	}
+}
+
+func TestLoadConfigSettings(t *testing.T) {
+	settings := map[string]string{"WORKERS": "2", "DEBUG": "1"}
+	config, err := loadConfig(defaultConfig, func(name string) (string, bool) {
+		value, found := settings[name]
+		return value, found
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Workers != 2 || !config.Debug || config.Timeout != 5 {
+		t.Errorf("got %+v", config)
+	}
+}
+
+func TestLoadConfigErrors(t *testing.T) {
+	for _, source := range []string{`os.env("HOME")`, `"not an object"`, `{ port: "http" }`} {
+		if _, err := loadConfig(source, func(string) (string, bool) { return "", false }); err == nil {
+			t.Errorf("loading %s did not fail", source)
+		}
+	}
+}
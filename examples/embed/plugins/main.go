@@ -0,0 +1,104 @@
+// Plugins hosts Luna plugins reacting to events. Every plugin sees the host
+// module registered from init(), and gets an outbox module of its own so
+// that what it sends is tied to it.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"luna/interp"
+)
+
+// hostVersion is what host.version() tells the plugins
+const hostVersion = "1.4.0"
+
+func init() {
+	interp.RegisterModule("host", map[string]interp.NativeFunctionCall{
+		"version": func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) {
+			return interp.MakeString(hostVersion), nil
+		},
+	})
+}
+
+// plugins are the scripts of the plugins by name, each defines on_event
+var plugins = map[string]string{
+	"greeter": `fn on_event name { if name == "join" { outbox.send("welcome, host " + host.version()) } }`,
+	"audit":   `fn on_event name { outbox.send("saw " + name) }`,
+}
+
+// Plugin is a loaded plugin and the messages it sent
+type Plugin struct {
+	Name string
+	Sent []string
+	luna *interp.Luna
+	env  *interp.Environment
+}
+
+// loadPlugin runs the script of a plugin in its own environment, where
+// the network is off
+func loadPlugin(name, script string) (*Plugin, error) {
+	plugin := &Plugin{Name: name, env: interp.NewGlobalEnvironment()}
+	plugin.luna = interp.NewLuna(plugin.env)
+	if err := interp.DisableModule(plugin.env, "http"); err != nil {
+		return nil, err
+	}
+
+	err := plugin.luna.RegisterModule("outbox", map[string]interp.NativeFunctionCall{
+		"send": func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) {
+			for _, arg := range args {
+				message, err := interp.FromLuna(arg)
+				if err != nil {
+					return nil, err
+				}
+				plugin.Sent = append(plugin.Sent, fmt.Sprint(message))
+			}
+			return interp.MakeVoid(), nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := plugin.luna.Evaluate(script); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", name, err)
+	}
+	return plugin, nil
+}
+
+// Dispatch gives an event to the on_event function of the plugin
+func (p *Plugin) Dispatch(event string) error {
+	handler := p.env.LookupVar("on_event")
+	if handler.Type() != interp.FUNCTION_TYPE {
+		return fmt.Errorf("plugin %s has no on_event function", p.Name)
+	}
+	if _, err := p.luna.Call(handler, interp.MakeString(event)); err != nil {
+		return fmt.Errorf("plugin %s: %w", p.Name, err)
+	}
+	return nil
+}
+
+func main() {
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		plugin, err := loadPlugin(name, plugins[name])
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, event := range []string{"join", "leave"} {
+			if err := plugin.Dispatch(event); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		}
+		fmt.Printf("%s: %s\n", name, strings.Join(plugin.Sent, "; "))
+	}
+}
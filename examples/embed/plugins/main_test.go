@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"luna/interp"
+)
+
+func TestPlugins(t *testing.T) {
+	greeter, err := loadPlugin("greeter", plugins["greeter"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	audit, err := loadPlugin("audit", plugins["audit"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, event := range []string{"join", "leave"} {
+		if err := greeter.Dispatch(event); err != nil {
+			t.Fatal(err)
+		}
+		if err := audit.Dispatch(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if want := []string{"welcome, host " + hostVersion}; !slices.Equal(greeter.Sent, want) {
+		t.Errorf("greeter sent %v, want %v", greeter.Sent, want)
+	}
+	if want := []string{"saw join", "saw leave"}; !slices.Equal(audit.Sent, want) {
+		t.Errorf("audit sent %v, want %v", audit.Sent, want)
+	}
+}
+
+func TestPluginWithoutHandler(t *testing.T) {
+	plugin, err := loadPlugin("empty", `x: var = 1`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := plugin.Dispatch("join"); err == nil {
+		t.Error("dispatching to a plugin without on_event did not fail")
+	}
+}
+
+func TestPluginNetworkIsOff(t *testing.T) {
+	plugin, err := loadPlugin("server", `fn on_event name { http.serve(8080, lambda request { "hi" }) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var permission *interp.PermissionError
+	if err := plugin.Dispatch("join"); !errors.As(err, &permission) {
+		t.Errorf("got %v, want a PermissionError", err)
+	}
+}
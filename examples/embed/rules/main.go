@@ -0,0 +1,80 @@
+// Rules is a rule engine whose rules are Luna functions. The script is
+// trusted with the orders only, so it runs sandboxed and within limits.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"luna/interp"
+)
+
+// rulesScript flags orders, each function of the object it ends with is a rule
+const rulesScript = `
+fn large order { order.total > 1000 }
+fn first_order order { order.customer.orders == 0 }
+fn express_abroad order { order.express && order.country != "FR" }
+
+{ large, first_order, express_abroad }
+`
+
+type Customer struct {
+	Name   string `luna:"name"`
+	Orders int    `luna:"orders"`
+}
+
+type Order struct {
+	Total    float64  `luna:"total"`
+	Country  string   `luna:"country"`
+	Express  bool     `luna:"express"`
+	Customer Customer `luna:"customer"`
+}
+
+// matchingRules runs the rules of script on order and returns the names of
+// those it matches, sorted
+func matchingRules(script string, order Order) ([]string, error) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	if err := luna.Sandbox(); err != nil {
+		return nil, err
+	}
+	luna.SetLimits(interp.Limits{MaxCallDepth: 100, MaxSteps: 100000})
+
+	result, err := luna.Evaluate(script)
+	if err != nil {
+		return nil, err
+	}
+	rules, ok := result.(*interp.ObjectValue)
+	if !ok {
+		return nil, fmt.Errorf("the rules script must end with an object of rules, got %s", result.Type())
+	}
+
+	value, err := interp.ToLuna(order)
+	if err != nil {
+		return nil, err
+	}
+	var matched []string
+	for name, rule := range rules.Properties {
+		verdict, err := luna.Call(rule, value)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %w", name, err)
+		}
+		if verdict.IsTruthy() {
+			matched = append(matched, name)
+		}
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+func main() {
+	order := Order{Total: 1250, Country: "DE", Express: true, Customer: Customer{Name: "Ada", Orders: 0}}
+	matched, err := matchingRules(rulesScript, order)
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	for _, name := range matched {
+		fmt.Println(name)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"luna/interp"
+)
+
+func TestMatchingRules(t *testing.T) {
+	order := Order{Total: 1250, Country: "DE", Express: true, Customer: Customer{Name: "Ada", Orders: 0}}
+	matched, err := matchingRules(rulesScript, order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"express_abroad", "first_order", "large"}; !slices.Equal(matched, want) {
+		t.Errorf("matched %v, want %v", matched, want)
+	}
+
+	order = Order{Total: 20, Country: "FR", Customer: Customer{Orders: 3}}
+	if matched, err = matchingRules(rulesScript, order); err != nil || len(matched) != 0 {
+		t.Errorf("matched %v (%v), want none", matched, err)
+	}
+}
+
+func TestRulesAreSandboxed(t *testing.T) {
+	script := `fn leak order { file.size("/etc/hostname") }
+{ leak }`
+	var permission *interp.PermissionError
+	if _, err := matchingRules(script, Order{}); !errors.As(err, &permission) {
+		t.Errorf("got %v, want a PermissionError", err)
+	}
+}
+
+func TestRulesAreLimited(t *testing.T) {
+	script := `fn forever order { while true { } }
+{ forever }`
+	var limit *interp.LimitError
+	if _, err := matchingRules(script, Order{}); !errors.As(err, &limit) {
+		t.Errorf("got %v, want a LimitError", err)
+	}
+}
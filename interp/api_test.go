@@ -0,0 +1,303 @@
+package interp_test
+
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"luna/interp"
+)
+
+// The API embedders use, see examples/embed. Changing one of these
+// signatures breaks their programs, this file stops compiling first.
+var (
+	_ func(*interp.Environment) *interp.Luna                                                       = interp.NewLuna
+	_ func() *interp.Environment                                                                   = interp.NewGlobalEnvironment
+	_ func(*interp.Environment) *interp.Environment                                                = interp.NewEnvironment
+	_ func(*interp.Luna, string) (interp.RuntimeValue, error)                                      = (*interp.Luna).Evaluate
+	_ func(*interp.Luna, interp.RuntimeValue, ...interp.RuntimeValue) (interp.RuntimeValue, error) = (*interp.Luna).Call
+	_ func(*interp.Luna) error                                                                     = (*interp.Luna).Sandbox
+	_ func(*interp.Luna, interp.Limits)                                                            = (*interp.Luna).SetLimits
+	_ func(*interp.Luna, interp.Options)                                                           = (*interp.Luna).SetOptions
+	_ func(*interp.Luna, string, map[string]interp.NativeFunctionCall) error                       = (*interp.Luna).RegisterModule
+	_ func(string, map[string]interp.NativeFunctionCall)                                           = interp.RegisterModule
+	_ func(string, string)                                                                         = interp.RegisterDoc
+	_ func(*interp.Environment, string) error                                                      = interp.DisableModule
+	_ func(*interp.Environment) error                                                              = interp.Sandbox
+	_ func(any) (interp.RuntimeValue, error)                                                       = interp.ToLuna
+	_ func(interp.RuntimeValue) (any, error)                                                       = interp.FromLuna
+	_ func(interp.RuntimeValue, any) error                                                         = interp.FromLunaInto
+	_ func(*interp.Environment, string, interp.RuntimeValue, bool) interp.RuntimeValue             = (*interp.Environment).DeclareVar
+	_ func(*interp.Environment, string) interp.RuntimeValue                                        = (*interp.Environment).LookupVar
+	_ func(string, interp.NativeFunctionCall) interp.RuntimeValue                                  = interp.MakeNativeFunction
+
+	_ interp.NativeFunctionCall = func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) {
+		return nil, nil
+	}
+	_ error = &interp.PermissionError{}
+	_ error = &interp.LimitError{}
+	_ error = &interp.ExitError{}
+	_       = interp.Limits{MaxCallDepth: 0, MaxSteps: 0, MaxAllocations: 0, Timeout: 0}
+)
+
+func TestEvaluateAndConvert(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	result, err := luna.Evaluate(`{ name: "luna", tags: ["a", "b"], size: 2 + 3 }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var value struct {
+		Name string   `luna:"name"`
+		Tags []string `luna:"tags"`
+		Size int      `luna:"size"`
+	}
+	if err := interp.FromLunaInto(result, &value); err != nil {
+		t.Fatal(err)
+	}
+	if value.Name != "luna" || len(value.Tags) != 2 || value.Size != 5 {
+		t.Errorf("got %+v", value)
+	}
+}
+
+func TestCall(t *testing.T) {
+	env := interp.NewGlobalEnvironment()
+	luna := interp.NewLuna(env)
+	if _, err := luna.Evaluate(`fn add a b { return a + b }`); err != nil {
+		t.Fatal(err)
+	}
+	result, err := luna.Call(env.LookupVar("add"), interp.MakeInt(2), interp.MakeInt(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum, err := interp.FromLuna(result); err != nil || sum != int64(5) {
+		t.Errorf("add(2, 3) is %v", result)
+	}
+}
+
+func TestRegisterModuleOnOneInterpreter(t *testing.T) {
+	first := interp.NewLuna(interp.NewGlobalEnvironment())
+	second := interp.NewLuna(interp.NewGlobalEnvironment())
+	err := first.RegisterModule("answers", map[string]interp.NativeFunctionCall{
+		"get": func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) {
+			return interp.MakeInt(42), nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := first.Evaluate(`answers.get()`)
+	if answer, _ := interp.FromLuna(result); err != nil || answer != int64(42) {
+		t.Errorf("answers.get() is %v (%v)", result, err)
+	}
+	if _, err := second.Evaluate(`answers.get()`); err == nil {
+		t.Error("a module registered on one interpreter reached another")
+	}
+}
+
+func TestSandboxAndLimits(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	if err := luna.Sandbox(); err != nil {
+		t.Fatal(err)
+	}
+	luna.SetLimits(interp.Limits{MaxSteps: 1000})
+
+	var permission *interp.PermissionError
+	if _, err := luna.Evaluate(`exit(1)`); !errors.As(err, &permission) {
+		t.Errorf("exit in the sandbox gave %v, want a PermissionError", err)
+	}
+	var limit *interp.LimitError
+	if _, err := luna.Evaluate(`while true { }`); !errors.As(err, &limit) {
+		t.Errorf("an endless loop gave %v, want a LimitError", err)
+	}
+
+	// The sandbox bounds a run on its own
+	bounded := interp.NewLuna(interp.NewGlobalEnvironment())
+	if err := bounded.Sandbox(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bounded.Evaluate(`while true { }`); !errors.As(err, &limit) {
+		t.Errorf("an endless loop in the sandbox gave %v, want a LimitError", err)
+	}
+	bounded.SetLimits(interp.Limits{Timeout: 50 * time.Millisecond})
+	if _, err := bounded.Evaluate(`while true { }`); !errors.As(err, &limit) || limit.Limit != "time" {
+		t.Errorf("an endless loop with a timeout gave %v, want the time limit", err)
+	}
+}
+
+func TestOptionsAndExit(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	options := interp.DefaultOptions
+	options.StrictTruthiness = true
+	luna.SetOptions(options)
+	if _, err := luna.Evaluate(`if 1 { 2 }`); err == nil {
+		t.Error("a number as a condition passed with StrictTruthiness")
+	}
+	if other, err := interp.NewLuna(interp.NewGlobalEnvironment()).Evaluate(`if 1 { 2 }`); err != nil {
+		t.Errorf("the options of one interpreter reached another: %v (%v)", other, err)
+	}
+
+	var exit *interp.ExitError
+	if _, err := luna.Evaluate(`os.exit(3)`); !errors.As(err, &exit) || exit.Code != 3 {
+		t.Errorf("os.exit(3) gave %v, want an ExitError with code 3", err)
+	}
+}
+
+func TestInterpretersRunTasksSideBySide(t *testing.T) {
+	const script = `
+c: var = chan()
+total: var = 0
+fn produce n {
+    for i in range(n) { c.send(i) }
+    c.close()
+}
+spawn produce(200)
+for v in c { total = total + v }
+total
+`
+	results := make([]interp.RuntimeValue, 4)
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = interp.NewLuna(interp.NewGlobalEnvironment()).Evaluate(script)
+		}()
+	}
+	wg.Wait()
+
+	for i, result := range results {
+		if total, _ := interp.FromLuna(result); errs[i] != nil || total != int64(19900) {
+			t.Errorf("interpreter %d got %v (%v), want 19900", i, result, errs[i])
+		}
+	}
+}
+
+func TestConvertMapsSetsAndFieldOrder(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	result, err := luna.Evaluate(`{ names: map([[1, "one"], [2, "two"]]), seen: set([3, 1, 3]) }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := interp.FromLuna(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"names": map[any]any{int64(1): "one", int64(2): "two"}, "seen": []any{int64(3), int64(1)}}
+	if !reflect.DeepEqual(plain, want) {
+		t.Errorf("FromLuna gave %v, want %v", plain, want)
+	}
+
+	var value struct {
+		Names map[int]string `luna:"names"`
+		Seen  []int          `luna:"seen"`
+	}
+	if err := interp.FromLunaInto(result, &value); err != nil {
+		t.Fatal(err)
+	}
+	if value.Names[2] != "two" || !slices.Equal(value.Seen, []int{3, 1}) {
+		t.Errorf("FromLunaInto gave %+v", value)
+	}
+
+	converted, err := interp.ToLuna(struct{ Zebra, Apple, Mango int }{1, 2, 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := converted.(*interp.ObjectValue).Keys(); !slices.Equal(keys, []string{"Zebra", "Apple", "Mango"}) {
+		t.Errorf("struct fields became %v, want them in declaration order", keys)
+	}
+}
+
+func TestConvertCircularValues(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	for _, script := range []string{
+		`a: var = [1]
+a.push(a)
+a`,
+		`o: var = { name: "loop" }
+o.self = o
+o`,
+		`m: var = map()
+m.set("m", m)
+m`,
+	} {
+		result, err := luna.Evaluate(script)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := interp.FromLuna(result); err == nil {
+			t.Errorf("FromLuna of %s did not fail", script)
+		}
+		var target any
+		if err := interp.FromLunaInto(result, &target); err == nil {
+			t.Errorf("FromLunaInto of %s did not fail", script)
+		}
+	}
+
+	type node struct {
+		Name string
+		Next *node
+	}
+	result, err := luna.Evaluate(`o`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded struct {
+		Name string `luna:"name"`
+		Self *node  `luna:"self"`
+	}
+	if err := interp.FromLunaInto(result, &decoded); err == nil {
+		t.Error("FromLunaInto of an object holding itself into a struct did not fail")
+	}
+
+	loop := &node{Name: "loop"}
+	loop.Next = loop
+	if _, err := interp.ToLuna(loop); err == nil {
+		t.Error("ToLuna of a struct pointing to itself did not fail")
+	}
+	m := map[string]any{}
+	m["m"] = m
+	if _, err := interp.ToLuna(m); err == nil {
+		t.Error("ToLuna of a map holding itself did not fail")
+	}
+
+	// The same value twice is no cycle
+	shared := &node{Name: "shared"}
+	if _, err := interp.ToLuna([]*node{shared, shared}); err != nil {
+		t.Errorf("ToLuna of a value seen twice failed: %v", err)
+	}
+}
+
+func TestNumbersAtTheEdgeOfInts(t *testing.T) {
+	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+	for script, want := range map[string]string{
+		`1 << 62`:                "4611686018427387904",
+		`1 << 63`:                "9.223372036854776e+18",
+		`1 << 64`:                "1.8446744073709552e+19",
+		`-1 << 63`:               "-9223372036854775808",
+		`typeof(3)`:              "'number'",
+		`typeof 3.5`:             "'number'",
+		`-(2.0 ** 63)`:           "-9.223372036854776e+18",
+		`2.0 ** 63`:              "9.223372036854776e+18",
+		`-(2.0 ** 62)`:           "-4611686018427387904",
+		`typeof(1 << 64)`:        "'number'",
+		`(1 << 64) == 2.0 ** 64`: "true",
+	} {
+		result, err := luna.Evaluate(script)
+		if err != nil {
+			t.Errorf("%s failed: %v", script, err)
+			continue
+		}
+		if result.String() != want {
+			t.Errorf("%s is %s, want %s", script, result.String(), want)
+		}
+	}
+
+	if text := interp.MakeNumber(-(1 << 63)).String(); text != "-9.223372036854776e+18" {
+		t.Errorf("the float -2**63 prints as %s", text)
+	}
+}
@@ -0,0 +1,276 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// The assert module checks values in tests, failing with an AssertionError:
+//
+//	assert.eq(actual, expected, message)  deep equality, with a diff when it fails
+//	assert.true(value, message)           a truthy value
+//	assert.throws(fn, message)            fn fails, its error message is returned
+//
+// The message is optional. `luna test` runs every function whose name starts
+// with test_ in the files ending in _test.luna under the paths it is given,
+// the current directory by default, and reports each of them:
+//
+//	PASS math_test.luna test_add
+//	FAIL math_test.luna test_split (6µs)
+//	  assert.eq failed
+//	  - ['a', 'b']
+//	  + ['a', 'b', '']
+//	1 passed, 1 failed
+//
+// It exits with 1 when a test fails.
+
+// testPrefix starts the names of the functions `luna test` runs
+const testPrefix = "test_"
+
+// AssertionError is raised by a failed assertion, Expected and Actual are
+// set by assert.eq
+type AssertionError struct {
+	Message  string
+	Expected RuntimeValue
+	Actual   RuntimeValue
+}
+
+func (e *AssertionError) Error() string {
+	if e.Expected == nil {
+		return e.Message
+	}
+	return e.Message + "\n" + valueDiff(e.Expected, e.Actual)
+}
+
+// assertMessage is the optional message argument at index, fallback without one
+func assertMessage(args []RuntimeValue, index int, fallback string) string {
+	if index < len(args) {
+		return interpolatedText(args[index])
+	}
+	return fallback
+}
+
+func createAssertObject() RuntimeValue {
+	assertProps := make(map[string]RuntimeValue)
+
+	assertProps["eq"] = MakeNativeFunction("eq", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("assert.eq expects 2 or 3 arguments, got %d", len(args))
+		}
+		if isEqual(args[0], args[1]) {
+			return MakeVoid(), nil
+		}
+		return nil, &AssertionError{Message: assertMessage(args, 2, "assert.eq failed"), Expected: args[1], Actual: args[0]}
+	})
+
+	assertProps["true"] = MakeNativeFunction("true", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("assert.true expects 1 or 2 arguments, got %d", len(args))
+		}
+		if args[0].IsTruthy() {
+			return MakeVoid(), nil
+		}
+		return nil, &AssertionError{Message: assertMessage(args, 1, fmt.Sprintf("assert.true failed: %s is not truthy", stableString(args[0])))}
+	})
+
+	assertProps["throws"] = MakeNativeFunction("throws", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("assert.throws expects 1 or 2 arguments, got %d", len(args))
+		}
+		_, err := callValue(args[0], nil, env)
+		if err == nil {
+			return nil, &AssertionError{Message: assertMessage(args, 1, "assert.throws failed: the function did not fail")}
+		}
+		return MakeString(err.Error()), nil
+	})
+
+	return MakeObject(assertProps)
+}
+
+// assertLines renders a value a line per element or property once it is
+// too long for one, so that diffs point at what differs
+func assertLines(value RuntimeValue, indent string) []string {
+	text := stableString(value)
+	if len(text) <= diffValueWidth {
+		return []string{indent + text}
+	}
+
+	var open, close string
+	var items [][]string
+	switch v := value.(type) {
+	case *ArrayValue:
+		open, close = "[", "]"
+		for _, elem := range v.Elements {
+			items = append(items, assertLines(elem, indent+"  "))
+		}
+	case *ObjectValue:
+		open, close = "{", "}"
+		keys := make([]string, 0, len(v.Properties))
+		for key := range v.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			lines := assertLines(v.Properties[key], indent+"  ")
+			lines[0] = indent + "  " + key + ": " + strings.TrimPrefix(lines[0], indent+"  ")
+			items = append(items, lines)
+		}
+	default:
+		return []string{indent + text}
+	}
+
+	lines := []string{indent + open}
+	for i, item := range items {
+		if i < len(items)-1 {
+			item[len(item)-1] += ","
+		}
+		lines = append(lines, item...)
+	}
+	return append(lines, indent+close)
+}
+
+// valueDiff shows the lines of expected missing from actual with -, the
+// lines of actual not in expected with +
+func valueDiff(expected, actual RuntimeValue) string {
+	before, after := assertLines(expected, ""), assertLines(actual, "")
+
+	// Longest common subsequence of the lines, from the end
+	common := make([][]int, len(before)+1)
+	for i := range common {
+		common[i] = make([]int, len(after)+1)
+	}
+	for i := len(before) - 1; i >= 0; i-- {
+		for j := len(after) - 1; j >= 0; j-- {
+			if before[i] == after[j] {
+				common[i][j] = common[i+1][j+1] + 1
+			} else {
+				common[i][j] = max(common[i+1][j], common[i][j+1])
+			}
+		}
+	}
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case i < len(before) && j < len(after) && before[i] == after[j]:
+			lines = append(lines, "  "+before[i])
+			i++
+			j++
+		case j == len(after) || (i < len(before) && common[i+1][j] >= common[i][j+1]):
+			lines = append(lines, red("- "+before[i]))
+			i++
+		default:
+			lines = append(lines, green("+ "+after[j]))
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isTestFile reports whether path is a file `luna test` runs, name_test.luna
+func isTestFile(path string) bool {
+	for _, extension := range lunaExtensions {
+		if strings.HasSuffix(path, "_test"+extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSourceFile reports whether path is a Luna file of any name, which
+// `luna test --doc` looks for examples in
+func isSourceFile(path string) bool {
+	for _, extension := range lunaExtensions {
+		if strings.HasSuffix(path, extension) {
+			return true
+		}
+	}
+	return false
+}
+
+// findTestFiles lists the files under paths that match, a file given by
+// name is taken whatever its name
+func findTestFiles(paths []string, match func(string) bool) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.IsDir() && file != path && (strings.HasPrefix(entry.Name(), ".") || entry.Name() == packagesDir) {
+				return filepath.SkipDir
+			}
+			if !entry.IsDir() && match(file) {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runTestFile runs filename and then each of its test_ functions, sorted by name
+func runTestFile(filename string) (passed int, failed int, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not read file '%s': %v", filename, err)
+	}
+
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	if err := setupFromFlags(env); err != nil {
+		return 0, 0, err
+	}
+	env.SetModule(filename)
+
+	if _, err := NewLuna(env).Evaluate(string(data)); err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	var names []string
+	for name, value := range env.variables {
+		if _, isFunction := value.(*FunctionValue); isFunction && strings.HasPrefix(name, testPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		start := time.Now()
+		_, err := callValue(env.variables[name], nil, env)
+		elapsed := time.Since(start).Round(time.Microsecond)
+		if err == nil {
+			passed++
+			fmt.Printf("%s %s %s\n", green("PASS"), filename, name)
+			continue
+		}
+
+		failed++
+		fmt.Printf("%s %s %s %s\n", red("FAIL"), filename, name, gray("("+elapsed.String()+")"))
+		message := err.Error()
+		var assertion *AssertionError
+		if !errors.As(err, &assertion) {
+			message = formatError(errorLabel(err), message)
+		}
+		fmt.Println("  " + strings.ReplaceAll(message, "\n", "\n  "))
+	}
+	return passed, failed, nil
+}
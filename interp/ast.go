@@ -1,4 +1,6 @@
-package main
+package interp
+
+import "math/big"
 
 type NodeType string
 
@@ -8,10 +10,13 @@ const (
 	FUNCTION_DECLARATION NodeType = "FunctionDeclaration"
 	IF_STATEMENT         NodeType = "IfStatement"
 	WHILE_STATEMENT      NodeType = "WhileStatement"
+	DO_WHILE_STATEMENT   NodeType = "DoWhileStatement"
 	FOR_STATEMENT        NodeType = "ForStatement"
+	FOR_IN_STATEMENT     NodeType = "ForInStatement"
 	RETURN_EXPR          NodeType = "ReturnExpr"
 	DEBUG_STATEMENT      NodeType = "DebugStatement"
 	USE_STATEMENT        NodeType = "UseStatement"
+	COMMENT_NODE         NodeType = "Comment"
 
 	// Expressions
 	IDENTIFIER_NODE   NodeType = "Identifier"
@@ -29,6 +34,14 @@ const (
 	ACTION_ASSIGNMENT_EXPR NodeType = "ActionAssignmentExpr"
 	CALL_EXPR              NodeType = "CallExpr"
 	MEMBER_EXPR            NodeType = "MemberExpr"
+	SLICE_EXPR             NodeType = "SliceExpr"
+	RANGE_EXPR             NodeType = "RangeExpr"
+	SPREAD_ELEMENT         NodeType = "SpreadElement"
+	MATCH_EXPR             NodeType = "MatchExpr"
+	SPAWN_EXPR             NodeType = "SpawnExpr"
+	AWAIT_EXPR             NodeType = "AwaitExpr"
+	ARRAY_PATTERN          NodeType = "ArrayPattern"
+	OBJECT_PATTERN         NodeType = "ObjectPattern"
 	TERNARY_EXPR           NodeType = "TernaryExpr"
 	TYPEOF_EXPR            NodeType = "TypeofExpr"
 
@@ -54,19 +67,29 @@ func (p *Program) Kind() NodeType { return PROGRAM_NODE }
 
 // Literals
 type Identifier struct {
-	Value string
+	Value    string
+	Position Position
 }
 
 func (i *Identifier) Kind() NodeType { return IDENTIFIER_NODE }
 
 type NumericLiteral struct {
 	Value float64
+	Raw   string // literal as written in the source
+	IsInt bool   // a whole number literal, evaluated to an int
+	Int   int64
+	Big   *big.Int // set for bigint literals like 123n
+	foldCache
 }
 
 func (n *NumericLiteral) Kind() NodeType { return NUMERIC_LITERAL }
 
 type StringLiteral struct {
 	Value string
+	Raw   string       // literal as written in the source, quotes included
+	Parts []Expression // text and interpolated expressions, nil for a plain string
+	Specs []formatSpec // format spec of each part, nil when no part has one
+	foldCache
 }
 
 func (s *StringLiteral) Kind() NodeType { return STRING_LITERAL }
@@ -93,8 +116,9 @@ type ArrayLiteral struct {
 func (a *ArrayLiteral) Kind() NodeType { return ARRAY_LITERAL }
 
 type Property struct {
-	Key   string
-	Value Expression
+	Key    string
+	Value  Expression
+	Spread bool // {...value}, Key is empty
 }
 
 type ObjectLiteral struct {
@@ -108,6 +132,7 @@ type BinaryExpr struct {
 	Left     Expression
 	Right    Expression
 	Operator string
+	foldCache
 }
 
 func (b *BinaryExpr) Kind() NodeType { return BINARY_EXPR }
@@ -115,13 +140,15 @@ func (b *BinaryExpr) Kind() NodeType { return BINARY_EXPR }
 type UnaryExpr struct {
 	Value    Expression
 	Operator string
+	foldCache
 }
 
 func (u *UnaryExpr) Kind() NodeType { return UNARY_EXPR }
 
 type AssignmentExpr struct {
-	Assigne Expression
-	Value   Expression
+	Assigne  Expression
+	Value    Expression
+	Operator string // "<<" for x <<= v, "" for a plain assignment
 }
 
 func (a *AssignmentExpr) Kind() NodeType { return ASSIGNMENT_EXPR }
@@ -139,9 +166,17 @@ type ActionAssignmentExpr struct {
 
 func (a *ActionAssignmentExpr) Kind() NodeType { return ACTION_ASSIGNMENT_EXPR }
 
+// NamedArg is a `name=value` argument bound to a parameter by name
+type NamedArg struct {
+	Name     string
+	Value    Expression
+	Position Position
+}
+
 type CallExpr struct {
 	Caller Expression
 	Args   []Expression
+	Named  []NamedArg
 }
 
 func (c *CallExpr) Kind() NodeType { return CALL_EXPR }
@@ -150,10 +185,75 @@ type MemberExpr struct {
 	Object   Expression
 	Property Expression
 	Computed bool
+	Optional bool // a?.b, undef instead of an error when a is null or undef
+	methodCache
 }
 
 func (m *MemberExpr) Kind() NodeType { return MEMBER_EXPR }
 
+// SliceExpr is object[start:end], either bound may be omitted (nil)
+type SliceExpr struct {
+	Object Expression
+	Start  Expression
+	End    Expression
+}
+
+func (s *SliceExpr) Kind() NodeType { return SLICE_EXPR }
+
+// RangeExpr is start..end, or start..=end when Inclusive is set
+type RangeExpr struct {
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+func (r *RangeExpr) Kind() NodeType { return RANGE_EXPR }
+
+// PatternElement is one binding of a destructuring pattern
+type PatternElement struct {
+	Key          string     // property to read, for object patterns
+	Target       Expression // an Identifier or a nested pattern
+	DefaultValue Expression // used when the value is missing or undef
+	Rest         bool       // ...name collects the remaining elements or properties
+}
+
+// ArrayPattern is a destructuring target like [a, b = 1, ...rest]
+type ArrayPattern struct {
+	Elements []PatternElement
+}
+
+func (a *ArrayPattern) Kind() NodeType { return ARRAY_PATTERN }
+
+// ObjectPattern is a destructuring target like {name, age = 0, id: key}
+type ObjectPattern struct {
+	Properties []PatternElement
+}
+
+func (o *ObjectPattern) Kind() NodeType { return OBJECT_PATTERN }
+
+// MatchArm is `pattern, pattern => body`, or `else => body` when Default is set
+type MatchArm struct {
+	Patterns []Expression
+	Body     []Statement
+	Block    bool // the body was written as a { ... } block
+	Default  bool
+}
+
+// MatchExpr runs the first arm with a pattern equal to Subject and yields its value
+type MatchExpr struct {
+	Subject Expression
+	Arms    []MatchArm
+}
+
+func (m *MatchExpr) Kind() NodeType { return MATCH_EXPR }
+
+// SpreadElement is ...value inside call arguments or array literals
+type SpreadElement struct {
+	Argument Expression
+}
+
+func (s *SpreadElement) Kind() NodeType { return SPREAD_ELEMENT }
+
 type TernaryExpr struct {
 	Condition  Expression
 	Consequent Expression
@@ -162,6 +262,20 @@ type TernaryExpr struct {
 
 func (t *TernaryExpr) Kind() NodeType { return TERNARY_EXPR }
 
+// SpawnExpr runs a call on its own task: spawn worker(ch)
+type SpawnExpr struct {
+	Call *CallExpr
+}
+
+func (s *SpawnExpr) Kind() NodeType { return SPAWN_EXPR }
+
+// AwaitExpr waits for a task and yields its result: await fetch(url)
+type AwaitExpr struct {
+	Value Expression
+}
+
+func (a *AwaitExpr) Kind() NodeType { return AWAIT_EXPR }
+
 type TypeofExpr struct {
 	Value Expression
 }
@@ -196,6 +310,20 @@ func (l *LogicalExpr) Kind() NodeType { return LOGICAL_EXPR }
 type Parameter struct {
 	Name         string
 	DefaultValue Expression
+	Rest         bool       // ...name collects the remaining arguments
+	Pattern      Expression // destructuring pattern, Name is empty
+	Position     Position
+}
+
+// Label names the parameter for display
+func (p Parameter) Label() string {
+	switch p.Pattern.(type) {
+	case *ArrayPattern:
+		return "[...]"
+	case *ObjectPattern:
+		return "{...}"
+	}
+	return p.Name
 }
 
 // Statements
@@ -205,6 +333,10 @@ type FunctionDeclaration struct {
 	Parameters []Parameter
 	Body       []Statement
 	Export     bool
+	Inline     bool     // declared with the ':' expression body syntax
+	Async      bool     // calls run on a task and return it
+	Doc        string   // the ## lines before the declaration
+	Position   Position // of the name, for a named function
 }
 
 func (f *FunctionDeclaration) Kind() NodeType { return FUNCTION_DECLARATION }
@@ -213,6 +345,7 @@ type IfStatement struct {
 	Test       Expression
 	Consequent []Statement
 	Alternate  []Statement
+	Modifier   string // "if" or "unless" for a condition written after its statement, x() if ready
 }
 
 func (i *IfStatement) Kind() NodeType { return IF_STATEMENT }
@@ -224,6 +357,16 @@ type WhileStatement struct {
 
 func (w *WhileStatement) Kind() NodeType { return WHILE_STATEMENT }
 
+// DoWhileStatement runs its body once before testing, `do { } while test`
+// repeats while the test holds and `repeat { } until test` until it does
+type DoWhileStatement struct {
+	Body  []Statement
+	Test  Expression
+	Until bool
+}
+
+func (d *DoWhileStatement) Kind() NodeType { return DO_WHILE_STATEMENT }
+
 type ForStatement struct {
 	Declaration Expression
 	Test        Expression
@@ -233,14 +376,27 @@ type ForStatement struct {
 
 func (f *ForStatement) Kind() NodeType { return FOR_STATEMENT }
 
+// ForInStatement is `for name in iterable { ... }`
+type ForInStatement struct {
+	Variable *Identifier
+	Iterable Expression
+	Body     []Statement
+}
+
+func (f *ForInStatement) Kind() NodeType { return FOR_IN_STATEMENT }
+
 type ReturnExpr struct {
-	Value Expression
+	Value    Expression
+	Position Position
 }
 
 func (r *ReturnExpr) Kind() NodeType { return RETURN_EXPR }
 
+// DebugStatement is debug x or debug "label" { a, b }
 type DebugStatement struct {
-	Props []Expression
+	Label    Expression // a string literal, nil without a label
+	Props    []Expression
+	Position Position
 }
 
 func (d *DebugStatement) Kind() NodeType { return DEBUG_STATEMENT }
@@ -250,3 +406,11 @@ type UseStatement struct {
 }
 
 func (u *UseStatement) Kind() NodeType { return USE_STATEMENT }
+
+// Comment is only produced when the tokenizer keeps comments (formatter)
+type Comment struct {
+	Text     string
+	Trailing bool // written after the code of its line, which it stays with
+}
+
+func (c *Comment) Kind() NodeType { return COMMENT_NODE }
@@ -0,0 +1,217 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bench(name, fn, iterations) times a function from a script. `luna bench
+// file` runs the file, then every top-level function whose name starts with
+// bench_, and compares them:
+//
+//	benchmark           runs        ns/op       median          p95  vs fastest
+//	bench_join          1000         2140       2.05µs       2.87µs     fastest
+//	bench_concat        1000         5310       5.12µs       6.40µs       2.48x
+//
+// --iterations=N sets the runs of each function, 1000 by default. Every
+// benchmark is warmed up with a tenth of its runs first.
+
+const defaultBenchIterations = 1000
+
+// benchPrefix starts the names of the functions `luna bench` runs
+const benchPrefix = "bench_"
+
+// BenchResult holds the timings of a benchmark run
+type BenchResult struct {
+	Name       string
+	Iterations int
+	Mean       time.Duration
+	Median     time.Duration
+	P95        time.Duration
+	Min        time.Duration
+	Max        time.Duration
+}
+
+// runBenchmark warms fn up, then times each of the iterations separately
+func runBenchmark(name string, fn RuntimeValue, iterations int, env *Environment) (*BenchResult, error) {
+	warmup := max(1, iterations/10)
+	for i := 0; i < warmup; i++ {
+		if _, err := callValue(fn, nil, env); err != nil {
+			return nil, err
+		}
+	}
+
+	samples := make([]time.Duration, iterations)
+	var total time.Duration
+	for i := range samples {
+		start := time.Now()
+		if _, err := callValue(fn, nil, env); err != nil {
+			return nil, err
+		}
+		samples[i] = time.Since(start)
+		total += samples[i]
+	}
+	sort.Slice(samples, func(a, b int) bool { return samples[a] < samples[b] })
+
+	return &BenchResult{
+		Name:       name,
+		Iterations: iterations,
+		Mean:       total / time.Duration(iterations),
+		Median:     samples[iterations/2],
+		P95:        samples[min(iterations-1, iterations*95/100)],
+		Min:        samples[0],
+		Max:        samples[iterations-1],
+	}, nil
+}
+
+func (b *BenchResult) String() string {
+	return fmt.Sprintf("%s %s  %s ns/op  median %s  p95 %s  %s",
+		magenta("bench"), bold(b.Name), yellow(strconv.FormatInt(b.Mean.Nanoseconds(), 10)), yellow(b.Median.String()),
+		yellow(b.P95.String()), gray(fmt.Sprintf("(%d runs)", b.Iterations)))
+}
+
+// Object converts the result to a Luna object with timings in milliseconds, like io.time
+func (b *BenchResult) Object() RuntimeValue {
+	ms := func(d time.Duration) RuntimeValue { return MakeNumber(float64(d) / float64(time.Millisecond)) }
+	return MakeObject(map[string]RuntimeValue{
+		"name":       MakeString(b.Name),
+		"iterations": MakeInt(int64(b.Iterations)),
+		"nsPerOp":    MakeInt(b.Mean.Nanoseconds()),
+		"mean":       ms(b.Mean),
+		"median":     ms(b.Median),
+		"p95":        ms(b.P95),
+		"min":        ms(b.Min),
+		"max":        ms(b.Max),
+	})
+}
+
+// benchNative implements bench(name, fn, iterations)
+func benchNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("bench expects 2 or 3 arguments, got %d", len(args))
+	}
+	name, ok := args[0].(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("bench expects a name string as first argument")
+	}
+	if args[1].Type() != FUNCTION_TYPE && args[1].Type() != NATIVE_FN_TYPE {
+		return nil, fmt.Errorf("bench expects a function as second argument")
+	}
+
+	iterations := defaultBenchIterations
+	if len(args) == 3 {
+		count, ok := toFloat(args[2])
+		if !ok || count < 1 {
+			return nil, fmt.Errorf("bench iterations must be a positive number")
+		}
+		iterations = int(count)
+	}
+
+	result, err := runBenchmark(name.Value, args[1], iterations, env)
+	if err != nil {
+		return nil, fmt.Errorf("bench %s: %v", name.Value, err)
+	}
+	fmt.Println(result)
+	return result.Object(), nil
+}
+
+// runBenchFile runs filename and then each of its bench_ functions, sorted by name
+func runBenchFile(filename string, iterations int) ([]*BenchResult, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("could not read file '%s': %v", filename, err)
+	}
+
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	if err := setupFromFlags(env); err != nil {
+		return nil, err
+	}
+
+	if _, err := NewLuna(env).Evaluate(string(data)); err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	var names []string
+	for name, value := range env.variables {
+		if _, isFunction := value.(*FunctionValue); isFunction && strings.HasPrefix(name, benchPrefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	results := make([]*BenchResult, 0, len(names))
+	for _, name := range names {
+		result, err := runBenchmark(name, env.variables[name], iterations, env)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// benchTable lines the results up, each compared with the fastest
+func benchTable(results []*BenchResult) string {
+	fastest := results[0].Mean
+	width := len("benchmark")
+	for _, result := range results {
+		fastest = min(fastest, result.Mean)
+		width = max(width, len(result.Name))
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, gray("%-*s %8s %12s %12s %12s  %10s")+"\n", width, "benchmark", "runs", "ns/op", "median", "p95", "vs fastest")
+	for _, result := range results {
+		comparison := green("fastest")
+		if result.Mean != fastest {
+			comparison = yellow(fmt.Sprintf("%.2fx", float64(result.Mean)/float64(max(fastest, 1))))
+		}
+		fmt.Fprintf(&out, "%-*s %8d %12d %12s %12s  %10s\n", width, bold(result.Name), result.Iterations,
+			result.Mean.Nanoseconds(), result.Median, result.P95, comparison)
+	}
+	return out.String()
+}
+
+// runBench is the entry point of `luna bench`
+func runBench(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: luna bench [--iterations=N] <file>...")
+		return
+	}
+
+	iterations := defaultBenchIterations
+	if value, found := flagValue("--iterations"); found {
+		count, err := strconv.Atoi(value)
+		if err != nil || count < 1 {
+			fmt.Println(formatError("Error", "--iterations must be a positive number"))
+			os.Exit(1)
+		}
+		iterations = count
+	}
+
+	failed := false
+	for _, filename := range args {
+		results, err := runBenchFile(filename, iterations)
+		if err != nil {
+			fmt.Println(formatError("Error", err.Error()))
+			failed = true
+			continue
+		}
+		if len(results) == 0 {
+			fmt.Println(gray(fmt.Sprintf("%s: no %s functions", filename, benchPrefix)))
+			continue
+		}
+		if len(args) > 1 {
+			fmt.Println(bold(filename))
+		}
+		fmt.Print(benchTable(results))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,316 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// browseRow is one line of the :browse tree
+type browseRow struct {
+	path  string // unique key of the row, used to remember what is expanded
+	depth int
+	label string
+	value RuntimeValue
+}
+
+// Browser is the full screen variable explorer of the REPL
+type Browser struct {
+	env      *Environment
+	reader   *bufio.Reader
+	expanded map[string]bool
+	rows     []browseRow
+	selected int
+	offset   int // first row on screen
+
+	filter    string
+	searching bool // the filter is being typed
+}
+
+// builtinNames are the globals every environment starts with, :browse hides them
+func builtinNames() map[string]bool {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	names := make(map[string]bool, len(env.variables))
+	for name := range env.variables {
+		names[name] = true
+	}
+	return names
+}
+
+// userVariables lists the bindings made in the session, innermost scope first
+func userVariables(env *Environment) map[string]RuntimeValue {
+	builtins := builtinNames()
+	variables := make(map[string]RuntimeValue)
+	for scope := env; scope != nil; scope = scope.parent {
+		for name, value := range scope.variables {
+			if _, shadowed := variables[name]; shadowed {
+				continue
+			}
+			if builtins[name] && scope.parent == nil {
+				continue
+			}
+			variables[name] = value
+		}
+	}
+	return variables
+}
+
+// valueSize describes how big a value is: elements, properties or characters
+func valueSize(value RuntimeValue) string {
+	switch v := value.(type) {
+	case *ArrayValue:
+		return strconv.Itoa(len(v.Elements)) + " items"
+	case *ObjectValue:
+		return strconv.Itoa(len(v.Properties)) + " keys"
+	case *StringValue:
+		return strconv.Itoa(utf8.RuneCountInString(v.Value)) + " chars"
+	case *RangeValue:
+		return strconv.Itoa(v.Len()) + " items"
+	}
+	return ""
+}
+
+func expandable(value RuntimeValue) bool {
+	switch v := value.(type) {
+	case *ArrayValue:
+		return len(v.Elements) > 0
+	case *ObjectValue:
+		return len(v.Properties) > 0
+	}
+	return false
+}
+
+// build flattens the variables and the expanded values into rows
+func (b *Browser) build() {
+	b.rows = b.rows[:0]
+	variables := userVariables(b.env)
+	filter := strings.ToLower(b.filter)
+	for _, name := range sortedKeys(variables) {
+		if filter != "" && !strings.Contains(strings.ToLower(name), filter) {
+			continue
+		}
+		b.addRow(browseRow{path: name, label: name, value: variables[name]})
+	}
+	b.selected = max(0, min(b.selected, len(b.rows)-1))
+}
+
+func (b *Browser) addRow(row browseRow) {
+	b.rows = append(b.rows, row)
+	if !b.expanded[row.path] {
+		return
+	}
+	switch v := row.value.(type) {
+	case *ArrayValue:
+		for i, element := range v.Elements {
+			b.addRow(browseRow{path: fmt.Sprintf("%s[%d]", row.path, i), depth: row.depth + 1, label: strconv.Itoa(i), value: element})
+		}
+	case *ObjectValue:
+		for _, key := range v.Keys() {
+			b.addRow(browseRow{path: row.path + "." + key, depth: row.depth + 1, label: key, value: v.Properties[key]})
+		}
+	}
+}
+
+func (b *Browser) render() {
+	width, height, err := terminalSize(int(os.Stdout.Fd()))
+	if err != nil || width == 0 || height == 0 {
+		width, height = 80, 24
+	}
+	visible := max(1, height-2) // title and status lines
+
+	if b.selected < b.offset {
+		b.offset = b.selected
+	}
+	if b.selected >= b.offset+visible {
+		b.offset = b.selected - visible + 1
+	}
+
+	var out strings.Builder
+	out.WriteString("\033[H\033[J")
+	out.WriteString(bold(fmt.Sprintf(" Variables (%d)", len(b.rows))) + "\r\n")
+
+	for i := b.offset; i < min(len(b.rows), b.offset+visible); i++ {
+		row := b.rows[i]
+		marker := "  "
+		if expandable(row.value) {
+			marker = "▸ "
+			if b.expanded[row.path] {
+				marker = "▾ "
+			}
+		}
+
+		info := string(row.value.Type())
+		if size := valueSize(row.value); size != "" {
+			info += ", " + size
+		}
+		line := strings.Repeat("  ", row.depth) + marker + row.label + " (" + info + ") "
+		preview := stableString(row.value)
+		if room := width - utf8.RuneCountInString(line) - 1; room > 1 && utf8.RuneCountInString(preview) > room {
+			preview = string([]rune(preview)[:room-1]) + "…"
+		} else if room <= 1 {
+			preview = ""
+		}
+
+		text := strings.Repeat("  ", row.depth) + gray(marker) + blue(row.label) + gray(" ("+info+") ") + preview
+		if i == b.selected {
+			text = "\033[7m" + stripColor(text) + "\033[0m"
+		}
+		out.WriteString(text + "\r\n")
+	}
+
+	if b.searching {
+		out.WriteString(yellow("/") + b.filter)
+	} else {
+		status := "↑↓ move  → expand  ← collapse  / search  q quit"
+		if b.filter != "" {
+			status = "filter: " + b.filter + "  " + status
+		}
+		out.WriteString(gray(status))
+	}
+	fmt.Print(out.String())
+}
+
+// collapse folds the selected row, or moves to its parent when it is not expanded
+func (b *Browser) collapse() {
+	if len(b.rows) == 0 {
+		return
+	}
+	row := b.rows[b.selected]
+	if b.expanded[row.path] {
+		delete(b.expanded, row.path)
+		return
+	}
+	for i := b.selected - 1; i >= 0; i-- {
+		if b.rows[i].depth < row.depth {
+			b.selected = i
+			return
+		}
+	}
+}
+
+// key applies one key press, reporting whether the browser should close
+func (b *Browser) key(char rune) (bool, error) {
+	if b.searching {
+		switch char {
+		case '\r', '\n':
+			b.searching = false
+		case 27: // Escape clears the search
+			b.searching = false
+			b.filter = ""
+		case 127, 8:
+			if b.filter != "" {
+				_, size := utf8.DecodeLastRuneInString(b.filter)
+				b.filter = b.filter[:len(b.filter)-size]
+			}
+		default:
+			if char >= ' ' {
+				b.filter += string(char)
+			}
+		}
+		b.selected = 0
+		return false, nil
+	}
+
+	switch char {
+	case 'q', 3: // q or Ctrl+C
+		return true, nil
+	case 27:
+		if b.reader.Buffered() == 0 {
+			return true, nil // a lone Escape
+		}
+		sequence, err := b.readCSI()
+		if err != nil {
+			return true, err
+		}
+		switch sequence {
+		case "A":
+			b.selected = max(0, b.selected-1)
+		case "B":
+			b.selected = min(len(b.rows)-1, b.selected+1)
+		case "C":
+			b.expand()
+		case "D":
+			b.collapse()
+		}
+	case 'k':
+		b.selected = max(0, b.selected-1)
+	case 'j':
+		b.selected = min(len(b.rows)-1, b.selected+1)
+	case 'l', '\r', '\n':
+		b.expand()
+	case 'h':
+		b.collapse()
+	case '/':
+		b.searching = true
+		b.filter = ""
+	}
+	return false, nil
+}
+
+func (b *Browser) expand() {
+	if len(b.rows) == 0 {
+		return
+	}
+	if row := b.rows[b.selected]; expandable(row.value) {
+		b.expanded[row.path] = true
+	}
+}
+
+func (b *Browser) readCSI() (string, error) {
+	if next, _, err := b.reader.ReadRune(); err != nil || (next != '[' && next != 'O') {
+		return "", err
+	}
+	sequence := ""
+	for {
+		char, _, err := b.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		sequence += string(char)
+		if char >= '@' && char <= '~' {
+			return sequence, nil
+		}
+	}
+}
+
+// runBrowser opens the explorer on the alternate screen until q is pressed.
+// Without a terminal it prints the variables instead.
+func runBrowser(env *Environment, reader *bufio.Reader) {
+	browser := &Browser{env: env, reader: reader, expanded: make(map[string]bool)}
+
+	restore, err := enableRawMode(int(os.Stdin.Fd()))
+	if err != nil {
+		browser.build()
+		for _, row := range browser.rows {
+			fmt.Println(blue(row.label) + gray(" ("+string(row.value.Type())+") ") + truncateValue(stableString(row.value)))
+		}
+		return
+	}
+	defer restore()
+
+	fmt.Print("\033[?1049h\033[?25l") // alternate screen, hidden cursor
+	defer fmt.Print("\033[?25h\033[?1049l")
+
+	for {
+		browser.build()
+		if len(browser.rows) == 0 && browser.filter == "" && !browser.searching {
+			fmt.Print("\033[H\033[J" + gray("No variables yet, press any key"))
+			reader.ReadRune()
+			return
+		}
+		browser.render()
+
+		char, _, err := reader.ReadRune()
+		if err != nil {
+			return
+		}
+		done, err := browser.key(char)
+		if done || err != nil {
+			return
+		}
+	}
+}
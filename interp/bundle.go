@@ -0,0 +1,305 @@
+package interp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A bundle is a luna runtime with scripts appended to it:
+//
+//	runtime executable | manifest JSON | payload length (8 bytes) | bundleMagic
+//
+// Any luna binary can be used as the runtime, so building for another
+// platform only needs a luna binary built for it (--runtime=path). The std
+// modules are natives compiled into the runtime, its checksum covers them.
+// The scripts given after the entry are the modules it uses, `use` in a
+// built binary reads them from the bundle before looking on disk.
+const bundleMagic = "LUNABNDL"
+
+// embeddedFiles are the scripts of the bundle this binary runs, by name
+var embeddedFiles map[string][]byte
+
+// BundleFile is a script embedded in a bundle, with the hash of its source
+type BundleFile struct {
+	Name    string `json:"name"`
+	SHA256  string `json:"sha256"`
+	Content []byte `json:"content"`
+}
+
+// Bundle is the manifest appended to a built binary
+type Bundle struct {
+	Entry   string       `json:"entry"`
+	Runtime string       `json:"runtime"` // sha256 of the runtime executable, std modules included
+	Files   []BundleFile `json:"files"`
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// bundleName is the name a script is embedded under, the path it was built
+// from cleaned and with forward slashes
+func bundleName(path string) string {
+	return filepath.ToSlash(filepath.Clean(path))
+}
+
+// readModuleFile reads the file of a module, from the bundle when this
+// binary embeds it
+func readModuleFile(path string) ([]byte, error) {
+	if content, embedded := embeddedFiles[bundleName(path)]; embedded {
+		return content, nil
+	}
+	return os.ReadFile(path)
+}
+
+// flagValue returns the value of a --name=value flag passed to the interpreter
+func flagValue(name string) (string, bool) {
+	args, _ := splitArgs()
+	for _, arg := range args {
+		if value, found := strings.CutPrefix(arg, name+"="); found {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// splitBundle separates a binary into its runtime and bundle, the bundle is nil for plain binaries
+func splitBundle(data []byte) ([]byte, *Bundle, error) {
+	trailer := 8 + len(bundleMagic)
+	if len(data) < trailer || string(data[len(data)-len(bundleMagic):]) != bundleMagic {
+		return data, nil, nil
+	}
+
+	size := binary.BigEndian.Uint64(data[len(data)-trailer:])
+	if size > uint64(len(data)-trailer) {
+		return nil, nil, fmt.Errorf("corrupt bundle: payload larger than the binary")
+	}
+	start := len(data) - trailer - int(size)
+
+	var bundle Bundle
+	if err := json.Unmarshal(data[start:len(data)-trailer], &bundle); err != nil {
+		return nil, nil, fmt.Errorf("corrupt bundle: %v", err)
+	}
+	return data[:start], &bundle, nil
+}
+
+// buildBundle appends the scripts to runtime, the first file is the entry point
+func buildBundle(runtime []byte, filenames []string) ([]byte, error) {
+	runtime, _, err := splitBundle(runtime)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := Bundle{Entry: bundleName(filenames[0]), Runtime: sha256Hex(runtime)}
+	for _, filename := range filenames {
+		content, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Files = append(bundle.Files, BundleFile{
+			Name:    bundleName(filename),
+			SHA256:  sha256Hex(content),
+			Content: content,
+		})
+	}
+
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	out.Write(runtime)
+	out.Write(payload)
+	binary.Write(&out, binary.BigEndian, uint64(len(payload)))
+	out.WriteString(bundleMagic)
+	return out.Bytes(), nil
+}
+
+// verifyBundle checks that the artifact is intact and, when sources are
+// given, that it was built from them. It returns one line per problem.
+func verifyBundle(data []byte, sources []string) ([]string, error) {
+	runtime, bundle, err := splitBundle(data)
+	if err != nil {
+		return nil, err
+	}
+	if bundle == nil {
+		return nil, fmt.Errorf("not a luna bundle")
+	}
+
+	var problems []string
+	if sha256Hex(runtime) != bundle.Runtime {
+		problems = append(problems, "runtime does not match its recorded checksum")
+	}
+
+	embedded := make(map[string]BundleFile)
+	for _, file := range bundle.Files {
+		embedded[file.Name] = file
+		if sha256Hex(file.Content) != file.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: embedded content does not match its recorded checksum", file.Name))
+		}
+	}
+
+	for _, source := range sources {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, err
+		}
+		file, exists := embedded[bundleName(source)]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("%s: not embedded in the binary", source))
+		} else if sha256Hex(content) != file.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: source differs from the embedded version", source))
+		}
+	}
+
+	return problems, nil
+}
+
+// hasBundleTrailer checks the end of a file for bundleMagic without reading all of it
+func hasBundleTrailer(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil || info.Size() < int64(len(bundleMagic)) {
+		return false
+	}
+	magic := make([]byte, len(bundleMagic))
+	if _, err := file.ReadAt(magic, info.Size()-int64(len(magic))); err != nil {
+		return false
+	}
+	return string(magic) == bundleMagic
+}
+
+// runEmbeddedBundle runs the entry script when this binary was produced by
+// `luna build`, it reports false for a plain luna binary
+func runEmbeddedBundle() bool {
+	executable, err := os.Executable()
+	if err != nil || !hasBundleTrailer(executable) {
+		return false
+	}
+	data, err := os.ReadFile(executable)
+	if err != nil {
+		return false
+	}
+	_, bundle, err := splitBundle(data)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	if bundle == nil {
+		return false
+	}
+
+	embeddedFiles = make(map[string][]byte, len(bundle.Files))
+	for _, file := range bundle.Files {
+		embeddedFiles[file.Name] = file.Content
+	}
+
+	for _, file := range bundle.Files {
+		if file.Name != bundle.Entry {
+			continue
+		}
+
+		// All the arguments of a built binary belong to its script
+		scriptArgs = os.Args[1:]
+		env := NewEnvironment(nil)
+		setupNativeFunctions(env)
+		env.SetModule(file.Name)
+		if _, err := NewLuna(env).Evaluate(string(file.Content)); err != nil {
+			exitOnRequest(err)
+			fmt.Println(formatError("Error", err.Error()))
+			os.Exit(1)
+		}
+		waitForTimers(env)
+		return true
+	}
+
+	fmt.Println(formatError("Error", fmt.Sprintf("bundle has no entry script '%s'", bundle.Entry)))
+	os.Exit(1)
+	return true
+}
+
+// runBuild is the entry point of `luna build`
+func runBuild(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: luna build <main.ln> [module...] [--out=path] [--runtime=luna-binary]")
+		return
+	}
+
+	runtimePath, found := flagValue("--runtime")
+	if !found {
+		executable, err := os.Executable()
+		if err != nil {
+			fmt.Println(formatError("Error", err.Error()))
+			os.Exit(1)
+		}
+		runtimePath = executable
+	}
+	runtime, err := os.ReadFile(runtimePath)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+
+	output, found := flagValue("--out")
+	if !found {
+		output = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+	}
+
+	data, err := buildBundle(runtime, args)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	if err := os.WriteFile(output, data, 0755); err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+
+	fmt.Println(green("Built " + output))
+	_, bundle, _ := splitBundle(data)
+	fmt.Printf("  %s %s\n", gray(bundle.Runtime), "runtime and std modules")
+	for _, file := range bundle.Files {
+		fmt.Printf("  %s %s\n", gray(file.SHA256), file.Name)
+	}
+}
+
+// runVerify is the entry point of `luna verify binary`
+func runVerify(args []string) {
+	if len(args) < 2 || args[0] != "binary" {
+		fmt.Println("Usage: luna verify binary <artifact> [source...]")
+		return
+	}
+
+	data, err := os.ReadFile(args[1])
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+
+	problems, err := verifyBundle(data, args[2:])
+	if err != nil {
+		fmt.Println(formatError("Error", fmt.Sprintf("%s: %v", args[1], err)))
+		os.Exit(1)
+	}
+	if len(problems) > 0 {
+		for _, problem := range problems {
+			fmt.Println(red("✗ ") + problem)
+		}
+		os.Exit(1)
+	}
+	fmt.Println(green("✓ " + args[1] + " matches its declared sources"))
+}
@@ -0,0 +1,188 @@
+package interp
+
+import (
+	"strings"
+	"sync/atomic"
+)
+
+// The tree-walker keeps what it derives from a node the first time it
+// evaluates it, so a function that runs many times, from a loop or defined
+// once in the REPL, does not redo the work on every call. Subtrees made only
+// of literals are folded: the value of a number literal, 60 * 60 * 24,
+// -1 and "total: {2 ** 10}" are computed once and reused. Interpolated
+// strings are split into text and expressions by the parser already, so only
+// their constant expressions are left to fold. A method call such as
+// name.upper() remembers the method it found for the type of its receiver,
+// the next call on a receiver of that type skips the lookup and the binding
+// of the method. Nodes are shared by tasks running in parallel, the cache is
+// written atomically.
+
+// cachedValue is the folded value of a node, nil when the node depends on
+// something only known at run time
+type cachedValue struct {
+	value RuntimeValue
+}
+
+// foldCache is embedded in the nodes that may fold
+type foldCache struct {
+	folded atomic.Pointer[cachedValue]
+}
+
+// constantValue is the value of node when it is made only of literals,
+// computed on the first call and remembered on the node
+func constantValue(node Expression) (RuntimeValue, bool) {
+	var cache *foldCache
+	switch n := node.(type) {
+	case *NumericLiteral:
+		cache = &n.foldCache
+	case *StringLiteral:
+		cache = &n.foldCache
+	case *BinaryExpr:
+		cache = &n.foldCache
+	case *UnaryExpr:
+		cache = &n.foldCache
+	case *BooleanLiteral:
+		return MakeBool(n.Value), true
+	default:
+		return nil, false
+	}
+
+	cached := cache.folded.Load()
+	if cached == nil {
+		cached = &cachedValue{value: foldNode(node)}
+		cache.folded.Store(cached)
+	}
+	return cached.value, cached.value != nil
+}
+
+// foldNode computes the value of a constant node, nil when it is not
+// constant or evaluating it fails, the error is left for run time
+func foldNode(node Expression) RuntimeValue {
+	switch n := node.(type) {
+	case *NumericLiteral:
+		return numericLiteralValue(n)
+	case *StringLiteral:
+		if n.Parts == nil {
+			return MakeString(n.Value)
+		}
+		var result strings.Builder
+		for i, part := range n.Parts {
+			value, ok := constantValue(part)
+			if !ok {
+				return nil
+			}
+			text, err := partText(n, i, value)
+			if err != nil {
+				return nil
+			}
+			result.WriteString(text)
+		}
+		return MakeString(result.String())
+	case *BinaryExpr:
+		left, ok := constantValue(n.Left)
+		if !ok {
+			return nil
+		}
+		right, ok := constantValue(n.Right)
+		if !ok {
+			return nil
+		}
+		value, err := evaluateBinaryOperation(left, right, n.Operator)
+		if err != nil {
+			return nil
+		}
+		return value
+	case *UnaryExpr:
+		switch n.Operator {
+		case "!", "-", "+", "~":
+		default:
+			return nil // ++ and -- change a variable
+		}
+		operand, ok := constantValue(n.Value)
+		if !ok {
+			return nil
+		}
+		// How ! reads anything but a boolean depends on the truthiness
+		// option of the environment running it
+		if _, isBool := operand.(*BooleanValue); n.Operator == "!" && !isBool {
+			return nil
+		}
+		value, err := evaluateUnaryOperation(operand, n.Operator, nil)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+	return nil
+}
+
+// prototypeCall calls a method of a prototype table on its receiver
+type prototypeCall func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error)
+
+// cachedMethod is the method a call site found for receivers of one type
+type cachedMethod struct {
+	receiver ValueType
+	call     prototypeCall
+}
+
+// methodCache is embedded in member expressions, an inline cache of the
+// last receiver type a method call went through
+type methodCache struct {
+	method atomic.Pointer[cachedMethod]
+}
+
+// cachedMethod is the prototype method node names on receiver, looked up
+// once per receiver type and call site, nil when the receiver has no such
+// method or the call must go through the full lookup
+func (node *MemberExpr) cachedMethod(receiver RuntimeValue) prototypeCall {
+	identifier, ok := node.Property.(*Identifier)
+	if node.Computed || !ok || profiler != nil {
+		return nil
+	}
+	if cached := node.method.Load(); cached != nil && cached.receiver == receiver.Type() {
+		return cached.call
+	}
+	call := lookupMethod(receiver, identifier.Value)
+	if call != nil {
+		node.method.Store(&cachedMethod{receiver: receiver.Type(), call: call})
+	}
+	return call
+}
+
+// lookupMethod finds key in the prototype table of the type of receiver,
+// the types without one are left to prototypeMethod
+func lookupMethod(receiver RuntimeValue, key string) prototypeCall {
+	switch receiver.(type) {
+	case *StringValue:
+		if fn, ok := StringPrototype[key]; ok {
+			return func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(receiver.(*StringValue), args, env)
+			}
+		}
+	case *ArrayValue:
+		if fn, ok := ArrayPrototype[key]; ok {
+			return func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(receiver.(*ArrayValue), args, env)
+			}
+		}
+	case *RangeValue:
+		if fn, ok := RangePrototype[key]; ok {
+			return func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(receiver.(*RangeValue), args, env)
+			}
+		}
+	case *MapValue:
+		if fn, ok := MapPrototype[key]; ok {
+			return func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(receiver.(*MapValue), args, env)
+			}
+		}
+	case *SetValue:
+		if fn, ok := SetPrototype[key]; ok {
+			return func(receiver RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(receiver.(*SetValue), args, env)
+			}
+		}
+	}
+	return nil
+}
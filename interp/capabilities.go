@@ -0,0 +1,127 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// nativeModules are the globals that can be disabled when embedding Luna
+var nativeModules = []string{"io", "math", "msgpack", "cbor", "proto", "mock", "http", "bench", "assert", "file", "time", "date", "os", "crypto", "secrets", "schema", "fuzzy", "colors", "ui"}
+
+// moduleNames are the native modules of env, those every environment has
+// and those registered on its interpreter alone
+func (env *Environment) moduleNames() []string {
+	return append(slices.Clone(nativeModules), env.root().modules...)
+}
+
+// PermissionError is raised by the natives of a disabled module
+type PermissionError struct {
+	Module string
+	Name   string
+}
+
+func (e *PermissionError) Error() string {
+	if e.Name == e.Module {
+		return fmt.Sprintf("%s is disabled in this environment", e.Module)
+	}
+	return fmt.Sprintf("%s.%s is disabled in this environment", e.Module, e.Name)
+}
+
+// errorLabel names the kind of error for display
+func errorLabel(err error) string {
+	var permission *PermissionError
+	if errors.As(err, &permission) {
+		return "PermissionError"
+	}
+	var assertion *AssertionError
+	if errors.As(err, &assertion) {
+		return "AssertionError"
+	}
+	var limit *LimitError
+	if errors.As(err, &limit) {
+		return "LimitError"
+	}
+	var syntax *SyntaxError
+	if errors.As(err, &syntax) {
+		return "SyntaxError"
+	}
+	return "Error"
+}
+
+// disabledNative stands in for a native of a disabled module
+func disabledNative(module, name string) RuntimeValue {
+	return MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		return nil, &PermissionError{Module: module, Name: name}
+	})
+}
+
+// DisableModule replaces the natives of a module with ones raising a
+// PermissionError, the module itself stays defined so scripts can adapt
+func DisableModule(env *Environment, module string) error {
+	if !slices.Contains(env.moduleNames(), module) {
+		return fmt.Errorf("unknown module '%s'", module)
+	}
+
+	if env.disabled == nil {
+		env.disabled = make(map[string]bool)
+	}
+	env.disabled[module] = true
+
+	switch value := env.LookupVar(module).(type) {
+	case *NativeFunctionValue:
+		env.variables[module] = disabledNative(module, module)
+	case *ObjectValue:
+		properties := make(map[string]RuntimeValue, len(value.Properties))
+		for name, property := range value.Properties {
+			if property.Type() == NATIVE_FN_TYPE {
+				property = disabledNative(module, name)
+			}
+			properties[name] = property
+		}
+		env.variables[module] = MakeObject(properties)
+	}
+	return nil
+}
+
+// disableModulesFromFlags applies --disable=io,http to env
+func disableModulesFromFlags(env *Environment) error {
+	list, found := flagValue("--disable")
+	if !found {
+		return nil
+	}
+	for _, module := range strings.Split(list, ",") {
+		if module = strings.TrimSpace(module); module == "" {
+			continue
+		}
+		if err := DisableModule(env, module); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// capabilitiesNative returns { module: enabled } for every native module
+func capabilitiesNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	root := env.root()
+	names := env.moduleNames()
+	sort.Strings(names)
+	capabilities := make(map[string]RuntimeValue, len(names))
+	for _, name := range names {
+		capabilities[name] = MakeBool(!root.disabled[name])
+	}
+	return MakeObject(capabilities), nil
+}
+
+// setupCapabilities applies the command line restrictions
+func setupCapabilities(env *Environment) error {
+	if err := disableModulesFromFlags(env); err != nil {
+		return err
+	}
+	if hasFlag("--sandbox") {
+		return Sandbox(env)
+	}
+	return nil
+}
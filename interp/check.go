@@ -0,0 +1,512 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type symbol struct {
+	name     string
+	position Position
+	used     bool
+	param    bool
+	native   bool
+	function *FunctionDeclaration // set when the symbol is a named function
+}
+
+type checkScope struct {
+	parent     *checkScope
+	symbols    map[string]*symbol
+	global     bool
+	inFunction bool // the scope is a function body or is inside one
+	// function bodies are checked once the enclosing body is done, like they run after it
+	deferred []*FunctionDeclaration
+}
+
+// Checker statically analyses an AST without evaluating it
+type Checker struct {
+	scope       *checkScope
+	diagnostics []Diagnostic
+	warnings    bool // also look for what Warnings reports
+	// when not nil, the symbol each identifier declares or refers to, by its index
+	resolved map[int]*symbol
+}
+
+func NewChecker() *Checker {
+	globals := &checkScope{symbols: make(map[string]*symbol), global: true}
+
+	// Natives are always in scope
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	for name := range env.variables {
+		globals.symbols[name] = &symbol{name: name, used: true, native: true}
+	}
+
+	return &Checker{scope: globals}
+}
+
+// Check walks the program and returns the diagnostics sorted by position
+func (c *Checker) Check(program *Program) []Diagnostic {
+	c.checkBody(program.Body)
+	c.flushDeferred()
+
+	sort.SliceStable(c.diagnostics, func(i, j int) bool {
+		return c.diagnostics[i].Span.Start.Index < c.diagnostics[j].Span.Start.Index
+	})
+	return c.diagnostics
+}
+
+// Warnings walks the program for the warnings shown after it runs: names a
+// function creates by assignment, variables shadowing outer ones and unused
+// parameters
+func (c *Checker) Warnings(program *Program) []Diagnostic {
+	c.warnings = true
+	var warnings []Diagnostic
+	for _, diagnostic := range c.Check(program) {
+		switch diagnostic.Code {
+		case codeImplicitGlobal, codeShadowed, codeUnusedParam:
+			warnings = append(warnings, diagnostic)
+		}
+	}
+	return warnings
+}
+
+func (c *Checker) report(code string, position Position, format string, args ...any) {
+	c.diagnostics = append(c.diagnostics, newDiagnostic(code, fmt.Sprintf(format, args...), &Span{Start: position}))
+}
+
+func (c *Checker) pushScope() {
+	c.scope = &checkScope{parent: c.scope, symbols: make(map[string]*symbol), inFunction: c.scope.inFunction}
+}
+
+// popScope reports unused locals and leaves the scope
+func (c *Checker) popScope() {
+	c.flushDeferred()
+	for name, sym := range c.scope.symbols {
+		if !sym.used && !sym.param && sym.function == nil && !strings.HasPrefix(name, "_") {
+			c.report(codeUnused, sym.position, "unused variable '%s'", name)
+		}
+		if c.warnings && sym.param && !sym.used && !strings.HasPrefix(name, "_") {
+			c.report(codeUnusedParam, sym.position, "unused parameter '%s'", name)
+		}
+	}
+	c.scope = c.scope.parent
+}
+
+func (c *Checker) flushDeferred() {
+	for len(c.scope.deferred) > 0 {
+		fn := c.scope.deferred[0]
+		c.scope.deferred = c.scope.deferred[1:]
+		c.checkFunctionBody(fn)
+	}
+}
+
+func (c *Checker) lookup(name string) *symbol {
+	for scope := c.scope; scope != nil; scope = scope.parent {
+		if sym, exists := scope.symbols[name]; exists {
+			return sym
+		}
+	}
+	return nil
+}
+
+func (c *Checker) declare(name string, position Position) *symbol {
+	sym := &symbol{name: name, position: position, used: c.scope.global}
+	c.scope.symbols[name] = sym
+	c.resolve(position, sym)
+	return sym
+}
+
+// declareLocal declares a parameter, loop variable or declared variable,
+// which hides a variable of the same name in an outer scope
+func (c *Checker) declareLocal(name string, position Position) *symbol {
+	if c.warnings && !c.scope.global && !strings.HasPrefix(name, "_") && c.scope.symbols[name] == nil {
+		if outer := c.lookup(name); outer != nil && !outer.native {
+			c.report(codeShadowed, position, "'%s' shadows a variable of an outer scope", name)
+		}
+	}
+	return c.declare(name, position)
+}
+
+// declareByAssignment declares the target of a plain assignment to a new
+// name. At the top level that is how scripts make their globals, inside a
+// function it is usually a misspelt or undeclared outer variable.
+func (c *Checker) declareByAssignment(ident *Identifier) {
+	if sym := c.lookup(ident.Value); sym != nil {
+		c.resolve(ident.Position, sym)
+		return
+	}
+	if c.scope.inFunction && !strings.HasPrefix(ident.Value, "_") {
+		c.report(codeImplicitGlobal, ident.Position, "assignment creates the undeclared '%s', declare it with '%s: var = ...'", ident.Value, ident.Value)
+	}
+	c.declare(ident.Value, ident.Position)
+}
+
+// resolve remembers that the identifier at position is sym, for resolveSymbols
+func (c *Checker) resolve(position Position, sym *symbol) {
+	if c.resolved != nil {
+		c.resolved[position.Index] = sym
+	}
+}
+
+func (c *Checker) use(ident *Identifier) {
+	if sym := c.lookup(ident.Value); sym != nil {
+		sym.used = true
+		c.resolve(ident.Position, sym)
+		return
+	}
+	var known []string
+	for scope := c.scope; scope != nil; scope = scope.parent {
+		for name := range scope.symbols {
+			known = append(known, name)
+		}
+	}
+	c.report(codeUndefined, ident.Position, "undefined variable '%s'%s", ident.Value, didYouMean(ident.Value, known))
+}
+
+func (c *Checker) checkBody(body []Statement) {
+	reported := false
+	for i, stmt := range body {
+		c.checkStatement(stmt)
+		if ret, ok := stmt.(*ReturnExpr); ok && !reported && hasCode(body[i+1:]) {
+			c.report(codeUnreachable, ret.Position, "unreachable code after return")
+			reported = true
+		}
+	}
+}
+
+func hasCode(body []Statement) bool {
+	for _, stmt := range body {
+		if _, ok := stmt.(*Comment); !ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Checker) checkStatement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *FunctionDeclaration:
+		c.checkFunction(s)
+	case *IfStatement:
+		c.checkCondition(s.Test)
+		c.checkBody(s.Consequent)
+		c.checkBody(s.Alternate)
+	case *WhileStatement:
+		c.checkCondition(s.Test)
+		c.checkBody(s.Consequent)
+	case *DoWhileStatement:
+		c.checkBody(s.Body)
+		c.checkCondition(s.Test)
+	case *ForStatement:
+		c.pushScope()
+		c.checkExpression(s.Declaration)
+		c.checkCondition(s.Test)
+		c.checkBody(s.Body)
+		c.checkExpression(s.Increaser)
+		c.popScope()
+	case *ForInStatement:
+		c.checkExpression(s.Iterable)
+		c.pushScope()
+		c.declareLocal(s.Variable.Value, s.Variable.Position)
+		c.checkBody(s.Body)
+		c.popScope()
+	case *ReturnExpr:
+		c.checkExpression(s.Value)
+	case *DebugStatement:
+		c.checkExpression(s.Label)
+		for _, prop := range s.Props {
+			c.checkExpression(prop)
+		}
+	case *UseStatement, *Comment:
+	default:
+		c.checkExpression(stmt)
+	}
+}
+
+// checkCondition checks the test of an if, while, for or ternary, where an
+// assignment is almost always a mistyped comparison: if x = 5 { }
+func (c *Checker) checkCondition(test Expression) {
+	c.checkExpression(test)
+
+	conditions := []Expression{test}
+	for len(conditions) > 0 {
+		condition := conditions[0]
+		conditions = conditions[1:]
+		switch e := condition.(type) {
+		case *AssignmentExpr:
+			position, _ := nodePosition(e)
+			c.report(codeConditionAssign, position, "assignment used as a condition, did you mean '=='?")
+		case *LogicalExpr:
+			conditions = append(conditions, e.Left, e.Right)
+		case *UnaryExpr:
+			if e.Operator == "!" {
+				conditions = append(conditions, e.Value)
+			}
+		}
+	}
+}
+
+func (c *Checker) checkFunction(fn *FunctionDeclaration) {
+	if fn.Name != "" {
+		sym := c.declare(fn.Name, fn.Position)
+		sym.function = fn
+	}
+	for _, param := range fn.Parameters {
+		if param.DefaultValue != nil {
+			c.checkExpression(param.DefaultValue)
+		}
+	}
+	c.scope.deferred = append(c.scope.deferred, fn)
+}
+
+func (c *Checker) checkFunctionBody(fn *FunctionDeclaration) {
+	c.pushScope()
+	c.scope.inFunction = true
+	for _, param := range fn.Parameters {
+		if param.Pattern != nil {
+			c.checkPattern(param.Pattern, func(ident *Identifier) {
+				c.declareLocal(ident.Value, ident.Position).param = true
+			})
+			continue
+		}
+		c.declareLocal(param.Name, param.Position).param = true
+	}
+	c.checkBody(fn.Body)
+	c.popScope()
+}
+
+func (c *Checker) checkExpression(expr Expression) {
+	switch e := expr.(type) {
+	case nil:
+	case *Identifier:
+		c.use(e)
+	case *StringLiteral:
+		for _, part := range e.Parts {
+			c.checkExpression(part)
+		}
+	case *ArrayLiteral:
+		for _, elem := range e.Elements {
+			c.checkExpression(elem)
+		}
+	case *ObjectLiteral:
+		for _, prop := range e.Properties {
+			c.checkExpression(prop.Value)
+		}
+	case *BinaryExpr:
+		c.checkExpression(e.Left)
+		c.checkExpression(e.Right)
+	case *EqualityExpr:
+		c.checkExpression(e.Left)
+		c.checkExpression(e.Right)
+	case *InequalityExpr:
+		c.checkExpression(e.Left)
+		c.checkExpression(e.Right)
+	case *LogicalExpr:
+		c.checkExpression(e.Left)
+		c.checkExpression(e.Right)
+	case *UnaryExpr:
+		c.checkExpression(e.Value)
+	case *TypeofExpr:
+		c.checkExpression(e.Value)
+	case *SpawnExpr:
+		c.checkExpression(e.Call)
+	case *AwaitExpr:
+		c.checkExpression(e.Value)
+	case *TernaryExpr:
+		c.checkCondition(e.Condition)
+		c.checkExpression(e.Consequent)
+		c.checkExpression(e.Alternate)
+	case *AssignmentExpr:
+		c.checkExpression(e.Value)
+		if isPattern(e.Assigne) {
+			c.checkPattern(e.Assigne, c.declareByAssignment)
+		} else if ident, ok := e.Assigne.(*Identifier); ok && e.Operator == "" {
+			c.declareByAssignment(ident)
+		} else {
+			c.checkExpression(e.Assigne)
+		}
+	case *ActionAssignmentExpr:
+		c.checkExpression(e.Value)
+		if ident, ok := e.Assigne.(*Identifier); ok {
+			c.declareLocal(ident.Value, ident.Position)
+		} else if isPattern(e.Assigne) {
+			c.checkPattern(e.Assigne, func(ident *Identifier) {
+				c.declareLocal(ident.Value, ident.Position)
+			})
+		}
+	case *MemberExpr:
+		c.checkExpression(e.Object)
+		if e.Computed {
+			c.checkExpression(e.Property)
+		}
+	case *SpreadElement:
+		c.checkExpression(e.Argument)
+	case *MatchExpr:
+		c.checkExpression(e.Subject)
+		for _, arm := range e.Arms {
+			for _, pattern := range arm.Patterns {
+				c.checkExpression(pattern)
+			}
+			c.checkBody(arm.Body)
+		}
+	case *RangeExpr:
+		c.checkExpression(e.Start)
+		c.checkExpression(e.End)
+	case *SliceExpr:
+		c.checkExpression(e.Object)
+		c.checkExpression(e.Start)
+		c.checkExpression(e.End)
+	case *CallExpr:
+		c.checkExpression(e.Caller)
+		for _, arg := range e.Args {
+			c.checkExpression(arg)
+		}
+		for _, arg := range e.Named {
+			c.checkExpression(arg.Value)
+		}
+		c.checkArgumentCount(e)
+	case *FunctionDeclaration:
+		c.checkFunction(e)
+	}
+}
+
+func isPattern(expr Expression) bool {
+	switch expr.(type) {
+	case *ArrayPattern, *ObjectPattern:
+		return true
+	}
+	return false
+}
+
+// checkPattern checks the defaults of a destructuring pattern and binds each of its names
+func (c *Checker) checkPattern(pattern Expression, bind func(ident *Identifier)) {
+	var elements []PatternElement
+	switch p := pattern.(type) {
+	case *Identifier:
+		bind(p)
+		return
+	case *ArrayPattern:
+		elements = p.Elements
+	case *ObjectPattern:
+		elements = p.Properties
+	}
+	for _, element := range elements {
+		c.checkExpression(element.DefaultValue)
+		c.checkPattern(element.Target, bind)
+	}
+}
+
+// checkArgumentCount compares a call against the declaration of the function it calls
+func (c *Checker) checkArgumentCount(call *CallExpr) {
+	ident, ok := call.Caller.(*Identifier)
+	if !ok {
+		return
+	}
+	sym := c.lookup(ident.Value)
+	if sym == nil || sym.function == nil {
+		return
+	}
+
+	// A spread argument's length is only known at runtime
+	for _, arg := range call.Args {
+		if _, ok := arg.(*SpreadElement); ok {
+			return
+		}
+	}
+
+	params := sym.function.Parameters
+	named := make(map[string]bool)
+	for _, arg := range call.Named {
+		named[arg.Name] = true
+	}
+
+	// Named arguments may fill the fields of a destructured options parameter
+	for name := range named {
+		if i := optionsParameter(params, name); i >= 0 {
+			if i < len(call.Args) {
+				c.report(codeArguments, ident.Position, "%s: argument '%s' given both in an object and by name", ident.Value, name)
+			}
+			delete(named, name)
+		}
+	}
+
+	var missing []string
+	variadic := false
+	for i, param := range params {
+		_, isOptions := param.Pattern.(*ObjectPattern)
+		switch {
+		case param.Rest:
+			variadic = true
+		case isOptions:
+			// Left out, every field takes its default
+		case named[param.Name]:
+			if i < len(call.Args) {
+				c.report(codeArguments, ident.Position, "%s: argument '%s' given both by position and by name", ident.Value, param.Name)
+			}
+			delete(named, param.Name)
+		case param.DefaultValue == nil && i >= len(call.Args):
+			missing = append(missing, param.Name)
+		}
+	}
+	for _, arg := range call.Named {
+		if named[arg.Name] {
+			c.report(codeArguments, arg.Position, "%s has no parameter named '%s'", ident.Value, arg.Name)
+		}
+	}
+
+	switch {
+	case !variadic && len(call.Args) > len(params):
+		c.report(codeArguments, ident.Position, "%s expects at most %d arguments, got %d", ident.Value, len(params), len(call.Args))
+	case len(missing) > 0:
+		c.report(codeArguments, ident.Position, "%s is missing arguments: %s", ident.Value, strings.Join(missing, ", "))
+	}
+}
+
+// runCheck parses each file without running it and prints the diagnostics
+func runCheck(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: luna check <file>...")
+		return
+	}
+
+	problems := 0
+	for _, filename := range args {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			fmt.Printf("Error: Could not read file '%s': %v\n", filename, err)
+			problems++
+			continue
+		}
+
+		tokens, err := NewTokenizer(string(data)).Tokenize()
+		if err != nil {
+			reportError(err, filename, filename+": ")
+			problems++
+			continue
+		}
+
+		ast, err := NewParser(tokens, string(data)).ProduceAST()
+		if err != nil {
+			reportError(err, filename, filename+": ")
+			problems++
+			continue
+		}
+
+		for _, diagnostic := range NewChecker().Check(ast.(*Program)) {
+			problems++
+			if jsonErrors() {
+				diagnostic.File = filename
+				printDiagnostic(diagnostic)
+				continue
+			}
+			fmt.Printf("%s:%s\n", filename, yellow(diagnostic.String()))
+		}
+	}
+
+	if problems > 0 {
+		os.Exit(1)
+	}
+}
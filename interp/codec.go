@@ -0,0 +1,593 @@
+package interp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// sortedKeys returns the keys of an object in a deterministic order
+func sortedKeys(properties map[string]RuntimeValue) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isInteger reports whether a number can be encoded as a 64-bit integer
+func isInteger(value float64) bool {
+	return value == math.Trunc(value) && value >= math.MinInt64 && value < math.MaxInt64
+}
+
+// MESSAGEPACK ---
+
+// MsgpackEncode encodes a Luna value as MessagePack
+func MsgpackEncode(value RuntimeValue) ([]byte, error) {
+	return appendMsgpack(nil, value, nil)
+}
+
+// appendMsgpack encodes value, path holds the arrays and objects around it
+func appendMsgpack(buf []byte, value RuntimeValue, path []RuntimeValue) ([]byte, error) {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return nil, fmt.Errorf("cannot encode a circular structure as msgpack")
+		}
+	}
+
+	switch v := value.(type) {
+	case *NullValue, *UndefinedValue, *VoidValue:
+		return append(buf, 0xc0), nil
+	case *BooleanValue:
+		if v.Value {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case *NumberValue:
+		if !isInteger(v.Value) {
+			buf = append(buf, 0xcb)
+			return binary.BigEndian.AppendUint64(buf, math.Float64bits(v.Value)), nil
+		}
+		return appendMsgpackInt(buf, int64(v.Value)), nil
+	case *IntValue:
+		return appendMsgpackInt(buf, v.Value), nil
+	case *StringValue:
+		n := len(v.Value)
+		switch {
+		case n < 32:
+			buf = append(buf, 0xa0|byte(n))
+		case n <= math.MaxUint8:
+			buf = append(buf, 0xd9, byte(n))
+		case n <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xda), uint16(n))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xdb), uint32(n))
+		}
+		return append(buf, v.Value...), nil
+	case *ArrayValue:
+		n := len(v.Elements)
+		switch {
+		case n < 16:
+			buf = append(buf, 0x90|byte(n))
+		case n <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xdc), uint16(n))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xdd), uint32(n))
+		}
+		for _, elem := range v.Elements {
+			var err error
+			if buf, err = appendMsgpack(buf, elem, path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case *ObjectValue:
+		n := len(v.Properties)
+		switch {
+		case n < 16:
+			buf = append(buf, 0x80|byte(n))
+		case n <= math.MaxUint16:
+			buf = binary.BigEndian.AppendUint16(append(buf, 0xde), uint16(n))
+		default:
+			buf = binary.BigEndian.AppendUint32(append(buf, 0xdf), uint32(n))
+		}
+		for _, key := range sortedKeys(v.Properties) {
+			var err error
+			if buf, err = appendMsgpack(buf, MakeString(key), path); err != nil {
+				return nil, err
+			}
+			if buf, err = appendMsgpack(buf, v.Properties[key], path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("cannot encode value of type %s as msgpack", value.Type())
+	}
+}
+
+// appendMsgpackInt encodes n in the smallest integer format that holds it
+func appendMsgpackInt(buf []byte, n int64) []byte {
+	switch {
+	case n >= 0 && n < 128:
+		return append(buf, byte(n))
+	case n < 0 && n >= -32:
+		return append(buf, byte(n))
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		return append(buf, 0xd0, byte(n))
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		return binary.BigEndian.AppendUint16(append(buf, 0xd1), uint16(n))
+	case n >= math.MinInt32 && n <= math.MaxInt32:
+		return binary.BigEndian.AppendUint32(append(buf, 0xd2), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, 0xd3), uint64(n))
+	}
+}
+
+// unsignedNumber is an int when n fits 64 signed bits, a float otherwise
+func unsignedNumber(n uint64) RuntimeValue {
+	if n > math.MaxInt64 {
+		return MakeNumber(float64(n))
+	}
+	return MakeInt(int64(n))
+}
+
+// MsgpackDecode decodes a single MessagePack value
+func MsgpackDecode(data []byte) (RuntimeValue, error) {
+	reader := &byteReader{data: data}
+	value, err := reader.msgpack()
+	if err != nil {
+		return nil, fmt.Errorf("msgpack: %v", err)
+	}
+	if reader.position != len(data) {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes", len(data)-reader.position)
+	}
+	return value, nil
+}
+
+func (r *byteReader) msgpack() (RuntimeValue, error) {
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case b <= 0x7f:
+		return MakeInt(int64(b)), nil
+	case b >= 0xe0:
+		return MakeInt(int64(int8(b))), nil
+	case b&0xe0 == 0xa0:
+		return r.msgpackString(int(b & 0x1f))
+	case b&0xf0 == 0x90:
+		return r.msgpackArray(int(b & 0x0f))
+	case b&0xf0 == 0x80:
+		return r.msgpackMap(int(b & 0x0f))
+	}
+
+	switch b {
+	case 0xc0:
+		return MakeNull(), nil
+	case 0xc2:
+		return MakeBool(false), nil
+	case 0xc3:
+		return MakeBool(true), nil
+	case 0xc4, 0xd9:
+		n, err := r.uint(1)
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackString(int(n))
+	case 0xc5, 0xda:
+		n, err := r.uint(2)
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackString(int(n))
+	case 0xc6, 0xdb:
+		n, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackString(int(n))
+	case 0xca:
+		bits, err := r.uint(4)
+		if err != nil {
+			return nil, err
+		}
+		return MakeNumber(float64(math.Float32frombits(uint32(bits)))), nil
+	case 0xcb:
+		bits, err := r.uint(8)
+		if err != nil {
+			return nil, err
+		}
+		return MakeNumber(math.Float64frombits(bits)), nil
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		n, err := r.uint(1 << (b - 0xcc))
+		if err != nil {
+			return nil, err
+		}
+		return unsignedNumber(n), nil
+	case 0xd0:
+		n, err := r.uint(1)
+		return MakeInt(int64(int8(n))), err
+	case 0xd1:
+		n, err := r.uint(2)
+		return MakeInt(int64(int16(n))), err
+	case 0xd2:
+		n, err := r.uint(4)
+		return MakeInt(int64(int32(n))), err
+	case 0xd3:
+		n, err := r.uint(8)
+		return MakeInt(int64(n)), err
+	case 0xdc, 0xdd:
+		n, err := r.uint(2 << (b - 0xdc))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackArray(int(n))
+	case 0xde, 0xdf:
+		n, err := r.uint(2 << (b - 0xde))
+		if err != nil {
+			return nil, err
+		}
+		return r.msgpackMap(int(n))
+	default:
+		return nil, fmt.Errorf("unsupported type byte 0x%02x", b)
+	}
+}
+
+func (r *byteReader) msgpackString(n int) (RuntimeValue, error) {
+	data, err := r.bytes(n)
+	if err != nil {
+		return nil, err
+	}
+	return MakeString(string(data)), nil
+}
+
+func (r *byteReader) msgpackArray(n int) (RuntimeValue, error) {
+	if n > r.remaining() {
+		return nil, errUnexpectedEnd
+	}
+	elements := make([]RuntimeValue, 0, n)
+	for i := 0; i < n; i++ {
+		elem, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		elements = append(elements, elem)
+	}
+	return MakeArray(elements), nil
+}
+
+func (r *byteReader) msgpackMap(n int) (RuntimeValue, error) {
+	if n > r.remaining() {
+		return nil, errUnexpectedEnd
+	}
+	object := newObject()
+	for i := 0; i < n; i++ {
+		key, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		value, err := r.msgpack()
+		if err != nil {
+			return nil, err
+		}
+		object.Set(objectKey(key), value)
+	}
+	return object, nil
+}
+
+// objectKey turns a decoded map key into a Luna property name
+func objectKey(key RuntimeValue) string {
+	if str, ok := key.(*StringValue); ok {
+		return str.Value
+	}
+	return key.String()
+}
+
+// CBOR ---
+
+const (
+	cborUnsigned byte = iota
+	cborNegative
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// CborEncode encodes a Luna value as CBOR (RFC 8949)
+func CborEncode(value RuntimeValue) ([]byte, error) {
+	return appendCbor(nil, value, nil)
+}
+
+// appendCborInt encodes n as an unsigned or a negative integer
+func appendCborInt(buf []byte, n int64) []byte {
+	if n < 0 {
+		return appendCborHead(buf, cborNegative, uint64(-1-n))
+	}
+	return appendCborHead(buf, cborUnsigned, uint64(n))
+}
+
+func appendCborHead(buf []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(buf, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(buf, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(buf, major|25), uint16(n))
+	case n <= math.MaxUint32:
+		return binary.BigEndian.AppendUint32(append(buf, major|26), uint32(n))
+	default:
+		return binary.BigEndian.AppendUint64(append(buf, major|27), n)
+	}
+}
+
+// appendCbor encodes value, path holds the arrays and objects around it
+func appendCbor(buf []byte, value RuntimeValue, path []RuntimeValue) ([]byte, error) {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return nil, fmt.Errorf("cannot encode a circular structure as cbor")
+		}
+	}
+
+	switch v := value.(type) {
+	case *NullValue:
+		return append(buf, 0xf6), nil
+	case *UndefinedValue, *VoidValue:
+		return append(buf, 0xf7), nil
+	case *BooleanValue:
+		if v.Value {
+			return append(buf, 0xf5), nil
+		}
+		return append(buf, 0xf4), nil
+	case *NumberValue:
+		if !isInteger(v.Value) {
+			return binary.BigEndian.AppendUint64(append(buf, 0xfb), math.Float64bits(v.Value)), nil
+		}
+		return appendCborInt(buf, int64(v.Value)), nil
+	case *IntValue:
+		return appendCborInt(buf, v.Value), nil
+	case *StringValue:
+		buf = appendCborHead(buf, cborText, uint64(len(v.Value)))
+		return append(buf, v.Value...), nil
+	case *ArrayValue:
+		buf = appendCborHead(buf, cborArray, uint64(len(v.Elements)))
+		for _, elem := range v.Elements {
+			var err error
+			if buf, err = appendCbor(buf, elem, path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case *ObjectValue:
+		buf = appendCborHead(buf, cborMap, uint64(len(v.Properties)))
+		for _, key := range sortedKeys(v.Properties) {
+			var err error
+			buf = appendCborHead(buf, cborText, uint64(len(key)))
+			buf = append(buf, key...)
+			if buf, err = appendCbor(buf, v.Properties[key], path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("cannot encode value of type %s as cbor", value.Type())
+	}
+}
+
+// CborDecode decodes a single CBOR data item
+func CborDecode(data []byte) (RuntimeValue, error) {
+	reader := &byteReader{data: data}
+	value, err := reader.cbor()
+	if err != nil {
+		return nil, fmt.Errorf("cbor: %v", err)
+	}
+	if reader.position != len(data) {
+		return nil, fmt.Errorf("cbor: %d trailing bytes", len(data)-reader.position)
+	}
+	return value, nil
+}
+
+func (r *byteReader) cbor() (RuntimeValue, error) {
+	if err := r.enter(); err != nil {
+		return nil, err
+	}
+	defer r.leave()
+
+	b, err := r.byte()
+	if err != nil {
+		return nil, err
+	}
+	major, info := b>>5, b&0x1f
+
+	if major == cborSimple {
+		switch info {
+		case 20:
+			return MakeBool(false), nil
+		case 21:
+			return MakeBool(true), nil
+		case 22:
+			return MakeNull(), nil
+		case 23:
+			return MakeUndefined(), nil
+		case 25:
+			bits, err := r.uint(2)
+			return MakeNumber(float16ToFloat64(uint16(bits))), err
+		case 26:
+			bits, err := r.uint(4)
+			return MakeNumber(float64(math.Float32frombits(uint32(bits)))), err
+		case 27:
+			bits, err := r.uint(8)
+			return MakeNumber(math.Float64frombits(bits)), err
+		default:
+			return nil, fmt.Errorf("unsupported simple value %d", info)
+		}
+	}
+
+	var n uint64
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info <= 27:
+		if n, err = r.uint(1 << (info - 24)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("indefinite-length items are not supported")
+	}
+
+	switch major {
+	case cborUnsigned:
+		return unsignedNumber(n), nil
+	case cborNegative:
+		if n > math.MaxInt64 {
+			return MakeNumber(-1 - float64(n)), nil
+		}
+		return MakeInt(-1 - int64(n)), nil
+	case cborBytes, cborText:
+		if n > uint64(r.remaining()) {
+			return nil, errUnexpectedEnd
+		}
+		data, err := r.bytes(int(n))
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(string(data)), nil
+	case cborArray:
+		if n > uint64(r.remaining()) {
+			return nil, errUnexpectedEnd
+		}
+		elements := make([]RuntimeValue, 0, n)
+		for i := uint64(0); i < n; i++ {
+			elem, err := r.cbor()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, elem)
+		}
+		return MakeArray(elements), nil
+	case cborMap:
+		if n > uint64(r.remaining()) {
+			return nil, errUnexpectedEnd
+		}
+		object := newObject()
+		for i := uint64(0); i < n; i++ {
+			key, err := r.cbor()
+			if err != nil {
+				return nil, err
+			}
+			value, err := r.cbor()
+			if err != nil {
+				return nil, err
+			}
+			object.Set(objectKey(key), value)
+		}
+		return object, nil
+	default:
+		// Tags carry no meaning for Luna, decode the tagged item itself
+		return r.cbor()
+	}
+}
+
+func float16ToFloat64(bits uint16) float64 {
+	exponent := int(bits>>10) & 0x1f
+	mantissa := float64(bits & 0x3ff)
+
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 31:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+
+	if bits&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
+
+// byteReader is a bounds-checked cursor over encoded input
+type byteReader struct {
+	data     []byte
+	position int
+	depth    int // items being decoded around the current one
+}
+
+// maxDecodeDepth is how deeply arrays, maps and tags may nest in decoded
+// input, deeper input is rejected before it exhausts the stack
+const maxDecodeDepth = 1000
+
+var errUnexpectedEnd = fmt.Errorf("unexpected end of input")
+
+// enter starts decoding a nested item, failing past maxDecodeDepth
+func (r *byteReader) enter() error {
+	r.depth++
+	if r.depth > maxDecodeDepth {
+		return fmt.Errorf("input nested more than %d levels deep", maxDecodeDepth)
+	}
+	return nil
+}
+
+// leave ends the item enter started
+func (r *byteReader) leave() {
+	r.depth--
+}
+
+func (r *byteReader) remaining() int {
+	return len(r.data) - r.position
+}
+
+func (r *byteReader) byte() (byte, error) {
+	if r.remaining() < 1 {
+		return 0, errUnexpectedEnd
+	}
+	b := r.data[r.position]
+	r.position++
+	return b, nil
+}
+
+func (r *byteReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.remaining() < n {
+		return nil, errUnexpectedEnd
+	}
+	data := r.data[r.position : r.position+n]
+	r.position += n
+	return data, nil
+}
+
+// uint reads a big-endian unsigned integer of the given size
+func (r *byteReader) uint(size int) (uint64, error) {
+	data, err := r.bytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for _, b := range data {
+		n = n<<8 | uint64(b)
+	}
+	return n, nil
+}
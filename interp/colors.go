@@ -0,0 +1,385 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ANSI color codes
+const (
+	Reset  = "\033[0m"
+	Bold   = "\033[1m"
+	Dim    = "\033[2m"
+	Italic = "\033[3m"
+	Under  = "\033[4m"
+
+	// Foreground colors
+	Black   = "\033[30m"
+	Red     = "\033[31m"
+	Green   = "\033[32m"
+	Yellow  = "\033[33m"
+	Blue    = "\033[34m"
+	Magenta = "\033[35m"
+	Cyan    = "\033[36m"
+	White   = "\033[37m"
+	Gray    = "\033[90m"
+
+	// Background colors
+	BgBlack   = "\033[40m"
+	BgRed     = "\033[41m"
+	BgGreen   = "\033[42m"
+	BgYellow  = "\033[43m"
+	BgBlue    = "\033[44m"
+	BgMagenta = "\033[45m"
+	BgCyan    = "\033[46m"
+	BgWhite   = "\033[47m"
+)
+
+// Color functions
+func colorize(text, color string) string {
+	return color + text + Reset
+}
+
+func red(text string) string     { return colorize(text, Red) }
+func green(text string) string   { return colorize(text, Green) }
+func yellow(text string) string  { return colorize(text, Yellow) }
+func blue(text string) string    { return colorize(text, Blue) }
+func magenta(text string) string { return colorize(text, Magenta) }
+func cyan(text string) string    { return colorize(text, Cyan) }
+func white(text string) string   { return colorize(text, White) }
+func gray(text string) string    { return colorize(text, Gray) }
+func bold(text string) string    { return colorize(text, Bold) }
+func dim(text string) string     { return colorize(text, Dim) }
+func italic(text string) string  { return colorize(text, Italic) }
+func under(text string) string   { return colorize(text, Under) }
+
+// maxPrintDepth is how many arrays deep a value is printed, deeper ones collapse
+const maxPrintDepth = 8
+
+// Colorize runtime values for output
+func colorizeValue(result RuntimeValue, isInner bool, noString bool) string {
+	return colorizeNested(result, isInner, noString, maxPrintDepth, nil)
+}
+
+// colorizeNested colorizes result depth levels deep, path holds the values
+// being printed around it, which print as <circular> when they recur
+func colorizeNested(result RuntimeValue, isInner bool, noString bool, depth int, path []RuntimeValue) string {
+	if result == nil {
+		return gray("null")
+	}
+
+	switch result.(type) {
+	case *ArrayValue, *ObjectValue, *MapValue, *SetValue:
+		var entered bool
+		if path, entered = enterPath(path, result); !entered {
+			return gray("<circular>")
+		}
+	}
+
+	switch result.Type() {
+	case STRING_TYPE:
+		str := result.(*StringValue).Value
+		if noString {
+			return str
+		}
+		return green("'" + strings.ReplaceAll(str, "'", dim("'")) + "'")
+
+	case ARRAY_TYPE:
+		array := result.(*ArrayValue)
+		if depth <= 0 && len(array.Elements) > 0 {
+			return cyan(fmt.Sprintf("Array(%d) ", len(array.Elements))) + gray("[…]")
+		}
+
+		if len(array.Elements) <= summaryItems {
+			var elements []string
+			for _, elem := range array.Elements {
+				elements = append(elements, colorizeNested(elem, true, false, depth-1, path))
+			}
+			return cyan("[") + strings.Join(elements, ", ") + cyan("]")
+		} else {
+			// Large arrays are summarized, `:expand` shows all of them
+			var elements []string
+			for i := 0; i < summaryItems; i++ {
+				elements = append(elements, colorizeNested(array.Elements[i], true, false, depth-1, path))
+			}
+			return cyan(fmt.Sprintf("Array(%d) [", len(array.Elements))) +
+				strings.Join(elements, ", ") + gray(", …") + cyan("]")
+		}
+
+	case INT_TYPE:
+		return yellow(result.String())
+
+	case BIGINT_TYPE:
+		return yellow(result.String() + "n")
+
+	case NUMBER_TYPE:
+		num := result.(*NumberValue).Value
+		if num != num { // NaN check
+			return cyan("NaN")
+		}
+		return yellow(result.String())
+
+	case UNDEF_TYPE:
+		return gray("undef")
+
+	case VOID_TYPE:
+		return ""
+
+	case FUNCTION_TYPE:
+		fn := result.(*FunctionValue)
+		var name string
+
+		if fn.IsAnonymous() {
+			var paramStrs []string
+			for _, param := range fn.Parameters {
+				if param.DefaultValue != nil {
+					paramStrs = append(paramStrs, param.Label()+"=(...)")
+				} else {
+					paramStrs = append(paramStrs, param.Label())
+				}
+			}
+			name = magenta("lambda") + " " + strings.Join(paramStrs, " ")
+		} else {
+			exportPrefix := ""
+			if fn.Export {
+				exportPrefix = green("out") + " "
+			}
+
+			var paramStrs []string
+			for _, param := range fn.Parameters {
+				if param.DefaultValue != nil {
+					paramStrs = append(paramStrs, green(param.Label())+yellow("=(...)"))
+				} else {
+					paramStrs = append(paramStrs, green(param.Label()))
+				}
+			}
+
+			name = exportPrefix + magenta("fn") + " " + bold(blue(fn.Name)) + " " +
+				strings.Join(paramStrs, " ")
+
+			if len(fn.Parameters) > 0 {
+				name += " "
+			}
+		}
+
+		if fn.Async {
+			name = magenta("async") + " " + name
+		}
+
+		bodyIndicator := ""
+		if len(fn.Body) > 0 {
+			bodyIndicator = " ... "
+		}
+
+		return name + gray(fmt.Sprintf("{%s}", bodyIndicator))
+
+	case NATIVE_FN_TYPE:
+		fn := result.(*NativeFunctionValue)
+		if isInner {
+			return magenta("fn") + " " + cyan(fn.Name)
+		}
+		return magenta("fn") + " " + cyan(fn.Name) + " {\n" +
+			"  " + italic("(NAT-C)...") + "\n" +
+			"}"
+
+	case BOOLEAN_TYPE:
+		return magenta(result.String())
+
+	case NULL_TYPE:
+		return magenta("null")
+
+	case OBJECT_TYPE:
+		obj := result.(*ObjectValue)
+		if isInner {
+			return gray("{ ... }")
+		}
+
+		if len(obj.Properties) > summaryItems {
+			return inspectValue(obj, 1, summaryItems, 0)
+		}
+
+		var props []string
+		for _, key := range obj.Keys() {
+			props = append(props, fmt.Sprintf("  %s: %s", blue(key), colorizeNested(obj.Properties[key], true, false, depth-1, path)))
+		}
+
+		if len(props) == 0 {
+			return gray("{}")
+		}
+
+		return gray("{") + "\n" + strings.Join(props, ",\n") + "\n" + gray("}")
+
+	case MAP_TYPE:
+		m := result.(*MapValue)
+		if isInner {
+			return gray(fmt.Sprintf("map(%d) { ... }", m.Len()))
+		}
+		var entries []string
+		for _, entry := range m.entries {
+			entries = append(entries, "  "+colorizeNested(entry.key, true, false, depth-1, path)+gray(" => ")+colorizeNested(entry.value, true, false, depth-1, path))
+		}
+		if len(entries) == 0 {
+			return gray("map {}")
+		}
+		return gray("map {") + "\n" + strings.Join(entries, ",\n") + "\n" + gray("}")
+
+	case SET_TYPE:
+		s := result.(*SetValue)
+		if isInner && s.Len() > summaryItems {
+			return gray(fmt.Sprintf("set(%d) { ... }", s.Len()))
+		}
+		var elements []string
+		for i, element := range s.Elements() {
+			if i == summaryItems {
+				elements = append(elements, gray("…"))
+				break
+			}
+			elements = append(elements, colorizeNested(element, true, false, depth-1, path))
+		}
+		return gray("set {") + strings.Join(elements, ", ") + gray("}")
+
+	default:
+		return yellow(result.String())
+	}
+}
+
+// Format error messages with colors
+func formatError(errType, message string) string {
+	return fmt.Sprintf("%s: %s", red(under(bold(errType))), gray(message))
+}
+
+// formatDebug is the output of a debug statement on line, its label and values
+func formatDebug(line int, label string, props []string) string {
+	debugStyle := BgYellow + Red
+	header := colorize(" DEBUG ", debugStyle) + " " + gray(fmt.Sprintf("line %d", line))
+	if label != "" {
+		header += " " + bold(label)
+	}
+	return header + ": " + strings.Join(props, ", ")
+}
+
+// colorNames are the colors of the palette by name, as foreground codes
+var colorNames = map[string]int{
+	"black": 30, "red": 31, "green": 32, "yellow": 33, "blue": 34,
+	"magenta": 35, "cyan": 36, "white": 37, "gray": 90, "grey": 90,
+}
+
+// colorCode turns a color name, a 256-color index or a "#rrggbb" hex color
+// into the parameters of an escape, the background variant when bg is set
+func colorCode(value RuntimeValue, bg bool) (string, error) {
+	base := 38
+	if bg {
+		base = 48
+	}
+	switch v := value.(type) {
+	case *StringValue:
+		if code, ok := colorNames[strings.ToLower(v.Value)]; ok {
+			if bg {
+				code += 10
+			}
+			return strconv.Itoa(code), nil
+		}
+		if hex, ok := strings.CutPrefix(v.Value, "#"); ok && len(hex) == 6 {
+			if rgb, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return fmt.Sprintf("%d;2;%d;%d;%d", base, rgb>>16, rgb>>8&0xff, rgb&0xff), nil
+			}
+		}
+		return "", fmt.Errorf("unknown color '%s', expected a name, a number from 0 to 255 or #rrggbb", v.Value)
+	case *NumberValue, *IntValue:
+		n, ok := toInt(v)
+		if !ok || n < 0 || n > 255 {
+			return "", fmt.Errorf("color number must be an integer from 0 to 255, got %s", v.String())
+		}
+		return fmt.Sprintf("%d;5;%d", base, n), nil
+	}
+	return "", fmt.Errorf("color must be a string or a number, got %s", value.Type())
+}
+
+// colorsEnabled reports whether scripts should color their output, NO_COLOR
+// and the no-color pragma turn it off
+func colorsEnabled(env *Environment) bool {
+	return os.Getenv("NO_COLOR") == "" && !env.Pragmas().NoColor
+}
+
+// textArg reads the text argument of a colors function, other values are converted
+func textArg(args []RuntimeValue) string {
+	if str, ok := args[0].(*StringValue); ok {
+		return str.Value
+	}
+	return args[0].String()
+}
+
+func createColorsObject() RuntimeValue {
+	colorsProps := make(map[string]RuntimeValue)
+
+	// colors.red(text) and the others of the palette, plus the text styles
+	styles := map[string]string{"bold": Bold, "dim": Dim, "italic": Italic, "underline": Under}
+	for name, code := range colorNames {
+		styles[name] = fmt.Sprintf("\033[%dm", code)
+	}
+	for name, escape := range styles {
+		colorsProps[name] = MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("colors.%s expects 1 argument, got %d", name, len(args))
+			}
+			if !colorsEnabled(env) {
+				return MakeString(textArg(args)), nil
+			}
+			return MakeString(colorize(textArg(args), escape)), nil
+		})
+	}
+
+	// colors.style(text, { color, bg, bold, dim, italic, underline }), colors are
+	// names, 256-color numbers or "#rrggbb"
+	colorsProps["style"] = MakeNativeFunction("style", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("colors.style expects 2 arguments, got %d", len(args))
+		}
+		options, ok := args[1].(*ObjectValue)
+		if !ok {
+			return nil, fmt.Errorf("colors.style: options must be an object")
+		}
+
+		var codes []string
+		for _, key := range sortedKeys(options.Properties) {
+			value := options.Properties[key]
+			switch key {
+			case "color", "bg":
+				code, err := colorCode(value, key == "bg")
+				if err != nil {
+					return nil, fmt.Errorf("colors.style: %s: %v", key, err)
+				}
+				codes = append(codes, code)
+			case "bold", "dim", "italic", "underline":
+				if value.IsTruthy() {
+					codes = append(codes, map[string]string{"bold": "1", "dim": "2", "italic": "3", "underline": "4"}[key])
+				}
+			default:
+				return nil, fmt.Errorf("colors.style: unknown option '%s'", key)
+			}
+		}
+
+		text := textArg(args)
+		if len(codes) == 0 || !colorsEnabled(env) {
+			return MakeString(text), nil
+		}
+		return MakeString(colorize(text, "\033["+strings.Join(codes, ";")+"m")), nil
+	})
+
+	// colors.enabled() is false under NO_COLOR or the no-color pragma
+	colorsProps["enabled"] = MakeNativeFunction("enabled", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		return MakeBool(colorsEnabled(env)), nil
+	})
+
+	// colors.strip(text) removes the colors from text
+	colorsProps["strip"] = MakeNativeFunction("strip", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("colors.strip expects 1 argument, got %d", len(args))
+		}
+		return MakeString(stripColor(textArg(args))), nil
+	})
+
+	return MakeObject(colorsProps)
+}
@@ -0,0 +1,504 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Luna code runs under one interpreter lock per environment, like a GIL.
+// Tasks take turns between statements and give the lock up while they block
+// on a channel, so environments and values never see two goroutines at once.
+// Separate environments, like two interpreters embedded in one program, each
+// have their own lock and run side by side.
+
+// scheduler is the interpreter lock of an environment and the state that
+// goes with it, shared by every scope like the limits
+type scheduler struct {
+	lock       sync.Mutex
+	concurrent bool // set once the first task is spawned, never reset
+
+	// group is the group of the task holding the lock, nil outside of
+	// groups. Each task restores it when it gets the lock back.
+	group *TaskGroup
+
+	// timers counts the timeouts and intervals that keep a script running
+	timers sync.WaitGroup
+}
+
+// errCancelled stops the tasks of a cancelled group
+var errCancelled = errors.New("task cancelled")
+
+// startConcurrency takes the interpreter lock for the goroutine running the
+// script, it must be called from that goroutine before the first task starts
+func startConcurrency(env *Environment) {
+	s := env.tasks
+	if !s.concurrent {
+		s.lock.Lock()
+		s.concurrent = true
+	}
+}
+
+// yieldInterpreter lets the other tasks run a statement
+func yieldInterpreter(env *Environment) {
+	s := env.tasks
+	if s.concurrent {
+		group := s.group
+		s.lock.Unlock()
+		runtime.Gosched()
+		s.lock.Lock()
+		s.group = group
+	}
+}
+
+// blockingCall releases the interpreter lock while fn waits on something
+// outside the interpreter. fn must not touch environments or values.
+func blockingCall(env *Environment, fn func()) {
+	s := env.tasks
+	if !s.concurrent {
+		fn()
+		return
+	}
+	group := s.group
+	s.lock.Unlock()
+	defer func() {
+		s.lock.Lock()
+		s.group = group
+	}()
+	fn()
+}
+
+// runTask runs fn on its own goroutine under the interpreter lock of env,
+// as a member of group
+func runTask(env *Environment, group *TaskGroup, fn func()) {
+	startConcurrency(env)
+	s := env.tasks
+	go func() {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.group = group
+		fn()
+	}()
+}
+
+// checkCancelled stops the running task once its group is cancelled
+func checkCancelled(env *Environment) error {
+	if group := env.tasks.group; group != nil && group.Cancelled() {
+		return errCancelled
+	}
+	return nil
+}
+
+// cancelSignal is closed when the running task is cancelled, it is nil
+// (never ready) outside of groups
+func cancelSignal(env *Environment) <-chan struct{} {
+	if env.tasks.group == nil {
+		return nil
+	}
+	return env.tasks.group.done
+}
+
+// TaskGroup is a scope of tasks, leaving it waits for all of them. The first
+// error cancels the group, along with its nested groups, and is reported.
+type TaskGroup struct {
+	parent   *TaskGroup
+	children []*TaskGroup
+	done     chan struct{} // closed on cancellation
+	err      error
+	tasks    sync.WaitGroup
+}
+
+func newTaskGroup(parent *TaskGroup) *TaskGroup {
+	group := &TaskGroup{parent: parent, done: make(chan struct{})}
+	if parent != nil {
+		parent.children = append(parent.children, group)
+		if parent.Cancelled() {
+			group.Cancel(nil)
+		}
+	}
+	return group
+}
+
+func (g *TaskGroup) Cancelled() bool {
+	select {
+	case <-g.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Cancel stops the group's tasks at their next statement or channel
+// operation, err is the reason reported by the group (nil for none)
+func (g *TaskGroup) Cancel(err error) {
+	if g.Cancelled() {
+		return
+	}
+	g.err = err
+	close(g.done)
+	for _, child := range g.children {
+		child.Cancel(nil)
+	}
+}
+
+// detach forgets a finished group, so long-lived parents do not collect them
+func (g *TaskGroup) detach() {
+	if g.parent == nil {
+		return
+	}
+	siblings := g.parent.children
+	for i, child := range siblings {
+		if child == g {
+			g.parent.children = append(siblings[:i], siblings[i+1:]...)
+			return
+		}
+	}
+}
+
+// Spawn starts fn(args) as a task of the group
+func (g *TaskGroup) Spawn(fn RuntimeValue, args []RuntimeValue, env *Environment) {
+	g.tasks.Add(1)
+	runTask(env, g, func() {
+		defer g.tasks.Done()
+		if _, err := callValue(fn, args, env); err != nil && err != errCancelled {
+			g.Cancel(err)
+		}
+	})
+}
+
+// Wait blocks until every task of the group has finished, letting them run
+func (g *TaskGroup) Wait(env *Environment) {
+	blockingCall(env, g.tasks.Wait)
+}
+
+// groupNative runs body(g) where g.spawn(fn, ...args) starts tasks scoped to
+// the call: group returns once they have all finished, failing with the first
+// error of the body or a task. g.cancel() stops the remaining tasks.
+func groupNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 || (args[0].Type() != FUNCTION_TYPE && args[0].Type() != NATIVE_FN_TYPE) {
+		return nil, fmt.Errorf("group expects a function")
+	}
+
+	parent := env.tasks.group
+	group := newTaskGroup(parent)
+
+	handle := MakeObject(map[string]RuntimeValue{
+		"spawn": MakeNativeFunction("spawn", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if len(args) == 0 || (args[0].Type() != FUNCTION_TYPE && args[0].Type() != NATIVE_FN_TYPE) {
+				return nil, fmt.Errorf("group.spawn expects a function and its arguments")
+			}
+			if group.Cancelled() {
+				return nil, errCancelled
+			}
+			group.Spawn(args[0], args[1:], env)
+			return MakeVoid(), nil
+		}),
+		"cancel": MakeNativeFunction("cancel", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			group.Cancel(nil)
+			return MakeVoid(), nil
+		}),
+		"cancelled": MakeNativeFunction("cancelled", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return MakeBool(group.Cancelled()), nil
+		}),
+	})
+
+	// The body belongs to the group too, a failing task interrupts it
+	env.tasks.group = group
+	result, err := callValue(args[0], []RuntimeValue{handle}, env)
+	if err != nil && err != errCancelled {
+		group.Cancel(err)
+	}
+	group.Wait(env)
+	env.tasks.group = parent
+
+	switch {
+	case group.err != nil:
+		return nil, group.err
+	case parent != nil && parent.Cancelled():
+		return nil, errCancelled
+	case err == errCancelled:
+		return MakeVoid(), nil // cancelled with g.cancel()
+	}
+	return result, nil
+}
+
+func evaluateSpawnExpression(node *SpawnExpr, env *Environment) (RuntimeValue, error) {
+	// The callee and arguments are evaluated now, only the call runs later
+	fn, args, named, err := evaluateCallParts(node.Call, env)
+	if err != nil {
+		return nil, err
+	}
+
+	runTask(env, env.tasks.group, func() {
+		if _, err := invokeCall(fn, args, named, env); err != nil && err != errCancelled {
+			fmt.Println(formatError(errorLabel(err), "in spawned task: "+err.Error()))
+		}
+	})
+	return MakeVoid(), nil
+}
+
+// Channel Value, a Go channel carrying Luna values between tasks
+type ChannelValue struct {
+	ch     chan RuntimeValue
+	mu     sync.Mutex
+	closed bool
+}
+
+func (c *ChannelValue) Type() ValueType { return CHANNEL_TYPE }
+func (c *ChannelValue) String() string  { return fmt.Sprintf("chan(%d)", cap(c.ch)) }
+func (c *ChannelValue) IsTruthy() bool  { return true }
+func (c *ChannelValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, fn := range ChannelPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return fn(c, args, env)
+		}))
+	}
+
+	return &prototypes
+}
+
+func MakeChannel(capacity int) RuntimeValue {
+	return &ChannelValue{ch: make(chan RuntimeValue, capacity)}
+}
+
+// Send blocks until the value is received or buffered, letting the other
+// tasks of env run
+func (c *ChannelValue) Send(value RuntimeValue, env *Environment) (err error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		return fmt.Errorf("send on closed channel")
+	}
+
+	cancelled := cancelSignal(env)
+	blockingCall(env, func() {
+		// The channel may be closed while the send is waiting
+		defer func() {
+			if recover() != nil {
+				err = fmt.Errorf("send on closed channel")
+			}
+		}()
+		select {
+		case c.ch <- value:
+		case <-cancelled:
+			err = errCancelled
+		}
+	})
+	return err
+}
+
+// Receive blocks for the next value, ok is false once the channel is closed and drained
+func (c *ChannelValue) Receive(env *Environment) (value RuntimeValue, ok bool, err error) {
+	cancelled := cancelSignal(env)
+	blockingCall(env, func() {
+		select {
+		case value, ok = <-c.ch:
+		case <-cancelled:
+			err = errCancelled
+		}
+	})
+	return value, ok, err
+}
+
+func (c *ChannelValue) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return fmt.Errorf("close of closed channel")
+	}
+	c.closed = true
+	close(c.ch)
+	return nil
+}
+
+var ChannelPrototype = map[string]func(c *ChannelValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"send":  channelSend,
+	"recv":  channelRecv,
+	"close": channelClose,
+}
+
+func channelSend(c *ChannelValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("chan.send requires exactly one argument")
+	}
+	if err := c.Send(args[0], env); err != nil {
+		return nil, err
+	}
+	return MakeVoid(), nil
+}
+
+func channelRecv(c *ChannelValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("chan.recv takes no arguments")
+	}
+	value, ok, err := c.Receive(env)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return MakeUndefined(), nil
+	}
+	return value, nil
+}
+
+func channelClose(c *ChannelValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("chan.close takes no arguments")
+	}
+	if err := c.Close(); err != nil {
+		return nil, err
+	}
+	return MakeVoid(), nil
+}
+
+// chanNative creates a channel, unbuffered unless a capacity is given
+func chanNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("chan expects at most 1 argument, got %d", len(args))
+	}
+	capacity := 0
+	if len(args) == 1 {
+		size, ok := toInt(args[0])
+		if !ok || size < 0 {
+			return nil, fmt.Errorf("chan: capacity must be a non-negative integer")
+		}
+		capacity = int(size)
+	}
+	return MakeChannel(capacity), nil
+}
+
+// sleepFor pauses the running task, letting the others run, and wakes up
+// early with errCancelled if its group is cancelled
+func sleepFor(duration time.Duration, env *Environment) (err error) {
+	cancelled := cancelSignal(env)
+	blockingCall(env, func() {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-cancelled:
+			err = errCancelled
+		}
+	})
+	return err
+}
+
+// Task Value, the eventual result of an async function call or a timer
+type TaskValue struct {
+	done   chan struct{} // closed once result and err are set
+	group  *TaskGroup    // cancels the task and the groups it opened
+	result RuntimeValue
+	err    error
+}
+
+func (t *TaskValue) Type() ValueType { return TASK_TYPE }
+func (t *TaskValue) String() string {
+	if t.Done() {
+		return "task(done)"
+	}
+	return "task(pending)"
+}
+func (t *TaskValue) IsTruthy() bool { return true }
+func (t *TaskValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, fn := range TaskPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return fn(t, args, env)
+		}))
+	}
+
+	return &prototypes
+}
+
+// startTask runs fn on a task nested in the current group of env, the
+// returned task completes with its result. A cancelled task completes with undef.
+func startTask(env *Environment, fn func() (RuntimeValue, error)) *TaskValue {
+	task := &TaskValue{done: make(chan struct{}), group: newTaskGroup(env.tasks.group)}
+	runTask(env, task.group, func() {
+		result, err := fn()
+		if err == errCancelled && task.group.Cancelled() {
+			result, err = MakeUndefined(), nil
+		}
+		task.result, task.err = result, err
+		task.group.detach()
+		close(task.done)
+	})
+	return task
+}
+
+func (t *TaskValue) Done() bool {
+	select {
+	case <-t.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Await blocks until the task completes and returns its result or error,
+// letting the other tasks of env run
+func (t *TaskValue) Await(env *Environment) (RuntimeValue, error) {
+	cancelled := cancelSignal(env)
+	var err error
+	blockingCall(env, func() {
+		select {
+		case <-t.done:
+		case <-cancelled:
+			err = errCancelled
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t.result, t.err
+}
+
+var TaskPrototype = map[string]func(t *TaskValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"done":   taskDone,
+	"cancel": taskCancel,
+}
+
+func taskCancel(t *TaskValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("task.cancel takes no arguments")
+	}
+	t.group.Cancel(nil)
+	return MakeVoid(), nil
+}
+
+func taskDone(t *TaskValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 0 {
+		return nil, fmt.Errorf("task.done takes no arguments")
+	}
+	return MakeBool(t.Done()), nil
+}
+
+// evaluateAwaitExpression waits for a task, or for each task of an array,
+// other values are already available and pass through
+func evaluateAwaitExpression(node *AwaitExpr, env *Environment) (RuntimeValue, error) {
+	value, err := Evaluate(node.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case *TaskValue:
+		return v.Await(env)
+	case *ArrayValue:
+		results := make([]RuntimeValue, len(v.Elements))
+		for i, element := range v.Elements {
+			results[i] = element
+			if task, ok := element.(*TaskValue); ok {
+				if results[i], err = task.Await(env); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return MakeArray(results), nil
+	}
+	return value, nil
+}
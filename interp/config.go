@@ -0,0 +1,116 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Version is the version of the interpreter, shown by the REPL banner
+const Version = "0.1.0"
+
+// The REPL reads ~/.luna/config (or the file named by LUNA_CONFIG), one
+// `key = value` per line like luna.pkg:
+//
+//	banner = "Luna {version}, modules: {capabilities}"
+//	prompt = "luna> "
+//
+// A value may be quoted to keep surrounding spaces and use escapes like \n.
+// In the banner {version} is the interpreter version and {capabilities} the
+// native modules enabled in the session. --quiet or -q prints no banner.
+
+// replConfig is what the config file sets, empty strings keep the defaults
+type replConfig struct {
+	Banner string
+	Prompt string
+}
+
+// defaultConfigFile is ~/.luna/config unless LUNA_CONFIG names another file
+func defaultConfigFile() string {
+	if path := os.Getenv("LUNA_CONFIG"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".luna", "config")
+}
+
+// readConfig reads the REPL settings of path, a missing file sets nothing
+func readConfig(path string) (replConfig, error) {
+	var config replConfig
+	if path == "" {
+		return config, nil
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, found := strings.Cut(text, "=")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if !found || key == "" {
+			return config, fmt.Errorf("%s:%d: expected 'key = value'", path, line)
+		}
+		if strings.HasPrefix(value, `"`) {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return config, fmt.Errorf("%s:%d: invalid quoted value %s", path, line, value)
+			}
+			value = unquoted
+		}
+		switch key {
+		case "banner":
+			config.Banner = value
+		case "prompt":
+			config.Prompt = value
+		default:
+			return config, fmt.Errorf("%s:%d: unknown setting '%s', expected banner or prompt", path, line, key)
+		}
+	}
+	return config, scanner.Err()
+}
+
+// enabledModules lists the native modules env can use, sorted by name
+func enabledModules(env *Environment) []string {
+	var names []string
+	for _, name := range env.moduleNames() {
+		if !env.root().disabled[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// printBanner greets the REPL user, with the configured banner if there is one
+func printBanner(config replConfig, env *Environment) {
+	if hasFlag("--quiet", "-q") {
+		return
+	}
+	if config.Banner == "" {
+		fmt.Println(green("Welcome to the Luna REPL!"))
+		fmt.Println(gray("Type ") + green(under("exit()")) + gray(" to leave..."))
+		return
+	}
+	banner := strings.NewReplacer(
+		"{version}", Version,
+		"{capabilities}", strings.Join(enabledModules(env), ", "),
+	).Replace(config.Banner)
+	fmt.Println(banner)
+}
@@ -0,0 +1,183 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // timezone conversion works without a system database
+)
+
+// Dates are Luna objects: { timestamp, year, month, day, hour, minute,
+// second, millisecond, weekday, zone }. The timestamp, in milliseconds since
+// the Unix epoch, and the zone identify the date, the other fields are for reading.
+
+// defaultDateLayout is ISO 8601
+const defaultDateLayout = "YYYY-MM-DDTHH:mm:ssZ"
+
+// dateLayoutTokens map layout tokens to Go reference-time layouts, longest first
+var dateLayoutTokens = []struct{ token, layout string }{
+	{"YYYY", "2006"}, {"YY", "06"},
+	{"MMMM", "January"}, {"MMM", "Jan"}, {"MM", "01"}, {"M", "1"},
+	{"dddd", "Monday"}, {"ddd", "Mon"},
+	{"DD", "02"}, {"D", "2"},
+	{"HH", "15"}, {"hh", "03"}, {"h", "3"},
+	{"mm", "04"}, {"ss", "05"}, {"SSS", "000"},
+	{"A", "PM"}, {"ZZ", "-0700"}, {"Z", "Z07:00"},
+}
+
+// goLayout translates a layout like "YYYY-MM-DD HH:mm" to Go's reference time
+func goLayout(layout string) string {
+	var out strings.Builder
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, t := range dateLayoutTokens {
+			if strings.HasPrefix(layout[i:], t.token) {
+				out.WriteString(t.layout)
+				i += len(t.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out.WriteByte(layout[i])
+			i++
+		}
+	}
+	return out.String()
+}
+
+func makeDate(t time.Time) RuntimeValue {
+	return MakeObject(map[string]RuntimeValue{
+		"timestamp":   MakeInt(int64(t.UnixMilli())),
+		"year":        MakeInt(int64(t.Year())),
+		"month":       MakeInt(int64(t.Month())),
+		"day":         MakeInt(int64(t.Day())),
+		"hour":        MakeInt(int64(t.Hour())),
+		"minute":      MakeInt(int64(t.Minute())),
+		"second":      MakeInt(int64(t.Second())),
+		"millisecond": MakeInt(int64(t.Nanosecond() / int(time.Millisecond))),
+		"weekday":     MakeString(t.Weekday().String()),
+		"zone":        MakeString(t.Location().String()),
+	})
+}
+
+// dateArg accepts a date object or a timestamp in milliseconds (read in local time)
+func dateArg(name string, value RuntimeValue) (time.Time, error) {
+	switch v := value.(type) {
+	case *NumberValue, *IntValue:
+		timestamp, _ := toFloat(v)
+		return time.UnixMilli(int64(timestamp)), nil
+	case *ObjectValue:
+		timestamp, ok := toFloat(v.Properties["timestamp"])
+		if !ok {
+			return time.Time{}, fmt.Errorf("date.%s: date object has no timestamp", name)
+		}
+		t := time.UnixMilli(int64(timestamp))
+		if zone, ok := v.Properties["zone"].(*StringValue); ok {
+			location, err := time.LoadLocation(zone.Value)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("date.%s: %v", name, err)
+			}
+			t = t.In(location)
+		}
+		return t, nil
+	default:
+		return time.Time{}, fmt.Errorf("date.%s: expected a date or a timestamp", name)
+	}
+}
+
+// layoutArg reads an optional layout argument at index i
+func layoutArg(name string, args []RuntimeValue, i int) (string, error) {
+	if len(args) <= i {
+		return defaultDateLayout, nil
+	}
+	layout, ok := args[i].(*StringValue)
+	if !ok {
+		return "", fmt.Errorf("date.%s: layout must be a string", name)
+	}
+	return layout.Value, nil
+}
+
+func createDateObject() RuntimeValue {
+	dateProps := make(map[string]RuntimeValue)
+
+	dateProps["now"] = MakeNativeFunction("now", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("date.now takes no arguments")
+		}
+		return makeDate(time.Now()), nil
+	})
+
+	// date.parse(str, layout) reads a date, without a zone in the text it is local time
+	dateProps["parse"] = MakeNativeFunction("parse", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("date.parse expects a string and an optional layout")
+		}
+		text, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("date.parse: first argument must be a string")
+		}
+		layout, err := layoutArg("parse", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		t, err := time.ParseInLocation(goLayout(layout), text.Value, time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("date.parse: cannot read '%s' as %s", text.Value, layout)
+		}
+		return makeDate(t), nil
+	})
+
+	dateProps["format"] = MakeNativeFunction("format", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("date.format expects a date and an optional layout")
+		}
+		t, err := dateArg("format", args[0])
+		if err != nil {
+			return nil, err
+		}
+		layout, err := layoutArg("format", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(t.Format(goLayout(layout))), nil
+	})
+
+	// date.addDays(d, n) moves by calendar days, so the time of day survives DST changes
+	dateProps["addDays"] = MakeNativeFunction("addDays", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("date.addDays expects 2 arguments, got %d", len(args))
+		}
+		t, err := dateArg("addDays", args[0])
+		if err != nil {
+			return nil, err
+		}
+		days, ok := toInt(args[1])
+		if !ok {
+			return nil, fmt.Errorf("date.addDays: days must be an integer")
+		}
+		return makeDate(t.AddDate(0, 0, int(days))), nil
+	})
+
+	// date.inZone(d, "Europe/Paris") is the same instant seen in another timezone
+	dateProps["inZone"] = MakeNativeFunction("inZone", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("date.inZone expects 2 arguments, got %d", len(args))
+		}
+		t, err := dateArg("inZone", args[0])
+		if err != nil {
+			return nil, err
+		}
+		zone, ok := args[1].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("date.inZone: zone must be a string")
+		}
+		location, err := time.LoadLocation(zone.Value)
+		if err != nil {
+			return nil, fmt.Errorf("date.inZone: unknown timezone '%s'", zone.Value)
+		}
+		return makeDate(t.In(location)), nil
+	})
+
+	return MakeObject(dateProps)
+}
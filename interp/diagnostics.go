@@ -0,0 +1,210 @@
+package interp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Every error the tokenizer and the parser report is a SyntaxError: the
+// message, where in the source it is, and the source line with carets
+// under the offending text,
+//
+//	expected ')' after function arguments at line 3, column 12:
+//	io.print(1, 2
+//	             ^
+//
+// Line and column count from 1 when shown. Without the source, as for
+// code parsed from tokens alone, the position is given without the snippet.
+//
+// For tools, errors and the findings of `luna check` are Diagnostics with
+// a stable code, a severity, a span and hints. --json-errors prints them as
+// one JSON object per line instead of colored text:
+//
+//	{"code":"E101","severity":"error","message":"unterminated string, expected a closing \"",
+//	 "file":"main.ln","span":{"line":2,"column":5,"length":1},"hints":[...]}
+//
+//	E100 syntax error            E200 runtime error
+//	E101 unterminated literal    E201 disabled module (PermissionError)
+//	E102 invalid escape          E202 limit exceeded (LimitError)
+//	E103 invalid number literal  E300 undefined variable
+//	E104 unexpected character    E301 wrong arguments in a call
+//	W100 unused variable         W101 unreachable code
+//	W102 assignment as a condition
+//	W103 implicit global         W104 shadowed variable
+//	W105 unused parameter
+
+// Diagnostic codes
+const (
+	codeSyntax          = "E100"
+	codeUnterminated    = "E101"
+	codeInvalidEscape   = "E102"
+	codeInvalidNumber   = "E103"
+	codeUnexpectedChar  = "E104"
+	codeRuntime         = "E200"
+	codePermission      = "E201"
+	codeLimit           = "E202"
+	codeUndefined       = "E300"
+	codeArguments       = "E301"
+	codeUnused          = "W100"
+	codeUnreachable     = "W101"
+	codeConditionAssign = "W102"
+	codeImplicitGlobal  = "W103"
+	codeShadowed        = "W104"
+	codeUnusedParam     = "W105"
+)
+
+// Severity tells errors, which stop a program, from warnings
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Span is the stretch of source a diagnostic is about, Length 0 when only
+// the start is known
+type Span struct {
+	Start  Position
+	Length int
+}
+
+// Diagnostic is a problem found in a program, by the tokenizer, the parser,
+// the checker or while running it
+type Diagnostic struct {
+	Code     string
+	Severity Severity
+	Message  string
+	File     string
+	Span     *Span // nil for errors without a position, most runtime errors
+	Hints    []string
+}
+
+// newDiagnostic makes a diagnostic, a warning when code starts with W
+func newDiagnostic(code string, message string, span *Span) Diagnostic {
+	severity := SeverityError
+	if strings.HasPrefix(code, "W") {
+		severity = SeverityWarning
+	}
+	return Diagnostic{Code: code, Severity: severity, Message: message, Span: span}
+}
+
+func (d Diagnostic) String() string {
+	if d.Span == nil {
+		return d.Message
+	}
+	return fmt.Sprintf("%d:%d: %s", d.Span.Start.Line+1, d.Span.Start.Column+1, d.Message)
+}
+
+// MarshalJSON writes lines and columns counted from 1, like the text output
+func (d Diagnostic) MarshalJSON() ([]byte, error) {
+	type jsonSpan struct {
+		Line   int `json:"line"`
+		Column int `json:"column"`
+		Length int `json:"length"`
+	}
+	out := struct {
+		Code     string    `json:"code"`
+		Severity Severity  `json:"severity"`
+		Message  string    `json:"message"`
+		File     string    `json:"file,omitempty"`
+		Span     *jsonSpan `json:"span,omitempty"`
+		Hints    []string  `json:"hints,omitempty"`
+	}{Code: d.Code, Severity: d.Severity, Message: d.Message, File: d.File, Hints: d.Hints}
+	if d.Span != nil {
+		out.Span = &jsonSpan{Line: d.Span.Start.Line + 1, Column: d.Span.Start.Column + 1, Length: d.Span.Length}
+	}
+	return json.Marshal(out)
+}
+
+// SyntaxError is an error in the source of a program
+type SyntaxError struct {
+	Code     string
+	Message  string
+	Position Position // where the error starts, counted from 0
+	Length   int      // how many characters the carets cover
+	Line     string   // the source line the error is on, "" when unknown
+	Hints    []string
+}
+
+func (e *SyntaxError) Error() string {
+	at := fmt.Sprintf("%s at line %d, column %d", e.Message, e.Position.Line+1, e.Position.Column+1)
+	if e.Line == "" {
+		return at
+	}
+	pointer := strings.Repeat(" ", e.Position.Column) + strings.Repeat("^", max(e.Length, 1))
+	return at + ":\n" + e.Line + "\n" + pointer
+}
+
+// newSyntaxError reports message at position in code, the carets covering length characters
+func newSyntaxError(code string, message string, position Position, length int) *SyntaxError {
+	err := &SyntaxError{Code: codeSyntax, Message: message, Position: position, Length: length}
+	lines := strings.Split(code, "\n")
+	if code != "" && position.Line < len(lines) {
+		err.Line = strings.TrimRight(lines[position.Line], "\r")
+	}
+	return err
+}
+
+// errorAt reports a tokenizer error with a diagnostic code at position, covering length characters
+func (t *Tokenizer) errorAt(code string, position Position, length int, format string, args ...any) *SyntaxError {
+	err := newSyntaxError(string(t.input), fmt.Sprintf(format, args...), position, length)
+	err.Code = code
+	return err
+}
+
+// tokenLength is how many characters the carets under token cover
+func tokenLength(token Token) int {
+	if token.Type == NEWLINE || token.Type == EOF {
+		return 1
+	}
+	return utf8.RuneCountInString(token.Value)
+}
+
+// diagnosticOf describes an error of a program in file for --json-errors
+func diagnosticOf(err error, file string) Diagnostic {
+	var diagnostic Diagnostic
+	var syntax *SyntaxError
+	var permission *PermissionError
+	var limit *LimitError
+	switch {
+	case errors.As(err, &syntax):
+		diagnostic = newDiagnostic(syntax.Code, syntax.Message, &Span{Start: syntax.Position, Length: syntax.Length})
+		diagnostic.Hints = syntax.Hints
+	case errors.As(err, &permission):
+		diagnostic = newDiagnostic(codePermission, err.Error(), nil)
+		diagnostic.Hints = []string{"the module was turned off with --disable or DisableModule"}
+	case errors.As(err, &limit):
+		diagnostic = newDiagnostic(codeLimit, err.Error(), nil)
+	default:
+		diagnostic = newDiagnostic(codeRuntime, err.Error(), nil)
+	}
+	diagnostic.File = file
+	return diagnostic
+}
+
+// jsonErrors reports whether --json-errors asked for diagnostics as JSON
+func jsonErrors() bool {
+	return hasFlag("--json-errors")
+}
+
+// reportError prints an error of file, as colored text after prefix or
+// with --json-errors as a diagnostic
+func reportError(err error, file string, prefix string) {
+	if jsonErrors() {
+		printDiagnostic(diagnosticOf(err, file))
+		return
+	}
+	fmt.Println(formatError(errorLabel(err), prefix+err.Error()))
+}
+
+// printDiagnostic writes diagnostic as one line of JSON
+func printDiagnostic(diagnostic Diagnostic) {
+	data, err := json.Marshal(diagnostic)
+	if err != nil {
+		data, _ = json.Marshal(newDiagnostic(codeRuntime, err.Error(), nil))
+	}
+	fmt.Println(string(data))
+}
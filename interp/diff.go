@@ -0,0 +1,102 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// diffValueWidth is how much of a value :diff shows before truncating it
+const diffValueWidth = 60
+
+// stableString renders a value like String, but with object keys sorted so
+// that two renderings of an unchanged value are equal
+func stableString(value RuntimeValue) string {
+	return stableNested(value, nil)
+}
+
+// stableNested is stableString inside path, the arrays and objects around value
+func stableNested(value RuntimeValue, path []RuntimeValue) string {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return "<circular>"
+		}
+	}
+
+	switch v := value.(type) {
+	case *ArrayValue:
+		elements := make([]string, len(v.Elements))
+		for i, elem := range v.Elements {
+			elements[i] = stableNested(elem, path)
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *ObjectValue:
+		keys := make([]string, 0, len(v.Properties))
+		for key := range v.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		props := make([]string, len(keys))
+		for i, key := range keys {
+			props[i] = key + ": " + stableNested(v.Properties[key], path)
+		}
+		return "{" + strings.Join(props, ", ") + "}"
+	default:
+		return nestedString(value, path)
+	}
+}
+
+// Snapshot records the bindings of this scope as rendered values
+func (env *Environment) Snapshot() map[string]string {
+	snapshot := make(map[string]string, len(env.variables))
+	for name, value := range env.variables {
+		snapshot[name] = stableString(value)
+	}
+	return snapshot
+}
+
+func truncateValue(text string) string {
+	runes := []rune(text)
+	if len(runes) <= diffValueWidth {
+		return text
+	}
+	return string(runes[:diffValueWidth-1]) + "…"
+}
+
+// diffSnapshots describes the bindings added, changed and removed between two snapshots
+func diffSnapshots(before, after map[string]string) []string {
+	names := make([]string, 0, len(after))
+	for name := range after {
+		names = append(names, name)
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var lines []string
+	for _, name := range names {
+		old, existed := before[name]
+		value, exists := after[name]
+		switch {
+		case !existed:
+			lines = append(lines, green("+ ")+blue(name)+gray(" = ")+truncateValue(value))
+		case !exists:
+			lines = append(lines, red("- ")+blue(name))
+		case old != value:
+			lines = append(lines, yellow("~ ")+blue(name)+gray(": ")+truncateValue(old)+gray(" → ")+truncateValue(value))
+		}
+	}
+	return lines
+}
+
+// printDiff prints what changed in env since before
+func printDiff(before map[string]string, env *Environment) {
+	for _, line := range diffSnapshots(before, env.Snapshot()) {
+		fmt.Println(line)
+	}
+}
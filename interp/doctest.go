@@ -0,0 +1,143 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Doctest is a runnable example found in a ## doc comment:
+//
+//	## >> add(1, 2)
+//	## 3
+type Doctest struct {
+	Line     int
+	Code     string
+	Expected string
+}
+
+// extractDoctests collects the examples of every ## doc comment in code
+func extractDoctests(code string) []Doctest {
+	var doctests []Doctest
+	var current *Doctest
+
+	for i, line := range strings.Split(code, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !strings.HasPrefix(trimmed, "##") {
+			current = nil
+			continue
+		}
+
+		text := strings.TrimSpace(strings.TrimPrefix(trimmed, "##"))
+		switch {
+		case strings.HasPrefix(text, ">>"):
+			doctests = append(doctests, Doctest{Line: i + 1, Code: strings.TrimSpace(strings.TrimPrefix(text, ">>"))})
+			current = &doctests[len(doctests)-1]
+		case current != nil && text != "":
+			// Multi-line expected output is joined with newlines
+			if current.Expected != "" {
+				current.Expected += "\n"
+			}
+			current.Expected += text
+		default:
+			current = nil
+		}
+	}
+
+	return doctests
+}
+
+// runDoctests loads the file into a fresh environment and checks each example against it
+func runDoctests(filename string) (passed int, failed int, err error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not read file '%s': %v", filename, err)
+	}
+	code := string(data)
+	// A file without examples is not run, a directory may hold programs too
+	doctests := extractDoctests(code)
+	if len(doctests) == 0 {
+		return 0, 0, nil
+	}
+
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+
+	luna := NewLuna(env)
+	if _, err := luna.Evaluate(code); err != nil {
+		return 0, 0, fmt.Errorf("%s: %v", filename, err)
+	}
+
+	for _, doctest := range doctests {
+		location := fmt.Sprintf("%s:%d", filename, doctest.Line)
+
+		result, err := luna.Evaluate(doctest.Code)
+		if err != nil {
+			failed++
+			fmt.Printf("%s %s %s\n", red("FAIL"), location, gray(doctest.Code))
+			fmt.Println("  " + formatError("Error", err.Error()))
+			continue
+		}
+
+		actual := ""
+		if result != nil && result.Type() != VOID_TYPE {
+			actual = result.String()
+		}
+
+		if actual != doctest.Expected {
+			failed++
+			fmt.Printf("%s %s %s\n", red("FAIL"), location, gray(doctest.Code))
+			fmt.Printf("  expected: %s\n", green(doctest.Expected))
+			fmt.Printf("  got:      %s\n", red(actual))
+			continue
+		}
+
+		passed++
+	}
+
+	return passed, failed, nil
+}
+
+// runTest is the entry point of `luna test`, running the test_ functions of
+// the test files under args or, with --doc, the doctests of every file under
+// args
+func runTest(args []string) {
+	doc := hasFlag("--doc")
+	if doc && len(args) == 0 {
+		fmt.Println("Usage: luna test [--doc] <file or directory>...")
+		return
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	match, run := isTestFile, runTestFile
+	if doc {
+		match, run = isSourceFile, runDoctests
+	}
+	files, err := findTestFiles(paths, match)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+
+	totalPassed, totalFailed := 0, 0
+	for _, filename := range files {
+		passed, failed, err := run(filename)
+		if err != nil {
+			fmt.Println(formatError("Error", err.Error()))
+			totalFailed++
+			continue
+		}
+		totalPassed += passed
+		totalFailed += failed
+	}
+
+	summary := fmt.Sprintf("%d passed, %d failed", totalPassed, totalFailed)
+	if totalFailed > 0 {
+		fmt.Println(red(summary))
+		os.Exit(1)
+	}
+	fmt.Println(green(summary))
+}
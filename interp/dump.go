@@ -0,0 +1,176 @@
+package interp
+
+import (
+	"encoding/json"
+	"reflect"
+	"unicode"
+)
+
+var tokenTypeNames = map[TokenType]string{
+	IDENTIFIER:       "IDENTIFIER",
+	STRING:           "STRING",
+	RAW_STRING:       "RAW_STRING",
+	TEMPLATE_STRING:  "TEMPLATE_STRING",
+	INT:              "INT",
+	FLOAT:            "FLOAT",
+	BIGINT:           "BIGINT",
+	BOOLEAN:          "BOOLEAN",
+	UNDEFINED:        "UNDEFINED",
+	FN:               "FN",
+	LAMBDA:           "LAMBDA",
+	IF:               "IF",
+	UNLESS:           "UNLESS",
+	ELSE:             "ELSE",
+	RETURN:           "RETURN",
+	TYPEOF:           "TYPEOF",
+	FOR:              "FOR",
+	WHILE:            "WHILE",
+	DO:               "DO",
+	REPEAT:           "REPEAT",
+	UNTIL:            "UNTIL",
+	DEBUG:            "DEBUG",
+	USE:              "USE",
+	OUT:              "OUT",
+	MATCH:            "MATCH",
+	IN:               "IN",
+	SPAWN:            "SPAWN",
+	ASYNC:            "ASYNC",
+	AWAIT:            "AWAIT",
+	BINARY_OPERATOR:  "BINARY_OPERATOR",
+	EQUALS:           "EQUALS",
+	COMPOUND_ASSIGN:  "COMPOUND_ASSIGN",
+	EQUALITY_OP:      "EQUALITY_OP",
+	INEQUALITY_OP:    "INEQUALITY_OP",
+	SMALLER_THAN:     "SMALLER_THAN",
+	GREATER_THAN:     "GREATER_THAN",
+	SMALLER_OR_EQUAL: "SMALLER_OR_EQUAL",
+	GREATER_OR_EQUAL: "GREATER_OR_EQUAL",
+	AND:              "AND",
+	OR:               "OR",
+	NEGATION_OP:      "NEGATION_OP",
+	INCREMENT:        "INCREMENT",
+	DECREMENT:        "DECREMENT",
+	ARROW:            "ARROW",
+	RANGE:            "RANGE",
+	RANGE_INCLUSIVE:  "RANGE_INCLUSIVE",
+	COMMA:            "COMMA",
+	DOT:              "DOT",
+	ELLIPSIS:         "ELLIPSIS",
+	COLON:            "COLON",
+	SEMICOLON:        "SEMICOLON",
+	OPEN_PAREN:       "OPEN_PAREN",
+	CLOSE_PAREN:      "CLOSE_PAREN",
+	OPEN_BRACE:       "OPEN_BRACE",
+	CLOSE_BRACE:      "CLOSE_BRACE",
+	OPEN_BRACKET:     "OPEN_BRACKET",
+	CLOSE_BRACKET:    "CLOSE_BRACKET",
+	TERNARY:          "TERNARY",
+	OPTIONAL_DOT:     "OPTIONAL_DOT",
+	COMMENT:          "COMMENT",
+	DOC_COMMENT:      "DOC_COMMENT",
+	NEWLINE:          "NEWLINE",
+	EOF:              "EOF",
+}
+
+func (t TokenType) String() string {
+	if name, exists := tokenTypeNames[t]; exists {
+		return name
+	}
+	return "UNKNOWN"
+}
+
+type tokenDump struct {
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// dumpTokens renders tokens as indented JSON
+func dumpTokens(tokens []Token) (string, error) {
+	dumps := make([]tokenDump, 0, len(tokens))
+	for _, token := range tokens {
+		dumps = append(dumps, tokenDump{
+			Type:   token.Type.String(),
+			Value:  token.Value,
+			Line:   token.Position.Line + 1,
+			Column: token.Position.Column + 1,
+		})
+	}
+
+	data, err := json.MarshalIndent(dumps, "", "  ")
+	return string(data), err
+}
+
+// dumpAST renders an AST as indented JSON, tagging every node with its kind
+func dumpAST(node Statement) (string, error) {
+	data, err := json.MarshalIndent(dumpValue(reflect.ValueOf(node)), "", "  ")
+	return string(data), err
+}
+
+var statementType = reflect.TypeOf((*Statement)(nil)).Elem()
+
+func dumpValue(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		if v.Type().Implements(statementType) {
+			node := dumpValue(v.Elem()).(map[string]any)
+			node["kind"] = string(v.Interface().(Statement).Kind())
+			return node
+		}
+		return dumpValue(v.Elem())
+
+	case reflect.Struct:
+		if position, ok := v.Interface().(Position); ok {
+			// 1-based like the token dump and error messages
+			return map[string]any{"line": position.Line + 1, "column": position.Column + 1, "index": position.Index}
+		}
+
+		fields := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			fields[jsonName(field.Name)] = dumpValue(v.Field(i))
+		}
+		return fields
+
+	case reflect.Slice:
+		items := make([]any, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			items = append(items, dumpValue(v.Index(i)))
+		}
+		return items
+
+	default:
+		return v.Interface()
+	}
+}
+
+func jsonName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// dumpFile tokenizes code and dumps the tokens, or the parsed AST when ast is set
+func dumpFile(code string, ast bool) (string, error) {
+	tokens, err := NewTokenizer(code).Tokenize()
+	if err != nil {
+		return "", err
+	}
+
+	if !ast {
+		return dumpTokens(tokens)
+	}
+
+	program, err := NewParser(tokens, code).ProduceAST()
+	if err != nil {
+		return "", err
+	}
+	return dumpAST(program)
+}
@@ -0,0 +1,149 @@
+package interp
+
+type Environment struct {
+	parent    *Environment
+	variables map[string]RuntimeValue
+	constants map[string]bool
+	pragmas   *Pragmas        // set on the top-level scope of a module
+	module    string          // the file or URL of a module, likewise
+	disabled  map[string]bool // native modules disabled with DisableModule
+	modules   []string        // native modules added to this environment alone
+	limits    *limitState     // shared with the parent, created with the root
+	warnings  *warningList    // likewise
+	options   *Options        // likewise
+	tasks     *scheduler      // likewise
+}
+
+func NewEnvironment(parent *Environment) *Environment {
+	env := &Environment{
+		parent:    parent,
+		variables: make(map[string]RuntimeValue),
+		constants: make(map[string]bool),
+	}
+	if parent != nil {
+		env.limits = parent.limits
+		env.warnings = parent.warnings
+		env.options = parent.options
+		env.tasks = parent.tasks
+	} else {
+		options := DefaultOptions
+		env.limits = newLimitState()
+		env.warnings = &warningList{seen: make(map[string]bool)}
+		env.options = &options
+		env.tasks = &scheduler{}
+	}
+	return env
+}
+
+// Copy returns a sibling scope holding the same bindings, so later
+// assignments in either scope do not affect the other
+func (env *Environment) Copy() *Environment {
+	copied := NewEnvironment(env.parent)
+	for name, value := range env.variables {
+		copied.variables[name] = value
+	}
+	for name := range env.constants {
+		copied.constants[name] = true
+	}
+	copied.pragmas = env.pragmas
+	copied.module = env.module
+	copied.modules = env.modules
+	copied.limits = env.limits
+	copied.warnings = env.warnings
+	return copied
+}
+
+// SetPragmas attaches the pragmas of a module to its top-level scope
+func (env *Environment) SetPragmas(pragmas *Pragmas) {
+	env.pragmas = pragmas
+}
+
+// Pragmas returns the pragmas of the module this scope belongs to
+func (env *Environment) Pragmas() *Pragmas {
+	for current := env; current != nil; current = current.parent {
+		if current.pragmas != nil {
+			return current.pragmas
+		}
+	}
+	return &Pragmas{}
+}
+
+// SetModule records the file or URL of the module whose top-level scope
+// env is, the relative paths it uses start from there
+func (env *Environment) SetModule(location string) {
+	env.module = location
+}
+
+// Module returns the file or URL of the module this scope belongs to, ""
+// when it was not read from one
+func (env *Environment) Module() string {
+	for current := env; current != nil; current = current.parent {
+		if current.module != "" {
+			return current.module
+		}
+	}
+	return ""
+}
+
+// IsConstant reports whether name is bound to a constant in the scope that declares it
+func (env *Environment) IsConstant(name string) bool {
+	for current := env; current != nil; current = current.parent {
+		if _, exists := current.variables[name]; exists {
+			return current.constants[name]
+		}
+	}
+	return false
+}
+
+func (env *Environment) DeclareVar(name string, value RuntimeValue, isConstant bool) RuntimeValue {
+	env.variables[name] = value
+	if isConstant {
+		env.constants[name] = true
+	}
+	return value
+}
+
+func (env *Environment) AssignVar(name string, value RuntimeValue) RuntimeValue {
+	// Check if it's a constant
+	if env.constants[name] {
+		// For now, just return the value without error - could add error handling later
+		return value
+	}
+
+	// Find the environment that contains this variable
+	current := env
+	for current != nil {
+		if _, exists := current.variables[name]; exists {
+			current.variables[name] = value
+			return value
+		}
+		current = current.parent
+	}
+
+	// If not found, declare it in current environment
+	env.variables[name] = value
+	return value
+}
+
+func (env *Environment) LookupVar(name string) RuntimeValue {
+	current := env
+	for current != nil {
+		if value, exists := current.variables[name]; exists {
+			return value
+		}
+		current = current.parent
+	}
+	// Return undefined instead of panicking
+	return MakeUndefined()
+}
+
+func (env *Environment) HasVar(name string) bool {
+	current := env
+	for current != nil {
+		if _, exists := current.variables[name]; exists {
+			return true
+		}
+		current = current.parent
+	}
+	return false
+}
@@ -0,0 +1,192 @@
+package interp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// fileSHA256 hashes a file in chunks, so large files are never held in memory
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// listFiles returns the regular files below root, as slash separated paths relative to it
+func listFiles(root string) (map[string]fs.FileInfo, error) {
+	files := make(map[string]fs.FileInfo)
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(relative)] = info
+		return nil
+	})
+	return files, err
+}
+
+// DirDiff lists the files that differ between two directory trees
+type DirDiff struct {
+	Added   []string // only in the second tree
+	Removed []string // only in the first tree
+	Changed []string // in both with different content
+}
+
+// compareDirs compares two trees by size first and by checksum when sizes match
+func compareDirs(a, b string) (*DirDiff, error) {
+	before, err := listFiles(a)
+	if err != nil {
+		return nil, err
+	}
+	after, err := listFiles(b)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &DirDiff{}
+	for name, info := range after {
+		old, exists := before[name]
+		if !exists {
+			diff.Added = append(diff.Added, name)
+			continue
+		}
+		if old.Size() != info.Size() {
+			diff.Changed = append(diff.Changed, name)
+			continue
+		}
+		oldSum, err := fileSHA256(filepath.Join(a, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		newSum, err := fileSHA256(filepath.Join(b, filepath.FromSlash(name)))
+		if err != nil {
+			return nil, err
+		}
+		if oldSum != newSum {
+			diff.Changed = append(diff.Changed, name)
+		}
+	}
+	for name := range before {
+		if _, exists := after[name]; !exists {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
+
+func stringArray(values []string) RuntimeValue {
+	elements := make([]RuntimeValue, len(values))
+	for i, value := range values {
+		elements[i] = MakeString(value)
+	}
+	return MakeArray(elements)
+}
+
+// pathArgs checks that a file native got count path strings
+func pathArgs(name string, args []RuntimeValue, count int, env *Environment) ([]string, error) {
+	if len(args) != count {
+		return nil, fmt.Errorf("file.%s expects %d argument(s), got %d", name, count, len(args))
+	}
+	paths := make([]string, count)
+	for i, arg := range args {
+		str, ok := arg.(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("file.%s: path must be a string", name)
+		}
+		path, err := resolvePath(str.Value, env)
+		if err != nil {
+			return nil, fmt.Errorf("file.%s: %v", name, err)
+		}
+		paths[i] = path
+	}
+	return paths, nil
+}
+
+func createFileObject() RuntimeValue {
+	fileProps := make(map[string]RuntimeValue)
+
+	fileProps["sha256"] = MakeNativeFunction("sha256", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		paths, err := pathArgs("sha256", args, 1, env)
+		if err != nil {
+			return nil, err
+		}
+		sum, err := fileSHA256(paths[0])
+		if err != nil {
+			return nil, fmt.Errorf("file.sha256: %v", err)
+		}
+		return MakeString(sum), nil
+	})
+
+	fileProps["size"] = MakeNativeFunction("size", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		paths, err := pathArgs("size", args, 1, env)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(paths[0])
+		if err != nil {
+			return nil, fmt.Errorf("file.size: %v", err)
+		}
+		return MakeInt(info.Size()), nil
+	})
+
+	// file.modified(path) is the modification time in milliseconds since the Unix epoch
+	fileProps["modified"] = MakeNativeFunction("modified", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		paths, err := pathArgs("modified", args, 1, env)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(paths[0])
+		if err != nil {
+			return nil, fmt.Errorf("file.modified: %v", err)
+		}
+		return MakeInt(info.ModTime().UnixMilli()), nil
+	})
+
+	// file.dircmp(a, b) returns { added, removed, changed } going from a to b
+	fileProps["dircmp"] = MakeNativeFunction("dircmp", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		paths, err := pathArgs("dircmp", args, 2, env)
+		if err != nil {
+			return nil, err
+		}
+		diff, err := compareDirs(paths[0], paths[1])
+		if err != nil {
+			return nil, fmt.Errorf("file.dircmp: %v", err)
+		}
+		return MakeObject(map[string]RuntimeValue{
+			"added":   stringArray(diff.Added),
+			"removed": stringArray(diff.Removed),
+			"changed": stringArray(diff.Changed),
+		}), nil
+	})
+
+	return MakeObject(fileProps)
+}
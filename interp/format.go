@@ -0,0 +1,538 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Operator precedence levels, mirroring the parser's descent order
+const (
+	precAssignment = iota + 1
+	precTernary
+	precLogical
+	precEquality
+	precInequality
+	precRange
+	precBitwiseOr
+	precBitwiseXor
+	precBitwiseAnd
+	precShift
+	precAdditive
+	precMultiplicative
+	precUnary
+	precExponent
+	precPostfix
+	precPrimary
+)
+
+// binaryPrecedence is the level of an arithmetic or bitwise operator
+func binaryPrecedence(operator string) int {
+	switch operator {
+	case "|":
+		return precBitwiseOr
+	case "^":
+		return precBitwiseXor
+	case "&":
+		return precBitwiseAnd
+	case "<<", ">>":
+		return precShift
+	case "+", "-":
+		return precAdditive
+	case "**":
+		return precExponent
+	}
+	return precMultiplicative
+}
+
+const formatIndent = "    "
+
+// Printer renders an AST back to canonical Luna source
+type Printer struct {
+	builder strings.Builder
+	depth   int
+}
+
+func NewPrinter() *Printer {
+	return &Printer{}
+}
+
+// FormatSource parses code (keeping comments) and returns it pretty-printed
+func FormatSource(code string) (string, error) {
+	tokenizer := NewTokenizer(code)
+	tokenizer.keepComments = true
+	tokens, err := tokenizer.Tokenize()
+	if err != nil {
+		return "", err
+	}
+
+	ast, err := NewParser(tokens, code).ProduceAST()
+	if err != nil {
+		return "", err
+	}
+
+	printer := NewPrinter()
+	return printer.Print(ast), nil
+}
+
+func (p *Printer) Print(node Statement) string {
+	p.builder.Reset()
+	if program, ok := node.(*Program); ok {
+		p.printBody(program.Body, true)
+	} else {
+		p.printStatement(node)
+	}
+	return p.builder.String()
+}
+
+func (p *Printer) write(text string) {
+	p.builder.WriteString(text)
+}
+
+func (p *Printer) line(text string) {
+	p.write(strings.Repeat(formatIndent, p.depth) + text + "\n")
+}
+
+// printBody prints statements one per line, separating block functions with a blank line
+func (p *Printer) printBody(body []Statement, topLevel bool) {
+	var previous Statement
+	for _, stmt := range body {
+		if comment, ok := stmt.(*Comment); ok && comment.Trailing {
+			p.printStatement(stmt)
+			continue
+		}
+		if topLevel && previous != nil && (isBlockFunction(stmt) || isBlockFunction(previous)) {
+			if _, ok := previous.(*Comment); !ok {
+				p.write("\n")
+			}
+		}
+		p.printStatement(stmt)
+		previous = stmt
+	}
+}
+
+func isBlockFunction(stmt Statement) bool {
+	fn, ok := stmt.(*FunctionDeclaration)
+	return ok && !fn.Inline
+}
+
+func (p *Printer) printStatement(stmt Statement) {
+	switch s := stmt.(type) {
+	case *Comment:
+		text := strings.TrimRight(s.Text, " \t\r")
+		// A trailing comment goes back at the end of the line it followed
+		if printed := p.builder.String(); s.Trailing && strings.HasSuffix(printed, "\n") {
+			p.builder.Reset()
+			p.write(strings.TrimSuffix(printed, "\n") + " " + text + "\n")
+			return
+		}
+		p.line(text)
+	case *IfStatement:
+		p.write(strings.Repeat(formatIndent, p.depth))
+		p.printIf(s)
+		p.write("\n")
+	case *WhileStatement:
+		p.line("while " + p.expr(s.Test, precAssignment) + " {")
+		p.printBlock(s.Consequent)
+		p.line("}")
+	case *DoWhileStatement:
+		keyword, ending := "do", "while"
+		if s.Until {
+			keyword, ending = "repeat", "until"
+		}
+		p.line(keyword + " {")
+		p.printBlock(s.Body)
+		p.line("} " + ending + " " + p.expr(s.Test, precAssignment))
+	case *ForStatement:
+		p.line(fmt.Sprintf("for %s; %s; %s {",
+			p.expr(s.Declaration, precAssignment),
+			p.expr(s.Test, precAssignment),
+			p.expr(s.Increaser, precAssignment)))
+		p.printBlock(s.Body)
+		p.line("}")
+	case *ForInStatement:
+		p.line(fmt.Sprintf("for %s in %s {", s.Variable.Value, p.expr(s.Iterable, precAssignment)))
+		p.printBlock(s.Body)
+		p.line("}")
+	case *ReturnExpr:
+		p.line("return " + p.expr(s.Value, precAssignment))
+	case *DebugStatement:
+		switch {
+		case s.Label != nil:
+			p.line("debug " + p.expr(s.Label, precAssignment) + " {" + p.exprList(s.Props) + "}")
+		case len(s.Props) == 1:
+			p.line("debug " + p.expr(s.Props[0], precAssignment))
+		default:
+			p.line("debug {" + p.exprList(s.Props) + "}")
+		}
+	case *UseStatement:
+		p.line("use " + strconv.Quote(s.Path))
+	case *FunctionDeclaration:
+		p.line(p.function(s))
+	default:
+		p.line(p.expr(stmt, precAssignment))
+	}
+}
+
+func (p *Printer) printBlock(body []Statement) {
+	p.depth++
+	p.printBody(body, false)
+	p.depth--
+}
+
+func (p *Printer) printIf(s *IfStatement) {
+	if s.Modifier != "" {
+		statement := NewPrinter()
+		statement.printStatement(s.Consequent[0])
+		test := s.Test
+		if s.Modifier == "unless" {
+			test = test.(*UnaryExpr).Value
+		}
+		p.write(strings.TrimSuffix(statement.builder.String(), "\n") + " " + s.Modifier + " " + p.expr(test, precAssignment))
+		return
+	}
+	p.write("if " + p.expr(s.Test, precAssignment) + " {\n")
+	p.printBlock(s.Consequent)
+	p.write(strings.Repeat(formatIndent, p.depth) + "}")
+
+	if len(s.Alternate) == 0 {
+		return
+	}
+
+	if elseIf, ok := s.Alternate[0].(*IfStatement); ok && len(s.Alternate) == 1 {
+		p.write(" else ")
+		p.printIf(elseIf)
+		return
+	}
+
+	p.write(" else {\n")
+	p.printBlock(s.Alternate)
+	p.write(strings.Repeat(formatIndent, p.depth) + "}")
+}
+
+// function renders a function declaration or expression, keeping its inline/block form
+func (p *Printer) function(fn *FunctionDeclaration) string {
+	var params []string
+	for _, param := range fn.Parameters {
+		name := param.Name
+		if param.Pattern != nil {
+			name = p.expr(param.Pattern, precPrimary)
+		}
+		if param.Rest {
+			params = append(params, "..."+name)
+		} else if param.DefaultValue != nil {
+			params = append(params, name+"=("+p.expr(param.DefaultValue, precAssignment)+")")
+		} else {
+			params = append(params, name)
+		}
+	}
+
+	async := ""
+	if fn.Async {
+		async = "async "
+	}
+
+	var head string
+	switch {
+	case fn.Name == "" && fn.Inline && len(params) == 0:
+		return async + "fn:: " + p.inlineBody(fn)
+	case fn.Name == "":
+		head = async + "lambda"
+	default:
+		head = async + "fn " + fn.Name
+		if fn.Export {
+			head = "out " + head
+		}
+	}
+
+	if len(params) > 0 {
+		head += " " + strings.Join(params, " ")
+	}
+
+	if fn.Inline {
+		return head + ": " + p.inlineBody(fn)
+	}
+
+	if len(fn.Body) == 0 {
+		return head + " {}"
+	}
+
+	// The body is printed on its own, a comment right after { stays on the head
+	body := fn.Body
+	if comment, ok := body[0].(*Comment); ok && comment.Trailing {
+		head += " { " + strings.TrimRight(comment.Text, " \t\r")
+		body = body[1:]
+	} else {
+		head += " {"
+	}
+
+	inner := NewPrinter()
+	inner.depth = p.depth + 1
+	inner.printBody(body, false)
+	return head + "\n" + inner.builder.String() + strings.Repeat(formatIndent, p.depth) + "}"
+}
+
+// patternElement renders one binding of a destructuring pattern
+func (p *Printer) patternElement(element PatternElement, inObject bool) string {
+	target := p.expr(element.Target, precPrimary)
+	if element.Rest {
+		return "..." + target
+	}
+	if inObject {
+		if ident, ok := element.Target.(*Identifier); !ok || ident.Value != element.Key {
+			key := element.Key
+			if !isIdentifierName(key) {
+				key = strconv.Quote(key)
+			}
+			target = key + ": " + target
+		}
+	}
+	if element.DefaultValue != nil {
+		target += " = " + p.expr(element.DefaultValue, precTernary)
+	}
+	return target
+}
+
+// match renders a match expression with one arm per line
+func (p *Printer) match(m *MatchExpr) string {
+	pad := strings.Repeat(formatIndent, p.depth+1)
+	var b strings.Builder
+	b.WriteString("match " + p.expr(m.Subject, precAssignment) + " {\n")
+	for _, arm := range m.Arms {
+		head := "else"
+		if !arm.Default {
+			head = p.exprList(arm.Patterns)
+		}
+		b.WriteString(pad + head + " => ")
+
+		if !arm.Block && len(arm.Body) == 1 {
+			value := p.expr(arm.Body[0], precAssignment)
+			if _, isObject := arm.Body[0].(*ObjectLiteral); isObject {
+				value = "(" + value + ")" // a bare { would start a block
+			}
+			b.WriteString(value + "\n")
+			continue
+		}
+
+		inner := NewPrinter()
+		inner.depth = p.depth + 2
+		inner.printBody(arm.Body, false)
+		b.WriteString("{\n" + inner.builder.String() + pad + "}\n")
+	}
+	b.WriteString(strings.Repeat(formatIndent, p.depth) + "}")
+	return b.String()
+}
+
+func (p *Printer) inlineBody(fn *FunctionDeclaration) string {
+	if len(fn.Body) == 1 {
+		if ret, ok := fn.Body[0].(*ReturnExpr); ok {
+			return p.expr(ret.Value, precTernary)
+		}
+	}
+	return "undef"
+}
+
+func (p *Printer) exprList(exprs []Expression) string {
+	var parts []string
+	for _, expr := range exprs {
+		parts = append(parts, p.expr(expr, precAssignment))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// expr renders an expression, parenthesizing it when it binds looser than min
+func (p *Printer) expr(node Expression, min int) string {
+	text, prec := p.exprPrec(node)
+	if prec < min {
+		return "(" + text + ")"
+	}
+	return text
+}
+
+func (p *Printer) exprPrec(node Expression) (string, int) {
+	switch n := node.(type) {
+	case *Identifier:
+		return n.Value, precPrimary
+	case *NumericLiteral:
+		if n.Raw != "" {
+			return n.Raw, precPrimary
+		}
+		return strconv.FormatFloat(n.Value, 'g', -1, 64), precPrimary
+	case *StringLiteral:
+		if n.Raw != "" {
+			return n.Raw, precPrimary
+		}
+		return strconv.Quote(n.Value), precPrimary
+	case *BooleanLiteral:
+		return strconv.FormatBool(n.Value), precPrimary
+	case *UndefinedLiteral:
+		return "undef", precPrimary
+	case *NullLiteral:
+		return "null", precPrimary
+	case *ArrayLiteral:
+		return "[" + p.exprList(n.Elements) + "]", precPrimary
+	case *ObjectLiteral:
+		if len(n.Properties) == 0 {
+			return "{}", precPrimary
+		}
+		var props []string
+		for _, prop := range n.Properties {
+			if prop.Spread {
+				props = append(props, "..."+p.expr(prop.Value, precAssignment))
+				continue
+			}
+			key := prop.Key
+			if !isIdentifierName(key) {
+				key = strconv.Quote(key)
+			}
+			if ident, ok := prop.Value.(*Identifier); ok && ident.Value == prop.Key && key == prop.Key {
+				props = append(props, key)
+			} else {
+				props = append(props, key+": "+p.expr(prop.Value, precAssignment))
+			}
+		}
+		return "{ " + strings.Join(props, ", ") + " }", precPrimary
+	case *BinaryExpr:
+		prec := binaryPrecedence(n.Operator)
+		if n.Operator == "**" {
+			// Right-associative, and the exponent may be a unary expression
+			return p.expr(n.Left, prec+1) + " ** " + p.expr(n.Right, precUnary), prec
+		}
+		return p.expr(n.Left, prec) + " " + n.Operator + " " + p.expr(n.Right, prec+1), prec
+	case *LogicalExpr:
+		return p.expr(n.Left, precLogical) + " " + n.Operator + " " + p.expr(n.Right, precLogical+1), precLogical
+	case *EqualityExpr:
+		return p.expr(n.Left, precEquality) + " " + n.Operator + " " + p.expr(n.Right, precEquality+1), precEquality
+	case *InequalityExpr:
+		return p.expr(n.Left, precInequality) + " " + n.Operator + " " + p.expr(n.Right, precInequality+1), precInequality
+	case *RangeExpr:
+		operator := ".."
+		if n.Inclusive {
+			operator = "..="
+		}
+		return p.expr(n.Start, precBitwiseOr) + operator + p.expr(n.End, precBitwiseOr), precRange
+	case *UnaryExpr:
+		if operator, ok := strings.CutSuffix(n.Operator, "_post"); ok {
+			return p.expr(n.Value, precPostfix) + operator, precUnary
+		}
+		value := p.expr(n.Value, precUnary)
+		if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+			value = "(" + value + ")" // keep "- -x" from becoming "--x"
+		}
+		return n.Operator + value, precUnary
+	case *TypeofExpr:
+		return "typeof " + p.expr(n.Value, precUnary), precUnary
+	case *SpawnExpr:
+		return "spawn " + p.expr(n.Call, precPostfix), precUnary
+	case *AwaitExpr:
+		return "await " + p.expr(n.Value, precUnary), precUnary
+	case *AssignmentExpr:
+		return p.expr(n.Assigne, precTernary) + " " + n.Operator + "= " + p.expr(n.Value, precAssignment), precAssignment
+	case *ActionAssignmentExpr:
+		return p.expr(n.Assigne, precTernary) + ": " + n.Action.Name + " = " + p.expr(n.Value, precAssignment), precAssignment
+	case *TernaryExpr:
+		return p.expr(n.Condition, precLogical) + " ? " + p.expr(n.Consequent, precLogical) + " : " +
+			p.expr(n.Alternate, precTernary), precTernary
+	case *CallExpr:
+		if fn, ok := n.Caller.(*FunctionDeclaration); ok && fn.Name == "" && fn.Inline && len(fn.Parameters) == 0 && len(n.Args) == 0 {
+			return p.function(fn), precPostfix
+		}
+		args := p.exprList(n.Args)
+		for _, arg := range n.Named {
+			if args != "" {
+				args += ", "
+			}
+			args += arg.Name + "=" + p.expr(arg.Value, precAssignment)
+		}
+		return p.expr(n.Caller, precPostfix) + "(" + args + ")", precPostfix
+	case *MemberExpr:
+		object := p.expr(n.Object, precPrimary)
+		if _, ok := n.Object.(*MemberExpr); ok {
+			object, _ = p.exprPrec(n.Object)
+		}
+		if n.Computed {
+			return object + "[" + p.expr(n.Property, precAssignment) + "]", precPostfix
+		}
+		if n.Optional {
+			return object + "?." + p.expr(n.Property, precPrimary), precPostfix
+		}
+		return object + "." + p.expr(n.Property, precPrimary), precPostfix
+	case *SpreadElement:
+		return "..." + p.expr(n.Argument, precAssignment), precAssignment
+	case *MatchExpr:
+		return p.match(n), precPrimary
+	case *ArrayPattern:
+		var elements []string
+		for _, element := range n.Elements {
+			elements = append(elements, p.patternElement(element, false))
+		}
+		return "[" + strings.Join(elements, ", ") + "]", precPrimary
+	case *ObjectPattern:
+		var props []string
+		for _, element := range n.Properties {
+			props = append(props, p.patternElement(element, true))
+		}
+		return "{ " + strings.Join(props, ", ") + " }", precPrimary
+	case *SliceExpr:
+		object := p.expr(n.Object, precPrimary)
+		if _, ok := n.Object.(*MemberExpr); ok {
+			object, _ = p.exprPrec(n.Object)
+		}
+		var start, end string
+		if n.Start != nil {
+			start = p.expr(n.Start, precTernary)
+		}
+		if n.End != nil {
+			end = p.expr(n.End, precTernary)
+		}
+		return object + "[" + start + ":" + end + "]", precPostfix
+	case *FunctionDeclaration:
+		if n.Inline {
+			// An inline body extends as far as it can, so it binds loosest
+			return p.function(n), precAssignment
+		}
+		return p.function(n), precPrimary
+	default:
+		return fmt.Sprintf("<%s>", node.Kind()), precPrimary
+	}
+}
+
+func isIdentifierName(name string) bool {
+	if name == "" {
+		return false
+	}
+	tokens, err := NewTokenizer(name).Tokenize()
+	return err == nil && len(tokens) == 2 && tokens[0].Type == IDENTIFIER
+}
+
+// runFormat formats the given file, printing the result or writing it back with -w
+func runFormat(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: luna fmt <file> [-w]")
+		return
+	}
+
+	filename := args[0]
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error: Could not read file '%s': %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	formatted, err := FormatSource(string(data))
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+
+	if !hasFlag("-w", "--write") {
+		fmt.Print(formatted)
+		return
+	}
+
+	if err := os.WriteFile(filename, []byte(formatted), 0644); err != nil {
+		fmt.Printf("Error: Could not write file '%s': %v\n", filename, err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,156 @@
+package interp
+
+import (
+	"fmt"
+	"sort"
+)
+
+// levenshtein counts the single character insertions, deletions and
+// substitutions that turn a into b
+func levenshtein(a, b string) int {
+	x, y := []rune(a), []rune(b)
+	previous := make([]int, len(y)+1)
+	current := make([]int, len(y)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(x); i++ {
+		current[0] = i
+		for j := 1; j <= len(y); j++ {
+			cost := 1
+			if x[i-1] == y[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(y)]
+}
+
+// similarity is 1 for equal strings down to 0 for strings with nothing in common
+func similarity(a, b string) float64 {
+	longest := max(len([]rune(a)), len([]rune(b)))
+	if longest == 0 {
+		return 1
+	}
+	return 1 - float64(levenshtein(a, b))/float64(longest)
+}
+
+// closestMatch finds the candidate most similar to query, the first one on
+// ties, false when none reaches cutoff
+func closestMatch(query string, candidates []string, cutoff float64) (int, float64, bool) {
+	best, bestRatio := -1, cutoff
+	for i, candidate := range candidates {
+		if ratio := similarity(query, candidate); ratio > bestRatio || (best < 0 && ratio == bestRatio) {
+			best, bestRatio = i, ratio
+		}
+	}
+	return best, bestRatio, best >= 0
+}
+
+// suggestionCutoff is how similar a name must be to be suggested, lenght for length passes
+const suggestionCutoff = 0.6
+
+// didYouMean suggests the known name closest to a misspelled one, "" when none is close
+func didYouMean(name string, known []string) string {
+	sort.Strings(known) // the same suggestion every time
+	if i, _, ok := closestMatch(name, known, suggestionCutoff); ok && known[i] != name {
+		return fmt.Sprintf(", did you mean '%s'?", known[i])
+	}
+	return ""
+}
+
+// visibleNames lists the variables env can see
+func (env *Environment) visibleNames() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for current := env; current != nil; current = current.parent {
+		for name := range current.variables {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// fuzzyStrings reads the two string arguments of name
+func fuzzyStrings(name string, args []RuntimeValue) (string, string, error) {
+	if len(args) != 2 {
+		return "", "", fmt.Errorf("fuzzy.%s expects 2 arguments, got %d", name, len(args))
+	}
+	a, ok := args[0].(*StringValue)
+	b, ok2 := args[1].(*StringValue)
+	if !ok || !ok2 {
+		return "", "", fmt.Errorf("fuzzy.%s: arguments must be strings", name)
+	}
+	return a.Value, b.Value, nil
+}
+
+func createFuzzyObject() RuntimeValue {
+	fuzzyProps := make(map[string]RuntimeValue)
+
+	// fuzzy.distance(a, b) is the Levenshtein distance, counted in characters
+	fuzzyProps["distance"] = MakeNativeFunction("distance", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		a, b, err := fuzzyStrings("distance", args)
+		if err != nil {
+			return nil, err
+		}
+		return MakeInt(int64(levenshtein(a, b))), nil
+	})
+
+	// fuzzy.ratio(a, b) is the similarity from 0 to 1, 1 for equal strings
+	fuzzyProps["ratio"] = MakeNativeFunction("ratio", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		a, b, err := fuzzyStrings("ratio", args)
+		if err != nil {
+			return nil, err
+		}
+		return MakeNumber(similarity(a, b)), nil
+	})
+
+	// fuzzy.bestMatch(query, candidates, cutoff) is { match, ratio, index } for
+	// the closest candidate, undef when none has a ratio of at least cutoff (0 by default)
+	fuzzyProps["bestMatch"] = MakeNativeFunction("bestMatch", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("fuzzy.bestMatch expects 2 or 3 arguments, got %d", len(args))
+		}
+		query, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("fuzzy.bestMatch: query must be a string")
+		}
+		list, ok := args[1].(*ArrayValue)
+		if !ok {
+			return nil, fmt.Errorf("fuzzy.bestMatch: candidates must be an array of strings")
+		}
+		candidates := make([]string, len(list.Elements))
+		for i, element := range list.Elements {
+			candidate, ok := element.(*StringValue)
+			if !ok {
+				return nil, fmt.Errorf("fuzzy.bestMatch: candidates must be an array of strings")
+			}
+			candidates[i] = candidate.Value
+		}
+		cutoff := 0.0
+		if len(args) == 3 {
+			number, ok := toFloat(args[2])
+			if !ok || number < 0 || number > 1 {
+				return nil, fmt.Errorf("fuzzy.bestMatch: cutoff must be a number between 0 and 1")
+			}
+			cutoff = number
+		}
+
+		i, ratio, found := closestMatch(query.Value, candidates, cutoff)
+		if !found {
+			return MakeUndefined(), nil
+		}
+		return MakeObject(map[string]RuntimeValue{
+			"match": MakeString(candidates[i]),
+			"ratio": MakeNumber(ratio),
+			"index": MakeInt(int64(i)),
+		}), nil
+	})
+
+	return MakeObject(fuzzyProps)
+}
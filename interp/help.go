@@ -0,0 +1,211 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// help(value) describes a value at run time: the signature and ## doc
+// comment of a function, the description of a native, the members of a
+// module. Functions also answer a few questions about themselves:
+//
+//	fn.name      the declared name, "" for a lambda
+//	fn.params    the parameter names, "...rest" for a rest parameter
+//	fn.arity     how many arguments must be passed, defaults and rest aside
+//	fn.doc       the doc comment, or the description of a native
+//
+// Natives get their description from nativeDocs, by the path they are
+// reached at. Go code adding a module documents its functions with
+// RegisterDoc("db.query", "...") before registering it.
+
+// nativeDocs describes the natives, keyed by global name or module.member
+var nativeDocs = map[string]string{
+	"length":        "length(value) counts the items of a string, array, object, range, map or set",
+	"int":           "int(value) converts a number, numeric string or boolean to an int",
+	"float":         "float(value) converts a number, numeric string or boolean to a float",
+	"bigint":        "bigint(value) converts an integer or a string of digits to a bigint",
+	"bool":          "bool(value) is the truthiness of value",
+	"string":        "string(value) is value as text",
+	"format":        "format(template, values...) formats values with printf directives like %5d and %.2f",
+	"map":           "map(), map(pairs) or map(object) makes a map with number, string and boolean keys",
+	"set":           "set() or set(iterable) makes a set of distinct numbers, strings and booleans",
+	"range":         "range(end), range(start, end) or range(start, end, step) is a lazy range of numbers",
+	"typeof":        "typeof(value) names the type of value",
+	"serialize":     "serialize(value) encodes value as text that deserialize reads back",
+	"deserialize":   "deserialize(text) decodes text made by serialize",
+	"exit":          "exit(code) ends the program with an exit code, 0 by default",
+	"help":          "help(value) describes a function, a native or a module",
+	"secret":        "secret(value) wraps value so it is never printed",
+	"chan":          "chan(size) makes a channel for tasks, unbuffered without a size",
+	"group":         "group() collects tasks to wait for together",
+	"retry":         "retry(fn, options) calls fn again when it fails",
+	"ratelimit":     "ratelimit(fn, options) limits how often fn may be called",
+	"bench":         "bench(name, fn, iterations) times fn over many runs and reports ns/op",
+	"getPath":       "getPath(value, path) reads the value at a dotted path like 'a.b[0]'",
+	"setPath":       "setPath(value, path, new) stores new at a dotted path",
+	"paths":         "paths(value) lists the dotted paths of the leaves of value",
+	"assert.eq":     "assert.eq(actual, expected, message) fails with a diff unless the values are equal",
+	"assert.true":   "assert.true(value, message) fails unless value is truthy",
+	"assert.throws": "assert.throws(fn, message) fails unless fn fails, and is its error message",
+	"io.print":      "io.print(values...) prints values separated by spaces",
+	"io.inspect":    "io.inspect(value, options) prints value nested options.depth levels deep, options.items items of each and lines cut to options.width",
+	"io.input":      "io.input(prompt) reads a line from the terminal",
+	"io.time":       "io.time() is the milliseconds since the program started",
+	"math.abs":      "math.abs(x) is the absolute value of x",
+	"math.sqrt":     "math.sqrt(x) is the square root of x",
+	"math.pow":      "math.pow(x, y) is x to the power y",
+	"math.sin":      "math.sin(x) is the sine of x radians",
+	"math.cos":      "math.cos(x) is the cosine of x radians",
+	"math.tan":      "math.tan(x) is the tangent of x radians",
+	"math.floor":    "math.floor(x) rounds x down",
+	"math.ceil":     "math.ceil(x) rounds x up",
+	"math.round":    "math.round(x) rounds x to the nearest whole number",
+	"math.log":      "math.log(x) is the natural logarithm of x",
+	"math.exp":      "math.exp(x) is e to the power x",
+	"math.min":      "math.min(values...) is the smallest of values",
+	"math.max":      "math.max(values...) is the largest of values",
+	"math.random":   "math.random() is a random float in [0, 1)",
+}
+
+// RegisterDoc describes the native at path for help(), a global name or
+// module.function for the functions of a registered module
+func RegisterDoc(path, description string) {
+	nativeDocs[path] = description
+}
+
+// documentNatives gives the natives of env the descriptions of nativeDocs
+func documentNatives(env *Environment) {
+	for path, description := range nativeDocs {
+		name, member, isMember := strings.Cut(path, ".")
+		value := env.LookupVar(name)
+		if isMember {
+			object, ok := value.(*ObjectValue)
+			if !ok {
+				continue
+			}
+			value = object.Properties[member]
+		}
+		if native, ok := value.(*NativeFunctionValue); ok {
+			native.Doc = description
+		}
+	}
+}
+
+// functionProperty is fn.name, fn.params, fn.arity or fn.doc
+func functionProperty(fn RuntimeValue, key string) (RuntimeValue, bool) {
+	switch f := fn.(type) {
+	case *FunctionValue:
+		switch key {
+		case "name":
+			return MakeString(f.Name), true
+		case "params":
+			params := make([]RuntimeValue, len(f.Parameters))
+			for i, param := range f.Parameters {
+				params[i] = MakeString(parameterName(param))
+			}
+			return MakeArray(params), true
+		case "arity":
+			return MakeInt(int64(functionArity(f))), true
+		case "doc":
+			return MakeString(f.Doc), true
+		}
+	case *NativeFunctionValue:
+		switch key {
+		case "name":
+			return MakeString(f.Name), true
+		case "doc":
+			return MakeString(f.Doc), true
+		}
+	}
+	return nil, false
+}
+
+// parameterName is a parameter as listed by fn.params
+func parameterName(param Parameter) string {
+	if param.Rest {
+		return "..." + param.Label()
+	}
+	return param.Label()
+}
+
+// functionArity counts the parameters without a default before the rest parameter
+func functionArity(fn *FunctionValue) int {
+	arity := 0
+	for _, param := range fn.Parameters {
+		if param.Rest || param.DefaultValue != nil {
+			break
+		}
+		arity++
+	}
+	return arity
+}
+
+// functionSignature is the declaration line of fn, defaults written out
+func functionSignature(fn *FunctionValue) string {
+	parts := []string{"fn"}
+	if fn.Async {
+		parts = []string{"async", "fn"}
+	}
+	if fn.Name != "" {
+		parts = append(parts, fn.Name)
+	} else {
+		parts[len(parts)-1] = "lambda"
+	}
+	printer := NewPrinter()
+	for _, param := range fn.Parameters {
+		if param.DefaultValue != nil {
+			parts = append(parts, param.Label()+"=("+printer.expr(param.DefaultValue, precAssignment)+")")
+			continue
+		}
+		parts = append(parts, parameterName(param))
+	}
+	return strings.Join(parts, " ")
+}
+
+// helpText describes value for help()
+func helpText(value RuntimeValue) string {
+	var text strings.Builder
+	switch v := value.(type) {
+	case *FunctionValue:
+		text.WriteString(green(functionSignature(v)))
+		if v.Doc == "" {
+			text.WriteString("\n" + gray("    no doc comment"))
+			break
+		}
+		for _, line := range strings.Split(v.Doc, "\n") {
+			text.WriteString("\n    " + line)
+		}
+	case *NativeFunctionValue:
+		text.WriteString(green("native fn " + v.Name))
+		if v.Doc == "" {
+			text.WriteString("\n" + gray("    no description"))
+		} else {
+			text.WriteString("\n    " + v.Doc)
+		}
+	case *ObjectValue:
+		keys := slices.Clone(v.Keys())
+		slices.Sort(keys)
+		fmt.Fprintf(&text, "%s with %d members", green("object"), len(keys))
+		for _, key := range keys {
+			text.WriteString("\n    " + key)
+			if doc, ok := functionProperty(v.Properties[key], "doc"); ok {
+				if line, _, _ := strings.Cut(doc.(*StringValue).Value, "\n"); line != "" {
+					text.WriteString(gray(" - " + line))
+				}
+			}
+		}
+	default:
+		fmt.Fprintf(&text, "%s %s", green(string(value.Type())), value.String())
+	}
+	return text.String()
+}
+
+// helpNative is help(value), printing the description of value
+func helpNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("help expects 1 argument, got %d", len(args))
+	}
+	printOutput(helpText(args[0]))
+	return MakeVoid(), nil
+}
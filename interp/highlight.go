@@ -0,0 +1,276 @@
+package interp
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// `luna highlight file` classifies the tokens of a file the way the
+// tokenizer reads them, for editors and web playgrounds that highlight Luna:
+//
+//	luna highlight main.ln                 JSON, the default
+//	luna highlight --format=html main.ln   a <pre> with a span per token
+//
+// The JSON lists the tokens in order, the line and column counted from 1
+// and the length in characters, which for a multiline string or comment
+// runs over the line breaks:
+//
+//	{"file":"main.ln","tokens":[{"line":1,"column":1,"length":2,"type":"keyword","text":"fn"},...]}
+//
+// In HTML each token is a <span class="luna-TYPE">, the text between them
+// is kept as is. The types are
+//
+//	keyword        fn, if, for, match, and var or const in x: var = 1
+//	constant       true, false, undef and null
+//	string         every kind of string literal, interpolations included
+//	number         ints, floats and bigints
+//	comment        line, block and ## doc comments
+//	function-name  the name of a declared or called function
+//	builtin        a native like io, length or map
+//	property       a name after . or ?.
+//	variable       any other name
+//	operator       +, ==, &&, =, .. and the other operators
+//	punctuation    brackets, commas, dots and colons
+//
+// The REPL colors its input the same way as it is typed, see highlightStyles.
+
+// highlightToken is a classified stretch of source
+type highlightToken struct {
+	Position Position
+	Length   int // in runes
+	Type     string
+	Text     string
+}
+
+// highlightTokens classifies the tokens of code, comments included
+func highlightTokens(code string) ([]highlightToken, error) {
+	tokenizer := NewTokenizer(code)
+	tokenizer.keepComments = true
+	tokens, err := tokenizer.Tokenize()
+	if err != nil {
+		return nil, err
+	}
+
+	env := highlightNatives()
+	source := []rune(code)
+	var highlighted []highlightToken
+	for i, token := range tokens {
+		if token.Type == NEWLINE || token.Type == EOF {
+			continue
+		}
+
+		// A token runs up to the next one, whitespace aside, since its
+		// value is not always its text: strings lose their quotes and escapes
+		end := len(source)
+		if i+1 < len(tokens) {
+			end = min(tokens[i+1].Position.Index, len(source))
+		}
+		start := token.Position.Index
+		for end > start && unicode.IsSpace(source[end-1]) {
+			end--
+		}
+		if end <= start {
+			continue
+		}
+
+		highlighted = append(highlighted, highlightToken{
+			Position: token.Position,
+			Length:   end - start,
+			Type:     classifyToken(tokens, i, env),
+			Text:     string(source[start:end]),
+		})
+	}
+	return highlighted, nil
+}
+
+// highlightNatives is an environment with just the natives, to tell builtins apart
+var highlightNatives = sync.OnceValue(func() *Environment {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	return env
+})
+
+// highlightColors are the colors of the token types in the REPL, the other
+// types are left plain
+var highlightColors = map[string]string{
+	"keyword":       Magenta,
+	"constant":      Magenta,
+	"string":        Green,
+	"number":        Yellow,
+	"comment":       Gray,
+	"function-name": Blue,
+	"builtin":       Cyan,
+}
+
+// unclosedEnds are tried in turn to close code that is still being typed
+var unclosedEnds = []string{"", "\"", "'", "`", "*/"}
+
+// highlightStyles is the color of each rune of code, "" for plain text. An
+// unfinished string or comment is closed first so that it colors as one.
+func highlightStyles(code string) []string {
+	styles := make([]string, len([]rune(code)))
+	for _, end := range unclosedEnds {
+		tokens, err := highlightTokens(code + end)
+		if err != nil {
+			continue
+		}
+		for _, token := range tokens {
+			for i := token.Position.Index; i < min(token.Position.Index+token.Length, len(styles)); i++ {
+				styles[i] = highlightColors[token.Type]
+			}
+		}
+		break
+	}
+	return styles
+}
+
+// classifyToken is the highlight type of tokens[i]
+func classifyToken(tokens []Token, i int, env *Environment) string {
+	token := tokens[i]
+	if previous := nextToken(tokens, i, -1); (previous.Type == DOT || previous.Type == OPTIONAL_DOT) && token.Type != IDENTIFIER {
+		if _, isKeyword := keywords[token.Value]; isKeyword {
+			return classifyIdentifier(tokens, i, env)
+		}
+	}
+	switch {
+	case token.Type >= FN && token.Type <= AWAIT:
+		return "keyword"
+	case token.Type >= BINARY_OPERATOR && token.Type <= RANGE_INCLUSIVE:
+		return "operator"
+	case token.Type >= COMMA && token.Type <= OPTIONAL_DOT:
+		return "punctuation"
+	}
+
+	switch token.Type {
+	case BOOLEAN, UNDEFINED:
+		return "constant"
+	case STRING, RAW_STRING, TEMPLATE_STRING:
+		return "string"
+	case INT, FLOAT, BIGINT:
+		return "number"
+	case COMMENT, DOC_COMMENT:
+		return "comment"
+	case IDENTIFIER:
+		return classifyIdentifier(tokens, i, env)
+	}
+	return "variable"
+}
+
+// classifyIdentifier tells names apart by the tokens around them
+func classifyIdentifier(tokens []Token, i int, env *Environment) string {
+	previous, next := nextToken(tokens, i, -1), nextToken(tokens, i, 1)
+	switch {
+	case previous.Type == DOT || previous.Type == OPTIONAL_DOT:
+		if next.Type == OPEN_PAREN {
+			return "function-name"
+		}
+		return "property"
+	case previous.Type == COLON && next.Type == EQUALS && (tokens[i].Value == "var" || tokens[i].Value == "const"):
+		return "keyword"
+	case previous.Type == FN || previous.Type == LAMBDA:
+		return "function-name"
+	case tokens[i].Value == "null":
+		return "constant"
+	case next.Type == OPEN_PAREN:
+		return "function-name"
+	case env.HasVar(tokens[i].Value):
+		return "builtin"
+	}
+	return "variable"
+}
+
+// nextToken is the first token from tokens[i] in direction step that is
+// not a comment, an EOF token when there is none
+func nextToken(tokens []Token, i int, step int) Token {
+	for j := i + step; j >= 0 && j < len(tokens); j += step {
+		if tokens[j].Type != COMMENT && tokens[j].Type != DOC_COMMENT {
+			return tokens[j]
+		}
+	}
+	return Token{Type: EOF}
+}
+
+// highlightJSON is the JSON form of the tokens of file
+func highlightJSON(file string, tokens []highlightToken) (string, error) {
+	type jsonToken struct {
+		Line   int    `json:"line"`
+		Column int    `json:"column"`
+		Length int    `json:"length"`
+		Type   string `json:"type"`
+		Text   string `json:"text"`
+	}
+	out := struct {
+		File   string      `json:"file"`
+		Tokens []jsonToken `json:"tokens"`
+	}{File: file, Tokens: make([]jsonToken, len(tokens))}
+	for i, token := range tokens {
+		out.Tokens[i] = jsonToken{token.Position.Line + 1, token.Position.Column + 1, token.Length, token.Type, token.Text}
+	}
+	data, err := json.Marshal(out)
+	return string(data), err
+}
+
+// highlightHTML wraps each token of code in a span, keeping the text between them
+func highlightHTML(code string, tokens []highlightToken) string {
+	source := []rune(code)
+	var out strings.Builder
+	out.WriteString(`<pre class="luna"><code>`)
+	at := 0
+	for _, token := range tokens {
+		if token.Position.Index < at {
+			continue
+		}
+		out.WriteString(html.EscapeString(string(source[at:token.Position.Index])))
+		fmt.Fprintf(&out, `<span class="luna-%s">%s</span>`, token.Type, html.EscapeString(token.Text))
+		at = token.Position.Index + token.Length
+	}
+	out.WriteString(html.EscapeString(string(source[at:])))
+	out.WriteString("</code></pre>")
+	return out.String()
+}
+
+// runHighlight prints the classified tokens of a file
+func runHighlight(args []string) {
+	if len(args) == 0 {
+		fmt.Println("Usage: luna highlight [--format=json|html] <file>")
+		return
+	}
+
+	format, found := flagValue("--format")
+	if !found {
+		format = "json"
+	}
+	if format != "json" && format != "html" {
+		fmt.Println(formatError("Error", fmt.Sprintf("unknown format '%s', expected json or html", format)))
+		os.Exit(1)
+	}
+
+	filename := args[0]
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		fmt.Printf("Error: Could not read file '%s': %v\n", filename, err)
+		os.Exit(1)
+	}
+
+	tokens, err := highlightTokens(string(data))
+	if err != nil {
+		reportError(err, filename, filename+": ")
+		os.Exit(1)
+	}
+
+	if format == "html" {
+		fmt.Println(highlightHTML(string(data), tokens))
+		return
+	}
+	output, err := highlightJSON(filename, tokens)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	fmt.Println(output)
+}
@@ -0,0 +1,133 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// HTTPService runs a Luna handler function for every request
+type HTTPService struct {
+	handler RuntimeValue
+	env     *Environment
+	health  bool // serve /healthz and /metrics
+
+	requests   atomic.Int64
+	evalErrors atomic.Int64
+}
+
+// requestObject exposes a request to the Luna handler
+func requestObject(r *http.Request, body []byte) RuntimeValue {
+	headers := make(map[string]RuntimeValue)
+	for name := range r.Header {
+		headers[name] = MakeString(r.Header.Get(name))
+	}
+
+	return MakeObject(map[string]RuntimeValue{
+		"method":  MakeString(r.Method),
+		"path":    MakeString(r.URL.Path),
+		"query":   MakeString(r.URL.RawQuery),
+		"headers": MakeObject(headers),
+		"body":    MakeString(string(body)),
+	})
+}
+
+func (s *HTTPService) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if s.health {
+		switch r.URL.Path {
+		case "/healthz":
+			io.WriteString(w, "ok\n")
+			return
+		case "/metrics":
+			s.writeMetrics(w)
+			return
+		}
+	}
+
+	s.requests.Add(1)
+	body, _ := io.ReadAll(r.Body)
+
+	// Handlers run under the interpreter lock like spawned tasks
+	s.env.tasks.lock.Lock()
+	result, err := callValue(s.handler, []RuntimeValue{requestObject(r, body)}, s.env)
+	s.env.tasks.lock.Unlock()
+	if err != nil {
+		s.evalErrors.Add(1)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Handlers answer like mock routes: a body string or { status, body, headers }
+	response := mockResponse{status: http.StatusOK}
+	if result.Type() != VOID_TYPE && result.Type() != UNDEF_TYPE {
+		response, err = parseMockResponse(r.URL.Path, result)
+		if err != nil {
+			s.evalErrors.Add(1)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	for name, value := range response.headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(response.status)
+	io.WriteString(w, response.body)
+}
+
+// writeMetrics reports the interpreter stats in the Prometheus text format
+func (s *HTTPService) writeMetrics(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP luna_uptime_seconds Seconds since the interpreter started.")
+	fmt.Fprintln(w, "# TYPE luna_uptime_seconds gauge")
+	fmt.Fprintf(w, "luna_uptime_seconds %g\n", time.Since(startTime).Seconds())
+	fmt.Fprintln(w, "# HELP luna_requests_total Requests handled by the script.")
+	fmt.Fprintln(w, "# TYPE luna_requests_total counter")
+	fmt.Fprintf(w, "luna_requests_total %d\n", s.requests.Load())
+	fmt.Fprintln(w, "# HELP luna_eval_errors_total Requests whose handler failed.")
+	fmt.Fprintln(w, "# TYPE luna_eval_errors_total counter")
+	fmt.Fprintf(w, "luna_eval_errors_total %d\n", s.evalErrors.Load())
+}
+
+func createHTTPObject() RuntimeValue {
+	httpProps := make(map[string]RuntimeValue)
+
+	// http.serve(port, handler, { health: true }) blocks serving requests
+	httpProps["serve"] = MakeNativeFunction("serve", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 2 || len(args) > 3 {
+			return nil, fmt.Errorf("http.serve expects a port, a handler and optional options")
+		}
+		port, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("http.serve: port must be a number")
+		}
+		if args[1].Type() != FUNCTION_TYPE && args[1].Type() != NATIVE_FN_TYPE {
+			return nil, fmt.Errorf("http.serve: handler must be a function")
+		}
+
+		service := &HTTPService{handler: args[1], env: env}
+		if len(args) == 3 {
+			options, ok := args[2].(*ObjectValue)
+			if !ok {
+				return nil, fmt.Errorf("http.serve: options must be an object")
+			}
+			if health, exists := options.Properties["health"]; exists {
+				service.health = health.IsTruthy()
+			}
+		}
+
+		server := &http.Server{Addr: fmt.Sprintf(":%d", int(port)), Handler: service}
+		startConcurrency(env)
+		var err error
+		blockingCall(env, func() { err = server.ListenAndServe() })
+		if err != nil {
+			return nil, fmt.Errorf("http.serve: %v", err)
+		}
+		return MakeVoid(), nil
+	})
+
+	return MakeObject(httpProps)
+}
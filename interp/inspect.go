@@ -0,0 +1,225 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// summaryItems is how many elements of a large array or object are shown by default
+const summaryItems = 16
+
+// defaultInspectDepth is how many levels of nesting :inspect and :expand render
+const defaultInspectDepth = 2
+
+// inspectValue renders arrays and objects down to depth levels, showing at
+// most limit items of each (0 shows all of them)
+func inspectValue(value RuntimeValue, depth, limit, indent int) string {
+	return inspectNested(value, depth, limit, indent, nil)
+}
+
+// inspectNested is inspectValue with the arrays and objects being rendered
+// around value, which render as <circular> when they recur
+func inspectNested(value RuntimeValue, depth, limit, indent int, path []RuntimeValue) string {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return gray("<circular>")
+		}
+	}
+
+	switch v := value.(type) {
+	case *ArrayValue:
+		if len(v.Elements) == 0 {
+			return cyan("[]")
+		}
+		if depth <= 0 {
+			return cyan(fmt.Sprintf("Array(%d) ", len(v.Elements))) + gray("[…]")
+		}
+
+		count := len(v.Elements)
+		if limit > 0 && count > limit {
+			count = limit
+		}
+		elements := make([]string, count)
+		for i := range elements {
+			elements[i] = inspectNested(v.Elements[i], depth-1, limit, indent, path)
+		}
+
+		if count < len(v.Elements) {
+			return cyan(fmt.Sprintf("Array(%d) [", len(v.Elements))) + strings.Join(elements, ", ") + gray(", …") + cyan("]")
+		}
+		return cyan("[") + strings.Join(elements, ", ") + cyan("]")
+
+	case *ObjectValue:
+		if len(v.Properties) == 0 {
+			return gray("{}")
+		}
+		if depth <= 0 {
+			return gray(fmt.Sprintf("Object(%d) { … }", len(v.Properties)))
+		}
+
+		keys := v.Keys()
+		header := gray("{")
+		if limit > 0 && len(keys) > limit {
+			header = gray(fmt.Sprintf("Object(%d) {", len(keys)))
+			keys = keys[:limit]
+		}
+
+		pad := strings.Repeat("  ", indent+1)
+		var props []string
+		for _, key := range keys {
+			props = append(props, pad+blue(key)+": "+inspectNested(v.Properties[key], depth-1, limit, indent+1, path))
+		}
+		if hidden := len(v.Properties) - len(keys); hidden > 0 {
+			props = append(props, pad+gray(fmt.Sprintf("… %d more", hidden)))
+		}
+
+		return header + "\n" + strings.Join(props, ",\n") + "\n" + strings.Repeat("  ", indent) + gray("}")
+
+	default:
+		return colorizeValue(value, true, false)
+	}
+}
+
+// runInspectCommand handles the REPL commands
+//
+//	:inspect [--depth N] <expr>   summarized view, N levels deep
+//	:expand [--depth N] <expr>    the same without truncating large structures
+//
+// `_` in the expression refers to the last result. It reports whether input was a command.
+func runInspectCommand(input string, env *Environment, last RuntimeValue) bool {
+	command, rest, _ := strings.Cut(input, " ")
+	if command != ":inspect" && command != ":expand" {
+		return false
+	}
+
+	depth := defaultInspectDepth
+	rest = strings.TrimSpace(rest)
+	if after, ok := strings.CutPrefix(rest, "--depth"); ok {
+		fields := strings.Fields(after)
+		if len(fields) == 0 {
+			fmt.Println(formatError("Error", "--depth expects a number"))
+			return true
+		}
+		n, err := strconv.Atoi(fields[0])
+		if err != nil || n < 0 {
+			fmt.Println(formatError("Error", "--depth expects a non-negative number"))
+			return true
+		}
+		depth = n
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(after), fields[0]))
+	}
+	if rest == "" {
+		rest = "_"
+	}
+
+	scope := NewEnvironment(env)
+	if last != nil {
+		scope.DeclareVar("_", last, false)
+	}
+	value, err := NewLuna(scope).Evaluate(rest)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		return true
+	}
+
+	limit := summaryItems
+	if command == ":expand" {
+		limit = 0
+	}
+	fmt.Println(inspectValue(value, depth, limit, 0))
+	return true
+}
+
+// InspectOptions configure io.inspect
+type InspectOptions struct {
+	Depth int // levels of nesting shown
+	Items int // items shown of each array and object, 0 for all of them
+	Width int // characters of a line before it is cut with …, 0 for no limit
+}
+
+var defaultInspectOptions = InspectOptions{Depth: defaultInspectDepth, Items: summaryItems}
+
+func parseInspectOptions(value RuntimeValue) (InspectOptions, error) {
+	options := defaultInspectOptions
+	object, ok := value.(*ObjectValue)
+	if !ok {
+		return options, fmt.Errorf("io.inspect: options must be an object")
+	}
+
+	for _, name := range object.Keys() {
+		number, ok := toFloat(object.Properties[name])
+		if !ok || number < 0 || number != float64(int(number)) {
+			return options, fmt.Errorf("io.inspect: %s must be a non-negative integer", name)
+		}
+		switch name {
+		case "depth":
+			options.Depth = int(number)
+		case "items":
+			options.Items = int(number)
+		case "width":
+			options.Width = int(number)
+		default:
+			return options, fmt.Errorf("io.inspect: unknown option '%s'", name)
+		}
+	}
+	return options, nil
+}
+
+// inspectNative is io.inspect(value, options), which prints value the way
+// :inspect does, each line cut to options.width
+func inspectNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("io.inspect expects a value and optional options")
+	}
+	options := defaultInspectOptions
+	if len(args) == 2 {
+		var err error
+		if options, err = parseInspectOptions(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	output := inspectValue(args[0], options.Depth, options.Items, 0)
+	if options.Width > 0 {
+		lines := strings.Split(output, "\n")
+		for i, line := range lines {
+			lines[i] = truncateColored(line, options.Width)
+		}
+		output = strings.Join(lines, "\n")
+	}
+	if !colorsEnabled(env) {
+		output = stripColor(output)
+	}
+	printOutput(output)
+	return MakeVoid(), nil
+}
+
+// truncateColored is truncateText for text with ANSI colors, which are kept
+// and not counted
+func truncateColored(text string, width int) string {
+	if len([]rune(stripColor(text))) <= width {
+		return text
+	}
+
+	var out strings.Builder
+	visible := 0
+	for i := 0; i < len(text); {
+		if match := ansiEscape.FindStringIndex(text[i:]); match != nil && match[0] == 0 {
+			out.WriteString(text[i : i+match[1]])
+			i += match[1]
+			continue
+		}
+		if visible == width-1 {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(text[i:])
+		out.WriteRune(r)
+		visible++
+		i += size
+	}
+	return out.String() + "…" + Reset
+}
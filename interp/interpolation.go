@@ -0,0 +1,205 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// String interpolation: "n = {n + 1}" evaluates the expression between the
+// braces and inserts its value. The expressions are parsed with the rest of
+// the program, so a mistake inside a string is a syntax error with its
+// position, not a failure halfway through printing. "{{" and "}}" stand for
+// literal braces, a lone "}" is kept as is. A format spec may follow the
+// expression after a colon, "{total:.2f}", see printf.go.
+
+// parseStringLiteral splits a string token into its literal text and the
+// expressions interpolated in it
+func (p *Parser) parseStringLiteral(token Token) (*StringLiteral, error) {
+	literal := &StringLiteral{Value: token.Value, Raw: p.rawString(token)}
+	if !strings.ContainsAny(token.Value, "{}") {
+		return literal, nil
+	}
+
+	runes := []rune(token.Value)
+	var text strings.Builder
+	var parts []Expression
+	var specs []formatSpec
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '{' && i+1 < len(runes) && runes[i+1] == '{':
+			text.WriteRune('{')
+			i++
+		case runes[i] == '}' && i+1 < len(runes) && runes[i+1] == '}':
+			text.WriteRune('}')
+			i++
+		case runes[i] == '{':
+			end := interpolationEnd(runes, i)
+			brace := Token{Type: STRING, Value: "{", Position: interpolationPosition(token, runes, i)}
+			if end < 0 {
+				return nil, p.formatError("unclosed '{' in string, use '{{' for a literal brace", brace)
+			}
+
+			code, specText := splitFormatSpec(string(runes[i+1 : end]))
+			expr, err := p.parseInterpolation(code, brace)
+			if err != nil {
+				return nil, err
+			}
+			spec := formatSpec{width: -1, precision: -1}
+			if specText != "" {
+				if spec, err = parseFormatSpec(specText); err != nil {
+					return nil, p.formatError(err.Error(), brace)
+				}
+			}
+			if text.Len() > 0 {
+				parts = append(parts, &StringLiteral{Value: text.String()})
+				specs = append(specs, formatSpec{width: -1, precision: -1})
+				text.Reset()
+			}
+			parts = append(parts, expr)
+			specs = append(specs, spec)
+			i = end
+		default:
+			text.WriteRune(runes[i])
+		}
+	}
+
+	if parts == nil {
+		// Only escaped braces, the string is plain text after all
+		literal.Value = text.String()
+		return literal, nil
+	}
+	if text.Len() > 0 {
+		parts = append(parts, &StringLiteral{Value: text.String()})
+		specs = append(specs, formatSpec{width: -1, precision: -1})
+	}
+	literal.Parts = parts
+	if slices.ContainsFunc(specs, formatSpec.given) {
+		literal.Specs = specs
+	}
+	return literal, nil
+}
+
+// interpolationPosition is where the '{' at runes[i] is in the source. The
+// text of a template string is the source itself, lines included, other
+// strings are taken as one line since escapes change their length anyway.
+func interpolationPosition(token Token, runes []rune, i int) Position {
+	position := token.Position
+	if token.Type != TEMPLATE_STRING {
+		position.Column += i + 1 // after the opening quote
+		position.Index += i + 1
+		return position
+	}
+
+	position.Index += i + 2 // after the f and the backtick
+	lastLine := slices.Index(runes[:i], '\n')
+	if lastLine < 0 {
+		position.Column += i + 2
+		return position
+	}
+	for j := lastLine; j < i; j++ {
+		if runes[j] == '\n' {
+			position.Line++
+			lastLine = j
+		}
+	}
+	position.Column = i - lastLine - 1
+	return position
+}
+
+// interpolationEnd finds the '}' closing the '{' at start, skipping nested
+// braces and quoted strings, or -1 when there is none
+func interpolationEnd(runes []rune, start int) int {
+	depth := 0
+	var quote rune
+	for i := start; i < len(runes); i++ {
+		switch char := runes[i]; {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '{':
+			depth++
+		case char == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// parseInterpolation parses the code between braces as one expression, its
+// tokens are moved to where they are in the source so errors point there
+func (p *Parser) parseInterpolation(code string, brace Token) (Expression, error) {
+	if strings.TrimSpace(code) == "" {
+		return nil, p.formatError("empty interpolation '{}' in string, use '{{}}' for literal braces", brace)
+	}
+
+	tokens, err := NewTokenizer(code).Tokenize()
+	if err != nil {
+		var syntax *SyntaxError
+		if errors.As(err, &syntax) {
+			err = errors.New(syntax.Message)
+		}
+		return nil, p.formatError(fmt.Sprintf("invalid interpolation '{%s}': %v", code, err), brace)
+	}
+	for i := range tokens {
+		if tokens[i].Position.Line == 0 {
+			tokens[i].Position.Column += brace.Position.Column + 1
+		}
+		tokens[i].Position.Line += brace.Position.Line
+		tokens[i].Position.Index += brace.Position.Index + 1
+	}
+
+	inner := NewParser(tokens, p.code)
+	expr, err := inner.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if !inner.isEOF() {
+		return nil, inner.formatError(fmt.Sprintf("unexpected '%s' in interpolation", inner.at().Value), inner.at())
+	}
+	return expr, nil
+}
+
+// evaluateStringLiteral joins the text of a string with the values interpolated in it
+func evaluateStringLiteral(node *StringLiteral, env *Environment) (RuntimeValue, error) {
+	if value, ok := constantValue(node); ok {
+		return env.counted(value)
+	}
+
+	var result strings.Builder
+	for i, part := range node.Parts {
+		value, err := Evaluate(part, env)
+		if err != nil {
+			return nil, err
+		}
+		text, err := partText(node, i, value)
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(text)
+	}
+	return env.counted(MakeString(result.String()))
+}
+
+// partText is the text of the i-th part of node, formatted by its spec if it has one
+func partText(node *StringLiteral, i int, value RuntimeValue) (string, error) {
+	if node.Specs == nil || !node.Specs[i].given() {
+		return interpolatedText(value), nil
+	}
+	return formatValue(value, node.Specs[i])
+}
+
+// interpolatedText is how a value reads inside a string, strings without quotes
+func interpolatedText(value RuntimeValue) string {
+	if str, ok := value.(*StringValue); ok {
+		return str.Value
+	}
+	return value.String()
+}
@@ -0,0 +1,1771 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+)
+
+func Evaluate(node Statement, env *Environment) (RuntimeValue, error) {
+	switch n := node.(type) {
+	case *Program:
+		return evaluateProgram(n, env)
+	case *NumericLiteral:
+		value, _ := constantValue(n)
+		return value, nil
+	case *StringLiteral:
+		return evaluateStringLiteral(n, env)
+	case *BooleanLiteral:
+		return MakeBool(n.Value), nil
+	case *UndefinedLiteral:
+		return MakeUndefined(), nil
+	case *NullLiteral:
+		return MakeNull(), nil
+	case *Identifier:
+		return evaluateIdentifier(n, env)
+	case *ArrayLiteral:
+		return evaluateArrayLiteral(n, env)
+	case *ObjectLiteral:
+		return evaluateObjectLiteral(n, env)
+	case *BinaryExpr:
+		return evaluateBinaryExpression(n, env)
+	case *UnaryExpr:
+		return evaluateUnaryExpression(n, env)
+	case *AssignmentExpr:
+		return evaluateAssignmentExpression(n, env)
+	case *ActionAssignmentExpr:
+		return evaluateActionAssignmentExpression(n, env)
+	case *CallExpr:
+		return evaluateCallExpression(n, env)
+	case *MemberExpr:
+		return evaluateMemberExpression(n, env)
+	case *SliceExpr:
+		return evaluateSliceExpression(n, env)
+	case *RangeExpr:
+		return evaluateRangeExpression(n, env)
+	case *MatchExpr:
+		return evaluateMatchExpression(n, env)
+	case *SpawnExpr:
+		return evaluateSpawnExpression(n, env)
+	case *AwaitExpr:
+		return evaluateAwaitExpression(n, env)
+	case *SpreadElement:
+		return nil, fmt.Errorf("spread syntax is only allowed in calls, arrays and objects")
+	case *TernaryExpr:
+		return evaluateTernaryExpression(n, env)
+	case *TypeofExpr:
+		return evaluateTypeofExpression(n, env)
+	case *EqualityExpr:
+		return evaluateEqualityExpression(n, env)
+	case *InequalityExpr:
+		return evaluateInequalityExpression(n, env)
+	case *LogicalExpr:
+		return evaluateLogicalExpression(n, env)
+	case *FunctionDeclaration:
+		return evaluateFunctionDeclaration(n, env)
+	case *IfStatement:
+		return evaluateIfStatement(n, env)
+	case *WhileStatement:
+		return evaluateWhileStatement(n, env)
+	case *DoWhileStatement:
+		return evaluateDoWhileStatement(n, env)
+	case *ForStatement:
+		return evaluateForStatement(n, env)
+	case *ForInStatement:
+		return evaluateForInStatement(n, env)
+	case *ReturnExpr:
+		value, err := Evaluate(n.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		return MakeReturn(value), nil
+	case *DebugStatement:
+		return evaluateDebugStatement(n, env)
+	case *Comment:
+		return nil, nil
+	case *UseStatement:
+		return evaluateUseStatement(n, env)
+	default:
+		return nil, fmt.Errorf("unsupported AST node: %T", node)
+	}
+}
+
+func evaluateProgram(program *Program, env *Environment) (RuntimeValue, error) {
+	var lastEvaluated RuntimeValue = MakeVoid()
+
+	for _, statement := range program.Body {
+		result, err := evaluateStatement(statement, env)
+		if err != nil {
+			return nil, err
+		}
+		if result != nil {
+			lastEvaluated = result
+		}
+	}
+
+	return lastEvaluated, nil
+}
+
+// evaluateIdentifier reads a variable, a name never declared is undef
+// except in strict modules where it is an error
+func evaluateIdentifier(node *Identifier, env *Environment) (RuntimeValue, error) {
+	if env.Strict() && !env.HasVar(node.Value) {
+		return nil, fmt.Errorf("undefined variable: %s%s", node.Value, didYouMean(node.Value, env.visibleNames()))
+	}
+	return env.LookupVar(node.Value), nil
+}
+
+// evaluateElements evaluates a list of expressions, expanding ...array spreads
+func evaluateElements(nodes []Expression, env *Environment) ([]RuntimeValue, error) {
+	values := make([]RuntimeValue, 0, len(nodes))
+	for _, node := range nodes {
+		spread, isSpread := node.(*SpreadElement)
+		if !isSpread {
+			value, err := Evaluate(node, env)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+			continue
+		}
+
+		value, err := Evaluate(spread.Argument, env)
+		if err != nil {
+			return nil, err
+		}
+		switch spreaded := value.(type) {
+		case *ArrayValue:
+			values = append(values, spreaded.Elements...)
+		case *RangeValue:
+			for i := 0; i < spreaded.Len(); i++ {
+				values = append(values, spreaded.Item(i))
+			}
+		default:
+			return nil, fmt.Errorf("cannot spread %s, expected an array", value.Type())
+		}
+	}
+	return values, nil
+}
+
+func evaluateArrayLiteral(node *ArrayLiteral, env *Environment) (RuntimeValue, error) {
+	elements, err := evaluateElements(node.Elements, env)
+	if err != nil {
+		return nil, err
+	}
+	return env.counted(MakeArray(elements))
+}
+
+func evaluateObjectLiteral(node *ObjectLiteral, env *Environment) (RuntimeValue, error) {
+	result := newObject()
+	for _, prop := range node.Properties {
+		value, err := Evaluate(prop.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if prop.Spread {
+			object, ok := value.(*ObjectValue)
+			if !ok {
+				return nil, fmt.Errorf("cannot spread %s into an object", value.Type())
+			}
+			for _, key := range object.Keys() {
+				result.Set(key, object.Properties[key])
+			}
+			continue
+		}
+		result.Set(prop.Key, value)
+	}
+	return env.counted(result)
+}
+
+func evaluateBinaryExpression(node *BinaryExpr, env *Environment) (RuntimeValue, error) {
+	if value, ok := constantValue(node); ok {
+		return env.counted(value)
+	}
+
+	left, err := Evaluate(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := Evaluate(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := evaluateBinaryOperation(left, right, node.Operator)
+	if err != nil {
+		return nil, err
+	}
+	return env.counted(result)
+}
+
+// The operators of evaluateBinaryOperation accept:
+//
+//	number op number   arithmetic, dividing by zero gives ±Inf or NaN like IEEE 754
+//	number op number   the bitwise & | ^ << >>, on whole numbers only
+//	string + any       concatenation, the other side is converted with String
+//
+// Every other combination is an error. Nothing is coerced implicitly:
+// booleans, null and undef never act as numbers, and the message suggests
+// the explicit conversion that was probably meant.
+func evaluateBinaryOperation(left, right RuntimeValue, operator string) (RuntimeValue, error) {
+	// Handle numeric operations, see numbers.go for ints and floats
+	if a, ok := left.(*IntValue); ok {
+		if b, ok := right.(*IntValue); ok {
+			if result, handled, err := evaluateIntOperation(a.Value, b.Value, operator); handled {
+				return result, err
+			}
+		}
+	}
+	if a, ok := toBig(left); ok && (left.Type() == BIGINT_TYPE || right.Type() == BIGINT_TYPE) {
+		if b, ok := toBig(right); ok {
+			if result, handled, err := evaluateBigOperation(a, b, operator); handled {
+				return result, err
+			}
+		}
+	}
+	if isNumeric(left.Type()) && isNumeric(right.Type()) {
+		if result, handled, err := evaluateBitwiseOperation(left, right, operator); handled {
+			return result, err
+		}
+	}
+	if leftVal, ok := toFloat(left); ok {
+		if rightVal, ok := toFloat(right); ok {
+			if result, handled, err := evaluateFloatOperation(leftVal, rightVal, operator); handled {
+				return result, err
+			}
+		}
+	}
+
+	// Handle string concatenation
+	if operator == "+" && (left.Type() == STRING_TYPE || right.Type() == STRING_TYPE) {
+		leftStr := left.String()
+		rightStr := right.String()
+		if left.Type() == STRING_TYPE {
+			leftStr = left.(*StringValue).Value
+		}
+		if right.Type() == STRING_TYPE {
+			rightStr = right.(*StringValue).Value
+		}
+		return MakeString(leftStr + rightStr), nil
+	}
+
+	return nil, binaryOperationError(left, right, operator)
+}
+
+var operatorVerbs = map[string]string{
+	"+":  "add",
+	"-":  "subtract",
+	"*":  "multiply",
+	"/":  "divide",
+	"//": "divide",
+	"%":  "take the remainder of",
+	"**": "raise",
+	"&":  "apply & to",
+	"|":  "apply | to",
+	"^":  "apply ^ to",
+	"<<": "shift",
+	">>": "shift",
+}
+
+// binaryOperationError explains why two operands cannot be combined and what was likely intended
+func binaryOperationError(left, right RuntimeValue, operator string) error {
+	verb, known := operatorVerbs[operator]
+	if !known {
+		return fmt.Errorf("unsupported binary operator: %s", operator)
+	}
+	message := fmt.Sprintf("cannot %s %s and %s", verb, left.Type(), right.Type())
+
+	has := func(t ValueType) bool { return left.Type() == t || right.Type() == t }
+	hasNumber := isNumeric(left.Type()) || isNumeric(right.Type())
+	hint := ""
+	switch {
+	case has(NULL_TYPE) || has(UNDEF_TYPE) || has(VOID_TYPE):
+		hint = "a value is missing, check that it was assigned"
+	case has(BOOLEAN_TYPE) && hasNumber:
+		hint = "use int(b) if intended"
+	case operator == "*" && has(STRING_TYPE) && hasNumber:
+		hint = "use s.repeat(n) to repeat a string"
+	case has(STRING_TYPE) && hasNumber:
+		hint = "use int(s) or float(s) to convert the string"
+	case operator == "+" && left.Type() == ARRAY_TYPE && right.Type() == ARRAY_TYPE:
+		hint = "use a.concat(b) to join arrays"
+	case operator == "+" && left.Type() == OBJECT_TYPE && right.Type() == OBJECT_TYPE:
+		hint = "use a.merge(b) to combine objects"
+	}
+	if hint != "" {
+		message += "; " + hint
+	}
+	return errors.New(message)
+}
+
+func evaluateUnaryExpression(node *UnaryExpr, env *Environment) (RuntimeValue, error) {
+	// Handle postfix increment/decrement
+	if node.Operator == "++_post" || node.Operator == "--_post" {
+		// Only valid on identifiers
+		ident, ok := node.Value.(*Identifier)
+		if !ok {
+			return nil, fmt.Errorf("postfix operator only valid on identifiers")
+		}
+		val := env.LookupVar(ident.Value)
+		if val == nil || !isNumeric(val.Type()) {
+			return nil, fmt.Errorf("cannot apply %s to non-number variable", node.Operator[:2])
+		}
+		newVal, err := evaluateBinaryOperation(val, MakeInt(1), node.Operator[:1])
+		if err != nil {
+			return nil, err
+		}
+		env.AssignVar(ident.Value, newVal)
+		return val, nil // Return old value (postfix)
+	}
+
+	// Prefix unary
+	switch node.Operator {
+	case "!", "-", "+", "~":
+		if value, ok := constantValue(node); ok {
+			return value, nil
+		}
+		value, err := Evaluate(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		return evaluateUnaryOperation(value, node.Operator, env)
+	case "++":
+		ident, ok := node.Value.(*Identifier)
+		if !ok {
+			return nil, fmt.Errorf("prefix ++ only valid on identifiers")
+		}
+		val := env.LookupVar(ident.Value)
+		if val == nil || !isNumeric(val.Type()) {
+			return nil, fmt.Errorf("cannot increment non-number variable")
+		}
+		newVal, err := evaluateBinaryOperation(val, MakeInt(1), "+")
+		if err != nil {
+			return nil, err
+		}
+		env.AssignVar(ident.Value, newVal)
+		return newVal, nil // Return new value (prefix)
+	case "--":
+		ident, ok := node.Value.(*Identifier)
+		if !ok {
+			return nil, fmt.Errorf("prefix -- only valid on identifiers")
+		}
+		val := env.LookupVar(ident.Value)
+		if val == nil || !isNumeric(val.Type()) {
+			return nil, fmt.Errorf("cannot decrement non-number variable")
+		}
+		newVal, err := evaluateBinaryOperation(val, MakeInt(1), "-")
+		if err != nil {
+			return nil, err
+		}
+		env.AssignVar(ident.Value, newVal)
+		return newVal, nil // Return new value (prefix)
+	}
+
+	return nil, fmt.Errorf("unsupported unary operator: %s", node.Operator)
+}
+
+// evaluateUnaryOperation applies one of the prefix operators ! - + ~ to a
+// value, env decides how ! reads a value that is not a boolean
+func evaluateUnaryOperation(value RuntimeValue, operator string, env *Environment) (RuntimeValue, error) {
+	switch operator {
+	case "!":
+		truthy, err := conditionValue(value, env)
+		if err != nil {
+			return nil, err
+		}
+		return MakeBool(!truthy), nil
+	case "-":
+		if isNumeric(value.Type()) {
+			return evaluateBinaryOperation(MakeInt(0), value, "-")
+		}
+		return nil, fmt.Errorf("cannot negate non-number value")
+	case "+":
+		if isNumeric(value.Type()) {
+			return value, nil
+		}
+		return nil, fmt.Errorf("cannot apply unary plus to non-number value")
+	case "~":
+		if !isNumeric(value.Type()) {
+			return nil, fmt.Errorf("cannot apply ~ to non-number value")
+		}
+		if bigint, ok := value.(*BigIntValue); ok {
+			return MakeBigInt(new(big.Int).Not(bigint.Value)), nil
+		}
+		n, err := bitwiseOperand("~", value)
+		if err != nil {
+			return nil, err
+		}
+		return MakeInt(^n), nil
+	}
+	return nil, fmt.Errorf("unsupported unary operator: %s", operator)
+}
+
+// numericLiteralValue is the int, float or bigint a number literal stands for
+func numericLiteralValue(n *NumericLiteral) RuntimeValue {
+	if n.Big != nil {
+		return MakeBigInt(n.Big)
+	}
+	if n.IsInt {
+		return MakeInt(n.Int)
+	}
+	return MakeNumber(n.Value)
+}
+
+// bindPattern destructures value into the names of pattern, using declare to bind each one
+func bindPattern(pattern Expression, value RuntimeValue, env *Environment, declare func(name string, value RuntimeValue)) error {
+	switch target := pattern.(type) {
+	case *Identifier:
+		declare(target.Value, value)
+		return nil
+
+	case *ArrayPattern:
+		array, ok := value.(*ArrayValue)
+		if !ok {
+			return fmt.Errorf("cannot destructure %s as an array", value.Type())
+		}
+		for i, element := range target.Elements {
+			if element.Rest {
+				rest := []RuntimeValue{}
+				if i < len(array.Elements) {
+					rest = append(rest, array.Elements[i:]...)
+				}
+				declare(element.Target.(*Identifier).Value, MakeArray(rest))
+				break
+			}
+
+			var item RuntimeValue = MakeUndefined()
+			if i < len(array.Elements) {
+				item = array.Elements[i]
+			}
+			if err := bindPatternElement(element, item, env, declare); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ObjectPattern:
+		object, ok := value.(*ObjectValue)
+		if !ok {
+			return fmt.Errorf("cannot destructure %s as an object", value.Type())
+		}
+		taken := make(map[string]bool)
+		for _, element := range target.Properties {
+			if element.Rest {
+				rest := newObject()
+				for _, key := range object.Keys() {
+					if !taken[key] {
+						rest.Set(key, object.Properties[key])
+					}
+				}
+				declare(element.Target.(*Identifier).Value, rest)
+				break
+			}
+
+			taken[element.Key] = true
+			item, exists := object.Properties[element.Key]
+			if !exists {
+				item = MakeUndefined()
+			}
+			if err := bindPatternElement(element, item, env, declare); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("invalid destructuring target")
+}
+
+func bindPatternElement(element PatternElement, value RuntimeValue, env *Environment, declare func(name string, value RuntimeValue)) error {
+	if value.Type() == UNDEF_TYPE && element.DefaultValue != nil {
+		defaultValue, err := Evaluate(element.DefaultValue, env)
+		if err != nil {
+			return err
+		}
+		value = defaultValue
+	}
+	return bindPattern(element.Target, value, env, declare)
+}
+
+func evaluateAssignmentExpression(node *AssignmentExpr, env *Environment) (RuntimeValue, error) {
+	switch node.Assigne.(type) {
+	case *ArrayPattern, *ObjectPattern:
+		value, err := Evaluate(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		var strictErr error
+		err = bindPattern(node.Assigne, value, env, func(name string, value RuntimeValue) {
+			if err := checkStrictAssignment(name, env); err != nil {
+				if strictErr == nil {
+					strictErr = err
+				}
+				return
+			}
+			if env.HasVar(name) {
+				env.AssignVar(name, value)
+			} else {
+				env.DeclareVar(name, value, false)
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		if strictErr != nil {
+			return nil, strictErr
+		}
+		return value, nil
+	}
+
+	if identifier, ok := node.Assigne.(*Identifier); ok {
+		var current RuntimeValue
+		if node.Operator != "" {
+			var err error
+			if current, err = Evaluate(identifier, env); err != nil {
+				return nil, err
+			}
+		}
+		value, err := Evaluate(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		if current != nil {
+			if value, err = compoundValue(current, value, node.Operator, env); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkStrictAssignment(identifier.Value, env); err != nil {
+			return nil, err
+		}
+
+		// Fix: Check if variable exists in current or parent environment
+		// If it exists, assign to existing variable instead of creating new one
+		if env.HasVar(identifier.Value) {
+			return env.AssignVar(identifier.Value, value), nil
+		} else {
+			return env.DeclareVar(identifier.Value, value, false), nil
+		}
+	} else if memberExpr, ok := node.Assigne.(*MemberExpr); ok {
+		object, err := Evaluate(memberExpr.Object, env)
+		if err != nil {
+			return nil, err
+		}
+
+		// a?.b = v does nothing when a is null or undef, strict modules refuse it
+		if memberExpr.Optional && isNullish(object) {
+			if env.Strict() {
+				return nil, fmt.Errorf("cannot set a property of %s", object.Type())
+			}
+			return MakeUndefined(), nil
+		}
+
+		var property RuntimeValue
+		if memberExpr.Property.Kind() == IDENTIFIER_NODE && !memberExpr.Computed {
+			ident := memberExpr.Property.(*Identifier)
+			property = MakeString(ident.Value)
+		} else {
+			prop, err := Evaluate(memberExpr.Property, env)
+			if err != nil {
+				return nil, err
+			}
+			property = prop
+		}
+
+		var key string
+		var keyInt int
+		number, isNumber := toFloat(property)
+		if str, ok := property.(*StringValue); ok {
+			key = str.Value
+		} else if isNumber {
+			keyInt = int(number)
+			numVal := fmt.Sprint(keyInt)
+			key = numVal
+		} else {
+			return nil, fmt.Errorf("invalid property key type")
+		}
+
+		// is it object or array
+		if object.Type() == OBJECT_TYPE {
+			objectVal := object.(*ObjectValue)
+			if objectVal.Frozen {
+				return nil, fmt.Errorf("cannot assign to property '%s' of a frozen object", key)
+			}
+			current, exists := objectVal.Properties[key]
+			if !exists {
+				current = MakeUndefined()
+			}
+			value, err := evaluateAssignedValue(node, current, env)
+			if err != nil {
+				return nil, err
+			}
+			objectVal.Set(key, value)
+			return value, nil
+		} else if object.Type() == ARRAY_TYPE {
+			arrayVal := object.(*ArrayValue)
+			if !isNumber {
+				return nil, fmt.Errorf("array index must be a number, got %s", property.Type())
+			}
+			index, ok := resolveIndex(number, len(arrayVal.Elements))
+			if !ok {
+				return nil, fmt.Errorf("array index %d out of range (length %d)", keyInt, len(arrayVal.Elements))
+			}
+			value, err := evaluateAssignedValue(node, arrayVal.Elements[index], env)
+			if err != nil {
+				return nil, err
+			}
+			arrayVal.Elements[index] = value
+			return value, nil
+		} else {
+			return nil, fmt.Errorf("cannot assign to non-object (%s)", object.Type())
+		}
+	}
+
+	return nil, fmt.Errorf("invalid assignment target")
+}
+
+// evaluateAssignedValue evaluates the value a member assignment stores,
+// combined with the current value for compound forms like a[i] += 1
+func evaluateAssignedValue(node *AssignmentExpr, current RuntimeValue, env *Environment) (RuntimeValue, error) {
+	value, err := Evaluate(node.Value, env)
+	if err != nil || node.Operator == "" {
+		return value, err
+	}
+	return compoundValue(current, value, node.Operator, env)
+}
+
+// compoundValue is what x op= value stores, the current value of x combined with value
+func compoundValue(current, value RuntimeValue, operator string, env *Environment) (RuntimeValue, error) {
+	result, err := evaluateBinaryOperation(current, value, operator)
+	if err != nil {
+		return nil, err
+	}
+	return env.counted(result)
+}
+
+// isNullish reports whether a value is null or undef, where ?. stops
+func isNullish(value RuntimeValue) bool {
+	return value.Type() == NULL_TYPE || value.Type() == UNDEF_TYPE
+}
+
+// checkStrictAssignment rejects, in strict modules, assigning to a name that
+// was never declared or that is bound to a constant
+func checkStrictAssignment(name string, env *Environment) error {
+	if !env.Strict() {
+		return nil
+	}
+	if !env.HasVar(name) {
+		return fmt.Errorf("assignment to undeclared variable '%s' in strict mode, declare it with '%s: var = ...'", name, name)
+	}
+	if env.IsConstant(name) {
+		return fmt.Errorf("cannot assign to constant '%s'", name)
+	}
+	return nil
+}
+
+func evaluateActionAssignmentExpression(node *ActionAssignmentExpr, env *Environment) (RuntimeValue, error) {
+	switch node.Assigne.(type) {
+	case *ArrayPattern, *ObjectPattern:
+		if node.Action.Name != "const" && node.Action.Name != "var" && node.Action.Name != "out" {
+			return nil, fmt.Errorf("unsupported action: %s", node.Action.Name)
+		}
+		value, err := Evaluate(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+		constant := node.Action.Name == "const"
+		err = bindPattern(node.Assigne, value, env, func(name string, value RuntimeValue) {
+			env.DeclareVar(name, value, constant)
+		})
+		if err != nil {
+			return nil, err
+		}
+		return value, nil
+	}
+
+	if identifier, ok := node.Assigne.(*Identifier); ok {
+		value, err := Evaluate(node.Value, env)
+		if err != nil {
+			return nil, err
+		}
+
+		switch node.Action.Name {
+		case "const":
+			return env.DeclareVar(identifier.Value, value, true), nil
+		case "var":
+			return env.DeclareVar(identifier.Value, value, false), nil
+		case "out":
+			// Mark as exported (simplified - just declare normally for now)
+			return env.DeclareVar(identifier.Value, value, false), nil
+		default:
+			return nil, fmt.Errorf("unsupported action: %s", node.Action.Name)
+		}
+	}
+
+	return nil, fmt.Errorf("invalid assignment target")
+}
+
+func evaluateCallExpression(node *CallExpr, env *Environment) (RuntimeValue, error) {
+	member, ok := node.Caller.(*MemberExpr)
+	if !ok || len(node.Named) > 0 {
+		fn, args, named, err := evaluateCallParts(node, env)
+		if err != nil {
+			return nil, err
+		}
+		return invokeCall(fn, args, named, env)
+	}
+
+	// A method call goes through the cache of its call site, see cache.go
+	receiver, err := Evaluate(member.Object, env)
+	if err != nil {
+		return nil, err
+	}
+	method := member.cachedMethod(receiver)
+	if method == nil {
+		fn, err := memberValue(member, receiver, env)
+		if err != nil {
+			return nil, err
+		}
+		args, named, err := evaluateArguments(node, env)
+		if err != nil {
+			return nil, err
+		}
+		return invokeCall(fn, args, named, env)
+	}
+	args, err := evaluateElements(node.Args, env)
+	if err != nil {
+		return nil, err
+	}
+	result, err := method(receiver, args, env)
+	if err != nil {
+		return nil, err
+	}
+	return env.counted(result)
+}
+
+// evaluateCallParts evaluates the callee and the arguments of a call
+func evaluateCallParts(node *CallExpr, env *Environment) (RuntimeValue, []RuntimeValue, map[string]RuntimeValue, error) {
+	fn, err := Evaluate(node.Caller, env)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	args, named, err := evaluateArguments(node, env)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return fn, args, named, nil
+}
+
+// evaluateArguments evaluates the positional and the named arguments of a call
+func evaluateArguments(node *CallExpr, env *Environment) ([]RuntimeValue, map[string]RuntimeValue, error) {
+	args, err := evaluateElements(node.Args, env)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var named map[string]RuntimeValue
+	if len(node.Named) > 0 {
+		named = make(map[string]RuntimeValue, len(node.Named))
+		for _, arg := range node.Named {
+			if _, exists := named[arg.Name]; exists {
+				return nil, nil, fmt.Errorf("argument '%s' given more than once", arg.Name)
+			}
+			value, err := Evaluate(arg.Value, env)
+			if err != nil {
+				return nil, nil, err
+			}
+			named[arg.Name] = value
+		}
+	}
+	return args, named, nil
+}
+
+// invokeCall calls fn with evaluated arguments
+func invokeCall(fn RuntimeValue, args []RuntimeValue, named map[string]RuntimeValue, env *Environment) (RuntimeValue, error) {
+	switch f := fn.(type) {
+	case *FunctionValue:
+		return callFunctionNamed(f, args, named, env)
+	case *NativeFunctionValue:
+		if named != nil {
+			return nil, fmt.Errorf("native function %s does not accept named arguments", f.Name)
+		}
+		result, err := callNative(f, args, env)
+		if err != nil {
+			return nil, err
+		}
+		return env.counted(result)
+	default:
+		return nil, fmt.Errorf("cannot call non-function value")
+	}
+}
+
+// callValue calls a Luna or native function value from Go
+func callValue(fn RuntimeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	switch f := fn.(type) {
+	case *FunctionValue:
+		return callFunction(f, args, env)
+	case *NativeFunctionValue:
+		return callNative(f, args, env)
+	default:
+		return nil, fmt.Errorf("cannot call non-function value")
+	}
+}
+
+func callFunction(fn *FunctionValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return callFunctionNamed(fn, args, nil, env)
+}
+
+// callFunctionNamed calls fn with positional arguments followed by arguments bound by parameter name
+func callFunctionNamed(fn *FunctionValue, args []RuntimeValue, named map[string]RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if fn.Async {
+		body := *fn
+		body.Async = false
+		return startTask(env, func() (RuntimeValue, error) {
+			return callFunctionNamed(&body, args, named, env)
+		}), nil
+	}
+
+	// Named arguments that are no parameter fill the fields of a destructured
+	// options parameter, draw(x=1, y=2) for fn draw {x, y} { ... }
+	var options map[int]map[string]RuntimeValue
+	for name, value := range named {
+		found := false
+		for i, param := range fn.Parameters {
+			if param.Name != name || param.Rest {
+				continue
+			}
+			if i < len(args) {
+				return nil, fmt.Errorf("argument '%s' given both by position and by name", name)
+			}
+			found = true
+		}
+		if i := optionsParameter(fn.Parameters, name); !found && i >= 0 {
+			if i < len(args) {
+				return nil, fmt.Errorf("argument '%s' given both in an object and by name", name)
+			}
+			if options == nil {
+				options = make(map[int]map[string]RuntimeValue)
+			}
+			if options[i] == nil {
+				options[i] = make(map[string]RuntimeValue)
+			}
+			options[i][name] = value
+			found = true
+		}
+		if !found {
+			if fn.IsAnonymous() {
+				return nil, fmt.Errorf("function has no parameter named '%s'", name)
+			}
+			return nil, fmt.Errorf("%s has no parameter named '%s'", fn.Name, name)
+		}
+	}
+
+	if err := fn.DeclarationEnv.enterCall(); err != nil {
+		return nil, err
+	}
+	defer fn.DeclarationEnv.leaveCall()
+	if profiler != nil {
+		frame := profiler.enter(profileName(fn))
+		defer profiler.leave(frame)
+	}
+
+	// Create new scope for function execution
+	fnEnv := NewEnvironment(fn.DeclarationEnv)
+
+	// Bind parameters with default value support
+	for i, param := range fn.Parameters {
+		var value RuntimeValue = MakeUndefined()
+
+		if namedValue, exists := named[param.Name]; exists && !param.Rest {
+			value = namedValue
+		} else if fields, exists := options[i]; exists {
+			value = MakeObject(fields)
+		} else if param.Rest {
+			// Collect the remaining arguments into an array
+			rest := []RuntimeValue{}
+			if i < len(args) {
+				rest = append(rest, args[i:]...)
+			}
+			value = MakeArray(rest)
+		} else if i < len(args) {
+			// Use provided argument
+			value = args[i]
+		} else if param.DefaultValue != nil {
+			// Use default value
+			defaultVal, err := Evaluate(param.DefaultValue, fn.DeclarationEnv)
+			if err != nil {
+				return nil, fmt.Errorf("error evaluating default parameter %s: %v", param.Name, err)
+			}
+			value = defaultVal
+		}
+		// If no argument and no default, value remains undefined
+
+		if _, isObject := param.Pattern.(*ObjectPattern); isObject && value.Type() == UNDEF_TYPE {
+			// Leaving out an options object leaves every field to its default
+			value = MakeObject(map[string]RuntimeValue{})
+		}
+		if param.Pattern != nil {
+			err := bindPattern(param.Pattern, value, fnEnv, func(name string, value RuntimeValue) {
+				fnEnv.DeclareVar(name, value, false)
+			})
+			if err != nil {
+				return nil, fmt.Errorf("parameter %d: %v", i+1, err)
+			}
+			continue
+		}
+		fnEnv.DeclareVar(param.Name, value, false)
+	}
+
+	// Execute function body
+	var result RuntimeValue = MakeVoid()
+	for _, stmt := range fn.Body {
+		val, err := evaluateStatement(stmt, fnEnv)
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			if val.Type() == RETURN_TYPE {
+				return val.(*ReturnValue).Value, nil
+			}
+			result = val
+		}
+	}
+
+	return result, nil
+}
+
+// optionsParameter finds the destructured object parameter with a field
+// called name, or -1
+func optionsParameter(parameters []Parameter, name string) int {
+	for i, param := range parameters {
+		pattern, ok := param.Pattern.(*ObjectPattern)
+		if !ok {
+			continue
+		}
+		for _, element := range pattern.Properties {
+			if element.Key == name && !element.Rest {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func evaluateMemberExpression(node *MemberExpr, env *Environment) (RuntimeValue, error) {
+	object, err := Evaluate(node.Object, env)
+	if err != nil {
+		return nil, err
+	}
+	return memberValue(node, object, env)
+}
+
+// memberValue is the member node names on object, already evaluated
+func memberValue(node *MemberExpr, object RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if node.Optional && isNullish(object) {
+		return MakeUndefined(), nil
+	}
+
+	var key string
+	var index *NumberValue // set for computed numeric access
+	if node.Computed {
+		prop, err := Evaluate(node.Property, env)
+		if err != nil {
+			return nil, err
+		}
+		if prop.Type() == STRING_TYPE {
+			key = prop.(*StringValue).Value
+		} else if number, ok := toFloat(prop); ok {
+			index = &NumberValue{Value: number}
+			key = strconv.FormatFloat(number, 'g', -1, 64)
+		} else {
+			return nil, fmt.Errorf("invalid property key type")
+		}
+	} else {
+		if identifier, ok := node.Property.(*Identifier); ok {
+			key = identifier.Value
+		} else {
+			return nil, fmt.Errorf("invalid property access")
+		}
+	}
+
+	switch obj := object.(type) {
+	case *ArrayValue:
+		if index != nil {
+			if i, ok := resolveIndex(index.Value, len(obj.Elements)); ok {
+				return obj.Elements[i], nil
+			}
+			return MakeUndefined(), nil
+		}
+		return prototypeMethod(obj, key), nil
+	case *ObjectValue:
+		if value, exists := obj.Properties[key]; exists {
+			return value, nil
+		}
+		return prototypeMethod(obj, key), nil
+	case *RangeValue:
+		if index != nil {
+			if i, ok := resolveIndex(index.Value, obj.Len()); ok {
+				return obj.Item(i), nil
+			}
+			return MakeUndefined(), nil
+		}
+		return prototypeMethod(obj, key), nil
+	case *StringValue:
+		if index != nil {
+			runes := []rune(obj.Value)
+			if i, ok := resolveIndex(index.Value, len(runes)); ok {
+				return MakeString(string(runes[i])), nil
+			}
+			return MakeUndefined(), nil
+		}
+		return prototypeMethod(obj, key), nil
+	case *FunctionValue, *NativeFunctionValue:
+		if property, ok := functionProperty(obj, key); ok && index == nil {
+			return property, nil
+		}
+		return prototypeMethod(obj, key), nil
+	default:
+		return prototypeMethod(obj, key), nil
+	}
+}
+
+// prototypeMethod is the native method key names on value, undef when there
+// is none. The types with a prototype table bind only the method asked for
+// rather than every method of the table.
+func prototypeMethod(value RuntimeValue, key string) RuntimeValue {
+	switch v := value.(type) {
+	case *StringValue:
+		if fn, ok := StringPrototype[key]; ok {
+			return MakeNativeFunction(key, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(v, args, env)
+			})
+		}
+		return MakeUndefined()
+	case *ArrayValue:
+		if fn, ok := ArrayPrototype[key]; ok {
+			return MakeNativeFunction(key, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(v, args, env)
+			})
+		}
+		return MakeUndefined()
+	case *RangeValue:
+		if fn, ok := RangePrototype[key]; ok {
+			return MakeNativeFunction(key, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(v, args, env)
+			})
+		}
+		return MakeUndefined()
+	case *MapValue:
+		if fn, ok := MapPrototype[key]; ok {
+			return MakeNativeFunction(key, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(v, args, env)
+			})
+		}
+		return MakeUndefined()
+	case *SetValue:
+		if fn, ok := SetPrototype[key]; ok {
+			return MakeNativeFunction(key, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+				return fn(v, args, env)
+			})
+		}
+		return MakeUndefined()
+	}
+	for _, protoFn := range *value.Prototypes() {
+		if protoFn.(*NativeFunctionValue).Name == key {
+			return protoFn
+		}
+	}
+	return MakeUndefined()
+}
+
+// resolveIndex maps a possibly negative or fractional index onto [0, length)
+func resolveIndex(index float64, length int) (int, bool) {
+	i := int(math.Floor(index))
+	if i < 0 {
+		i += length
+	}
+	return i, i >= 0 && i < length
+}
+
+// clampSliceBound resolves a slice bound, counting negatives from the end and clamping to [0, length]
+func clampSliceBound(bound RuntimeValue, fallback int, length int) (int, error) {
+	if bound == nil {
+		return fallback, nil
+	}
+	num, ok := toFloat(bound)
+	if !ok {
+		return 0, fmt.Errorf("slice bounds must be numbers, got %s", bound.Type())
+	}
+
+	i := int(math.Floor(num))
+	if i < 0 {
+		i += length
+	}
+	return max(0, min(i, length)), nil
+}
+
+func evaluateSliceExpression(node *SliceExpr, env *Environment) (RuntimeValue, error) {
+	object, err := Evaluate(node.Object, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var bounds [2]RuntimeValue
+	for i, expr := range []Expression{node.Start, node.End} {
+		if expr == nil {
+			continue
+		}
+		if bounds[i], err = Evaluate(expr, env); err != nil {
+			return nil, err
+		}
+	}
+
+	var length int
+	var runes []rune // strings are sliced by character
+	switch obj := object.(type) {
+	case *ArrayValue:
+		length = len(obj.Elements)
+	case *StringValue:
+		runes = []rune(obj.Value)
+		length = len(runes)
+	default:
+		return nil, fmt.Errorf("cannot slice value of type %s", object.Type())
+	}
+
+	start, err := clampSliceBound(bounds[0], 0, length)
+	if err != nil {
+		return nil, err
+	}
+	end, err := clampSliceBound(bounds[1], length, length)
+	if err != nil {
+		return nil, err
+	}
+	end = max(start, end)
+
+	if _, ok := object.(*StringValue); ok {
+		return MakeString(string(runes[start:end])), nil
+	}
+	elements := make([]RuntimeValue, end-start)
+	copy(elements, object.(*ArrayValue).Elements[start:end])
+	return MakeArray(elements), nil
+}
+
+func evaluateRangeExpression(node *RangeExpr, env *Environment) (RuntimeValue, error) {
+	start, err := Evaluate(node.Start, env)
+	if err != nil {
+		return nil, err
+	}
+	end, err := Evaluate(node.End, env)
+	if err != nil {
+		return nil, err
+	}
+
+	startVal, startOk := toFloat(start)
+	endVal, endOk := toFloat(end)
+	if !startOk || !endOk {
+		return nil, fmt.Errorf("range bounds must be numbers, got %s and %s", start.Type(), end.Type())
+	}
+	return MakeRange(startVal, endVal, node.Inclusive), nil
+}
+
+func evaluateTernaryExpression(node *TernaryExpr, env *Environment) (RuntimeValue, error) {
+	condition, err := Evaluate(node.Condition, env)
+	if err != nil {
+		return nil, err
+	}
+
+	truthy, err := conditionValue(condition, env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy {
+		return Evaluate(node.Consequent, env)
+	} else {
+		return Evaluate(node.Alternate, env)
+	}
+}
+
+func evaluateTypeofExpression(node *TypeofExpr, env *Environment) (RuntimeValue, error) {
+	value, err := Evaluate(node.Value, env)
+	if err != nil {
+		return nil, err
+	}
+
+	return MakeString(typeName(value)), nil
+}
+
+func evaluateEqualityExpression(node *EqualityExpr, env *Environment) (RuntimeValue, error) {
+	left, err := Evaluate(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := Evaluate(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Operator {
+	case "==":
+		return MakeBool(isEqual(left, right)), nil
+	case "!=":
+		return MakeBool(!isEqual(left, right)), nil
+	default:
+		return nil, fmt.Errorf("unsupported equality operator: %s", node.Operator)
+	}
+}
+
+func evaluateInequalityExpression(node *InequalityExpr, env *Environment) (RuntimeValue, error) {
+	left, err := Evaluate(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := Evaluate(node.Right, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Strings compare lexicographically
+	if left.Type() == STRING_TYPE && right.Type() == STRING_TYPE {
+		return compareStrings(left.(*StringValue).Value, right.(*StringValue).Value, node.Operator)
+	}
+
+	if !isNumeric(left.Type()) || !isNumeric(right.Type()) {
+		return nil, fmt.Errorf("cannot compare %s with %s", left.Type(), right.Type())
+	}
+
+	// Numbers compare exactly, NaN compares false with anything
+	if !isNaN(left) && !isNaN(right) {
+		order := compareNumeric(left, right)
+		switch node.Operator {
+		case "<":
+			return MakeBool(order < 0), nil
+		case ">":
+			return MakeBool(order > 0), nil
+		case "<=":
+			return MakeBool(order <= 0), nil
+		case ">=":
+			return MakeBool(order >= 0), nil
+		}
+	}
+	leftVal, _ := toFloat(left)
+	rightVal, _ := toFloat(right)
+
+	switch node.Operator {
+	case "<":
+		return MakeBool(leftVal < rightVal), nil
+	case ">":
+		return MakeBool(leftVal > rightVal), nil
+	case "<=":
+		return MakeBool(leftVal <= rightVal), nil
+	case ">=":
+		return MakeBool(leftVal >= rightVal), nil
+	default:
+		return nil, fmt.Errorf("unsupported inequality operator: %s", node.Operator)
+	}
+}
+
+func compareStrings(left, right string, operator string) (RuntimeValue, error) {
+	switch operator {
+	case "<":
+		return MakeBool(left < right), nil
+	case ">":
+		return MakeBool(left > right), nil
+	case "<=":
+		return MakeBool(left <= right), nil
+	case ">=":
+		return MakeBool(left >= right), nil
+	default:
+		return nil, fmt.Errorf("unsupported inequality operator: %s", operator)
+	}
+}
+
+func evaluateLogicalExpression(node *LogicalExpr, env *Environment) (RuntimeValue, error) {
+	left, err := Evaluate(node.Left, env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.Operator {
+	case "&&":
+		if !left.IsTruthy() {
+			return left, nil
+		}
+		return Evaluate(node.Right, env)
+	case "||":
+		if left.IsTruthy() {
+			return left, nil
+		}
+		return Evaluate(node.Right, env)
+	default:
+		return nil, fmt.Errorf("unsupported logical operator: %s", node.Operator)
+	}
+}
+
+func evaluateFunctionDeclaration(node *FunctionDeclaration, env *Environment) (RuntimeValue, error) {
+	anonymous := node.Name == ""
+	fn := MakeFunction(node.Name, node.Parameters, node.Body, env, node.Export, anonymous)
+	fn.(*FunctionValue).Async = node.Async
+	fn.(*FunctionValue).Doc = node.Doc
+	if !anonymous {
+		env.DeclareVar(node.Name, fn, true)
+	}
+	return fn, nil
+}
+
+func evaluateMatchExpression(node *MatchExpr, env *Environment) (RuntimeValue, error) {
+	subject, err := Evaluate(node.Subject, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var selected *MatchArm
+	for i := range node.Arms {
+		arm := &node.Arms[i]
+		if arm.Default {
+			if selected == nil {
+				selected = arm
+			}
+			continue
+		}
+		for _, pattern := range arm.Patterns {
+			value, err := Evaluate(pattern, env)
+			if err != nil {
+				return nil, err
+			}
+			if isEqual(subject, value) {
+				selected = arm
+				break
+			}
+		}
+		if selected != nil && !selected.Default {
+			break
+		}
+	}
+
+	// Without a matching arm or default the match yields undef
+	if selected == nil {
+		return MakeUndefined(), nil
+	}
+
+	var result RuntimeValue = MakeVoid()
+	for _, stmt := range selected.Body {
+		val, err := evaluateStatement(stmt, env)
+		if err != nil {
+			return nil, err
+		}
+		if val != nil {
+			if val.Type() == RETURN_TYPE {
+				return val, nil
+			}
+			result = val
+		}
+	}
+	return result, nil
+}
+
+func evaluateIfStatement(node *IfStatement, env *Environment) (RuntimeValue, error) {
+	condition, err := Evaluate(node.Test, env)
+	if err != nil {
+		return nil, err
+	}
+
+	// Don't create new environment for if statements - use parent environment
+	var result RuntimeValue = MakeVoid()
+
+	truthy, err := conditionValue(condition, env)
+	if err != nil {
+		return nil, err
+	}
+	if truthy {
+		for _, stmt := range node.Consequent {
+			val, err := evaluateStatement(stmt, env) // Use parent env instead of new env
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					return val, nil
+				}
+				result = val
+			}
+		}
+	} else if len(node.Alternate) > 0 {
+		for _, stmt := range node.Alternate {
+			val, err := evaluateStatement(stmt, env) // Use parent env instead of new env
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					return val, nil
+				}
+				result = val
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func evaluateWhileStatement(node *WhileStatement, env *Environment) (RuntimeValue, error) {
+	var result RuntimeValue = MakeVoid()
+
+	for {
+		if err := env.countStep(); err != nil {
+			return nil, err
+		}
+		condition, err := Evaluate(node.Test, env)
+		if err != nil {
+			return nil, err
+		}
+
+		truthy, err := conditionValue(condition, env)
+		if err != nil {
+			return nil, err
+		}
+		if !truthy {
+			break
+		}
+
+		for _, stmt := range node.Consequent {
+			val, err := evaluateStatement(stmt, env)
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					return val, nil
+				}
+				result = val
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// evaluateDoWhileStatement runs the body, then tests, until the loop is done
+func evaluateDoWhileStatement(node *DoWhileStatement, env *Environment) (RuntimeValue, error) {
+	var result RuntimeValue = MakeVoid()
+
+	for {
+		if err := env.countStep(); err != nil {
+			return nil, err
+		}
+
+		for _, stmt := range node.Body {
+			val, err := evaluateStatement(stmt, env)
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					return val, nil
+				}
+				result = val
+			}
+		}
+
+		condition, err := Evaluate(node.Test, env)
+		if err != nil {
+			return nil, err
+		}
+		truthy, err := conditionValue(condition, env)
+		if err != nil {
+			return nil, err
+		}
+		if truthy == node.Until {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func evaluateForStatement(node *ForStatement, env *Environment) (RuntimeValue, error) {
+	forEnv := NewEnvironment(env)
+	var result RuntimeValue = MakeVoid()
+
+	// Execute declaration
+	_, err := Evaluate(node.Declaration, forEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		if err := forEnv.countStep(); err != nil {
+			return nil, err
+		}
+
+		// Test condition
+		condition, err := Evaluate(node.Test, forEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		truthy, err := conditionValue(condition, env)
+		if err != nil {
+			return nil, err
+		}
+		if !truthy {
+			break
+		}
+
+		// Execute body
+		for _, stmt := range node.Body {
+			val, err := evaluateStatement(stmt, forEnv)
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					return val, nil
+				}
+				result = val
+			}
+		}
+
+		// Each iteration gets its own copy of the loop bindings, so closures
+		// created in the body keep the values of their iteration
+		forEnv = forEnv.Copy()
+
+		// Execute increaser
+		_, err = Evaluate(node.Increaser, forEnv)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// iterateValue calls fn with each item of an array, range, string, channel
+// or the keys of an object, stopping early when fn returns false
+func iterateValue(value RuntimeValue, env *Environment, fn func(item RuntimeValue) (bool, error)) error {
+	switch v := value.(type) {
+	case *RangeValue:
+		// Ranges are never materialized, so huge ones cost nothing up front
+		for i := 0; i < v.Len(); i++ {
+			if more, err := fn(v.Item(i)); !more || err != nil {
+				return err
+			}
+		}
+	case *ArrayValue:
+		elements := append([]RuntimeValue(nil), v.Elements...)
+		for _, element := range elements {
+			if more, err := fn(element); !more || err != nil {
+				return err
+			}
+		}
+	case *StringValue:
+		for _, char := range v.Value {
+			if more, err := fn(MakeString(string(char))); !more || err != nil {
+				return err
+			}
+		}
+	case *ObjectValue:
+		for _, key := range v.Keys() {
+			if more, err := fn(MakeString(key)); !more || err != nil {
+				return err
+			}
+		}
+	case *MapValue:
+		for _, key := range v.Keys() {
+			if more, err := fn(key); !more || err != nil {
+				return err
+			}
+		}
+	case *SetValue:
+		for _, element := range v.Elements() {
+			if more, err := fn(element); !more || err != nil {
+				return err
+			}
+		}
+	case *ChannelValue:
+		// Receives until the channel is closed
+		for {
+			item, ok, err := v.Receive(env)
+			if err != nil || !ok {
+				return err
+			}
+			if more, err := fn(item); !more || err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot iterate over %s", value.Type())
+	}
+	return nil
+}
+
+func evaluateForInStatement(node *ForInStatement, env *Environment) (RuntimeValue, error) {
+	iterable, err := Evaluate(node.Iterable, env)
+	if err != nil {
+		return nil, err
+	}
+
+	var result RuntimeValue = MakeVoid()
+	var returned RuntimeValue
+	err = iterateValue(iterable, env, func(item RuntimeValue) (bool, error) {
+		if err := env.countStep(); err != nil {
+			return false, err
+		}
+
+		// Each iteration gets its own scope, like the C-style for loop
+		iterEnv := NewEnvironment(env)
+		iterEnv.DeclareVar(node.Variable.Value, item, false)
+
+		for _, stmt := range node.Body {
+			val, err := evaluateStatement(stmt, iterEnv)
+			if err != nil {
+				return false, err
+			}
+			if val != nil {
+				if val.Type() == RETURN_TYPE {
+					returned = val
+					return false, nil
+				}
+				result = val
+			}
+		}
+		return true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if returned != nil {
+		return returned, nil
+	}
+	return result, nil
+}
+
+// evaluateDebugStatement prints the values of debug { a, b } to stderr as
+// a=1, b='x', each named by its expression unless it is a literal, nested
+// values --debug-depth=N levels deep
+func evaluateDebugStatement(node *DebugStatement, env *Environment) (RuntimeValue, error) {
+	label := ""
+	if node.Label != nil {
+		value, err := Evaluate(node.Label, env)
+		if err != nil {
+			return nil, err
+		}
+		label = interpolatedText(value)
+	}
+
+	depth := env.options.DebugDepth
+	printer := NewPrinter()
+	var props []string
+	for _, prop := range node.Props {
+		value, err := Evaluate(prop, env)
+		if err != nil {
+			return nil, err
+		}
+		text := inspectValue(value, depth, summaryItems, 0)
+		if _, constant := constantValue(prop); !constant {
+			text = blue(printer.expr(prop, precAssignment)) + "=" + text
+		}
+		props = append(props, text)
+	}
+
+	output := formatDebug(node.Position.Line+1, label, props)
+	if !colorsEnabled(env) {
+		output = stripColor(output)
+	}
+	fmt.Fprintln(os.Stderr, output)
+	return MakeVoid(), nil
+}
+
+// isEqual is the == operator: values of different types are never equal (null
+// and undef included) except ints and floats of the same value, arrays and
+// objects are compared by content and functions, channels and tasks by identity
+func isEqual(left, right RuntimeValue) bool {
+	return deepEqual(left, right, make(map[[2]RuntimeValue]bool))
+}
+
+func deepEqual(left, right RuntimeValue, seen map[[2]RuntimeValue]bool) bool {
+	if isNumeric(left.Type()) && isNumeric(right.Type()) {
+		return numbersEqual(left, right)
+	}
+	if left.Type() != right.Type() {
+		return false
+	}
+
+	switch left.Type() {
+	case BOOLEAN_TYPE:
+		return left.(*BooleanValue).Value == right.(*BooleanValue).Value
+	case STRING_TYPE:
+		return left.(*StringValue).Value == right.(*StringValue).Value
+	case RANGE_TYPE:
+		return *left.(*RangeValue) == *right.(*RangeValue)
+	case NULL_TYPE, UNDEF_TYPE, VOID_TYPE:
+		return true
+	case ARRAY_TYPE, OBJECT_TYPE, MAP_TYPE, SET_TYPE:
+		if left == right {
+			return true
+		}
+		// A pair already being compared is assumed equal, so cycles end
+		pair := [2]RuntimeValue{left, right}
+		if seen[pair] {
+			return true
+		}
+		seen[pair] = true
+
+		if left.Type() == ARRAY_TYPE {
+			a, b := left.(*ArrayValue).Elements, right.(*ArrayValue).Elements
+			if len(a) != len(b) {
+				return false
+			}
+			for i := range a {
+				if !deepEqual(a[i], b[i], seen) {
+					return false
+				}
+			}
+			return true
+		}
+
+		if left.Type() == SET_TYPE {
+			a, b := left.(*SetValue), right.(*SetValue)
+			if a.Len() != b.Len() {
+				return false
+			}
+			for _, element := range a.Elements() {
+				if found, _ := b.Has(element); !found {
+					return false
+				}
+			}
+			return true
+		}
+		if left.Type() == MAP_TYPE {
+			a, b := left.(*MapValue), right.(*MapValue)
+			if a.Len() != b.Len() {
+				return false
+			}
+			for _, entry := range a.entries {
+				other, found, _ := b.Get(entry.key)
+				if !found || !deepEqual(entry.value, other, seen) {
+					return false
+				}
+			}
+			return true
+		}
+
+		a, b := left.(*ObjectValue).Properties, right.(*ObjectValue).Properties
+		if len(a) != len(b) {
+			return false
+		}
+		for key, value := range a {
+			other, exists := b[key]
+			if !exists || !deepEqual(value, other, seen) {
+				return false
+			}
+		}
+		return true
+	default:
+		return left == right // functions, channels and tasks
+	}
+}
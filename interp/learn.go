@@ -0,0 +1,148 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Lesson is a single step of the interactive tutorial
+type Lesson struct {
+	Title    string
+	Text     string
+	Task     string
+	Expected string // Luna expression whose value the answer must match
+	Hint     string
+}
+
+var lessons = []Lesson{
+	{
+		Title:    "Numbers",
+		Text:     "Luna evaluates expressions as you type them.\nArithmetic works the way you would expect: 1 + 2 * 3",
+		Task:     "Compute the sum of 40 and 2.",
+		Expected: "42",
+		Hint:     "Try typing: 40 + 2",
+	},
+	{
+		Title:    "Strings",
+		Text:     "Strings are written between quotes and can be joined with +.",
+		Task:     "Build the string 'hello luna' by joining 'hello ' and 'luna'.",
+		Expected: "'hello luna'",
+		Hint:     "Try typing: 'hello ' + 'luna'",
+	},
+	{
+		Title:    "Variables",
+		Text:     "Assign a value to a name with =, and use the name later on.\nSeveral statements can be separated with ;",
+		Task:     "Store 7 in x, then evaluate x * 6.",
+		Expected: "42",
+		Hint:     "Try typing: x = 7; x * 6",
+	},
+	{
+		Title:    "Functions",
+		Text:     "Functions are declared with fn, followed by the name and the parameters.\nA function written with ':' returns its expression: fn double n: n * 2",
+		Task:     "Declare fn square n: n * n, then call square(9).",
+		Expected: "81",
+		Hint:     "Try typing: fn square n: n * n; square(9)",
+	},
+	{
+		Title:    "Conditions",
+		Text:     "The ternary operator picks a value based on a condition: cond ? a : b\nfalse, 0, '', [], {}, null and undef count as false, bool(x) shows how a value counts.",
+		Task:     "Evaluate to 'big' when 10 > 5, 'small' otherwise.",
+		Expected: "'big'",
+		Hint:     "Try typing: 10 > 5 ? 'big' : 'small'",
+	},
+	{
+		Title:    "Arrays",
+		Text:     "Arrays hold a list of values and have prototype functions like push and length.",
+		Task:     "Create the array [1, 2], push 3 into it, and evaluate its length.",
+		Expected: "3",
+		Hint:     "Try typing: a = [1, 2]; a.push(3); a.length()",
+	},
+}
+
+// runLearn walks the user through the tutorial lessons
+func runLearn() {
+	fmt.Println(green("Welcome to the Luna tutorial!"))
+	fmt.Println(gray("Type ") + green(under(":skip")) + gray(" to skip a lesson, ") +
+		green(under(":hint")) + gray(" for a hint, or ") + green(under("exit()")) + gray(" to leave..."))
+
+	readline := NewReadline(white(">> "))
+
+	for i, lesson := range lessons {
+		fmt.Println()
+		fmt.Println(bold(blue(fmt.Sprintf("Lesson %d/%d: %s", i+1, len(lessons), lesson.Title))))
+		fmt.Println(lesson.Text)
+		fmt.Println(yellow("Task: ") + lesson.Task)
+
+		expected, err := evaluateSandboxed(lesson.Expected)
+		if err != nil {
+			fmt.Println(formatError("Error", err.Error()))
+			continue
+		}
+
+		for {
+			input, err := readline.ReadLine()
+			if err != nil {
+				return
+			}
+
+			input = strings.TrimSpace(input)
+			if input == "" {
+				continue
+			}
+
+			if input == "exit()" {
+				fmt.Println(gray("Exiting..."))
+				return
+			}
+
+			if input == ":hint" {
+				fmt.Println(gray(lesson.Hint))
+				continue
+			}
+
+			if input == ":skip" {
+				fmt.Println(gray("Skipped."))
+				break
+			}
+
+			result, err := evaluateSandboxed(input)
+			if err != nil {
+				fmt.Println(formatError("Error", err.Error()))
+				continue
+			}
+
+			if output := colorizeValue(result, false, false); output != "" {
+				fmt.Println(output)
+			}
+
+			if result.Type() == expected.Type() && result.String() == expected.String() {
+				fmt.Println(green("Correct!"))
+				break
+			}
+			fmt.Println(red("Not quite, try again.") + gray(" (:hint for a hint)"))
+		}
+	}
+
+	fmt.Println()
+	fmt.Println(green("You finished the tutorial. Happy hacking!"))
+}
+
+// evaluateSandboxed runs code in a fresh sandboxed environment so answers
+// cannot leak between lessons nor reach outside the interpreter
+func evaluateSandboxed(code string) (RuntimeValue, error) {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+
+	luna := NewLuna(env)
+	if err := luna.Sandbox(); err != nil {
+		return nil, err
+	}
+	result, err := luna.Evaluate(code)
+	if err != nil {
+		return nil, err
+	}
+	if result.Type() == RETURN_TYPE {
+		result = result.(*ReturnValue).Value
+	}
+	return result, nil
+}
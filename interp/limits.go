@@ -0,0 +1,146 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Limits cap what a program may use, so runaway recursion or loops end in
+// an error the script and its host can handle instead of crashing the
+// interpreter. A zero field means no limit. The command line sets them with
+// --max-depth=N, --max-steps=N and --max-allocations=N, a host with
+// luna.SetLimits.
+//
+// MaxAllocations is a budget, not a memory limit: every string, array,
+// object, map and set the program creates counts once, for the whole run,
+// however short-lived it is and whatever its size.
+//
+// Timeout counts from the first step of a run, each Evaluate and Call of a
+// host starting a new one. It is checked between steps, a native blocking
+// on input or a sleep is not cut short.
+type Limits struct {
+	MaxCallDepth   int           // nested calls of Luna functions
+	MaxSteps       int           // statements and loop iterations evaluated
+	MaxAllocations int           // strings, arrays, objects, maps and sets created so far
+	Timeout        time.Duration // time a run may take
+}
+
+// DefaultLimits apply to every environment set up afterwards. The call depth
+// stays well below the point where Go runs out of stack.
+var DefaultLimits = Limits{MaxCallDepth: 10000}
+
+// LimitError is raised when a program goes over one of its limits
+type LimitError struct {
+	Limit string
+	Max   int
+}
+
+func (e *LimitError) Error() string {
+	if e.Limit == "time" {
+		return fmt.Sprintf("time limit of %s exceeded", time.Duration(e.Max)*time.Millisecond)
+	}
+	return fmt.Sprintf("%s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// limitState is shared by every scope of an environment, spawned tasks
+// included, so their calls count towards the same depth
+type limitState struct {
+	Limits
+	depth       int
+	steps       int
+	allocations int
+	deadline    time.Time // set on the first step of a run when there is a Timeout
+}
+
+// deadlineCheck is how many steps go by between two looks at the clock
+const deadlineCheck = 256
+
+func newLimitState() *limitState {
+	return &limitState{Limits: DefaultLimits}
+}
+
+// SetLimits changes the limits of the environment of this interpreter
+func (l *Luna) SetLimits(limits Limits) {
+	l.env.limits.Limits = limits
+}
+
+// startRun gives a call from the host its own Timeout, calls made from Luna
+// code back into the host share the one running
+func (env *Environment) startRun() {
+	if env.limits.depth == 0 {
+		env.limits.deadline = time.Time{}
+	}
+}
+
+// enterCall counts a call of a Luna function, leaveCall must follow it
+func (env *Environment) enterCall() error {
+	state := env.limits
+	state.depth++
+	if state.MaxCallDepth > 0 && state.depth > state.MaxCallDepth {
+		state.depth--
+		return &LimitError{Limit: "call depth", Max: state.MaxCallDepth}
+	}
+	return nil
+}
+
+func (env *Environment) leaveCall() {
+	env.limits.depth--
+}
+
+// countStep counts a statement or loop iteration
+func (env *Environment) countStep() error {
+	state := env.limits
+	state.steps++
+	if state.MaxSteps > 0 && state.steps > state.MaxSteps {
+		return &LimitError{Limit: "step", Max: state.MaxSteps}
+	}
+	if state.Timeout > 0 {
+		if state.deadline.IsZero() {
+			state.deadline = time.Now().Add(state.Timeout)
+		} else if state.steps%deadlineCheck == 0 && time.Now().After(state.deadline) {
+			return &LimitError{Limit: "time", Max: int(state.Timeout / time.Millisecond)}
+		}
+	}
+	return nil
+}
+
+// counted passes on a value just created, counting it against the
+// allocation budget when it is a string, array, object, map or set
+func (env *Environment) counted(value RuntimeValue) (RuntimeValue, error) {
+	switch value.(type) {
+	case *StringValue, *ArrayValue, *ObjectValue, *MapValue, *SetValue:
+	default:
+		return value, nil
+	}
+	state := env.limits
+	state.allocations++
+	if state.MaxAllocations > 0 && state.allocations > state.MaxAllocations {
+		return nil, &LimitError{Limit: "allocation", Max: state.MaxAllocations}
+	}
+	return value, nil
+}
+
+// setupLimits applies --max-depth, --max-steps and --max-allocations to env
+func setupLimits(env *Environment) error {
+	flags := []struct {
+		name  string
+		field *int
+	}{
+		{"--max-depth", &env.limits.MaxCallDepth},
+		{"--max-steps", &env.limits.MaxSteps},
+		{"--max-allocations", &env.limits.MaxAllocations},
+	}
+	for _, flag := range flags {
+		value, found := flagValue(flag.name)
+		if !found {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			return fmt.Errorf("%s expects a number of 0 or more, got '%s'", flag.name, value)
+		}
+		*flag.field = n
+	}
+	return nil
+}
@@ -0,0 +1,520 @@
+package interp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// `luna lsp` is a language server: editors like VS Code start it and talk
+// the Language Server Protocol to it over stdin and stdout. It keeps the
+// open documents, checks each version as it is typed and publishes the
+// syntax errors and checker findings, and answers
+//
+//	textDocument/hover        the signature and doc comment of a function,
+//	                          what a parameter or variable is, a native's description
+//	textDocument/definition   where a function, parameter or variable is declared
+//	textDocument/completion   keywords, natives and declared names, the
+//	                          members of a native module after `io.`
+//
+// Documents are synced whole. The editor counts columns in UTF-16 units,
+// the tokenizer in runes, positions are converted both ways.
+
+// lspMessage is a request, a response or a notification
+type lspMessage struct {
+	JSONRPC string           `json:"jsonrpc"`
+	ID      *json.RawMessage `json:"id,omitempty"`
+	Method  string           `json:"method,omitempty"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+}
+
+type lspError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspLocation struct {
+	URI   string   `json:"uri"`
+	Range lspRange `json:"range"`
+}
+
+// lspDiagnostic is a Diagnostic as the protocol has it, severity 1 for an
+// error and 2 for a warning
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCompletionItem struct {
+	Label         string `json:"label"`
+	Kind          int    `json:"kind"`
+	Detail        string `json:"detail,omitempty"`
+	Documentation string `json:"documentation,omitempty"`
+}
+
+// Completion item kinds of the protocol
+const (
+	completionFunction = 3
+	completionField    = 5
+	completionVariable = 6
+	completionModule   = 9
+	completionKeyword  = 14
+)
+
+// lspTextParams are the parameters of the requests about a place in a document
+type lspTextParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+	Position       lspPosition `json:"position"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+// lspDocument is an open document, with the symbols of the last version that parsed
+type lspDocument struct {
+	lines   []string
+	symbols *SymbolTable
+}
+
+type lspServer struct {
+	reader    *bufio.Reader
+	writer    io.Writer
+	documents map[string]*lspDocument
+	natives   map[string]RuntimeValue
+	shutdown  bool
+}
+
+// runLSP serves the editor on stdin and stdout until it says exit
+func runLSP() {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	server := &lspServer{
+		reader:    bufio.NewReader(os.Stdin),
+		writer:    os.Stdout,
+		documents: make(map[string]*lspDocument),
+		natives:   env.variables,
+	}
+	for {
+		message, err := server.read()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "luna lsp:", err)
+			return
+		}
+		if message.Method == "exit" {
+			if !server.shutdown {
+				os.Exit(1)
+			}
+			return
+		}
+		server.handle(message)
+	}
+}
+
+// read reads the next message, a Content-Length header and a JSON body
+func (s *lspServer) read() (*lspMessage, error) {
+	length := -1
+	for {
+		line, err := s.reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok && strings.EqualFold(name, "Content-Length") {
+			if length, err = strconv.Atoi(strings.TrimSpace(value)); err != nil {
+				return nil, fmt.Errorf("invalid Content-Length '%s'", strings.TrimSpace(value))
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without a Content-Length")
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, body); err != nil {
+		return nil, err
+	}
+	var message lspMessage
+	if err := json.Unmarshal(body, &message); err != nil {
+		return nil, fmt.Errorf("invalid message: %v", err)
+	}
+	return &message, nil
+}
+
+// send writes a message with its Content-Length header
+func (s *lspServer) send(message any) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "luna lsp:", err)
+		return
+	}
+	fmt.Fprintf(s.writer, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+func (s *lspServer) notify(method string, params any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// handle answers a request or acts on a notification
+func (s *lspServer) handle(message *lspMessage) {
+	var params lspTextParams
+	if len(message.Params) > 0 {
+		if err := json.Unmarshal(message.Params, &params); err != nil {
+			s.reply(message, nil, &lspError{Code: -32602, Message: err.Error()})
+			return
+		}
+	}
+	uri := params.TextDocument.URI
+
+	switch message.Method {
+	case "initialize":
+		s.reply(message, map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // the whole document on every change
+				"hoverProvider":      true,
+				"definitionProvider": true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+			},
+			"serverInfo": map[string]any{"name": "luna", "version": Version},
+		}, nil)
+	case "shutdown":
+		s.shutdown = true
+		s.reply(message, nil, nil)
+	case "textDocument/didOpen":
+		s.update(uri, params.TextDocument.Text)
+	case "textDocument/didChange":
+		if len(params.ContentChanges) > 0 {
+			s.update(uri, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		delete(s.documents, uri)
+		s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": []lspDiagnostic{}})
+	case "textDocument/hover":
+		s.reply(message, s.hover(uri, params.Position), nil)
+	case "textDocument/definition":
+		s.reply(message, s.definition(uri, params.Position), nil)
+	case "textDocument/completion":
+		s.reply(message, s.completion(uri, params.Position), nil)
+	default:
+		// Notifications we do not know are ignored, requests get an error
+		if message.ID != nil {
+			s.reply(message, nil, &lspError{Code: -32601, Message: "method not found: " + message.Method})
+		}
+	}
+}
+
+func (s *lspServer) reply(message *lspMessage, result any, err *lspError) {
+	if message.ID == nil {
+		return
+	}
+	// A response has either a result, null included, or an error
+	response := map[string]any{"jsonrpc": "2.0", "id": message.ID, "result": result}
+	if err != nil {
+		delete(response, "result")
+		response["error"] = err
+	}
+	s.send(response)
+}
+
+// update takes a new version of a document, checks it and publishes what was found
+func (s *lspServer) update(uri string, text string) {
+	document, open := s.documents[uri]
+	if !open {
+		document = &lspDocument{}
+		s.documents[uri] = document
+	}
+	document.lines = strings.Split(text, "\n")
+
+	var diagnostics []Diagnostic
+	if _, err := parsePragmas(text); err != nil {
+		diagnostics = append(diagnostics, diagnosticOf(err, ""))
+	}
+	tokens, err := NewTokenizer(text).Tokenize()
+	var ast Statement
+	if err == nil {
+		ast, err = NewParser(tokens, text).ProduceAST()
+	}
+	if err != nil {
+		diagnostics = append(diagnostics, diagnosticOf(err, ""))
+	} else {
+		symbols, found := resolveSymbols(ast.(*Program))
+		document.symbols = symbols
+		diagnostics = append(diagnostics, found...)
+	}
+
+	published := make([]lspDiagnostic, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		published[i] = lspDiagnostic{Code: diagnostic.Code, Source: "luna", Message: diagnostic.Message, Severity: 1}
+		if diagnostic.Severity == SeverityWarning {
+			published[i].Severity = 2
+		}
+		if diagnostic.Span != nil {
+			length := diagnostic.Span.Length
+			if length == 0 {
+				length = document.wordLength(diagnostic.Span.Start)
+			}
+			published[i].Range = document.rangeOf(diagnostic.Span.Start, length)
+		}
+	}
+	s.notify("textDocument/publishDiagnostics", map[string]any{"uri": uri, "diagnostics": published})
+}
+
+// lspWord is the identifier at a place in a document, and the one before
+// the dot in front of it when it is a member like io.print
+type lspWord struct {
+	text     string
+	position Position
+	object   string
+}
+
+// wordAt finds the identifier under the cursor, or just before it while
+// it is typed. ok is false when there is none and not right after a dot.
+func (d *lspDocument) wordAt(position lspPosition) (lspWord, bool) {
+	if position.Line < 0 || position.Line >= len(d.lines) {
+		return lspWord{}, false
+	}
+	line := []rune(d.lines[position.Line])
+	column := runeColumn(d.lines[position.Line], position.Character)
+	start, end := column, column
+	for start > 0 && isIdentifierRune(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentifierRune(line[end]) {
+		end++
+	}
+
+	word := lspWord{text: string(line[start:end]), position: Position{Line: position.Line, Column: start}}
+	for i := 0; i < position.Line; i++ {
+		word.position.Index += len([]rune(d.lines[i])) + 1
+	}
+	word.position.Index += start
+
+	if start > 0 && line[start-1] == '.' {
+		objectEnd := start - 1
+		objectStart := objectEnd
+		for objectStart > 0 && isIdentifierRune(line[objectStart-1]) {
+			objectStart--
+		}
+		word.object = string(line[objectStart:objectEnd])
+	}
+	return word, word.text != "" || word.object != ""
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordLength is how many runes the identifier at position spans, at least 1
+func (d *lspDocument) wordLength(position Position) int {
+	if position.Line >= len(d.lines) {
+		return 1
+	}
+	line := []rune(d.lines[position.Line])
+	length := 0
+	for position.Column+length < len(line) && isIdentifierRune(line[position.Column+length]) {
+		length++
+	}
+	return max(length, 1)
+}
+
+// rangeOf is the range of length runes from position, in UTF-16 columns
+func (d *lspDocument) rangeOf(position Position, length int) lspRange {
+	line := ""
+	if position.Line < len(d.lines) {
+		line = d.lines[position.Line]
+	}
+	return lspRange{
+		Start: lspPosition{Line: position.Line, Character: utf16Column(line, position.Column)},
+		End:   lspPosition{Line: position.Line, Character: utf16Column(line, position.Column+length)},
+	}
+}
+
+// runeColumn converts a UTF-16 column of line to a rune column
+func runeColumn(line string, character int) int {
+	column, units := 0, 0
+	for _, r := range line {
+		if units >= character {
+			break
+		}
+		units += utf16.RuneLen(r)
+		column++
+	}
+	return column
+}
+
+// utf16Column converts a rune column of line to a UTF-16 column
+func utf16Column(line string, column int) int {
+	units := 0
+	for i, r := range []rune(line) {
+		if i >= column {
+			return units
+		}
+		units += utf16.RuneLen(r)
+	}
+	return units + column - len([]rune(line))
+}
+
+// declarationAt is what the identifier under the cursor refers to
+func (s *lspServer) declarationAt(uri string, position lspPosition) (*lspDocument, lspWord, *Declaration) {
+	document, open := s.documents[uri]
+	if !open {
+		return nil, lspWord{}, nil
+	}
+	word, ok := document.wordAt(position)
+	if !ok || word.text == "" || word.object != "" || document.symbols == nil {
+		return document, word, nil
+	}
+	return document, word, document.symbols.At(word.position.Index)
+}
+
+// hover describes the name under the cursor in markdown
+func (s *lspServer) hover(uri string, position lspPosition) any {
+	document, word, declaration := s.declarationAt(uri, position)
+	if document == nil || word.text == "" {
+		return nil
+	}
+
+	var text string
+	switch {
+	case word.object != "":
+		object, ok := s.natives[word.object].(*ObjectValue)
+		if !ok {
+			return nil
+		}
+		member, ok := object.Properties[word.text]
+		if !ok {
+			return nil
+		}
+		text = s.describeNative(word.object+"."+word.text, member)
+	case declaration == nil:
+		return nil
+	case declaration.Kind == SymbolNative:
+		text = s.describeNative(declaration.Name, s.natives[declaration.Name])
+	case declaration.Kind == SymbolFunction:
+		fn := declaration.Function
+		text = "```luna\n" + functionSignature(&FunctionValue{Name: fn.Name, Parameters: fn.Parameters, Async: fn.Async}) + "\n```"
+		if fn.Doc != "" {
+			text += "\n\n" + fn.Doc
+		}
+	default:
+		text = fmt.Sprintf("```luna\n(%s) %s\n```", declaration.Kind, declaration.Name)
+	}
+
+	return map[string]any{
+		"contents": map[string]any{"kind": "markdown", "value": text},
+		"range":    document.rangeOf(word.position, len([]rune(word.text))),
+	}
+}
+
+// describeNative is the hover text of the native reached at path
+func (s *lspServer) describeNative(path string, value RuntimeValue) string {
+	switch v := value.(type) {
+	case *NativeFunctionValue:
+		text := "```luna\nnative fn " + path + "\n```"
+		if v.Doc != "" {
+			text += "\n\n" + v.Doc
+		}
+		return text
+	case *ObjectValue:
+		return "```luna\n(module) " + path + "\n```"
+	case nil:
+		return "```luna\n(native) " + path + "\n```"
+	}
+	return "```luna\n(native) " + path + " = " + value.String() + "\n```"
+}
+
+// definition is where the name under the cursor is declared
+func (s *lspServer) definition(uri string, position lspPosition) any {
+	document, _, declaration := s.declarationAt(uri, position)
+	if declaration == nil || declaration.Kind == SymbolNative {
+		return nil
+	}
+	return lspLocation{URI: uri, Range: document.rangeOf(declaration.Position, len([]rune(declaration.Name)))}
+}
+
+// completion lists what may be typed at the cursor, the editor narrows it down
+func (s *lspServer) completion(uri string, position lspPosition) []lspCompletionItem {
+	items := []lspCompletionItem{}
+	document, open := s.documents[uri]
+	if !open {
+		return items
+	}
+
+	// After a dot only the members of a native module fit
+	if word, ok := document.wordAt(position); ok && word.object != "" {
+		if object, ok := s.natives[word.object].(*ObjectValue); ok {
+			for _, key := range object.Keys() {
+				item := lspCompletionItem{Label: key, Kind: completionField}
+				if native, ok := object.Properties[key].(*NativeFunctionValue); ok {
+					item.Kind, item.Documentation = completionFunction, native.Doc
+				}
+				items = append(items, item)
+			}
+		}
+		sortCompletions(items)
+		return items
+	}
+
+	for keyword := range keywords {
+		items = append(items, lspCompletionItem{Label: keyword, Kind: completionKeyword})
+	}
+	for name, value := range s.natives {
+		item := lspCompletionItem{Label: name, Kind: completionVariable, Detail: "native"}
+		switch v := value.(type) {
+		case *NativeFunctionValue:
+			item.Kind, item.Documentation = completionFunction, v.Doc
+		case *ObjectValue:
+			item.Kind = completionModule
+		}
+		items = append(items, item)
+	}
+	if document.symbols != nil {
+		seen := make(map[string]bool)
+		for _, declaration := range document.symbols.Declarations {
+			if seen[declaration.Name] || s.natives[declaration.Name] != nil {
+				continue
+			}
+			seen[declaration.Name] = true
+			item := lspCompletionItem{Label: declaration.Name, Kind: completionVariable, Detail: string(declaration.Kind)}
+			if fn := declaration.Function; fn != nil {
+				item.Kind = completionFunction
+				item.Detail = functionSignature(&FunctionValue{Name: fn.Name, Parameters: fn.Parameters, Async: fn.Async})
+				item.Documentation = fn.Doc
+			}
+			items = append(items, item)
+		}
+	}
+	sortCompletions(items)
+	return items
+}
+
+func sortCompletions(items []lspCompletionItem) {
+	sort.Slice(items, func(i, j int) bool { return items[i].Label < items[j].Label })
+}
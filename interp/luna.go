@@ -0,0 +1,77 @@
+package interp
+
+// Luna evaluates code in an environment. A Go program embeds the
+// interpreter by importing luna/interp:
+//
+//	luna := interp.NewLuna(interp.NewGlobalEnvironment())
+//	result, err := luna.Evaluate("1 + 2")
+//
+// ToLuna and FromLuna convert values, Call runs a function a script
+// defined. examples/embed holds complete hosts.
+type Luna struct {
+	env *Environment
+}
+
+func NewLuna(env *Environment) *Luna {
+	return &Luna{env: env}
+}
+
+// NewGlobalEnvironment returns a top-level environment holding the natives
+// and the modules registered with RegisterModule, what scripts expect
+func NewGlobalEnvironment() *Environment {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	return env
+}
+
+// Call runs a Luna function, or a native one, with args in the environment
+// of this interpreter
+func (l *Luna) Call(fn RuntimeValue, args ...RuntimeValue) (RuntimeValue, error) {
+	l.env.startRun()
+	result, err := callValue(fn, args, l.env)
+	if err != nil {
+		return nil, err
+	}
+	if result.Type() == RETURN_TYPE {
+		result = result.(*ReturnValue).Value
+	}
+	return result, nil
+}
+
+func (l *Luna) Tokenize(code string) ([]Token, error) {
+	tokenizer := NewTokenizer(code)
+	return tokenizer.Tokenize()
+}
+
+func (l *Luna) Parse(tokens []Token) (Statement, error) {
+	parser := NewParser(tokens, "")
+	return parser.ProduceAST()
+}
+
+func (l *Luna) Evaluate(code string) (RuntimeValue, error) {
+	pragmas, err := parsePragmas(code)
+	if err != nil {
+		return nil, err
+	}
+	if pragmas != nil {
+		l.env.SetPragmas(pragmas)
+	}
+
+	tokens, err := l.Tokenize(code)
+	if err != nil {
+		return nil, err
+	}
+
+	// The parser gets the code to show where syntax errors are
+	ast, err := NewParser(tokens, code).ProduceAST()
+	if err != nil {
+		return nil, err
+	}
+
+	return l.EvaluateAST(ast)
+}
+
+func (l *Luna) EvaluateAST(ast Statement) (RuntimeValue, error) {
+	l.env.startRun()
+	return Evaluate(ast, l.env)
+}
@@ -0,0 +1,564 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Main runs the luna command: a built binary, a subcommand, scripts or the
+// REPL, as os.Args ask
+func Main() {
+
+	// Binaries produced by `luna build` run their embedded script
+	if runEmbeddedBundle() {
+		return
+	}
+
+	// luna -e "code" runs a one-liner, the arguments after it are the script's.
+	// Several -e run in order in one environment: luna -e 'x = 2' -e 'io.print(x)'
+	if codes, rest, found := inlineProgram(); found {
+		scriptArgs = rest
+		sources := readPreloads()
+		for i, code := range codes {
+			name := "-e"
+			if len(codes) > 1 {
+				name = fmt.Sprintf("-e #%d", i+1)
+			}
+			sources = append(sources, sourceFile{name: name, code: code})
+		}
+		if status := runProgram(sources...); status != 0 {
+			os.Exit(status)
+		}
+		return
+	}
+
+	// get args
+	args := make([]string, 0)
+	interpreterArgs, _ := splitArgs()
+	for i := 0; i < len(interpreterArgs); i++ {
+		arg := interpreterArgs[i]
+		if arg == "--preload" {
+			i++ // the file is the flag's, not the program
+			continue
+		}
+		if strings.HasPrefix(arg, "--") {
+			// skip flags
+			continue
+		}
+		if strings.HasPrefix(arg, "-") && arg != "-" {
+			// skip short flags, a lone - reads the program from stdin
+			continue
+		}
+		args = append(args, arg)
+	}
+
+	// Subcommands
+	if len(args) > 0 {
+		switch args[0] {
+		case "learn":
+			runLearn()
+			return
+		case "fmt":
+			runFormat(args[1:])
+			return
+		case "test":
+			runTest(args[1:])
+			return
+		case "check":
+			runCheck(args[1:])
+			return
+		case "lsp":
+			runLSP()
+			return
+		case "highlight":
+			runHighlight(args[1:])
+			return
+		case "bench":
+			runBench(args[1:])
+			return
+		case "build":
+			runBuild(args[1:])
+			return
+		case "verify":
+			runVerify(args[1:])
+			return
+		case "add":
+			runAdd(args[1:])
+			return
+		case "install":
+			runInstall()
+			return
+		}
+	}
+
+	// If there are arguments, treat them as the files to execute
+	if len(args) > 0 {
+		// Arguments after the last program file are the script's, as os.args
+		filenames := programFiles(args)
+		setScriptArgs()
+
+		sources := readPreloads()
+		for _, filename := range filenames {
+			source, err := readSource(filename)
+			if err != nil {
+				fmt.Printf("Error: Could not read file '%s': %v\n", filename, err)
+				os.Exit(1)
+			}
+			sources = append(sources, source)
+		}
+
+		if status := runProgram(sources...); status != 0 {
+			os.Exit(status)
+		}
+		return
+	}
+
+	session, err := newReplSession()
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	env := session.env
+
+	// A broken config file is reported, the REPL still starts with the defaults
+	config, err := readConfig(defaultConfigFile())
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+	}
+	printBanner(config, env)
+	session.preload()
+
+	prompt := white(">> ")
+	if config.Prompt != "" {
+		prompt = config.Prompt
+	}
+	readline := NewReadline(prompt)
+	showDiff := false // :diff prints the bindings each input changed
+	if isTerminal(int(os.Stdin.Fd())) {
+		readline.SetMultiline(true)
+		readline.SetHighlight(os.Getenv("NO_COLOR") == "")
+		if path := defaultHistoryFile(); path != "" {
+			readline.LoadHistory(path)
+		}
+	}
+
+	for {
+		// Spawned tasks keep running while the prompt waits
+		var input string
+		var err error
+		blockingCall(env, func() { input, err = readline.ReadLine() })
+		if err != nil {
+			break
+		}
+
+		input = strings.TrimSpace(input)
+		if input == "" {
+			continue
+		}
+
+		if input == "exit()" {
+			fmt.Println(gray("Exiting..."))
+			break
+		}
+
+		if runInspectCommand(input, env, session.last) {
+			continue
+		}
+
+		if session.runCommand(input) {
+			env = session.env
+			continue
+		}
+
+		if input == ":browse" {
+			runBrowser(env, readline.reader)
+			continue
+		}
+
+		if input == ":diff" {
+			showDiff = !showDiff
+			if showDiff {
+				fmt.Println(gray("diff on"))
+			} else {
+				fmt.Println(gray("diff off"))
+			}
+			continue
+		}
+
+		// Check for balanced brackets
+		if !isBalanced(input) {
+			for {
+				nesting := countNesting(input)
+				line, err := readline.ReadLine(strings.Repeat("  ", nesting) + gray("... "))
+				if err != nil {
+					break
+				}
+				input += " " + line
+				if isBalanced(input) {
+					break
+				}
+			}
+		}
+
+		var before map[string]string
+		if showDiff {
+			before = env.Snapshot()
+		}
+
+		luna := NewLuna(env)
+		result, err := luna.Evaluate(input)
+		if err != nil {
+			exitOnRequest(err)
+			reportError(err, "", "")
+		} else {
+			session.record(input)
+		}
+		if err == nil && result != nil && result.Type() != VOID_TYPE {
+			session.remember(result)
+
+			// Colorize the output
+			output := displayValue(result, env, false)
+			if output != "" {
+				fmt.Println(output)
+			}
+		}
+
+		if showDiff {
+			printDiff(before, env)
+		}
+	}
+}
+
+// runProgram evaluates the files of a program in order in one shared
+// environment, and prints the result or error, it returns the exit status:
+// 1 when the program failed, the code of exit(code) when it called it
+func runProgram(sources ...sourceFile) int {
+	// Dump tokens or AST instead of evaluating
+	if hasFlag("--tokens", "--ast") {
+		for _, source := range sources {
+			output, err := dumpFile(source.code, hasFlag("--ast"))
+			if err != nil {
+				reportError(err, source.name, source.label(len(sources)))
+				return 1
+			}
+			fmt.Println(output)
+		}
+		return 0
+	}
+
+	// Create a new Luna instance and evaluate the program
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	if err := setupFromFlags(env); err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		return 1
+	}
+	setupProfile()
+
+	// --record keeps a trace of the run to step through afterwards, the
+	// replay shows the lines of the last file
+	var recorder *Recorder
+	if _, found := flagValue("--record"); found || hasFlag("--record") {
+		recorder = NewRecorder(env, recordLimit())
+		recorder.Start()
+		defer func() {
+			recorder.Stop()
+			(&Replayer{recorder: recorder, lines: strings.Split(sources[len(sources)-1].code, "\n")}).Run()
+		}()
+	}
+
+	luna := NewLuna(env)
+	var result RuntimeValue
+	for _, source := range sources {
+		collectWarnings(env, source.code, source.name)
+		env.SetModule(source.name)
+		var err error
+		result, err = luna.Evaluate(source.code)
+		if err != nil {
+			stopStatusLines()
+			var exit *ExitError
+			if errors.As(err, &exit) {
+				finishProgram(env)
+				return exit.Code
+			}
+			if jsonErrors() {
+				printDiagnostic(diagnosticOf(err, source.name))
+				finishProgram(env)
+				return 1
+			}
+			message := formatError(errorLabel(err), source.label(len(sources))+err.Error())
+			if env.Pragmas().NoColor {
+				message = stripColor(message)
+			}
+			fmt.Println(message)
+			finishProgram(env)
+			return 1
+		}
+	}
+
+	if result != nil && result.Type() != VOID_TYPE {
+		// Colorize the output, unless the file has a no-color pragma
+		output := displayValue(result, env, false)
+		if output != "" {
+			printOutput(output)
+		}
+	}
+
+	// Pending timeouts and intervals keep the script running
+	waitForTimers(env)
+	stopStatusLines()
+	finishProgram(env)
+	return 0
+}
+
+// exitOnRequest ends the luna command when err is the exit() of a script
+func exitOnRequest(err error) {
+	var exit *ExitError
+	if errors.As(err, &exit) {
+		os.Exit(exit.Code)
+	}
+}
+
+// finishProgram reports what was collected while the program ran, the
+// warnings and the profile
+func finishProgram(env *Environment) {
+	printWarnings(env)
+	reportProfile()
+}
+
+// sourceFile is one file of a program, - for stdin and -e for a one-liner
+type sourceFile struct {
+	name string
+	code string
+}
+
+// label prefixes errors with the file name when a program has several files
+func (s sourceFile) label(files int) string {
+	if files > 1 {
+		return s.name + ": "
+	}
+	return ""
+}
+
+// entryFiles are the files looked for run when a directory is given instead of a file
+var entryFiles = []string{"main.luna", "main.ln"}
+
+// lunaExtensions mark the arguments that are program files rather than script arguments
+var lunaExtensions = []string{".luna", ".ln", ".lnx"}
+
+// subcommands are the first arguments that run a tool instead of a program
+var subcommands = []string{"learn", "fmt", "test", "check", "lsp", "highlight", "bench", "build", "verify", "add", "install"}
+
+// splitArgs splits the command line into the interpreter's arguments, its
+// flags up to and including the program files, and the script's, the ones
+// after them: in `luna tool.ln --ast` the script gets --ast. -- ends the
+// interpreter's arguments too, luna -- tool.ln runs tool.ln and luna -e code
+// -- -x gives the code -x. A subcommand reads all of the arguments.
+func splitArgs() (interpreter []string, script []string) {
+	args := os.Args[1:]
+	inline := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			if inline || i+1 == len(args) {
+				return args[:i], args[i+1:]
+			}
+			return append(args[:i:i], args[i+1]), args[i+2:]
+		case arg == "--preload":
+			i++ // the file is the flag's, not the program
+		case arg == "-e" || arg == "-c":
+			i++ // so is the code
+			inline = true
+		case strings.HasPrefix(arg, "-") && arg != "-":
+			// a flag, a lone - reads the program from stdin
+		case inline:
+			return args[:i], args[i:]
+		case slices.Contains(subcommands, arg):
+			return args, nil
+		default:
+			end := i + len(programFiles(args[i:]))
+			return args[:end], args[end:]
+		}
+	}
+	return args, nil
+}
+
+// programFiles splits the leading program files from the arguments, the
+// first argument is always a program, the following ones only when they are
+// existing Luna files
+func programFiles(args []string) []string {
+	files := args[:1]
+	for _, arg := range args[1:] {
+		info, err := os.Stat(arg)
+		if err != nil || info.IsDir() || !slices.Contains(lunaExtensions, filepath.Ext(arg)) {
+			break
+		}
+		files = append(files, arg)
+	}
+	return files
+}
+
+// readSource reads a program file, - is stdin and a directory runs its main.luna
+func readSource(filename string) (sourceFile, error) {
+	if filename == "-" {
+		data, err := io.ReadAll(os.Stdin)
+		return sourceFile{name: filename, code: string(data)}, err
+	}
+
+	path := filename
+	if info, err := os.Stat(filename); err == nil && info.IsDir() {
+		path = ""
+		for _, entry := range entryFiles {
+			if _, err := os.Stat(filepath.Join(filename, entry)); err == nil {
+				path = filepath.Join(filename, entry)
+				break
+			}
+		}
+		if path == "" {
+			return sourceFile{}, fmt.Errorf("no %s in directory", strings.Join(entryFiles, " or "))
+		}
+	}
+
+	// try to read the relative file (using fs library)
+	data, err := fs.ReadFile(os.DirFS("."), filepath.ToSlash(filepath.Clean(path)))
+	return sourceFile{name: path, code: string(data)}, err
+}
+
+// inlineProgram finds the code of each -e (or -c) and the script's
+// arguments, the ones after the last. It exits when a -e has no code.
+func inlineProgram() ([]string, []string, bool) {
+	args, rest := splitArgs()
+	var codes []string
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-e" || args[i] == "-c" {
+			if i+1 == len(args) {
+				fmt.Println(formatError("Error", args[i]+" needs an argument"))
+				os.Exit(1)
+			}
+			i++
+			codes = append(codes, args[i])
+		}
+	}
+	return codes, rest, len(codes) > 0
+}
+
+// preloadFiles are the files given with --preload file or --preload=file,
+// run before the program or the REPL in the same environment
+func preloadFiles() []string {
+	var files []string
+	args, _ := splitArgs()
+	for i := 0; i < len(args); i++ {
+		if file, found := strings.CutPrefix(args[i], "--preload="); found {
+			files = append(files, file)
+		} else if args[i] == "--preload" && i+1 < len(args) {
+			i++
+			files = append(files, args[i])
+		}
+	}
+	return files
+}
+
+// readPreloads reads the --preload files, exiting when one cannot be read
+func readPreloads() []sourceFile {
+	var sources []sourceFile
+	for _, file := range preloadFiles() {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Printf("Error: Could not read preload file '%s': %v\n", file, err)
+			os.Exit(1)
+		}
+		sources = append(sources, sourceFile{name: file, code: string(data)})
+	}
+	return sources
+}
+
+// hasFlag reports whether any of the given flags was passed to the interpreter
+func hasFlag(names ...string) bool {
+	args, _ := splitArgs()
+	for _, arg := range args {
+		for _, name := range names {
+			if arg == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isBalanced(input string) bool {
+	stack := 0
+	inString := false
+	escaped := false
+
+	for _, char := range input {
+		if escaped {
+			escaped = false
+			continue
+		}
+
+		if char == '\\' {
+			escaped = true
+			continue
+		}
+
+		if char == '"' || char == '\'' {
+			inString = !inString
+			continue
+		}
+
+		if !inString {
+			switch char {
+			case '{', '(', '[':
+				stack++
+			case '}', ')', ']':
+				stack--
+			}
+		}
+	}
+
+	return stack == 0
+}
+
+// countNesting returns the current nesting level of brackets in the input string.
+func countNesting(input string) int {
+	stack := 0
+	inString := false
+	escaped := false
+
+	for _, char := range input {
+		if escaped {
+			escaped = false
+			continue
+		}
+
+		if char == '\\' {
+			escaped = true
+			continue
+		}
+
+		if char == '"' || char == '\'' {
+			inString = !inString
+			continue
+		}
+
+		if !inString {
+			switch char {
+			case '{', '(', '[':
+				stack++
+			case '}', ')', ']':
+				if stack > 0 {
+					stack--
+				}
+			}
+		}
+	}
+
+	return stack
+}
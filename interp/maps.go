@@ -0,0 +1,257 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// A map holds entries under number, string and boolean keys without turning
+// them into strings the way object properties do, so 1, "1" and true are
+// three different keys. Numbers equal by value are the same key, 1 and 1.0
+// and 1n, like ==. Entries keep the order they were first set in, for-in
+// walks the keys in that order.
+//
+//	m = map()                    empty
+//	m = map([[1, "one"], [2, "two"]])
+//	m = map({ a: 1 })            from the properties of an object
+//	m.set(3, "three")            m.get(3), m.has(3), m.delete(3), m.size()
+
+// mapKey identifies a key whatever its number kind
+type mapKey struct {
+	kind byte // 'n' number, 's' string, 'b' boolean
+	text string
+}
+
+// mapEntry is a key as first set and its current value
+type mapEntry struct {
+	key   RuntimeValue
+	value RuntimeValue
+}
+
+// Map Value, an ordered dictionary with non-string keys
+type MapValue struct {
+	entries []mapEntry
+	index   map[mapKey]int // position of each key in entries
+}
+
+func MakeMap() *MapValue {
+	return &MapValue{index: make(map[mapKey]int)}
+}
+
+func (m *MapValue) Type() ValueType { return MAP_TYPE }
+func (m *MapValue) String() string  { return nestedString(m, nil) }
+func (m *MapValue) IsTruthy() bool  { return len(m.entries) > 0 }
+func (m *MapValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, fn := range MapPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return fn(m, args, env)
+		}))
+	}
+
+	return &prototypes
+}
+
+// keyOf normalizes a map key, other types than numbers, strings and booleans are an error
+func keyOf(key RuntimeValue) (mapKey, error) {
+	switch k := key.(type) {
+	case *StringValue:
+		return mapKey{'s', k.Value}, nil
+	case *BooleanValue:
+		return mapKey{'b', k.String()}, nil
+	case *IntValue:
+		return mapKey{'n', strconv.FormatInt(k.Value, 10)}, nil
+	case *BigIntValue:
+		return mapKey{'n', k.Value.String()}, nil
+	case *NumberValue:
+		// Whole floats share the key of the int with their value
+		if !math.IsInf(k.Value, 0) && k.Value == math.Trunc(k.Value) {
+			whole, _ := big.NewFloat(k.Value).Int(nil)
+			return mapKey{'n', whole.String()}, nil
+		}
+		return mapKey{'n', strconv.FormatFloat(k.Value, 'g', -1, 64)}, nil
+	}
+	return mapKey{}, fmt.Errorf("expected a number, string or boolean, got %s", key.Type())
+}
+
+// Get finds the value under key
+func (m *MapValue) Get(key RuntimeValue) (RuntimeValue, bool, error) {
+	k, err := keyOf(key)
+	if err != nil {
+		return nil, false, err
+	}
+	if i, ok := m.index[k]; ok {
+		return m.entries[i].value, true, nil
+	}
+	return nil, false, nil
+}
+
+// Set stores value under key, a new key goes after the existing ones
+func (m *MapValue) Set(key, value RuntimeValue) error {
+	k, err := keyOf(key)
+	if err != nil {
+		return err
+	}
+	if i, ok := m.index[k]; ok {
+		m.entries[i].value = value
+		return nil
+	}
+	m.index[k] = len(m.entries)
+	m.entries = append(m.entries, mapEntry{key: key, value: value})
+	return nil
+}
+
+// Delete removes key, reporting whether it was there
+func (m *MapValue) Delete(key RuntimeValue) (bool, error) {
+	k, err := keyOf(key)
+	if err != nil {
+		return false, err
+	}
+	i, ok := m.index[k]
+	if !ok {
+		return false, nil
+	}
+	delete(m.index, k)
+	m.entries = append(m.entries[:i:i], m.entries[i+1:]...)
+	for j := i; j < len(m.entries); j++ {
+		k, _ := keyOf(m.entries[j].key)
+		m.index[k] = j
+	}
+	return true, nil
+}
+
+// Keys lists the keys in insertion order
+func (m *MapValue) Keys() []RuntimeValue {
+	keys := make([]RuntimeValue, len(m.entries))
+	for i, entry := range m.entries {
+		keys[i] = entry.key
+	}
+	return keys
+}
+
+func (m *MapValue) Len() int { return len(m.entries) }
+
+var MapPrototype = map[string]func(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"get":     mapGet,
+	"set":     mapSet,
+	"has":     mapHas,
+	"delete":  mapDelete,
+	"size":    mapSize,
+	"keys":    mapKeys,
+	"values":  mapValues,
+	"entries": mapEntries,
+}
+
+// mapGet is m.get(key, default), default (or undef) when key is missing
+func mapGet(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("map.get requires a key and an optional default")
+	}
+	value, found, err := m.Get(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("map.get: %v", err)
+	}
+	if found {
+		return value, nil
+	}
+	if len(args) == 2 {
+		return args[1], nil
+	}
+	return MakeUndefined(), nil
+}
+
+// mapSet is m.set(key, value), returning the map
+func mapSet(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("map.set requires a key and a value")
+	}
+	if err := m.Set(args[0], args[1]); err != nil {
+		return nil, fmt.Errorf("map.set: %v", err)
+	}
+	return m, nil
+}
+
+func mapHas(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("map.has requires a key")
+	}
+	_, found, err := m.Get(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("map.has: %v", err)
+	}
+	return MakeBool(found), nil
+}
+
+// mapDelete is m.delete(key), true when the key was there
+func mapDelete(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("map.delete requires a key")
+	}
+	found, err := m.Delete(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("map.delete: %v", err)
+	}
+	return MakeBool(found), nil
+}
+
+func mapSize(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeInt(int64(m.Len())), nil
+}
+
+func mapKeys(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeArray(m.Keys()), nil
+}
+
+func mapValues(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	values := make([]RuntimeValue, len(m.entries))
+	for i, entry := range m.entries {
+		values[i] = entry.value
+	}
+	return MakeArray(values), nil
+}
+
+// mapEntries is m.entries(), the [key, value] pairs in order
+func mapEntries(m *MapValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	entries := make([]RuntimeValue, len(m.entries))
+	for i, entry := range m.entries {
+		entries[i] = MakeArray([]RuntimeValue{entry.key, entry.value})
+	}
+	return MakeArray(entries), nil
+}
+
+// mapNative is map(), map(pairs) or map(object)
+func mapNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("map expects at most 1 argument, got %d", len(args))
+	}
+	m := MakeMap()
+	if len(args) == 0 {
+		return env.counted(m)
+	}
+
+	switch source := args[0].(type) {
+	case *ArrayValue:
+		for i, element := range source.Elements {
+			pair, ok := element.(*ArrayValue)
+			if !ok || len(pair.Elements) != 2 {
+				return nil, fmt.Errorf("map: element %d must be a [key, value] pair", i)
+			}
+			if err := m.Set(pair.Elements[0], pair.Elements[1]); err != nil {
+				return nil, fmt.Errorf("map: %v", err)
+			}
+		}
+	case *ObjectValue:
+		for _, key := range source.Keys() {
+			m.Set(MakeString(key), source.Properties[key])
+		}
+	case *MapValue:
+		for _, entry := range source.entries {
+			m.Set(entry.key, entry.value)
+		}
+	default:
+		return nil, fmt.Errorf("map expects an array of pairs or an object, got %s", args[0].Type())
+	}
+	return env.counted(m)
+}
@@ -0,0 +1,426 @@
+package interp
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ToLuna and FromLuna move data between Go and scripts, so hosts do not
+// build ObjectValue trees by hand. Go integers become ints, ints become int64,
+// bigints *big.Int and floats float64. Maps with any key type become objects (keys formatted
+// with fmt, in sorted order), structs become objects of their exported fields
+// in declaration order. A field is named by its `luna:"name"` tag, else its
+// `json:"name"` tag, else its Go name, and "-" leaves it out. A value that
+// contains itself cannot be converted either way, it is an error.
+
+var (
+	runtimeValueType = reflect.TypeOf((*RuntimeValue)(nil)).Elem()
+	timeType         = reflect.TypeOf(time.Time{})
+	bigIntType       = reflect.TypeOf(big.Int{})
+)
+
+// errConvertCircular is returned for a value that contains itself
+var errConvertCircular = fmt.Errorf("cannot convert a circular structure")
+
+// goReference is a pointer, map or slice being converted by ToLuna
+type goReference struct {
+	pointer uintptr
+	typ     reflect.Type
+}
+
+// enterGoPath adds v to path, the references being converted around it, like
+// enterPath does for Luna values. It reports false when v is on path already
+// or path is maxDecodeDepth deep.
+func enterGoPath(path []goReference, v reflect.Value) ([]goReference, bool) {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return path, true
+		}
+	default:
+		return path, true
+	}
+	reference := goReference{pointer: v.Pointer(), typ: v.Type()}
+	if len(path) >= maxDecodeDepth || slices.Contains(path, reference) {
+		return path, false
+	}
+	return append(path, reference), true
+}
+
+// ToLuna converts a Go value to a RuntimeValue
+func ToLuna(value any) (RuntimeValue, error) {
+	if value == nil {
+		return MakeNull(), nil
+	}
+	return toLuna(reflect.ValueOf(value), nil)
+}
+
+// toLuna converts v, path holds the references around it
+func toLuna(v reflect.Value, path []goReference) (RuntimeValue, error) {
+	if v.Type().Implements(runtimeValueType) && !(v.Kind() == reflect.Interface && v.IsNil()) {
+		if value, ok := v.Interface().(RuntimeValue); ok {
+			return value, nil
+		}
+	}
+	if v.Type() == timeType {
+		return makeDate(v.Interface().(time.Time)), nil
+	}
+	if v.Type() == bigIntType {
+		value := v.Interface().(big.Int)
+		return MakeBigInt(new(big.Int).Set(&value)), nil
+	}
+	if call, ok := v.Interface().(func([]RuntimeValue, *Environment) (RuntimeValue, error)); ok {
+		return MakeNativeFunction("native", call), nil
+	}
+	var entered bool
+	if path, entered = enterGoPath(path, v); !entered {
+		return nil, errConvertCircular
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return MakeNull(), nil
+		}
+		return toLuna(v.Elem(), path)
+	case reflect.Bool:
+		return MakeBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return MakeInt(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return unsignedNumber(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return MakeNumber(v.Float()), nil
+	case reflect.String:
+		return MakeString(v.String()), nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return MakeString(string(v.Bytes())), nil
+		}
+		elements := make([]RuntimeValue, v.Len())
+		for i := range elements {
+			element, err := toLuna(v.Index(i), path)
+			if err != nil {
+				return nil, fmt.Errorf("[%d]: %v", i, err)
+			}
+			elements[i] = element
+		}
+		return MakeArray(elements), nil
+	case reflect.Map:
+		properties := make(map[string]RuntimeValue, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+			value, err := toLuna(iter.Value(), path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			properties[key] = value
+		}
+		return MakeObject(properties), nil
+	case reflect.Struct:
+		object := newObject()
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			value, err := toLuna(v.Field(i), path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+			object.Set(name, value)
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("cannot convert %s to a Luna value", v.Type())
+}
+
+// fieldName is the property name of a struct field, false for fields left out
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	for _, key := range []string{"luna", "json"} {
+		if tag, ok := field.Tag.Lookup(key); ok {
+			name, _, _ := strings.Cut(tag, ",")
+			if name == "-" {
+				return "", false
+			}
+			if name != "" {
+				return name, true
+			}
+		}
+	}
+	return field.Name, true
+}
+
+// FromLuna converts a RuntimeValue to plain Go values: nil, bool, int64,
+// float64, string, []any for arrays and sets, map[string]any for objects and
+// map[any]any for maps. Values without a Go counterpart, like functions,
+// channels or secrets, are returned as they are.
+func FromLuna(value RuntimeValue) (any, error) {
+	return fromLunaValue(value, nil)
+}
+
+// fromLunaValue converts value, path holds the containers around it
+func fromLunaValue(value RuntimeValue, path []RuntimeValue) (any, error) {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue, *MapValue, *SetValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered || len(path) > maxDecodeDepth {
+			return nil, errConvertCircular
+		}
+	}
+
+	switch v := value.(type) {
+	case *NullValue, *UndefinedValue, *VoidValue:
+		return nil, nil
+	case *BooleanValue:
+		return v.Value, nil
+	case *NumberValue:
+		return v.Value, nil
+	case *IntValue:
+		return v.Value, nil
+	case *BigIntValue:
+		return new(big.Int).Set(v.Value), nil
+	case *StringValue:
+		return v.Value, nil
+	case *ArrayValue:
+		return fromLunaElements(v.Elements, path)
+	case *SetValue:
+		return fromLunaElements(v.Elements(), path)
+	case *RangeValue:
+		elements := make([]any, v.Len())
+		for i := range elements {
+			elements[i] = v.At(i)
+		}
+		return elements, nil
+	case *ObjectValue:
+		properties := make(map[string]any, len(v.Properties))
+		for key, property := range v.Properties {
+			converted, err := fromLunaValue(property, path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			properties[key] = converted
+		}
+		return properties, nil
+	case *MapValue:
+		entries := make(map[any]any, v.Len())
+		for _, key := range v.Keys() {
+			// Keys are numbers, strings and booleans, they convert without error
+			goKey, _ := fromLunaValue(key, path)
+			property, _, _ := v.Get(key)
+			converted, err := fromLunaValue(property, path)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", key, err)
+			}
+			entries[goKey] = converted
+		}
+		return entries, nil
+	}
+	return value, nil
+}
+
+// fromLunaElements converts the elements of an array or a set
+func fromLunaElements(elements []RuntimeValue, path []RuntimeValue) ([]any, error) {
+	converted := make([]any, len(elements))
+	for i, element := range elements {
+		value, err := fromLunaValue(element, path)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %v", i, err)
+		}
+		converted[i] = value
+	}
+	return converted, nil
+}
+
+// FromLunaInto stores a RuntimeValue in the Go value target points to,
+// converting numbers to the kind of the target, objects to structs or maps,
+// maps to maps and arrays and sets to slices
+func FromLunaInto(value RuntimeValue, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return fmt.Errorf("FromLunaInto needs a non-nil pointer, got %T", target)
+	}
+	return fromLuna(value, v.Elem(), nil)
+}
+
+// fromLuna stores value in target, path holds the containers around it
+func fromLuna(value RuntimeValue, target reflect.Value, path []RuntimeValue) error {
+	if target.Type() == runtimeValueType {
+		target.Set(reflect.ValueOf(&value).Elem())
+		return nil
+	}
+	if target.Type() == timeType {
+		t, err := dateArg("FromLunaInto", value)
+		if err != nil {
+			return err
+		}
+		target.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if target.Type() == bigIntType {
+		n, ok := toBig(value)
+		if !ok {
+			return fmt.Errorf("cannot store %s in %s", value.Type(), target.Type())
+		}
+		target.Set(reflect.ValueOf(*new(big.Int).Set(n)))
+		return nil
+	}
+
+	switch value.(type) {
+	case *NullValue, *UndefinedValue, *VoidValue:
+		target.Set(reflect.Zero(target.Type()))
+		return nil
+	}
+
+	mismatch := func() error {
+		return fmt.Errorf("cannot store %s in %s", value.Type(), target.Type())
+	}
+	// enter puts value on path before its elements are stored
+	enter := func() error {
+		var entered bool
+		if path, entered = enterPath(path, value); !entered || len(path) > maxDecodeDepth {
+			return errConvertCircular
+		}
+		return nil
+	}
+
+	switch target.Kind() {
+	case reflect.Pointer:
+		element := reflect.New(target.Type().Elem())
+		if err := fromLuna(value, element.Elem(), path); err != nil {
+			return err
+		}
+		target.Set(element)
+	case reflect.Interface:
+		plain, err := fromLunaValue(value, path)
+		if err != nil {
+			return err
+		}
+		converted := reflect.ValueOf(plain)
+		if !converted.Type().AssignableTo(target.Type()) {
+			return mismatch()
+		}
+		target.Set(converted)
+	case reflect.Bool:
+		b, ok := value.(*BooleanValue)
+		if !ok {
+			return mismatch()
+		}
+		target.SetBool(b.Value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt(value)
+		if !ok || target.OverflowInt(n) {
+			return mismatch()
+		}
+		target.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		n, ok := toInt(value)
+		if !ok || n < 0 || target.OverflowUint(uint64(n)) {
+			return mismatch()
+		}
+		target.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		n, ok := toFloat(value)
+		if !ok {
+			return mismatch()
+		}
+		target.SetFloat(n)
+	case reflect.String:
+		s, ok := value.(*StringValue)
+		if !ok {
+			return mismatch()
+		}
+		target.SetString(s.Value)
+	case reflect.Slice:
+		if s, ok := value.(*StringValue); ok && target.Type().Elem().Kind() == reflect.Uint8 {
+			target.SetBytes([]byte(s.Value))
+			return nil
+		}
+		var elements []RuntimeValue
+		switch v := value.(type) {
+		case *ArrayValue:
+			elements = v.Elements
+		case *SetValue:
+			elements = v.Elements()
+		default:
+			return mismatch()
+		}
+		if err := enter(); err != nil {
+			return err
+		}
+		slice := reflect.MakeSlice(target.Type(), len(elements), len(elements))
+		for i, element := range elements {
+			if err := fromLuna(element, slice.Index(i), path); err != nil {
+				return fmt.Errorf("[%d]: %v", i, err)
+			}
+		}
+		target.Set(slice)
+	case reflect.Map:
+		var keys []RuntimeValue
+		switch v := value.(type) {
+		case *ObjectValue:
+			if target.Type().Key().Kind() != reflect.String {
+				return mismatch()
+			}
+			for _, key := range v.Keys() {
+				keys = append(keys, MakeString(key))
+			}
+		case *MapValue:
+			keys = v.Keys()
+		default:
+			return mismatch()
+		}
+		if err := enter(); err != nil {
+			return err
+		}
+		m := reflect.MakeMapWithSize(target.Type(), len(keys))
+		for _, key := range keys {
+			var property RuntimeValue
+			if object, ok := value.(*ObjectValue); ok {
+				property = object.Properties[key.(*StringValue).Value]
+			} else {
+				property, _, _ = value.(*MapValue).Get(key)
+			}
+			goKey := reflect.New(target.Type().Key()).Elem()
+			if err := fromLuna(key, goKey, path); err != nil {
+				return fmt.Errorf("key %s: %v", key, err)
+			}
+			element := reflect.New(target.Type().Elem()).Elem()
+			if err := fromLuna(property, element, path); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+			m.SetMapIndex(goKey, element)
+		}
+		target.Set(m)
+	case reflect.Struct:
+		object, ok := value.(*ObjectValue)
+		if !ok {
+			return mismatch()
+		}
+		if err := enter(); err != nil {
+			return err
+		}
+		for i := 0; i < target.NumField(); i++ {
+			name, ok := fieldName(target.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if property, exists := object.Properties[name]; exists {
+				if err := fromLuna(property, target.Field(i), path); err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+			}
+		}
+	default:
+		return mismatch()
+	}
+	return nil
+}
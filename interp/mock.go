@@ -0,0 +1,166 @@
+package interp
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// mockResponse is a canned response served by a mock route
+type mockResponse struct {
+	status  int
+	body    string
+	headers map[string]string
+}
+
+// MockServer serves canned responses described by a Luna routes object
+type MockServer struct {
+	server   *http.Server
+	listener net.Listener
+	routes   map[string]mockResponse // "METHOD /path" or "/path" for any method
+
+	mu       sync.Mutex
+	requests []RuntimeValue
+}
+
+// parseMockResponse accepts a body string or an object { status, body, headers }
+func parseMockResponse(route string, value RuntimeValue) (mockResponse, error) {
+	response := mockResponse{status: http.StatusOK, headers: make(map[string]string)}
+
+	switch v := value.(type) {
+	case *StringValue:
+		response.body = v.Value
+	case *ObjectValue:
+		if status, exists := v.Properties["status"]; exists {
+			num, ok := toFloat(status)
+			if !ok {
+				return response, fmt.Errorf("route '%s': status must be a number", route)
+			}
+			response.status = int(num)
+		}
+		if body, exists := v.Properties["body"]; exists {
+			if str, ok := body.(*StringValue); ok {
+				response.body = str.Value
+			} else {
+				response.body = body.String()
+			}
+		}
+		if headers, exists := v.Properties["headers"]; exists {
+			object, ok := headers.(*ObjectValue)
+			if !ok {
+				return response, fmt.Errorf("route '%s': headers must be an object", route)
+			}
+			for name, header := range object.Properties {
+				if str, ok := header.(*StringValue); ok {
+					response.headers[name] = str.Value
+				} else {
+					response.headers[name] = header.String()
+				}
+			}
+		}
+	default:
+		return response, fmt.Errorf("route '%s': response must be a string or an object, got %s", route, value.Type())
+	}
+
+	return response, nil
+}
+
+// NewMockServer starts serving the routes on a random local port
+func NewMockServer(routes *ObjectValue) (*MockServer, error) {
+	mock := &MockServer{routes: make(map[string]mockResponse)}
+	for _, route := range routes.Keys() {
+		value := routes.Properties[route]
+		response, err := parseMockResponse(route, value)
+		if err != nil {
+			return nil, err
+		}
+		mock.routes[strings.TrimSpace(route)] = response
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	mock.listener = listener
+	mock.server = &http.Server{Handler: mock}
+
+	go mock.server.Serve(listener)
+	return mock, nil
+}
+
+func (m *MockServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	m.mu.Lock()
+	m.requests = append(m.requests, MakeObject(map[string]RuntimeValue{
+		"method": MakeString(r.Method),
+		"path":   MakeString(r.URL.Path),
+		"query":  MakeString(r.URL.RawQuery),
+		"body":   MakeString(string(body)),
+	}))
+	m.mu.Unlock()
+
+	response, exists := m.routes[r.Method+" "+r.URL.Path]
+	if !exists {
+		response, exists = m.routes[r.URL.Path]
+	}
+	if !exists {
+		http.Error(w, "no mock route for "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+		return
+	}
+
+	for name, value := range response.headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(response.status)
+	io.WriteString(w, response.body)
+}
+
+func (m *MockServer) URL() string {
+	return "http://" + m.listener.Addr().String()
+}
+
+// Requests returns a snapshot of the requests received so far
+func (m *MockServer) Requests() []RuntimeValue {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]RuntimeValue{}, m.requests...)
+}
+
+func (m *MockServer) Close() error {
+	return m.server.Close()
+}
+
+func createMockObject() RuntimeValue {
+	mockProps := make(map[string]RuntimeValue)
+
+	mockProps["http"] = MakeNativeFunction("http", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 || args[0].Type() != OBJECT_TYPE {
+			return nil, fmt.Errorf("mock.http expects a routes object")
+		}
+
+		mock, err := NewMockServer(args[0].(*ObjectValue))
+		if err != nil {
+			return nil, fmt.Errorf("mock.http: %v", err)
+		}
+
+		serverProps := make(map[string]RuntimeValue)
+		serverProps["url"] = MakeString(mock.URL())
+		serverProps["requests"] = MakeNativeFunction("requests", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return MakeArray(mock.Requests()), nil
+		})
+		serverProps["close"] = MakeNativeFunction("close", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if err := mock.Close(); err != nil {
+				return nil, fmt.Errorf("mock.close: %v", err)
+			}
+			return MakeVoid(), nil
+		})
+
+		return MakeObject(serverProps), nil
+	})
+
+	return MakeObject(mockProps)
+}
@@ -0,0 +1,259 @@
+package interp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Modules are loaded by `use "path"`: the module runs once in its own scope
+// and the names it declares with `out` become visible to the user. A module
+// can be a file, relative to the file that uses it, an installed package
+// ("pkg:name", see packages.go) or an https:// URL. URLs
+// are downloaded once into the module cache, a "#sha256-<hex>" suffix pins
+// the content they must have. --offline only uses what is already cached.
+
+// moduleFetchTimeout bounds the download of a module from a URL
+const moduleFetchTimeout = 30 * time.Second
+
+var (
+	loadedModules  = make(map[string]map[string]RuntimeValue) // exports by module location
+	loadingModules = make(map[string]bool)                    // modules being evaluated, to catch cycles
+)
+
+// moduleCacheDir is where modules from URLs are kept, ~/.luna/cache
+func moduleCacheDir() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".luna", "cache"), nil
+}
+
+func evaluateUseStatement(node *UseStatement, env *Environment) (RuntimeValue, error) {
+	// use "strict" is a pragma, read before the module ran
+	if node.Path == "strict" {
+		if !env.Strict() {
+			return nil, fmt.Errorf("use \"strict\" must come before the first line of code")
+		}
+		return MakeVoid(), nil
+	}
+
+	if env.root().disabled["use"] {
+		return nil, &PermissionError{Module: "use", Name: "use"}
+	}
+
+	path, err := resolvePath(node.Path, env)
+	if err != nil {
+		return nil, fmt.Errorf("use \"%s\": %v", node.Path, err)
+	}
+	path = relativeToModule(path, env.Module())
+	location := path
+	if !isModuleURL(location) && !strings.HasPrefix(location, packagePrefix) {
+		if absolute, err := filepath.Abs(location); err == nil {
+			location = absolute
+		}
+	}
+
+	exports, loaded := loadedModules[location]
+	if !loaded {
+		if loadingModules[location] {
+			return nil, fmt.Errorf("circular use of \"%s\"", node.Path)
+		}
+		loadingModules[location] = true
+		var err error
+		exports, err = loadModule(path, env)
+		delete(loadingModules, location)
+		if err != nil {
+			return nil, fmt.Errorf("use \"%s\": %v", node.Path, err)
+		}
+		loadedModules[location] = exports
+	}
+
+	for name, value := range exports {
+		env.DeclareVar(name, value, true)
+	}
+	return MakeVoid(), nil
+}
+
+func isModuleURL(path string) bool {
+	return strings.HasPrefix(path, "https://")
+}
+
+// relativeToModule resolves a relative path used by module against the
+// directory of its file or URL, other paths are returned as they are
+func relativeToModule(path, module string) string {
+	if module == "" || isModuleURL(path) || strings.HasPrefix(path, packagePrefix) || filepath.IsAbs(path) {
+		return path
+	}
+	if isModuleURL(module) {
+		base, err := url.Parse(module)
+		if err != nil {
+			return path
+		}
+		reference, err := url.Parse(filepath.ToSlash(path))
+		if err != nil {
+			return path
+		}
+		return base.ResolveReference(reference).String()
+	}
+	return filepath.Join(filepath.Dir(module), path)
+}
+
+// loadModule reads and evaluates a module, returning the values it exports
+func loadModule(path string, env *Environment) (map[string]RuntimeValue, error) {
+	var code []byte
+	var err error
+	module := path
+	switch {
+	case isModuleURL(path):
+		code, err = fetchModule(path, env)
+	case strings.HasPrefix(path, packagePrefix):
+		if module, err = packageFile(strings.TrimPrefix(path, packagePrefix)); err == nil {
+			code, err = os.ReadFile(module)
+		}
+	default:
+		code, err = readModuleFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	pragmas, err := parsePragmas(string(code))
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := NewTokenizer(string(code)).Tokenize()
+	if err != nil {
+		return nil, err
+	}
+	program, err := NewParser(tokens, string(code)).ProduceAST()
+	if err != nil {
+		return nil, err
+	}
+
+	// The module sees the globals, not the scope it is used from
+	root := env
+	for root.parent != nil {
+		root = root.parent
+	}
+	scope := NewEnvironment(root)
+	if pragmas == nil {
+		pragmas = &Pragmas{}
+	}
+	scope.SetPragmas(pragmas)
+	scope.SetModule(module)
+	if _, err := Evaluate(program, scope); err != nil {
+		return nil, err
+	}
+
+	exports := make(map[string]RuntimeValue)
+	for _, stmt := range program.(*Program).Body {
+		switch s := stmt.(type) {
+		case *FunctionDeclaration:
+			if s.Export {
+				exports[s.Name] = scope.variables[s.Name]
+			}
+		case *ActionAssignmentExpr:
+			if identifier, ok := s.Assigne.(*Identifier); ok && s.Action.Name == "out" {
+				exports[identifier.Value] = scope.variables[identifier.Value]
+			}
+		}
+	}
+	return exports, nil
+}
+
+// fetchModule returns the code of a module URL from the cache, downloading it
+// the first time. The hash of the download is stored next to it, so a cached
+// module edited afterwards is refused rather than run. A pin the cached
+// module does not match downloads it again, the content changed since. With
+// the Offline option (--offline) only the cache is read.
+func fetchModule(url string, env *Environment) ([]byte, error) {
+	url, pinned, _ := strings.Cut(url, "#sha256-")
+
+	dir, err := moduleCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	key := sha256.Sum256([]byte(url))
+	cached := filepath.Join(dir, hex.EncodeToString(key[:])+".luna")
+
+	code, err := os.ReadFile(cached)
+	if err == nil {
+		recorded, err := os.ReadFile(cached + ".sha256")
+		if err != nil {
+			return nil, fmt.Errorf("cached module has no recorded hash, delete %s to download it again", cached)
+		}
+		sum := sha256.Sum256(code)
+		if hex.EncodeToString(sum[:]) != strings.TrimSpace(string(recorded)) {
+			return nil, fmt.Errorf("cached module does not match its recorded hash, delete %s to download it again", cached)
+		}
+		err = checkPinned(pinned, hex.EncodeToString(sum[:]))
+		if err == nil {
+			return code, nil
+		}
+		if env.options.Offline {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if env.options.Offline {
+		return nil, fmt.Errorf("not in the module cache and --offline is set")
+	}
+
+	// Other tasks run while the download waits on the network
+	var digest string
+	blockingCall(env, func() { code, digest, err = downloadModule(url, pinned) })
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cached, code, 0644); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(cached+".sha256", []byte(digest+"\n"), 0644); err != nil {
+		return nil, err
+	}
+	return code, nil
+}
+
+// downloadModule fetches the code at url and its sha256, which must be pinned when given
+func downloadModule(url, pinned string) ([]byte, string, error) {
+	client := &http.Client{Timeout: moduleFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download failed: %s", resp.Status)
+	}
+	code, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sum := sha256.Sum256(code)
+	digest := hex.EncodeToString(sum[:])
+	return code, digest, checkPinned(pinned, digest)
+}
+
+// checkPinned compares the hash of a module with the one pinned in its URL, if any
+func checkPinned(pinned, digest string) error {
+	if pinned != "" && pinned != digest {
+		return fmt.Errorf("integrity check failed: expected sha256 %s, got %s", pinned, digest)
+	}
+	return nil
+}
@@ -0,0 +1,546 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var startTime = time.Now()
+
+func setupNativeFunctions(env *Environment) {
+
+	// I/O functions
+
+	// String functions
+	env.DeclareVar("length", MakeNativeFunction("length", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("length expects 1 argument, got %d", len(args))
+		}
+
+		switch args[0].Type() {
+		case STRING_TYPE:
+			return MakeInt(int64(utf8.RuneCountInString(args[0].(*StringValue).Value))), nil
+		case ARRAY_TYPE:
+			return MakeInt(int64(len(args[0].(*ArrayValue).Elements))), nil
+		case OBJECT_TYPE:
+			return MakeInt(int64(len(args[0].(*ObjectValue).Properties))), nil
+		case MAP_TYPE:
+			return MakeInt(int64(args[0].(*MapValue).Len())), nil
+		case SET_TYPE:
+			return MakeInt(int64(args[0].(*SetValue).Len())), nil
+		case RANGE_TYPE:
+			return MakeInt(int64(args[0].(*RangeValue).Len())), nil
+		default:
+			return nil, fmt.Errorf("length not supported for type %s", args[0].Type())
+		}
+	}), true)
+
+	// Type conversion functions
+	env.DeclareVar("int", MakeNativeFunction("int", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("int expects 1 argument, got %d", len(args))
+		}
+
+		switch args[0].Type() {
+		case INT_TYPE:
+			return args[0], nil
+		case BIGINT_TYPE:
+			if value, ok := toInt(args[0]); ok {
+				return MakeInt(value), nil
+			}
+			return nil, fmt.Errorf("int: %sn does not fit in 64 bits", args[0].String())
+		case NUMBER_TYPE:
+			value := args[0].(*NumberValue).Value
+			return wholeNumber(math.Trunc(value)), nil
+		case STRING_TYPE:
+			value := strings.TrimSpace(args[0].(*StringValue).Value)
+			if parsed, err := strconv.ParseInt(value, 10, 64); err == nil {
+				return MakeInt(parsed), nil
+			}
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				return wholeNumber(math.Trunc(parsed)), nil
+			}
+			return MakeInt(0), nil
+		case BOOLEAN_TYPE:
+			return MakeInt(int64(boolNumber(args[0].(*BooleanValue).Value))), nil
+		default:
+			return MakeInt(0), nil
+		}
+	}), true)
+
+	env.DeclareVar("float", MakeNativeFunction("float", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("float expects 1 argument, got %d", len(args))
+		}
+
+		switch args[0].Type() {
+		case NUMBER_TYPE:
+			return args[0], nil
+		case INT_TYPE, BIGINT_TYPE:
+			value, _ := toFloat(args[0])
+			return MakeNumber(value), nil
+		case STRING_TYPE:
+			value := args[0].(*StringValue).Value
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				return MakeNumber(parsed), nil
+			}
+			return MakeNumber(0), nil
+		case BOOLEAN_TYPE:
+			return MakeNumber(boolNumber(args[0].(*BooleanValue).Value)), nil
+		default:
+			return MakeNumber(0), nil
+		}
+	}), true)
+
+	env.DeclareVar("bigint", MakeNativeFunction("bigint", bigintNative), true)
+
+	env.DeclareVar("bool", MakeNativeFunction("bool", boolNative), true)
+
+	env.DeclareVar("string", MakeNativeFunction("string", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string expects 1 argument, got %d", len(args))
+		}
+
+		switch args[0].Type() {
+		case STRING_TYPE:
+			return args[0], nil
+		case NUMBER_TYPE:
+			value := args[0].(*NumberValue).Value
+			return MakeString(strconv.FormatFloat(value, 'g', -1, 64)), nil
+		case INT_TYPE:
+			return MakeString(args[0].String()), nil
+		case BOOLEAN_TYPE:
+			value := args[0].(*BooleanValue).Value
+			return MakeString(strconv.FormatBool(value)), nil
+		default:
+			return MakeString(args[0].String()), nil
+		}
+	}), true)
+
+	// printf-like formatting: format("%-8s %6.2f", name, price)
+	env.DeclareVar("format", MakeNativeFunction("format", formatNative), true)
+
+	// Ordered maps with number, string and boolean keys: map(), map(pairs), map(object)
+	env.DeclareVar("map", MakeNativeFunction("map", mapNative), true)
+
+	// Sets of distinct numbers, strings and booleans: set(), set(iterable)
+	env.DeclareVar("set", MakeNativeFunction("set", setNative), true)
+
+	// Lazy numeric ranges: range(end), range(start, end), range(start, end, step)
+	env.DeclareVar("range", MakeNativeFunction("range", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 3 {
+			return nil, fmt.Errorf("range expects 1 to 3 arguments, got %d", len(args))
+		}
+		bounds := []float64{0, 0, 1}
+		for i, arg := range args {
+			bound, ok := toFloat(arg)
+			if !ok {
+				return nil, fmt.Errorf("range expects numbers")
+			}
+			bounds[i] = bound
+		}
+		if len(args) == 1 {
+			bounds[0], bounds[1] = 0, bounds[0]
+		}
+		if bounds[2] == 0 {
+			return nil, fmt.Errorf("range step cannot be 0")
+		}
+		return &RangeValue{Start: bounds[0], End: bounds[1], Step: bounds[2]}, nil
+	}), true)
+
+	// Type checking function
+	env.DeclareVar("typeof", MakeNativeFunction("typeof", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("typeget expects 1 argument, got %d", len(args))
+		}
+		return MakeString(typeName(args[0])), nil
+	}), true)
+
+	// Serialization functions (bytes are carried in a string)
+	env.DeclareVar("serialize", MakeNativeFunction("serialize", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("serialize expects 1 argument, got %d", len(args))
+		}
+		data, err := SerializeValue(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(string(data)), nil
+	}), true)
+
+	env.DeclareVar("deserialize", MakeNativeFunction("deserialize", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("deserialize expects 1 argument, got %d", len(args))
+		}
+		data, err := bytesArgument(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("deserialize: %v", err)
+		}
+		return DeserializeValue(data)
+	}), true)
+
+	// Constants
+	env.DeclareVar("true", MakeBool(true), true)
+	env.DeclareVar("false", MakeBool(false), true)
+	env.DeclareVar("null", MakeNull(), true)
+	env.DeclareVar("undef", MakeUndefined(), true)
+
+	// Exit function
+	env.DeclareVar("exit", MakeNativeFunction("exit", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		code, err := exitCode("exit", args)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Println(gray("Exiting..."))
+		return nil, &ExitError{Code: code}
+	}), true)
+
+	// OBJECTS ---
+	// Create IO object with all math functions
+	IOObject := createIOObject()
+	env.DeclareVar("io", IOObject, true)
+
+	// Create math object with all math functions
+	mathObject := createMathObject()
+	env.DeclareVar("math", mathObject, true)
+
+	// Binary codecs
+	env.DeclareVar("msgpack", createCodecObject("msgpack", MsgpackEncode, MsgpackDecode), true)
+	env.DeclareVar("cbor", createCodecObject("cbor", CborEncode, CborDecode), true)
+
+	// Protocol buffers (dynamic, from descriptor sets)
+	env.DeclareVar("proto", createProtoObject(), true)
+
+	// Sleeping and timers
+	env.DeclareVar("time", createTimeObject(), true)
+
+	// Secrets that stay out of logs, and comparing them safely
+	env.DeclareVar("secret", MakeNativeFunction("secret", secretNative), true)
+	env.DeclareVar("crypto", createCryptoObject(), true)
+	env.DeclareVar("secrets", createSecretsObject(), true)
+
+	// Process, environment variables and subprocesses
+	env.DeclareVar("os", createOSObject(), true)
+
+	// The arguments after the script's filename, also available as os.args
+	env.DeclareVar("args", stringArray(scriptArgs), false)
+
+	// Dates, parsing and formatting
+	env.DeclareVar("date", createDateObject(), true)
+
+	// File checksums and metadata
+	env.DeclareVar("file", createFileObject(), true)
+
+	// HTTP services
+	env.DeclareVar("http", createHTTPObject(), true)
+
+	// Mock servers for testing scripts offline
+	env.DeclareVar("mock", createMockObject(), true)
+
+	// Which native modules this environment allows
+	env.DeclareVar("capabilities", MakeNativeFunction("capabilities", capabilitiesNative), true)
+
+	// Channels for spawned tasks
+	env.DeclareVar("chan", MakeNativeFunction("chan", chanNative), true)
+	env.DeclareVar("group", MakeNativeFunction("group", groupNative), true)
+
+	// Retrying and rate limiting flaky operations
+	env.DeclareVar("retry", MakeNativeFunction("retry", retryNative), true)
+	env.DeclareVar("ratelimit", MakeNativeFunction("ratelimit", ratelimitNative), true)
+
+	// Micro-benchmarks
+	env.DeclareVar("bench", MakeNativeFunction("bench", benchNative), true)
+
+	// Assertions for tests, see `luna test`
+	env.DeclareVar("assert", createAssertObject(), true)
+
+	// Reading and writing nested values by path, "a.b[2].c"
+	env.DeclareVar("getPath", MakeNativeFunction("getPath", getPathNative), true)
+	env.DeclareVar("setPath", MakeNativeFunction("setPath", setPathNative), true)
+	env.DeclareVar("paths", MakeNativeFunction("paths", pathsNative), true)
+
+	// Validating the shape of decoded data
+	env.DeclareVar("schema", createSchemaObject(), true)
+
+	// Edit distance and closest matches, for suggestions and dedup
+	env.DeclareVar("fuzzy", createFuzzyObject(), true)
+
+	// Colored output in the interpreter's palette
+	env.DeclareVar("colors", createColorsObject(), true)
+
+	// Spinners and progress bars that stay below the output
+	env.DeclareVar("ui", createUIObject(), true)
+
+	// Modules added by Go code, see plugins.go
+	// Descriptions of the natives and functions: help(value)
+	env.DeclareVar("help", MakeNativeFunction("help", helpNative), true)
+	documentNatives(env)
+
+	declareRegisteredModules(env)
+}
+
+// boolNumber is the explicit conversion of int(b) and float(b): true is 1, false is 0
+func boolNumber(value bool) float64 {
+	if value {
+		return 1
+	}
+	return 0
+}
+
+// createCodecObject exposes a binary codec as encode/decode natives (bytes are carried in a string)
+func createCodecObject(name string, encode func(RuntimeValue) ([]byte, error), decode func([]byte) (RuntimeValue, error)) RuntimeValue {
+	codecProps := make(map[string]RuntimeValue)
+
+	codecProps["encode"] = MakeNativeFunction("encode", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s.encode expects 1 argument, got %d", name, len(args))
+		}
+		data, err := encode(args[0])
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(string(data)), nil
+	})
+
+	codecProps["decode"] = MakeNativeFunction("decode", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("%s.decode expects 1 argument, got %d", name, len(args))
+		}
+		data, err := bytesArgument(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s.decode: %v", name, err)
+		}
+		return decode(data)
+	})
+
+	return MakeObject(codecProps)
+}
+
+func createIOObject() RuntimeValue {
+	ioProps := make(map[string]RuntimeValue)
+
+	// Math functions
+	ioProps["print"] = MakeNativeFunction("print", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		var output []string
+		for _, arg := range args {
+			if arg.Type() == STRING_TYPE {
+				output = append(output, arg.(*StringValue).Value)
+			} else {
+				// Use colorized output for non-string values
+				output = append(output, displayValue(arg, env, true))
+			}
+		}
+		printOutput(strings.Join(output, " "))
+		return MakeVoid(), nil
+	})
+
+	ioProps["inspect"] = MakeNativeFunction("inspect", inspectNative)
+
+	ioProps["input"] = MakeNativeFunction("input", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		// The status lines give way to the prompt and come back below the answer
+		status.pause()
+		defer status.resume()
+		if len(args) > 0 && args[0].Type() == STRING_TYPE {
+			fmt.Print(args[0].(*StringValue).Value)
+		}
+
+		scanner := bufio.NewScanner(os.Stdin)
+		if scanner.Scan() {
+			return MakeString(scanner.Text()), nil
+		}
+		return MakeString(""), nil
+	})
+
+	ioProps["time"] = MakeNativeFunction("time", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		elapsed := time.Since(startTime).Seconds() * 1000 // milliseconds
+		return MakeNumber(elapsed), nil
+	})
+
+	return MakeObject(ioProps)
+}
+
+func createMathObject() RuntimeValue {
+	mathProps := make(map[string]RuntimeValue)
+
+	// Math functions
+	mathProps["abs"] = MakeNativeFunction("abs", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("abs expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("abs expects a number")
+		}
+		if n, isInt := args[0].(*IntValue); isInt && n.Value != math.MinInt64 {
+			return MakeInt(max(n.Value, -n.Value)), nil
+		}
+		return MakeNumber(math.Abs(value)), nil
+	})
+
+	mathProps["sqrt"] = MakeNativeFunction("sqrt", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("sqrt expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("sqrt expects a number")
+		}
+		return MakeNumber(math.Sqrt(value)), nil
+	})
+
+	mathProps["pow"] = MakeNativeFunction("pow", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("pow expects 2 arguments, got %d", len(args))
+		}
+		if !isNumeric(args[0].Type()) || !isNumeric(args[1].Type()) {
+			return nil, fmt.Errorf("pow expects numbers")
+		}
+		return evaluateBinaryOperation(args[0], args[1], "**")
+	})
+
+	mathProps["sin"] = MakeNativeFunction("sin", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("sin expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("sin expects a number")
+		}
+		return MakeNumber(math.Sin(value)), nil
+	})
+
+	mathProps["cos"] = MakeNativeFunction("cos", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("cos expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("cos expects a number")
+		}
+		return MakeNumber(math.Cos(value)), nil
+	})
+
+	mathProps["tan"] = MakeNativeFunction("tan", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("tan expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("tan expects a number")
+		}
+		return MakeNumber(math.Tan(value)), nil
+	})
+
+	mathProps["floor"] = MakeNativeFunction("floor", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("floor expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("floor expects a number")
+		}
+		return wholeNumber(math.Floor(value)), nil
+	})
+
+	mathProps["ceil"] = MakeNativeFunction("ceil", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("ceil expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("ceil expects a number")
+		}
+		return wholeNumber(math.Ceil(value)), nil
+	})
+
+	mathProps["round"] = MakeNativeFunction("round", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("round expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("round expects a number")
+		}
+		return wholeNumber(math.Round(value)), nil
+	})
+
+	mathProps["log"] = MakeNativeFunction("log", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("log expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("log expects a number")
+		}
+		return MakeNumber(math.Log(value)), nil
+	})
+
+	mathProps["exp"] = MakeNativeFunction("exp", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("exp expects 1 argument, got %d", len(args))
+		}
+		value, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("exp expects a number")
+		}
+		return MakeNumber(math.Exp(value)), nil
+	})
+
+	mathProps["min"] = MakeNativeFunction("min", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) == 0 {
+			return MakeNumber(math.Inf(1)), nil
+		}
+
+		// The smallest argument itself, so ints stay ints
+		var min RuntimeValue
+		for _, arg := range args {
+			if !isNumeric(arg.Type()) {
+				return nil, fmt.Errorf("min expects numbers")
+			}
+			if min == nil || compareNumeric(arg, min) < 0 {
+				min = arg
+			}
+		}
+		return min, nil
+	})
+
+	mathProps["max"] = MakeNativeFunction("max", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) == 0 {
+			return MakeNumber(math.Inf(-1)), nil
+		}
+
+		var max RuntimeValue
+		for _, arg := range args {
+			if !isNumeric(arg.Type()) {
+				return nil, fmt.Errorf("max expects numbers")
+			}
+			if max == nil || compareNumeric(arg, max) > 0 {
+				max = arg
+			}
+		}
+		return max, nil
+	})
+
+	mathProps["random"] = MakeNativeFunction("random", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		return MakeNumber(rand.Float64()), nil
+	})
+
+	// Math constants
+	mathProps["PI"] = MakeNumber(math.Pi)
+	mathProps["E"] = MakeNumber(math.E)
+	mathProps["LN2"] = MakeNumber(math.Ln2)
+	mathProps["LN10"] = MakeNumber(math.Ln10)
+	mathProps["LOG2E"] = MakeNumber(math.Log2E)
+	mathProps["LOG10E"] = MakeNumber(math.Log10E)
+	mathProps["SQRT1_2"] = MakeNumber(math.Sqrt2 / 2)
+	mathProps["SQRT2"] = MakeNumber(math.Sqrt2)
+
+	return MakeObject(mathProps)
+}
@@ -0,0 +1,398 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// Luna has two kinds of numbers. Whole number literals are ints, exact up
+// to 64 bits, anything with a fraction or exponent is a float. typeof gives
+// "number" for both. Arithmetic keeps ints as ints and promotes to float as
+// soon as a float takes part:
+//
+//	int + - * % int   int, a float when the result overflows 64 bits
+//	int ** int        int for a non-negative exponent, otherwise a float
+//	any / any         float, 7 / 2 is 3.5
+//	int // int        int, division rounded down, 7 // 2 is 3
+//	any % any         takes the sign of the divisor, -7 % 2 is 1, so that
+//	                  a == (a // b) * b + a % b
+//	int op float      float
+//
+// Bigints, written 123n or made with bigint(), never overflow. An int
+// meeting a bigint becomes one, a float meeting a bigint makes the result a
+// float, and / between bigints is a float like everywhere else.
+//
+// Numbers with the same value are equal, 1 == 1.0 == 1n. The bitwise
+// operators & | ^ << >> and ~ work on the 64 bits of ints, whole floats
+// are converted first and any other float is an error. A << whose result
+// does not fit 64 bits gives a float, 1 << 64 is 2.0 ** 64.
+
+// typeName is what typeof gives for value, ints and floats are both numbers
+func typeName(value RuntimeValue) string {
+	if value.Type() == INT_TYPE {
+		return string(NUMBER_TYPE)
+	}
+	return string(value.Type())
+}
+
+// isNumeric reports whether t is one of the number types
+func isNumeric(t ValueType) bool {
+	return t == NUMBER_TYPE || t == INT_TYPE || t == BIGINT_TYPE
+}
+
+// toFloat reads an int or a float as a float64
+func toFloat(value RuntimeValue) (float64, bool) {
+	switch v := value.(type) {
+	case *NumberValue:
+		return v.Value, true
+	case *IntValue:
+		return float64(v.Value), true
+	case *BigIntValue:
+		f, _ := new(big.Float).SetInt(v.Value).Float64()
+		return f, true
+	}
+	return 0, false
+}
+
+// toInt reads an int, or a float without a fraction, as an int64
+func toInt(value RuntimeValue) (int64, bool) {
+	switch v := value.(type) {
+	case *IntValue:
+		return v.Value, true
+	case *NumberValue:
+		if isInteger(v.Value) {
+			return int64(v.Value), true
+		}
+	case *BigIntValue:
+		if v.Value.IsInt64() {
+			return v.Value.Int64(), true
+		}
+	}
+	return 0, false
+}
+
+// toBig reads an int or a bigint as a big.Int, which must not be changed
+func toBig(value RuntimeValue) (*big.Int, bool) {
+	switch v := value.(type) {
+	case *IntValue:
+		return big.NewInt(v.Value), true
+	case *BigIntValue:
+		return v.Value, true
+	}
+	return nil, false
+}
+
+// isNaN reports whether value is the float NaN
+func isNaN(value RuntimeValue) bool {
+	number, ok := value.(*NumberValue)
+	return ok && math.IsNaN(number.Value)
+}
+
+// wholeNumber is an int for whole values that fit, a float otherwise, for
+// results like math.floor that are whole numbers unless NaN or infinite
+func wholeNumber(value float64) RuntimeValue {
+	if isInteger(value) {
+		return MakeInt(int64(value))
+	}
+	return MakeNumber(value)
+}
+
+// evaluateIntOperation applies an arithmetic operator to two ints, false
+// when the operator is not arithmetic
+func evaluateIntOperation(a, b int64, operator string) (RuntimeValue, bool, error) {
+	switch operator {
+	case "+":
+		if r := a + b; (a^r)&(b^r) >= 0 {
+			return MakeInt(r), true, nil
+		}
+	case "-":
+		if r := a - b; (a^b)&(a^r) >= 0 {
+			return MakeInt(r), true, nil
+		}
+	case "*":
+		if r, ok := multiplyInts(a, b); ok {
+			return MakeInt(r), true, nil
+		}
+	case "/":
+		return MakeNumber(float64(a) / float64(b)), true, nil
+	case "//":
+		if b == 0 {
+			return nil, true, fmt.Errorf("integer division by zero")
+		}
+		if a == math.MinInt64 && b == -1 {
+			break // overflows, the float result is exact enough
+		}
+		q := a / b
+		if (a%b != 0) && ((a < 0) != (b < 0)) {
+			q-- // round towards negative infinity
+		}
+		return MakeInt(q), true, nil
+	case "%":
+		if b == 0 {
+			return nil, true, fmt.Errorf("integer division by zero")
+		}
+		if b == -1 {
+			return MakeInt(0), true, nil
+		}
+		r := a % b
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b // the remainder of the division rounded down
+		}
+		return MakeInt(r), true, nil
+	case "**":
+		if b < 0 {
+			break
+		}
+		if r, ok := powerInt(a, b); ok {
+			return MakeInt(r), true, nil
+		}
+	default:
+		return nil, false, nil
+	}
+	// Results that do not fit 64 bits become floats
+	return evaluateFloatOperation(float64(a), float64(b), operator)
+}
+
+// evaluateFloatOperation applies an arithmetic operator to two floats
+func evaluateFloatOperation(a, b float64, operator string) (RuntimeValue, bool, error) {
+	switch operator {
+	case "+":
+		return MakeNumber(a + b), true, nil
+	case "-":
+		return MakeNumber(a - b), true, nil
+	case "*":
+		return MakeNumber(a * b), true, nil
+	case "/":
+		return MakeNumber(a / b), true, nil
+	case "//":
+		return MakeNumber(math.Floor(a / b)), true, nil
+	case "%":
+		r := math.Mod(a, b)
+		if r != 0 && (r < 0) != (b < 0) {
+			r += b
+		}
+		return MakeNumber(r), true, nil
+	case "**":
+		return MakeNumber(math.Pow(a, b)), true, nil
+	}
+	return nil, false, nil
+}
+
+// bitwiseOperand reads a number as the int a bitwise operator works on, floats must be whole
+func bitwiseOperand(operator string, value RuntimeValue) (int64, error) {
+	n, ok := toInt(value)
+	if !ok {
+		return 0, fmt.Errorf("%s needs whole numbers, got %s", operator, value.String())
+	}
+	return n, nil
+}
+
+// evaluateBitwiseOperation applies a bitwise operator to two numbers, false
+// when the operator is not bitwise
+func evaluateBitwiseOperation(left, right RuntimeValue, operator string) (RuntimeValue, bool, error) {
+	switch operator {
+	case "&", "|", "^", "<<", ">>":
+	default:
+		return nil, false, nil
+	}
+	a, err := bitwiseOperand(operator, left)
+	if err != nil {
+		return nil, true, err
+	}
+	b, err := bitwiseOperand(operator, right)
+	if err != nil {
+		return nil, true, err
+	}
+
+	switch operator {
+	case "&":
+		return MakeInt(a & b), true, nil
+	case "|":
+		return MakeInt(a | b), true, nil
+	case "^":
+		return MakeInt(a ^ b), true, nil
+	}
+	if b < 0 {
+		return nil, true, fmt.Errorf("negative shift count %d", b)
+	}
+	if operator == "<<" {
+		if r := a << b; b < 64 && r>>b == a {
+			return MakeInt(r), true, nil
+		}
+		return MakeNumber(math.Ldexp(float64(a), int(min(b, math.MaxInt32)))), true, nil
+	}
+	return MakeInt(a >> b), true, nil // keeps the sign, -8 >> 1 is -4
+}
+
+// evaluateBigOperation applies an arithmetic or bitwise operator to two
+// bigints, false when the operator is neither
+func evaluateBigOperation(a, b *big.Int, operator string) (RuntimeValue, bool, error) {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(a, b)
+	case "-":
+		result.Sub(a, b)
+	case "*":
+		result.Mul(a, b)
+	case "/":
+		if b.Sign() == 0 {
+			x, _ := new(big.Float).SetInt(a).Float64()
+			return evaluateFloatOperation(x, 0, "/")
+		}
+		quotient, _ := new(big.Float).Quo(new(big.Float).SetInt(a), new(big.Float).SetInt(b)).Float64()
+		return MakeNumber(quotient), true, nil
+	case "//", "%":
+		if b.Sign() == 0 {
+			return nil, true, fmt.Errorf("integer division by zero")
+		}
+		quotient, remainder := new(big.Int).QuoRem(a, b, new(big.Int))
+		if remainder.Sign() != 0 && remainder.Sign() != b.Sign() {
+			quotient.Sub(quotient, big.NewInt(1)) // round towards negative infinity
+			remainder.Add(remainder, b)
+		}
+		if operator == "%" {
+			return MakeBigInt(remainder), true, nil
+		}
+		return MakeBigInt(quotient), true, nil
+	case "**":
+		if b.Sign() < 0 {
+			x, _ := new(big.Float).SetInt(a).Float64()
+			y, _ := new(big.Float).SetInt(b).Float64()
+			return MakeNumber(math.Pow(x, y)), true, nil
+		}
+		result.Exp(a, b, nil)
+	case "&":
+		result.And(a, b)
+	case "|":
+		result.Or(a, b)
+	case "^":
+		result.Xor(a, b)
+	case "<<", ">>":
+		if b.Sign() < 0 || !b.IsUint64() || b.Uint64() > math.MaxUint32 {
+			return nil, true, fmt.Errorf("invalid shift count %s", b.String())
+		}
+		if operator == "<<" {
+			result.Lsh(a, uint(b.Uint64()))
+		} else {
+			result.Rsh(a, uint(b.Uint64())) // keeps the sign like >> on ints
+		}
+	default:
+		return nil, false, nil
+	}
+	return MakeBigInt(result), true, nil
+}
+
+// bigintNative is bigint(value), converting an int, a float (truncated), a
+// string of digits or a boolean to a bigint
+func bigintNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("bigint expects 1 argument, got %d", len(args))
+	}
+	switch v := args[0].(type) {
+	case *BigIntValue:
+		return v, nil
+	case *IntValue:
+		return MakeBigInt(big.NewInt(v.Value)), nil
+	case *NumberValue:
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			return nil, fmt.Errorf("bigint: cannot convert %s to a bigint", v.String())
+		}
+		value, _ := big.NewFloat(v.Value).Int(nil)
+		return MakeBigInt(value), nil
+	case *StringValue:
+		digits := strings.TrimSuffix(strings.TrimSpace(v.Value), "n")
+		value, ok := new(big.Int).SetString(digits, 10)
+		if !ok {
+			return nil, fmt.Errorf("bigint: cannot convert '%s' to a bigint", v.Value)
+		}
+		return MakeBigInt(value), nil
+	case *BooleanValue:
+		return MakeBigInt(big.NewInt(int64(boolNumber(v.Value)))), nil
+	}
+	return nil, fmt.Errorf("bigint: cannot convert %s to a bigint", args[0].Type())
+}
+
+// multiplyInts multiplies, false on overflow
+func multiplyInts(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	r := a * b
+	if r/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64) {
+		return 0, false
+	}
+	return r, true
+}
+
+// powerInt raises base to a non-negative exponent by squaring, false on overflow
+func powerInt(base, exponent int64) (int64, bool) {
+	result := int64(1)
+	for exponent > 0 {
+		var ok bool
+		if exponent&1 == 1 {
+			if result, ok = multiplyInts(result, base); !ok {
+				return 0, false
+			}
+		}
+		exponent >>= 1
+		if exponent > 0 {
+			if base, ok = multiplyInts(base, base); !ok {
+				return 0, false
+			}
+		}
+	}
+	return result, true
+}
+
+// compareNumbers orders two floats, -1, 0 or 1
+func compareNumbers(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// compareNumeric orders two numbers exactly, whatever their kinds, NaN is
+// ordered like 0
+func compareNumeric(left, right RuntimeValue) int {
+	a, leftInt := left.(*IntValue)
+	b, rightInt := right.(*IntValue)
+	if leftInt && rightInt {
+		switch {
+		case a.Value < b.Value:
+			return -1
+		case a.Value > b.Value:
+			return 1
+		}
+		return 0
+	}
+	if x, ok := toBig(left); ok {
+		if y, ok := toBig(right); ok {
+			return x.Cmp(y)
+		}
+	}
+	x, _ := toFloat(left)
+	y, _ := toFloat(right)
+	// A bigint beyond the precision of a float is compared in full
+	if bigint, ok := left.(*BigIntValue); ok && !math.IsNaN(y) && !math.IsInf(y, 0) {
+		return new(big.Float).SetInt(bigint.Value).Cmp(new(big.Float).SetFloat64(y))
+	}
+	if bigint, ok := right.(*BigIntValue); ok && !math.IsNaN(x) && !math.IsInf(x, 0) {
+		return new(big.Float).SetFloat64(x).Cmp(new(big.Float).SetInt(bigint.Value))
+	}
+	return compareNumbers(x, y)
+}
+
+// numbersEqual compares two numbers of any kind by value
+func numbersEqual(left, right RuntimeValue) bool {
+	if isNaN(left) || isNaN(right) {
+		return false
+	}
+	return compareNumeric(left, right) == 0
+}
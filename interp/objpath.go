@@ -0,0 +1,238 @@
+package interp
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Paths address a value inside nested objects and arrays: "a.b[2].c". A key
+// that is not a plain name is written quoted in brackets, like ["x y"], and
+// a negative index counts from the end of an array.
+
+// pathSegment is one step of a path, a key or an array index
+type pathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+var plainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// parsePath splits a path into its segments
+func parsePath(path string) ([]pathSegment, error) {
+	var segments []pathSegment
+	for i := 0; i < len(path); {
+		switch {
+		case path[i] == '[':
+			end := strings.IndexByte(path[i:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed '[' in path '%s'", path)
+			}
+			inside := path[i+1 : i+end]
+			if unquoted, err := strconv.Unquote(inside); err == nil {
+				segments = append(segments, pathSegment{key: unquoted})
+			} else if index, err := strconv.Atoi(inside); err == nil {
+				segments = append(segments, pathSegment{index: index, isIndex: true})
+			} else {
+				return nil, fmt.Errorf("invalid index [%s] in path '%s'", inside, path)
+			}
+			i += end + 1
+		case path[i] == '.' && i > 0:
+			i++
+		default:
+			end := strings.IndexAny(path[i:], ".[")
+			if end < 0 {
+				end = len(path) - i
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("empty key in path '%s'", path)
+			}
+			segments = append(segments, pathSegment{key: path[i : i+end]})
+			i += end
+		}
+	}
+	return segments, nil
+}
+
+// child reads one segment of value, false when it is not there
+func (s pathSegment) child(value RuntimeValue) (RuntimeValue, bool) {
+	switch v := value.(type) {
+	case *ObjectValue:
+		if s.isIndex {
+			child, exists := v.Properties[strconv.Itoa(s.index)]
+			return child, exists
+		}
+		child, exists := v.Properties[s.key]
+		return child, exists
+	case *ArrayValue:
+		if !s.isIndex {
+			return nil, false
+		}
+		i, ok := resolveIndex(float64(s.index), len(v.Elements))
+		if !ok {
+			return nil, false
+		}
+		return v.Elements[i], true
+	}
+	return nil, false
+}
+
+// joinPath adds a key to a path, quoting keys that are not plain names
+func joinPath(path, key string) string {
+	switch {
+	case !plainKey.MatchString(key):
+		return path + "[" + strconv.Quote(key) + "]"
+	case path == "":
+		return key
+	}
+	return path + "." + key
+}
+
+// pathArg reads the path argument of name
+func pathArg(name string, value RuntimeValue) ([]pathSegment, error) {
+	path, ok := value.(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("%s: path must be a string", name)
+	}
+	segments, err := parsePath(path.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return segments, nil
+}
+
+// getPathNative is getPath(value, path, default), default (or undef) when the path leads nowhere
+func getPathNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, fmt.Errorf("getPath expects 2 or 3 arguments, got %d", len(args))
+	}
+	segments, err := pathArg("getPath", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	value := args[0]
+	for _, segment := range segments {
+		child, ok := segment.child(value)
+		if !ok {
+			if len(args) == 3 {
+				return args[2], nil
+			}
+			return MakeUndefined(), nil
+		}
+		value = child
+	}
+	return value, nil
+}
+
+// setPathNative is setPath(value, path, new), creating the objects and arrays missing on the way
+func setPathNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 3 {
+		return nil, fmt.Errorf("setPath expects 3 arguments, got %d", len(args))
+	}
+	segments, err := pathArg("setPath", args[1])
+	if err != nil {
+		return nil, err
+	}
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("setPath: path is empty")
+	}
+
+	value := args[0]
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		child, exists := segment.child(value)
+		if !last && exists && (child.Type() == OBJECT_TYPE || child.Type() == ARRAY_TYPE) {
+			value = child
+			continue
+		}
+
+		// Missing containers are arrays when indexed, objects otherwise
+		next := args[2]
+		if !last {
+			if segments[i+1].isIndex {
+				next = MakeArray([]RuntimeValue{})
+			} else {
+				next = MakeObject(map[string]RuntimeValue{})
+			}
+		}
+
+		switch v := value.(type) {
+		case *ObjectValue:
+			if v.Frozen {
+				return nil, fmt.Errorf("setPath: cannot assign to a frozen object")
+			}
+			key := segment.key
+			if segment.isIndex {
+				key = strconv.Itoa(segment.index)
+			}
+			v.Set(key, next)
+		case *ArrayValue:
+			if !segment.isIndex {
+				return nil, fmt.Errorf("setPath: cannot set key '%s' of an array", segment.key)
+			}
+			index := segment.index
+			if index < 0 {
+				resolved, ok := resolveIndex(float64(index), len(v.Elements))
+				if !ok {
+					return nil, fmt.Errorf("setPath: array index %d out of range (length %d)", index, len(v.Elements))
+				}
+				index = resolved
+			}
+			// Setting past the end grows the array, the gap is undef
+			for len(v.Elements) <= index {
+				v.Elements = append(v.Elements, MakeUndefined())
+			}
+			v.Elements[index] = next
+		default:
+			return nil, fmt.Errorf("setPath: cannot set a property of %s", value.Type())
+		}
+		value = next
+	}
+	return args[0], nil
+}
+
+// pathsNative is paths(value), the paths of every leaf inside value in a stable order
+func pathsNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("paths expects 1 argument, got %d", len(args))
+	}
+	var paths []string
+	collectPaths(args[0], "", &paths, nil)
+	return stringArray(paths), nil
+}
+
+// collectPaths adds the leaves of value, containers of path around it are
+// skipped where they recur, so that a value containing itself ends
+func collectPaths(value RuntimeValue, prefix string, paths *[]string, path []RuntimeValue) {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return
+		}
+	}
+
+	switch v := value.(type) {
+	case *ObjectValue:
+		if len(v.Properties) == 0 && prefix != "" {
+			*paths = append(*paths, prefix)
+		}
+		for _, key := range sortedKeys(v.Properties) {
+			collectPaths(v.Properties[key], joinPath(prefix, key), paths, path)
+		}
+	case *ArrayValue:
+		if len(v.Elements) == 0 && prefix != "" {
+			*paths = append(*paths, prefix)
+		}
+		for i, element := range v.Elements {
+			collectPaths(element, fmt.Sprintf("%s[%d]", prefix, i), paths, path)
+		}
+	default:
+		if prefix != "" {
+			*paths = append(*paths, prefix)
+		}
+	}
+}
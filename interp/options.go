@@ -0,0 +1,72 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Options change how an environment runs code beyond what the code asks
+// for. The command line sets them with --strict, --expand-paths,
+// --truthiness=strict, --trace, --offline and --debug-depth=N, a host with
+// luna.SetOptions; evaluation never reads the flags itself.
+type Options struct {
+	Strict           bool // every module is strict, as with use "strict"
+	ExpandPaths      bool // every module expands its paths, see paths.go
+	StrictTruthiness bool // conditions must be booleans, see truthiness.go
+	Trace            bool // statements are printed as they run, see trace.go
+	Offline          bool // use only reads the module cache, see modules.go
+	DebugDepth       int  // levels of nested values debug shows
+}
+
+// DefaultOptions apply to every environment set up afterwards
+var DefaultOptions = Options{DebugDepth: defaultInspectDepth}
+
+// SetOptions changes the options of the environment of this interpreter
+func (l *Luna) SetOptions(options Options) {
+	*l.env.options = options
+}
+
+// optionsFromFlags reads the options from the command line
+func optionsFromFlags() (Options, error) {
+	options := DefaultOptions
+	options.Strict = hasFlag("--strict")
+	options.ExpandPaths = hasFlag("--expand-paths")
+	options.Trace = hasFlag("--trace")
+	options.Offline = hasFlag("--offline")
+
+	if mode, found := flagValue("--truthiness"); found {
+		switch mode {
+		case "strict":
+			options.StrictTruthiness = true
+		case "default":
+			options.StrictTruthiness = false
+		default:
+			return options, fmt.Errorf("unknown truthiness '%s', expected strict or default", mode)
+		}
+	}
+	if value, found := flagValue("--debug-depth"); found {
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 0 {
+			return options, fmt.Errorf("--debug-depth expects a number of 0 or more, got '%s'", value)
+		}
+		options.DebugDepth = depth
+	}
+	return options, nil
+}
+
+// setupFromFlags applies the command line to an environment the luna
+// command created: the disabled modules, the limits and the options
+func setupFromFlags(env *Environment) error {
+	if err := setupCapabilities(env); err != nil {
+		return err
+	}
+	if err := setupLimits(env); err != nil {
+		return err
+	}
+	options, err := optionsFromFlags()
+	if err != nil {
+		return err
+	}
+	*env.options = options
+	return nil
+}
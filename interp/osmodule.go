@@ -0,0 +1,167 @@
+package interp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// scriptArgs are the command line arguments after the script's filename
+var scriptArgs []string
+
+// setScriptArgs records the arguments after the program files, see splitArgs
+func setScriptArgs() {
+	_, scriptArgs = splitArgs()
+}
+
+func createOSObject() RuntimeValue {
+	osProps := make(map[string]RuntimeValue)
+
+	osProps["args"] = stringArray(scriptArgs)
+	osProps["platform"] = MakeString(runtime.GOOS)
+
+	// os.env(name) is undef for unset variables, so it can be told apart from ""
+	osProps["env"] = MakeNativeFunction("env", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("os.env expects 1 argument, got %d", len(args))
+		}
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("os.env: name must be a string")
+		}
+		value, set := os.LookupEnv(name.Value)
+		if !set {
+			return MakeUndefined(), nil
+		}
+		return MakeString(value), nil
+	})
+
+	osProps["setEnv"] = MakeNativeFunction("setEnv", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("os.setEnv expects 2 arguments, got %d", len(args))
+		}
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("os.setEnv: name must be a string")
+		}
+		value, ok := args[1].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("os.setEnv: value must be a string")
+		}
+		if err := os.Setenv(name.Value, value.Value); err != nil {
+			return nil, fmt.Errorf("os.setEnv: %v", err)
+		}
+		return MakeVoid(), nil
+	})
+
+	osProps["exit"] = MakeNativeFunction("exit", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		code, err := exitCode("os.exit", args)
+		if err != nil {
+			return nil, err
+		}
+		return nil, &ExitError{Code: code}
+	})
+
+	osProps["cwd"] = MakeNativeFunction("cwd", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 0 {
+			return nil, fmt.Errorf("os.cwd takes no arguments")
+		}
+		dir, err := os.Getwd()
+		if err != nil {
+			return nil, fmt.Errorf("os.cwd: %v", err)
+		}
+		return MakeString(dir), nil
+	})
+
+	osProps["chdir"] = MakeNativeFunction("chdir", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("os.chdir expects 1 argument, got %d", len(args))
+		}
+		dir, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("os.chdir: path must be a string")
+		}
+		if err := os.Chdir(dir.Value); err != nil {
+			return nil, fmt.Errorf("os.chdir: %v", err)
+		}
+		return MakeVoid(), nil
+	})
+
+	// os.exec(cmd, [args]) runs a program and returns { stdout, stderr, code },
+	// a non-zero exit code is not an error, failing to start the program is
+	osProps["exec"] = MakeNativeFunction("exec", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("os.exec expects a command and optional arguments")
+		}
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("os.exec: command must be a string")
+		}
+		var cmdArgs []string
+		if len(args) == 2 {
+			array, ok := args[1].(*ArrayValue)
+			if !ok {
+				return nil, fmt.Errorf("os.exec: arguments must be an array of strings")
+			}
+			for _, element := range array.Elements {
+				str, ok := element.(*StringValue)
+				if !ok {
+					return nil, fmt.Errorf("os.exec: arguments must be an array of strings")
+				}
+				cmdArgs = append(cmdArgs, str.Value)
+			}
+		}
+
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(name.Value, cmdArgs...)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		var err error
+		blockingCall(env, func() { err = cmd.Run() })
+		code := 0
+		if err != nil {
+			var exitErr *exec.ExitError
+			if !errors.As(err, &exitErr) {
+				return nil, fmt.Errorf("os.exec: %v", err)
+			}
+			code = exitErr.ExitCode()
+		}
+
+		return MakeObject(map[string]RuntimeValue{
+			"stdout": MakeString(stdout.String()),
+			"stderr": MakeString(stderr.String()),
+			"code":   MakeInt(int64(code)),
+		}), nil
+	})
+
+	return MakeObject(osProps)
+}
+
+// ExitError ends a program from exit() or os.exit(), it is not caught on
+// its way out. The luna command exits with Code, a host decides.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit(%d)", e.Code)
+}
+
+// exitCode reads the optional integer code of exit(code), defaulting to 0
+func exitCode(name string, args []RuntimeValue) (int, error) {
+	if len(args) > 1 {
+		return 0, fmt.Errorf("%s expects at most 1 argument, got %d", name, len(args))
+	}
+	if len(args) == 0 {
+		return 0, nil
+	}
+	number, ok := toInt(args[0])
+	if !ok {
+		return 0, fmt.Errorf("%s: code must be an integer", name)
+	}
+	return int(number), nil
+}
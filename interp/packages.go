@@ -0,0 +1,195 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// A project lists its dependencies in luna.pkg, one `name = source` per line:
+//
+//	utils = https://example.com/utils.luna#sha256-<hex>
+//	json  = git+https://github.com/someone/json.git#v1.2.0
+//
+// `luna install` vendors them under luna_modules/, a URL as name.luna and a
+// git repository as the directory name/ (run through its main.luna), and
+// scripts load them with `use "pkg:name"`.
+
+const (
+	manifestFile  = "luna.pkg"
+	packagesDir   = "luna_modules"
+	packagePrefix = "pkg:"
+)
+
+// Dependency is one line of the manifest
+type Dependency struct {
+	Name   string
+	Source string
+}
+
+// isGitSource reports whether a source is a repository rather than a single file
+func isGitSource(source string) bool {
+	source, _, _ = strings.Cut(source, "#")
+	return strings.HasPrefix(source, "git+") || strings.HasPrefix(source, "git@") || strings.HasSuffix(source, ".git")
+}
+
+// packageName derives the name of a dependency from its source, https://x/utils.luna is utils
+func packageName(source string) string {
+	source, _, _ = strings.Cut(source, "#")
+	name := filepath.Base(strings.TrimSuffix(source, "/"))
+	for _, extension := range append([]string{".git"}, lunaExtensions...) {
+		name = strings.TrimSuffix(name, extension)
+	}
+	return name
+}
+
+// readManifest reads the dependencies of luna.pkg, a missing manifest has none
+func readManifest() ([]Dependency, error) {
+	file, err := os.Open(manifestFile)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var dependencies []Dependency
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		name, source, found := strings.Cut(text, "=")
+		name, source = strings.TrimSpace(name), strings.TrimSpace(source)
+		if !found || name == "" || source == "" {
+			return nil, fmt.Errorf("%s:%d: expected 'name = source'", manifestFile, line)
+		}
+		dependencies = append(dependencies, Dependency{Name: name, Source: source})
+	}
+	return dependencies, scanner.Err()
+}
+
+// packageFile finds the file `use "pkg:name"` runs
+func packageFile(name string) (string, error) {
+	for _, extension := range lunaExtensions {
+		file := filepath.Join(packagesDir, name+extension)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	for _, entry := range entryFiles {
+		file := filepath.Join(packagesDir, name, entry)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("package '%s' is not installed, run luna install", name)
+}
+
+// install vendors a dependency under luna_modules, unless it already is
+func (d Dependency) install() error {
+	if _, err := packageFile(d.Name); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(packagesDir, 0755); err != nil {
+		return err
+	}
+
+	source, fragment, _ := strings.Cut(d.Source, "#")
+	if isGitSource(d.Source) {
+		// For a repository the fragment is the branch or tag to check out
+		args := []string{"clone", "--quiet", "--depth", "1"}
+		if fragment != "" {
+			args = append(args, "--branch", fragment)
+		}
+		args = append(args, strings.TrimPrefix(source, "git+"), filepath.Join(packagesDir, d.Name))
+		cmd := exec.Command("git", args...)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("git clone: %v", err)
+		}
+		_, err := packageFile(d.Name)
+		return err
+	}
+
+	if !isModuleURL(source) {
+		return fmt.Errorf("unsupported source '%s', expected an https:// URL or a git repository", d.Source)
+	}
+	code, _, err := downloadModule(source, strings.TrimPrefix(fragment, "sha256-"))
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(packagesDir, d.Name+".luna"), code, 0644)
+}
+
+// runInstall is the entry point of `luna install`
+func runInstall() {
+	dependencies, err := readManifest()
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	if len(dependencies) == 0 {
+		fmt.Println(gray("No dependencies in " + manifestFile))
+		return
+	}
+
+	failed := false
+	for _, dependency := range dependencies {
+		if err := dependency.install(); err != nil {
+			fmt.Println(formatError("Error", fmt.Sprintf("%s: %v", dependency.Name, err)))
+			failed = true
+			continue
+		}
+		fmt.Println(green("✓ ") + dependency.Name + gray(" "+dependency.Source))
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// runAdd is the entry point of `luna add <source> [--name=name]`
+func runAdd(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: luna add <source> [--name=name]")
+		return
+	}
+
+	dependency := Dependency{Name: packageName(args[0]), Source: args[0]}
+	if name, found := flagValue("--name"); found {
+		dependency.Name = name
+	}
+
+	dependencies, err := readManifest()
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	for _, existing := range dependencies {
+		if existing.Name == dependency.Name {
+			fmt.Println(formatError("Error", fmt.Sprintf("%s is already a dependency (%s)", existing.Name, existing.Source)))
+			os.Exit(1)
+		}
+	}
+
+	if err := dependency.install(); err != nil {
+		fmt.Println(formatError("Error", fmt.Sprintf("%s: %v", dependency.Name, err)))
+		os.Exit(1)
+	}
+
+	file, err := os.OpenFile(manifestFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err == nil {
+		_, err = fmt.Fprintf(file, "%s = %s\n", dependency.Name, dependency.Source)
+		file.Close()
+	}
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		os.Exit(1)
+	}
+	fmt.Println(green("Added " + dependency.Name))
+}
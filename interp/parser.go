@@ -0,0 +1,1732 @@
+package interp
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Parser struct {
+	tokens   []Token
+	position int
+	code     string
+	comments []*Comment // comments seen but not yet attached to the AST
+	doc      []string   // ## lines seen since the last statement began
+}
+
+func NewParser(tokens []Token, code string) *Parser {
+	return &Parser{
+		tokens:   tokens,
+		position: 0,
+		code:     code,
+	}
+}
+
+func (p *Parser) ProduceAST() (Statement, error) {
+	program := &Program{Body: []Statement{}}
+
+	for !p.isEOF() {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			program.Body = append(program.Body, stmt)
+		}
+	}
+
+	// Trailing comments at the end of the file
+	for _, comment := range p.comments {
+		program.Body = append(program.Body, comment)
+	}
+	p.comments = nil
+
+	return program, nil
+}
+
+func (p *Parser) parseStatement() (Statement, error) {
+	token := p.at()
+	var returned Statement
+	var err error
+
+	// Comments preceding the statement are emitted as statements of their own
+	if len(p.comments) > 0 {
+		comment := p.comments[0]
+		p.comments = p.comments[1:]
+		return comment, nil
+	}
+
+	// ## lines document the function declared right after them, blank
+	// lines in between aside, and are dropped before any other statement
+	var doc []string
+	if token.Type != NEWLINE {
+		doc, p.doc = p.doc, nil
+	}
+
+	modifiable := false // return, debug and expressions may end with if or unless
+	switch token.Type {
+	case OUT:
+		returned, err = p.parseFunctionDeclaration()
+	case FN:
+		returned, err = p.parseFunctionDeclaration()
+	case ASYNC:
+		if p.peek().Type == FN {
+			returned, err = p.parseFunctionDeclaration()
+		} else {
+			returned, err = p.parseExpression()
+		}
+	case IF:
+		returned, err = p.parseIfStatement()
+	case WHILE:
+		returned, err = p.parseWhileStatement()
+	case DO, REPEAT:
+		returned, err = p.parseDoWhileStatement()
+	case FOR:
+		returned, err = p.parseForStatement()
+	case RETURN:
+		returned, err = p.parseReturnStatement()
+		modifiable = true
+	case DEBUG:
+		returned, err = p.parseDebugStatement()
+		modifiable = true
+	case USE:
+		returned, err = p.parseUseStatement()
+	case NEWLINE:
+		p.eat() // Skip newlines
+		returned, err = nil, nil
+	default:
+		returned, err = p.parseExpression()
+		modifiable = true
+	}
+
+	if err == nil && modifiable && (p.at().Type == IF || p.at().Type == UNLESS) {
+		returned, err = p.parseStatementModifier(returned)
+	}
+	if fn, ok := returned.(*FunctionDeclaration); ok && doc != nil {
+		fn.Doc = strings.Join(doc, "\n")
+	}
+
+	// if ; then eat ;
+	if p.at().Type == SEMICOLON {
+		p.eat()
+	}
+
+	return returned, err
+}
+
+// parseStatementModifier turns `stmt if cond` into an if statement running
+// stmt when cond holds, and `stmt unless cond` into one running it when it
+// does not
+func (p *Parser) parseStatementModifier(stmt Statement) (Statement, error) {
+	modifier := p.eat().Value
+	test, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if modifier == "unless" {
+		test = &UnaryExpr{Value: test, Operator: "!"}
+	}
+	return &IfStatement{Test: test, Consequent: []Statement{stmt}, Modifier: modifier}, nil
+}
+
+// formatError reports message as a syntax error at token
+func (p *Parser) formatError(message string, token Token) error {
+	return newSyntaxError(p.code, message, token.Position, tokenLength(token))
+}
+
+func (p *Parser) parseExpression() (Expression, error) {
+	return p.parseAssignmentExpression()
+}
+
+func (p *Parser) parseAssignmentExpression() (Expression, error) {
+	left, err := p.parseTernaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type == EQUALS {
+		equals := p.eat()
+
+		// Array and object literals on the left are destructuring patterns
+		if left, err = p.toAssignmentTarget(left, equals); err != nil {
+			return nil, err
+		}
+
+		// Fix: Use parseExpression to parse the right-hand side
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &AssignmentExpr{Assigne: left, Value: value}, nil
+	}
+
+	// x += 1 and the other compound forms combine the target with the value
+	if p.at().Type == COMPOUND_ASSIGN {
+		token := p.eat()
+		switch left.(type) {
+		case *Identifier, *MemberExpr:
+		default:
+			return nil, p.formatError("invalid target for "+token.Value, token)
+		}
+		value, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &AssignmentExpr{Assigne: left, Value: value, Operator: strings.TrimSuffix(token.Value, "=")}, nil
+	}
+
+	if p.at().Type == COLON {
+		// Action assignment (const, var, out, etc.)
+		p.eat() // consume :
+		actionToken := p.eat()
+		action := actionToken.Value
+
+		if left, err = p.toAssignmentTarget(left, actionToken); err != nil {
+			return nil, err
+		}
+
+		if p.at().Type == EQUALS {
+			p.eat() // consume =
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			return &ActionAssignmentExpr{
+				Assigne: left,
+				Value:   value,
+				Action:  ActionExpr{Name: action, Args: []Expression{}},
+			}, nil
+		}
+	}
+
+	return left, nil
+}
+
+// toAssignmentTarget turns array and object literals into destructuring
+// patterns and leaves other expressions as they are, errors are reported at token
+func (p *Parser) toAssignmentTarget(expr Expression, token Token) (Expression, error) {
+	switch e := expr.(type) {
+	case *ArrayLiteral:
+		pattern := &ArrayPattern{}
+		for i, elem := range e.Elements {
+			var element PatternElement
+			if spread, ok := elem.(*SpreadElement); ok {
+				if i != len(e.Elements)-1 {
+					return nil, p.formatError("rest element must be last in a destructuring pattern", token)
+				}
+				element.Rest = true
+				elem = spread.Argument
+			}
+			if err := p.toPatternElement(elem, &element, token); err != nil {
+				return nil, err
+			}
+			pattern.Elements = append(pattern.Elements, element)
+		}
+		return pattern, nil
+
+	case *ObjectLiteral:
+		pattern := &ObjectPattern{}
+		for i, prop := range e.Properties {
+			element := PatternElement{Key: prop.Key, Rest: prop.Spread}
+			if prop.Spread && i != len(e.Properties)-1 {
+				return nil, p.formatError("rest element must be last in a destructuring pattern", token)
+			}
+			if err := p.toPatternElement(prop.Value, &element, token); err != nil {
+				return nil, err
+			}
+			pattern.Properties = append(pattern.Properties, element)
+		}
+		return pattern, nil
+	}
+	return expr, nil
+}
+
+// toPatternElement fills in the target and default of a pattern element from `target` or `target = default`
+func (p *Parser) toPatternElement(expr Expression, element *PatternElement, token Token) error {
+	if assignment, ok := expr.(*AssignmentExpr); ok && !element.Rest {
+		element.DefaultValue = assignment.Value
+		expr = assignment.Assigne
+	}
+
+	switch expr.(type) {
+	case *Identifier:
+		element.Target = expr
+	case *ArrayLiteral, *ObjectLiteral, *ArrayPattern, *ObjectPattern:
+		if element.Rest {
+			return p.formatError("rest element must be a name", token)
+		}
+		target, err := p.toAssignmentTarget(expr, token)
+		if err != nil {
+			return err
+		}
+		element.Target = target
+	default:
+		return p.formatError("invalid destructuring target", token)
+	}
+	return nil
+}
+
+func (p *Parser) parseTernaryExpression() (Expression, error) {
+	expr, err := p.parseLogicalExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type == TERNARY {
+		p.eat()                                       // consume ?
+		consequent, err := p.parseLogicalExpression() // Parse up to logical level to avoid consuming the colon
+		if err != nil {
+			return nil, err
+		}
+
+		if p.at().Type != COLON {
+			return nil, p.formatError("expected ':' in ternary expression", p.at())
+		}
+		p.eat() // consume :
+
+		alternate, err := p.parseTernaryExpression() // Allow nested ternary
+		if err != nil {
+			return nil, err
+		}
+
+		return &TernaryExpr{
+			Condition:  expr,
+			Consequent: consequent,
+			Alternate:  alternate,
+		}, nil
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) parseLogicalExpression() (Expression, error) {
+	left, err := p.parseEqualityExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Type == AND || p.at().Type == OR {
+		operator := p.eat().Value
+		right, err := p.parseEqualityExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &LogicalExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseEqualityExpression() (Expression, error) {
+	left, err := p.parseInequalityExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Type == EQUALITY_OP || p.at().Type == INEQUALITY_OP {
+		operator := p.eat().Value
+		right, err := p.parseInequalityExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &EqualityExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseInequalityExpression() (Expression, error) {
+	left, err := p.parseRangeExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Type == SMALLER_THAN || p.at().Type == GREATER_THAN ||
+		p.at().Type == SMALLER_OR_EQUAL || p.at().Type == GREATER_OR_EQUAL {
+		operator := p.eat().Value
+		right, err := p.parseRangeExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &InequalityExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+// parseRangeExpression parses start..end and start..=end, which do not chain
+func (p *Parser) parseRangeExpression() (Expression, error) {
+	start, err := p.parseBitwiseOrExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != RANGE && p.at().Type != RANGE_INCLUSIVE {
+		return start, nil
+	}
+	inclusive := p.eat().Type == RANGE_INCLUSIVE
+
+	end, err := p.parseBitwiseOrExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &RangeExpr{Start: start, End: end, Inclusive: inclusive}, nil
+}
+
+// The bitwise operators bind tighter than comparisons, so x & mask == 0
+// tests the masked bits: | is loosest, then ^, then &, then the shifts.
+
+func (p *Parser) parseBitwiseOrExpression() (Expression, error) {
+	return p.parseBinaryLevel(p.parseBitwiseXorExpression, "|")
+}
+
+func (p *Parser) parseBitwiseXorExpression() (Expression, error) {
+	return p.parseBinaryLevel(p.parseBitwiseAndExpression, "^")
+}
+
+func (p *Parser) parseBitwiseAndExpression() (Expression, error) {
+	return p.parseBinaryLevel(p.parseShiftExpression, "&")
+}
+
+func (p *Parser) parseShiftExpression() (Expression, error) {
+	return p.parseBinaryLevel(p.parseAdditiveExpression, "<<", ">>")
+}
+
+// parseBinaryLevel parses a left-associative chain of operators over operands parsed by next
+func (p *Parser) parseBinaryLevel(next func() (Expression, error), operators ...string) (Expression, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Type == BINARY_OPERATOR && slices.Contains(operators, p.at().Value) {
+		operator := p.eat().Value
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseAdditiveExpression() (Expression, error) {
+	left, err := p.parseMultiplicativeExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Value == "+" || p.at().Value == "-" {
+		operator := p.eat().Value
+		right, err := p.parseMultiplicativeExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+func (p *Parser) parseMultiplicativeExpression() (Expression, error) {
+	left, err := p.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Value == "*" || p.at().Value == "/" || p.at().Value == "%" || p.at().Value == "//" {
+		operator := p.eat().Value
+		right, err := p.parseUnaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Left: left, Right: right, Operator: operator}
+	}
+
+	return left, nil
+}
+
+// Add support for postfix increment/decrement (x++, x--)
+func (p *Parser) parseUnaryExpression() (Expression, error) {
+	// Prefix unary
+	if p.at().Type == NEGATION_OP || p.at().Value == "+" || p.at().Value == "-" || p.at().Value == "~" ||
+		p.at().Type == INCREMENT || p.at().Type == DECREMENT {
+		operator := p.eat().Value
+		value, err := p.parseUnaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Value: value, Operator: operator}, nil
+	}
+
+	return p.parseExponentExpression()
+}
+
+// parseExponentExpression parses ** which binds tighter than a unary
+// operator on its left and is right-associative, -2 ** 2 is -4 and
+// 2 ** 3 ** 2 is 2 ** 9
+func (p *Parser) parseExponentExpression() (Expression, error) {
+	base, err := p.parsePostfixExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.at().Value != "**" {
+		return base, nil
+	}
+	operator := p.eat().Value
+	// The exponent may itself be negated, 2 ** -1
+	exponent, err := p.parseUnaryExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpr{Left: base, Right: exponent, Operator: operator}, nil
+}
+
+func (p *Parser) parsePostfixExpression() (Expression, error) {
+	// Parse primary/call/member first
+	expr, err := p.parseCallMemberExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	// Postfix unary (x++ or x--)
+	if p.at().Type == INCREMENT || p.at().Type == DECREMENT {
+		operator := p.eat().Value
+		return &UnaryExpr{Value: expr, Operator: operator + "_post"}, nil
+	}
+
+	return expr, nil
+}
+
+func (p *Parser) parseCallMemberExpression() (Expression, error) {
+	member, err := p.parseMemberExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type == OPEN_PAREN {
+		return p.parseCallExpression(member)
+	}
+
+	return member, nil
+}
+
+func (p *Parser) parseCallExpression(caller Expression) (Expression, error) {
+	callExpr := &CallExpr{Caller: caller, Args: []Expression{}}
+
+	p.eat() // consume (
+	if p.at().Type != CLOSE_PAREN {
+		for {
+			// Named argument: name=value
+			if p.at().Type == IDENTIFIER && p.peek().Type == EQUALS {
+				name := p.eat()
+				p.eat() // consume =
+				value, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				callExpr.Named = append(callExpr.Named, NamedArg{Name: name.Value, Value: value, Position: name.Position})
+			} else {
+				if len(callExpr.Named) > 0 {
+					return nil, p.formatError("positional argument after named arguments", p.at())
+				}
+				arg, err := p.parseSpreadableExpression()
+				if err != nil {
+					return nil, err
+				}
+				callExpr.Args = append(callExpr.Args, arg)
+			}
+
+			if p.at().Type == COMMA {
+				p.eat()
+			} else {
+				break
+			}
+		}
+	}
+
+	if p.at().Type != CLOSE_PAREN {
+		return nil, p.formatError("expected ')' after function arguments", p.at())
+	}
+	p.eat() // consume )
+
+	// Handle chained calls
+	if p.at().Type == OPEN_PAREN {
+		return p.parseCallExpression(callExpr)
+	}
+
+	return callExpr, nil
+}
+
+func (p *Parser) parseMemberExpression() (Expression, error) {
+	object, err := p.parsePrimaryExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.at().Type == DOT || p.at().Type == OPTIONAL_DOT || p.at().Type == OPEN_BRACKET {
+		if p.at().Type == DOT || p.at().Type == OPTIONAL_DOT {
+			optional := p.eat().Type == OPTIONAL_DOT // consume . or ?.
+
+			// Keywords are fine as property names: obj.match, assert.true
+			if _, isKeyword := keywords[p.at().Value]; isKeyword {
+				token := p.eat()
+				object = &MemberExpr{Object: object, Property: &Identifier{Value: token.Value, Position: token.Position}, Computed: false, Optional: optional}
+				continue
+			}
+
+			property, err := p.parsePrimaryExpression()
+			if err != nil {
+				return nil, err
+			}
+			object = &MemberExpr{Object: object, Property: property, Computed: false, Optional: optional}
+		} else {
+			p.eat() // consume [
+
+			// Bounds stop at the ternary level so the ':' of a slice is not taken for an action
+			var property Expression
+			if p.at().Type != COLON {
+				property, err = p.parseTernaryExpression()
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			if p.at().Type == COLON {
+				p.eat() // consume :
+				var end Expression
+				if p.at().Type != CLOSE_BRACKET {
+					end, err = p.parseTernaryExpression()
+					if err != nil {
+						return nil, err
+					}
+				}
+				if p.at().Type != CLOSE_BRACKET {
+					return nil, p.formatError("expected ']' after slice", p.at())
+				}
+				p.eat() // consume ]
+				object = &SliceExpr{Object: object, Start: property, End: end}
+				continue
+			}
+
+			if p.at().Type != CLOSE_BRACKET {
+				return nil, p.formatError("expected ']' after computed member access", p.at())
+			}
+			p.eat() // consume ]
+			object = &MemberExpr{Object: object, Property: property, Computed: true}
+		}
+	}
+
+	return object, nil
+}
+
+func (p *Parser) parsePrimaryExpression() (Expression, error) {
+	token := p.at()
+
+	switch token.Type {
+	case IDENTIFIER:
+		token := p.eat()
+		return &Identifier{Value: token.Value, Position: token.Position}, nil
+
+	case INT:
+		token := p.eat()
+		integer, ok := parseIntLiteral(token.Value)
+		if !ok {
+			return nil, p.formatError("invalid integer literal", token)
+		}
+		// Whole numbers too large for 64 bits stay floats
+		value, _ := new(big.Float).SetInt(integer).Float64()
+		return &NumericLiteral{Value: value, Raw: token.Value, IsInt: integer.IsInt64(), Int: integer.Int64()}, nil
+
+	case FLOAT:
+		token := p.eat()
+		value, err := strconv.ParseFloat(strings.ReplaceAll(token.Value, "_", ""), 64)
+		if err != nil {
+			return nil, p.formatError("float literal out of range", token)
+		}
+		return &NumericLiteral{Value: value, Raw: token.Value}, nil
+
+	case BIGINT:
+		token := p.eat()
+		value, ok := parseIntLiteral(token.Value)
+		if !ok {
+			return nil, p.formatError("invalid bigint literal", token)
+		}
+		return &NumericLiteral{Raw: token.Value + "n", Big: value}, nil
+
+	case STRING, TEMPLATE_STRING:
+		return p.parseStringLiteral(p.eat())
+
+	case RAW_STRING:
+		token := p.eat()
+		return &StringLiteral{Value: token.Value, Raw: p.rawString(token)}, nil
+
+	case BOOLEAN:
+		value := p.eat().Value == "true"
+		return &BooleanLiteral{Value: value}, nil
+
+	case UNDEFINED:
+		p.eat()
+		return &UndefinedLiteral{}, nil
+
+	case TYPEOF:
+		p.eat()
+		value, err := p.parseUnaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &TypeofExpr{Value: value}, nil
+
+	case AWAIT:
+		p.eat()
+		value, err := p.parseUnaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		return &AwaitExpr{Value: value}, nil
+
+	case ASYNC:
+		p.eat()
+		if p.at().Type != FN && p.at().Type != LAMBDA {
+			return nil, p.formatError("expected 'fn' or 'lambda' after 'async'", p.at())
+		}
+		expr, err := p.parseFunctionExpression()
+		if err != nil {
+			return nil, err
+		}
+		// fn:: expr is called right away, async fn:: expr starts a task
+		switch e := expr.(type) {
+		case *FunctionDeclaration:
+			e.Async = true
+		case *CallExpr:
+			e.Caller.(*FunctionDeclaration).Async = true
+		}
+		return expr, nil
+
+	case SPAWN:
+		p.eat()
+		value, err := p.parseCallMemberExpression()
+		if err != nil {
+			return nil, err
+		}
+		call, ok := value.(*CallExpr)
+		if !ok {
+			return nil, p.formatError("spawn expects a function call", token)
+		}
+		return &SpawnExpr{Call: call}, nil
+
+	case OPEN_PAREN:
+		p.eat() // consume (
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		if p.at().Type != CLOSE_PAREN {
+			return nil, p.formatError("expected ')' after expression", p.at())
+		}
+		p.eat() // consume )
+		return expr, nil
+
+	case OPEN_BRACKET:
+		return p.parseArrayLiteral()
+
+	case OPEN_BRACE:
+		return p.parseObjectLiteral()
+
+	case FN, LAMBDA:
+		return p.parseFunctionExpression()
+
+	case MATCH:
+		return p.parseMatchExpression()
+
+	default:
+		switch token.Type {
+		case EOF:
+			return nil, p.formatError("unexpected end of input", token)
+		case NEWLINE:
+			return nil, p.formatError("unexpected end of line", token)
+		}
+		return nil, p.formatError(fmt.Sprintf("unexpected token: %v", token.Value), token)
+	}
+}
+
+// parseMatchExpression parses
+//
+//	match subject {
+//	    1, 2 => "small"
+//	    3 => { ... }
+//	    else => "other"
+//	}
+func (p *Parser) parseMatchExpression() (Expression, error) {
+	p.eat() // consume match
+
+	subject, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	if p.at().Type != OPEN_BRACE {
+		return nil, p.formatError("expected '{' after match subject", p.at())
+	}
+	p.eat() // consume {
+
+	match := &MatchExpr{Subject: subject}
+	for p.skipNewlines(); p.at().Type != CLOSE_BRACE && !p.isEOF(); p.skipNewlines() {
+		var arm MatchArm
+		if p.at().Type == ELSE {
+			p.eat() // consume else
+			arm.Default = true
+		} else {
+			for {
+				pattern, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				arm.Patterns = append(arm.Patterns, pattern)
+				if p.at().Type != COMMA {
+					break
+				}
+				p.eat() // consume ,
+			}
+		}
+
+		if p.at().Type != ARROW {
+			return nil, p.formatError("expected '=>' after match pattern", p.at())
+		}
+		p.eat() // consume =>
+		p.skipNewlines()
+
+		if p.at().Type == OPEN_BRACE {
+			p.eat() // consume {
+			arm.Block = true
+			for !p.atBlockEnd() {
+				stmt, err := p.parseStatement()
+				if err != nil {
+					return nil, err
+				}
+				if stmt != nil {
+					arm.Body = append(arm.Body, stmt)
+				}
+			}
+			if p.at().Type != CLOSE_BRACE {
+				return nil, p.formatError("expected '}' after match arm", p.at())
+			}
+			p.eat() // consume }
+		} else {
+			value, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			arm.Body = []Statement{value}
+		}
+		match.Arms = append(match.Arms, arm)
+
+		// Arms may be separated by commas or semicolons
+		if p.at().Type == COMMA || p.at().Type == SEMICOLON {
+			p.eat()
+		}
+	}
+
+	if p.at().Type != CLOSE_BRACE {
+		return nil, p.formatError("expected '}' after match arms", p.at())
+	}
+	p.eat() // consume }
+
+	return match, nil
+}
+
+// parseSpreadableExpression parses an expression optionally prefixed with '...'
+func (p *Parser) parseSpreadableExpression() (Expression, error) {
+	if p.at().Type != ELLIPSIS {
+		return p.parseExpression()
+	}
+	p.eat() // consume ...
+
+	argument, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &SpreadElement{Argument: argument}, nil
+}
+
+func (p *Parser) parseArrayLiteral() (Expression, error) {
+	p.eat() // consume [
+	elements := []Expression{}
+
+	if p.at().Type != CLOSE_BRACKET {
+		for {
+			expr, err := p.parseSpreadableExpression()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, expr)
+
+			if p.at().Type == COMMA {
+				p.eat()
+			} else {
+				break
+			}
+		}
+	}
+
+	if p.at().Type != CLOSE_BRACKET {
+		return nil, p.formatError("expected ']' after array elements", p.at())
+	}
+	p.eat() // consume ]
+
+	return &ArrayLiteral{Elements: elements}, nil
+}
+
+func (p *Parser) parseObjectLiteral() (Expression, error) {
+	p.eat() // consume {
+	properties := []Property{}
+
+	if p.at().Type != CLOSE_BRACE {
+		for {
+			// Spread properties: { ...base, extra: 1 }
+			if p.at().Type == ELLIPSIS {
+				p.eat() // consume ...
+				value, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				properties = append(properties, Property{Value: value, Spread: true})
+
+				if p.at().Type == COMMA {
+					p.eat()
+					continue
+				}
+				break
+			}
+
+			_, isKeyword := keywords[p.at().Value]
+			if p.at().Type != IDENTIFIER && !isStringToken(p.at().Type) && !isKeyword {
+				return nil, p.formatError("expected property name", p.at())
+			}
+			keyToken := p.eat()
+			key := keyToken.Value
+
+			// Shorthand with a default, only meaningful as a destructuring pattern: { x = 1 }
+			if p.at().Type == EQUALS {
+				p.eat() // consume =
+				value, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				target := &Identifier{Value: key, Position: keyToken.Position}
+				properties = append(properties, Property{Key: key, Value: &AssignmentExpr{Assigne: target, Value: value}})
+			} else if p.at().Type == COMMA || p.at().Type == CLOSE_BRACE {
+				// Support shorthand property syntax: { x, y } instead of { x: x, y: y }
+				// Shorthand property
+				properties = append(properties, Property{Key: key, Value: &Identifier{Value: key, Position: keyToken.Position}})
+			} else {
+				if p.at().Type != COLON {
+					return nil, p.formatError("expected ':' after property name", p.at())
+				}
+				p.eat() // consume :
+
+				value, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+
+				properties = append(properties, Property{Key: key, Value: value})
+			}
+
+			if p.at().Type == COMMA {
+				p.eat()
+			} else {
+				break
+			}
+		}
+	}
+
+	if p.at().Type != CLOSE_BRACE {
+		return nil, p.formatError("expected '}' after object properties", p.at())
+	}
+	p.eat() // consume }
+
+	return &ObjectLiteral{Properties: properties}, nil
+}
+
+// Update parseFunctionExpression to handle fn:: syntax
+func (p *Parser) parseFunctionExpression() (Expression, error) {
+	isLambda := p.at().Type == LAMBDA
+	p.eat() // consume fn or lambda
+
+	name := ""
+
+	// Check for anonymous function syntax: fn: or fn::
+	if p.at().Type == COLON {
+		p.eat() // consume :
+
+		// Check for direct call syntax: fn::
+		if p.at().Type == COLON {
+			p.eat() // consume second :
+			// Parse the expression to call immediately
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			// Create anonymous function that returns the expression and call it immediately
+			body := []Statement{&ReturnExpr{Value: expr}}
+			fn := &FunctionDeclaration{
+				Name:       "",
+				Parameters: []Parameter{},
+				Body:       body,
+				Export:     false,
+				Inline:     true,
+			}
+			// Return a call expression
+			return &CallExpr{Caller: fn, Args: []Expression{}}, nil
+		}
+
+		// Parse parameters for fn: syntax
+		parameters, err := p.parseParameterList()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.at().Type != COLON {
+			return nil, p.formatError("expected ':' after function parameters in anonymous function", p.at())
+		}
+		p.eat() // consume :
+
+		// Parse the expression body
+		expr, err := p.parseTernaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		body := []Statement{&ReturnExpr{Value: expr}}
+
+		return &FunctionDeclaration{
+			Name:       "",
+			Parameters: parameters,
+			Body:       body,
+			Export:     false,
+			Inline:     true,
+		}, nil
+	}
+
+	// Regular function syntax
+	var position Position
+	if !isLambda && p.at().Type == IDENTIFIER {
+		position = p.at().Position
+		name = p.eat().Value
+	}
+
+	parameters, err := p.parseParameterList()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE && p.at().Type != COLON {
+		return nil, p.formatError("expected '{' or ':' after function parameters", p.at())
+	}
+
+	var body []Statement
+	inline := p.at().Type == COLON
+	if p.at().Type == OPEN_BRACE {
+		p.eat() // consume {
+		for !p.atBlockEnd() {
+			stmt, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			if stmt != nil {
+				body = append(body, stmt)
+			}
+		}
+		if p.at().Type != CLOSE_BRACE {
+			return nil, p.formatError("expected '}' after function body", p.at())
+		}
+		p.eat() // consume }
+	} else {
+		p.eat() // consume :
+		// Parse the full expression including ternary
+		expr, err := p.parseTernaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		body = []Statement{&ReturnExpr{Value: expr}}
+	}
+
+	return &FunctionDeclaration{
+		Name:       name,
+		Parameters: parameters,
+		Body:       body,
+		Export:     false,
+		Inline:     inline,
+		Position:   position,
+	}, nil
+}
+
+// Add new method to parse parameter list with defaults
+func (p *Parser) parseParameterList() ([]Parameter, error) {
+	var parameters []Parameter
+
+	for p.at().Type == IDENTIFIER || p.at().Type == ELLIPSIS || p.at().Type == OPEN_BRACKET || p.isObjectPatternParameter() {
+		// Rest parameter: ...name, which must come last
+		if p.at().Type == ELLIPSIS {
+			p.eat() // consume ...
+			if p.at().Type != IDENTIFIER {
+				return nil, p.formatError("expected parameter name after '...'", p.at())
+			}
+			name := p.eat()
+			parameters = append(parameters, Parameter{Name: name.Value, Rest: true, Position: name.Position})
+			if p.at().Type == IDENTIFIER || p.at().Type == ELLIPSIS {
+				return nil, p.formatError("rest parameter must be the last parameter", p.at())
+			}
+			break
+		}
+
+		var paramName string
+		var pattern Expression
+		position := p.at().Position
+		if p.at().Type == IDENTIFIER {
+			paramName = p.eat().Value
+		} else {
+			// Destructuring parameter: [a, b] or {name, age}
+			var literal Expression
+			var err error
+			open := p.at()
+			if p.at().Type == OPEN_BRACKET {
+				literal, err = p.parseArrayLiteral()
+			} else {
+				literal, err = p.parseObjectLiteral()
+			}
+			if err != nil {
+				return nil, err
+			}
+			if pattern, err = p.toAssignmentTarget(literal, open); err != nil {
+				return nil, err
+			}
+		}
+		var defaultValue Expression
+
+		// Check for default parameter syntax: param=(defaultValue)
+		if p.at().Type == EQUALS {
+			p.eat() // consume =
+			if p.at().Type != OPEN_PAREN {
+				return nil, p.formatError("expected '(' after '=' in default parameter", p.at())
+			}
+			p.eat() // consume (
+
+			defaultExpr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			defaultValue = defaultExpr
+
+			if p.at().Type != CLOSE_PAREN {
+				return nil, p.formatError("expected ')' after default parameter value", p.at())
+			}
+			p.eat() // consume )
+		}
+
+		parameters = append(parameters, Parameter{
+			Name:         paramName,
+			DefaultValue: defaultValue,
+			Pattern:      pattern,
+			Position:     position,
+		})
+	}
+
+	return parameters, nil
+}
+
+// isObjectPatternParameter tells a {name, age} parameter from the function
+// body: a pattern starts with a property and is followed by more parameters
+// or the start of the body
+func (p *Parser) isObjectPatternParameter() bool {
+	if p.at().Type != OPEN_BRACE || p.position+2 >= len(p.tokens) {
+		return false
+	}
+	first, second := p.tokens[p.position+1], p.tokens[p.position+2]
+	if first.Type == ELLIPSIS {
+		second = first
+	} else if first.Type != IDENTIFIER && !isStringToken(first.Type) {
+		return false
+	}
+	switch second.Type {
+	case COMMA, COLON, EQUALS, CLOSE_BRACE, ELLIPSIS:
+	default:
+		return false
+	}
+
+	depth := 0
+	for i := p.position; i < len(p.tokens); i++ {
+		switch p.tokens[i].Type {
+		case OPEN_BRACE, OPEN_BRACKET, OPEN_PAREN:
+			depth++
+		case CLOSE_BRACE, CLOSE_BRACKET, CLOSE_PAREN:
+			depth--
+		}
+		if depth > 0 {
+			continue
+		}
+
+		if i+1 >= len(p.tokens) {
+			return false
+		}
+		switch p.tokens[i+1].Type {
+		case OPEN_BRACE, COLON, EQUALS, IDENTIFIER, OPEN_BRACKET, ELLIPSIS:
+			return true
+		}
+		return false
+	}
+	return false
+}
+
+// Update parseFunctionDeclaration to use new parameter parsing
+func (p *Parser) parseFunctionDeclaration() (Statement, error) {
+	var t Token = p.eat() // consume fn/out/async
+
+	var out bool = false
+	if t.Type == OUT {
+		out = true
+		if p.at().Type == ASYNC {
+			t = p.eat() // out async fn
+		}
+	}
+	async := t.Type == ASYNC
+
+	if out || async {
+		// expect fn keyword
+		if p.at().Type != FN {
+			return nil, p.formatError(fmt.Sprintf("expected 'fn' after '%s'", t.Value), p.at())
+		}
+		p.eat() // consume fn
+	}
+
+	// Check for fn:: syntax at statement level
+	if p.at().Type == COLON {
+		p.eat() // consume :
+		if p.at().Type == COLON {
+			p.eat() // consume second :
+			// Parse the expression to call immediately
+			expr, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			// Create anonymous function that returns the expression and call it immediately
+			body := []Statement{&ReturnExpr{Value: expr}}
+			fn := &FunctionDeclaration{
+				Name:       "",
+				Parameters: []Parameter{},
+				Body:       body,
+				Export:     out,
+				Inline:     true,
+				Async:      async,
+			}
+			// Return a call expression as a statement
+			return fn, nil
+		}
+		// If only one colon, this is an error for function declaration
+		return nil, p.formatError("unexpected ':' after 'fn' in function declaration", p.at())
+	}
+
+	if p.at().Type != IDENTIFIER {
+		return nil, p.formatError("expected function name", p.at())
+	}
+	position := p.at().Position
+	name := p.eat().Value
+
+	parameters, err := p.parseParameterList()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE && p.at().Type != COLON {
+		return nil, p.formatError("expected '{' or ':' after function parameters", p.at())
+	}
+
+	var body []Statement
+	inline := p.at().Type == COLON
+	if p.at().Type == OPEN_BRACE {
+		p.eat() // consume {
+		for !p.atBlockEnd() {
+			stmt, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			if stmt != nil {
+				body = append(body, stmt)
+			}
+		}
+		if p.at().Type != CLOSE_BRACE {
+			return nil, p.formatError("expected '}' after function body", p.at())
+		}
+		p.eat() // consume }
+	} else {
+		// Colon syntax - single expression
+		p.eat() // consume :
+		// Parse the full expression including ternary
+		expr, err := p.parseTernaryExpression()
+		if err != nil {
+			return nil, err
+		}
+		// Wrap the expression in a return statement
+		body = []Statement{&ReturnExpr{Value: expr}}
+	}
+
+	return &FunctionDeclaration{
+		Name:       name,
+		Parameters: parameters,
+		Body:       body,
+		Export:     out,
+		Inline:     inline,
+		Async:      async,
+		Position:   position,
+	}, nil
+}
+
+func (p *Parser) parseIfStatement() (Statement, error) {
+	p.eat() // consume if
+
+	test, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE && p.at().Type != COLON {
+		return nil, p.formatError("expected '{' or ':' after if condition", p.at())
+	}
+
+	var consequent []Statement
+	if p.at().Type == OPEN_BRACE {
+		p.eat() // consume {
+		for !p.atBlockEnd() {
+			stmt, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			if stmt != nil {
+				consequent = append(consequent, stmt)
+			}
+		}
+		if p.at().Type != CLOSE_BRACE {
+			return nil, p.formatError("expected '}' after if body", p.at())
+		}
+		p.eat() // consume }
+	} else {
+		p.eat() // consume :
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			consequent = []Statement{stmt}
+		}
+	}
+
+	var alternate []Statement
+	if p.at().Type == ELSE {
+		p.eat() // consume else
+		if p.at().Type == IF {
+			// else if
+			elseIf, err := p.parseIfStatement()
+			if err != nil {
+				return nil, err
+			}
+			alternate = []Statement{elseIf}
+		} else {
+			// else block
+			if p.at().Type == OPEN_BRACE {
+				p.eat() // consume {
+				for !p.atBlockEnd() {
+					stmt, err := p.parseStatement()
+					if err != nil {
+						return nil, err
+					}
+					if stmt != nil {
+						alternate = append(alternate, stmt)
+					}
+				}
+				if p.at().Type != CLOSE_BRACE {
+					return nil, p.formatError("expected '}' after else body", p.at())
+				}
+				p.eat() // consume }
+			} else {
+				stmt, err := p.parseStatement()
+				if err != nil {
+					return nil, err
+				}
+				if stmt != nil {
+					alternate = []Statement{stmt}
+				}
+			}
+		}
+	}
+
+	return &IfStatement{
+		Test:       test,
+		Consequent: consequent,
+		Alternate:  alternate,
+	}, nil
+}
+
+// parseDoWhileStatement parses `do { ... } while test` and `repeat { ... } until test`
+func (p *Parser) parseDoWhileStatement() (Statement, error) {
+	keyword := p.eat() // consume do or repeat
+	ending, word := WHILE, "while"
+	if keyword.Type == REPEAT {
+		ending, word = UNTIL, "until"
+	}
+
+	if p.at().Type != OPEN_BRACE {
+		return nil, p.formatError(fmt.Sprintf("expected '{' after %s", keyword.Value), p.at())
+	}
+	p.eat() // consume {
+	var body []Statement
+	for !p.atBlockEnd() {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			body = append(body, stmt)
+		}
+	}
+	if p.at().Type != CLOSE_BRACE {
+		return nil, p.formatError(fmt.Sprintf("expected '}' after %s body", keyword.Value), p.at())
+	}
+	p.eat() // consume }
+
+	if p.at().Type != ending {
+		return nil, p.formatError(fmt.Sprintf("expected '%s' after %s block", word, keyword.Value), p.at())
+	}
+	p.eat() // consume while or until
+
+	test, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+	return &DoWhileStatement{Body: body, Test: test, Until: ending == UNTIL}, nil
+}
+
+func (p *Parser) parseWhileStatement() (Statement, error) {
+	p.eat() // consume while
+
+	test, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE && p.at().Type != COLON {
+		return nil, p.formatError("expected '{' or ':' after while condition", p.at())
+	}
+
+	var consequent []Statement
+	if p.at().Type == OPEN_BRACE {
+		p.eat() // consume {
+		for !p.atBlockEnd() {
+			stmt, err := p.parseStatement()
+			if err != nil {
+				return nil, err
+			}
+			if stmt != nil {
+				consequent = append(consequent, stmt)
+			}
+		}
+		if p.at().Type != CLOSE_BRACE {
+			return nil, p.formatError("expected '}' after while body", p.at())
+		}
+		p.eat() // consume }
+	} else {
+		p.eat() // consume :
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			consequent = []Statement{stmt}
+		}
+	}
+
+	return &WhileStatement{
+		Test:       test,
+		Consequent: consequent,
+	}, nil
+}
+
+func (p *Parser) parseForStatement() (Statement, error) {
+	p.eat() // consume for
+
+	if p.at().Type == IDENTIFIER && p.peek().Type == IN {
+		return p.parseForInStatement()
+	}
+
+	declaration, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != SEMICOLON {
+		return nil, p.formatError("expected ';' after for declaration", p.at())
+	}
+	p.eat() // consume ;
+
+	test, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != SEMICOLON {
+		return nil, p.formatError("expected ';' after for test", p.at())
+	}
+	p.eat() // consume ;
+
+	increaser, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE {
+		return nil, p.formatError("expected '{' after for header", p.at())
+	}
+
+	p.eat() // consume {
+
+	body := []Statement{}
+	for !p.atBlockEnd() {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			body = append(body, stmt)
+		}
+	}
+
+	if p.at().Type != CLOSE_BRACE {
+		return nil, p.formatError("expected '}' after for body", p.at())
+	}
+	p.eat() // consume }
+
+	return &ForStatement{
+		Declaration: declaration,
+		Test:        test,
+		Increaser:   increaser,
+		Body:        body,
+	}, nil
+}
+
+// parseForInStatement parses the rest of `for name in iterable { ... }`
+func (p *Parser) parseForInStatement() (Statement, error) {
+	token := p.eat() // name
+	p.eat()          // consume in
+
+	iterable, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	if p.at().Type != OPEN_BRACE {
+		return nil, p.formatError("expected '{' after for-in iterable", p.at())
+	}
+	p.eat() // consume {
+
+	body := []Statement{}
+	for !p.atBlockEnd() {
+		stmt, err := p.parseStatement()
+		if err != nil {
+			return nil, err
+		}
+		if stmt != nil {
+			body = append(body, stmt)
+		}
+	}
+
+	if p.at().Type != CLOSE_BRACE {
+		return nil, p.formatError("expected '}' after for body", p.at())
+	}
+	p.eat() // consume }
+
+	return &ForInStatement{
+		Variable: &Identifier{Value: token.Value, Position: token.Position},
+		Iterable: iterable,
+		Body:     body,
+	}, nil
+}
+
+func (p *Parser) parseReturnStatement() (Statement, error) {
+	token := p.eat() // consume return
+
+	value, err := p.parseExpression()
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReturnExpr{Value: value, Position: token.Position}, nil
+}
+
+func (p *Parser) parseDebugStatement() (Statement, error) {
+	position := p.eat().Position // consume debug
+
+	// A string right before the braces labels the output
+	var label Expression
+	if isStringToken(p.at().Type) && p.peek().Type == OPEN_BRACE {
+		var err error
+		if label, err = p.parsePrimaryExpression(); err != nil {
+			return nil, err
+		}
+	}
+
+	props := []Expression{}
+	if p.at().Type == OPEN_BRACE {
+		p.eat() // consume {
+		if p.at().Type != CLOSE_BRACE {
+			for {
+				expr, err := p.parseExpression()
+				if err != nil {
+					return nil, err
+				}
+				props = append(props, expr)
+
+				if p.at().Type == COMMA {
+					p.eat()
+				} else {
+					break
+				}
+			}
+		}
+		if p.at().Type != CLOSE_BRACE {
+			return nil, p.formatError("expected '}' after debug props", p.at())
+		}
+		p.eat() // consume }
+	} else {
+		expr, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		props = []Expression{expr}
+	}
+
+	return &DebugStatement{Label: label, Props: props, Position: position}, nil
+}
+
+func (p *Parser) parseUseStatement() (Statement, error) {
+	p.eat() // consume use
+
+	if !isStringToken(p.at().Type) {
+		return nil, p.formatError("expected string after use", p.at())
+	}
+	path := p.eat().Value
+
+	return &UseStatement{Path: path}, nil
+}
+
+// parseIntLiteral reads the digits of an INT or BIGINT token, in hex, octal
+// or binary after a 0x, 0o or 0b prefix and decimal otherwise, even with a
+// leading 0
+func parseIntLiteral(literal string) (*big.Int, bool) {
+	if len(literal) > 2 && literal[0] == '0' && strings.ContainsRune("xXoObB", rune(literal[1])) {
+		return new(big.Int).SetString(literal, 0)
+	}
+	return new(big.Int).SetString(strings.ReplaceAll(literal, "_", ""), 10)
+}
+
+// isStringToken reports whether t is one of the string literal tokens
+func isStringToken(t TokenType) bool {
+	return t == STRING || t == RAW_STRING || t == TEMPLATE_STRING
+}
+
+// rawString recovers a string literal exactly as written, when the source is known
+func (p *Parser) rawString(token Token) string {
+	input := []rune(p.code)
+	start := token.Position.Index
+	if token.Type == TEMPLATE_STRING {
+		start++ // the f of f`...`
+	}
+	if start >= len(input) {
+		return ""
+	}
+
+	quote := input[start]
+	escaped := false
+	for i := start + 1; i < len(input); i++ {
+		switch {
+		case quote == '`':
+			if input[i] == quote {
+				return string(input[token.Position.Index : i+1])
+			}
+		case escaped:
+			escaped = false
+		case input[i] == '\\':
+			escaped = true
+		case input[i] == quote:
+			return string(input[token.Position.Index : i+1])
+		}
+	}
+	return ""
+}
+
+func (p *Parser) at() Token {
+	// Comments are collected on the side so they never get in the way of the grammar
+	for p.position < len(p.tokens) {
+		switch token := p.tokens[p.position]; token.Type {
+		case COMMENT:
+			p.comments = append(p.comments, &Comment{Text: token.Value, Trailing: p.endsLine()})
+		case DOC_COMMENT:
+			p.doc = append(p.doc, docLine(token.Value))
+		default:
+			return p.tokens[p.position]
+		}
+		p.position++
+	}
+
+	return Token{Type: EOF, Value: "", Position: Position{}}
+}
+
+// endsLine reports whether the comment at the current position ends a line
+// that has code before it
+func (p *Parser) endsLine() bool {
+	if p.position == 0 || p.tokens[p.position-1].Type == NEWLINE {
+		return false
+	}
+	next := p.position + 1
+	return next == len(p.tokens) || p.tokens[next].Type == NEWLINE || p.tokens[next].Type == EOF
+}
+
+// docLine is the text of a ## line without the marker and the space after it
+func docLine(comment string) string {
+	line := strings.TrimPrefix(comment, "##")
+	return strings.TrimRight(strings.TrimPrefix(line, " "), " \t\r")
+}
+
+// skipNewlines moves past newline tokens where line breaks carry no meaning
+func (p *Parser) skipNewlines() {
+	for p.at().Type == NEWLINE {
+		p.eat()
+	}
+}
+
+// peek returns the token after the current one, skipping comments
+func (p *Parser) peek() Token {
+	p.at()
+	for i := p.position + 1; i < len(p.tokens); i++ {
+		if p.tokens[i].Type != COMMENT && p.tokens[i].Type != DOC_COMMENT {
+			return p.tokens[i]
+		}
+	}
+	return Token{Type: EOF, Value: "", Position: Position{}}
+}
+
+func (p *Parser) eat() Token {
+	token := p.at()
+	p.position++
+	return token
+}
+
+func (p *Parser) isEOF() bool {
+	return p.at().Type == EOF
+}
+
+// atBlockEnd reports whether the statements of a block end here, comments
+// read before its closing } still belong to the block
+func (p *Parser) atBlockEnd() bool {
+	return (p.at().Type == CLOSE_BRACE && len(p.comments) == 0) || p.isEOF()
+}
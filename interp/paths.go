@@ -0,0 +1,49 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Paths in `use` and in the file natives may start with ~ for the home
+// directory and name environment variables as $NAME or ${NAME} (written
+// "${{NAME}}" in a string literal, where braces interpolate), so a script
+// shared between machines finds its files on each of them. Expansion is off
+// unless the module asks for it with `#! expand-paths` or the program runs
+// with --expand-paths (the ExpandPaths option), since it changes the meaning
+// of paths containing $.
+
+// expandPath resolves a leading ~ or ~/ and the environment variables of
+// path, an unset variable is an error rather than an empty string
+func expandPath(path string) (string, error) {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("cannot expand ~: %v", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	var missing []string
+	path = os.Expand(path, func(name string) string {
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			missing = append(missing, "$"+name)
+		}
+		return value
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variable %s is not set", strings.Join(missing, ", "))
+	}
+	return path, nil
+}
+
+// resolvePath expands path when its module or the command line asked for it
+func resolvePath(path string, env *Environment) (string, error) {
+	if !env.options.ExpandPaths && !env.Pragmas().ExpandPaths {
+		return path, nil
+	}
+	return expandPath(path)
+}
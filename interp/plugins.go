@@ -0,0 +1,70 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Go code can add its own native modules. Registering a module from init(),
+// in this package or in a program importing it, makes it a global of every
+// environment:
+//
+//	func init() {
+//		interp.RegisterModule("db", map[string]interp.NativeFunctionCall{
+//			"query": func(args []interp.RuntimeValue, env *interp.Environment) (interp.RuntimeValue, error) { ... },
+//		})
+//	}
+//
+// A host holding a Luna instance can add a module to that one environment
+// with luna.RegisterModule instead, other environments never see it.
+// Registered modules are native modules like io or http: --disable and
+// DisableModule turn them off. examples/embed/plugins does both.
+
+// registeredModules are the modules added with RegisterModule, in order
+var registeredModules []registeredModule
+
+type registeredModule struct {
+	name      string
+	functions map[string]NativeFunctionCall
+}
+
+// RegisterModule adds a native module to every environment set up afterwards
+func RegisterModule(name string, functions map[string]NativeFunctionCall) {
+	registeredModules = append(registeredModules, registeredModule{name: name, functions: functions})
+	if !slices.Contains(nativeModules, name) {
+		nativeModules = append(nativeModules, name)
+	}
+}
+
+// RegisterModule adds a native module to the environment of this interpreter
+func (l *Luna) RegisterModule(name string, functions map[string]NativeFunctionCall) error {
+	known := slices.Contains(l.env.moduleNames(), name)
+	if l.env.HasVar(name) && !known {
+		return fmt.Errorf("cannot register module '%s', the name is taken", name)
+	}
+	if !known {
+		root := l.env.root()
+		root.modules = append(root.modules, name)
+	}
+	l.env.DeclareVar(name, moduleObject(name, functions), true)
+	return nil
+}
+
+// moduleObject builds the object of a registered module, with the
+// descriptions given to RegisterDoc
+func moduleObject(name string, functions map[string]NativeFunctionCall) RuntimeValue {
+	properties := make(map[string]RuntimeValue, len(functions))
+	for fnName, call := range functions {
+		fn := MakeNativeFunction(fnName, call)
+		fn.(*NativeFunctionValue).Doc = nativeDocs[name+"."+fnName]
+		properties[fnName] = fn
+	}
+	return MakeObject(properties)
+}
+
+// declareRegisteredModules adds the modules registered from Go to env
+func declareRegisteredModules(env *Environment) {
+	for _, module := range registeredModules {
+		env.DeclareVar(module.name, moduleObject(module.name, module.functions), true)
+	}
+}
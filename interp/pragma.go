@@ -0,0 +1,98 @@
+package interp
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Pragmas adjust the interpreter for a single module. They are written as
+// `#! name` comments before the first line of code:
+//
+//	#! strict
+//	#! no-color
+//	#! expand-paths
+//
+// `use "strict"` among those lines is the strict pragma too, and --strict
+// makes every module strict. In a strict module only `x: var = ...` and
+// `x: const = ...` declare, assigning to a name that was never declared is
+// an error instead of quietly making a new variable, so a typo shows up.
+type Pragmas struct {
+	Strict      bool // undeclared variables and constant reassignment are errors
+	NoColor     bool // values are printed without ANSI colors
+	ExpandPaths bool // ~ and $VARS are expanded in paths, see paths.go
+}
+
+// strictDirective matches the line `use "strict"`
+var strictDirective = regexp.MustCompile(`^use\s+("strict"|'strict')\s*;?$`)
+
+// Strict reports whether the module of this scope is strict, by pragma or
+// by the Strict option (--strict)
+func (env *Environment) Strict() bool {
+	return env.options.Strict || env.Pragmas().Strict
+}
+
+// parsePragmas reads the pragmas from the leading comments of code, it
+// stops at the first line that is not blank or a comment and returns nil
+// when there are none
+func parsePragmas(code string) (*Pragmas, error) {
+	pragmas := &Pragmas{}
+	found := false
+
+	for i, line := range strings.Split(code, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if strictDirective.MatchString(line) {
+			pragmas.Strict = true
+			found = true
+			continue
+		}
+		if !strings.HasPrefix(line, "#") {
+			break
+		}
+		if !strings.HasPrefix(line, "#!") {
+			continue
+		}
+
+		name := strings.TrimSpace(line[2:])
+		// A shebang on the first line is not a pragma
+		if i == 0 && strings.HasPrefix(name, "/") {
+			continue
+		}
+
+		found = true
+		switch {
+		case name == "strict":
+			pragmas.Strict = true
+		case name == "no-color":
+			pragmas.NoColor = true
+		case name == "expand-paths":
+			pragmas.ExpandPaths = true
+		default:
+			return nil, fmt.Errorf("unknown pragma '%s' at line %d", name, i+1)
+		}
+	}
+
+	if !found {
+		return nil, nil
+	}
+	return pragmas, nil
+}
+
+var ansiEscape = regexp.MustCompile("\033\\[[0-9;]*m")
+
+// stripColor removes ANSI color codes from text
+func stripColor(text string) string {
+	return ansiEscape.ReplaceAllString(text, "")
+}
+
+// displayValue colorizes value for output, unless the module or NO_COLOR asked for no colors
+func displayValue(value RuntimeValue, env *Environment, noString bool) string {
+	output := colorizeValue(value, false, noString)
+	if !colorsEnabled(env) {
+		return stripColor(output)
+	}
+	return output
+}
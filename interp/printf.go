@@ -0,0 +1,237 @@
+package interp
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode/utf8"
+)
+
+// Format specs are printf directives without the %: flags, a width, a
+// precision and a verb, each optional. They follow a colon in an
+// interpolation or a % in format():
+//
+//	"{price:.2f}"                3.14
+//	"{n:5d}" "{n:-5d}" "{n:05d}" right and left aligned, zero padded
+//	"{code:x}" "{code:#X}"       ff, 0XFF, also o and b
+//	"{name:10s}" "{name:.3s}"    padded and truncated text
+//	format("%s: %6.1f%%", k, v)  the same directives, %% is a percent sign
+//
+// d, x, X, o and b take integers (whole floats and bigints too), f, e, E, g
+// and G any number, s and v any value as it reads in a string. Without a
+// verb a precision means f for numbers and s otherwise. A colon inside
+// brackets or after a ternary ? is part of the expression, so "{c ? a : b}"
+// interpolates the whole conditional.
+
+// formatSpec is a parsed directive
+type formatSpec struct {
+	flags     string
+	width     int // -1 when not given
+	precision int // -1 when not given
+	verb      rune
+}
+
+// given reports whether the spec asks for anything
+func (spec formatSpec) given() bool {
+	return spec.flags != "" || spec.width >= 0 || spec.precision >= 0 || spec.verb != 0
+}
+
+// formatVerbs are the verbs a directive may end with
+const formatVerbs = "dxXobfeEgGsv"
+
+// parseFormatSpec reads the directive in text, which must be all of it
+func parseFormatSpec(text string) (formatSpec, error) {
+	spec, size := scanFormatSpec(text)
+	if size != len(text) {
+		return spec, fmt.Errorf("invalid format spec '%s', expected flags, width, .precision and one of %s", text, formatVerbs)
+	}
+	return spec, nil
+}
+
+// scanFormatSpec reads a directive at the start of text, returning how many
+// bytes it took, 0 when text does not start with one
+func scanFormatSpec(text string) (formatSpec, int) {
+	spec := formatSpec{width: -1, precision: -1}
+	i := 0
+	for i < len(text) && strings.IndexByte("-+ 0#", text[i]) >= 0 {
+		i++
+	}
+	spec.flags = text[:i]
+	if n := scanDigits(text[i:]); n > 0 {
+		fmt.Sscan(text[i:i+n], &spec.width)
+		i += n
+	}
+	if i < len(text) && text[i] == '.' {
+		n := scanDigits(text[i+1:])
+		if n == 0 {
+			return spec, 0
+		}
+		fmt.Sscan(text[i+1:i+1+n], &spec.precision)
+		i += 1 + n
+	}
+	if i < len(text) && strings.IndexByte(formatVerbs, text[i]) >= 0 {
+		spec.verb = rune(text[i])
+		i++
+	}
+	if i == 0 {
+		return spec, 0
+	}
+	return spec, i
+}
+
+func scanDigits(text string) int {
+	n := 0
+	for n < len(text) && text[n] >= '0' && text[n] <= '9' {
+		n++
+	}
+	return n
+}
+
+// directive is the spec as a Go format string with the given verb
+func (spec formatSpec) directive(verb rune) string {
+	var directive strings.Builder
+	directive.WriteString("%" + spec.flags)
+	if spec.width >= 0 {
+		fmt.Fprint(&directive, spec.width)
+	}
+	if spec.precision >= 0 {
+		fmt.Fprintf(&directive, ".%d", spec.precision)
+	}
+	directive.WriteRune(verb)
+	return directive.String()
+}
+
+// formatValue writes value as spec says
+func formatValue(value RuntimeValue, spec formatSpec) (string, error) {
+	verb := spec.verb
+	if verb == 0 || verb == 'v' {
+		verb = 's'
+		if _, ok := toFloat(value); ok && spec.precision >= 0 {
+			verb = 'f'
+		}
+	}
+
+	switch verb {
+	case 'd', 'x', 'X', 'o', 'b':
+		integer, ok := integerOf(value)
+		if !ok {
+			return "", fmt.Errorf("format '%c' expects an integer, got %s", verb, formatOperand(value))
+		}
+		return fmt.Sprintf(spec.directive(verb), integer), nil
+	case 'f', 'e', 'E', 'g', 'G':
+		number, ok := toFloat(value)
+		if !ok {
+			return "", fmt.Errorf("format '%c' expects a number, got %s", verb, value.Type())
+		}
+		return fmt.Sprintf(spec.directive(verb), number), nil
+	}
+	return fmt.Sprintf(spec.directive('s'), interpolatedText(value)), nil
+}
+
+// integerOf reads an int, a bigint or a whole float for the integer verbs
+func integerOf(value RuntimeValue) (*big.Int, bool) {
+	if integer, ok := toBig(value); ok {
+		return integer, true
+	}
+	if number, ok := value.(*NumberValue); ok && isInteger(number.Value) {
+		integer, _ := big.NewFloat(number.Value).Int(nil)
+		return integer, true
+	}
+	return nil, false
+}
+
+// formatOperand names a value in an error, numbers by value since the
+// type alone does not say why 1.5 is not an integer
+func formatOperand(value RuntimeValue) string {
+	if _, ok := toFloat(value); ok {
+		return value.String()
+	}
+	return string(value.Type())
+}
+
+// splitFormatSpec separates the spec from the expression of an
+// interpolation, the spec being after the last colon outside brackets and
+// strings when the expression has no ternary ? and the rest reads as one
+func splitFormatSpec(code string) (string, string) {
+	depth, colon := 0, -1
+	var quote rune
+	for i, char := range code {
+		switch {
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case strings.ContainsRune("([{", char):
+			depth++
+		case strings.ContainsRune(")]}", char):
+			depth--
+		case depth > 0:
+		case char == '?':
+			next, _ := utf8.DecodeRuneInString(code[i+1:])
+			if next != '.' && next != '?' && (i == 0 || code[i-1] != '?') {
+				return code, ""
+			}
+		case char == ':':
+			colon = i
+		}
+	}
+	if colon < 0 {
+		return code, ""
+	}
+	spec := code[colon+1:]
+	if spec == "" || strings.ContainsAny(spec, " \t") {
+		return code, ""
+	}
+	return code[:colon], spec
+}
+
+// formatNative is format(template, values...), printf with Luna's directives
+func formatNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 {
+		return nil, fmt.Errorf("format requires a template string")
+	}
+	template, ok := args[0].(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("format expects a template string, got %s", args[0].Type())
+	}
+
+	var result strings.Builder
+	values := args[1:]
+	next := 0
+	text := template.Value
+	for {
+		percent := strings.IndexByte(text, '%')
+		if percent < 0 {
+			result.WriteString(text)
+			break
+		}
+		result.WriteString(text[:percent])
+		text = text[percent+1:]
+		if strings.HasPrefix(text, "%") {
+			result.WriteByte('%')
+			text = text[1:]
+			continue
+		}
+
+		spec, size := scanFormatSpec(text)
+		if spec.verb == 0 {
+			return nil, fmt.Errorf("format: invalid directive '%%%s', expected a verb from %s or %%%%", text[:size], formatVerbs)
+		}
+		if next == len(values) {
+			return nil, fmt.Errorf("format: missing value for directive %d '%%%s'", next+1, text[:size])
+		}
+		formatted, err := formatValue(values[next], spec)
+		if err != nil {
+			return nil, err
+		}
+		result.WriteString(formatted)
+		next++
+		text = text[size:]
+	}
+	if next < len(values) {
+		return nil, fmt.Errorf("format: %d values for %d directives", len(values), next)
+	}
+	return env.counted(MakeString(result.String()))
+}
@@ -0,0 +1,318 @@
+package interp
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// --profile times every call of a Luna function or native while the program
+// runs and prints, once it ends, how often each was called, the time spent
+// in it (total) and in it minus its callees (self), the most expensive first:
+//
+//	profile: 12.4ms
+//	     calls        total         self  function
+//	       177       9.81ms       8.02ms  fib
+//	         1      12.37ms       2.31ms  main
+//	         3        204µs        204µs  print [native]
+//
+// The total of a recursive function counts the outermost calls only.
+// --profile=cpu.pprof writes the calls in the pprof format instead, for
+// `go tool pprof -top cpu.pprof` and the other pprof tools. Tasks running in
+// parallel are timed as if they were called from wherever the calls nest.
+
+// profileEntry is what is known of one function
+type profileEntry struct {
+	name   string
+	calls  int64
+	total  time.Duration
+	self   time.Duration
+	active int // calls on the stack, the total only counts the outermost
+}
+
+// profileFrame is a call being timed
+type profileFrame struct {
+	entry    *profileEntry
+	start    time.Time
+	children time.Duration // time spent in the calls it made
+	parent   *profileFrame
+}
+
+// profileStack is the time spent in a chain of calls, for pprof
+type profileStack struct {
+	names []string // innermost call first
+	calls int64
+	self  time.Duration
+}
+
+// Profiler collects the calls of a program run with --profile
+type Profiler struct {
+	mu      sync.Mutex
+	start   time.Time
+	entries map[string]*profileEntry
+	stacks  map[string]*profileStack
+	top     *profileFrame
+}
+
+// profiler is set by --profile, nil otherwise
+var profiler *Profiler
+
+// setupProfile reads --profile and --profile=file
+func setupProfile() {
+	if _, found := flagValue("--profile"); found || hasFlag("--profile") {
+		profiler = &Profiler{
+			start:   time.Now(),
+			entries: make(map[string]*profileEntry),
+			stacks:  make(map[string]*profileStack),
+		}
+	}
+}
+
+// enter starts timing a call of the function called name
+func (p *Profiler) enter(name string) *profileFrame {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	entry, exists := p.entries[name]
+	if !exists {
+		entry = &profileEntry{name: name}
+		p.entries[name] = entry
+	}
+	entry.calls++
+	entry.active++
+	frame := &profileFrame{entry: entry, start: time.Now(), parent: p.top}
+	p.top = frame
+	return frame
+}
+
+// leave stops timing the call of frame
+func (p *Profiler) leave(frame *profileFrame) {
+	elapsed := time.Since(frame.start)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entry := frame.entry
+	entry.active--
+	if entry.active == 0 {
+		entry.total += elapsed
+	}
+	self := max(elapsed-frame.children, 0)
+	entry.self += self
+	if frame.parent != nil {
+		frame.parent.children += elapsed
+	}
+
+	var names []string
+	for current := frame; current != nil; current = current.parent {
+		names = append(names, current.entry.name)
+	}
+	key := strings.Join(names, "\x00")
+	stack, exists := p.stacks[key]
+	if !exists {
+		stack = &profileStack{names: names}
+		p.stacks[key] = stack
+	}
+	stack.calls++
+	stack.self += self
+
+	// Calls of parallel tasks may end out of order
+	if p.top == frame {
+		p.top = frame.parent
+	} else {
+		for current := p.top; current != nil; current = current.parent {
+			if current.parent == frame {
+				current.parent = frame.parent
+				break
+			}
+		}
+	}
+}
+
+// profileName is how a function is listed in the profile
+func profileName(fn RuntimeValue) string {
+	switch f := fn.(type) {
+	case *FunctionValue:
+		if f.IsAnonymous() {
+			return "lambda"
+		}
+		return f.Name
+	case *NativeFunctionValue:
+		return f.Name + " [native]"
+	}
+	return string(fn.Type())
+}
+
+// callNative calls a native function, timing it under --profile
+func callNative(fn *NativeFunctionValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if profiler != nil {
+		frame := profiler.enter(profileName(fn))
+		defer profiler.leave(frame)
+	}
+	return fn.Call(args, env)
+}
+
+// reportProfile prints the profile, or writes it to the file --profile names
+func reportProfile() {
+	if profiler == nil {
+		return
+	}
+	if file, found := flagValue("--profile"); found && file != "" {
+		if err := os.WriteFile(file, profiler.pprof(), 0644); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Could not write profile '%s': %v\n", file, err)
+		}
+		return
+	}
+	fmt.Fprint(os.Stderr, profiler.report())
+}
+
+// report is the table of functions, the most self time first
+func (p *Profiler) report() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	entries := make([]*profileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].self != entries[j].self {
+			return entries[i].self > entries[j].self
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "%s %s\n", bold("profile:"), time.Since(p.start).Round(time.Microsecond))
+	fmt.Fprintf(&out, gray("%10s %12s %12s  %s")+"\n", "calls", "total", "self", "function")
+	for _, entry := range entries {
+		fmt.Fprintf(&out, "%10d %12s %12s  %s\n", entry.calls,
+			entry.total.Round(time.Microsecond), entry.self.Round(time.Microsecond), entry.name)
+	}
+	return out.String()
+}
+
+// pprof encodes the profile as a gzipped profile.proto, with a sample per
+// chain of calls valued by its calls and its self time in nanoseconds
+func (p *Profiler) pprof() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	table := []string{""}
+	stringIndex := map[string]int64{"": 0}
+	index := func(s string) int64 {
+		if i, exists := stringIndex[s]; exists {
+			return i
+		}
+		stringIndex[s] = int64(len(table))
+		table = append(table, s)
+		return stringIndex[s]
+	}
+	valueType := func(kind, unit string) []byte {
+		var message protoBuffer
+		message.varint(1, uint64(index(kind)))
+		message.varint(2, uint64(index(unit)))
+		return message.bytes()
+	}
+
+	var profile protoBuffer
+	profile.message(1, valueType("calls", "count"))
+	profile.message(1, valueType("time", "nanoseconds"))
+
+	// A function and a location of the same id for every name
+	ids := make(map[string]uint64)
+	names := make([]string, 0, len(p.entries))
+	for name := range p.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		ids[name] = uint64(i + 1)
+	}
+
+	keys := make([]string, 0, len(p.stacks))
+	for key := range p.stacks {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		stack := p.stacks[key]
+		var locations, values []uint64
+		for _, name := range stack.names {
+			locations = append(locations, ids[name])
+		}
+		values = append(values, uint64(stack.calls), uint64(stack.self.Nanoseconds()))
+		var sample protoBuffer
+		sample.packed(1, locations)
+		sample.packed(2, values)
+		profile.message(2, sample.bytes())
+	}
+
+	for _, name := range names {
+		var line, location, function protoBuffer
+		line.varint(1, ids[name])
+		location.varint(1, ids[name])
+		location.message(4, line.bytes())
+		profile.message(4, location.bytes())
+
+		function.varint(1, ids[name])
+		function.varint(2, uint64(index(name)))
+		function.varint(3, uint64(index(name)))
+		profile.message(5, function.bytes())
+	}
+
+	period := valueType("time", "nanoseconds")
+	for _, s := range table {
+		profile.message(6, []byte(s))
+	}
+	profile.varint(9, uint64(p.start.UnixNano()))
+	profile.varint(10, uint64(time.Since(p.start).Nanoseconds()))
+	profile.message(11, period)
+	profile.varint(12, 1)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write(profile.bytes())
+	writer.Close()
+	return compressed.Bytes()
+}
+
+// protoBuffer writes the few protobuf encodings profile.proto needs
+type protoBuffer struct {
+	data []byte
+}
+
+func (b *protoBuffer) bytes() []byte { return b.data }
+
+func (b *protoBuffer) raw(value uint64) {
+	for value >= 0x80 {
+		b.data = append(b.data, byte(value)|0x80)
+		value >>= 7
+	}
+	b.data = append(b.data, byte(value))
+}
+
+// varint writes an integer field
+func (b *protoBuffer) varint(field int, value uint64) {
+	b.raw(uint64(field) << 3)
+	b.raw(value)
+}
+
+// message writes a length-delimited field, a nested message or a string
+func (b *protoBuffer) message(field int, data []byte) {
+	b.raw(uint64(field)<<3 | 2)
+	b.raw(uint64(len(data)))
+	b.data = append(b.data, data...)
+}
+
+// packed writes a repeated integer field
+func (b *protoBuffer) packed(field int, values []uint64) {
+	var packed protoBuffer
+	for _, value := range values {
+		packed.raw(value)
+	}
+	b.message(field, packed.bytes())
+}
@@ -0,0 +1,499 @@
+package interp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Protobuf wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// Field types from descriptor.proto (FieldDescriptorProto.Type)
+const (
+	protoDouble   = 1
+	protoFloat    = 2
+	protoInt64    = 3
+	protoUint64   = 4
+	protoInt32    = 5
+	protoFixed64  = 6
+	protoFixed32  = 7
+	protoBool     = 8
+	protoString   = 9
+	protoMessage  = 11
+	protoBytes    = 12
+	protoUint32   = 13
+	protoEnum     = 14
+	protoSfixed32 = 15
+	protoSfixed64 = 16
+	protoSint32   = 17
+	protoSint64   = 18
+
+	protoLabelRepeated = 3
+)
+
+type protoField struct {
+	Name     string
+	Number   int
+	Label    int
+	Type     int
+	TypeName string // fully qualified, without the leading dot
+}
+
+type protoMessageType struct {
+	Name   string
+	Fields []*protoField
+}
+
+func (m *protoMessageType) field(number int) *protoField {
+	for _, field := range m.Fields {
+		if field.Number == number {
+			return field
+		}
+	}
+	return nil
+}
+
+// ProtoRegistry holds the message types loaded from descriptor sets
+type ProtoRegistry struct {
+	messages map[string]*protoMessageType
+}
+
+func NewProtoRegistry() *ProtoRegistry {
+	return &ProtoRegistry{messages: make(map[string]*protoMessageType)}
+}
+
+// protoRecord is one decoded key/value pair of the wire format
+type protoRecord struct {
+	number   int
+	wireType int
+	varint   uint64
+	data     []byte
+}
+
+// readProtoRecords splits an encoded message into its records
+func readProtoRecords(data []byte) ([]protoRecord, error) {
+	var records []protoRecord
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+
+		record := protoRecord{number: int(key >> 3), wireType: int(key & 7)}
+		switch record.wireType {
+		case wireVarint:
+			value, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid varint in field %d", record.number)
+			}
+			record.varint = value
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return nil, errUnexpectedEnd
+			}
+			record.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return nil, errUnexpectedEnd
+			}
+			record.varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, errUnexpectedEnd
+			}
+			record.data = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return nil, fmt.Errorf("unsupported wire type %d in field %d", record.wireType, record.number)
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// Load reads a FileDescriptorSet (protoc --descriptor_set_out) and returns the new message names
+func (r *ProtoRegistry) Load(filename string) ([]string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := readProtoRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid descriptor set: %v", err)
+	}
+
+	var loaded []string
+	for _, file := range files {
+		if file.number != 1 || file.wireType != wireBytes {
+			continue
+		}
+		records, err := readProtoRecords(file.data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file descriptor: %v", err)
+		}
+
+		pkg := ""
+		for _, record := range records {
+			if record.number == 2 {
+				pkg = string(record.data)
+			}
+		}
+		for _, record := range records {
+			if record.number == 4 {
+				names, err := r.loadMessage(pkg, record.data)
+				if err != nil {
+					return nil, err
+				}
+				loaded = append(loaded, names...)
+			}
+		}
+	}
+
+	sort.Strings(loaded)
+	return loaded, nil
+}
+
+// loadMessage registers a DescriptorProto and its nested types
+func (r *ProtoRegistry) loadMessage(scope string, data []byte) ([]string, error) {
+	records, err := readProtoRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid message descriptor: %v", err)
+	}
+
+	message := &protoMessageType{}
+	for _, record := range records {
+		if record.number == 1 {
+			message.Name = string(record.data)
+		}
+	}
+	if scope != "" {
+		message.Name = scope + "." + message.Name
+	}
+
+	loaded := []string{message.Name}
+	for _, record := range records {
+		switch record.number {
+		case 2:
+			field, err := parseProtoField(record.data)
+			if err != nil {
+				return nil, err
+			}
+			message.Fields = append(message.Fields, field)
+		case 3:
+			names, err := r.loadMessage(message.Name, record.data)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, names...)
+		}
+	}
+
+	r.messages[message.Name] = message
+	return loaded, nil
+}
+
+func parseProtoField(data []byte) (*protoField, error) {
+	records, err := readProtoRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field descriptor: %v", err)
+	}
+
+	field := &protoField{}
+	for _, record := range records {
+		switch record.number {
+		case 1:
+			field.Name = string(record.data)
+		case 3:
+			field.Number = int(record.varint)
+		case 4:
+			field.Label = int(record.varint)
+		case 5:
+			field.Type = int(record.varint)
+		case 6:
+			field.TypeName = strings.TrimPrefix(string(record.data), ".")
+		}
+	}
+	return field, nil
+}
+
+func (r *ProtoRegistry) message(name string) (*protoMessageType, error) {
+	message, exists := r.messages[strings.TrimPrefix(name, ".")]
+	if !exists {
+		return nil, fmt.Errorf("unknown message type '%s' (did you proto.load its descriptor?)", name)
+	}
+	return message, nil
+}
+
+// Decode turns an encoded message into a Luna object keyed by field name
+func (r *ProtoRegistry) Decode(typeName string, data []byte) (RuntimeValue, error) {
+	message, err := r.message(typeName)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := readProtoRecords(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", message.Name, err)
+	}
+
+	properties := make(map[string]RuntimeValue)
+	for _, record := range records {
+		field := message.field(record.number)
+		if field == nil {
+			continue // Unknown fields are skipped
+		}
+
+		var values []RuntimeValue
+		if record.wireType == wireBytes && isPackable(field.Type) {
+			if values, err = decodePacked(field, record.data); err != nil {
+				return nil, err
+			}
+		} else {
+			value, err := r.decodeScalar(field, record)
+			if err != nil {
+				return nil, err
+			}
+			values = []RuntimeValue{value}
+		}
+
+		if field.Label != protoLabelRepeated {
+			properties[field.Name] = values[len(values)-1]
+			continue
+		}
+		existing, ok := properties[field.Name].(*ArrayValue)
+		if !ok {
+			existing = MakeArray([]RuntimeValue{}).(*ArrayValue)
+			properties[field.Name] = existing
+		}
+		existing.Elements = append(existing.Elements, values...)
+	}
+
+	return MakeObject(properties), nil
+}
+
+func isPackable(fieldType int) bool {
+	return fieldType != protoString && fieldType != protoBytes && fieldType != protoMessage
+}
+
+func decodePacked(field *protoField, data []byte) ([]RuntimeValue, error) {
+	var values []RuntimeValue
+	for len(data) > 0 {
+		record := protoRecord{number: field.Number}
+		switch field.Type {
+		case protoDouble, protoFixed64, protoSfixed64:
+			if len(data) < 8 {
+				return nil, errUnexpectedEnd
+			}
+			record.varint = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protoFloat, protoFixed32, protoSfixed32:
+			if len(data) < 4 {
+				return nil, errUnexpectedEnd
+			}
+			record.varint = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		default:
+			value, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, fmt.Errorf("invalid packed varint in field %s", field.Name)
+			}
+			record.varint = value
+			data = data[n:]
+		}
+		values = append(values, scalarNumber(field.Type, record.varint))
+	}
+
+	if field.Type == protoBool {
+		for i, value := range values {
+			values[i] = MakeBool(value.IsTruthy())
+		}
+	}
+	return values, nil
+}
+
+func (r *ProtoRegistry) decodeScalar(field *protoField, record protoRecord) (RuntimeValue, error) {
+	switch field.Type {
+	case protoString, protoBytes:
+		return MakeString(string(record.data)), nil
+	case protoMessage:
+		return r.Decode(field.TypeName, record.data)
+	case protoBool:
+		return MakeBool(record.varint != 0), nil
+	default:
+		return scalarNumber(field.Type, record.varint), nil
+	}
+}
+
+// scalarNumber interprets raw wire bits according to the field type, integer types as ints
+func scalarNumber(fieldType int, bits uint64) RuntimeValue {
+	switch fieldType {
+	case protoDouble:
+		return MakeNumber(math.Float64frombits(bits))
+	case protoFloat:
+		return MakeNumber(float64(math.Float32frombits(uint32(bits))))
+	case protoInt32, protoEnum:
+		return MakeInt(int64(int32(bits)))
+	case protoSfixed32:
+		return MakeInt(int64(int32(uint32(bits))))
+	case protoInt64, protoSfixed64:
+		return MakeInt(int64(bits))
+	case protoSint32, protoSint64:
+		return MakeInt(int64(bits>>1) ^ -int64(bits&1))
+	default:
+		return unsignedNumber(bits)
+	}
+}
+
+// Encode turns a Luna object into an encoded message of the given type
+func (r *ProtoRegistry) Encode(typeName string, value RuntimeValue) ([]byte, error) {
+	message, err := r.message(typeName)
+	if err != nil {
+		return nil, err
+	}
+	object, ok := value.(*ObjectValue)
+	if !ok {
+		return nil, fmt.Errorf("%s: expected an object, got %s", message.Name, value.Type())
+	}
+
+	fields := append([]*protoField{}, message.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Number < fields[j].Number })
+
+	var buf []byte
+	for _, field := range fields {
+		property, exists := object.Properties[field.Name]
+		if !exists || property.Type() == NULL_TYPE || property.Type() == UNDEF_TYPE {
+			continue
+		}
+
+		values := []RuntimeValue{property}
+		if field.Label == protoLabelRepeated {
+			array, ok := property.(*ArrayValue)
+			if !ok {
+				return nil, fmt.Errorf("%s.%s: expected an array, got %s", message.Name, field.Name, property.Type())
+			}
+			values = array.Elements
+		}
+
+		for _, value := range values {
+			if buf, err = r.appendField(buf, field, value); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", message.Name, field.Name, err)
+			}
+		}
+	}
+	return buf, nil
+}
+
+func (r *ProtoRegistry) appendField(buf []byte, field *protoField, value RuntimeValue) ([]byte, error) {
+	key := func(wireType int) []byte {
+		return binary.AppendUvarint(buf, uint64(field.Number)<<3|uint64(wireType))
+	}
+
+	switch field.Type {
+	case protoString, protoBytes:
+		str, ok := value.(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("expected a string, got %s", value.Type())
+		}
+		buf = binary.AppendUvarint(key(wireBytes), uint64(len(str.Value)))
+		return append(buf, str.Value...), nil
+	case protoMessage:
+		data, err := r.Encode(field.TypeName, value)
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.AppendUvarint(key(wireBytes), uint64(len(data)))
+		return append(buf, data...), nil
+	case protoBool:
+		if value.IsTruthy() {
+			return binary.AppendUvarint(key(wireVarint), 1), nil
+		}
+		return binary.AppendUvarint(key(wireVarint), 0), nil
+	}
+
+	num, ok := toFloat(value)
+	if !ok {
+		return nil, fmt.Errorf("expected a number, got %s", value.Type())
+	}
+	n := int64(num)
+	if i, ok := value.(*IntValue); ok {
+		n = i.Value // exact beyond the 53 bits of a float
+	}
+
+	switch field.Type {
+	case protoDouble:
+		return binary.LittleEndian.AppendUint64(key(wireFixed64), math.Float64bits(num)), nil
+	case protoFloat:
+		return binary.LittleEndian.AppendUint32(key(wireFixed32), math.Float32bits(float32(num))), nil
+	case protoFixed64, protoSfixed64:
+		return binary.LittleEndian.AppendUint64(key(wireFixed64), uint64(n)), nil
+	case protoFixed32, protoSfixed32:
+		return binary.LittleEndian.AppendUint32(key(wireFixed32), uint32(n)), nil
+	case protoSint32, protoSint64:
+		return binary.AppendUvarint(key(wireVarint), uint64(n<<1)^uint64(n>>63)), nil
+	default:
+		return binary.AppendUvarint(key(wireVarint), uint64(n)), nil
+	}
+}
+
+func createProtoObject() RuntimeValue {
+	protoProps := make(map[string]RuntimeValue)
+	registry := NewProtoRegistry()
+
+	protoProps["load"] = MakeNativeFunction("load", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("proto.load expects a descriptor set file name")
+		}
+		filename, err := resolvePath(args[0].(*StringValue).Value, env)
+		if err != nil {
+			return nil, fmt.Errorf("proto.load: %v", err)
+		}
+		names, err := registry.Load(filename)
+		if err != nil {
+			return nil, fmt.Errorf("proto.load: %v", err)
+		}
+		messages := make([]RuntimeValue, len(names))
+		for i, name := range names {
+			messages[i] = MakeString(name)
+		}
+		return MakeArray(messages), nil
+	})
+
+	protoProps["decode"] = MakeNativeFunction("decode", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("proto.decode expects a message type and bytes")
+		}
+		data, err := bytesArgument(args[1])
+		if err != nil {
+			return nil, fmt.Errorf("proto.decode: %v", err)
+		}
+		return registry.Decode(args[0].(*StringValue).Value, data)
+	})
+
+	protoProps["encode"] = MakeNativeFunction("encode", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("proto.encode expects a message type and an object")
+		}
+		data, err := registry.Encode(args[0].(*StringValue).Value, args[1])
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(string(data)), nil
+	})
+
+	return MakeObject(protoProps)
+}
@@ -0,0 +1,664 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ARRAY PROTOTYPE FUNCTIONS ---
+func arrayLength(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	result := MakeInt(int64(len(a.Elements)))
+	return result, nil
+}
+
+func arrayPush(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("array.push requires at least one argument")
+	}
+	a.Elements = append(a.Elements, args...)
+	result := MakeInt(int64(len(a.Elements)))
+	return result, nil
+}
+
+func arrayPop(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(a.Elements) == 0 {
+		return nil, fmt.Errorf("array.pop called on an empty array")
+	}
+	lastIndex := len(a.Elements) - 1
+	poppedElement := a.Elements[lastIndex]
+	a.Elements = a.Elements[:lastIndex]
+	return poppedElement, nil
+}
+
+func arrayJoin(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("array.join requires exactly one argument")
+	}
+	separator, ok := args[0].(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("array.join argument must be a string")
+	}
+	var parts []string
+	for _, elem := range a.Elements {
+		if strElem, ok := elem.(*StringValue); ok {
+			parts = append(parts, strElem.Value)
+		} else {
+			return nil, fmt.Errorf("array.join elements must be strings")
+		}
+	}
+	result := MakeString(strings.Join(parts, separator.Value))
+	return result, nil
+}
+
+//
+// func arrayFilter(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+// 	if len(args) != 1 {
+// 		return nil, fmt.Errorf("array.filter requires exactly one argument")
+// 	}
+// 	filterFunc, ok := args[0].(*FunctionValue)
+// 	if !ok {
+// 		return nil, fmt.Errorf("array.filter argument must be a function")
+// 	}
+//
+// 	filteredElements := []RuntimeValue{}
+// 	for _, elem := range a.Elements {
+// 		result, err := evaluateCallExpression(&CallExpr{
+// 			Caller: &Identifier{Value: filterFunc.Name},
+// 			Args:   []Expression{elem.(Expression)},
+// 		}, env)
+// 		if err != nil {
+// 			return nil, err
+// 		}
+// 		if boolResult, ok := result.(*BooleanValue); ok && boolResult.Value {
+// 			filteredElements = append(filteredElements, elem)
+// 		}
+// 	}
+//
+// 	result := MakeArray(filteredElements)
+// 	return result, nil
+// }
+//
+// func arrayMap(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+// 	if len(args) != 1 {
+// 		return nil, fmt.Errorf("array.map requires exactly one argument")
+// 	}
+// 	mapFunc, ok := args[0].(*FunctionValue)
+// 	if !ok {
+// 		return nil, fmt.Errorf("array.map argument must be a function")
+// 	}
+//
+// 	mappedElements := []RuntimeValue{}
+// 	for _, elem := range a.Elements {
+// 		result, err := evaluateCallExpression(&CallExpr{
+// 			Caller: &Identifier{Value: mapFunc.Name},
+// 			Args:   []Expression{elem.(Expression)},
+// 		}, env)
+// 		if err != nil {
+// 			return nil, err
+// 		}
+// 		mappedElements = append(mappedElements, result)
+// 	}
+//
+// 	result := MakeArray(mappedElements)
+// 	return result, nil
+// }
+//
+// func arrayFind(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+// 	if len(args) != 1 {
+// 		return nil, fmt.Errorf("array.find requires exactly one argument")
+// 	}
+// 	findFunc, ok := args[0].(*FunctionValue)
+// 	if !ok {
+// 		return nil, fmt.Errorf("array.find argument must be a function")
+// 	}
+//
+// 	for _, elem := range a.Elements {
+// 		result, err := evaluateCallExpression(&CallExpr{
+// 			Caller: &Identifier{Value: findFunc.Name},
+// 			Args:   []Expression{elem.(Expression)},
+// 		}, env)
+// 		if err != nil {
+// 			return nil, err
+// 		}
+// 		if boolResult, ok := result.(*BooleanValue); ok && boolResult.Value {
+// 			return elem, nil
+// 		}
+// 	}
+//
+// 	return MakeNull(), nil // Return null if no element matches
+// }
+
+func arrayIncludes(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("array.includes requires exactly one argument")
+	}
+	return MakeBool(elementIndex(a.Elements, args[0]) >= 0), nil
+}
+
+// sameElement compares array elements by type and printed value, so equal arrays match too
+func sameElement(a, b RuntimeValue) bool {
+	return a.Type() == b.Type() && a.String() == b.String()
+}
+
+func elementIndex(elements []RuntimeValue, element RuntimeValue) int {
+	for i, elem := range elements {
+		if sameElement(elem, element) {
+			return i
+		}
+	}
+	return -1
+}
+
+// optionalBound returns args[i] as a slice bound, or nil when it is missing or undef
+func optionalBound(args []RuntimeValue, i int) RuntimeValue {
+	if i >= len(args) || args[i].Type() == UNDEF_TYPE {
+		return nil
+	}
+	return args[i]
+}
+
+func arraySlice(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	start, err := clampSliceBound(optionalBound(args, 0), 0, len(a.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("array.slice: %v", err)
+	}
+	end, err := clampSliceBound(optionalBound(args, 1), len(a.Elements), len(a.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("array.slice: %v", err)
+	}
+	return MakeArray(append([]RuntimeValue{}, a.Elements[start:max(start, end)]...)), nil
+}
+
+// arraySplice removes deleteCount elements at start, inserts the remaining arguments there and returns the removed ones
+func arraySplice(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("array.splice requires at least one argument")
+	}
+	start, err := clampSliceBound(args[0], 0, len(a.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("array.splice: %v", err)
+	}
+	count := len(a.Elements) - start
+	if len(args) > 1 {
+		num, ok := toFloat(args[1])
+		if !ok {
+			return nil, fmt.Errorf("array.splice delete count must be a number")
+		}
+		count = max(0, min(int(num), count))
+	}
+
+	removed := append([]RuntimeValue{}, a.Elements[start:start+count]...)
+	elements := append([]RuntimeValue{}, a.Elements[:start]...)
+	if len(args) > 2 {
+		elements = append(elements, args[2:]...)
+	}
+	a.Elements = append(elements, a.Elements[start+count:]...)
+	return MakeArray(removed), nil
+}
+
+// arrayConcat returns a new array with the arguments appended, arrays are appended element-wise
+func arrayConcat(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	elements := append([]RuntimeValue{}, a.Elements...)
+	for _, arg := range args {
+		if array, ok := arg.(*ArrayValue); ok {
+			elements = append(elements, array.Elements...)
+		} else {
+			elements = append(elements, arg)
+		}
+	}
+	return MakeArray(elements), nil
+}
+
+// arrayReverse reverses the array in place and returns it
+func arrayReverse(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	for i, j := 0, len(a.Elements)-1; i < j; i, j = i+1, j-1 {
+		a.Elements[i], a.Elements[j] = a.Elements[j], a.Elements[i]
+	}
+	return a, nil
+}
+
+func arrayIndexOf(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("array.indexOf requires exactly one argument")
+	}
+	return MakeInt(int64(elementIndex(a.Elements, args[0]))), nil
+}
+
+func arrayShift(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(a.Elements) == 0 {
+		return nil, fmt.Errorf("array.shift called on an empty array")
+	}
+	shifted := a.Elements[0]
+	a.Elements = a.Elements[1:]
+	return shifted, nil
+}
+
+func arrayUnshift(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("array.unshift requires at least one argument")
+	}
+	a.Elements = append(append([]RuntimeValue{}, args...), a.Elements...)
+	return MakeInt(int64(len(a.Elements))), nil
+}
+
+// flatten appends elements to into, opening nested arrays down to depth
+// levels. path holds the arrays being opened, one of them again contains
+// itself and is an error.
+func flatten(into []RuntimeValue, elements []RuntimeValue, depth int, path []RuntimeValue) ([]RuntimeValue, error) {
+	for _, elem := range elements {
+		nested, ok := elem.(*ArrayValue)
+		if !ok || depth == 0 {
+			into = append(into, elem)
+			continue
+		}
+		nestedPath, entered := enterPath(path, nested)
+		if !entered {
+			return nil, fmt.Errorf("array.flat: cannot flatten an array that contains itself")
+		}
+		var err error
+		if into, err = flatten(into, nested.Elements, depth-1, nestedPath); err != nil {
+			return nil, err
+		}
+	}
+	return into, nil
+}
+
+func arrayFlat(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	depth := 1
+	if len(args) > 0 {
+		num, ok := toFloat(args[0])
+		if !ok {
+			return nil, fmt.Errorf("array.flat depth must be a number")
+		}
+		depth = int(num)
+		if math.IsInf(num, 1) {
+			depth = math.MaxInt
+		}
+	}
+	elements, err := flatten([]RuntimeValue{}, a.Elements, depth, []RuntimeValue{a})
+	if err != nil {
+		return nil, err
+	}
+	return MakeArray(elements), nil
+}
+
+// arrayFill sets the elements from start up to end to a value, in place
+func arrayFill(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) == 0 || len(args) > 3 {
+		return nil, fmt.Errorf("array.fill requires one to three arguments")
+	}
+	start, err := clampSliceBound(optionalBound(args, 1), 0, len(a.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("array.fill: %v", err)
+	}
+	end, err := clampSliceBound(optionalBound(args, 2), len(a.Elements), len(a.Elements))
+	if err != nil {
+		return nil, fmt.Errorf("array.fill: %v", err)
+	}
+	for i := start; i < end; i++ {
+		a.Elements[i] = args[0]
+	}
+	return a, nil
+}
+
+// arrayUnique returns a new array keeping the first occurrence of each element
+func arrayUnique(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	elements := []RuntimeValue{}
+	for _, elem := range a.Elements {
+		if elementIndex(elements, elem) < 0 {
+			elements = append(elements, elem)
+		}
+	}
+	return MakeArray(elements), nil
+}
+
+// STRING PROTOTYPE FUNCTIONS ---
+
+// stringLength counts characters, not bytes
+func stringLength(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	result := MakeInt(int64(utf8.RuneCountInString(s.Value)))
+	return result, nil
+}
+
+func stringByteLength(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeInt(int64(len(s.Value))), nil
+}
+
+func stringToUpperCase(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	result := MakeString(strings.ToUpper(s.Value))
+	return result, nil
+}
+
+func stringToLowerCase(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	result := MakeString(strings.ToLower(s.Value))
+	return result, nil
+}
+
+func stringCharAt(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string.charAt requires exactly one argument")
+	}
+	index, ok := toFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("string.charAt argument must be a number")
+	}
+	runes := []rune(s.Value)
+	if index < 0 || int(index) >= len(runes) {
+		return MakeString(""), nil // Return empty string for out of bounds
+	}
+	result := MakeString(string(runes[int(index)]))
+	return result, nil
+}
+
+func stringSubstring(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("string.substring requires one or two arguments")
+	}
+	start, ok := toFloat(args[0])
+	if !ok {
+		return nil, fmt.Errorf("string.substring first argument must be a number")
+	}
+	runes := []rune(s.Value)
+	end := len(runes)
+	if len(args) == 2 {
+		endArg, ok := toFloat(args[1])
+		if !ok {
+			return nil, fmt.Errorf("string.substring second argument must be a number")
+		}
+		end = int(endArg)
+	}
+	if start < 0 || start > float64(len(runes)) || end < 0 || end > len(runes) || int(start) > end {
+		return nil, fmt.Errorf("string.substring indices out of bounds")
+	}
+	result := MakeString(string(runes[int(start):end]))
+	return result, nil
+}
+
+func stringSplit(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("string.split requires exactly one argument")
+	}
+	separator, ok := args[0].(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("string.split argument must be a string")
+	}
+	parts := strings.Split(s.Value, separator.Value)
+	result := MakeArray([]RuntimeValue{})
+	for _, part := range parts {
+		result.(*ArrayValue).Elements = append(result.(*ArrayValue).Elements, MakeString(part))
+	}
+	return result, nil
+}
+
+// stringArg returns args[i] as a string, naming the method in the error
+func stringArg(method string, args []RuntimeValue, i int) (string, error) {
+	if i >= len(args) {
+		return "", fmt.Errorf("string.%s requires at least %d argument(s)", method, i+1)
+	}
+	str, ok := args[i].(*StringValue)
+	if !ok {
+		return "", fmt.Errorf("string.%s argument %d must be a string", method, i+1)
+	}
+	return str.Value, nil
+}
+
+// numberArg returns args[i] as an integer, naming the method in the error
+func numberArg(method string, args []RuntimeValue, i int) (int, error) {
+	if i >= len(args) {
+		return 0, fmt.Errorf("string.%s requires at least %d argument(s)", method, i+1)
+	}
+	num, ok := toFloat(args[i])
+	if !ok {
+		return 0, fmt.Errorf("string.%s argument %d must be a number", method, i+1)
+	}
+	return int(num), nil
+}
+
+func stringTrim(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeString(strings.TrimSpace(s.Value)), nil
+}
+
+func stringTrimStart(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeString(strings.TrimLeftFunc(s.Value, unicode.IsSpace)), nil
+}
+
+func stringTrimEnd(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeString(strings.TrimRightFunc(s.Value, unicode.IsSpace)), nil
+}
+
+func stringReplace(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	old, err := stringArg("replace", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := stringArg("replace", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return MakeString(strings.Replace(s.Value, old, replacement, 1)), nil
+}
+
+func stringReplaceAll(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	old, err := stringArg("replaceAll", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := stringArg("replaceAll", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return MakeString(strings.ReplaceAll(s.Value, old, replacement)), nil
+}
+
+// stringIndexOf returns the index in characters of the first match at or after the optional start
+func stringIndexOf(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	search, err := stringArg("indexOf", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	runes := []rune(s.Value)
+	from := 0
+	if len(args) > 1 {
+		if from, err = numberArg("indexOf", args, 1); err != nil {
+			return nil, err
+		}
+		from = max(0, min(from, len(runes)))
+	}
+
+	index := strings.Index(string(runes[from:]), search)
+	if index < 0 {
+		return MakeInt(-1), nil
+	}
+	return MakeInt(int64(from + utf8.RuneCountInString(string(runes[from:])[:index]))), nil
+}
+
+func stringLastIndexOf(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	search, err := stringArg("lastIndexOf", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	index := strings.LastIndex(s.Value, search)
+	if index < 0 {
+		return MakeInt(-1), nil
+	}
+	return MakeInt(int64(utf8.RuneCountInString(s.Value[:index]))), nil
+}
+
+func stringStartsWith(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	prefix, err := stringArg("startsWith", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return MakeBool(strings.HasPrefix(s.Value, prefix)), nil
+}
+
+func stringEndsWith(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	suffix, err := stringArg("endsWith", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return MakeBool(strings.HasSuffix(s.Value, suffix)), nil
+}
+
+func stringIncludes(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	search, err := stringArg("includes", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	return MakeBool(strings.Contains(s.Value, search)), nil
+}
+
+func stringRepeat(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	count, err := numberArg("repeat", args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		return nil, fmt.Errorf("string.repeat count must not be negative")
+	}
+	return MakeString(strings.Repeat(s.Value, count)), nil
+}
+
+// padding builds the fill needed to bring s to length characters, cycling through pad
+func padding(method string, s string, args []RuntimeValue) (string, error) {
+	length, err := numberArg(method, args, 0)
+	if err != nil {
+		return "", err
+	}
+	pad := " "
+	if len(args) > 1 {
+		if pad, err = stringArg(method, args, 1); err != nil {
+			return "", err
+		}
+	}
+
+	missing := length - utf8.RuneCountInString(s)
+	padRunes := []rune(pad)
+	if missing <= 0 || len(padRunes) == 0 {
+		return "", nil
+	}
+	fill := make([]rune, missing)
+	for i := range fill {
+		fill[i] = padRunes[i%len(padRunes)]
+	}
+	return string(fill), nil
+}
+
+func stringPadStart(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	fill, err := padding("padStart", s.Value, args)
+	if err != nil {
+		return nil, err
+	}
+	return MakeString(fill + s.Value), nil
+}
+
+func stringPadEnd(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	fill, err := padding("padEnd", s.Value, args)
+	if err != nil {
+		return nil, err
+	}
+	return MakeString(s.Value + fill), nil
+}
+
+// stringSlice takes the characters from start up to end, negative bounds count from the end
+func stringSlice(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	runes := []rune(s.Value)
+	var bounds [2]RuntimeValue
+	copy(bounds[:], args)
+
+	start, err := clampSliceBound(bounds[0], 0, len(runes))
+	if err != nil {
+		return nil, fmt.Errorf("string.slice: %v", err)
+	}
+	end, err := clampSliceBound(bounds[1], len(runes), len(runes))
+	if err != nil {
+		return nil, fmt.Errorf("string.slice: %v", err)
+	}
+	return MakeString(string(runes[start:max(start, end)])), nil
+}
+
+// RANGE PROTOTYPE FUNCTIONS ---
+
+func rangeLength(r *RangeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeInt(int64(r.Len())), nil
+}
+
+func rangeToArray(r *RangeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	elements := make([]RuntimeValue, r.Len())
+	for i := range elements {
+		elements[i] = r.Item(i)
+	}
+	return MakeArray(elements), nil
+}
+
+func rangeIncludes(r *RangeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("range.includes requires exactly one argument")
+	}
+	num, ok := toFloat(args[0])
+	if !ok {
+		return MakeBool(false), nil
+	}
+	steps := (num - r.Start) / r.Step
+	i := int(math.Round(steps))
+	return MakeBool(math.Abs(steps-float64(i)) < 1e-9 && i >= 0 && i < r.Len()), nil
+}
+
+var ArrayPrototype = map[string]func(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"length": arrayLength,
+	"push":   arrayPush,
+	"pop":    arrayPop,
+	"join":   arrayJoin,
+	// "filter":   arrayFilter,
+	// "map":      arrayMap,
+	// "find":     arrayFind,
+	"includes": arrayIncludes,
+	"slice":    arraySlice,
+	"splice":   arraySplice,
+	"concat":   arrayConcat,
+	"reverse":  arrayReverse,
+	"indexOf":  arrayIndexOf,
+	"shift":    arrayShift,
+	"unshift":  arrayUnshift,
+	"flat":     arrayFlat,
+	"fill":     arrayFill,
+	"unique":   arrayUnique,
+	"sort":     arraySort,
+}
+
+// map to prototype functions
+var StringPrototype = map[string]func(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"length":      stringLength,
+	"byteLength":  stringByteLength,
+	"toUpperCase": stringToUpperCase,
+	"toLowerCase": stringToLowerCase,
+	"charAt":      stringCharAt,
+	"substring":   stringSubstring,
+	"split":       stringSplit,
+	"trim":        stringTrim,
+	"trimStart":   stringTrimStart,
+	"trimEnd":     stringTrimEnd,
+	"replace":     stringReplace,
+	"replaceAll":  stringReplaceAll,
+	"indexOf":     stringIndexOf,
+	"lastIndexOf": stringLastIndexOf,
+	"startsWith":  stringStartsWith,
+	"endsWith":    stringEndsWith,
+	"includes":    stringIncludes,
+	"repeat":      stringRepeat,
+	"padStart":    stringPadStart,
+	"padEnd":      stringPadEnd,
+	"slice":       stringSlice,
+	"normalize":   stringNormalize,
+	"casefold":    stringCasefold,
+	"compare":     stringCompare,
+}
+
+var RangePrototype = map[string]func(r *RangeValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"length":   rangeLength,
+	"toArray":  rangeToArray,
+	"includes": rangeIncludes,
+}
@@ -0,0 +1,900 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// KeyMode selects the keybindings of the line editor
+type KeyMode int
+
+const (
+	EmacsMode KeyMode = iota
+	ViMode
+)
+
+// editState is a snapshot of the line used by undo/redo
+type editState struct {
+	line   []rune
+	cursor int
+}
+
+const killRingSize = 16
+
+// Simple readline implementation with cursor movement
+type Readline struct {
+	prompt  string
+	line    []rune
+	cursor  int
+	history []string
+	histPos int
+	scanner *bufio.Scanner
+
+	historyFile string // history is appended here when set
+
+	mode     KeyMode
+	viNormal bool // vi command mode (as opposed to insert mode)
+
+	reader     *bufio.Reader
+	killRing   []string
+	yankLength int // length of the last yank, for yank-pop
+	undoStack  []editState
+	redoStack  []editState
+	lastAction string
+
+	// Multi-line block editing: r.line is lines[row] while it is being edited
+	multiline   bool
+	lines       [][]rune
+	row         int
+	renderedRow int  // terminal row of the cursor relative to the first line
+	accepting   bool // hides the suggestion while drawing the accepted line
+
+	highlight bool // colors the input as it is typed
+}
+
+func NewReadline(prompt string) *Readline {
+	r := &Readline{
+		prompt:  prompt,
+		line:    make([]rune, 0),
+		cursor:  0,
+		history: make([]string, 0),
+		histPos: -1,
+		scanner: bufio.NewScanner(os.Stdin),
+		reader:  bufio.NewReader(os.Stdin),
+	}
+
+	// LUNA_KEYMAP=vi switches the editor to vi bindings
+	if strings.EqualFold(os.Getenv("LUNA_KEYMAP"), "vi") {
+		r.mode = ViMode
+	}
+	return r
+}
+
+// defaultHistoryFile returns $LUNA_HISTORY or ~/.luna_history
+func defaultHistoryFile() string {
+	if path := os.Getenv("LUNA_HISTORY"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".luna_history")
+}
+
+// LoadHistory reads previous entries from path and persists new ones to it
+func (r *Readline) LoadHistory(path string) error {
+	r.historyFile = path
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	// Lines of a multi-line entry end with a backslash
+	entry := ""
+	for _, line := range strings.Split(string(data), "\n") {
+		if continued, ok := strings.CutSuffix(line, "\\"); ok {
+			entry += continued + "\n"
+			continue
+		}
+		entry += line
+		if strings.TrimSpace(entry) != "" {
+			r.history = append(r.history, entry)
+		}
+		entry = ""
+	}
+	return nil
+}
+
+// addHistory records an entry, skipping immediate duplicates
+func (r *Readline) addHistory(input string) {
+	if strings.TrimSpace(input) == "" {
+		return
+	}
+	if len(r.history) > 0 && r.history[len(r.history)-1] == input {
+		return
+	}
+	r.history = append(r.history, input)
+
+	if r.historyFile == "" {
+		return
+	}
+	file, err := os.OpenFile(r.historyFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	fmt.Fprintln(file, strings.ReplaceAll(input, "\n", "\\\n"))
+}
+
+// suggestion returns the rest of the most recent history entry starting with the line
+func (r *Readline) suggestion() string {
+	if r.accepting || len(r.lines) > 1 || len(r.line) == 0 || r.cursor != len(r.line) {
+		return ""
+	}
+	prefix := string(r.line)
+	for i := len(r.history) - 1; i >= 0; i-- {
+		if len(r.history[i]) > len(prefix) && strings.HasPrefix(r.history[i], prefix) {
+			return r.history[i][len(prefix):]
+		}
+	}
+	return ""
+}
+
+// acceptSuggestion completes the line with the current suggestion, if any
+func (r *Readline) acceptSuggestion() bool {
+	suggestion := r.suggestion()
+	if suggestion == "" {
+		return false
+	}
+	r.snapshot("")
+	r.line = append(r.line, []rune(suggestion)...)
+	r.cursor = len(r.line)
+	return true
+}
+
+// SetMultiline makes Enter open a new, auto-indented line while brackets are unbalanced
+func (r *Readline) SetMultiline(enabled bool) {
+	r.multiline = enabled
+}
+
+// SetHighlight colors keywords, strings, numbers and the other tokens of the
+// input, and marks the bracket matching the one at the cursor
+func (r *Readline) SetHighlight(enabled bool) {
+	r.highlight = enabled
+}
+
+// SetMode switches between emacs and vi keybindings
+func (r *Readline) SetMode(mode KeyMode) {
+	r.mode = mode
+}
+
+// ReadLine reads one line. With no argument the default prompt is printed,
+// a string argument is used as the prompt instead, and any other value prints none.
+func (r *Readline) ReadLine(printPrompt ...any) (string, error) {
+	prompt := r.prompt
+	if len(printPrompt) > 0 {
+		prompt, _ = printPrompt[0].(string)
+	}
+
+	if isTerminal(int(os.Stdin.Fd())) {
+		if restore, err := enableRawMode(int(os.Stdin.Fd())); err == nil {
+			defer restore()
+			return r.edit(prompt)
+		}
+	}
+
+	fmt.Print(prompt)
+	if r.scanner.Scan() {
+		input := r.scanner.Text()
+		r.addHistory(input)
+		return input, nil
+	}
+
+	if err := r.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// edit runs the interactive line editor until the line is accepted
+func (r *Readline) edit(prompt string) (string, error) {
+	r.setLine("")
+	r.renderedRow = 0
+	r.accepting = false
+	r.histPos = -1
+	r.undoStack = nil
+	r.redoStack = nil
+	r.lastAction = ""
+	r.viNormal = false
+	r.prompt, prompt = prompt, r.prompt
+	defer func() { r.prompt = prompt }()
+
+	r.refresh()
+	for {
+		char, _, err := r.reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+
+		done, err := r.handleKey(char)
+		if err != nil {
+			fmt.Print("\r\n")
+			return "", err
+		}
+		if done {
+			// Redraw with the cursor on the last line and without the suggestion
+			r.moveRow(len(r.lines) - 1)
+			r.cursor = len(r.line)
+			r.accepting = true
+			r.refresh()
+			fmt.Print("\r\n")
+
+			input := r.text()
+			r.addHistory(input)
+			return input, nil
+		}
+		r.refresh()
+	}
+}
+
+// rowPrompt is the prompt shown before the given line of the block
+func (r *Readline) rowPrompt(row int) string {
+	if row == 0 {
+		return r.prompt
+	}
+	return gray("... ")
+}
+
+// refresh redraws the whole block and the autosuggestion, then places the cursor
+func (r *Readline) refresh() {
+	r.lines[r.row] = r.line
+	if r.renderedRow > 0 {
+		fmt.Printf("\033[%dA", r.renderedRow)
+	}
+	fmt.Print("\r\033[J")
+
+	styles := r.styles()
+	for i, line := range r.lines {
+		if i > 0 {
+			fmt.Print("\r\n")
+		}
+		fmt.Print(r.rowPrompt(i) + styledText(line, styles[i]))
+	}
+	if suggestion := r.suggestion(); suggestion != "" {
+		fmt.Print(dim(suggestion))
+	}
+
+	// Reprinting the text before the cursor leaves the cursor in place
+	if up := len(r.lines) - 1 - r.row; up > 0 {
+		fmt.Printf("\033[%dA", up)
+	}
+	fmt.Print("\r" + r.rowPrompt(r.row) + styledText(r.line[:r.cursor], styles[r.row]))
+	r.renderedRow = r.row
+}
+
+// styles is the color of each rune of each line, all plain without highlighting
+func (r *Readline) styles() [][]string {
+	styles := make([][]string, len(r.lines))
+	if !r.highlight {
+		return styles
+	}
+
+	var block []rune
+	at := 0 // the cursor in block
+	for i, line := range r.lines {
+		if i == r.row {
+			at = len(block) + r.cursor
+		}
+		block = append(block, line...)
+		block = append(block, '\n')
+	}
+	flat := highlightStyles(string(block))
+
+	// The bracket at the cursor, or else the one before it, and its match
+	if !r.accepting {
+		for _, i := range []int{at, at - 1} {
+			if match := matchingBracket(block, flat, i); match >= 0 {
+				flat[i] = Bold + Under + flat[i]
+				flat[match] = Bold + Under + flat[match]
+				break
+			}
+		}
+	}
+
+	start := 0
+	for i, line := range r.lines {
+		styles[i] = flat[start : start+len(line)]
+		start += len(line) + 1
+	}
+	return styles
+}
+
+// matchingBracket is the index of the bracket matching the one at i in
+// text, -1 when there is none. Brackets in strings and comments, which have
+// a style, are not counted.
+func matchingBracket(text []rune, styles []string, i int) int {
+	const opening, closing = "([{", ")]}"
+	if i < 0 || i >= len(text) || styles[i] != "" {
+		return -1
+	}
+
+	step := 1
+	open, close := text[i], rune(0)
+	if index := strings.IndexRune(opening, open); index >= 0 {
+		close = rune(closing[index])
+	} else if index := strings.IndexRune(closing, open); index >= 0 {
+		open, close, step = text[i], rune(opening[index]), -1
+	} else {
+		return -1
+	}
+
+	depth := 0
+	for j := i; j >= 0 && j < len(text); j += step {
+		if styles[j] != "" {
+			continue
+		}
+		switch text[j] {
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// styledText is text with each run of runes of the same style colored
+func styledText(text []rune, styles []string) string {
+	var out strings.Builder
+	for i := 0; i < len(text); {
+		style := ""
+		if i < len(styles) {
+			style = styles[i]
+		}
+		j := i + 1
+		for j < len(text) && j < len(styles) && styles[j] == style {
+			j++
+		}
+		if style == "" {
+			out.WriteString(string(text[i:j]))
+		} else {
+			out.WriteString(colorize(string(text[i:j]), style))
+		}
+		i = j
+	}
+	return out.String()
+}
+
+// handleKey applies one key press, reporting whether the line was accepted
+func (r *Readline) handleKey(char rune) (bool, error) {
+	if r.mode == ViMode && r.viNormal {
+		return r.handleViNormal(char)
+	}
+
+	switch char {
+	case '\r', '\n':
+		return r.enter(), nil
+	case 3: // Ctrl+C clears the line
+		r.cancel()
+	case 4: // Ctrl+D
+		if len(r.lines) == 1 && len(r.line) == 0 {
+			return false, io.EOF
+		}
+		r.deleteChar()
+	case 127, 8: // Backspace
+		r.backspace()
+	case 1: // Ctrl+A
+		r.cursor = 0
+	case 5: // Ctrl+E
+		if !r.acceptSuggestion() {
+			r.cursor = len(r.line)
+		}
+	case 2: // Ctrl+B
+		r.MoveCursorLeft()
+	case 6: // Ctrl+F
+		if !r.acceptSuggestion() {
+			r.MoveCursorRight()
+		}
+	case 11: // Ctrl+K
+		r.kill(r.cursor, len(r.line), false)
+	case 21: // Ctrl+U
+		r.kill(0, r.cursor, true)
+	case 23: // Ctrl+W
+		r.kill(r.wordStart(), r.cursor, true)
+	case 25: // Ctrl+Y
+		r.yank()
+	case 31: // Ctrl+_
+		r.undo()
+	case 30: // Ctrl+^
+		r.redo()
+	case 16: // Ctrl+P
+		r.moveUp()
+	case 14: // Ctrl+N
+		r.moveDown()
+	case 27:
+		return false, r.handleEscape()
+	default:
+		if unicode.IsPrint(char) {
+			if strings.ContainsRune("}])", char) {
+				r.dedent()
+			}
+			r.insert(char)
+		}
+	}
+
+	if char != 25 {
+		r.yankLength = 0
+	}
+	return false, nil
+}
+
+// handleEscape decodes escape sequences and Alt+key combinations
+func (r *Readline) handleEscape() error {
+	// A lone Escape enters vi command mode
+	if r.reader.Buffered() == 0 {
+		if r.mode == ViMode {
+			r.viNormal = true
+			r.MoveCursorLeft()
+		}
+		return nil
+	}
+
+	next, _, err := r.reader.ReadRune()
+	if err != nil {
+		return err
+	}
+
+	switch next {
+	case '[', 'O':
+		return r.handleCSI()
+	case 127, 8: // Alt+Backspace
+		r.kill(r.wordStart(), r.cursor, true)
+	case 'd': // Alt+D
+		r.kill(r.cursor, r.wordEnd(), false)
+	case 'b':
+		r.cursor = r.wordStart()
+	case 'f':
+		r.cursor = r.wordEnd()
+	case 'y': // Alt+Y
+		r.yankPop()
+		return nil
+	case '_': // Alt+_
+		r.redo()
+	}
+	r.yankLength = 0
+	return nil
+}
+
+func (r *Readline) handleCSI() error {
+	sequence := ""
+	for {
+		char, _, err := r.reader.ReadRune()
+		if err != nil {
+			return err
+		}
+		sequence += string(char)
+		if char >= '@' && char <= '~' && char != '[' {
+			break
+		}
+	}
+
+	switch sequence {
+	case "A":
+		r.moveUp()
+	case "B":
+		r.moveDown()
+	case "C":
+		if !r.acceptSuggestion() {
+			r.MoveCursorRight()
+		}
+	case "D":
+		r.MoveCursorLeft()
+	case "H", "1~", "7~":
+		r.cursor = 0
+	case "F", "4~", "8~":
+		if !r.acceptSuggestion() {
+			r.cursor = len(r.line)
+		}
+	case "3~":
+		r.deleteChar()
+	}
+	return nil
+}
+
+// handleViNormal implements the vi command mode bindings
+func (r *Readline) handleViNormal(char rune) (bool, error) {
+	switch char {
+	case '\r', '\n':
+		return r.enter(), nil
+	case 3:
+		r.cancel()
+		r.viNormal = false
+	case 4:
+		if len(r.lines) == 1 && len(r.line) == 0 {
+			return false, io.EOF
+		}
+	case 'h':
+		r.MoveCursorLeft()
+	case 'l':
+		if r.cursor < len(r.line)-1 {
+			r.cursor++
+		}
+	case '0', '^':
+		r.cursor = 0
+	case '$':
+		r.cursor = max(0, len(r.line)-1)
+	case 'w':
+		r.cursor = min(r.wordEnd(), max(0, len(r.line)-1))
+	case 'b':
+		r.cursor = r.wordStart()
+	case 'x':
+		r.kill(r.cursor, min(r.cursor+1, len(r.line)), false)
+	case 'X':
+		r.backspace()
+	case 'D':
+		r.kill(r.cursor, len(r.line), false)
+	case 'C':
+		r.kill(r.cursor, len(r.line), false)
+		r.viNormal = false
+	case 'd', 'c':
+		// dd and cc operate on the whole line
+		if next, _, err := r.reader.ReadRune(); err == nil && next == char {
+			r.kill(0, len(r.line), false)
+			r.viNormal = char == 'd'
+		}
+	case 'i':
+		r.viNormal = false
+	case 'a':
+		r.MoveCursorRight()
+		r.viNormal = false
+	case 'A':
+		r.cursor = len(r.line)
+		r.viNormal = false
+	case 'I':
+		r.cursor = 0
+		r.viNormal = false
+	case 'p':
+		r.MoveCursorRight()
+		r.yank()
+	case 'P':
+		r.yank()
+	case 'u':
+		r.undo()
+	case 18: // Ctrl+R
+		r.redo()
+	case 'k':
+		r.moveUp()
+	case 'j':
+		r.moveDown()
+	}
+	return false, nil
+}
+
+// snapshot records the line for undo, merging runs of the same action
+func (r *Readline) snapshot(action string) {
+	if action != "" && action == r.lastAction {
+		return
+	}
+	r.lastAction = action
+	r.undoStack = append(r.undoStack, editState{line: append([]rune{}, r.line...), cursor: r.cursor})
+	r.redoStack = nil
+}
+
+func (r *Readline) undo() {
+	if len(r.undoStack) == 0 {
+		return
+	}
+	r.redoStack = append(r.redoStack, editState{line: append([]rune{}, r.line...), cursor: r.cursor})
+	state := r.undoStack[len(r.undoStack)-1]
+	r.undoStack = r.undoStack[:len(r.undoStack)-1]
+	r.line, r.cursor = state.line, state.cursor
+	r.lastAction = ""
+}
+
+func (r *Readline) redo() {
+	if len(r.redoStack) == 0 {
+		return
+	}
+	r.undoStack = append(r.undoStack, editState{line: append([]rune{}, r.line...), cursor: r.cursor})
+	state := r.redoStack[len(r.redoStack)-1]
+	r.redoStack = r.redoStack[:len(r.redoStack)-1]
+	r.line, r.cursor = state.line, state.cursor
+	r.lastAction = ""
+}
+
+// setLine replaces the whole block, leaving the cursor at its end
+func (r *Readline) setLine(text string) {
+	r.lines = nil
+	for _, line := range strings.Split(text, "\n") {
+		r.lines = append(r.lines, []rune(line))
+	}
+	r.row = len(r.lines) - 1
+	r.line = r.lines[r.row]
+	r.cursor = len(r.line)
+}
+
+// text returns the block with its lines joined by newlines
+func (r *Readline) text() string {
+	r.lines[r.row] = r.line
+	lines := make([]string, len(r.lines))
+	for i, line := range r.lines {
+		lines[i] = string(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cancel abandons the block on Ctrl+C
+func (r *Readline) cancel() {
+	r.moveRow(len(r.lines) - 1)
+	r.cursor = len(r.line)
+	r.refresh()
+	fmt.Print("^C\r\n")
+	r.renderedRow = 0
+	r.setLine("")
+}
+
+// moveRow makes another line of the block the one being edited
+func (r *Readline) moveRow(row int) {
+	if row == r.row {
+		return
+	}
+	r.lines[r.row] = r.line
+	r.row = row
+	r.line = r.lines[row]
+	r.cursor = min(r.cursor, len(r.line))
+	r.undoStack = nil
+	r.redoStack = nil
+	r.lastAction = ""
+}
+
+// moveUp goes to the previous line of the block, or back in history on the first line
+func (r *Readline) moveUp() {
+	if r.row > 0 {
+		r.moveRow(r.row - 1)
+		return
+	}
+	r.historyPrev()
+}
+
+// moveDown goes to the next line of the block, or forward in history on the last line
+func (r *Readline) moveDown() {
+	if r.row < len(r.lines)-1 {
+		r.moveRow(r.row + 1)
+		return
+	}
+	r.historyNext()
+}
+
+// blockIndent is the indentation for code following the given text
+func blockIndent(text string) string {
+	return strings.Repeat(formatIndent, countNesting(text))
+}
+
+// textBefore returns the block up to the cursor
+func (r *Readline) textBefore() string {
+	lines := make([]string, 0, r.row+1)
+	for _, line := range r.lines[:r.row] {
+		lines = append(lines, string(line))
+	}
+	return strings.Join(append(lines, string(r.line[:r.cursor])), "\n")
+}
+
+// enter accepts the block once its brackets balance, otherwise it splits the
+// line at the cursor and indents the new line to its nesting depth
+func (r *Readline) enter() bool {
+	if !r.multiline || isBalanced(r.text()) {
+		return true
+	}
+
+	indent := []rune(blockIndent(r.textBefore()))
+	before := append([]rune{}, r.line[:r.cursor]...)
+	after := []rune(strings.TrimLeft(string(r.line[r.cursor:]), " \t"))
+
+	r.lines[r.row] = before
+	r.lines = append(r.lines[:r.row+1], append([][]rune{append(indent, after...)}, r.lines[r.row+1:]...)...)
+	r.row++
+	r.line = r.lines[r.row]
+	r.cursor = len(indent)
+	r.undoStack = nil
+	r.redoStack = nil
+	r.lastAction = ""
+	return false
+}
+
+// dedent re-indents a line that starts with a closing bracket to the
+// depth of the block it closes
+func (r *Readline) dedent() {
+	if !r.multiline || r.row == 0 || strings.TrimSpace(string(r.line[:r.cursor])) != "" {
+		return
+	}
+
+	previous := make([]string, r.row)
+	for i, line := range r.lines[:r.row] {
+		previous[i] = string(line)
+	}
+	depth := countNesting(strings.Join(previous, "\n")) - 1
+	indent := []rune(strings.Repeat(formatIndent, max(depth, 0)))
+
+	r.snapshot("")
+	r.line = append(indent, r.line[r.cursor:]...)
+	r.cursor = len(indent)
+}
+
+func (r *Readline) insert(char rune) {
+	r.snapshot("insert")
+	r.line = append(r.line[:r.cursor], append([]rune{char}, r.line[r.cursor:]...)...)
+	r.cursor++
+}
+
+func (r *Readline) backspace() {
+	if r.cursor == 0 && r.row > 0 {
+		// Join with the previous line of the block
+		r.lines = append(r.lines[:r.row], r.lines[r.row+1:]...)
+		previous := r.lines[r.row-1]
+		r.row--
+		r.line = append(append([]rune{}, previous...), r.line...)
+		r.cursor = len(previous)
+		r.undoStack = nil
+		r.redoStack = nil
+		return
+	}
+	if r.cursor == 0 {
+		return
+	}
+	r.snapshot("delete")
+	r.line = append(r.line[:r.cursor-1], r.line[r.cursor:]...)
+	r.cursor--
+}
+
+func (r *Readline) deleteChar() {
+	if r.cursor >= len(r.line) && r.row < len(r.lines)-1 {
+		// Join the next line of the block onto this one
+		r.line = append(append([]rune{}, r.line...), r.lines[r.row+1]...)
+		r.lines = append(r.lines[:r.row+1], r.lines[r.row+2:]...)
+		r.undoStack = nil
+		r.redoStack = nil
+		return
+	}
+	if r.cursor >= len(r.line) {
+		return
+	}
+	r.snapshot("delete")
+	r.line = append(r.line[:r.cursor], r.line[r.cursor+1:]...)
+}
+
+// kill removes line[start:end] into the kill ring
+func (r *Readline) kill(start, end int, backward bool) {
+	if start >= end {
+		return
+	}
+	previous := r.lastAction // snapshot resets it
+	r.snapshot("")
+	killed := string(r.line[start:end])
+
+	// Consecutive kills accumulate into a single entry
+	if previous == "kill" && len(r.killRing) > 0 {
+		last := len(r.killRing) - 1
+		if backward {
+			r.killRing[last] = killed + r.killRing[last]
+		} else {
+			r.killRing[last] += killed
+		}
+	} else {
+		r.killRing = append(r.killRing, killed)
+		if len(r.killRing) > killRingSize {
+			r.killRing = r.killRing[1:]
+		}
+	}
+
+	r.line = append(r.line[:start], r.line[end:]...)
+	r.cursor = start
+	r.lastAction = "kill"
+}
+
+func (r *Readline) yank() {
+	if len(r.killRing) == 0 {
+		return
+	}
+	r.snapshot("")
+	text := []rune(r.killRing[len(r.killRing)-1])
+	r.line = append(r.line[:r.cursor], append(text, r.line[r.cursor:]...)...)
+	r.cursor += len(text)
+	r.yankLength = len(text)
+	r.lastAction = "yank"
+}
+
+// yankPop replaces the text just yanked with the previous kill ring entry
+func (r *Readline) yankPop() {
+	if r.yankLength == 0 || len(r.killRing) < 2 {
+		return
+	}
+	start := r.cursor - r.yankLength
+	r.line = append(r.line[:start], r.line[r.cursor:]...)
+	r.cursor = start
+
+	// Rotate the ring so the previous entry is on top
+	last := r.killRing[len(r.killRing)-1]
+	r.killRing = append([]string{last}, r.killRing[:len(r.killRing)-1]...)
+
+	text := []rune(r.killRing[len(r.killRing)-1])
+	r.line = append(r.line[:r.cursor], append(text, r.line[r.cursor:]...)...)
+	r.cursor += len(text)
+	r.yankLength = len(text)
+}
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || char == '_'
+}
+
+// wordStart returns the start of the word before the cursor
+func (r *Readline) wordStart() int {
+	i := r.cursor
+	for i > 0 && !isWordRune(r.line[i-1]) {
+		i--
+	}
+	for i > 0 && isWordRune(r.line[i-1]) {
+		i--
+	}
+	return i
+}
+
+// wordEnd returns the end of the word after the cursor
+func (r *Readline) wordEnd() int {
+	i := r.cursor
+	for i < len(r.line) && !isWordRune(r.line[i]) {
+		i++
+	}
+	for i < len(r.line) && isWordRune(r.line[i]) {
+		i++
+	}
+	return i
+}
+
+func (r *Readline) historyPrev() {
+	if len(r.history) == 0 {
+		return
+	}
+	if r.histPos == -1 {
+		r.histPos = len(r.history)
+	}
+	if r.histPos > 0 {
+		r.histPos--
+		r.setLine(r.history[r.histPos])
+	}
+}
+
+func (r *Readline) historyNext() {
+	if r.histPos == -1 {
+		return
+	}
+	if r.histPos < len(r.history)-1 {
+		r.histPos++
+		r.setLine(r.history[r.histPos])
+		return
+	}
+	r.histPos = -1
+	r.setLine("")
+}
+
+func (r *Readline) MoveCursorLeft() {
+	if r.cursor > 0 {
+		r.cursor--
+	}
+}
+
+func (r *Readline) MoveCursorRight() {
+	if r.cursor < len(r.line) {
+		r.cursor++
+	}
+}
@@ -0,0 +1,311 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// replCommands are the REPL commands and what they do, for :help
+var replCommands = [][2]string{
+	{":load <file>", "evaluate a file into the session"},
+	{":save <file>", "write the inputs that ran without error to a file"},
+	{":vars", "list the bindings of the session with their types"},
+	{":clear", "start over with a fresh environment"},
+	{":type <expr>", "show the type of an expression without printing it"},
+	{":inspect [--depth N] <expr>", "summarized view of a value"},
+	{":expand [--depth N] <expr>", "the same without truncating large structures"},
+	{":browse", "explore the bindings interactively"},
+	{":diff", "toggle printing the bindings each input changes"},
+	{":help", "list these commands"},
+	{"_, _1, _2, ...", "the last result and every result by number"},
+}
+
+// replSession is the state of the REPL: its environment, the last result
+// and the inputs that ran without error, which :save writes
+type replSession struct {
+	env      *Environment
+	builtins map[string]bool // the natives and preloads, left out of :vars
+	last     RuntimeValue    // `_` in :inspect, :expand and :type
+	results  int             // how many results were bound to _1, _2, ...
+	inputs   []replInput
+}
+
+// replInput is an input that ran without error and the number of the
+// result it gave, 0 when it gave none
+type replInput struct {
+	code   string
+	result int
+}
+
+// newReplSession sets up a session with the natives and the command line
+// flags, preload runs the --preload files into it
+func newReplSession() (*replSession, error) {
+	env := NewEnvironment(nil)
+	setupNativeFunctions(env)
+	if err := setupFromFlags(env); err != nil {
+		return nil, err
+	}
+	return &replSession{env: env}, nil
+}
+
+// reset gives the session a fresh environment and forgets its inputs, the
+// flags were already accepted when the session started
+func (s *replSession) reset() {
+	session, _ := newReplSession()
+	*s = *session
+	s.preload()
+}
+
+// preload runs the --preload files, what they define is there at the prompt
+func (s *replSession) preload() {
+	for _, source := range readPreloads() {
+		if _, err := NewLuna(s.env).Evaluate(source.code); err != nil {
+			exitOnRequest(err)
+			reportError(err, source.name, source.name+": ")
+		}
+	}
+
+	s.builtins = make(map[string]bool, len(s.env.variables))
+	for name := range s.env.variables {
+		s.builtins[name] = true
+	}
+}
+
+// remember binds the result of the input just recorded to _ and to the
+// next of _1, _2, ...
+func (s *replSession) remember(result RuntimeValue) {
+	s.last = result
+	s.results++
+	if len(s.inputs) > 0 {
+		s.inputs[len(s.inputs)-1].result = s.results
+	}
+	s.env.DeclareVar("_", result, false)
+	s.env.DeclareVar("_"+strconv.Itoa(s.results), result, false)
+}
+
+// isResultName reports whether name is _ or one of _1, _2, ...
+func isResultName(name string) bool {
+	digits, found := strings.CutPrefix(name, "_")
+	if !found {
+		return false
+	}
+	_, err := strconv.Atoi(digits)
+	return digits == "" || err == nil
+}
+
+// record keeps an input that ran without error for :save
+func (s *replSession) record(input string) {
+	s.inputs = append(s.inputs, replInput{code: input})
+}
+
+// resultReferences lists the names among _, _1, _2, ... that code reads
+func resultReferences(code string) []string {
+	tokens, err := NewTokenizer(code).Tokenize()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, token := range tokens {
+		if token.Type == IDENTIFIER && isResultName(token.Value) {
+			names = append(names, token.Value)
+		}
+	}
+	return names
+}
+
+// bindResult rewrites code so that it also assigns the value it gives to
+// name, code is kept as it is when its value has no expression
+func bindResult(code, name string) string {
+	tokens, err := NewTokenizer(code).Tokenize()
+	if err != nil {
+		return code
+	}
+	ast, err := NewParser(tokens, code).ProduceAST()
+	if err != nil {
+		return code
+	}
+	body := ast.(*Program).Body
+	if len(body) == 0 {
+		return code
+	}
+
+	switch last := body[len(body)-1].(type) {
+	case *AssignmentExpr:
+		if ident, ok := last.Assigne.(*Identifier); ok {
+			return code + "\n" + name + " = " + ident.Value
+		}
+	case *ActionAssignmentExpr:
+		if ident, ok := last.Assigne.(*Identifier); ok {
+			return code + "\n" + name + " = " + ident.Value
+		}
+	case *FunctionDeclaration:
+		if last.Name != "" {
+			return code + "\n" + name + " = " + last.Name
+		}
+	case *IfStatement, *WhileStatement, *DoWhileStatement, *ForStatement, *ForInStatement,
+		*ReturnExpr, *DebugStatement, *UseStatement, *Comment:
+	default:
+		if len(body) == 1 {
+			return name + " = " + code
+		}
+		// The statements before the last one are printed back as they were
+		printer := NewPrinter()
+		return printer.Print(&Program{Body: body[:len(body)-1]}) + name + " = " + printer.expr(last, precAssignment)
+	}
+	return code
+}
+
+// savedInputs are the inputs as :save writes them. A result a later input
+// reads as _ or _N is assigned to _N and _ by the input that gave it, so
+// that the file runs on its own.
+func (s *replSession) savedInputs() []string {
+	referenced := make(map[int]bool)
+	last := 0
+	for _, input := range s.inputs {
+		for _, name := range resultReferences(input.code) {
+			if number, err := strconv.Atoi(name[1:]); err == nil {
+				referenced[number] = true
+			} else {
+				referenced[last] = true
+			}
+		}
+		if input.result != 0 {
+			last = input.result
+		}
+	}
+
+	lines := make([]string, 0, len(s.inputs))
+	for _, input := range s.inputs {
+		if input.result == 0 || !referenced[input.result] {
+			lines = append(lines, input.code)
+			continue
+		}
+		name := "_" + strconv.Itoa(input.result)
+		lines = append(lines, bindResult(input.code, name), "_ = "+name)
+	}
+	return lines
+}
+
+// runCommand handles :load, :save, :vars, :clear, :type and :help, it
+// reports whether input was one of them
+func (s *replSession) runCommand(input string) bool {
+	command, rest, _ := strings.Cut(input, " ")
+	rest = strings.TrimSpace(rest)
+
+	switch command {
+	case ":load":
+		s.load(rest)
+	case ":save":
+		s.save(rest)
+	case ":vars":
+		s.printVars()
+	case ":clear":
+		s.reset()
+		fmt.Println(gray("environment cleared"))
+	case ":type":
+		s.printType(rest)
+	case ":help":
+		width := 0
+		for _, entry := range replCommands {
+			width = max(width, len(entry[0]))
+		}
+		for _, entry := range replCommands {
+			fmt.Printf("  %s  %s\n", green(fmt.Sprintf("%-*s", width, entry[0])), gray(entry[1]))
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// load evaluates a file into the session
+func (s *replSession) load(filename string) {
+	if filename == "" {
+		fmt.Println(formatError("Error", ":load expects a file"))
+		return
+	}
+	source, err := readSource(filename)
+	if err != nil {
+		fmt.Println(formatError("Error", fmt.Sprintf("could not read file '%s': %v", filename, err)))
+		return
+	}
+	// Its use statements are relative to the file, the inputs to the working directory
+	s.env.SetModule(source.name)
+	defer s.env.SetModule("")
+	if _, err := NewLuna(s.env).Evaluate(source.code); err != nil {
+		exitOnRequest(err)
+		reportError(err, source.name, source.name+": ")
+		return
+	}
+	s.record(strings.TrimRight(source.code, "\n"))
+	fmt.Println(gray("loaded " + source.name))
+}
+
+// save writes the inputs of the session to a file, one after the other
+func (s *replSession) save(filename string) {
+	if filename == "" {
+		fmt.Println(formatError("Error", ":save expects a file"))
+		return
+	}
+	code := strings.Join(s.savedInputs(), "\n")
+	if code != "" {
+		code += "\n"
+	}
+	if err := os.WriteFile(filename, []byte(code), 0644); err != nil {
+		fmt.Println(formatError("Error", fmt.Sprintf("could not write file '%s': %v", filename, err)))
+		return
+	}
+	fmt.Println(gray(fmt.Sprintf("saved %d inputs to %s", len(s.inputs), filename)))
+}
+
+// printVars lists the bindings the session made, sorted by name
+func (s *replSession) printVars() {
+	var names []string
+	for name := range s.env.variables {
+		if !s.builtins[name] && !isResultName(name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		fmt.Println(gray("no variables"))
+		return
+	}
+	sort.Strings(names)
+
+	width := 0
+	for _, name := range names {
+		width = max(width, len(name))
+	}
+	for _, name := range names {
+		value := s.env.variables[name]
+		text := truncateValue(strings.ReplaceAll(stableString(value), "\n", " "))
+		if fn, ok := value.(*FunctionValue); ok {
+			text = functionSignature(fn)
+		}
+		fmt.Printf("  %s  %s  %s\n", bold(fmt.Sprintf("%-*s", width, name)), cyan(fmt.Sprintf("%-8s", value.Type())), gray(text))
+	}
+}
+
+// printType evaluates expr and prints its type, functions with their signature
+func (s *replSession) printType(expr string) {
+	if expr == "" {
+		expr = "_"
+	}
+	scope := NewEnvironment(s.env)
+	if s.last != nil {
+		scope.DeclareVar("_", s.last, false)
+	}
+	value, err := NewLuna(scope).Evaluate(expr)
+	if err != nil {
+		fmt.Println(formatError("Error", err.Error()))
+		return
+	}
+	if fn, ok := value.(*FunctionValue); ok {
+		fmt.Println(cyan(string(value.Type())) + " " + gray(functionSignature(fn)))
+		return
+	}
+	fmt.Println(cyan(string(value.Type())))
+}
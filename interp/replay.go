@@ -0,0 +1,309 @@
+package interp
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// defaultRecordLimit is how many statements a --record run keeps, older ones are dropped
+const defaultRecordLimit = 10000
+
+// statementHook, when set, runs after every statement of a body
+var statementHook func(stmt Statement, env *Environment)
+
+// evaluateStatement evaluates one statement of a body and reports it to the
+// statement hook, spawned tasks get their turn between statements
+func evaluateStatement(stmt Statement, env *Environment) (RuntimeValue, error) {
+	if err := env.countStep(); err != nil {
+		return nil, err
+	}
+	tracing := env.options.Trace
+	if tracing && tracesBefore(stmt) {
+		traceStatement(stmt, env, nil)
+	}
+	result, err := Evaluate(stmt, env)
+	if tracing && err == nil && !tracesBefore(stmt) {
+		traceStatement(stmt, env, result)
+	}
+	if statementHook != nil {
+		statementHook(stmt, env)
+	}
+	yieldInterpreter(env)
+	if err == nil {
+		err = checkCancelled(env)
+	}
+	return result, err
+}
+
+var positionType = reflect.TypeOf(Position{})
+
+// nodePosition finds the first source position inside a node
+func nodePosition(node Statement) (Position, bool) {
+	if _, ok := node.(*FunctionDeclaration); ok {
+		return Position{}, false // the first position would be inside the body
+	}
+	return findPosition(reflect.ValueOf(node))
+}
+
+func findPosition(v reflect.Value) (Position, bool) {
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return Position{}, false
+		}
+		return findPosition(v.Elem())
+	case reflect.Struct:
+		if v.Type() == positionType {
+			return v.Interface().(Position), true
+		}
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			if position, ok := findPosition(v.Field(i)); ok {
+				return position, true
+			}
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if position, ok := findPosition(v.Index(i)); ok {
+				return position, true
+			}
+		}
+	}
+	return Position{}, false
+}
+
+// BindingChange is a variable declared or assigned by a statement
+type BindingChange struct {
+	Name string
+	Old  string // empty when the statement declared the variable
+	New  string
+}
+
+// TraceStep is one executed statement of a recording
+type TraceStep struct {
+	Position    Position
+	HasPosition bool
+	Changes     []BindingChange
+}
+
+// Recorder keeps a bounded trace of the statements run and the bindings they changed
+type Recorder struct {
+	limit     int
+	steps     []TraceStep
+	dropped   int // steps discarded to stay within limit
+	snapshots map[*Environment]map[string]string
+}
+
+// NewRecorder starts recording, taking the current bindings of env as the baseline
+func NewRecorder(env *Environment, limit int) *Recorder {
+	recorder := &Recorder{limit: limit, snapshots: make(map[*Environment]map[string]string)}
+	recorder.snapshots[env] = env.Snapshot()
+	return recorder
+}
+
+func (r *Recorder) record(stmt Statement, env *Environment) {
+	if _, isComment := stmt.(*Comment); isComment {
+		return
+	}
+
+	// Scopes of finished calls are never seen again, forget them now and then
+	if len(r.snapshots) > 1024 {
+		for scope := range r.snapshots {
+			if scope.parent != nil {
+				delete(r.snapshots, scope)
+			}
+		}
+	}
+
+	step := TraceStep{}
+	step.Position, step.HasPosition = nodePosition(stmt)
+
+	// Statements may assign to any enclosing scope, not only their own
+	for scope := env; scope != nil; scope = scope.parent {
+		before := r.snapshots[scope]
+		after := scope.Snapshot()
+		r.snapshots[scope] = after
+
+		for _, name := range sortedNames(after) {
+			old, existed := before[name]
+			if !existed || old != after[name] {
+				step.Changes = append(step.Changes, BindingChange{Name: name, Old: old, New: after[name]})
+			}
+		}
+	}
+
+	if len(r.steps) == r.limit {
+		r.steps = r.steps[1:]
+		r.dropped++
+	}
+	r.steps = append(r.steps, step)
+}
+
+// Start installs the recorder as the statement hook
+func (r *Recorder) Start() {
+	statementHook = r.record
+}
+
+// Stop removes the statement hook
+func (r *Recorder) Stop() {
+	statementHook = nil
+}
+
+// recordLimit reads the limit of --record=N, defaulting to defaultRecordLimit
+func recordLimit() int {
+	if value, found := flagValue("--record"); found {
+		if limit, err := strconv.Atoi(value); err == nil && limit > 0 {
+			return limit
+		}
+	}
+	return defaultRecordLimit
+}
+
+// Replayer steps backwards and forwards through a recording
+type Replayer struct {
+	recorder *Recorder
+	lines    []string
+	current  int
+}
+
+// bindingsAt replays the changes up to step i to rebuild the values seen so far
+func (r *Replayer) bindingsAt(i int) map[string]string {
+	bindings := make(map[string]string)
+	for _, step := range r.recorder.steps[:i+1] {
+		for _, change := range step.Changes {
+			bindings[change.Name] = change.New
+		}
+	}
+	return bindings
+}
+
+func (r *Replayer) printStep() {
+	steps := r.recorder.steps
+	step := steps[r.current]
+
+	location := gray("(no position)")
+	if step.HasPosition {
+		source := ""
+		if step.Position.Line < len(r.lines) {
+			source = strings.TrimSpace(r.lines[step.Position.Line])
+		}
+		location = gray(fmt.Sprintf("line %d: ", step.Position.Line+1)) + source
+	}
+	fmt.Printf("%s %s\n", cyan(fmt.Sprintf("step %d/%d", r.recorder.dropped+r.current+1, r.recorder.dropped+len(steps))), location)
+
+	for _, change := range step.Changes {
+		if change.Old == "" {
+			fmt.Println("  " + green("+ ") + blue(change.Name) + gray(" = ") + truncateValue(change.New))
+		} else {
+			fmt.Println("  " + yellow("~ ") + blue(change.Name) + gray(": ") + truncateValue(change.Old) + gray(" → ") + truncateValue(change.New))
+		}
+	}
+}
+
+// goTo moves to step i (counted like the display, from 1), clamped to the recording
+func (r *Replayer) goTo(i int) {
+	r.current = max(0, min(i-1-r.recorder.dropped, len(r.recorder.steps)-1))
+}
+
+// history lists the steps that changed name
+func (r *Replayer) history(name string) {
+	found := false
+	for i, step := range r.recorder.steps {
+		for _, change := range step.Changes {
+			if change.Name != name {
+				continue
+			}
+			found = true
+			line := ""
+			if step.HasPosition {
+				line = fmt.Sprintf(" (line %d)", step.Position.Line+1)
+			}
+			fmt.Printf("  %s%s %s\n", cyan(fmt.Sprintf("step %d", r.recorder.dropped+i+1)), gray(line), truncateValue(change.New))
+		}
+	}
+	if !found {
+		fmt.Println(gray("  " + name + " never changed in the recording"))
+	}
+}
+
+const replayHelp = `  n, <enter>   next step         p   previous step
+  g <step>     go to a step      s   first step     e   last step
+  w <name>     steps that changed a variable
+  v            values of all variables at this step
+  q            quit`
+
+// Run reads stepping commands from stdin until q or EOF
+func (r *Replayer) Run() {
+	if len(r.recorder.steps) == 0 {
+		fmt.Println(gray("Nothing was recorded."))
+		return
+	}
+
+	fmt.Println(green(fmt.Sprintf("Recorded %d steps. Type h for help.", r.recorder.dropped+len(r.recorder.steps))))
+	r.current = len(r.recorder.steps) - 1
+	r.printStep()
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(white("replay> "))
+		if !scanner.Scan() {
+			fmt.Println()
+			return
+		}
+		command, argument, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+		argument = strings.TrimSpace(argument)
+
+		switch command {
+		case "", "n", "next":
+			r.current = min(r.current+1, len(r.recorder.steps)-1)
+		case "p", "prev", "back":
+			r.current = max(r.current-1, 0)
+		case "s", "start":
+			r.current = 0
+		case "e", "end":
+			r.current = len(r.recorder.steps) - 1
+		case "g", "goto":
+			step, err := strconv.Atoi(argument)
+			if err != nil {
+				fmt.Println(formatError("Error", "g expects a step number"))
+				continue
+			}
+			r.goTo(step)
+		case "w", "where":
+			r.history(argument)
+			continue
+		case "v", "values":
+			bindings := r.bindingsAt(r.current)
+			for _, name := range sortedNames(bindings) {
+				fmt.Println("  " + blue(name) + gray(" = ") + truncateValue(bindings[name]))
+			}
+			continue
+		case "h", "help":
+			fmt.Println(replayHelp)
+			continue
+		case "q", "quit":
+			return
+		default:
+			fmt.Println(formatError("Error", "unknown command '"+command+"', type h for help"))
+			continue
+		}
+		r.printStep()
+	}
+}
+
+// sortedNames returns the names of a snapshot in a deterministic order
+func sortedNames(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,151 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// RetryOptions configure retry, durations are in milliseconds like io.time
+type RetryOptions struct {
+	Attempts int
+	Backoff  float64 // delay before the second attempt, doubled after each failure
+	Jitter   float64 // fraction of the delay to randomize, 0.2 means ±20%
+}
+
+var defaultRetryOptions = RetryOptions{Attempts: 3, Backoff: 100, Jitter: 0}
+
+func parseRetryOptions(value RuntimeValue) (RetryOptions, error) {
+	options := defaultRetryOptions
+	object, ok := value.(*ObjectValue)
+	if !ok {
+		return options, fmt.Errorf("retry: options must be an object")
+	}
+
+	for _, name := range object.Keys() {
+		property := object.Properties[name]
+		number, ok := toFloat(property)
+		if !ok {
+			return options, fmt.Errorf("retry: %s must be a number", name)
+		}
+		switch name {
+		case "attempts":
+			if number < 1 || number != float64(int(number)) {
+				return options, fmt.Errorf("retry: attempts must be a positive integer")
+			}
+			options.Attempts = int(number)
+		case "backoff":
+			if number < 0 {
+				return options, fmt.Errorf("retry: backoff cannot be negative")
+			}
+			options.Backoff = number
+		case "jitter":
+			if number < 0 || number > 1 {
+				return options, fmt.Errorf("retry: jitter must be between 0 and 1")
+			}
+			options.Jitter = number
+		default:
+			return options, fmt.Errorf("retry: unknown option '%s'", name)
+		}
+	}
+	return options, nil
+}
+
+// delay is the wait after the given failed attempt, counted from 0
+func (o RetryOptions) delay(attempt int) time.Duration {
+	milliseconds := o.Backoff * float64(int(1)<<min(attempt, 30))
+	milliseconds *= 1 + o.Jitter*(2*rand.Float64()-1)
+	return time.Duration(milliseconds * float64(time.Millisecond))
+}
+
+// retryNative calls fn until it succeeds, waiting longer after each failure
+func retryNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("retry expects a function and optional options")
+	}
+	if args[0].Type() != FUNCTION_TYPE && args[0].Type() != NATIVE_FN_TYPE {
+		return nil, fmt.Errorf("retry: first argument must be a function")
+	}
+	options := defaultRetryOptions
+	if len(args) == 2 {
+		var err error
+		if options, err = parseRetryOptions(args[1]); err != nil {
+			return nil, err
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < options.Attempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepFor(options.delay(attempt-1), env); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := callValue(args[0], nil, env)
+		if err == nil {
+			return result, nil
+		}
+		// Permission errors, exit and cancellation will not go away by trying again
+		var permission *PermissionError
+		var exit *ExitError
+		if err == errCancelled || errors.As(err, &permission) || errors.As(err, &exit) {
+			return nil, err
+		}
+		lastErr = err
+	}
+	return nil, fmt.Errorf("retry: gave up after %d attempts: %w", options.Attempts, lastErr)
+}
+
+// RateLimiter lets at most n calls start in any window of per
+type RateLimiter struct {
+	n      int
+	per    time.Duration
+	starts []time.Time // start times of the calls in the current window, oldest first
+}
+
+// wait blocks until a call may start and records it, letting the other
+// tasks of env run
+func (l *RateLimiter) wait(env *Environment) error {
+	for {
+		now := time.Now()
+		for len(l.starts) > 0 && now.Sub(l.starts[0]) >= l.per {
+			l.starts = l.starts[1:]
+		}
+		if len(l.starts) < l.n {
+			l.starts = append(l.starts, now)
+			return nil
+		}
+		if err := sleepFor(l.per-now.Sub(l.starts[0]), env); err != nil {
+			return err
+		}
+	}
+}
+
+// ratelimitNative returns limited(fn, ...args), which calls fn(...args)
+// once fewer than n calls have started in the last per milliseconds
+func ratelimitNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("ratelimit expects 2 arguments, got %d", len(args))
+	}
+	n, ok := toInt(args[0])
+	if !ok || n < 1 {
+		return nil, fmt.Errorf("ratelimit: n must be a positive integer")
+	}
+	per, ok := toFloat(args[1])
+	if !ok || per <= 0 {
+		return nil, fmt.Errorf("ratelimit: per must be a positive number of milliseconds")
+	}
+
+	limiter := &RateLimiter{n: int(n), per: time.Duration(per * float64(time.Millisecond))}
+	return MakeNativeFunction("limited", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) == 0 || (args[0].Type() != FUNCTION_TYPE && args[0].Type() != NATIVE_FN_TYPE) {
+			return nil, fmt.Errorf("limited expects a function and its arguments")
+		}
+		if err := limiter.wait(env); err != nil {
+			return nil, err
+		}
+		return callValue(args[0], args[1:], env)
+	}), nil
+}
@@ -0,0 +1,73 @@
+package interp
+
+import "sort"
+
+// resolveSymbols works out, for the language server, what every name in a
+// program refers to: the declaration of each function, parameter and
+// variable, and for each identifier the declaration it is or uses. It
+// walks the program with the checker, so names are scoped the way `luna
+// check` scopes them, and returns the checker's diagnostics along the way.
+
+// SymbolKind tells what declared a name
+type SymbolKind string
+
+const (
+	SymbolFunction  SymbolKind = "function"
+	SymbolParameter SymbolKind = "parameter"
+	SymbolVariable  SymbolKind = "variable"
+	SymbolNative    SymbolKind = "native"
+)
+
+// Declaration is a name declared in a program, or a native
+type Declaration struct {
+	Name     string
+	Kind     SymbolKind
+	Position Position             // where the name is declared, unset for natives
+	Function *FunctionDeclaration // the function, for SymbolFunction
+}
+
+// SymbolTable holds the declarations of a program and what its identifiers refer to
+type SymbolTable struct {
+	Declarations []*Declaration // in the order they appear, natives left out
+	references   map[int]*Declaration
+}
+
+// At is the declaration of the identifier starting at index, nil when
+// there is no identifier there or it is undeclared
+func (t *SymbolTable) At(index int) *Declaration {
+	return t.references[index]
+}
+
+// resolveSymbols checks program and resolves its names
+func resolveSymbols(program *Program) (*SymbolTable, []Diagnostic) {
+	checker := NewChecker()
+	checker.warnings = true
+	checker.resolved = make(map[int]*symbol)
+	diagnostics := checker.Check(program)
+
+	table := &SymbolTable{references: make(map[int]*Declaration)}
+	declarations := make(map[*symbol]*Declaration)
+	for index, sym := range checker.resolved {
+		declaration, seen := declarations[sym]
+		if !seen {
+			declaration = &Declaration{Name: sym.name, Kind: SymbolVariable, Position: sym.position, Function: sym.function}
+			switch {
+			case sym.native:
+				declaration.Kind = SymbolNative
+			case sym.function != nil:
+				declaration.Kind = SymbolFunction
+			case sym.param:
+				declaration.Kind = SymbolParameter
+			}
+			declarations[sym] = declaration
+			if !sym.native {
+				table.Declarations = append(table.Declarations, declaration)
+			}
+		}
+		table.references[index] = declaration
+	}
+	sort.Slice(table.Declarations, func(i, j int) bool {
+		return table.Declarations[i].Position.Index < table.Declarations[j].Position.Index
+	})
+	return table, diagnostics
+}
@@ -0,0 +1,83 @@
+package interp
+
+import (
+	"fmt"
+	"time"
+)
+
+// A sandboxed environment can run untrusted scripts: everything reaching
+// outside the interpreter is disabled, the file system, processes, the
+// network, secrets, exit and use. What is left computes, prints and reads
+// from stdin. `luna --sandbox script.luna` runs a script sandboxed, a host
+// calls luna.Sandbox() before evaluating.
+//
+// The sandbox also bounds how long a script runs, with SandboxLimits, so an
+// endless loop or recursion ends in a LimitError. Limits set afterwards, with
+// the flags or luna.SetLimits, replace them.
+
+// SandboxLimits are the limits a sandbox applies when env has none as tight
+var SandboxLimits = Limits{MaxCallDepth: 1000, MaxSteps: 10_000_000, Timeout: 5 * time.Second}
+
+// sandboxModules are the native modules disabled as a whole by the sandbox
+var sandboxModules = []string{"file", "os", "http", "mock", "secrets"}
+
+// sandboxNatives are the single natives disabled in modules that are otherwise safe
+var sandboxNatives = map[string][]string{
+	"proto": {"load"}, // reads descriptor sets from files
+}
+
+// Sandbox disables the natives of env that could harm the host
+func Sandbox(env *Environment) error {
+	for _, module := range sandboxModules {
+		if err := DisableModule(env, module); err != nil {
+			return err
+		}
+	}
+	for module, names := range sandboxNatives {
+		object, ok := env.LookupVar(module).(*ObjectValue)
+		if !ok {
+			return fmt.Errorf("unknown module '%s'", module)
+		}
+		properties := make(map[string]RuntimeValue, len(object.Properties))
+		for name, property := range object.Properties {
+			properties[name] = property
+		}
+		for _, name := range names {
+			properties[name] = disabledNative(module, name)
+		}
+		env.variables[module] = MakeObject(properties)
+	}
+
+	env.variables["exit"] = disabledNative("exit", "exit")
+	if env.disabled == nil {
+		env.disabled = make(map[string]bool)
+	}
+	env.disabled["use"] = true
+
+	limits := &env.limits.Limits
+	limits.MaxCallDepth = tighter(limits.MaxCallDepth, SandboxLimits.MaxCallDepth)
+	limits.MaxSteps = tighter(limits.MaxSteps, SandboxLimits.MaxSteps)
+	limits.Timeout = time.Duration(tighter(int(limits.Timeout), int(SandboxLimits.Timeout)))
+	return nil
+}
+
+// tighter is the smaller of two limits, where zero means none
+func tighter(current, limit int) int {
+	if current == 0 || (limit != 0 && limit < current) {
+		return limit
+	}
+	return current
+}
+
+// Sandbox disables the natives of this interpreter that could harm the host
+func (l *Luna) Sandbox() error {
+	return Sandbox(l.env)
+}
+
+// root is the top-level scope env belongs to
+func (env *Environment) root() *Environment {
+	for env.parent != nil {
+		env = env.parent
+	}
+	return env
+}
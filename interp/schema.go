@@ -0,0 +1,302 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Schemas describe the shape of a value with a subset of JSON Schema:
+//
+//	{
+//	  type: "object",
+//	  required: ["name"],
+//	  properties: {
+//	    name: {type: "string", minLength: 1},
+//	    age:  {type: "integer", minimum: 0},
+//	    tags: {type: "array", items: {type: "string"}},
+//	  },
+//	  additionalProperties: false,
+//	}
+//
+// Supported keywords: type (a name or an array of names), enum, required,
+// properties, additionalProperties, items, minimum, maximum, minLength,
+// maxLength, minItems, maxItems and pattern.
+
+// schemaError is one problem found in a value, at a path like "tags[2]"
+type schemaError struct {
+	path    string
+	message string
+}
+
+type schemaValidator struct {
+	errors []schemaError
+}
+
+func (s *schemaValidator) fail(path, format string, args ...any) {
+	s.errors = append(s.errors, schemaError{path: path, message: fmt.Sprintf(format, args...)})
+}
+
+// schemaType names the JSON Schema type of a value
+func schemaType(value RuntimeValue) string {
+	switch v := value.(type) {
+	case *NullValue:
+		return "null"
+	case *BooleanValue:
+		return "boolean"
+	case *NumberValue:
+		if isInteger(v.Value) {
+			return "integer"
+		}
+		return "number"
+	case *IntValue, *BigIntValue:
+		return "integer"
+	case *StringValue:
+		return "string"
+	case *ArrayValue:
+		return "array"
+	case *ObjectValue:
+		return "object"
+	}
+	return string(value.Type())
+}
+
+// schemaNumber reads a numeric keyword of a schema
+func schemaNumber(schema *ObjectValue, keyword string) (float64, bool, error) {
+	value, exists := schema.Properties[keyword]
+	if !exists {
+		return 0, false, nil
+	}
+	number, ok := toFloat(value)
+	if !ok {
+		return 0, false, fmt.Errorf("schema: %s must be a number", keyword)
+	}
+	return number, true, nil
+}
+
+func (s *schemaValidator) validate(value RuntimeValue, schemaValue RuntimeValue, path string) error {
+	schema, ok := schemaValue.(*ObjectValue)
+	if !ok {
+		return fmt.Errorf("schema: expected a schema object at '%s', got %s", displayPath(path), schemaValue.Type())
+	}
+	actual := schemaType(value)
+
+	if expected, exists := schema.Properties["type"]; exists {
+		var names []string
+		switch t := expected.(type) {
+		case *StringValue:
+			names = []string{t.Value}
+		case *ArrayValue:
+			for _, element := range t.Elements {
+				name, ok := element.(*StringValue)
+				if !ok {
+					return fmt.Errorf("schema: type must be a string or an array of strings")
+				}
+				names = append(names, name.Value)
+			}
+		default:
+			return fmt.Errorf("schema: type must be a string or an array of strings")
+		}
+
+		matches := false
+		for _, name := range names {
+			// An integer is a number too
+			matches = matches || name == actual || (name == "number" && actual == "integer")
+		}
+		if !matches {
+			s.fail(path, "expected %s, got %s", strings.Join(names, " or "), actual)
+			return nil // the other keywords would only repeat the mismatch
+		}
+	}
+
+	if enum, exists := schema.Properties["enum"]; exists {
+		options, ok := enum.(*ArrayValue)
+		if !ok {
+			return fmt.Errorf("schema: enum must be an array")
+		}
+		found := false
+		for _, option := range options.Elements {
+			found = found || isEqual(value, option)
+		}
+		if !found {
+			allowed := make([]string, len(options.Elements))
+			for i, option := range options.Elements {
+				allowed[i] = option.String()
+			}
+			s.fail(path, "must be one of %s", strings.Join(allowed, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case *NumberValue, *IntValue, *BigIntValue:
+		number, _ := toFloat(v)
+		if minimum, ok, err := schemaNumber(schema, "minimum"); err != nil {
+			return err
+		} else if ok && number < minimum {
+			s.fail(path, "must be at least %s", formatSchemaNumber(minimum))
+		}
+		if maximum, ok, err := schemaNumber(schema, "maximum"); err != nil {
+			return err
+		} else if ok && number > maximum {
+			s.fail(path, "must be at most %s", formatSchemaNumber(maximum))
+		}
+
+	case *StringValue:
+		length := float64(len([]rune(v.Value)))
+		if minLength, ok, err := schemaNumber(schema, "minLength"); err != nil {
+			return err
+		} else if ok && length < minLength {
+			s.fail(path, "must be at least %s characters long", formatSchemaNumber(minLength))
+		}
+		if maxLength, ok, err := schemaNumber(schema, "maxLength"); err != nil {
+			return err
+		} else if ok && length > maxLength {
+			s.fail(path, "must be at most %s characters long", formatSchemaNumber(maxLength))
+		}
+		if pattern, exists := schema.Properties["pattern"]; exists {
+			source, ok := pattern.(*StringValue)
+			if !ok {
+				return fmt.Errorf("schema: pattern must be a string")
+			}
+			re, err := regexp.Compile(source.Value)
+			if err != nil {
+				return fmt.Errorf("schema: invalid pattern: %v", err)
+			}
+			if !re.MatchString(v.Value) {
+				s.fail(path, "must match %s", source.Value)
+			}
+		}
+
+	case *ArrayValue:
+		count := float64(len(v.Elements))
+		if minItems, ok, err := schemaNumber(schema, "minItems"); err != nil {
+			return err
+		} else if ok && count < minItems {
+			s.fail(path, "must have at least %s items", formatSchemaNumber(minItems))
+		}
+		if maxItems, ok, err := schemaNumber(schema, "maxItems"); err != nil {
+			return err
+		} else if ok && count > maxItems {
+			s.fail(path, "must have at most %s items", formatSchemaNumber(maxItems))
+		}
+		if items, exists := schema.Properties["items"]; exists {
+			for i, element := range v.Elements {
+				if err := s.validate(element, items, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+
+	case *ObjectValue:
+		if required, exists := schema.Properties["required"]; exists {
+			names, ok := required.(*ArrayValue)
+			if !ok {
+				return fmt.Errorf("schema: required must be an array")
+			}
+			for _, name := range names.Elements {
+				key, ok := name.(*StringValue)
+				if !ok {
+					return fmt.Errorf("schema: required must list strings")
+				}
+				if _, exists := v.Properties[key.Value]; !exists {
+					s.fail(joinPath(path, key.Value), "is required")
+				}
+			}
+		}
+
+		properties := map[string]RuntimeValue{}
+		if declared, exists := schema.Properties["properties"]; exists {
+			object, ok := declared.(*ObjectValue)
+			if !ok {
+				return fmt.Errorf("schema: properties must be an object")
+			}
+			properties = object.Properties
+		}
+		for _, key := range sortedKeys(v.Properties) {
+			if property, declared := properties[key]; declared {
+				if err := s.validate(v.Properties[key], property, joinPath(path, key)); err != nil {
+					return err
+				}
+				continue
+			}
+			switch additional := schema.Properties["additionalProperties"].(type) {
+			case *BooleanValue:
+				if !additional.Value {
+					s.fail(joinPath(path, key), "is not allowed")
+				}
+			case *ObjectValue:
+				if err := s.validate(v.Properties[key], additional, joinPath(path, key)); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// displayPath shows the root of the value as "value"
+func displayPath(path string) string {
+	if path == "" {
+		return "value"
+	}
+	return path
+}
+
+func formatSchemaNumber(n float64) string {
+	if isInteger(n) && math.Abs(n) < 1e15 {
+		return strconv.FormatInt(int64(n), 10)
+	}
+	return strconv.FormatFloat(n, 'g', -1, 64)
+}
+
+// validateSchema runs a schema over a value, the error is about the schema itself
+func validateSchema(name string, args []RuntimeValue) ([]schemaError, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("schema.%s expects 2 arguments, got %d", name, len(args))
+	}
+	validator := &schemaValidator{}
+	if err := validator.validate(args[0], args[1], ""); err != nil {
+		return nil, err
+	}
+	return validator.errors, nil
+}
+
+func createSchemaObject() RuntimeValue {
+	schemaProps := make(map[string]RuntimeValue)
+
+	// schema.validate(value, schema) lists the problems as [{ path, message }], empty when valid
+	schemaProps["validate"] = MakeNativeFunction("validate", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		problems, err := validateSchema("validate", args)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]RuntimeValue, len(problems))
+		for i, problem := range problems {
+			list[i] = MakeObject(map[string]RuntimeValue{
+				"path":    MakeString(problem.path),
+				"message": MakeString(problem.message),
+			})
+		}
+		return MakeArray(list), nil
+	})
+
+	// schema.assert(value, schema) fails with every problem when the value does not fit
+	schemaProps["assert"] = MakeNativeFunction("assert", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		problems, err := validateSchema("assert", args)
+		if err != nil {
+			return nil, err
+		}
+		if len(problems) == 0 {
+			return args[0], nil
+		}
+		lines := make([]string, len(problems))
+		for i, problem := range problems {
+			lines[i] = displayPath(problem.path) + ": " + problem.message
+		}
+		return nil, fmt.Errorf("value does not match the schema:\n  %s", strings.Join(lines, "\n  "))
+	})
+
+	return MakeObject(schemaProps)
+}
@@ -0,0 +1,78 @@
+package interp
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+)
+
+// redacted is how a secret is shown everywhere: printing, debug, inspect, :diff and recordings
+const redacted = "<secret>"
+
+// Secret Value wraps a value so it never ends up in output by accident,
+// secret.reveal() is the only way back to the value
+type SecretValue struct {
+	Value RuntimeValue
+}
+
+func (s *SecretValue) Type() ValueType { return SECRET_TYPE }
+func (s *SecretValue) String() string  { return redacted }
+func (s *SecretValue) IsTruthy() bool  { return s.Value.IsTruthy() }
+func (s *SecretValue) Prototypes() *[]RuntimeValue {
+	return &[]RuntimeValue{
+		MakeNativeFunction("reveal", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if len(args) != 0 {
+				return nil, fmt.Errorf("secret.reveal takes no arguments")
+			}
+			return s.Value, nil
+		}),
+	}
+}
+
+// secretNative wraps a value with secret(value), wrapping a secret again changes nothing
+func secretNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("secret expects 1 argument, got %d", len(args))
+	}
+	if secret, ok := args[0].(*SecretValue); ok {
+		return secret, nil
+	}
+	return &SecretValue{Value: args[0]}, nil
+}
+
+// secretBytes reads a string or a secret string for comparison
+func secretBytes(value RuntimeValue) ([]byte, error) {
+	if secret, ok := value.(*SecretValue); ok {
+		value = secret.Value
+	}
+	str, ok := value.(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("crypto.constantTimeEquals expects strings or secret strings, got %s", value.Type())
+	}
+	return []byte(str.Value), nil
+}
+
+func createCryptoObject() RuntimeValue {
+	cryptoProps := make(map[string]RuntimeValue)
+
+	// crypto.constantTimeEquals(a, b) takes as long for any two inputs, so
+	// comparing a token does not reveal how much of it was right. The inputs
+	// are hashed first, which hides their lengths as well.
+	cryptoProps["constantTimeEquals"] = MakeNativeFunction("constantTimeEquals", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("crypto.constantTimeEquals expects 2 arguments, got %d", len(args))
+		}
+		a, err := secretBytes(args[0])
+		if err != nil {
+			return nil, err
+		}
+		b, err := secretBytes(args[1])
+		if err != nil {
+			return nil, err
+		}
+		sumA, sumB := sha256.Sum256(a), sha256.Sum256(b)
+		return MakeBool(subtle.ConstantTimeCompare(sumA[:], sumB[:]) == 1), nil
+	})
+
+	return MakeObject(cryptoProps)
+}
@@ -0,0 +1,208 @@
+package interp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// keyringService is the service name Luna's credentials are stored under
+const keyringService = "luna"
+
+// dotenvValue looks name up in the .env file of the working directory
+func dotenvValue(name string) (string, bool) {
+	file, err := os.Open(".env")
+	if err != nil {
+		return "", false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found || strings.TrimSpace(key) != name {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// keyringGet reads name from the OS keyring, the Keychain on macOS and the
+// Secret Service (secret-tool) on linux, a missing entry is not an error
+func keyringGet(name string, env *Environment) (string, bool, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", keyringService, "-a", name, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", keyringService, "account", name)
+	default:
+		return "", false, fmt.Errorf("no keyring support on %s", runtime.GOOS)
+	}
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	var err error
+	blockingCall(env, func() { err = cmd.Run() })
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return strings.TrimSuffix(stdout.String(), "\n"), true, nil
+}
+
+// keyringSet stores value as name in the OS keyring
+func keyringSet(name, value string, env *Environment) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "add-generic-password", "-U", "-s", keyringService, "-a", name, "-w", value)
+	case "linux":
+		cmd = exec.Command("secret-tool", "store", "--label", keyringService+" "+name, "service", keyringService, "account", name)
+		cmd.Stdin = strings.NewReader(value)
+	default:
+		return fmt.Errorf("no keyring support on %s", runtime.GOOS)
+	}
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	var err error
+	blockingCall(env, func() { err = cmd.Run() })
+	if err != nil && stderr.Len() > 0 {
+		return errors.New(strings.TrimSpace(stderr.String()))
+	}
+	return err
+}
+
+// readHidden reads a line from the terminal without echoing it
+func readHidden() (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !isTerminal(fd) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return "", err
+		}
+		return strings.TrimRight(line, "\r\n"), nil
+	}
+
+	restore, err := enableRawMode(fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	var input []rune
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		r, _, err := reader.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Print("\r\n")
+			return string(input), nil
+		case 3: // ctrl-c
+			fmt.Print("\r\n")
+			return "", errors.New("cancelled")
+		case 127, 8:
+			if len(input) > 0 {
+				input = input[:len(input)-1]
+			}
+		default:
+			input = append(input, r)
+		}
+	}
+}
+
+func createSecretsObject() RuntimeValue {
+	secretsProps := make(map[string]RuntimeValue)
+
+	// secrets.get(name) looks in the environment, then .env, then the OS
+	// keyring, and gives back a secret, or undef when name is nowhere
+	secretsProps["get"] = MakeNativeFunction("get", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("secrets.get expects 1 argument, got %d", len(args))
+		}
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("secrets.get: name must be a string")
+		}
+
+		if value, found := os.LookupEnv(name.Value); found {
+			return &SecretValue{Value: MakeString(value)}, nil
+		}
+		if value, found := dotenvValue(name.Value); found {
+			return &SecretValue{Value: MakeString(value)}, nil
+		}
+		value, found, err := keyringGet(name.Value, env)
+		if err != nil || !found {
+			// Without a keyring the other sources are all there is
+			return MakeUndefined(), nil
+		}
+		return &SecretValue{Value: MakeString(value)}, nil
+	})
+
+	// secrets.set(name, value) stores a string or a secret in the OS keyring
+	secretsProps["set"] = MakeNativeFunction("set", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("secrets.set expects 2 arguments, got %d", len(args))
+		}
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("secrets.set: name must be a string")
+		}
+		value := args[1]
+		if secret, ok := value.(*SecretValue); ok {
+			value = secret.Value
+		}
+		str, ok := value.(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("secrets.set: value must be a string or a secret string")
+		}
+		if err := keyringSet(name.Value, str.Value, env); err != nil {
+			return nil, fmt.Errorf("secrets.set: %v", err)
+		}
+		return MakeVoid(), nil
+	})
+
+	// secrets.prompt(label) asks for a credential without echoing what is typed
+	secretsProps["prompt"] = MakeNativeFunction("prompt", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("secrets.prompt expects 1 argument, got %d", len(args))
+		}
+		label, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("secrets.prompt: label must be a string")
+		}
+		fmt.Print(label.Value)
+
+		var input string
+		var err error
+		blockingCall(env, func() { input, err = readHidden() })
+		if err != nil {
+			return nil, fmt.Errorf("secrets.prompt: %v", err)
+		}
+		return &SecretValue{Value: MakeString(input)}, nil
+	})
+
+	return MakeObject(secretsProps)
+}
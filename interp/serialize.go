@@ -0,0 +1,255 @@
+package interp
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Binary format: a version header followed by one tagged value.
+// Strings, arrays and objects are prefixed with their length as a uvarint,
+// floats are stored as little-endian float64 bits, ints as varints and
+// bigints as their decimal digits.
+const serializeVersion byte = 1
+
+const (
+	tagNull byte = iota
+	tagUndef
+	tagFalse
+	tagTrue
+	tagNumber
+	tagString
+	tagArray
+	tagObject
+	tagInt
+	tagBigInt
+)
+
+// SerializeValue encodes a Luna value into the compact binary format
+func SerializeValue(value RuntimeValue) ([]byte, error) {
+	return appendValue([]byte{'L', serializeVersion}, value, nil)
+}
+
+// errCircular is returned for a value that contains itself
+var errCircular = fmt.Errorf("cannot serialize a circular structure")
+
+// appendValue encodes value, path holds the arrays and objects around it
+func appendValue(buf []byte, value RuntimeValue, path []RuntimeValue) ([]byte, error) {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue:
+		var entered bool
+		if path, entered = enterPath(path, value); !entered {
+			return nil, errCircular
+		}
+	}
+
+	switch v := value.(type) {
+	case *NullValue:
+		return append(buf, tagNull), nil
+	case *UndefinedValue, *VoidValue:
+		return append(buf, tagUndef), nil
+	case *BooleanValue:
+		if v.Value {
+			return append(buf, tagTrue), nil
+		}
+		return append(buf, tagFalse), nil
+	case *NumberValue:
+		buf = append(buf, tagNumber)
+		return binary.LittleEndian.AppendUint64(buf, math.Float64bits(v.Value)), nil
+	case *IntValue:
+		return binary.AppendVarint(append(buf, tagInt), v.Value), nil
+	case *BigIntValue:
+		return appendString(append(buf, tagBigInt), v.Value.String()), nil
+	case *StringValue:
+		buf = append(buf, tagString)
+		return appendString(buf, v.Value), nil
+	case *ArrayValue:
+		buf = append(buf, tagArray)
+		buf = binary.AppendUvarint(buf, uint64(len(v.Elements)))
+		for _, elem := range v.Elements {
+			var err error
+			if buf, err = appendValue(buf, elem, path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	case *ObjectValue:
+		buf = append(buf, tagObject)
+		buf = binary.AppendUvarint(buf, uint64(len(v.Properties)))
+
+		// Insertion order keeps the output deterministic and survives the round trip
+		for _, key := range v.Keys() {
+			buf = appendString(buf, key)
+			var err error
+			if buf, err = appendValue(buf, v.Properties[key], path); err != nil {
+				return nil, err
+			}
+		}
+		return buf, nil
+	default:
+		return nil, fmt.Errorf("cannot serialize value of type %s", value.Type())
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// DeserializeValue decodes data produced by SerializeValue
+func DeserializeValue(data []byte) (RuntimeValue, error) {
+	if len(data) < 2 || data[0] != 'L' {
+		return nil, fmt.Errorf("invalid serialized data")
+	}
+	if data[1] != serializeVersion {
+		return nil, fmt.Errorf("unsupported serialization version %d", data[1])
+	}
+
+	decoder := &valueDecoder{data: data, position: 2}
+	value, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.position != len(data) {
+		return nil, fmt.Errorf("invalid serialized data: %d trailing bytes", len(data)-decoder.position)
+	}
+	return value, nil
+}
+
+type valueDecoder struct {
+	data     []byte
+	position int
+	depth    int // arrays and objects being decoded around the current value
+}
+
+var errTruncated = fmt.Errorf("invalid serialized data: unexpected end of input")
+
+func (d *valueDecoder) value() (RuntimeValue, error) {
+	d.depth++
+	defer func() { d.depth-- }()
+	if d.depth > maxDecodeDepth {
+		return nil, fmt.Errorf("invalid serialized data: nested more than %d levels deep", maxDecodeDepth)
+	}
+
+	if d.position >= len(d.data) {
+		return nil, errTruncated
+	}
+	tag := d.data[d.position]
+	d.position++
+
+	switch tag {
+	case tagNull:
+		return MakeNull(), nil
+	case tagUndef:
+		return MakeUndefined(), nil
+	case tagFalse:
+		return MakeBool(false), nil
+	case tagTrue:
+		return MakeBool(true), nil
+	case tagNumber:
+		if d.position+8 > len(d.data) {
+			return nil, errTruncated
+		}
+		bits := binary.LittleEndian.Uint64(d.data[d.position:])
+		d.position += 8
+		return MakeNumber(math.Float64frombits(bits)), nil
+	case tagInt:
+		n, size := binary.Varint(d.data[d.position:])
+		if size <= 0 {
+			return nil, errTruncated
+		}
+		d.position += size
+		return MakeInt(n), nil
+	case tagBigInt:
+		s, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		n, ok := new(big.Int).SetString(s, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid serialized data: bad bigint '%s'", s)
+		}
+		return MakeBigInt(n), nil
+	case tagString:
+		s, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return MakeString(s), nil
+	case tagArray:
+		count, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		elements := make([]RuntimeValue, 0, count)
+		for i := 0; i < count; i++ {
+			elem, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			elements = append(elements, elem)
+		}
+		return MakeArray(elements), nil
+	case tagObject:
+		count, err := d.length()
+		if err != nil {
+			return nil, err
+		}
+		object := newObject()
+		for i := 0; i < count; i++ {
+			key, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			value, err := d.value()
+			if err != nil {
+				return nil, err
+			}
+			object.Set(key, value)
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("invalid serialized data: unknown tag %d", tag)
+	}
+}
+
+// length reads a uvarint count, bounded by the remaining input
+func (d *valueDecoder) length() (int, error) {
+	n, size := binary.Uvarint(d.data[d.position:])
+	if size <= 0 || n > uint64(len(d.data)-d.position-size) {
+		return 0, errTruncated
+	}
+	d.position += size
+	return int(n), nil
+}
+
+func (d *valueDecoder) string() (string, error) {
+	n, err := d.length()
+	if err != nil {
+		return "", err
+	}
+	s := string(d.data[d.position : d.position+n])
+	d.position += n
+	return s, nil
+}
+
+// bytesArgument accepts binary data as a string or as an array of byte values
+func bytesArgument(value RuntimeValue) ([]byte, error) {
+	switch v := value.(type) {
+	case *StringValue:
+		return []byte(v.Value), nil
+	case *ArrayValue:
+		data := make([]byte, len(v.Elements))
+		for i, elem := range v.Elements {
+			num, ok := toFloat(elem)
+			if !ok || num < 0 || num > 255 {
+				return nil, fmt.Errorf("byte arrays may only contain numbers from 0 to 255")
+			}
+			data[i] = byte(num)
+		}
+		return data, nil
+	default:
+		return nil, fmt.Errorf("expected a string or an array of bytes, got %s", value.Type())
+	}
+}
@@ -0,0 +1,235 @@
+package interp
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A set holds distinct numbers, strings and booleans, with the same notion
+// of sameness as map keys: 1 and "1" differ, 1 and 1.0 do not. Elements keep
+// the order they were first added in.
+//
+//	s = set([3, 1, 3])           set {3, 1}
+//	s.add(2)                     s.has(2), s.remove(2), s.size()
+//	s.union(t)                   s.intersect(t), s.difference(t), new sets
+//	for x in s { }               s.toArray()
+
+// Set Value, an ordered collection of distinct values
+type SetValue struct {
+	elements []RuntimeValue
+	index    map[mapKey]int // position of each element in elements
+}
+
+func MakeSet() *SetValue {
+	return &SetValue{index: make(map[mapKey]int)}
+}
+
+func (s *SetValue) Type() ValueType { return SET_TYPE }
+func (s *SetValue) String() string {
+	elements := make([]string, len(s.elements))
+	for i, element := range s.elements {
+		elements[i] = element.String()
+	}
+	return "set {" + strings.Join(elements, ", ") + "}"
+}
+func (s *SetValue) IsTruthy() bool { return len(s.elements) > 0 }
+func (s *SetValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, fn := range SetPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return fn(s, args, env)
+		}))
+	}
+
+	return &prototypes
+}
+
+// Has reports whether value is in the set
+func (s *SetValue) Has(value RuntimeValue) (bool, error) {
+	k, err := keyOf(value)
+	if err != nil {
+		return false, err
+	}
+	_, found := s.index[k]
+	return found, nil
+}
+
+// Add puts value in the set, after the others when it is new
+func (s *SetValue) Add(value RuntimeValue) error {
+	k, err := keyOf(value)
+	if err != nil {
+		return err
+	}
+	if _, found := s.index[k]; !found {
+		s.index[k] = len(s.elements)
+		s.elements = append(s.elements, value)
+	}
+	return nil
+}
+
+// Remove takes value out of the set, reporting whether it was there
+func (s *SetValue) Remove(value RuntimeValue) (bool, error) {
+	k, err := keyOf(value)
+	if err != nil {
+		return false, err
+	}
+	i, found := s.index[k]
+	if !found {
+		return false, nil
+	}
+	delete(s.index, k)
+	s.elements = append(s.elements[:i:i], s.elements[i+1:]...)
+	for j := i; j < len(s.elements); j++ {
+		k, _ := keyOf(s.elements[j])
+		s.index[k] = j
+	}
+	return true, nil
+}
+
+// Elements lists the elements in insertion order, the slice must not be changed
+func (s *SetValue) Elements() []RuntimeValue { return s.elements }
+
+func (s *SetValue) Len() int { return len(s.elements) }
+
+// setFrom collects the items of an iterable (a set, array, range, string,
+// or the keys of an object or map) into a new set
+func setFrom(name string, value RuntimeValue) (*SetValue, error) {
+	switch value.(type) {
+	case *SetValue, *ArrayValue, *RangeValue, *StringValue, *ObjectValue, *MapValue:
+	default:
+		return nil, fmt.Errorf("%s expects a set or something iterable, got %s", name, value.Type())
+	}
+	s := MakeSet()
+	// Channels are refused above, iterating never blocks and needs no environment
+	err := iterateValue(value, nil, func(item RuntimeValue) (bool, error) {
+		return true, s.Add(item)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return s, nil
+}
+
+var SetPrototype = map[string]func(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error){
+	"add":        setAdd,
+	"has":        setHas,
+	"remove":     setRemove,
+	"size":       setSize,
+	"union":      setUnion,
+	"intersect":  setIntersect,
+	"difference": setDifference,
+	"toArray":    setToArray,
+}
+
+// setAdd is s.add(values...), returning the set
+func setAdd(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("set.add requires at least one argument")
+	}
+	for _, arg := range args {
+		if err := s.Add(arg); err != nil {
+			return nil, fmt.Errorf("set.add: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func setHas(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("set.has requires exactly one argument")
+	}
+	found, err := s.Has(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("set.has: %v", err)
+	}
+	return MakeBool(found), nil
+}
+
+// setRemove is s.remove(value), true when value was in the set
+func setRemove(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("set.remove requires exactly one argument")
+	}
+	found, err := s.Remove(args[0])
+	if err != nil {
+		return nil, fmt.Errorf("set.remove: %v", err)
+	}
+	return MakeBool(found), nil
+}
+
+func setSize(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeInt(int64(s.Len())), nil
+}
+
+// setOperand reads the other side of union, intersect and difference
+func setOperand(name string, args []RuntimeValue) (*SetValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("set.%s requires exactly one argument", name)
+	}
+	if other, ok := args[0].(*SetValue); ok {
+		return other, nil
+	}
+	return setFrom("set."+name, args[0])
+}
+
+// setUnion is a new set with the elements of s followed by the new ones of other
+func setUnion(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	other, err := setOperand("union", args)
+	if err != nil {
+		return nil, err
+	}
+	result, _ := setFrom("set.union", s)
+	for _, element := range other.elements {
+		result.Add(element)
+	}
+	return env.counted(result)
+}
+
+// setIntersect is a new set with the elements of s also in other
+func setIntersect(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	other, err := setOperand("intersect", args)
+	if err != nil {
+		return nil, err
+	}
+	result := MakeSet()
+	for _, element := range s.elements {
+		if found, _ := other.Has(element); found {
+			result.Add(element)
+		}
+	}
+	return env.counted(result)
+}
+
+// setDifference is a new set with the elements of s not in other
+func setDifference(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	other, err := setOperand("difference", args)
+	if err != nil {
+		return nil, err
+	}
+	result := MakeSet()
+	for _, element := range s.elements {
+		if found, _ := other.Has(element); !found {
+			result.Add(element)
+		}
+	}
+	return env.counted(result)
+}
+
+func setToArray(s *SetValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return env.counted(MakeArray(append([]RuntimeValue(nil), s.elements...)))
+}
+
+// setNative is set() or set(iterable)
+func setNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("set expects at most 1 argument, got %d", len(args))
+	}
+	if len(args) == 0 {
+		return env.counted(MakeSet())
+	}
+	s, err := setFrom("set", args[0])
+	if err != nil {
+		return nil, err
+	}
+	return env.counted(s)
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package interp
+
+import "errors"
+
+// Raw terminal handling is only implemented for linux, other platforms use plain line input
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func enableRawMode(fd int) (func(), error) {
+	return nil, errors.New("raw mode not supported on this platform")
+}
+
+func terminalSize(fd int) (int, int, error) {
+	return 0, 0, errors.New("terminal size not supported on this platform")
+}
@@ -0,0 +1,112 @@
+package interp
+
+import (
+	"fmt"
+	"time"
+)
+
+// waitForTimers is the event loop: after the script ends, the process stays
+// alive until every timeout of env has fired and every interval was cancelled
+func waitForTimers(env *Environment) {
+	blockingCall(env, env.tasks.timers.Wait)
+}
+
+// millisecondsArg reads a duration argument given in milliseconds
+func millisecondsArg(name string, value RuntimeValue) (time.Duration, error) {
+	ms, ok := toFloat(value)
+	if !ok || ms < 0 {
+		return 0, fmt.Errorf("time.%s: milliseconds must be a non-negative number", name)
+	}
+	return time.Duration(ms * float64(time.Millisecond)), nil
+}
+
+// startTimer runs fn as a task that holds the event loop of env open until it ends
+func startTimer(env *Environment, name string, fn func() (RuntimeValue, error)) *TaskValue {
+	timers := &env.tasks.timers
+	timers.Add(1)
+	return startTask(env, func() (RuntimeValue, error) {
+		defer timers.Done()
+		result, err := fn()
+		if err != nil && err != errCancelled {
+			fmt.Println(formatError(errorLabel(err), "in "+name+" callback: "+err.Error()))
+		}
+		return result, err
+	})
+}
+
+func timerArgs(name string, args []RuntimeValue) (time.Duration, RuntimeValue, error) {
+	if len(args) < 2 {
+		return 0, nil, fmt.Errorf("time.%s expects milliseconds, a function and its arguments", name)
+	}
+	delay, err := millisecondsArg(name, args[0])
+	if err != nil {
+		return 0, nil, err
+	}
+	if args[1].Type() != FUNCTION_TYPE && args[1].Type() != NATIVE_FN_TYPE {
+		return 0, nil, fmt.Errorf("time.%s: second argument must be a function", name)
+	}
+	return delay, args[1], nil
+}
+
+func createTimeObject() RuntimeValue {
+	timeProps := make(map[string]RuntimeValue)
+
+	// time.sleep(ms) pauses the current task, other tasks and timers keep running
+	timeProps["sleep"] = MakeNativeFunction("sleep", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("time.sleep expects 1 argument, got %d", len(args))
+		}
+		delay, err := millisecondsArg("sleep", args[0])
+		if err != nil {
+			return nil, err
+		}
+		if err := sleepFor(delay, env); err != nil {
+			return nil, err
+		}
+		return MakeVoid(), nil
+	})
+
+	// time.timeout(ms, fn, ...args) calls fn once after ms, the returned task
+	// resolves to its result, or to undef when cancelled first
+	timeProps["timeout"] = MakeNativeFunction("timeout", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		delay, fn, err := timerArgs("timeout", args)
+		if err != nil {
+			return nil, err
+		}
+		fnArgs := args[2:]
+		return startTimer(env, "timeout", func() (RuntimeValue, error) {
+			if err := sleepFor(delay, env); err != nil {
+				return nil, err
+			}
+			return callValue(fn, fnArgs, env)
+		}), nil
+	})
+
+	// time.interval(ms, fn, ...args) calls fn every ms until the returned task
+	// is cancelled or fn fails
+	timeProps["interval"] = MakeNativeFunction("interval", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		delay, fn, err := timerArgs("interval", args)
+		if err != nil {
+			return nil, err
+		}
+		if delay <= 0 {
+			return nil, fmt.Errorf("time.interval: milliseconds must be positive")
+		}
+		fnArgs := args[2:]
+		return startTimer(env, "interval", func() (RuntimeValue, error) {
+			next := time.Now()
+			for {
+				// Ticks are scheduled from the start, slow callbacks do not drift the interval
+				next = next.Add(delay)
+				if err := sleepFor(time.Until(next), env); err != nil {
+					return nil, err
+				}
+				if _, err := callValue(fn, fnArgs, env); err != nil {
+					return nil, err
+				}
+			}
+		}), nil
+	})
+
+	return MakeObject(timeProps)
+}
@@ -0,0 +1,663 @@
+package interp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type TokenType int
+
+const (
+	// Literals
+	IDENTIFIER TokenType = iota
+	STRING
+	RAW_STRING      // `text`, kept exactly as written
+	TEMPLATE_STRING // f`text {expr}`, raw text with interpolation
+	INT
+	FLOAT
+	BIGINT // 123n
+	BOOLEAN
+	UNDEFINED
+
+	// Keywords
+	FN
+	LAMBDA
+	IF
+	UNLESS // postfix only, return x unless ready
+	ELSE
+	RETURN
+	TYPEOF
+	FOR
+	WHILE
+	DO     // do { } while cond
+	REPEAT // repeat { } until cond
+	UNTIL
+	DEBUG
+	USE
+	OUT
+	MATCH
+	IN
+	SPAWN
+	ASYNC
+	AWAIT
+
+	// Operators
+	BINARY_OPERATOR
+	EQUALS
+	COMPOUND_ASSIGN // x += 1, x <<= 2
+	EQUALITY_OP
+	INEQUALITY_OP
+	SMALLER_THAN
+	GREATER_THAN
+	SMALLER_OR_EQUAL
+	GREATER_OR_EQUAL
+	AND
+	OR
+	NEGATION_OP
+	INCREMENT
+	DECREMENT
+	ARROW
+	RANGE
+	RANGE_INCLUSIVE
+
+	// Punctuation
+	COMMA
+	DOT
+	ELLIPSIS
+	COLON
+	SEMICOLON
+	OPEN_PAREN
+	CLOSE_PAREN
+	OPEN_BRACE
+	CLOSE_BRACE
+	OPEN_BRACKET
+	CLOSE_BRACKET
+	TERNARY
+	OPTIONAL_DOT // ?.
+
+	// Special
+	COMMENT
+	DOC_COMMENT // ## lines documenting the function declared after them
+	NEWLINE
+	EOF
+)
+
+var keywords = map[string]TokenType{
+	"fn":     FN,
+	"lambda": LAMBDA,
+	"if":     IF,
+	"unless": UNLESS,
+	"else":   ELSE,
+	"return": RETURN,
+	"typeof": TYPEOF,
+	"for":    FOR,
+	"while":  WHILE,
+	"do":     DO,
+	"repeat": REPEAT,
+	"until":  UNTIL,
+	"debug":  DEBUG,
+	"use":    USE,
+	"out":    OUT,
+	"match":  MATCH,
+	"in":     IN,
+	"spawn":  SPAWN,
+	"async":  ASYNC,
+	"await":  AWAIT,
+	"true":   BOOLEAN,
+	"false":  BOOLEAN,
+	"undef":  UNDEFINED,
+}
+
+type Position struct {
+	Line   int
+	Column int
+	Index  int
+}
+
+type Token struct {
+	Type     TokenType
+	Value    string
+	Position Position
+}
+
+type Tokenizer struct {
+	input    []rune
+	position int
+	line     int
+	index    int
+
+	// keepComments emits COMMENT tokens instead of skipping them (used by the formatter)
+	keepComments bool
+}
+
+func NewTokenizer(input string) *Tokenizer {
+	return &Tokenizer{
+		input:    []rune(input),
+		position: 0,
+		line:     0,
+		index:    0,
+	}
+}
+
+func (t *Tokenizer) Tokenize() ([]Token, error) {
+	var tokens []Token
+
+	for t.position < len(t.input) {
+		char := t.current()
+
+		switch {
+		case char == '\n':
+			tokens = append(tokens, Token{NEWLINE, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+			t.line++
+			t.index = 0
+
+		case unicode.IsSpace(char):
+			t.advance()
+
+		case char == '#':
+			// Skip comments
+			startPos := Position{t.line, t.index, t.position}
+			var comment strings.Builder
+			for t.position < len(t.input) && t.current() != '\n' {
+				comment.WriteRune(t.current())
+				t.advance()
+			}
+			text := comment.String()
+			if t.keepComments {
+				tokens = append(tokens, Token{COMMENT, text, startPos})
+			} else if strings.HasPrefix(text, "##") && !strings.HasPrefix(text, "###") {
+				tokens = append(tokens, Token{DOC_COMMENT, text, startPos})
+			}
+
+		case char == '/' && t.peek() == '*':
+			startPos := Position{t.line, t.index, t.position}
+			comment, err := t.readBlockComment()
+			if err != nil {
+				return nil, err
+			}
+			if t.keepComments {
+				tokens = append(tokens, Token{COMMENT, comment, startPos})
+			}
+
+		case char == '"' || char == '\'':
+			startPos := Position{t.line, t.index, t.position}
+			str, err := t.readString(char)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{STRING, str, startPos})
+
+		case char == '`':
+			startPos := Position{t.line, t.index, t.position}
+			str, err := t.readRawString()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{RAW_STRING, str, startPos})
+
+		case char == 'f' && t.peek() == '`':
+			startPos := Position{t.line, t.index, t.position}
+			t.advance() // the f of f`...`
+			str, err := t.readRawString()
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{TEMPLATE_STRING, str, startPos})
+
+		case isDecimalDigit(char):
+			startPos := Position{t.line, t.index, t.position}
+			num, isFloat, err := t.readNumber()
+			if err != nil {
+				return nil, err
+			}
+			tokenType := INT
+			if isFloat {
+				tokenType = FLOAT
+			} else if t.current() == 'n' && !unicode.IsLetter(t.peek()) && !unicode.IsDigit(t.peek()) && t.peek() != '_' {
+				t.advance() // the n of 123n
+				tokenType = BIGINT
+			}
+			tokens = append(tokens, Token{tokenType, num, startPos})
+
+		case unicode.IsLetter(char) || char == '_':
+			startPos := Position{t.line, t.index, t.position}
+			identifier := t.readIdentifier()
+			tokenType := IDENTIFIER
+			if kw, exists := keywords[identifier]; exists {
+				tokenType = kw
+			}
+			tokens = append(tokens, Token{tokenType, identifier, startPos})
+
+		case char == '(':
+			tokens = append(tokens, Token{OPEN_PAREN, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == ')':
+			tokens = append(tokens, Token{CLOSE_PAREN, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == '{':
+			tokens = append(tokens, Token{OPEN_BRACE, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == '}':
+			tokens = append(tokens, Token{CLOSE_BRACE, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == '[':
+			tokens = append(tokens, Token{OPEN_BRACKET, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == ']':
+			tokens = append(tokens, Token{CLOSE_BRACKET, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == ',':
+			tokens = append(tokens, Token{COMMA, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == '.':
+			if t.peek() == '.' && t.position+2 < len(t.input) && t.input[t.position+2] == '.' {
+				tokens = append(tokens, Token{ELLIPSIS, "...", Position{t.line, t.index, t.position}})
+				t.advance()
+				t.advance()
+				t.advance()
+				continue
+			}
+			if t.peek() == '.' {
+				// Ranges: start..end and start..=end
+				startPos := Position{t.line, t.index, t.position}
+				t.advance()
+				t.advance()
+				if t.current() == '=' {
+					t.advance()
+					tokens = append(tokens, Token{RANGE_INCLUSIVE, "..=", startPos})
+				} else {
+					tokens = append(tokens, Token{RANGE, "..", startPos})
+				}
+				continue
+			}
+			tokens = append(tokens, Token{DOT, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == ':':
+			tokens = append(tokens, Token{COLON, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == ';':
+			tokens = append(tokens, Token{SEMICOLON, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		case char == '?' && t.peek() == '.' && !unicode.IsDigit(t.peekAt(2)):
+			tokens = append(tokens, Token{OPTIONAL_DOT, "?.", Position{t.line, t.index, t.position}})
+			t.advance()
+			t.advance()
+
+		case char == '?':
+			tokens = append(tokens, Token{TERNARY, string(char), Position{t.line, t.index, t.position}})
+			t.advance()
+
+		default:
+			if t.isOperator(char) {
+				startPos := Position{t.line, t.index, t.position}
+				op := t.readOperator()
+				tokens = append(tokens, Token{t.getOperatorType(op), op, startPos})
+			} else {
+				return nil, t.errorAt(codeUnexpectedChar, Position{t.line, t.index, t.position}, 1, "unexpected character '%c'", char)
+			}
+		}
+	}
+
+	tokens = append(tokens, Token{EOF, "", Position{t.line, t.index, t.position}})
+	return tokens, nil
+}
+
+func (t *Tokenizer) current() rune {
+	if t.position >= len(t.input) {
+		return 0
+	}
+	return t.input[t.position]
+}
+
+func (t *Tokenizer) peek() rune {
+	if t.position+1 >= len(t.input) {
+		return 0
+	}
+	return t.input[t.position+1]
+}
+
+// peekAt returns the character offset places ahead, or 0 past the end
+func (t *Tokenizer) peekAt(offset int) rune {
+	if t.position+offset >= len(t.input) {
+		return 0
+	}
+	return t.input[t.position+offset]
+}
+
+func (t *Tokenizer) advance() {
+	if t.position < len(t.input) {
+		t.position++
+		t.index++
+	}
+}
+
+// readString reads a quoted string and decodes its escapes: \n \t \r \\ \"
+// \', \xNN for the character U+00NN, \uXXXX and \u{X...} for any code point.
+// Any other backslash is an error, so a typo does not go unnoticed.
+func (t *Tokenizer) readString(quote rune) (string, error) {
+	start := Position{t.line, t.index, t.position}
+	t.advance() // Skip opening quote
+	var result strings.Builder
+
+	for t.position < len(t.input) {
+		char := t.current()
+
+		if char == '\\' {
+			escape := Position{t.line, t.index, t.position}
+			t.advance()
+			decoded, err := t.readEscape()
+			if err != nil {
+				syntax := t.errorAt(codeInvalidEscape, escape, t.position-escape.Index, "%v in string", err)
+				syntax.Hints = []string{`write \\ for a backslash, or use a backtick string to keep backslashes as they are`}
+				return "", syntax
+			}
+			result.WriteRune(decoded)
+			continue
+		} else if char == quote {
+			t.advance() // Skip closing quote
+			return result.String(), nil
+		} else {
+			result.WriteRune(char)
+		}
+
+		t.advance()
+		if char == '\n' {
+			t.line++
+			t.index = 0
+		}
+	}
+
+	return "", t.errorAt(codeUnterminated, start, 1, "unterminated string, expected a closing %c", quote)
+}
+
+// readEscape decodes the escape after a backslash
+func (t *Tokenizer) readEscape() (rune, error) {
+	if t.position >= len(t.input) {
+		return 0, fmt.Errorf("unfinished escape")
+	}
+	char := t.current()
+	t.advance()
+	switch char {
+	case 'n':
+		return '\n', nil
+	case 't':
+		return '\t', nil
+	case 'r':
+		return '\r', nil
+	case '\\', '"', '\'':
+		return char, nil
+	case 'x':
+		return t.readHexEscape("\\x", 2)
+	case 'u':
+		if t.current() != '{' {
+			return t.readHexEscape("\\u", 4)
+		}
+		t.advance()
+		code, err := t.readHexEscape("\\u{", -1)
+		if err != nil {
+			return 0, err
+		}
+		if t.current() != '}' {
+			return 0, fmt.Errorf("unclosed '\\u{' escape")
+		}
+		t.advance()
+		return code, nil
+	}
+	return 0, fmt.Errorf("invalid escape '\\%c'", char)
+}
+
+// readHexEscape reads the code point of an escape, exactly size hex digits
+// or, when size is -1, from 1 to 6 of them
+func (t *Tokenizer) readHexEscape(escape string, size int) (rune, error) {
+	start := t.position
+	for t.position < len(t.input) && isHexDigit(t.current()) && (size < 0 && t.position-start < 6 || t.position-start < size) {
+		t.advance()
+	}
+	digits := string(t.input[start:t.position])
+	if size < 0 && digits == "" {
+		return 0, fmt.Errorf("'%s' escape needs 1 to 6 hex digits", escape)
+	}
+	if size > 0 && len(digits) != size {
+		return 0, fmt.Errorf("'%s' escape needs %d hex digits", escape, size)
+	}
+	code, _ := strconv.ParseUint(digits, 16, 32)
+	if !utf8.ValidRune(rune(code)) {
+		return 0, fmt.Errorf("U+%X in '%s' escape is not a valid code point", code, escape)
+	}
+	return rune(code), nil
+}
+
+// readBlockComment reads a /* ... */ comment, which may span lines and
+// contain other block comments
+func (t *Tokenizer) readBlockComment() (string, error) {
+	start := Position{t.line, t.index, t.position}
+	depth := 0
+	for t.position < len(t.input) {
+		switch {
+		case t.matches("/*"):
+			depth++
+			t.advance()
+			t.advance()
+		case t.matches("*/"):
+			depth--
+			t.advance()
+			t.advance()
+			if depth == 0 {
+				return string(t.input[start.Index:t.position]), nil
+			}
+		case t.current() == '\n':
+			t.advance()
+			t.line++
+			t.index = 0
+		default:
+			t.advance()
+		}
+	}
+	err := t.errorAt(codeUnterminated, start, 2, "unterminated block comment, expected */")
+	err.Hints = []string{"block comments nest, every /* inside one needs its own */"}
+	return "", err
+}
+
+// readRawString reads a backtick string, which may span lines and has no escapes
+func (t *Tokenizer) readRawString() (string, error) {
+	start := Position{t.line, t.index, t.position}
+	t.advance() // Skip opening backtick
+	var result strings.Builder
+
+	for t.position < len(t.input) {
+		char := t.current()
+		t.advance()
+		switch char {
+		case '`':
+			return result.String(), nil
+		case '\n':
+			t.line++
+			t.index = 0
+		}
+		result.WriteRune(char)
+	}
+
+	return "", t.errorAt(codeUnterminated, start, 1, "unterminated raw string, expected a closing `")
+}
+
+// readNumber reads a number literal as written: decimal digits with an
+// optional fraction and exponent (1.5, 1e-9, 2.5E+3), or an integer in hex,
+// octal or binary (0xFF, 0o777, 0b1010). Digits may be grouped with
+// underscores, 1_000_000, and the parser removes them.
+func (t *Tokenizer) readNumber() (string, bool, error) {
+	start := Position{t.line, t.index, t.position}
+	digits, prefixed := numberBase(t.current(), t.peek())
+	if prefixed {
+		t.advance()
+		t.advance()
+	}
+	t.readDigits(digits)
+	isFloat := false
+
+	if !prefixed {
+		if t.current() == '.' && t.peek() != '.' {
+			isFloat = true // a range like 1..5 is left alone
+			t.advance()
+			t.readDigits(digits)
+		}
+		sign := 1
+		if t.peek() == '+' || t.peek() == '-' {
+			sign = 2
+		}
+		if (t.current() == 'e' || t.current() == 'E') && isDecimalDigit(t.peekAt(sign)) {
+			isFloat = true
+			for range sign {
+				t.advance()
+			}
+			t.readDigits(digits)
+		}
+	}
+
+	literal := string(t.input[start.Index:t.position])
+	invalid := func(reason string) (string, bool, error) {
+		return "", false, t.errorAt(codeInvalidNumber, start, utf8.RuneCountInString(literal), "invalid number literal '%s', %s", literal, reason)
+	}
+	if prefixed && len(literal) == 2 {
+		return invalid("expected digits after the prefix")
+	}
+	if prefixed && (isDecimalDigit(t.current()) || unicode.IsLetter(t.current()) && t.current() != 'n') {
+		literal += string(t.current())
+		return invalid("digit out of range")
+	}
+	for i, char := range literal {
+		if char == '_' && (i == 0 || !digits(rune(literal[i-1])) || i+1 == len(literal) || !digits(rune(literal[i+1]))) {
+			return invalid("'_' must be between digits")
+		}
+	}
+	return literal, isFloat, nil
+}
+
+// numberBase picks the digits of a literal starting with first and second,
+// prefixed when it starts with 0x, 0o or 0b
+func numberBase(first, second rune) (func(rune) bool, bool) {
+	if first != '0' {
+		return isDecimalDigit, false
+	}
+	switch second {
+	case 'x', 'X':
+		return isHexDigit, true
+	case 'o', 'O':
+		return func(char rune) bool { return char >= '0' && char <= '7' }, true
+	case 'b', 'B':
+		return func(char rune) bool { return char == '0' || char == '1' }, true
+	}
+	return isDecimalDigit, false
+}
+
+// readDigits reads digits of one base along with the underscores between them
+func (t *Tokenizer) readDigits(digits func(rune) bool) {
+	for t.position < len(t.input) && (digits(t.current()) || t.current() == '_') {
+		t.advance()
+	}
+}
+
+func isDecimalDigit(char rune) bool {
+	return char >= '0' && char <= '9'
+}
+
+func isHexDigit(char rune) bool {
+	return isDecimalDigit(char) || char >= 'a' && char <= 'f' || char >= 'A' && char <= 'F'
+}
+
+func (t *Tokenizer) readIdentifier() string {
+	var result strings.Builder
+
+	for t.position < len(t.input) && (unicode.IsLetter(t.current()) || unicode.IsDigit(t.current()) || t.current() == '_') {
+		result.WriteRune(t.current())
+		t.advance()
+	}
+
+	return result.String()
+}
+
+func (t *Tokenizer) isOperator(char rune) bool {
+	operators := "+-*/%=<>!&|^~"
+	return strings.ContainsRune(operators, char)
+}
+
+// multiCharOperators are the operators longer than one character, longest first
+var multiCharOperators = []string{
+	"<<=", ">>=", "**=", "//=",
+	"==", "!=", "<=", ">=", "&&", "||", "++", "--", "=>", "**", "//", "<<", ">>",
+	"+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=",
+}
+
+// readOperator reads the longest operator at the current position, so
+// "x=-1" is x, =, -1 rather than an unknown "=-"
+func (t *Tokenizer) readOperator() string {
+	for _, op := range multiCharOperators {
+		if t.matches(op) {
+			for range op {
+				t.advance()
+			}
+			return op
+		}
+	}
+	op := string(t.current())
+	t.advance()
+	return op
+}
+
+// matches reports whether the input continues with s
+func (t *Tokenizer) matches(s string) bool {
+	for i, r := range []rune(s) {
+		if t.peekAt(i) != r {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Tokenizer) getOperatorType(op string) TokenType {
+	switch op {
+	case "=":
+		return EQUALS
+	case "==":
+		return EQUALITY_OP
+	case "!=":
+		return INEQUALITY_OP
+	case "<":
+		return SMALLER_THAN
+	case ">":
+		return GREATER_THAN
+	case "<=":
+		return SMALLER_OR_EQUAL
+	case ">=":
+		return GREATER_OR_EQUAL
+	case "&&":
+		return AND
+	case "||":
+		return OR
+	case "!":
+		return NEGATION_OP
+	case "++":
+		return INCREMENT
+	case "--":
+		return DECREMENT
+	case "=>":
+		return ARROW
+	case "+=", "-=", "*=", "/=", "//=", "%=", "**=", "&=", "|=", "^=", "<<=", ">>=":
+		return COMPOUND_ASSIGN
+	default:
+		return BINARY_OPERATOR
+	}
+}
@@ -0,0 +1,57 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// --trace prints every statement to stderr as it runs, where it is in the
+// source and what it evaluated to, indented by how deep in calls it runs:
+//
+//	   5:1   total = add(1, 2) => 3
+//	   2:5     return a + b => 3
+//
+// Statements with a body (if, loops, match, function declarations) are
+// printed before they run, so their body follows them, the others once
+// they are done, after the statements of the calls they make.
+
+// traceWidth is how many characters of a statement or value a trace line shows
+const traceWidth = 60
+
+// tracesBefore reports whether stmt is traced before it runs
+func tracesBefore(stmt Statement) bool {
+	switch stmt.(type) {
+	case *IfStatement, *WhileStatement, *DoWhileStatement, *ForStatement, *ForInStatement, *MatchExpr, *FunctionDeclaration:
+		return true
+	}
+	return false
+}
+
+// traceStatement prints stmt with its result, nil when it has not run yet
+func traceStatement(stmt Statement, env *Environment, result RuntimeValue) {
+	if _, isComment := stmt.(*Comment); isComment {
+		return
+	}
+
+	location := "    "
+	if fn, ok := stmt.(*FunctionDeclaration); ok && fn.Name != "" {
+		location = fmt.Sprintf("%4d:%-3d", fn.Position.Line+1, fn.Position.Column+1)
+	} else if position, ok := nodePosition(stmt); ok {
+		location = fmt.Sprintf("%4d:%-3d", position.Line+1, position.Column+1)
+	}
+	text, _, _ := strings.Cut(strings.TrimSpace(NewPrinter().Print(stmt)), "\n")
+	line := gray(location) + " " + strings.Repeat("  ", env.limits.depth) + truncateText(text, traceWidth)
+	if returned, ok := result.(*ReturnValue); ok {
+		result = returned.Value
+	}
+	if result != nil && result.Type() != VOID_TYPE {
+		value := strings.ReplaceAll(stripColor(colorizeValue(result, true, false)), "\n", " ")
+		line += gray(" => ") + truncateText(value, traceWidth)
+	}
+
+	if !colorsEnabled(env) {
+		line = stripColor(line)
+	}
+	fmt.Fprintln(os.Stderr, line)
+}
@@ -0,0 +1,31 @@
+package interp
+
+import "fmt"
+
+// Truthiness decides how if, while, for, ?: and ! read a value. By default:
+//
+//	falsy   false, 0, NaN, "", [], {}, an empty map, set or range, null, undef
+//	truthy  everything else, including functions, channels and tasks
+//
+// With --truthiness=strict (the StrictTruthiness option), conditions must be
+// booleans and anything else is an error. bool(x) converts explicitly with
+// the default rules.
+
+// conditionValue reads value as a condition in env
+func conditionValue(value RuntimeValue, env *Environment) (bool, error) {
+	if boolean, ok := value.(*BooleanValue); ok {
+		return boolean.Value, nil
+	}
+	if env.options.StrictTruthiness {
+		return false, fmt.Errorf("condition must be a boolean, got %s; use bool(x) to convert (--truthiness=strict)", value.Type())
+	}
+	return value.IsTruthy(), nil
+}
+
+// boolNative is the explicit conversion bool(x)
+func boolNative(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("bool expects 1 argument, got %d", len(args))
+	}
+	return MakeBool(args[0].IsTruthy()), nil
+}
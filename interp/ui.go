@@ -0,0 +1,384 @@
+package interp
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Spinners and progress bars draw status lines at the bottom of the
+// terminal and redraw them in place. io.print goes through the same board:
+// it erases the status lines, prints, and draws them again below, so output
+// and status never end up on the same line. When stdout is not a terminal
+// nothing is animated and only the final messages are printed.
+
+// statusWidget is a spinner or progress bar shown on the board
+type statusWidget interface {
+	render(width int) string
+}
+
+// statusBoard owns the status lines currently on screen
+type statusBoard struct {
+	mu      sync.Mutex
+	widgets []statusWidget
+	drawn   int // status lines on screen, the cursor sits at the end of the last one
+	ticker  *time.Ticker
+	tty     bool
+	paused  int // io.input is waiting for an answer, nothing is drawn
+}
+
+var status = &statusBoard{tty: isTerminal(int(os.Stdout.Fd()))}
+
+// spinnerFrames are shown in turn while a spinner runs
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+const spinnerInterval = 80 * time.Millisecond
+
+// clear erases the status lines, the lock must be held
+func (b *statusBoard) clear() {
+	if b.drawn == 0 {
+		return
+	}
+	out := "\r\033[K" + strings.Repeat("\033[1A\033[K", b.drawn-1)
+	os.Stdout.WriteString(out)
+	b.drawn = 0
+}
+
+// draw writes the status lines below the cursor, the lock must be held
+func (b *statusBoard) draw() {
+	if !b.tty || b.paused > 0 || len(b.widgets) == 0 {
+		return
+	}
+	width, _, err := terminalSize(int(os.Stdout.Fd()))
+	if err != nil || width < 10 {
+		width = 80
+	}
+	lines := make([]string, len(b.widgets))
+	for i, widget := range b.widgets {
+		lines[i] = widget.render(width - 1) // a full line would wrap
+	}
+	os.Stdout.WriteString(strings.Join(lines, "\n"))
+	b.drawn = len(lines)
+}
+
+// update changes a widget while the animation cannot draw it, then redraws
+func (b *statusBoard) update(change func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	change()
+	b.clear()
+	b.draw()
+}
+
+// println prints a line of output above the status lines
+func (b *statusBoard) println(line string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.clear()
+	fmt.Println(line)
+	b.draw()
+}
+
+// pause takes the status lines off the screen until resume
+func (b *statusBoard) pause() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.paused++
+	b.clear()
+}
+
+func (b *statusBoard) resume() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.paused--
+	b.draw()
+}
+
+// add shows a widget below the others, starting the animation with the first one
+func (b *statusBoard) add(widget statusWidget) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.widgets = append(b.widgets, widget)
+	if b.tty && b.ticker == nil {
+		b.ticker = time.NewTicker(spinnerInterval)
+		go b.animate(b.ticker)
+	}
+	b.clear()
+	b.draw()
+}
+
+// remove takes a widget off the board, printing its final line in its place
+func (b *statusBoard) remove(widget statusWidget, final string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, w := range b.widgets {
+		if w == widget {
+			b.widgets = append(b.widgets[:i], b.widgets[i+1:]...)
+			break
+		}
+	}
+	if len(b.widgets) == 0 && b.ticker != nil {
+		b.ticker.Stop()
+		b.ticker = nil
+	}
+	b.clear()
+	if final != "" {
+		fmt.Println(final)
+	}
+	b.draw()
+}
+
+func (b *statusBoard) animate(ticker *time.Ticker) {
+	for range ticker.C {
+		b.mu.Lock()
+		if b.ticker != ticker {
+			b.mu.Unlock()
+			return
+		}
+		for _, widget := range b.widgets {
+			if spinner, ok := widget.(*spinnerWidget); ok {
+				spinner.frame++
+			}
+		}
+		b.clear()
+		b.draw()
+		b.mu.Unlock()
+	}
+}
+
+// stopStatusLines ends every widget still running, leaving its last state
+// on screen, before the program prints an error or exits
+func stopStatusLines() {
+	status.mu.Lock()
+	defer status.mu.Unlock()
+	if status.ticker != nil {
+		status.ticker.Stop()
+		status.ticker = nil
+	}
+	if status.drawn > 0 {
+		fmt.Println()
+		status.drawn = 0
+	}
+	status.widgets = nil
+}
+
+// printOutput prints a line of script output, keeping the status lines below it
+func printOutput(line string) {
+	status.println(line)
+}
+
+// truncateText shortens text to width characters, ending it with … when cut
+func truncateText(text string, width int) string {
+	runes := []rune(text)
+	if width <= 0 {
+		return ""
+	}
+	if len(runes) <= width {
+		return text
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+type spinnerWidget struct {
+	message string
+	frame   int
+	color   bool
+}
+
+func (s *spinnerWidget) render(width int) string {
+	frame := spinnerFrames[s.frame%len(spinnerFrames)]
+	if s.color {
+		frame = cyan(frame)
+	}
+	return frame + " " + truncateText(s.message, width-2)
+}
+
+type progressWidget struct {
+	message string
+	current int
+	total   int
+	color   bool
+}
+
+const progressBarWidth = 30
+
+func (p *progressWidget) render(width int) string {
+	ratio := 1.0
+	if p.total > 0 {
+		ratio = min(float64(p.current)/float64(p.total), 1)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("█", filled)
+	rest := strings.Repeat("░", progressBarWidth-filled)
+	if p.color {
+		bar, rest = green(bar), gray(rest)
+	}
+	counts := fmt.Sprintf(" %d/%d %3d%%", p.current, p.total, int(ratio*100))
+	line := bar + rest + counts
+	if p.message != "" {
+		// The message gives way first when the terminal is narrow
+		line = truncateText(p.message, width-progressBarWidth-len(counts)-1) + " " + line
+	}
+	return line
+}
+
+// messageArg reads the optional message argument of name
+func messageArg(name string, args []RuntimeValue, index int) (string, error) {
+	if len(args) <= index {
+		return "", nil
+	}
+	message, ok := args[index].(*StringValue)
+	if !ok {
+		return "", fmt.Errorf("%s: message must be a string", name)
+	}
+	return message.Value, nil
+}
+
+// makeSpinner is the object returned by ui.spinner(message)
+func makeSpinner(spinner *spinnerWidget) RuntimeValue {
+	stopped := false
+	finish := func(name, symbol string, args []RuntimeValue) (RuntimeValue, error) {
+		message, err := messageArg("spinner."+name, args, 0)
+		if err != nil {
+			return nil, err
+		}
+		if stopped {
+			return MakeVoid(), nil
+		}
+		stopped = true
+		if message == "" && symbol != "" {
+			message = spinner.message
+		}
+		if symbol != "" {
+			message = symbol + " " + message
+		}
+		status.remove(spinner, message)
+		return MakeVoid(), nil
+	}
+
+	return MakeObject(map[string]RuntimeValue{
+		// spinner.update(message) changes the text next to the spinner
+		"update": MakeNativeFunction("update", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			message, err := messageArg("spinner.update", args, 0)
+			if err != nil {
+				return nil, err
+			}
+			status.update(func() { spinner.message = message })
+			return MakeVoid(), nil
+		}),
+		// spinner.stop(message) removes the spinner, printing message if given
+		"stop": MakeNativeFunction("stop", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return finish("stop", "", args)
+		}),
+		// spinner.succeed(message) and spinner.fail(message) leave a ✔ or ✖ line
+		"succeed": MakeNativeFunction("succeed", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			symbol := "✔"
+			if spinner.color {
+				symbol = green(symbol)
+			}
+			return finish("succeed", symbol, args)
+		}),
+		"fail": MakeNativeFunction("fail", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			symbol := "✖"
+			if spinner.color {
+				symbol = red(symbol)
+			}
+			return finish("fail", symbol, args)
+		}),
+	})
+}
+
+// makeProgress is the object returned by ui.progress(total, message)
+func makeProgress(progress *progressWidget) RuntimeValue {
+	finished := false
+	setCurrent := func(n int64) (RuntimeValue, error) {
+		if !finished {
+			status.update(func() { progress.current = int(max(0, min(n, int64(progress.total)))) })
+		}
+		return MakeVoid(), nil
+	}
+
+	return MakeObject(map[string]RuntimeValue{
+		// progress.tick(n) advances by n steps, 1 by default
+		"tick": MakeNativeFunction("tick", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			step := int64(1)
+			if len(args) > 0 {
+				n, ok := toInt(args[0])
+				if !ok {
+					return nil, fmt.Errorf("progress.tick: steps must be an integer")
+				}
+				step = n
+			}
+			return setCurrent(int64(progress.current) + step)
+		}),
+		// progress.set(n) jumps to step n
+		"set": MakeNativeFunction("set", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if len(args) != 1 {
+				return nil, fmt.Errorf("progress.set expects 1 argument, got %d", len(args))
+			}
+			n, ok := toInt(args[0])
+			if !ok {
+				return nil, fmt.Errorf("progress.set: step must be an integer")
+			}
+			return setCurrent(n)
+		}),
+		// progress.update(message) changes the text before the bar
+		"update": MakeNativeFunction("update", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			message, err := messageArg("progress.update", args, 0)
+			if err != nil {
+				return nil, err
+			}
+			status.update(func() { progress.message = message })
+			return MakeVoid(), nil
+		}),
+		// progress.done() leaves the bar as it is and moves on
+		"done": MakeNativeFunction("done", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			if !finished {
+				finished = true
+				status.remove(progress, progress.render(80))
+			}
+			return MakeVoid(), nil
+		}),
+		"total": MakeInt(int64(progress.total)),
+	})
+}
+
+func createUIObject() RuntimeValue {
+	uiProps := make(map[string]RuntimeValue)
+
+	// ui.spinner(message) shows an animated spinner until stop, succeed or fail
+	uiProps["spinner"] = MakeNativeFunction("spinner", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) > 1 {
+			return nil, fmt.Errorf("ui.spinner expects at most 1 argument, got %d", len(args))
+		}
+		message, err := messageArg("ui.spinner", args, 0)
+		if err != nil {
+			return nil, err
+		}
+		spinner := &spinnerWidget{message: message, color: colorsEnabled(env)}
+		status.add(spinner)
+		return makeSpinner(spinner), nil
+	})
+
+	// ui.progress(total, message) shows a bar counting steps up to total
+	uiProps["progress"] = MakeNativeFunction("progress", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 {
+			return nil, fmt.Errorf("ui.progress expects a total and an optional message")
+		}
+		total, ok := toInt(args[0])
+		if !ok || total < 0 {
+			return nil, fmt.Errorf("ui.progress: total must be a non-negative integer")
+		}
+		message, err := messageArg("ui.progress", args, 1)
+		if err != nil {
+			return nil, err
+		}
+		progress := &progressWidget{message: message, total: int(total), color: colorsEnabled(env)}
+		status.add(progress)
+		return makeProgress(progress), nil
+	})
+
+	return MakeObject(uiProps)
+}
@@ -0,0 +1,321 @@
+package interp
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Normalization, case folding and collation of text, so "é" written as one
+// character or as "e" plus an accent is the same string, and sorting puts
+// "éclair" next to "eclair" rather than after "zebra". The data comes from
+// unicode_tables.go, generated from the Unicode Character Database by
+// tools/unicodetables.
+
+//go:generate go run ../tools/unicodetables -o unicode_tables.go
+
+// Hangul syllables decompose algorithmically rather than through the tables
+const (
+	hangulBase   = 0xAC00
+	hangulLBase  = 0x1100
+	hangulVBase  = 0x1161
+	hangulTBase  = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulNCount = hangulVCount * hangulTCount
+	hangulCount  = hangulLCount * hangulNCount
+)
+
+// combiningClass is the canonical combining class of r, 0 for starters
+func combiningClass(r rune) uint8 {
+	i := sort.Search(len(combiningClasses), func(i int) bool { return combiningClasses[i].hi >= r })
+	if i < len(combiningClasses) && combiningClasses[i].lo <= r {
+		return combiningClasses[i].class
+	}
+	return 0
+}
+
+// decomposeRune appends the full decomposition of r, compatibility mappings included when compat is set
+func decomposeRune(out []rune, r rune, compat bool) []rune {
+	if s := r - hangulBase; s >= 0 && s < hangulCount {
+		out = append(out, hangulLBase+s/hangulNCount, hangulVBase+(s%hangulNCount)/hangulTCount)
+		if t := s % hangulTCount; t != 0 {
+			out = append(out, hangulTBase+t)
+		}
+		return out
+	}
+	mapping, ok := canonicalDecompositions[r]
+	if !ok && compat {
+		mapping, ok = compatibilityDecompositions[r]
+	}
+	if !ok {
+		return append(out, r)
+	}
+	for _, c := range mapping {
+		out = decomposeRune(out, c, compat)
+	}
+	return out
+}
+
+// decompose fully decomposes s and puts combining marks in canonical order
+func decompose(s string, compat bool) []rune {
+	var runes []rune
+	for _, r := range s {
+		runes = decomposeRune(runes, r, compat)
+	}
+	// A stable sort of each run of marks by class, the runs are short
+	for i := 1; i < len(runes); i++ {
+		class := combiningClass(runes[i])
+		if class == 0 {
+			continue
+		}
+		for j := i; j > 0; j-- {
+			previous := combiningClass(runes[j-1])
+			if previous == 0 || previous <= class {
+				break
+			}
+			runes[j-1], runes[j] = runes[j], runes[j-1]
+		}
+	}
+	return runes
+}
+
+// composePair is the primary composite of two characters, if there is one
+func composePair(a, b rune) (rune, bool) {
+	if l := a - hangulLBase; l >= 0 && l < hangulLCount {
+		if v := b - hangulVBase; v >= 0 && v < hangulVCount {
+			return hangulBase + (l*hangulVCount+v)*hangulTCount, true
+		}
+	}
+	if s := a - hangulBase; s >= 0 && s < hangulCount && s%hangulTCount == 0 {
+		if t := b - hangulTBase; t > 0 && t < hangulTCount {
+			return a + t, true
+		}
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
+
+// compose recombines decomposed text, each mark joins the last starter unless another mark blocks it
+func compose(runes []rune) []rune {
+	out := make([]rune, 0, len(runes))
+	starter := -1
+	var lastClass uint8
+	for _, r := range runes {
+		class := combiningClass(r)
+		if starter >= 0 && (starter == len(out)-1 || (lastClass != 0 && lastClass < class)) {
+			if c, ok := composePair(out[starter], r); ok {
+				out[starter] = c
+				continue
+			}
+		}
+		if class == 0 {
+			starter = len(out)
+		}
+		lastClass = class
+		out = append(out, r)
+	}
+	return out
+}
+
+// normalize puts s in one of the four Unicode normalization forms
+func normalize(s, form string) (string, error) {
+	switch strings.ToUpper(form) {
+	case "NFC":
+		return string(compose(decompose(s, false))), nil
+	case "NFD":
+		return string(decompose(s, false)), nil
+	case "NFKC":
+		return string(compose(decompose(s, true))), nil
+	case "NFKD":
+		return string(decompose(s, true)), nil
+	}
+	return "", fmt.Errorf("unknown normalization form '%s', expected NFC, NFD, NFKC or NFKD", form)
+}
+
+// casefold maps s to a form where case differences disappear, "Straße" and "STRASSE" fold the same
+func casefold(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if folded, ok := caseFolds[r]; ok {
+			b.WriteString(folded)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Collation compares strings on three levels: first the base letters, then
+// the accents, then the case, lowercase first. A locale may move letters the
+// way its alphabet does: Swedish sorts å, ä and ö after z, Spanish ñ after n.
+
+// collationTailorings maps a language to the letters it sorts apart, with
+// the letter each one follows and the order among letters following the same one
+var collationTailorings = map[string]map[rune]struct {
+	after rune
+	order int
+}{
+	"sv": {'å': {'z', 1}, 'ä': {'z', 2}, 'ö': {'z', 3}},
+	"fi": {'å': {'z', 1}, 'ä': {'z', 2}, 'ö': {'z', 3}},
+	"da": {'æ': {'z', 1}, 'ø': {'z', 2}, 'å': {'z', 3}},
+	"nb": {'æ': {'z', 1}, 'ø': {'z', 2}, 'å': {'z', 3}},
+	"nn": {'æ': {'z', 1}, 'ø': {'z', 2}, 'å': {'z', 3}},
+	"no": {'æ': {'z', 1}, 'ø': {'z', 2}, 'å': {'z', 3}},
+	"es": {'ñ': {'n', 1}},
+}
+
+// collationKey holds the weights of a string on each level
+type collationKey struct {
+	primary   []int
+	secondary []int
+	tertiary  []int
+	text      string // the NFC form, which breaks the remaining ties
+}
+
+func (k collationKey) compare(other collationKey) int {
+	if c := slices.Compare(k.primary, other.primary); c != 0 {
+		return c
+	}
+	if c := slices.Compare(k.secondary, other.secondary); c != 0 {
+		return c
+	}
+	if c := slices.Compare(k.tertiary, other.tertiary); c != 0 {
+		return c
+	}
+	return strings.Compare(k.text, other.text)
+}
+
+// Primary weights leave room for tailored letters between code points
+const collationSpacing = 8
+
+// collationKeyOf computes the weights of s for a locale like "sv" or "sv-SE", "" for the default order
+func collationKeyOf(s, locale string) collationKey {
+	language, _, _ := strings.Cut(strings.ToLower(strings.ReplaceAll(locale, "_", "-")), "-")
+	tailoring := collationTailorings[language]
+
+	key := collationKey{text: string(compose(decompose(s, false)))}
+	for _, r := range key.text {
+		upper := 0
+		if unicode.IsUpper(r) || unicode.IsTitle(r) {
+			upper = 1
+		}
+		if tailored, ok := tailoring[[]rune(casefold(string(r)))[0]]; ok {
+			key.primary = append(key.primary, int(tailored.after)*collationSpacing+tailored.order)
+			key.secondary = append(key.secondary, 0)
+			key.tertiary = append(key.tertiary, upper)
+			continue
+		}
+		for _, c := range decompose(string(r), true) {
+			if combiningClass(c) != 0 {
+				// Accents weigh on the second level only, after a separator for their letter
+				key.secondary = append(key.secondary, int(c))
+				continue
+			}
+			for _, folded := range casefold(string(c)) {
+				key.primary = append(key.primary, int(folded)*collationSpacing)
+				key.secondary = append(key.secondary, 0)
+				key.tertiary = append(key.tertiary, upper)
+			}
+		}
+	}
+	return key
+}
+
+// collate orders two strings for a locale, -1, 0 or 1, canonically equivalent strings are equal
+func collate(a, b, locale string) int {
+	if a == b {
+		return 0
+	}
+	return collationKeyOf(a, locale).compare(collationKeyOf(b, locale))
+}
+
+// localeArg reads the optional locale argument of name
+func localeArg(name string, args []RuntimeValue, index int) (string, error) {
+	if len(args) <= index {
+		return "", nil
+	}
+	locale, ok := args[index].(*StringValue)
+	if !ok {
+		return "", fmt.Errorf("%s locale must be a string", name)
+	}
+	return locale.Value, nil
+}
+
+func stringNormalize(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("string.normalize accepts at most one argument")
+	}
+	form := "NFC"
+	if len(args) == 1 {
+		name, ok := args[0].(*StringValue)
+		if !ok {
+			return nil, fmt.Errorf("string.normalize argument must be a string")
+		}
+		form = name.Value
+	}
+	result, err := normalize(s.Value, form)
+	if err != nil {
+		return nil, fmt.Errorf("string.normalize: %v", err)
+	}
+	return MakeString(result), nil
+}
+
+func stringCasefold(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	return MakeString(casefold(s.Value)), nil
+}
+
+// stringCompare is s.compare(other, locale), -1, 0 or 1 in the collation order of the locale
+func stringCompare(s *StringValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) < 1 || len(args) > 2 {
+		return nil, fmt.Errorf("string.compare requires one or two arguments")
+	}
+	other, ok := args[0].(*StringValue)
+	if !ok {
+		return nil, fmt.Errorf("string.compare argument must be a string")
+	}
+	locale, err := localeArg("string.compare", args, 1)
+	if err != nil {
+		return nil, err
+	}
+	return MakeInt(int64(collate(s.Value, other.Value, locale))), nil
+}
+
+// arraySort sorts numbers by value and strings in the collation order of an optional locale, in place
+func arraySort(a *ArrayValue, args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+	if len(args) > 1 {
+		return nil, fmt.Errorf("array.sort accepts at most one argument")
+	}
+	locale, err := localeArg("array.sort", args, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	numbers, strs := true, true
+	for _, element := range a.Elements {
+		isNumber := isNumeric(element.Type())
+		_, isString := element.(*StringValue)
+		numbers, strs = numbers && isNumber, strs && isString
+	}
+	switch {
+	case len(a.Elements) < 2:
+	case numbers:
+		slices.SortStableFunc(a.Elements, func(x, y RuntimeValue) int {
+			return compareNumeric(x, y)
+		})
+	case strs:
+		keys := make(map[RuntimeValue]collationKey, len(a.Elements))
+		for _, element := range a.Elements {
+			keys[element] = collationKeyOf(element.(*StringValue).Value, locale)
+		}
+		slices.SortStableFunc(a.Elements, func(x, y RuntimeValue) int {
+			return keys[x].compare(keys[y])
+		})
+	default:
+		return nil, fmt.Errorf("array.sort can only sort an array of numbers or an array of strings")
+	}
+	return a, nil
+}
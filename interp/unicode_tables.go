@@ -0,0 +1,8674 @@
+// Code generated by tools/unicodetables from Unicode 14.0.0. DO NOT EDIT.
+
+package interp
+
+// canonicalDecompositions maps a character to its canonical decomposition, one level deep
+var canonicalDecompositions = map[rune]string{
+	0x00C0:  "\u0041\u0300",
+	0x00C1:  "\u0041\u0301",
+	0x00C2:  "\u0041\u0302",
+	0x00C3:  "\u0041\u0303",
+	0x00C4:  "\u0041\u0308",
+	0x00C5:  "\u0041\u030a",
+	0x00C7:  "\u0043\u0327",
+	0x00C8:  "\u0045\u0300",
+	0x00C9:  "\u0045\u0301",
+	0x00CA:  "\u0045\u0302",
+	0x00CB:  "\u0045\u0308",
+	0x00CC:  "\u0049\u0300",
+	0x00CD:  "\u0049\u0301",
+	0x00CE:  "\u0049\u0302",
+	0x00CF:  "\u0049\u0308",
+	0x00D1:  "\u004e\u0303",
+	0x00D2:  "\u004f\u0300",
+	0x00D3:  "\u004f\u0301",
+	0x00D4:  "\u004f\u0302",
+	0x00D5:  "\u004f\u0303",
+	0x00D6:  "\u004f\u0308",
+	0x00D9:  "\u0055\u0300",
+	0x00DA:  "\u0055\u0301",
+	0x00DB:  "\u0055\u0302",
+	0x00DC:  "\u0055\u0308",
+	0x00DD:  "\u0059\u0301",
+	0x00E0:  "\u0061\u0300",
+	0x00E1:  "\u0061\u0301",
+	0x00E2:  "\u0061\u0302",
+	0x00E3:  "\u0061\u0303",
+	0x00E4:  "\u0061\u0308",
+	0x00E5:  "\u0061\u030a",
+	0x00E7:  "\u0063\u0327",
+	0x00E8:  "\u0065\u0300",
+	0x00E9:  "\u0065\u0301",
+	0x00EA:  "\u0065\u0302",
+	0x00EB:  "\u0065\u0308",
+	0x00EC:  "\u0069\u0300",
+	0x00ED:  "\u0069\u0301",
+	0x00EE:  "\u0069\u0302",
+	0x00EF:  "\u0069\u0308",
+	0x00F1:  "\u006e\u0303",
+	0x00F2:  "\u006f\u0300",
+	0x00F3:  "\u006f\u0301",
+	0x00F4:  "\u006f\u0302",
+	0x00F5:  "\u006f\u0303",
+	0x00F6:  "\u006f\u0308",
+	0x00F9:  "\u0075\u0300",
+	0x00FA:  "\u0075\u0301",
+	0x00FB:  "\u0075\u0302",
+	0x00FC:  "\u0075\u0308",
+	0x00FD:  "\u0079\u0301",
+	0x00FF:  "\u0079\u0308",
+	0x0100:  "\u0041\u0304",
+	0x0101:  "\u0061\u0304",
+	0x0102:  "\u0041\u0306",
+	0x0103:  "\u0061\u0306",
+	0x0104:  "\u0041\u0328",
+	0x0105:  "\u0061\u0328",
+	0x0106:  "\u0043\u0301",
+	0x0107:  "\u0063\u0301",
+	0x0108:  "\u0043\u0302",
+	0x0109:  "\u0063\u0302",
+	0x010A:  "\u0043\u0307",
+	0x010B:  "\u0063\u0307",
+	0x010C:  "\u0043\u030c",
+	0x010D:  "\u0063\u030c",
+	0x010E:  "\u0044\u030c",
+	0x010F:  "\u0064\u030c",
+	0x0112:  "\u0045\u0304",
+	0x0113:  "\u0065\u0304",
+	0x0114:  "\u0045\u0306",
+	0x0115:  "\u0065\u0306",
+	0x0116:  "\u0045\u0307",
+	0x0117:  "\u0065\u0307",
+	0x0118:  "\u0045\u0328",
+	0x0119:  "\u0065\u0328",
+	0x011A:  "\u0045\u030c",
+	0x011B:  "\u0065\u030c",
+	0x011C:  "\u0047\u0302",
+	0x011D:  "\u0067\u0302",
+	0x011E:  "\u0047\u0306",
+	0x011F:  "\u0067\u0306",
+	0x0120:  "\u0047\u0307",
+	0x0121:  "\u0067\u0307",
+	0x0122:  "\u0047\u0327",
+	0x0123:  "\u0067\u0327",
+	0x0124:  "\u0048\u0302",
+	0x0125:  "\u0068\u0302",
+	0x0128:  "\u0049\u0303",
+	0x0129:  "\u0069\u0303",
+	0x012A:  "\u0049\u0304",
+	0x012B:  "\u0069\u0304",
+	0x012C:  "\u0049\u0306",
+	0x012D:  "\u0069\u0306",
+	0x012E:  "\u0049\u0328",
+	0x012F:  "\u0069\u0328",
+	0x0130:  "\u0049\u0307",
+	0x0134:  "\u004a\u0302",
+	0x0135:  "\u006a\u0302",
+	0x0136:  "\u004b\u0327",
+	0x0137:  "\u006b\u0327",
+	0x0139:  "\u004c\u0301",
+	0x013A:  "\u006c\u0301",
+	0x013B:  "\u004c\u0327",
+	0x013C:  "\u006c\u0327",
+	0x013D:  "\u004c\u030c",
+	0x013E:  "\u006c\u030c",
+	0x0143:  "\u004e\u0301",
+	0x0144:  "\u006e\u0301",
+	0x0145:  "\u004e\u0327",
+	0x0146:  "\u006e\u0327",
+	0x0147:  "\u004e\u030c",
+	0x0148:  "\u006e\u030c",
+	0x014C:  "\u004f\u0304",
+	0x014D:  "\u006f\u0304",
+	0x014E:  "\u004f\u0306",
+	0x014F:  "\u006f\u0306",
+	0x0150:  "\u004f\u030b",
+	0x0151:  "\u006f\u030b",
+	0x0154:  "\u0052\u0301",
+	0x0155:  "\u0072\u0301",
+	0x0156:  "\u0052\u0327",
+	0x0157:  "\u0072\u0327",
+	0x0158:  "\u0052\u030c",
+	0x0159:  "\u0072\u030c",
+	0x015A:  "\u0053\u0301",
+	0x015B:  "\u0073\u0301",
+	0x015C:  "\u0053\u0302",
+	0x015D:  "\u0073\u0302",
+	0x015E:  "\u0053\u0327",
+	0x015F:  "\u0073\u0327",
+	0x0160:  "\u0053\u030c",
+	0x0161:  "\u0073\u030c",
+	0x0162:  "\u0054\u0327",
+	0x0163:  "\u0074\u0327",
+	0x0164:  "\u0054\u030c",
+	0x0165:  "\u0074\u030c",
+	0x0168:  "\u0055\u0303",
+	0x0169:  "\u0075\u0303",
+	0x016A:  "\u0055\u0304",
+	0x016B:  "\u0075\u0304",
+	0x016C:  "\u0055\u0306",
+	0x016D:  "\u0075\u0306",
+	0x016E:  "\u0055\u030a",
+	0x016F:  "\u0075\u030a",
+	0x0170:  "\u0055\u030b",
+	0x0171:  "\u0075\u030b",
+	0x0172:  "\u0055\u0328",
+	0x0173:  "\u0075\u0328",
+	0x0174:  "\u0057\u0302",
+	0x0175:  "\u0077\u0302",
+	0x0176:  "\u0059\u0302",
+	0x0177:  "\u0079\u0302",
+	0x0178:  "\u0059\u0308",
+	0x0179:  "\u005a\u0301",
+	0x017A:  "\u007a\u0301",
+	0x017B:  "\u005a\u0307",
+	0x017C:  "\u007a\u0307",
+	0x017D:  "\u005a\u030c",
+	0x017E:  "\u007a\u030c",
+	0x01A0:  "\u004f\u031b",
+	0x01A1:  "\u006f\u031b",
+	0x01AF:  "\u0055\u031b",
+	0x01B0:  "\u0075\u031b",
+	0x01CD:  "\u0041\u030c",
+	0x01CE:  "\u0061\u030c",
+	0x01CF:  "\u0049\u030c",
+	0x01D0:  "\u0069\u030c",
+	0x01D1:  "\u004f\u030c",
+	0x01D2:  "\u006f\u030c",
+	0x01D3:  "\u0055\u030c",
+	0x01D4:  "\u0075\u030c",
+	0x01D5:  "\u00dc\u0304",
+	0x01D6:  "\u00fc\u0304",
+	0x01D7:  "\u00dc\u0301",
+	0x01D8:  "\u00fc\u0301",
+	0x01D9:  "\u00dc\u030c",
+	0x01DA:  "\u00fc\u030c",
+	0x01DB:  "\u00dc\u0300",
+	0x01DC:  "\u00fc\u0300",
+	0x01DE:  "\u00c4\u0304",
+	0x01DF:  "\u00e4\u0304",
+	0x01E0:  "\u0226\u0304",
+	0x01E1:  "\u0227\u0304",
+	0x01E2:  "\u00c6\u0304",
+	0x01E3:  "\u00e6\u0304",
+	0x01E6:  "\u0047\u030c",
+	0x01E7:  "\u0067\u030c",
+	0x01E8:  "\u004b\u030c",
+	0x01E9:  "\u006b\u030c",
+	0x01EA:  "\u004f\u0328",
+	0x01EB:  "\u006f\u0328",
+	0x01EC:  "\u01ea\u0304",
+	0x01ED:  "\u01eb\u0304",
+	0x01EE:  "\u01b7\u030c",
+	0x01EF:  "\u0292\u030c",
+	0x01F0:  "\u006a\u030c",
+	0x01F4:  "\u0047\u0301",
+	0x01F5:  "\u0067\u0301",
+	0x01F8:  "\u004e\u0300",
+	0x01F9:  "\u006e\u0300",
+	0x01FA:  "\u00c5\u0301",
+	0x01FB:  "\u00e5\u0301",
+	0x01FC:  "\u00c6\u0301",
+	0x01FD:  "\u00e6\u0301",
+	0x01FE:  "\u00d8\u0301",
+	0x01FF:  "\u00f8\u0301",
+	0x0200:  "\u0041\u030f",
+	0x0201:  "\u0061\u030f",
+	0x0202:  "\u0041\u0311",
+	0x0203:  "\u0061\u0311",
+	0x0204:  "\u0045\u030f",
+	0x0205:  "\u0065\u030f",
+	0x0206:  "\u0045\u0311",
+	0x0207:  "\u0065\u0311",
+	0x0208:  "\u0049\u030f",
+	0x0209:  "\u0069\u030f",
+	0x020A:  "\u0049\u0311",
+	0x020B:  "\u0069\u0311",
+	0x020C:  "\u004f\u030f",
+	0x020D:  "\u006f\u030f",
+	0x020E:  "\u004f\u0311",
+	0x020F:  "\u006f\u0311",
+	0x0210:  "\u0052\u030f",
+	0x0211:  "\u0072\u030f",
+	0x0212:  "\u0052\u0311",
+	0x0213:  "\u0072\u0311",
+	0x0214:  "\u0055\u030f",
+	0x0215:  "\u0075\u030f",
+	0x0216:  "\u0055\u0311",
+	0x0217:  "\u0075\u0311",
+	0x0218:  "\u0053\u0326",
+	0x0219:  "\u0073\u0326",
+	0x021A:  "\u0054\u0326",
+	0x021B:  "\u0074\u0326",
+	0x021E:  "\u0048\u030c",
+	0x021F:  "\u0068\u030c",
+	0x0226:  "\u0041\u0307",
+	0x0227:  "\u0061\u0307",
+	0x0228:  "\u0045\u0327",
+	0x0229:  "\u0065\u0327",
+	0x022A:  "\u00d6\u0304",
+	0x022B:  "\u00f6\u0304",
+	0x022C:  "\u00d5\u0304",
+	0x022D:  "\u00f5\u0304",
+	0x022E:  "\u004f\u0307",
+	0x022F:  "\u006f\u0307",
+	0x0230:  "\u022e\u0304",
+	0x0231:  "\u022f\u0304",
+	0x0232:  "\u0059\u0304",
+	0x0233:  "\u0079\u0304",
+	0x0340:  "\u0300",
+	0x0341:  "\u0301",
+	0x0343:  "\u0313",
+	0x0344:  "\u0308\u0301",
+	0x0374:  "\u02b9",
+	0x037E:  "\u003b",
+	0x0385:  "\u00a8\u0301",
+	0x0386:  "\u0391\u0301",
+	0x0387:  "\u00b7",
+	0x0388:  "\u0395\u0301",
+	0x0389:  "\u0397\u0301",
+	0x038A:  "\u0399\u0301",
+	0x038C:  "\u039f\u0301",
+	0x038E:  "\u03a5\u0301",
+	0x038F:  "\u03a9\u0301",
+	0x0390:  "\u03ca\u0301",
+	0x03AA:  "\u0399\u0308",
+	0x03AB:  "\u03a5\u0308",
+	0x03AC:  "\u03b1\u0301",
+	0x03AD:  "\u03b5\u0301",
+	0x03AE:  "\u03b7\u0301",
+	0x03AF:  "\u03b9\u0301",
+	0x03B0:  "\u03cb\u0301",
+	0x03CA:  "\u03b9\u0308",
+	0x03CB:  "\u03c5\u0308",
+	0x03CC:  "\u03bf\u0301",
+	0x03CD:  "\u03c5\u0301",
+	0x03CE:  "\u03c9\u0301",
+	0x03D3:  "\u03d2\u0301",
+	0x03D4:  "\u03d2\u0308",
+	0x0400:  "\u0415\u0300",
+	0x0401:  "\u0415\u0308",
+	0x0403:  "\u0413\u0301",
+	0x0407:  "\u0406\u0308",
+	0x040C:  "\u041a\u0301",
+	0x040D:  "\u0418\u0300",
+	0x040E:  "\u0423\u0306",
+	0x0419:  "\u0418\u0306",
+	0x0439:  "\u0438\u0306",
+	0x0450:  "\u0435\u0300",
+	0x0451:  "\u0435\u0308",
+	0x0453:  "\u0433\u0301",
+	0x0457:  "\u0456\u0308",
+	0x045C:  "\u043a\u0301",
+	0x045D:  "\u0438\u0300",
+	0x045E:  "\u0443\u0306",
+	0x0476:  "\u0474\u030f",
+	0x0477:  "\u0475\u030f",
+	0x04C1:  "\u0416\u0306",
+	0x04C2:  "\u0436\u0306",
+	0x04D0:  "\u0410\u0306",
+	0x04D1:  "\u0430\u0306",
+	0x04D2:  "\u0410\u0308",
+	0x04D3:  "\u0430\u0308",
+	0x04D6:  "\u0415\u0306",
+	0x04D7:  "\u0435\u0306",
+	0x04DA:  "\u04d8\u0308",
+	0x04DB:  "\u04d9\u0308",
+	0x04DC:  "\u0416\u0308",
+	0x04DD:  "\u0436\u0308",
+	0x04DE:  "\u0417\u0308",
+	0x04DF:  "\u0437\u0308",
+	0x04E2:  "\u0418\u0304",
+	0x04E3:  "\u0438\u0304",
+	0x04E4:  "\u0418\u0308",
+	0x04E5:  "\u0438\u0308",
+	0x04E6:  "\u041e\u0308",
+	0x04E7:  "\u043e\u0308",
+	0x04EA:  "\u04e8\u0308",
+	0x04EB:  "\u04e9\u0308",
+	0x04EC:  "\u042d\u0308",
+	0x04ED:  "\u044d\u0308",
+	0x04EE:  "\u0423\u0304",
+	0x04EF:  "\u0443\u0304",
+	0x04F0:  "\u0423\u0308",
+	0x04F1:  "\u0443\u0308",
+	0x04F2:  "\u0423\u030b",
+	0x04F3:  "\u0443\u030b",
+	0x04F4:  "\u0427\u0308",
+	0x04F5:  "\u0447\u0308",
+	0x04F8:  "\u042b\u0308",
+	0x04F9:  "\u044b\u0308",
+	0x0622:  "\u0627\u0653",
+	0x0623:  "\u0627\u0654",
+	0x0624:  "\u0648\u0654",
+	0x0625:  "\u0627\u0655",
+	0x0626:  "\u064a\u0654",
+	0x06C0:  "\u06d5\u0654",
+	0x06C2:  "\u06c1\u0654",
+	0x06D3:  "\u06d2\u0654",
+	0x0929:  "\u0928\u093c",
+	0x0931:  "\u0930\u093c",
+	0x0934:  "\u0933\u093c",
+	0x0958:  "\u0915\u093c",
+	0x0959:  "\u0916\u093c",
+	0x095A:  "\u0917\u093c",
+	0x095B:  "\u091c\u093c",
+	0x095C:  "\u0921\u093c",
+	0x095D:  "\u0922\u093c",
+	0x095E:  "\u092b\u093c",
+	0x095F:  "\u092f\u093c",
+	0x09CB:  "\u09c7\u09be",
+	0x09CC:  "\u09c7\u09d7",
+	0x09DC:  "\u09a1\u09bc",
+	0x09DD:  "\u09a2\u09bc",
+	0x09DF:  "\u09af\u09bc",
+	0x0A33:  "\u0a32\u0a3c",
+	0x0A36:  "\u0a38\u0a3c",
+	0x0A59:  "\u0a16\u0a3c",
+	0x0A5A:  "\u0a17\u0a3c",
+	0x0A5B:  "\u0a1c\u0a3c",
+	0x0A5E:  "\u0a2b\u0a3c",
+	0x0B48:  "\u0b47\u0b56",
+	0x0B4B:  "\u0b47\u0b3e",
+	0x0B4C:  "\u0b47\u0b57",
+	0x0B5C:  "\u0b21\u0b3c",
+	0x0B5D:  "\u0b22\u0b3c",
+	0x0B94:  "\u0b92\u0bd7",
+	0x0BCA:  "\u0bc6\u0bbe",
+	0x0BCB:  "\u0bc7\u0bbe",
+	0x0BCC:  "\u0bc6\u0bd7",
+	0x0C48:  "\u0c46\u0c56",
+	0x0CC0:  "\u0cbf\u0cd5",
+	0x0CC7:  "\u0cc6\u0cd5",
+	0x0CC8:  "\u0cc6\u0cd6",
+	0x0CCA:  "\u0cc6\u0cc2",
+	0x0CCB:  "\u0cca\u0cd5",
+	0x0D4A:  "\u0d46\u0d3e",
+	0x0D4B:  "\u0d47\u0d3e",
+	0x0D4C:  "\u0d46\u0d57",
+	0x0DDA:  "\u0dd9\u0dca",
+	0x0DDC:  "\u0dd9\u0dcf",
+	0x0DDD:  "\u0ddc\u0dca",
+	0x0DDE:  "\u0dd9\u0ddf",
+	0x0F43:  "\u0f42\u0fb7",
+	0x0F4D:  "\u0f4c\u0fb7",
+	0x0F52:  "\u0f51\u0fb7",
+	0x0F57:  "\u0f56\u0fb7",
+	0x0F5C:  "\u0f5b\u0fb7",
+	0x0F69:  "\u0f40\u0fb5",
+	0x0F73:  "\u0f71\u0f72",
+	0x0F75:  "\u0f71\u0f74",
+	0x0F76:  "\u0fb2\u0f80",
+	0x0F78:  "\u0fb3\u0f80",
+	0x0F81:  "\u0f71\u0f80",
+	0x0F93:  "\u0f92\u0fb7",
+	0x0F9D:  "\u0f9c\u0fb7",
+	0x0FA2:  "\u0fa1\u0fb7",
+	0x0FA7:  "\u0fa6\u0fb7",
+	0x0FAC:  "\u0fab\u0fb7",
+	0x0FB9:  "\u0f90\u0fb5",
+	0x1026:  "\u1025\u102e",
+	0x1B06:  "\u1b05\u1b35",
+	0x1B08:  "\u1b07\u1b35",
+	0x1B0A:  "\u1b09\u1b35",
+	0x1B0C:  "\u1b0b\u1b35",
+	0x1B0E:  "\u1b0d\u1b35",
+	0x1B12:  "\u1b11\u1b35",
+	0x1B3B:  "\u1b3a\u1b35",
+	0x1B3D:  "\u1b3c\u1b35",
+	0x1B40:  "\u1b3e\u1b35",
+	0x1B41:  "\u1b3f\u1b35",
+	0x1B43:  "\u1b42\u1b35",
+	0x1E00:  "\u0041\u0325",
+	0x1E01:  "\u0061\u0325",
+	0x1E02:  "\u0042\u0307",
+	0x1E03:  "\u0062\u0307",
+	0x1E04:  "\u0042\u0323",
+	0x1E05:  "\u0062\u0323",
+	0x1E06:  "\u0042\u0331",
+	0x1E07:  "\u0062\u0331",
+	0x1E08:  "\u00c7\u0301",
+	0x1E09:  "\u00e7\u0301",
+	0x1E0A:  "\u0044\u0307",
+	0x1E0B:  "\u0064\u0307",
+	0x1E0C:  "\u0044\u0323",
+	0x1E0D:  "\u0064\u0323",
+	0x1E0E:  "\u0044\u0331",
+	0x1E0F:  "\u0064\u0331",
+	0x1E10:  "\u0044\u0327",
+	0x1E11:  "\u0064\u0327",
+	0x1E12:  "\u0044\u032d",
+	0x1E13:  "\u0064\u032d",
+	0x1E14:  "\u0112\u0300",
+	0x1E15:  "\u0113\u0300",
+	0x1E16:  "\u0112\u0301",
+	0x1E17:  "\u0113\u0301",
+	0x1E18:  "\u0045\u032d",
+	0x1E19:  "\u0065\u032d",
+	0x1E1A:  "\u0045\u0330",
+	0x1E1B:  "\u0065\u0330",
+	0x1E1C:  "\u0228\u0306",
+	0x1E1D:  "\u0229\u0306",
+	0x1E1E:  "\u0046\u0307",
+	0x1E1F:  "\u0066\u0307",
+	0x1E20:  "\u0047\u0304",
+	0x1E21:  "\u0067\u0304",
+	0x1E22:  "\u0048\u0307",
+	0x1E23:  "\u0068\u0307",
+	0x1E24:  "\u0048\u0323",
+	0x1E25:  "\u0068\u0323",
+	0x1E26:  "\u0048\u0308",
+	0x1E27:  "\u0068\u0308",
+	0x1E28:  "\u0048\u0327",
+	0x1E29:  "\u0068\u0327",
+	0x1E2A:  "\u0048\u032e",
+	0x1E2B:  "\u0068\u032e",
+	0x1E2C:  "\u0049\u0330",
+	0x1E2D:  "\u0069\u0330",
+	0x1E2E:  "\u00cf\u0301",
+	0x1E2F:  "\u00ef\u0301",
+	0x1E30:  "\u004b\u0301",
+	0x1E31:  "\u006b\u0301",
+	0x1E32:  "\u004b\u0323",
+	0x1E33:  "\u006b\u0323",
+	0x1E34:  "\u004b\u0331",
+	0x1E35:  "\u006b\u0331",
+	0x1E36:  "\u004c\u0323",
+	0x1E37:  "\u006c\u0323",
+	0x1E38:  "\u1e36\u0304",
+	0x1E39:  "\u1e37\u0304",
+	0x1E3A:  "\u004c\u0331",
+	0x1E3B:  "\u006c\u0331",
+	0x1E3C:  "\u004c\u032d",
+	0x1E3D:  "\u006c\u032d",
+	0x1E3E:  "\u004d\u0301",
+	0x1E3F:  "\u006d\u0301",
+	0x1E40:  "\u004d\u0307",
+	0x1E41:  "\u006d\u0307",
+	0x1E42:  "\u004d\u0323",
+	0x1E43:  "\u006d\u0323",
+	0x1E44:  "\u004e\u0307",
+	0x1E45:  "\u006e\u0307",
+	0x1E46:  "\u004e\u0323",
+	0x1E47:  "\u006e\u0323",
+	0x1E48:  "\u004e\u0331",
+	0x1E49:  "\u006e\u0331",
+	0x1E4A:  "\u004e\u032d",
+	0x1E4B:  "\u006e\u032d",
+	0x1E4C:  "\u00d5\u0301",
+	0x1E4D:  "\u00f5\u0301",
+	0x1E4E:  "\u00d5\u0308",
+	0x1E4F:  "\u00f5\u0308",
+	0x1E50:  "\u014c\u0300",
+	0x1E51:  "\u014d\u0300",
+	0x1E52:  "\u014c\u0301",
+	0x1E53:  "\u014d\u0301",
+	0x1E54:  "\u0050\u0301",
+	0x1E55:  "\u0070\u0301",
+	0x1E56:  "\u0050\u0307",
+	0x1E57:  "\u0070\u0307",
+	0x1E58:  "\u0052\u0307",
+	0x1E59:  "\u0072\u0307",
+	0x1E5A:  "\u0052\u0323",
+	0x1E5B:  "\u0072\u0323",
+	0x1E5C:  "\u1e5a\u0304",
+	0x1E5D:  "\u1e5b\u0304",
+	0x1E5E:  "\u0052\u0331",
+	0x1E5F:  "\u0072\u0331",
+	0x1E60:  "\u0053\u0307",
+	0x1E61:  "\u0073\u0307",
+	0x1E62:  "\u0053\u0323",
+	0x1E63:  "\u0073\u0323",
+	0x1E64:  "\u015a\u0307",
+	0x1E65:  "\u015b\u0307",
+	0x1E66:  "\u0160\u0307",
+	0x1E67:  "\u0161\u0307",
+	0x1E68:  "\u1e62\u0307",
+	0x1E69:  "\u1e63\u0307",
+	0x1E6A:  "\u0054\u0307",
+	0x1E6B:  "\u0074\u0307",
+	0x1E6C:  "\u0054\u0323",
+	0x1E6D:  "\u0074\u0323",
+	0x1E6E:  "\u0054\u0331",
+	0x1E6F:  "\u0074\u0331",
+	0x1E70:  "\u0054\u032d",
+	0x1E71:  "\u0074\u032d",
+	0x1E72:  "\u0055\u0324",
+	0x1E73:  "\u0075\u0324",
+	0x1E74:  "\u0055\u0330",
+	0x1E75:  "\u0075\u0330",
+	0x1E76:  "\u0055\u032d",
+	0x1E77:  "\u0075\u032d",
+	0x1E78:  "\u0168\u0301",
+	0x1E79:  "\u0169\u0301",
+	0x1E7A:  "\u016a\u0308",
+	0x1E7B:  "\u016b\u0308",
+	0x1E7C:  "\u0056\u0303",
+	0x1E7D:  "\u0076\u0303",
+	0x1E7E:  "\u0056\u0323",
+	0x1E7F:  "\u0076\u0323",
+	0x1E80:  "\u0057\u0300",
+	0x1E81:  "\u0077\u0300",
+	0x1E82:  "\u0057\u0301",
+	0x1E83:  "\u0077\u0301",
+	0x1E84:  "\u0057\u0308",
+	0x1E85:  "\u0077\u0308",
+	0x1E86:  "\u0057\u0307",
+	0x1E87:  "\u0077\u0307",
+	0x1E88:  "\u0057\u0323",
+	0x1E89:  "\u0077\u0323",
+	0x1E8A:  "\u0058\u0307",
+	0x1E8B:  "\u0078\u0307",
+	0x1E8C:  "\u0058\u0308",
+	0x1E8D:  "\u0078\u0308",
+	0x1E8E:  "\u0059\u0307",
+	0x1E8F:  "\u0079\u0307",
+	0x1E90:  "\u005a\u0302",
+	0x1E91:  "\u007a\u0302",
+	0x1E92:  "\u005a\u0323",
+	0x1E93:  "\u007a\u0323",
+	0x1E94:  "\u005a\u0331",
+	0x1E95:  "\u007a\u0331",
+	0x1E96:  "\u0068\u0331",
+	0x1E97:  "\u0074\u0308",
+	0x1E98:  "\u0077\u030a",
+	0x1E99:  "\u0079\u030a",
+	0x1E9B:  "\u017f\u0307",
+	0x1EA0:  "\u0041\u0323",
+	0x1EA1:  "\u0061\u0323",
+	0x1EA2:  "\u0041\u0309",
+	0x1EA3:  "\u0061\u0309",
+	0x1EA4:  "\u00c2\u0301",
+	0x1EA5:  "\u00e2\u0301",
+	0x1EA6:  "\u00c2\u0300",
+	0x1EA7:  "\u00e2\u0300",
+	0x1EA8:  "\u00c2\u0309",
+	0x1EA9:  "\u00e2\u0309",
+	0x1EAA:  "\u00c2\u0303",
+	0x1EAB:  "\u00e2\u0303",
+	0x1EAC:  "\u1ea0\u0302",
+	0x1EAD:  "\u1ea1\u0302",
+	0x1EAE:  "\u0102\u0301",
+	0x1EAF:  "\u0103\u0301",
+	0x1EB0:  "\u0102\u0300",
+	0x1EB1:  "\u0103\u0300",
+	0x1EB2:  "\u0102\u0309",
+	0x1EB3:  "\u0103\u0309",
+	0x1EB4:  "\u0102\u0303",
+	0x1EB5:  "\u0103\u0303",
+	0x1EB6:  "\u1ea0\u0306",
+	0x1EB7:  "\u1ea1\u0306",
+	0x1EB8:  "\u0045\u0323",
+	0x1EB9:  "\u0065\u0323",
+	0x1EBA:  "\u0045\u0309",
+	0x1EBB:  "\u0065\u0309",
+	0x1EBC:  "\u0045\u0303",
+	0x1EBD:  "\u0065\u0303",
+	0x1EBE:  "\u00ca\u0301",
+	0x1EBF:  "\u00ea\u0301",
+	0x1EC0:  "\u00ca\u0300",
+	0x1EC1:  "\u00ea\u0300",
+	0x1EC2:  "\u00ca\u0309",
+	0x1EC3:  "\u00ea\u0309",
+	0x1EC4:  "\u00ca\u0303",
+	0x1EC5:  "\u00ea\u0303",
+	0x1EC6:  "\u1eb8\u0302",
+	0x1EC7:  "\u1eb9\u0302",
+	0x1EC8:  "\u0049\u0309",
+	0x1EC9:  "\u0069\u0309",
+	0x1ECA:  "\u0049\u0323",
+	0x1ECB:  "\u0069\u0323",
+	0x1ECC:  "\u004f\u0323",
+	0x1ECD:  "\u006f\u0323",
+	0x1ECE:  "\u004f\u0309",
+	0x1ECF:  "\u006f\u0309",
+	0x1ED0:  "\u00d4\u0301",
+	0x1ED1:  "\u00f4\u0301",
+	0x1ED2:  "\u00d4\u0300",
+	0x1ED3:  "\u00f4\u0300",
+	0x1ED4:  "\u00d4\u0309",
+	0x1ED5:  "\u00f4\u0309",
+	0x1ED6:  "\u00d4\u0303",
+	0x1ED7:  "\u00f4\u0303",
+	0x1ED8:  "\u1ecc\u0302",
+	0x1ED9:  "\u1ecd\u0302",
+	0x1EDA:  "\u01a0\u0301",
+	0x1EDB:  "\u01a1\u0301",
+	0x1EDC:  "\u01a0\u0300",
+	0x1EDD:  "\u01a1\u0300",
+	0x1EDE:  "\u01a0\u0309",
+	0x1EDF:  "\u01a1\u0309",
+	0x1EE0:  "\u01a0\u0303",
+	0x1EE1:  "\u01a1\u0303",
+	0x1EE2:  "\u01a0\u0323",
+	0x1EE3:  "\u01a1\u0323",
+	0x1EE4:  "\u0055\u0323",
+	0x1EE5:  "\u0075\u0323",
+	0x1EE6:  "\u0055\u0309",
+	0x1EE7:  "\u0075\u0309",
+	0x1EE8:  "\u01af\u0301",
+	0x1EE9:  "\u01b0\u0301",
+	0x1EEA:  "\u01af\u0300",
+	0x1EEB:  "\u01b0\u0300",
+	0x1EEC:  "\u01af\u0309",
+	0x1EED:  "\u01b0\u0309",
+	0x1EEE:  "\u01af\u0303",
+	0x1EEF:  "\u01b0\u0303",
+	0x1EF0:  "\u01af\u0323",
+	0x1EF1:  "\u01b0\u0323",
+	0x1EF2:  "\u0059\u0300",
+	0x1EF3:  "\u0079\u0300",
+	0x1EF4:  "\u0059\u0323",
+	0x1EF5:  "\u0079\u0323",
+	0x1EF6:  "\u0059\u0309",
+	0x1EF7:  "\u0079\u0309",
+	0x1EF8:  "\u0059\u0303",
+	0x1EF9:  "\u0079\u0303",
+	0x1F00:  "\u03b1\u0313",
+	0x1F01:  "\u03b1\u0314",
+	0x1F02:  "\u1f00\u0300",
+	0x1F03:  "\u1f01\u0300",
+	0x1F04:  "\u1f00\u0301",
+	0x1F05:  "\u1f01\u0301",
+	0x1F06:  "\u1f00\u0342",
+	0x1F07:  "\u1f01\u0342",
+	0x1F08:  "\u0391\u0313",
+	0x1F09:  "\u0391\u0314",
+	0x1F0A:  "\u1f08\u0300",
+	0x1F0B:  "\u1f09\u0300",
+	0x1F0C:  "\u1f08\u0301",
+	0x1F0D:  "\u1f09\u0301",
+	0x1F0E:  "\u1f08\u0342",
+	0x1F0F:  "\u1f09\u0342",
+	0x1F10:  "\u03b5\u0313",
+	0x1F11:  "\u03b5\u0314",
+	0x1F12:  "\u1f10\u0300",
+	0x1F13:  "\u1f11\u0300",
+	0x1F14:  "\u1f10\u0301",
+	0x1F15:  "\u1f11\u0301",
+	0x1F18:  "\u0395\u0313",
+	0x1F19:  "\u0395\u0314",
+	0x1F1A:  "\u1f18\u0300",
+	0x1F1B:  "\u1f19\u0300",
+	0x1F1C:  "\u1f18\u0301",
+	0x1F1D:  "\u1f19\u0301",
+	0x1F20:  "\u03b7\u0313",
+	0x1F21:  "\u03b7\u0314",
+	0x1F22:  "\u1f20\u0300",
+	0x1F23:  "\u1f21\u0300",
+	0x1F24:  "\u1f20\u0301",
+	0x1F25:  "\u1f21\u0301",
+	0x1F26:  "\u1f20\u0342",
+	0x1F27:  "\u1f21\u0342",
+	0x1F28:  "\u0397\u0313",
+	0x1F29:  "\u0397\u0314",
+	0x1F2A:  "\u1f28\u0300",
+	0x1F2B:  "\u1f29\u0300",
+	0x1F2C:  "\u1f28\u0301",
+	0x1F2D:  "\u1f29\u0301",
+	0x1F2E:  "\u1f28\u0342",
+	0x1F2F:  "\u1f29\u0342",
+	0x1F30:  "\u03b9\u0313",
+	0x1F31:  "\u03b9\u0314",
+	0x1F32:  "\u1f30\u0300",
+	0x1F33:  "\u1f31\u0300",
+	0x1F34:  "\u1f30\u0301",
+	0x1F35:  "\u1f31\u0301",
+	0x1F36:  "\u1f30\u0342",
+	0x1F37:  "\u1f31\u0342",
+	0x1F38:  "\u0399\u0313",
+	0x1F39:  "\u0399\u0314",
+	0x1F3A:  "\u1f38\u0300",
+	0x1F3B:  "\u1f39\u0300",
+	0x1F3C:  "\u1f38\u0301",
+	0x1F3D:  "\u1f39\u0301",
+	0x1F3E:  "\u1f38\u0342",
+	0x1F3F:  "\u1f39\u0342",
+	0x1F40:  "\u03bf\u0313",
+	0x1F41:  "\u03bf\u0314",
+	0x1F42:  "\u1f40\u0300",
+	0x1F43:  "\u1f41\u0300",
+	0x1F44:  "\u1f40\u0301",
+	0x1F45:  "\u1f41\u0301",
+	0x1F48:  "\u039f\u0313",
+	0x1F49:  "\u039f\u0314",
+	0x1F4A:  "\u1f48\u0300",
+	0x1F4B:  "\u1f49\u0300",
+	0x1F4C:  "\u1f48\u0301",
+	0x1F4D:  "\u1f49\u0301",
+	0x1F50:  "\u03c5\u0313",
+	0x1F51:  "\u03c5\u0314",
+	0x1F52:  "\u1f50\u0300",
+	0x1F53:  "\u1f51\u0300",
+	0x1F54:  "\u1f50\u0301",
+	0x1F55:  "\u1f51\u0301",
+	0x1F56:  "\u1f50\u0342",
+	0x1F57:  "\u1f51\u0342",
+	0x1F59:  "\u03a5\u0314",
+	0x1F5B:  "\u1f59\u0300",
+	0x1F5D:  "\u1f59\u0301",
+	0x1F5F:  "\u1f59\u0342",
+	0x1F60:  "\u03c9\u0313",
+	0x1F61:  "\u03c9\u0314",
+	0x1F62:  "\u1f60\u0300",
+	0x1F63:  "\u1f61\u0300",
+	0x1F64:  "\u1f60\u0301",
+	0x1F65:  "\u1f61\u0301",
+	0x1F66:  "\u1f60\u0342",
+	0x1F67:  "\u1f61\u0342",
+	0x1F68:  "\u03a9\u0313",
+	0x1F69:  "\u03a9\u0314",
+	0x1F6A:  "\u1f68\u0300",
+	0x1F6B:  "\u1f69\u0300",
+	0x1F6C:  "\u1f68\u0301",
+	0x1F6D:  "\u1f69\u0301",
+	0x1F6E:  "\u1f68\u0342",
+	0x1F6F:  "\u1f69\u0342",
+	0x1F70:  "\u03b1\u0300",
+	0x1F71:  "\u03ac",
+	0x1F72:  "\u03b5\u0300",
+	0x1F73:  "\u03ad",
+	0x1F74:  "\u03b7\u0300",
+	0x1F75:  "\u03ae",
+	0x1F76:  "\u03b9\u0300",
+	0x1F77:  "\u03af",
+	0x1F78:  "\u03bf\u0300",
+	0x1F79:  "\u03cc",
+	0x1F7A:  "\u03c5\u0300",
+	0x1F7B:  "\u03cd",
+	0x1F7C:  "\u03c9\u0300",
+	0x1F7D:  "\u03ce",
+	0x1F80:  "\u1f00\u0345",
+	0x1F81:  "\u1f01\u0345",
+	0x1F82:  "\u1f02\u0345",
+	0x1F83:  "\u1f03\u0345",
+	0x1F84:  "\u1f04\u0345",
+	0x1F85:  "\u1f05\u0345",
+	0x1F86:  "\u1f06\u0345",
+	0x1F87:  "\u1f07\u0345",
+	0x1F88:  "\u1f08\u0345",
+	0x1F89:  "\u1f09\u0345",
+	0x1F8A:  "\u1f0a\u0345",
+	0x1F8B:  "\u1f0b\u0345",
+	0x1F8C:  "\u1f0c\u0345",
+	0x1F8D:  "\u1f0d\u0345",
+	0x1F8E:  "\u1f0e\u0345",
+	0x1F8F:  "\u1f0f\u0345",
+	0x1F90:  "\u1f20\u0345",
+	0x1F91:  "\u1f21\u0345",
+	0x1F92:  "\u1f22\u0345",
+	0x1F93:  "\u1f23\u0345",
+	0x1F94:  "\u1f24\u0345",
+	0x1F95:  "\u1f25\u0345",
+	0x1F96:  "\u1f26\u0345",
+	0x1F97:  "\u1f27\u0345",
+	0x1F98:  "\u1f28\u0345",
+	0x1F99:  "\u1f29\u0345",
+	0x1F9A:  "\u1f2a\u0345",
+	0x1F9B:  "\u1f2b\u0345",
+	0x1F9C:  "\u1f2c\u0345",
+	0x1F9D:  "\u1f2d\u0345",
+	0x1F9E:  "\u1f2e\u0345",
+	0x1F9F:  "\u1f2f\u0345",
+	0x1FA0:  "\u1f60\u0345",
+	0x1FA1:  "\u1f61\u0345",
+	0x1FA2:  "\u1f62\u0345",
+	0x1FA3:  "\u1f63\u0345",
+	0x1FA4:  "\u1f64\u0345",
+	0x1FA5:  "\u1f65\u0345",
+	0x1FA6:  "\u1f66\u0345",
+	0x1FA7:  "\u1f67\u0345",
+	0x1FA8:  "\u1f68\u0345",
+	0x1FA9:  "\u1f69\u0345",
+	0x1FAA:  "\u1f6a\u0345",
+	0x1FAB:  "\u1f6b\u0345",
+	0x1FAC:  "\u1f6c\u0345",
+	0x1FAD:  "\u1f6d\u0345",
+	0x1FAE:  "\u1f6e\u0345",
+	0x1FAF:  "\u1f6f\u0345",
+	0x1FB0:  "\u03b1\u0306",
+	0x1FB1:  "\u03b1\u0304",
+	0x1FB2:  "\u1f70\u0345",
+	0x1FB3:  "\u03b1\u0345",
+	0x1FB4:  "\u03ac\u0345",
+	0x1FB6:  "\u03b1\u0342",
+	0x1FB7:  "\u1fb6\u0345",
+	0x1FB8:  "\u0391\u0306",
+	0x1FB9:  "\u0391\u0304",
+	0x1FBA:  "\u0391\u0300",
+	0x1FBB:  "\u0386",
+	0x1FBC:  "\u0391\u0345",
+	0x1FBE:  "\u03b9",
+	0x1FC1:  "\u00a8\u0342",
+	0x1FC2:  "\u1f74\u0345",
+	0x1FC3:  "\u03b7\u0345",
+	0x1FC4:  "\u03ae\u0345",
+	0x1FC6:  "\u03b7\u0342",
+	0x1FC7:  "\u1fc6\u0345",
+	0x1FC8:  "\u0395\u0300",
+	0x1FC9:  "\u0388",
+	0x1FCA:  "\u0397\u0300",
+	0x1FCB:  "\u0389",
+	0x1FCC:  "\u0397\u0345",
+	0x1FCD:  "\u1fbf\u0300",
+	0x1FCE:  "\u1fbf\u0301",
+	0x1FCF:  "\u1fbf\u0342",
+	0x1FD0:  "\u03b9\u0306",
+	0x1FD1:  "\u03b9\u0304",
+	0x1FD2:  "\u03ca\u0300",
+	0x1FD3:  "\u0390",
+	0x1FD6:  "\u03b9\u0342",
+	0x1FD7:  "\u03ca\u0342",
+	0x1FD8:  "\u0399\u0306",
+	0x1FD9:  "\u0399\u0304",
+	0x1FDA:  "\u0399\u0300",
+	0x1FDB:  "\u038a",
+	0x1FDD:  "\u1ffe\u0300",
+	0x1FDE:  "\u1ffe\u0301",
+	0x1FDF:  "\u1ffe\u0342",
+	0x1FE0:  "\u03c5\u0306",
+	0x1FE1:  "\u03c5\u0304",
+	0x1FE2:  "\u03cb\u0300",
+	0x1FE3:  "\u03b0",
+	0x1FE4:  "\u03c1\u0313",
+	0x1FE5:  "\u03c1\u0314",
+	0x1FE6:  "\u03c5\u0342",
+	0x1FE7:  "\u03cb\u0342",
+	0x1FE8:  "\u03a5\u0306",
+	0x1FE9:  "\u03a5\u0304",
+	0x1FEA:  "\u03a5\u0300",
+	0x1FEB:  "\u038e",
+	0x1FEC:  "\u03a1\u0314",
+	0x1FED:  "\u00a8\u0300",
+	0x1FEE:  "\u0385",
+	0x1FEF:  "\u0060",
+	0x1FF2:  "\u1f7c\u0345",
+	0x1FF3:  "\u03c9\u0345",
+	0x1FF4:  "\u03ce\u0345",
+	0x1FF6:  "\u03c9\u0342",
+	0x1FF7:  "\u1ff6\u0345",
+	0x1FF8:  "\u039f\u0300",
+	0x1FF9:  "\u038c",
+	0x1FFA:  "\u03a9\u0300",
+	0x1FFB:  "\u038f",
+	0x1FFC:  "\u03a9\u0345",
+	0x1FFD:  "\u00b4",
+	0x2000:  "\u2002",
+	0x2001:  "\u2003",
+	0x2126:  "\u03a9",
+	0x212A:  "\u004b",
+	0x212B:  "\u00c5",
+	0x219A:  "\u2190\u0338",
+	0x219B:  "\u2192\u0338",
+	0x21AE:  "\u2194\u0338",
+	0x21CD:  "\u21d0\u0338",
+	0x21CE:  "\u21d4\u0338",
+	0x21CF:  "\u21d2\u0338",
+	0x2204:  "\u2203\u0338",
+	0x2209:  "\u2208\u0338",
+	0x220C:  "\u220b\u0338",
+	0x2224:  "\u2223\u0338",
+	0x2226:  "\u2225\u0338",
+	0x2241:  "\u223c\u0338",
+	0x2244:  "\u2243\u0338",
+	0x2247:  "\u2245\u0338",
+	0x2249:  "\u2248\u0338",
+	0x2260:  "\u003d\u0338",
+	0x2262:  "\u2261\u0338",
+	0x226D:  "\u224d\u0338",
+	0x226E:  "\u003c\u0338",
+	0x226F:  "\u003e\u0338",
+	0x2270:  "\u2264\u0338",
+	0x2271:  "\u2265\u0338",
+	0x2274:  "\u2272\u0338",
+	0x2275:  "\u2273\u0338",
+	0x2278:  "\u2276\u0338",
+	0x2279:  "\u2277\u0338",
+	0x2280:  "\u227a\u0338",
+	0x2281:  "\u227b\u0338",
+	0x2284:  "\u2282\u0338",
+	0x2285:  "\u2283\u0338",
+	0x2288:  "\u2286\u0338",
+	0x2289:  "\u2287\u0338",
+	0x22AC:  "\u22a2\u0338",
+	0x22AD:  "\u22a8\u0338",
+	0x22AE:  "\u22a9\u0338",
+	0x22AF:  "\u22ab\u0338",
+	0x22E0:  "\u227c\u0338",
+	0x22E1:  "\u227d\u0338",
+	0x22E2:  "\u2291\u0338",
+	0x22E3:  "\u2292\u0338",
+	0x22EA:  "\u22b2\u0338",
+	0x22EB:  "\u22b3\u0338",
+	0x22EC:  "\u22b4\u0338",
+	0x22ED:  "\u22b5\u0338",
+	0x2329:  "\u3008",
+	0x232A:  "\u3009",
+	0x2ADC:  "\u2add\u0338",
+	0x304C:  "\u304b\u3099",
+	0x304E:  "\u304d\u3099",
+	0x3050:  "\u304f\u3099",
+	0x3052:  "\u3051\u3099",
+	0x3054:  "\u3053\u3099",
+	0x3056:  "\u3055\u3099",
+	0x3058:  "\u3057\u3099",
+	0x305A:  "\u3059\u3099",
+	0x305C:  "\u305b\u3099",
+	0x305E:  "\u305d\u3099",
+	0x3060:  "\u305f\u3099",
+	0x3062:  "\u3061\u3099",
+	0x3065:  "\u3064\u3099",
+	0x3067:  "\u3066\u3099",
+	0x3069:  "\u3068\u3099",
+	0x3070:  "\u306f\u3099",
+	0x3071:  "\u306f\u309a",
+	0x3073:  "\u3072\u3099",
+	0x3074:  "\u3072\u309a",
+	0x3076:  "\u3075\u3099",
+	0x3077:  "\u3075\u309a",
+	0x3079:  "\u3078\u3099",
+	0x307A:  "\u3078\u309a",
+	0x307C:  "\u307b\u3099",
+	0x307D:  "\u307b\u309a",
+	0x3094:  "\u3046\u3099",
+	0x309E:  "\u309d\u3099",
+	0x30AC:  "\u30ab\u3099",
+	0x30AE:  "\u30ad\u3099",
+	0x30B0:  "\u30af\u3099",
+	0x30B2:  "\u30b1\u3099",
+	0x30B4:  "\u30b3\u3099",
+	0x30B6:  "\u30b5\u3099",
+	0x30B8:  "\u30b7\u3099",
+	0x30BA:  "\u30b9\u3099",
+	0x30BC:  "\u30bb\u3099",
+	0x30BE:  "\u30bd\u3099",
+	0x30C0:  "\u30bf\u3099",
+	0x30C2:  "\u30c1\u3099",
+	0x30C5:  "\u30c4\u3099",
+	0x30C7:  "\u30c6\u3099",
+	0x30C9:  "\u30c8\u3099",
+	0x30D0:  "\u30cf\u3099",
+	0x30D1:  "\u30cf\u309a",
+	0x30D3:  "\u30d2\u3099",
+	0x30D4:  "\u30d2\u309a",
+	0x30D6:  "\u30d5\u3099",
+	0x30D7:  "\u30d5\u309a",
+	0x30D9:  "\u30d8\u3099",
+	0x30DA:  "\u30d8\u309a",
+	0x30DC:  "\u30db\u3099",
+	0x30DD:  "\u30db\u309a",
+	0x30F4:  "\u30a6\u3099",
+	0x30F7:  "\u30ef\u3099",
+	0x30F8:  "\u30f0\u3099",
+	0x30F9:  "\u30f1\u3099",
+	0x30FA:  "\u30f2\u3099",
+	0x30FE:  "\u30fd\u3099",
+	0xF900:  "\u8c48",
+	0xF901:  "\u66f4",
+	0xF902:  "\u8eca",
+	0xF903:  "\u8cc8",
+	0xF904:  "\u6ed1",
+	0xF905:  "\u4e32",
+	0xF906:  "\u53e5",
+	0xF907:  "\u9f9c",
+	0xF908:  "\u9f9c",
+	0xF909:  "\u5951",
+	0xF90A:  "\u91d1",
+	0xF90B:  "\u5587",
+	0xF90C:  "\u5948",
+	0xF90D:  "\u61f6",
+	0xF90E:  "\u7669",
+	0xF90F:  "\u7f85",
+	0xF910:  "\u863f",
+	0xF911:  "\u87ba",
+	0xF912:  "\u88f8",
+	0xF913:  "\u908f",
+	0xF914:  "\u6a02",
+	0xF915:  "\u6d1b",
+	0xF916:  "\u70d9",
+	0xF917:  "\u73de",
+	0xF918:  "\u843d",
+	0xF919:  "\u916a",
+	0xF91A:  "\u99f1",
+	0xF91B:  "\u4e82",
+	0xF91C:  "\u5375",
+	0xF91D:  "\u6b04",
+	0xF91E:  "\u721b",
+	0xF91F:  "\u862d",
+	0xF920:  "\u9e1e",
+	0xF921:  "\u5d50",
+	0xF922:  "\u6feb",
+	0xF923:  "\u85cd",
+	0xF924:  "\u8964",
+	0xF925:  "\u62c9",
+	0xF926:  "\u81d8",
+	0xF927:  "\u881f",
+	0xF928:  "\u5eca",
+	0xF929:  "\u6717",
+	0xF92A:  "\u6d6a",
+	0xF92B:  "\u72fc",
+	0xF92C:  "\u90ce",
+	0xF92D:  "\u4f86",
+	0xF92E:  "\u51b7",
+	0xF92F:  "\u52de",
+	0xF930:  "\u64c4",
+	0xF931:  "\u6ad3",
+	0xF932:  "\u7210",
+	0xF933:  "\u76e7",
+	0xF934:  "\u8001",
+	0xF935:  "\u8606",
+	0xF936:  "\u865c",
+	0xF937:  "\u8def",
+	0xF938:  "\u9732",
+	0xF939:  "\u9b6f",
+	0xF93A:  "\u9dfa",
+	0xF93B:  "\u788c",
+	0xF93C:  "\u797f",
+	0xF93D:  "\u7da0",
+	0xF93E:  "\u83c9",
+	0xF93F:  "\u9304",
+	0xF940:  "\u9e7f",
+	0xF941:  "\u8ad6",
+	0xF942:  "\u58df",
+	0xF943:  "\u5f04",
+	0xF944:  "\u7c60",
+	0xF945:  "\u807e",
+	0xF946:  "\u7262",
+	0xF947:  "\u78ca",
+	0xF948:  "\u8cc2",
+	0xF949:  "\u96f7",
+	0xF94A:  "\u58d8",
+	0xF94B:  "\u5c62",
+	0xF94C:  "\u6a13",
+	0xF94D:  "\u6dda",
+	0xF94E:  "\u6f0f",
+	0xF94F:  "\u7d2f",
+	0xF950:  "\u7e37",
+	0xF951:  "\u964b",
+	0xF952:  "\u52d2",
+	0xF953:  "\u808b",
+	0xF954:  "\u51dc",
+	0xF955:  "\u51cc",
+	0xF956:  "\u7a1c",
+	0xF957:  "\u7dbe",
+	0xF958:  "\u83f1",
+	0xF959:  "\u9675",
+	0xF95A:  "\u8b80",
+	0xF95B:  "\u62cf",
+	0xF95C:  "\u6a02",
+	0xF95D:  "\u8afe",
+	0xF95E:  "\u4e39",
+	0xF95F:  "\u5be7",
+	0xF960:  "\u6012",
+	0xF961:  "\u7387",
+	0xF962:  "\u7570",
+	0xF963:  "\u5317",
+	0xF964:  "\u78fb",
+	0xF965:  "\u4fbf",
+	0xF966:  "\u5fa9",
+	0xF967:  "\u4e0d",
+	0xF968:  "\u6ccc",
+	0xF969:  "\u6578",
+	0xF96A:  "\u7d22",
+	0xF96B:  "\u53c3",
+	0xF96C:  "\u585e",
+	0xF96D:  "\u7701",
+	0xF96E:  "\u8449",
+	0xF96F:  "\u8aaa",
+	0xF970:  "\u6bba",
+	0xF971:  "\u8fb0",
+	0xF972:  "\u6c88",
+	0xF973:  "\u62fe",
+	0xF974:  "\u82e5",
+	0xF975:  "\u63a0",
+	0xF976:  "\u7565",
+	0xF977:  "\u4eae",
+	0xF978:  "\u5169",
+	0xF979:  "\u51c9",
+	0xF97A:  "\u6881",
+	0xF97B:  "\u7ce7",
+	0xF97C:  "\u826f",
+	0xF97D:  "\u8ad2",
+	0xF97E:  "\u91cf",
+	0xF97F:  "\u52f5",
+	0xF980:  "\u5442",
+	0xF981:  "\u5973",
+	0xF982:  "\u5eec",
+	0xF983:  "\u65c5",
+	0xF984:  "\u6ffe",
+	0xF985:  "\u792a",
+	0xF986:  "\u95ad",
+	0xF987:  "\u9a6a",
+	0xF988:  "\u9e97",
+	0xF989:  "\u9ece",
+	0xF98A:  "\u529b",
+	0xF98B:  "\u66c6",
+	0xF98C:  "\u6b77",
+	0xF98D:  "\u8f62",
+	0xF98E:  "\u5e74",
+	0xF98F:  "\u6190",
+	0xF990:  "\u6200",
+	0xF991:  "\u649a",
+	0xF992:  "\u6f23",
+	0xF993:  "\u7149",
+	0xF994:  "\u7489",
+	0xF995:  "\u79ca",
+	0xF996:  "\u7df4",
+	0xF997:  "\u806f",
+	0xF998:  "\u8f26",
+	0xF999:  "\u84ee",
+	0xF99A:  "\u9023",
+	0xF99B:  "\u934a",
+	0xF99C:  "\u5217",
+	0xF99D:  "\u52a3",
+	0xF99E:  "\u54bd",
+	0xF99F:  "\u70c8",
+	0xF9A0:  "\u88c2",
+	0xF9A1:  "\u8aaa",
+	0xF9A2:  "\u5ec9",
+	0xF9A3:  "\u5ff5",
+	0xF9A4:  "\u637b",
+	0xF9A5:  "\u6bae",
+	0xF9A6:  "\u7c3e",
+	0xF9A7:  "\u7375",
+	0xF9A8:  "\u4ee4",
+	0xF9A9:  "\u56f9",
+	0xF9AA:  "\u5be7",
+	0xF9AB:  "\u5dba",
+	0xF9AC:  "\u601c",
+	0xF9AD:  "\u73b2",
+	0xF9AE:  "\u7469",
+	0xF9AF:  "\u7f9a",
+	0xF9B0:  "\u8046",
+	0xF9B1:  "\u9234",
+	0xF9B2:  "\u96f6",
+	0xF9B3:  "\u9748",
+	0xF9B4:  "\u9818",
+	0xF9B5:  "\u4f8b",
+	0xF9B6:  "\u79ae",
+	0xF9B7:  "\u91b4",
+	0xF9B8:  "\u96b8",
+	0xF9B9:  "\u60e1",
+	0xF9BA:  "\u4e86",
+	0xF9BB:  "\u50da",
+	0xF9BC:  "\u5bee",
+	0xF9BD:  "\u5c3f",
+	0xF9BE:  "\u6599",
+	0xF9BF:  "\u6a02",
+	0xF9C0:  "\u71ce",
+	0xF9C1:  "\u7642",
+	0xF9C2:  "\u84fc",
+	0xF9C3:  "\u907c",
+	0xF9C4:  "\u9f8d",
+	0xF9C5:  "\u6688",
+	0xF9C6:  "\u962e",
+	0xF9C7:  "\u5289",
+	0xF9C8:  "\u677b",
+	0xF9C9:  "\u67f3",
+	0xF9CA:  "\u6d41",
+	0xF9CB:  "\u6e9c",
+	0xF9CC:  "\u7409",
+	0xF9CD:  "\u7559",
+	0xF9CE:  "\u786b",
+	0xF9CF:  "\u7d10",
+	0xF9D0:  "\u985e",
+	0xF9D1:  "\u516d",
+	0xF9D2:  "\u622e",
+	0xF9D3:  "\u9678",
+	0xF9D4:  "\u502b",
+	0xF9D5:  "\u5d19",
+	0xF9D6:  "\u6dea",
+	0xF9D7:  "\u8f2a",
+	0xF9D8:  "\u5f8b",
+	0xF9D9:  "\u6144",
+	0xF9DA:  "\u6817",
+	0xF9DB:  "\u7387",
+	0xF9DC:  "\u9686",
+	0xF9DD:  "\u5229",
+	0xF9DE:  "\u540f",
+	0xF9DF:  "\u5c65",
+	0xF9E0:  "\u6613",
+	0xF9E1:  "\u674e",
+	0xF9E2:  "\u68a8",
+	0xF9E3:  "\u6ce5",
+	0xF9E4:  "\u7406",
+	0xF9E5:  "\u75e2",
+	0xF9E6:  "\u7f79",
+	0xF9E7:  "\u88cf",
+	0xF9E8:  "\u88e1",
+	0xF9E9:  "\u91cc",
+	0xF9EA:  "\u96e2",
+	0xF9EB:  "\u533f",
+	0xF9EC:  "\u6eba",
+	0xF9ED:  "\u541d",
+	0xF9EE:  "\u71d0",
+	0xF9EF:  "\u7498",
+	0xF9F0:  "\u85fa",
+	0xF9F1:  "\u96a3",
+	0xF9F2:  "\u9c57",
+	0xF9F3:  "\u9e9f",
+	0xF9F4:  "\u6797",
+	0xF9F5:  "\u6dcb",
+	0xF9F6:  "\u81e8",
+	0xF9F7:  "\u7acb",
+	0xF9F8:  "\u7b20",
+	0xF9F9:  "\u7c92",
+	0xF9FA:  "\u72c0",
+	0xF9FB:  "\u7099",
+	0xF9FC:  "\u8b58",
+	0xF9FD:  "\u4ec0",
+	0xF9FE:  "\u8336",
+	0xF9FF:  "\u523a",
+	0xFA00:  "\u5207",
+	0xFA01:  "\u5ea6",
+	0xFA02:  "\u62d3",
+	0xFA03:  "\u7cd6",
+	0xFA04:  "\u5b85",
+	0xFA05:  "\u6d1e",
+	0xFA06:  "\u66b4",
+	0xFA07:  "\u8f3b",
+	0xFA08:  "\u884c",
+	0xFA09:  "\u964d",
+	0xFA0A:  "\u898b",
+	0xFA0B:  "\u5ed3",
+	0xFA0C:  "\u5140",
+	0xFA0D:  "\u55c0",
+	0xFA10:  "\u585a",
+	0xFA12:  "\u6674",
+	0xFA15:  "\u51de",
+	0xFA16:  "\u732a",
+	0xFA17:  "\u76ca",
+	0xFA18:  "\u793c",
+	0xFA19:  "\u795e",
+	0xFA1A:  "\u7965",
+	0xFA1B:  "\u798f",
+	0xFA1C:  "\u9756",
+	0xFA1D:  "\u7cbe",
+	0xFA1E:  "\u7fbd",
+	0xFA20:  "\u8612",
+	0xFA22:  "\u8af8",
+	0xFA25:  "\u9038",
+	0xFA26:  "\u90fd",
+	0xFA2A:  "\u98ef",
+	0xFA2B:  "\u98fc",
+	0xFA2C:  "\u9928",
+	0xFA2D:  "\u9db4",
+	0xFA2E:  "\u90de",
+	0xFA2F:  "\u96b7",
+	0xFA30:  "\u4fae",
+	0xFA31:  "\u50e7",
+	0xFA32:  "\u514d",
+	0xFA33:  "\u52c9",
+	0xFA34:  "\u52e4",
+	0xFA35:  "\u5351",
+	0xFA36:  "\u559d",
+	0xFA37:  "\u5606",
+	0xFA38:  "\u5668",
+	0xFA39:  "\u5840",
+	0xFA3A:  "\u58a8",
+	0xFA3B:  "\u5c64",
+	0xFA3C:  "\u5c6e",
+	0xFA3D:  "\u6094",
+	0xFA3E:  "\u6168",
+	0xFA3F:  "\u618e",
+	0xFA40:  "\u61f2",
+	0xFA41:  "\u654f",
+	0xFA42:  "\u65e2",
+	0xFA43:  "\u6691",
+	0xFA44:  "\u6885",
+	0xFA45:  "\u6d77",
+	0xFA46:  "\u6e1a",
+	0xFA47:  "\u6f22",
+	0xFA48:  "\u716e",
+	0xFA49:  "\u722b",
+	0xFA4A:  "\u7422",
+	0xFA4B:  "\u7891",
+	0xFA4C:  "\u793e",
+	0xFA4D:  "\u7949",
+	0xFA4E:  "\u7948",
+	0xFA4F:  "\u7950",
+	0xFA50:  "\u7956",
+	0xFA51:  "\u795d",
+	0xFA52:  "\u798d",
+	0xFA53:  "\u798e",
+	0xFA54:  "\u7a40",
+	0xFA55:  "\u7a81",
+	0xFA56:  "\u7bc0",
+	0xFA57:  "\u7df4",
+	0xFA58:  "\u7e09",
+	0xFA59:  "\u7e41",
+	0xFA5A:  "\u7f72",
+	0xFA5B:  "\u8005",
+	0xFA5C:  "\u81ed",
+	0xFA5D:  "\u8279",
+	0xFA5E:  "\u8279",
+	0xFA5F:  "\u8457",
+	0xFA60:  "\u8910",
+	0xFA61:  "\u8996",
+	0xFA62:  "\u8b01",
+	0xFA63:  "\u8b39",
+	0xFA64:  "\u8cd3",
+	0xFA65:  "\u8d08",
+	0xFA66:  "\u8fb6",
+	0xFA67:  "\u9038",
+	0xFA68:  "\u96e3",
+	0xFA69:  "\u97ff",
+	0xFA6A:  "\u983b",
+	0xFA6B:  "\u6075",
+	0xFA6C:  "\U000242ee",
+	0xFA6D:  "\u8218",
+	0xFA70:  "\u4e26",
+	0xFA71:  "\u51b5",
+	0xFA72:  "\u5168",
+	0xFA73:  "\u4f80",
+	0xFA74:  "\u5145",
+	0xFA75:  "\u5180",
+	0xFA76:  "\u52c7",
+	0xFA77:  "\u52fa",
+	0xFA78:  "\u559d",
+	0xFA79:  "\u5555",
+	0xFA7A:  "\u5599",
+	0xFA7B:  "\u55e2",
+	0xFA7C:  "\u585a",
+	0xFA7D:  "\u58b3",
+	0xFA7E:  "\u5944",
+	0xFA7F:  "\u5954",
+	0xFA80:  "\u5a62",
+	0xFA81:  "\u5b28",
+	0xFA82:  "\u5ed2",
+	0xFA83:  "\u5ed9",
+	0xFA84:  "\u5f69",
+	0xFA85:  "\u5fad",
+	0xFA86:  "\u60d8",
+	0xFA87:  "\u614e",
+	0xFA88:  "\u6108",
+	0xFA89:  "\u618e",
+	0xFA8A:  "\u6160",
+	0xFA8B:  "\u61f2",
+	0xFA8C:  "\u6234",
+	0xFA8D:  "\u63c4",
+	0xFA8E:  "\u641c",
+	0xFA8F:  "\u6452",
+	0xFA90:  "\u6556",
+	0xFA91:  "\u6674",
+	0xFA92:  "\u6717",
+	0xFA93:  "\u671b",
+	0xFA94:  "\u6756",
+	0xFA95:  "\u6b79",
+	0xFA96:  "\u6bba",
+	0xFA97:  "\u6d41",
+	0xFA98:  "\u6edb",
+	0xFA99:  "\u6ecb",
+	0xFA9A:  "\u6f22",
+	0xFA9B:  "\u701e",
+	0xFA9C:  "\u716e",
+	0xFA9D:  "\u77a7",
+	0xFA9E:  "\u7235",
+	0xFA9F:  "\u72af",
+	0xFAA0:  "\u732a",
+	0xFAA1:  "\u7471",
+	0xFAA2:  "\u7506",
+	0xFAA3:  "\u753b",
+	0xFAA4:  "\u761d",
+	0xFAA5:  "\u761f",
+	0xFAA6:  "\u76ca",
+	0xFAA7:  "\u76db",
+	0xFAA8:  "\u76f4",
+	0xFAA9:  "\u774a",
+	0xFAAA:  "\u7740",
+	0xFAAB:  "\u78cc",
+	0xFAAC:  "\u7ab1",
+	0xFAAD:  "\u7bc0",
+	0xFAAE:  "\u7c7b",
+	0xFAAF:  "\u7d5b",
+	0xFAB0:  "\u7df4",
+	0xFAB1:  "\u7f3e",
+	0xFAB2:  "\u8005",
+	0xFAB3:  "\u8352",
+	0xFAB4:  "\u83ef",
+	0xFAB5:  "\u8779",
+	0xFAB6:  "\u8941",
+	0xFAB7:  "\u8986",
+	0xFAB8:  "\u8996",
+	0xFAB9:  "\u8abf",
+	0xFABA:  "\u8af8",
+	0xFABB:  "\u8acb",
+	0xFABC:  "\u8b01",
+	0xFABD:  "\u8afe",
+	0xFABE:  "\u8aed",
+	0xFABF:  "\u8b39",
+	0xFAC0:  "\u8b8a",
+	0xFAC1:  "\u8d08",
+	0xFAC2:  "\u8f38",
+	0xFAC3:  "\u9072",
+	0xFAC4:  "\u9199",
+	0xFAC5:  "\u9276",
+	0xFAC6:  "\u967c",
+	0xFAC7:  "\u96e3",
+	0xFAC8:  "\u9756",
+	0xFAC9:  "\u97db",
+	0xFACA:  "\u97ff",
+	0xFACB:  "\u980b",
+	0xFACC:  "\u983b",
+	0xFACD:  "\u9b12",
+	0xFACE:  "\u9f9c",
+	0xFACF:  "\U0002284a",
+	0xFAD0:  "\U00022844",
+	0xFAD1:  "\U000233d5",
+	0xFAD2:  "\u3b9d",
+	0xFAD3:  "\u4018",
+	0xFAD4:  "\u4039",
+	0xFAD5:  "\U00025249",
+	0xFAD6:  "\U00025cd0",
+	0xFAD7:  "\U00027ed3",
+	0xFAD8:  "\u9f43",
+	0xFAD9:  "\u9f8e",
+	0xFB1D:  "\u05d9\u05b4",
+	0xFB1F:  "\u05f2\u05b7",
+	0xFB2A:  "\u05e9\u05c1",
+	0xFB2B:  "\u05e9\u05c2",
+	0xFB2C:  "\ufb49\u05c1",
+	0xFB2D:  "\ufb49\u05c2",
+	0xFB2E:  "\u05d0\u05b7",
+	0xFB2F:  "\u05d0\u05b8",
+	0xFB30:  "\u05d0\u05bc",
+	0xFB31:  "\u05d1\u05bc",
+	0xFB32:  "\u05d2\u05bc",
+	0xFB33:  "\u05d3\u05bc",
+	0xFB34:  "\u05d4\u05bc",
+	0xFB35:  "\u05d5\u05bc",
+	0xFB36:  "\u05d6\u05bc",
+	0xFB38:  "\u05d8\u05bc",
+	0xFB39:  "\u05d9\u05bc",
+	0xFB3A:  "\u05da\u05bc",
+	0xFB3B:  "\u05db\u05bc",
+	0xFB3C:  "\u05dc\u05bc",
+	0xFB3E:  "\u05de\u05bc",
+	0xFB40:  "\u05e0\u05bc",
+	0xFB41:  "\u05e1\u05bc",
+	0xFB43:  "\u05e3\u05bc",
+	0xFB44:  "\u05e4\u05bc",
+	0xFB46:  "\u05e6\u05bc",
+	0xFB47:  "\u05e7\u05bc",
+	0xFB48:  "\u05e8\u05bc",
+	0xFB49:  "\u05e9\u05bc",
+	0xFB4A:  "\u05ea\u05bc",
+	0xFB4B:  "\u05d5\u05b9",
+	0xFB4C:  "\u05d1\u05bf",
+	0xFB4D:  "\u05db\u05bf",
+	0xFB4E:  "\u05e4\u05bf",
+	0x1109A: "\U00011099\U000110ba",
+	0x1109C: "\U0001109b\U000110ba",
+	0x110AB: "\U000110a5\U000110ba",
+	0x1112E: "\U00011131\U00011127",
+	0x1112F: "\U00011132\U00011127",
+	0x1134B: "\U00011347\U0001133e",
+	0x1134C: "\U00011347\U00011357",
+	0x114BB: "\U000114b9\U000114ba",
+	0x114BC: "\U000114b9\U000114b0",
+	0x114BE: "\U000114b9\U000114bd",
+	0x115BA: "\U000115b8\U000115af",
+	0x115BB: "\U000115b9\U000115af",
+	0x11938: "\U00011935\U00011930",
+	0x1D15E: "\U0001d157\U0001d165",
+	0x1D15F: "\U0001d158\U0001d165",
+	0x1D160: "\U0001d15f\U0001d16e",
+	0x1D161: "\U0001d15f\U0001d16f",
+	0x1D162: "\U0001d15f\U0001d170",
+	0x1D163: "\U0001d15f\U0001d171",
+	0x1D164: "\U0001d15f\U0001d172",
+	0x1D1BB: "\U0001d1b9\U0001d165",
+	0x1D1BC: "\U0001d1ba\U0001d165",
+	0x1D1BD: "\U0001d1bb\U0001d16e",
+	0x1D1BE: "\U0001d1bc\U0001d16e",
+	0x1D1BF: "\U0001d1bb\U0001d16f",
+	0x1D1C0: "\U0001d1bc\U0001d16f",
+	0x2F800: "\u4e3d",
+	0x2F801: "\u4e38",
+	0x2F802: "\u4e41",
+	0x2F803: "\U00020122",
+	0x2F804: "\u4f60",
+	0x2F805: "\u4fae",
+	0x2F806: "\u4fbb",
+	0x2F807: "\u5002",
+	0x2F808: "\u507a",
+	0x2F809: "\u5099",
+	0x2F80A: "\u50e7",
+	0x2F80B: "\u50cf",
+	0x2F80C: "\u349e",
+	0x2F80D: "\U0002063a",
+	0x2F80E: "\u514d",
+	0x2F80F: "\u5154",
+	0x2F810: "\u5164",
+	0x2F811: "\u5177",
+	0x2F812: "\U0002051c",
+	0x2F813: "\u34b9",
+	0x2F814: "\u5167",
+	0x2F815: "\u518d",
+	0x2F816: "\U0002054b",
+	0x2F817: "\u5197",
+	0x2F818: "\u51a4",
+	0x2F819: "\u4ecc",
+	0x2F81A: "\u51ac",
+	0x2F81B: "\u51b5",
+	0x2F81C: "\U000291df",
+	0x2F81D: "\u51f5",
+	0x2F81E: "\u5203",
+	0x2F81F: "\u34df",
+	0x2F820: "\u523b",
+	0x2F821: "\u5246",
+	0x2F822: "\u5272",
+	0x2F823: "\u5277",
+	0x2F824: "\u3515",
+	0x2F825: "\u52c7",
+	0x2F826: "\u52c9",
+	0x2F827: "\u52e4",
+	0x2F828: "\u52fa",
+	0x2F829: "\u5305",
+	0x2F82A: "\u5306",
+	0x2F82B: "\u5317",
+	0x2F82C: "\u5349",
+	0x2F82D: "\u5351",
+	0x2F82E: "\u535a",
+	0x2F82F: "\u5373",
+	0x2F830: "\u537d",
+	0x2F831: "\u537f",
+	0x2F832: "\u537f",
+	0x2F833: "\u537f",
+	0x2F834: "\U00020a2c",
+	0x2F835: "\u7070",
+	0x2F836: "\u53ca",
+	0x2F837: "\u53df",
+	0x2F838: "\U00020b63",
+	0x2F839: "\u53eb",
+	0x2F83A: "\u53f1",
+	0x2F83B: "\u5406",
+	0x2F83C: "\u549e",
+	0x2F83D: "\u5438",
+	0x2F83E: "\u5448",
+	0x2F83F: "\u5468",
+	0x2F840: "\u54a2",
+	0x2F841: "\u54f6",
+	0x2F842: "\u5510",
+	0x2F843: "\u5553",
+	0x2F844: "\u5563",
+	0x2F845: "\u5584",
+	0x2F846: "\u5584",
+	0x2F847: "\u5599",
+	0x2F848: "\u55ab",
+	0x2F849: "\u55b3",
+	0x2F84A: "\u55c2",
+	0x2F84B: "\u5716",
+	0x2F84C: "\u5606",
+	0x2F84D: "\u5717",
+	0x2F84E: "\u5651",
+	0x2F84F: "\u5674",
+	0x2F850: "\u5207",
+	0x2F851: "\u58ee",
+	0x2F852: "\u57ce",
+	0x2F853: "\u57f4",
+	0x2F854: "\u580d",
+	0x2F855: "\u578b",
+	0x2F856: "\u5832",
+	0x2F857: "\u5831",
+	0x2F858: "\u58ac",
+	0x2F859: "\U000214e4",
+	0x2F85A: "\u58f2",
+	0x2F85B: "\u58f7",
+	0x2F85C: "\u5906",
+	0x2F85D: "\u591a",
+	0x2F85E: "\u5922",
+	0x2F85F: "\u5962",
+	0x2F860: "\U000216a8",
+	0x2F861: "\U000216ea",
+	0x2F862: "\u59ec",
+	0x2F863: "\u5a1b",
+	0x2F864: "\u5a27",
+	0x2F865: "\u59d8",
+	0x2F866: "\u5a66",
+	0x2F867: "\u36ee",
+	0x2F868: "\u36fc",
+	0x2F869: "\u5b08",
+	0x2F86A: "\u5b3e",
+	0x2F86B: "\u5b3e",
+	0x2F86C: "\U000219c8",
+	0x2F86D: "\u5bc3",
+	0x2F86E: "\u5bd8",
+	0x2F86F: "\u5be7",
+	0x2F870: "\u5bf3",
+	0x2F871: "\U00021b18",
+	0x2F872: "\u5bff",
+	0x2F873: "\u5c06",
+	0x2F874: "\u5f53",
+	0x2F875: "\u5c22",
+	0x2F876: "\u3781",
+	0x2F877: "\u5c60",
+	0x2F878: "\u5c6e",
+	0x2F879: "\u5cc0",
+	0x2F87A: "\u5c8d",
+	0x2F87B: "\U00021de4",
+	0x2F87C: "\u5d43",
+	0x2F87D: "\U00021de6",
+	0x2F87E: "\u5d6e",
+	0x2F87F: "\u5d6b",
+	0x2F880: "\u5d7c",
+	0x2F881: "\u5de1",
+	0x2F882: "\u5de2",
+	0x2F883: "\u382f",
+	0x2F884: "\u5dfd",
+	0x2F885: "\u5e28",
+	0x2F886: "\u5e3d",
+	0x2F887: "\u5e69",
+	0x2F888: "\u3862",
+	0x2F889: "\U00022183",
+	0x2F88A: "\u387c",
+	0x2F88B: "\u5eb0",
+	0x2F88C: "\u5eb3",
+	0x2F88D: "\u5eb6",
+	0x2F88E: "\u5eca",
+	0x2F88F: "\U0002a392",
+	0x2F890: "\u5efe",
+	0x2F891: "\U00022331",
+	0x2F892: "\U00022331",
+	0x2F893: "\u8201",
+	0x2F894: "\u5f22",
+	0x2F895: "\u5f22",
+	0x2F896: "\u38c7",
+	0x2F897: "\U000232b8",
+	0x2F898: "\U000261da",
+	0x2F899: "\u5f62",
+	0x2F89A: "\u5f6b",
+	0x2F89B: "\u38e3",
+	0x2F89C: "\u5f9a",
+	0x2F89D: "\u5fcd",
+	0x2F89E: "\u5fd7",
+	0x2F89F: "\u5ff9",
+	0x2F8A0: "\u6081",
+	0x2F8A1: "\u393a",
+	0x2F8A2: "\u391c",
+	0x2F8A3: "\u6094",
+	0x2F8A4: "\U000226d4",
+	0x2F8A5: "\u60c7",
+	0x2F8A6: "\u6148",
+	0x2F8A7: "\u614c",
+	0x2F8A8: "\u614e",
+	0x2F8A9: "\u614c",
+	0x2F8AA: "\u617a",
+	0x2F8AB: "\u618e",
+	0x2F8AC: "\u61b2",
+	0x2F8AD: "\u61a4",
+	0x2F8AE: "\u61af",
+	0x2F8AF: "\u61de",
+	0x2F8B0: "\u61f2",
+	0x2F8B1: "\u61f6",
+	0x2F8B2: "\u6210",
+	0x2F8B3: "\u621b",
+	0x2F8B4: "\u625d",
+	0x2F8B5: "\u62b1",
+	0x2F8B6: "\u62d4",
+	0x2F8B7: "\u6350",
+	0x2F8B8: "\U00022b0c",
+	0x2F8B9: "\u633d",
+	0x2F8BA: "\u62fc",
+	0x2F8BB: "\u6368",
+	0x2F8BC: "\u6383",
+	0x2F8BD: "\u63e4",
+	0x2F8BE: "\U00022bf1",
+	0x2F8BF: "\u6422",
+	0x2F8C0: "\u63c5",
+	0x2F8C1: "\u63a9",
+	0x2F8C2: "\u3a2e",
+	0x2F8C3: "\u6469",
+	0x2F8C4: "\u647e",
+	0x2F8C5: "\u649d",
+	0x2F8C6: "\u6477",
+	0x2F8C7: "\u3a6c",
+	0x2F8C8: "\u654f",
+	0x2F8C9: "\u656c",
+	0x2F8CA: "\U0002300a",
+	0x2F8CB: "\u65e3",
+	0x2F8CC: "\u66f8",
+	0x2F8CD: "\u6649",
+	0x2F8CE: "\u3b19",
+	0x2F8CF: "\u6691",
+	0x2F8D0: "\u3b08",
+	0x2F8D1: "\u3ae4",
+	0x2F8D2: "\u5192",
+	0x2F8D3: "\u5195",
+	0x2F8D4: "\u6700",
+	0x2F8D5: "\u669c",
+	0x2F8D6: "\u80ad",
+	0x2F8D7: "\u43d9",
+	0x2F8D8: "\u6717",
+	0x2F8D9: "\u671b",
+	0x2F8DA: "\u6721",
+	0x2F8DB: "\u675e",
+	0x2F8DC: "\u6753",
+	0x2F8DD: "\U000233c3",
+	0x2F8DE: "\u3b49",
+	0x2F8DF: "\u67fa",
+	0x2F8E0: "\u6785",
+	0x2F8E1: "\u6852",
+	0x2F8E2: "\u6885",
+	0x2F8E3: "\U0002346d",
+	0x2F8E4: "\u688e",
+	0x2F8E5: "\u681f",
+	0x2F8E6: "\u6914",
+	0x2F8E7: "\u3b9d",
+	0x2F8E8: "\u6942",
+	0x2F8E9: "\u69a3",
+	0x2F8EA: "\u69ea",
+	0x2F8EB: "\u6aa8",
+	0x2F8EC: "\U000236a3",
+	0x2F8ED: "\u6adb",
+	0x2F8EE: "\u3c18",
+	0x2F8EF: "\u6b21",
+	0x2F8F0: "\U000238a7",
+	0x2F8F1: "\u6b54",
+	0x2F8F2: "\u3c4e",
+	0x2F8F3: "\u6b72",
+	0x2F8F4: "\u6b9f",
+	0x2F8F5: "\u6bba",
+	0x2F8F6: "\u6bbb",
+	0x2F8F7: "\U00023a8d",
+	0x2F8F8: "\U00021d0b",
+	0x2F8F9: "\U00023afa",
+	0x2F8FA: "\u6c4e",
+	0x2F8FB: "\U00023cbc",
+	0x2F8FC: "\u6cbf",
+	0x2F8FD: "\u6ccd",
+	0x2F8FE: "\u6c67",
+	0x2F8FF: "\u6d16",
+	0x2F900: "\u6d3e",
+	0x2F901: "\u6d77",
+	0x2F902: "\u6d41",
+	0x2F903: "\u6d69",
+	0x2F904: "\u6d78",
+	0x2F905: "\u6d85",
+	0x2F906: "\U00023d1e",
+	0x2F907: "\u6d34",
+	0x2F908: "\u6e2f",
+	0x2F909: "\u6e6e",
+	0x2F90A: "\u3d33",
+	0x2F90B: "\u6ecb",
+	0x2F90C: "\u6ec7",
+	0x2F90D: "\U00023ed1",
+	0x2F90E: "\u6df9",
+	0x2F90F: "\u6f6e",
+	0x2F910: "\U00023f5e",
+	0x2F911: "\U00023f8e",
+	0x2F912: "\u6fc6",
+	0x2F913: "\u7039",
+	0x2F914: "\u701e",
+	0x2F915: "\u701b",
+	0x2F916: "\u3d96",
+	0x2F917: "\u704a",
+	0x2F918: "\u707d",
+	0x2F919: "\u7077",
+	0x2F91A: "\u70ad",
+	0x2F91B: "\U00020525",
+	0x2F91C: "\u7145",
+	0x2F91D: "\U00024263",
+	0x2F91E: "\u719c",
+	0x2F91F: "\U000243ab",
+	0x2F920: "\u7228",
+	0x2F921: "\u7235",
+	0x2F922: "\u7250",
+	0x2F923: "\U00024608",
+	0x2F924: "\u7280",
+	0x2F925: "\u7295",
+	0x2F926: "\U00024735",
+	0x2F927: "\U00024814",
+	0x2F928: "\u737a",
+	0x2F929: "\u738b",
+	0x2F92A: "\u3eac",
+	0x2F92B: "\u73a5",
+	0x2F92C: "\u3eb8",
+	0x2F92D: "\u3eb8",
+	0x2F92E: "\u7447",
+	0x2F92F: "\u745c",
+	0x2F930: "\u7471",
+	0x2F931: "\u7485",
+	0x2F932: "\u74ca",
+	0x2F933: "\u3f1b",
+	0x2F934: "\u7524",
+	0x2F935: "\U00024c36",
+	0x2F936: "\u753e",
+	0x2F937: "\U00024c92",
+	0x2F938: "\u7570",
+	0x2F939: "\U0002219f",
+	0x2F93A: "\u7610",
+	0x2F93B: "\U00024fa1",
+	0x2F93C: "\U00024fb8",
+	0x2F93D: "\U00025044",
+	0x2F93E: "\u3ffc",
+	0x2F93F: "\u4008",
+	0x2F940: "\u76f4",
+	0x2F941: "\U000250f3",
+	0x2F942: "\U000250f2",
+	0x2F943: "\U00025119",
+	0x2F944: "\U00025133",
+	0x2F945: "\u771e",
+	0x2F946: "\u771f",
+	0x2F947: "\u771f",
+	0x2F948: "\u774a",
+	0x2F949: "\u4039",
+	0x2F94A: "\u778b",
+	0x2F94B: "\u4046",
+	0x2F94C: "\u4096",
+	0x2F94D: "\U0002541d",
+	0x2F94E: "\u784e",
+	0x2F94F: "\u788c",
+	0x2F950: "\u78cc",
+	0x2F951: "\u40e3",
+	0x2F952: "\U00025626",
+	0x2F953: "\u7956",
+	0x2F954: "\U0002569a",
+	0x2F955: "\U000256c5",
+	0x2F956: "\u798f",
+	0x2F957: "\u79eb",
+	0x2F958: "\u412f",
+	0x2F959: "\u7a40",
+	0x2F95A: "\u7a4a",
+	0x2F95B: "\u7a4f",
+	0x2F95C: "\U0002597c",
+	0x2F95D: "\U00025aa7",
+	0x2F95E: "\U00025aa7",
+	0x2F95F: "\u7aee",
+	0x2F960: "\u4202",
+	0x2F961: "\U00025bab",
+	0x2F962: "\u7bc6",
+	0x2F963: "\u7bc9",
+	0x2F964: "\u4227",
+	0x2F965: "\U00025c80",
+	0x2F966: "\u7cd2",
+	0x2F967: "\u42a0",
+	0x2F968: "\u7ce8",
+	0x2F969: "\u7ce3",
+	0x2F96A: "\u7d00",
+	0x2F96B: "\U00025f86",
+	0x2F96C: "\u7d63",
+	0x2F96D: "\u4301",
+	0x2F96E: "\u7dc7",
+	0x2F96F: "\u7e02",
+	0x2F970: "\u7e45",
+	0x2F971: "\u4334",
+	0x2F972: "\U00026228",
+	0x2F973: "\U00026247",
+	0x2F974: "\u4359",
+	0x2F975: "\U000262d9",
+	0x2F976: "\u7f7a",
+	0x2F977: "\U0002633e",
+	0x2F978: "\u7f95",
+	0x2F979: "\u7ffa",
+	0x2F97A: "\u8005",
+	0x2F97B: "\U000264da",
+	0x2F97C: "\U00026523",
+	0x2F97D: "\u8060",
+	0x2F97E: "\U000265a8",
+	0x2F97F: "\u8070",
+	0x2F980: "\U0002335f",
+	0x2F981: "\u43d5",
+	0x2F982: "\u80b2",
+	0x2F983: "\u8103",
+	0x2F984: "\u440b",
+	0x2F985: "\u813e",
+	0x2F986: "\u5ab5",
+	0x2F987: "\U000267a7",
+	0x2F988: "\U000267b5",
+	0x2F989: "\U00023393",
+	0x2F98A: "\U0002339c",
+	0x2F98B: "\u8201",
+	0x2F98C: "\u8204",
+	0x2F98D: "\u8f9e",
+	0x2F98E: "\u446b",
+	0x2F98F: "\u8291",
+	0x2F990: "\u828b",
+	0x2F991: "\u829d",
+	0x2F992: "\u52b3",
+	0x2F993: "\u82b1",
+	0x2F994: "\u82b3",
+	0x2F995: "\u82bd",
+	0x2F996: "\u82e6",
+	0x2F997: "\U00026b3c",
+	0x2F998: "\u82e5",
+	0x2F999: "\u831d",
+	0x2F99A: "\u8363",
+	0x2F99B: "\u83ad",
+	0x2F99C: "\u8323",
+	0x2F99D: "\u83bd",
+	0x2F99E: "\u83e7",
+	0x2F99F: "\u8457",
+	0x2F9A0: "\u8353",
+	0x2F9A1: "\u83ca",
+	0x2F9A2: "\u83cc",
+	0x2F9A3: "\u83dc",
+	0x2F9A4: "\U00026c36",
+	0x2F9A5: "\U00026d6b",
+	0x2F9A6: "\U00026cd5",
+	0x2F9A7: "\u452b",
+	0x2F9A8: "\u84f1",
+	0x2F9A9: "\u84f3",
+	0x2F9AA: "\u8516",
+	0x2F9AB: "\U000273ca",
+	0x2F9AC: "\u8564",
+	0x2F9AD: "\U00026f2c",
+	0x2F9AE: "\u455d",
+	0x2F9AF: "\u4561",
+	0x2F9B0: "\U00026fb1",
+	0x2F9B1: "\U000270d2",
+	0x2F9B2: "\u456b",
+	0x2F9B3: "\u8650",
+	0x2F9B4: "\u865c",
+	0x2F9B5: "\u8667",
+	0x2F9B6: "\u8669",
+	0x2F9B7: "\u86a9",
+	0x2F9B8: "\u8688",
+	0x2F9B9: "\u870e",
+	0x2F9BA: "\u86e2",
+	0x2F9BB: "\u8779",
+	0x2F9BC: "\u8728",
+	0x2F9BD: "\u876b",
+	0x2F9BE: "\u8786",
+	0x2F9BF: "\u45d7",
+	0x2F9C0: "\u87e1",
+	0x2F9C1: "\u8801",
+	0x2F9C2: "\u45f9",
+	0x2F9C3: "\u8860",
+	0x2F9C4: "\u8863",
+	0x2F9C5: "\U00027667",
+	0x2F9C6: "\u88d7",
+	0x2F9C7: "\u88de",
+	0x2F9C8: "\u4635",
+	0x2F9C9: "\u88fa",
+	0x2F9CA: "\u34bb",
+	0x2F9CB: "\U000278ae",
+	0x2F9CC: "\U00027966",
+	0x2F9CD: "\u46be",
+	0x2F9CE: "\u46c7",
+	0x2F9CF: "\u8aa0",
+	0x2F9D0: "\u8aed",
+	0x2F9D1: "\u8b8a",
+	0x2F9D2: "\u8c55",
+	0x2F9D3: "\U00027ca8",
+	0x2F9D4: "\u8cab",
+	0x2F9D5: "\u8cc1",
+	0x2F9D6: "\u8d1b",
+	0x2F9D7: "\u8d77",
+	0x2F9D8: "\U00027f2f",
+	0x2F9D9: "\U00020804",
+	0x2F9DA: "\u8dcb",
+	0x2F9DB: "\u8dbc",
+	0x2F9DC: "\u8df0",
+	0x2F9DD: "\U000208de",
+	0x2F9DE: "\u8ed4",
+	0x2F9DF: "\u8f38",
+	0x2F9E0: "\U000285d2",
+	0x2F9E1: "\U000285ed",
+	0x2F9E2: "\u9094",
+	0x2F9E3: "\u90f1",
+	0x2F9E4: "\u9111",
+	0x2F9E5: "\U0002872e",
+	0x2F9E6: "\u911b",
+	0x2F9E7: "\u9238",
+	0x2F9E8: "\u92d7",
+	0x2F9E9: "\u92d8",
+	0x2F9EA: "\u927c",
+	0x2F9EB: "\u93f9",
+	0x2F9EC: "\u9415",
+	0x2F9ED: "\U00028bfa",
+	0x2F9EE: "\u958b",
+	0x2F9EF: "\u4995",
+	0x2F9F0: "\u95b7",
+	0x2F9F1: "\U00028d77",
+	0x2F9F2: "\u49e6",
+	0x2F9F3: "\u96c3",
+	0x2F9F4: "\u5db2",
+	0x2F9F5: "\u9723",
+	0x2F9F6: "\U00029145",
+	0x2F9F7: "\U0002921a",
+	0x2F9F8: "\u4a6e",
+	0x2F9F9: "\u4a76",
+	0x2F9FA: "\u97e0",
+	0x2F9FB: "\U0002940a",
+	0x2F9FC: "\u4ab2",
+	0x2F9FD: "\U00029496",
+	0x2F9FE: "\u980b",
+	0x2F9FF: "\u980b",
+	0x2FA00: "\u9829",
+	0x2FA01: "\U000295b6",
+	0x2FA02: "\u98e2",
+	0x2FA03: "\u4b33",
+	0x2FA04: "\u9929",
+	0x2FA05: "\u99a7",
+	0x2FA06: "\u99c2",
+	0x2FA07: "\u99fe",
+	0x2FA08: "\u4bce",
+	0x2FA09: "\U00029b30",
+	0x2FA0A: "\u9b12",
+	0x2FA0B: "\u9c40",
+	0x2FA0C: "\u9cfd",
+	0x2FA0D: "\u4cce",
+	0x2FA0E: "\u4ced",
+	0x2FA0F: "\u9d67",
+	0x2FA10: "\U0002a0ce",
+	0x2FA11: "\u4cf8",
+	0x2FA12: "\U0002a105",
+	0x2FA13: "\U0002a20e",
+	0x2FA14: "\U0002a291",
+	0x2FA15: "\u9ebb",
+	0x2FA16: "\u4d56",
+	0x2FA17: "\u9ef9",
+	0x2FA18: "\u9efe",
+	0x2FA19: "\u9f05",
+	0x2FA1A: "\u9f0f",
+	0x2FA1B: "\u9f16",
+	0x2FA1C: "\u9f3b",
+	0x2FA1D: "\U0002a600",
+}
+
+// compatibilityDecompositions maps a character to its compatibility decomposition, one level deep
+var compatibilityDecompositions = map[rune]string{
+	0x00A0:  "\u0020",
+	0x00A8:  "\u0020\u0308",
+	0x00AA:  "\u0061",
+	0x00AF:  "\u0020\u0304",
+	0x00B2:  "\u0032",
+	0x00B3:  "\u0033",
+	0x00B4:  "\u0020\u0301",
+	0x00B5:  "\u03bc",
+	0x00B8:  "\u0020\u0327",
+	0x00B9:  "\u0031",
+	0x00BA:  "\u006f",
+	0x00BC:  "\u0031\u2044\u0034",
+	0x00BD:  "\u0031\u2044\u0032",
+	0x00BE:  "\u0033\u2044\u0034",
+	0x0132:  "\u0049\u004a",
+	0x0133:  "\u0069\u006a",
+	0x013F:  "\u004c\u00b7",
+	0x0140:  "\u006c\u00b7",
+	0x0149:  "\u02bc\u006e",
+	0x017F:  "\u0073",
+	0x01C4:  "\u0044\u017d",
+	0x01C5:  "\u0044\u017e",
+	0x01C6:  "\u0064\u017e",
+	0x01C7:  "\u004c\u004a",
+	0x01C8:  "\u004c\u006a",
+	0x01C9:  "\u006c\u006a",
+	0x01CA:  "\u004e\u004a",
+	0x01CB:  "\u004e\u006a",
+	0x01CC:  "\u006e\u006a",
+	0x01F1:  "\u0044\u005a",
+	0x01F2:  "\u0044\u007a",
+	0x01F3:  "\u0064\u007a",
+	0x02B0:  "\u0068",
+	0x02B1:  "\u0266",
+	0x02B2:  "\u006a",
+	0x02B3:  "\u0072",
+	0x02B4:  "\u0279",
+	0x02B5:  "\u027b",
+	0x02B6:  "\u0281",
+	0x02B7:  "\u0077",
+	0x02B8:  "\u0079",
+	0x02D8:  "\u0020\u0306",
+	0x02D9:  "\u0020\u0307",
+	0x02DA:  "\u0020\u030a",
+	0x02DB:  "\u0020\u0328",
+	0x02DC:  "\u0020\u0303",
+	0x02DD:  "\u0020\u030b",
+	0x02E0:  "\u0263",
+	0x02E1:  "\u006c",
+	0x02E2:  "\u0073",
+	0x02E3:  "\u0078",
+	0x02E4:  "\u0295",
+	0x037A:  "\u0020\u0345",
+	0x0384:  "\u0020\u0301",
+	0x03D0:  "\u03b2",
+	0x03D1:  "\u03b8",
+	0x03D2:  "\u03a5",
+	0x03D5:  "\u03c6",
+	0x03D6:  "\u03c0",
+	0x03F0:  "\u03ba",
+	0x03F1:  "\u03c1",
+	0x03F2:  "\u03c2",
+	0x03F4:  "\u0398",
+	0x03F5:  "\u03b5",
+	0x03F9:  "\u03a3",
+	0x0587:  "\u0565\u0582",
+	0x0675:  "\u0627\u0674",
+	0x0676:  "\u0648\u0674",
+	0x0677:  "\u06c7\u0674",
+	0x0678:  "\u064a\u0674",
+	0x0E33:  "\u0e4d\u0e32",
+	0x0EB3:  "\u0ecd\u0eb2",
+	0x0EDC:  "\u0eab\u0e99",
+	0x0EDD:  "\u0eab\u0ea1",
+	0x0F0C:  "\u0f0b",
+	0x0F77:  "\u0fb2\u0f81",
+	0x0F79:  "\u0fb3\u0f81",
+	0x10FC:  "\u10dc",
+	0x1D2C:  "\u0041",
+	0x1D2D:  "\u00c6",
+	0x1D2E:  "\u0042",
+	0x1D30:  "\u0044",
+	0x1D31:  "\u0045",
+	0x1D32:  "\u018e",
+	0x1D33:  "\u0047",
+	0x1D34:  "\u0048",
+	0x1D35:  "\u0049",
+	0x1D36:  "\u004a",
+	0x1D37:  "\u004b",
+	0x1D38:  "\u004c",
+	0x1D39:  "\u004d",
+	0x1D3A:  "\u004e",
+	0x1D3C:  "\u004f",
+	0x1D3D:  "\u0222",
+	0x1D3E:  "\u0050",
+	0x1D3F:  "\u0052",
+	0x1D40:  "\u0054",
+	0x1D41:  "\u0055",
+	0x1D42:  "\u0057",
+	0x1D43:  "\u0061",
+	0x1D44:  "\u0250",
+	0x1D45:  "\u0251",
+	0x1D46:  "\u1d02",
+	0x1D47:  "\u0062",
+	0x1D48:  "\u0064",
+	0x1D49:  "\u0065",
+	0x1D4A:  "\u0259",
+	0x1D4B:  "\u025b",
+	0x1D4C:  "\u025c",
+	0x1D4D:  "\u0067",
+	0x1D4F:  "\u006b",
+	0x1D50:  "\u006d",
+	0x1D51:  "\u014b",
+	0x1D52:  "\u006f",
+	0x1D53:  "\u0254",
+	0x1D54:  "\u1d16",
+	0x1D55:  "\u1d17",
+	0x1D56:  "\u0070",
+	0x1D57:  "\u0074",
+	0x1D58:  "\u0075",
+	0x1D59:  "\u1d1d",
+	0x1D5A:  "\u026f",
+	0x1D5B:  "\u0076",
+	0x1D5C:  "\u1d25",
+	0x1D5D:  "\u03b2",
+	0x1D5E:  "\u03b3",
+	0x1D5F:  "\u03b4",
+	0x1D60:  "\u03c6",
+	0x1D61:  "\u03c7",
+	0x1D62:  "\u0069",
+	0x1D63:  "\u0072",
+	0x1D64:  "\u0075",
+	0x1D65:  "\u0076",
+	0x1D66:  "\u03b2",
+	0x1D67:  "\u03b3",
+	0x1D68:  "\u03c1",
+	0x1D69:  "\u03c6",
+	0x1D6A:  "\u03c7",
+	0x1D78:  "\u043d",
+	0x1D9B:  "\u0252",
+	0x1D9C:  "\u0063",
+	0x1D9D:  "\u0255",
+	0x1D9E:  "\u00f0",
+	0x1D9F:  "\u025c",
+	0x1DA0:  "\u0066",
+	0x1DA1:  "\u025f",
+	0x1DA2:  "\u0261",
+	0x1DA3:  "\u0265",
+	0x1DA4:  "\u0268",
+	0x1DA5:  "\u0269",
+	0x1DA6:  "\u026a",
+	0x1DA7:  "\u1d7b",
+	0x1DA8:  "\u029d",
+	0x1DA9:  "\u026d",
+	0x1DAA:  "\u1d85",
+	0x1DAB:  "\u029f",
+	0x1DAC:  "\u0271",
+	0x1DAD:  "\u0270",
+	0x1DAE:  "\u0272",
+	0x1DAF:  "\u0273",
+	0x1DB0:  "\u0274",
+	0x1DB1:  "\u0275",
+	0x1DB2:  "\u0278",
+	0x1DB3:  "\u0282",
+	0x1DB4:  "\u0283",
+	0x1DB5:  "\u01ab",
+	0x1DB6:  "\u0289",
+	0x1DB7:  "\u028a",
+	0x1DB8:  "\u1d1c",
+	0x1DB9:  "\u028b",
+	0x1DBA:  "\u028c",
+	0x1DBB:  "\u007a",
+	0x1DBC:  "\u0290",
+	0x1DBD:  "\u0291",
+	0x1DBE:  "\u0292",
+	0x1DBF:  "\u03b8",
+	0x1E9A:  "\u0061\u02be",
+	0x1FBD:  "\u0020\u0313",
+	0x1FBF:  "\u0020\u0313",
+	0x1FC0:  "\u0020\u0342",
+	0x1FFE:  "\u0020\u0314",
+	0x2002:  "\u0020",
+	0x2003:  "\u0020",
+	0x2004:  "\u0020",
+	0x2005:  "\u0020",
+	0x2006:  "\u0020",
+	0x2007:  "\u0020",
+	0x2008:  "\u0020",
+	0x2009:  "\u0020",
+	0x200A:  "\u0020",
+	0x2011:  "\u2010",
+	0x2017:  "\u0020\u0333",
+	0x2024:  "\u002e",
+	0x2025:  "\u002e\u002e",
+	0x2026:  "\u002e\u002e\u002e",
+	0x202F:  "\u0020",
+	0x2033:  "\u2032\u2032",
+	0x2034:  "\u2032\u2032\u2032",
+	0x2036:  "\u2035\u2035",
+	0x2037:  "\u2035\u2035\u2035",
+	0x203C:  "\u0021\u0021",
+	0x203E:  "\u0020\u0305",
+	0x2047:  "\u003f\u003f",
+	0x2048:  "\u003f\u0021",
+	0x2049:  "\u0021\u003f",
+	0x2057:  "\u2032\u2032\u2032\u2032",
+	0x205F:  "\u0020",
+	0x2070:  "\u0030",
+	0x2071:  "\u0069",
+	0x2074:  "\u0034",
+	0x2075:  "\u0035",
+	0x2076:  "\u0036",
+	0x2077:  "\u0037",
+	0x2078:  "\u0038",
+	0x2079:  "\u0039",
+	0x207A:  "\u002b",
+	0x207B:  "\u2212",
+	0x207C:  "\u003d",
+	0x207D:  "\u0028",
+	0x207E:  "\u0029",
+	0x207F:  "\u006e",
+	0x2080:  "\u0030",
+	0x2081:  "\u0031",
+	0x2082:  "\u0032",
+	0x2083:  "\u0033",
+	0x2084:  "\u0034",
+	0x2085:  "\u0035",
+	0x2086:  "\u0036",
+	0x2087:  "\u0037",
+	0x2088:  "\u0038",
+	0x2089:  "\u0039",
+	0x208A:  "\u002b",
+	0x208B:  "\u2212",
+	0x208C:  "\u003d",
+	0x208D:  "\u0028",
+	0x208E:  "\u0029",
+	0x2090:  "\u0061",
+	0x2091:  "\u0065",
+	0x2092:  "\u006f",
+	0x2093:  "\u0078",
+	0x2094:  "\u0259",
+	0x2095:  "\u0068",
+	0x2096:  "\u006b",
+	0x2097:  "\u006c",
+	0x2098:  "\u006d",
+	0x2099:  "\u006e",
+	0x209A:  "\u0070",
+	0x209B:  "\u0073",
+	0x209C:  "\u0074",
+	0x20A8:  "\u0052\u0073",
+	0x2100:  "\u0061\u002f\u0063",
+	0x2101:  "\u0061\u002f\u0073",
+	0x2102:  "\u0043",
+	0x2103:  "\u00b0\u0043",
+	0x2105:  "\u0063\u002f\u006f",
+	0x2106:  "\u0063\u002f\u0075",
+	0x2107:  "\u0190",
+	0x2109:  "\u00b0\u0046",
+	0x210A:  "\u0067",
+	0x210B:  "\u0048",
+	0x210C:  "\u0048",
+	0x210D:  "\u0048",
+	0x210E:  "\u0068",
+	0x210F:  "\u0127",
+	0x2110:  "\u0049",
+	0x2111:  "\u0049",
+	0x2112:  "\u004c",
+	0x2113:  "\u006c",
+	0x2115:  "\u004e",
+	0x2116:  "\u004e\u006f",
+	0x2119:  "\u0050",
+	0x211A:  "\u0051",
+	0x211B:  "\u0052",
+	0x211C:  "\u0052",
+	0x211D:  "\u0052",
+	0x2120:  "\u0053\u004d",
+	0x2121:  "\u0054\u0045\u004c",
+	0x2122:  "\u0054\u004d",
+	0x2124:  "\u005a",
+	0x2128:  "\u005a",
+	0x212C:  "\u0042",
+	0x212D:  "\u0043",
+	0x212F:  "\u0065",
+	0x2130:  "\u0045",
+	0x2131:  "\u0046",
+	0x2133:  "\u004d",
+	0x2134:  "\u006f",
+	0x2135:  "\u05d0",
+	0x2136:  "\u05d1",
+	0x2137:  "\u05d2",
+	0x2138:  "\u05d3",
+	0x2139:  "\u0069",
+	0x213B:  "\u0046\u0041\u0058",
+	0x213C:  "\u03c0",
+	0x213D:  "\u03b3",
+	0x213E:  "\u0393",
+	0x213F:  "\u03a0",
+	0x2140:  "\u2211",
+	0x2145:  "\u0044",
+	0x2146:  "\u0064",
+	0x2147:  "\u0065",
+	0x2148:  "\u0069",
+	0x2149:  "\u006a",
+	0x2150:  "\u0031\u2044\u0037",
+	0x2151:  "\u0031\u2044\u0039",
+	0x2152:  "\u0031\u2044\u0031\u0030",
+	0x2153:  "\u0031\u2044\u0033",
+	0x2154:  "\u0032\u2044\u0033",
+	0x2155:  "\u0031\u2044\u0035",
+	0x2156:  "\u0032\u2044\u0035",
+	0x2157:  "\u0033\u2044\u0035",
+	0x2158:  "\u0034\u2044\u0035",
+	0x2159:  "\u0031\u2044\u0036",
+	0x215A:  "\u0035\u2044\u0036",
+	0x215B:  "\u0031\u2044\u0038",
+	0x215C:  "\u0033\u2044\u0038",
+	0x215D:  "\u0035\u2044\u0038",
+	0x215E:  "\u0037\u2044\u0038",
+	0x215F:  "\u0031\u2044",
+	0x2160:  "\u0049",
+	0x2161:  "\u0049\u0049",
+	0x2162:  "\u0049\u0049\u0049",
+	0x2163:  "\u0049\u0056",
+	0x2164:  "\u0056",
+	0x2165:  "\u0056\u0049",
+	0x2166:  "\u0056\u0049\u0049",
+	0x2167:  "\u0056\u0049\u0049\u0049",
+	0x2168:  "\u0049\u0058",
+	0x2169:  "\u0058",
+	0x216A:  "\u0058\u0049",
+	0x216B:  "\u0058\u0049\u0049",
+	0x216C:  "\u004c",
+	0x216D:  "\u0043",
+	0x216E:  "\u0044",
+	0x216F:  "\u004d",
+	0x2170:  "\u0069",
+	0x2171:  "\u0069\u0069",
+	0x2172:  "\u0069\u0069\u0069",
+	0x2173:  "\u0069\u0076",
+	0x2174:  "\u0076",
+	0x2175:  "\u0076\u0069",
+	0x2176:  "\u0076\u0069\u0069",
+	0x2177:  "\u0076\u0069\u0069\u0069",
+	0x2178:  "\u0069\u0078",
+	0x2179:  "\u0078",
+	0x217A:  "\u0078\u0069",
+	0x217B:  "\u0078\u0069\u0069",
+	0x217C:  "\u006c",
+	0x217D:  "\u0063",
+	0x217E:  "\u0064",
+	0x217F:  "\u006d",
+	0x2189:  "\u0030\u2044\u0033",
+	0x222C:  "\u222b\u222b",
+	0x222D:  "\u222b\u222b\u222b",
+	0x222F:  "\u222e\u222e",
+	0x2230:  "\u222e\u222e\u222e",
+	0x2460:  "\u0031",
+	0x2461:  "\u0032",
+	0x2462:  "\u0033",
+	0x2463:  "\u0034",
+	0x2464:  "\u0035",
+	0x2465:  "\u0036",
+	0x2466:  "\u0037",
+	0x2467:  "\u0038",
+	0x2468:  "\u0039",
+	0x2469:  "\u0031\u0030",
+	0x246A:  "\u0031\u0031",
+	0x246B:  "\u0031\u0032",
+	0x246C:  "\u0031\u0033",
+	0x246D:  "\u0031\u0034",
+	0x246E:  "\u0031\u0035",
+	0x246F:  "\u0031\u0036",
+	0x2470:  "\u0031\u0037",
+	0x2471:  "\u0031\u0038",
+	0x2472:  "\u0031\u0039",
+	0x2473:  "\u0032\u0030",
+	0x2474:  "\u0028\u0031\u0029",
+	0x2475:  "\u0028\u0032\u0029",
+	0x2476:  "\u0028\u0033\u0029",
+	0x2477:  "\u0028\u0034\u0029",
+	0x2478:  "\u0028\u0035\u0029",
+	0x2479:  "\u0028\u0036\u0029",
+	0x247A:  "\u0028\u0037\u0029",
+	0x247B:  "\u0028\u0038\u0029",
+	0x247C:  "\u0028\u0039\u0029",
+	0x247D:  "\u0028\u0031\u0030\u0029",
+	0x247E:  "\u0028\u0031\u0031\u0029",
+	0x247F:  "\u0028\u0031\u0032\u0029",
+	0x2480:  "\u0028\u0031\u0033\u0029",
+	0x2481:  "\u0028\u0031\u0034\u0029",
+	0x2482:  "\u0028\u0031\u0035\u0029",
+	0x2483:  "\u0028\u0031\u0036\u0029",
+	0x2484:  "\u0028\u0031\u0037\u0029",
+	0x2485:  "\u0028\u0031\u0038\u0029",
+	0x2486:  "\u0028\u0031\u0039\u0029",
+	0x2487:  "\u0028\u0032\u0030\u0029",
+	0x2488:  "\u0031\u002e",
+	0x2489:  "\u0032\u002e",
+	0x248A:  "\u0033\u002e",
+	0x248B:  "\u0034\u002e",
+	0x248C:  "\u0035\u002e",
+	0x248D:  "\u0036\u002e",
+	0x248E:  "\u0037\u002e",
+	0x248F:  "\u0038\u002e",
+	0x2490:  "\u0039\u002e",
+	0x2491:  "\u0031\u0030\u002e",
+	0x2492:  "\u0031\u0031\u002e",
+	0x2493:  "\u0031\u0032\u002e",
+	0x2494:  "\u0031\u0033\u002e",
+	0x2495:  "\u0031\u0034\u002e",
+	0x2496:  "\u0031\u0035\u002e",
+	0x2497:  "\u0031\u0036\u002e",
+	0x2498:  "\u0031\u0037\u002e",
+	0x2499:  "\u0031\u0038\u002e",
+	0x249A:  "\u0031\u0039\u002e",
+	0x249B:  "\u0032\u0030\u002e",
+	0x249C:  "\u0028\u0061\u0029",
+	0x249D:  "\u0028\u0062\u0029",
+	0x249E:  "\u0028\u0063\u0029",
+	0x249F:  "\u0028\u0064\u0029",
+	0x24A0:  "\u0028\u0065\u0029",
+	0x24A1:  "\u0028\u0066\u0029",
+	0x24A2:  "\u0028\u0067\u0029",
+	0x24A3:  "\u0028\u0068\u0029",
+	0x24A4:  "\u0028\u0069\u0029",
+	0x24A5:  "\u0028\u006a\u0029",
+	0x24A6:  "\u0028\u006b\u0029",
+	0x24A7:  "\u0028\u006c\u0029",
+	0x24A8:  "\u0028\u006d\u0029",
+	0x24A9:  "\u0028\u006e\u0029",
+	0x24AA:  "\u0028\u006f\u0029",
+	0x24AB:  "\u0028\u0070\u0029",
+	0x24AC:  "\u0028\u0071\u0029",
+	0x24AD:  "\u0028\u0072\u0029",
+	0x24AE:  "\u0028\u0073\u0029",
+	0x24AF:  "\u0028\u0074\u0029",
+	0x24B0:  "\u0028\u0075\u0029",
+	0x24B1:  "\u0028\u0076\u0029",
+	0x24B2:  "\u0028\u0077\u0029",
+	0x24B3:  "\u0028\u0078\u0029",
+	0x24B4:  "\u0028\u0079\u0029",
+	0x24B5:  "\u0028\u007a\u0029",
+	0x24B6:  "\u0041",
+	0x24B7:  "\u0042",
+	0x24B8:  "\u0043",
+	0x24B9:  "\u0044",
+	0x24BA:  "\u0045",
+	0x24BB:  "\u0046",
+	0x24BC:  "\u0047",
+	0x24BD:  "\u0048",
+	0x24BE:  "\u0049",
+	0x24BF:  "\u004a",
+	0x24C0:  "\u004b",
+	0x24C1:  "\u004c",
+	0x24C2:  "\u004d",
+	0x24C3:  "\u004e",
+	0x24C4:  "\u004f",
+	0x24C5:  "\u0050",
+	0x24C6:  "\u0051",
+	0x24C7:  "\u0052",
+	0x24C8:  "\u0053",
+	0x24C9:  "\u0054",
+	0x24CA:  "\u0055",
+	0x24CB:  "\u0056",
+	0x24CC:  "\u0057",
+	0x24CD:  "\u0058",
+	0x24CE:  "\u0059",
+	0x24CF:  "\u005a",
+	0x24D0:  "\u0061",
+	0x24D1:  "\u0062",
+	0x24D2:  "\u0063",
+	0x24D3:  "\u0064",
+	0x24D4:  "\u0065",
+	0x24D5:  "\u0066",
+	0x24D6:  "\u0067",
+	0x24D7:  "\u0068",
+	0x24D8:  "\u0069",
+	0x24D9:  "\u006a",
+	0x24DA:  "\u006b",
+	0x24DB:  "\u006c",
+	0x24DC:  "\u006d",
+	0x24DD:  "\u006e",
+	0x24DE:  "\u006f",
+	0x24DF:  "\u0070",
+	0x24E0:  "\u0071",
+	0x24E1:  "\u0072",
+	0x24E2:  "\u0073",
+	0x24E3:  "\u0074",
+	0x24E4:  "\u0075",
+	0x24E5:  "\u0076",
+	0x24E6:  "\u0077",
+	0x24E7:  "\u0078",
+	0x24E8:  "\u0079",
+	0x24E9:  "\u007a",
+	0x24EA:  "\u0030",
+	0x2A0C:  "\u222b\u222b\u222b\u222b",
+	0x2A74:  "\u003a\u003a\u003d",
+	0x2A75:  "\u003d\u003d",
+	0x2A76:  "\u003d\u003d\u003d",
+	0x2C7C:  "\u006a",
+	0x2C7D:  "\u0056",
+	0x2D6F:  "\u2d61",
+	0x2E9F:  "\u6bcd",
+	0x2EF3:  "\u9f9f",
+	0x2F00:  "\u4e00",
+	0x2F01:  "\u4e28",
+	0x2F02:  "\u4e36",
+	0x2F03:  "\u4e3f",
+	0x2F04:  "\u4e59",
+	0x2F05:  "\u4e85",
+	0x2F06:  "\u4e8c",
+	0x2F07:  "\u4ea0",
+	0x2F08:  "\u4eba",
+	0x2F09:  "\u513f",
+	0x2F0A:  "\u5165",
+	0x2F0B:  "\u516b",
+	0x2F0C:  "\u5182",
+	0x2F0D:  "\u5196",
+	0x2F0E:  "\u51ab",
+	0x2F0F:  "\u51e0",
+	0x2F10:  "\u51f5",
+	0x2F11:  "\u5200",
+	0x2F12:  "\u529b",
+	0x2F13:  "\u52f9",
+	0x2F14:  "\u5315",
+	0x2F15:  "\u531a",
+	0x2F16:  "\u5338",
+	0x2F17:  "\u5341",
+	0x2F18:  "\u535c",
+	0x2F19:  "\u5369",
+	0x2F1A:  "\u5382",
+	0x2F1B:  "\u53b6",
+	0x2F1C:  "\u53c8",
+	0x2F1D:  "\u53e3",
+	0x2F1E:  "\u56d7",
+	0x2F1F:  "\u571f",
+	0x2F20:  "\u58eb",
+	0x2F21:  "\u5902",
+	0x2F22:  "\u590a",
+	0x2F23:  "\u5915",
+	0x2F24:  "\u5927",
+	0x2F25:  "\u5973",
+	0x2F26:  "\u5b50",
+	0x2F27:  "\u5b80",
+	0x2F28:  "\u5bf8",
+	0x2F29:  "\u5c0f",
+	0x2F2A:  "\u5c22",
+	0x2F2B:  "\u5c38",
+	0x2F2C:  "\u5c6e",
+	0x2F2D:  "\u5c71",
+	0x2F2E:  "\u5ddb",
+	0x2F2F:  "\u5de5",
+	0x2F30:  "\u5df1",
+	0x2F31:  "\u5dfe",
+	0x2F32:  "\u5e72",
+	0x2F33:  "\u5e7a",
+	0x2F34:  "\u5e7f",
+	0x2F35:  "\u5ef4",
+	0x2F36:  "\u5efe",
+	0x2F37:  "\u5f0b",
+	0x2F38:  "\u5f13",
+	0x2F39:  "\u5f50",
+	0x2F3A:  "\u5f61",
+	0x2F3B:  "\u5f73",
+	0x2F3C:  "\u5fc3",
+	0x2F3D:  "\u6208",
+	0x2F3E:  "\u6236",
+	0x2F3F:  "\u624b",
+	0x2F40:  "\u652f",
+	0x2F41:  "\u6534",
+	0x2F42:  "\u6587",
+	0x2F43:  "\u6597",
+	0x2F44:  "\u65a4",
+	0x2F45:  "\u65b9",
+	0x2F46:  "\u65e0",
+	0x2F47:  "\u65e5",
+	0x2F48:  "\u66f0",
+	0x2F49:  "\u6708",
+	0x2F4A:  "\u6728",
+	0x2F4B:  "\u6b20",
+	0x2F4C:  "\u6b62",
+	0x2F4D:  "\u6b79",
+	0x2F4E:  "\u6bb3",
+	0x2F4F:  "\u6bcb",
+	0x2F50:  "\u6bd4",
+	0x2F51:  "\u6bdb",
+	0x2F52:  "\u6c0f",
+	0x2F53:  "\u6c14",
+	0x2F54:  "\u6c34",
+	0x2F55:  "\u706b",
+	0x2F56:  "\u722a",
+	0x2F57:  "\u7236",
+	0x2F58:  "\u723b",
+	0x2F59:  "\u723f",
+	0x2F5A:  "\u7247",
+	0x2F5B:  "\u7259",
+	0x2F5C:  "\u725b",
+	0x2F5D:  "\u72ac",
+	0x2F5E:  "\u7384",
+	0x2F5F:  "\u7389",
+	0x2F60:  "\u74dc",
+	0x2F61:  "\u74e6",
+	0x2F62:  "\u7518",
+	0x2F63:  "\u751f",
+	0x2F64:  "\u7528",
+	0x2F65:  "\u7530",
+	0x2F66:  "\u758b",
+	0x2F67:  "\u7592",
+	0x2F68:  "\u7676",
+	0x2F69:  "\u767d",
+	0x2F6A:  "\u76ae",
+	0x2F6B:  "\u76bf",
+	0x2F6C:  "\u76ee",
+	0x2F6D:  "\u77db",
+	0x2F6E:  "\u77e2",
+	0x2F6F:  "\u77f3",
+	0x2F70:  "\u793a",
+	0x2F71:  "\u79b8",
+	0x2F72:  "\u79be",
+	0x2F73:  "\u7a74",
+	0x2F74:  "\u7acb",
+	0x2F75:  "\u7af9",
+	0x2F76:  "\u7c73",
+	0x2F77:  "\u7cf8",
+	0x2F78:  "\u7f36",
+	0x2F79:  "\u7f51",
+	0x2F7A:  "\u7f8a",
+	0x2F7B:  "\u7fbd",
+	0x2F7C:  "\u8001",
+	0x2F7D:  "\u800c",
+	0x2F7E:  "\u8012",
+	0x2F7F:  "\u8033",
+	0x2F80:  "\u807f",
+	0x2F81:  "\u8089",
+	0x2F82:  "\u81e3",
+	0x2F83:  "\u81ea",
+	0x2F84:  "\u81f3",
+	0x2F85:  "\u81fc",
+	0x2F86:  "\u820c",
+	0x2F87:  "\u821b",
+	0x2F88:  "\u821f",
+	0x2F89:  "\u826e",
+	0x2F8A:  "\u8272",
+	0x2F8B:  "\u8278",
+	0x2F8C:  "\u864d",
+	0x2F8D:  "\u866b",
+	0x2F8E:  "\u8840",
+	0x2F8F:  "\u884c",
+	0x2F90:  "\u8863",
+	0x2F91:  "\u897e",
+	0x2F92:  "\u898b",
+	0x2F93:  "\u89d2",
+	0x2F94:  "\u8a00",
+	0x2F95:  "\u8c37",
+	0x2F96:  "\u8c46",
+	0x2F97:  "\u8c55",
+	0x2F98:  "\u8c78",
+	0x2F99:  "\u8c9d",
+	0x2F9A:  "\u8d64",
+	0x2F9B:  "\u8d70",
+	0x2F9C:  "\u8db3",
+	0x2F9D:  "\u8eab",
+	0x2F9E:  "\u8eca",
+	0x2F9F:  "\u8f9b",
+	0x2FA0:  "\u8fb0",
+	0x2FA1:  "\u8fb5",
+	0x2FA2:  "\u9091",
+	0x2FA3:  "\u9149",
+	0x2FA4:  "\u91c6",
+	0x2FA5:  "\u91cc",
+	0x2FA6:  "\u91d1",
+	0x2FA7:  "\u9577",
+	0x2FA8:  "\u9580",
+	0x2FA9:  "\u961c",
+	0x2FAA:  "\u96b6",
+	0x2FAB:  "\u96b9",
+	0x2FAC:  "\u96e8",
+	0x2FAD:  "\u9751",
+	0x2FAE:  "\u975e",
+	0x2FAF:  "\u9762",
+	0x2FB0:  "\u9769",
+	0x2FB1:  "\u97cb",
+	0x2FB2:  "\u97ed",
+	0x2FB3:  "\u97f3",
+	0x2FB4:  "\u9801",
+	0x2FB5:  "\u98a8",
+	0x2FB6:  "\u98db",
+	0x2FB7:  "\u98df",
+	0x2FB8:  "\u9996",
+	0x2FB9:  "\u9999",
+	0x2FBA:  "\u99ac",
+	0x2FBB:  "\u9aa8",
+	0x2FBC:  "\u9ad8",
+	0x2FBD:  "\u9adf",
+	0x2FBE:  "\u9b25",
+	0x2FBF:  "\u9b2f",
+	0x2FC0:  "\u9b32",
+	0x2FC1:  "\u9b3c",
+	0x2FC2:  "\u9b5a",
+	0x2FC3:  "\u9ce5",
+	0x2FC4:  "\u9e75",
+	0x2FC5:  "\u9e7f",
+	0x2FC6:  "\u9ea5",
+	0x2FC7:  "\u9ebb",
+	0x2FC8:  "\u9ec3",
+	0x2FC9:  "\u9ecd",
+	0x2FCA:  "\u9ed1",
+	0x2FCB:  "\u9ef9",
+	0x2FCC:  "\u9efd",
+	0x2FCD:  "\u9f0e",
+	0x2FCE:  "\u9f13",
+	0x2FCF:  "\u9f20",
+	0x2FD0:  "\u9f3b",
+	0x2FD1:  "\u9f4a",
+	0x2FD2:  "\u9f52",
+	0x2FD3:  "\u9f8d",
+	0x2FD4:  "\u9f9c",
+	0x2FD5:  "\u9fa0",
+	0x3000:  "\u0020",
+	0x3036:  "\u3012",
+	0x3038:  "\u5341",
+	0x3039:  "\u5344",
+	0x303A:  "\u5345",
+	0x309B:  "\u0020\u3099",
+	0x309C:  "\u0020\u309a",
+	0x309F:  "\u3088\u308a",
+	0x30FF:  "\u30b3\u30c8",
+	0x3131:  "\u1100",
+	0x3132:  "\u1101",
+	0x3133:  "\u11aa",
+	0x3134:  "\u1102",
+	0x3135:  "\u11ac",
+	0x3136:  "\u11ad",
+	0x3137:  "\u1103",
+	0x3138:  "\u1104",
+	0x3139:  "\u1105",
+	0x313A:  "\u11b0",
+	0x313B:  "\u11b1",
+	0x313C:  "\u11b2",
+	0x313D:  "\u11b3",
+	0x313E:  "\u11b4",
+	0x313F:  "\u11b5",
+	0x3140:  "\u111a",
+	0x3141:  "\u1106",
+	0x3142:  "\u1107",
+	0x3143:  "\u1108",
+	0x3144:  "\u1121",
+	0x3145:  "\u1109",
+	0x3146:  "\u110a",
+	0x3147:  "\u110b",
+	0x3148:  "\u110c",
+	0x3149:  "\u110d",
+	0x314A:  "\u110e",
+	0x314B:  "\u110f",
+	0x314C:  "\u1110",
+	0x314D:  "\u1111",
+	0x314E:  "\u1112",
+	0x314F:  "\u1161",
+	0x3150:  "\u1162",
+	0x3151:  "\u1163",
+	0x3152:  "\u1164",
+	0x3153:  "\u1165",
+	0x3154:  "\u1166",
+	0x3155:  "\u1167",
+	0x3156:  "\u1168",
+	0x3157:  "\u1169",
+	0x3158:  "\u116a",
+	0x3159:  "\u116b",
+	0x315A:  "\u116c",
+	0x315B:  "\u116d",
+	0x315C:  "\u116e",
+	0x315D:  "\u116f",
+	0x315E:  "\u1170",
+	0x315F:  "\u1171",
+	0x3160:  "\u1172",
+	0x3161:  "\u1173",
+	0x3162:  "\u1174",
+	0x3163:  "\u1175",
+	0x3164:  "\u1160",
+	0x3165:  "\u1114",
+	0x3166:  "\u1115",
+	0x3167:  "\u11c7",
+	0x3168:  "\u11c8",
+	0x3169:  "\u11cc",
+	0x316A:  "\u11ce",
+	0x316B:  "\u11d3",
+	0x316C:  "\u11d7",
+	0x316D:  "\u11d9",
+	0x316E:  "\u111c",
+	0x316F:  "\u11dd",
+	0x3170:  "\u11df",
+	0x3171:  "\u111d",
+	0x3172:  "\u111e",
+	0x3173:  "\u1120",
+	0x3174:  "\u1122",
+	0x3175:  "\u1123",
+	0x3176:  "\u1127",
+	0x3177:  "\u1129",
+	0x3178:  "\u112b",
+	0x3179:  "\u112c",
+	0x317A:  "\u112d",
+	0x317B:  "\u112e",
+	0x317C:  "\u112f",
+	0x317D:  "\u1132",
+	0x317E:  "\u1136",
+	0x317F:  "\u1140",
+	0x3180:  "\u1147",
+	0x3181:  "\u114c",
+	0x3182:  "\u11f1",
+	0x3183:  "\u11f2",
+	0x3184:  "\u1157",
+	0x3185:  "\u1158",
+	0x3186:  "\u1159",
+	0x3187:  "\u1184",
+	0x3188:  "\u1185",
+	0x3189:  "\u1188",
+	0x318A:  "\u1191",
+	0x318B:  "\u1192",
+	0x318C:  "\u1194",
+	0x318D:  "\u119e",
+	0x318E:  "\u11a1",
+	0x3192:  "\u4e00",
+	0x3193:  "\u4e8c",
+	0x3194:  "\u4e09",
+	0x3195:  "\u56db",
+	0x3196:  "\u4e0a",
+	0x3197:  "\u4e2d",
+	0x3198:  "\u4e0b",
+	0x3199:  "\u7532",
+	0x319A:  "\u4e59",
+	0x319B:  "\u4e19",
+	0x319C:  "\u4e01",
+	0x319D:  "\u5929",
+	0x319E:  "\u5730",
+	0x319F:  "\u4eba",
+	0x3200:  "\u0028\u1100\u0029",
+	0x3201:  "\u0028\u1102\u0029",
+	0x3202:  "\u0028\u1103\u0029",
+	0x3203:  "\u0028\u1105\u0029",
+	0x3204:  "\u0028\u1106\u0029",
+	0x3205:  "\u0028\u1107\u0029",
+	0x3206:  "\u0028\u1109\u0029",
+	0x3207:  "\u0028\u110b\u0029",
+	0x3208:  "\u0028\u110c\u0029",
+	0x3209:  "\u0028\u110e\u0029",
+	0x320A:  "\u0028\u110f\u0029",
+	0x320B:  "\u0028\u1110\u0029",
+	0x320C:  "\u0028\u1111\u0029",
+	0x320D:  "\u0028\u1112\u0029",
+	0x320E:  "\u0028\u1100\u1161\u0029",
+	0x320F:  "\u0028\u1102\u1161\u0029",
+	0x3210:  "\u0028\u1103\u1161\u0029",
+	0x3211:  "\u0028\u1105\u1161\u0029",
+	0x3212:  "\u0028\u1106\u1161\u0029",
+	0x3213:  "\u0028\u1107\u1161\u0029",
+	0x3214:  "\u0028\u1109\u1161\u0029",
+	0x3215:  "\u0028\u110b\u1161\u0029",
+	0x3216:  "\u0028\u110c\u1161\u0029",
+	0x3217:  "\u0028\u110e\u1161\u0029",
+	0x3218:  "\u0028\u110f\u1161\u0029",
+	0x3219:  "\u0028\u1110\u1161\u0029",
+	0x321A:  "\u0028\u1111\u1161\u0029",
+	0x321B:  "\u0028\u1112\u1161\u0029",
+	0x321C:  "\u0028\u110c\u116e\u0029",
+	0x321D:  "\u0028\u110b\u1169\u110c\u1165\u11ab\u0029",
+	0x321E:  "\u0028\u110b\u1169\u1112\u116e\u0029",
+	0x3220:  "\u0028\u4e00\u0029",
+	0x3221:  "\u0028\u4e8c\u0029",
+	0x3222:  "\u0028\u4e09\u0029",
+	0x3223:  "\u0028\u56db\u0029",
+	0x3224:  "\u0028\u4e94\u0029",
+	0x3225:  "\u0028\u516d\u0029",
+	0x3226:  "\u0028\u4e03\u0029",
+	0x3227:  "\u0028\u516b\u0029",
+	0x3228:  "\u0028\u4e5d\u0029",
+	0x3229:  "\u0028\u5341\u0029",
+	0x322A:  "\u0028\u6708\u0029",
+	0x322B:  "\u0028\u706b\u0029",
+	0x322C:  "\u0028\u6c34\u0029",
+	0x322D:  "\u0028\u6728\u0029",
+	0x322E:  "\u0028\u91d1\u0029",
+	0x322F:  "\u0028\u571f\u0029",
+	0x3230:  "\u0028\u65e5\u0029",
+	0x3231:  "\u0028\u682a\u0029",
+	0x3232:  "\u0028\u6709\u0029",
+	0x3233:  "\u0028\u793e\u0029",
+	0x3234:  "\u0028\u540d\u0029",
+	0x3235:  "\u0028\u7279\u0029",
+	0x3236:  "\u0028\u8ca1\u0029",
+	0x3237:  "\u0028\u795d\u0029",
+	0x3238:  "\u0028\u52b4\u0029",
+	0x3239:  "\u0028\u4ee3\u0029",
+	0x323A:  "\u0028\u547c\u0029",
+	0x323B:  "\u0028\u5b66\u0029",
+	0x323C:  "\u0028\u76e3\u0029",
+	0x323D:  "\u0028\u4f01\u0029",
+	0x323E:  "\u0028\u8cc7\u0029",
+	0x323F:  "\u0028\u5354\u0029",
+	0x3240:  "\u0028\u796d\u0029",
+	0x3241:  "\u0028\u4f11\u0029",
+	0x3242:  "\u0028\u81ea\u0029",
+	0x3243:  "\u0028\u81f3\u0029",
+	0x3244:  "\u554f",
+	0x3245:  "\u5e7c",
+	0x3246:  "\u6587",
+	0x3247:  "\u7b8f",
+	0x3250:  "\u0050\u0054\u0045",
+	0x3251:  "\u0032\u0031",
+	0x3252:  "\u0032\u0032",
+	0x3253:  "\u0032\u0033",
+	0x3254:  "\u0032\u0034",
+	0x3255:  "\u0032\u0035",
+	0x3256:  "\u0032\u0036",
+	0x3257:  "\u0032\u0037",
+	0x3258:  "\u0032\u0038",
+	0x3259:  "\u0032\u0039",
+	0x325A:  "\u0033\u0030",
+	0x325B:  "\u0033\u0031",
+	0x325C:  "\u0033\u0032",
+	0x325D:  "\u0033\u0033",
+	0x325E:  "\u0033\u0034",
+	0x325F:  "\u0033\u0035",
+	0x3260:  "\u1100",
+	0x3261:  "\u1102",
+	0x3262:  "\u1103",
+	0x3263:  "\u1105",
+	0x3264:  "\u1106",
+	0x3265:  "\u1107",
+	0x3266:  "\u1109",
+	0x3267:  "\u110b",
+	0x3268:  "\u110c",
+	0x3269:  "\u110e",
+	0x326A:  "\u110f",
+	0x326B:  "\u1110",
+	0x326C:  "\u1111",
+	0x326D:  "\u1112",
+	0x326E:  "\u1100\u1161",
+	0x326F:  "\u1102\u1161",
+	0x3270:  "\u1103\u1161",
+	0x3271:  "\u1105\u1161",
+	0x3272:  "\u1106\u1161",
+	0x3273:  "\u1107\u1161",
+	0x3274:  "\u1109\u1161",
+	0x3275:  "\u110b\u1161",
+	0x3276:  "\u110c\u1161",
+	0x3277:  "\u110e\u1161",
+	0x3278:  "\u110f\u1161",
+	0x3279:  "\u1110\u1161",
+	0x327A:  "\u1111\u1161",
+	0x327B:  "\u1112\u1161",
+	0x327C:  "\u110e\u1161\u11b7\u1100\u1169",
+	0x327D:  "\u110c\u116e\u110b\u1174",
+	0x327E:  "\u110b\u116e",
+	0x3280:  "\u4e00",
+	0x3281:  "\u4e8c",
+	0x3282:  "\u4e09",
+	0x3283:  "\u56db",
+	0x3284:  "\u4e94",
+	0x3285:  "\u516d",
+	0x3286:  "\u4e03",
+	0x3287:  "\u516b",
+	0x3288:  "\u4e5d",
+	0x3289:  "\u5341",
+	0x328A:  "\u6708",
+	0x328B:  "\u706b",
+	0x328C:  "\u6c34",
+	0x328D:  "\u6728",
+	0x328E:  "\u91d1",
+	0x328F:  "\u571f",
+	0x3290:  "\u65e5",
+	0x3291:  "\u682a",
+	0x3292:  "\u6709",
+	0x3293:  "\u793e",
+	0x3294:  "\u540d",
+	0x3295:  "\u7279",
+	0x3296:  "\u8ca1",
+	0x3297:  "\u795d",
+	0x3298:  "\u52b4",
+	0x3299:  "\u79d8",
+	0x329A:  "\u7537",
+	0x329B:  "\u5973",
+	0x329C:  "\u9069",
+	0x329D:  "\u512a",
+	0x329E:  "\u5370",
+	0x329F:  "\u6ce8",
+	0x32A0:  "\u9805",
+	0x32A1:  "\u4f11",
+	0x32A2:  "\u5199",
+	0x32A3:  "\u6b63",
+	0x32A4:  "\u4e0a",
+	0x32A5:  "\u4e2d",
+	0x32A6:  "\u4e0b",
+	0x32A7:  "\u5de6",
+	0x32A8:  "\u53f3",
+	0x32A9:  "\u533b",
+	0x32AA:  "\u5b97",
+	0x32AB:  "\u5b66",
+	0x32AC:  "\u76e3",
+	0x32AD:  "\u4f01",
+	0x32AE:  "\u8cc7",
+	0x32AF:  "\u5354",
+	0x32B0:  "\u591c",
+	0x32B1:  "\u0033\u0036",
+	0x32B2:  "\u0033\u0037",
+	0x32B3:  "\u0033\u0038",
+	0x32B4:  "\u0033\u0039",
+	0x32B5:  "\u0034\u0030",
+	0x32B6:  "\u0034\u0031",
+	0x32B7:  "\u0034\u0032",
+	0x32B8:  "\u0034\u0033",
+	0x32B9:  "\u0034\u0034",
+	0x32BA:  "\u0034\u0035",
+	0x32BB:  "\u0034\u0036",
+	0x32BC:  "\u0034\u0037",
+	0x32BD:  "\u0034\u0038",
+	0x32BE:  "\u0034\u0039",
+	0x32BF:  "\u0035\u0030",
+	0x32C0:  "\u0031\u6708",
+	0x32C1:  "\u0032\u6708",
+	0x32C2:  "\u0033\u6708",
+	0x32C3:  "\u0034\u6708",
+	0x32C4:  "\u0035\u6708",
+	0x32C5:  "\u0036\u6708",
+	0x32C6:  "\u0037\u6708",
+	0x32C7:  "\u0038\u6708",
+	0x32C8:  "\u0039\u6708",
+	0x32C9:  "\u0031\u0030\u6708",
+	0x32CA:  "\u0031\u0031\u6708",
+	0x32CB:  "\u0031\u0032\u6708",
+	0x32CC:  "\u0048\u0067",
+	0x32CD:  "\u0065\u0072\u0067",
+	0x32CE:  "\u0065\u0056",
+	0x32CF:  "\u004c\u0054\u0044",
+	0x32D0:  "\u30a2",
+	0x32D1:  "\u30a4",
+	0x32D2:  "\u30a6",
+	0x32D3:  "\u30a8",
+	0x32D4:  "\u30aa",
+	0x32D5:  "\u30ab",
+	0x32D6:  "\u30ad",
+	0x32D7:  "\u30af",
+	0x32D8:  "\u30b1",
+	0x32D9:  "\u30b3",
+	0x32DA:  "\u30b5",
+	0x32DB:  "\u30b7",
+	0x32DC:  "\u30b9",
+	0x32DD:  "\u30bb",
+	0x32DE:  "\u30bd",
+	0x32DF:  "\u30bf",
+	0x32E0:  "\u30c1",
+	0x32E1:  "\u30c4",
+	0x32E2:  "\u30c6",
+	0x32E3:  "\u30c8",
+	0x32E4:  "\u30ca",
+	0x32E5:  "\u30cb",
+	0x32E6:  "\u30cc",
+	0x32E7:  "\u30cd",
+	0x32E8:  "\u30ce",
+	0x32E9:  "\u30cf",
+	0x32EA:  "\u30d2",
+	0x32EB:  "\u30d5",
+	0x32EC:  "\u30d8",
+	0x32ED:  "\u30db",
+	0x32EE:  "\u30de",
+	0x32EF:  "\u30df",
+	0x32F0:  "\u30e0",
+	0x32F1:  "\u30e1",
+	0x32F2:  "\u30e2",
+	0x32F3:  "\u30e4",
+	0x32F4:  "\u30e6",
+	0x32F5:  "\u30e8",
+	0x32F6:  "\u30e9",
+	0x32F7:  "\u30ea",
+	0x32F8:  "\u30eb",
+	0x32F9:  "\u30ec",
+	0x32FA:  "\u30ed",
+	0x32FB:  "\u30ef",
+	0x32FC:  "\u30f0",
+	0x32FD:  "\u30f1",
+	0x32FE:  "\u30f2",
+	0x32FF:  "\u4ee4\u548c",
+	0x3300:  "\u30a2\u30d1\u30fc\u30c8",
+	0x3301:  "\u30a2\u30eb\u30d5\u30a1",
+	0x3302:  "\u30a2\u30f3\u30da\u30a2",
+	0x3303:  "\u30a2\u30fc\u30eb",
+	0x3304:  "\u30a4\u30cb\u30f3\u30b0",
+	0x3305:  "\u30a4\u30f3\u30c1",
+	0x3306:  "\u30a6\u30a9\u30f3",
+	0x3307:  "\u30a8\u30b9\u30af\u30fc\u30c9",
+	0x3308:  "\u30a8\u30fc\u30ab\u30fc",
+	0x3309:  "\u30aa\u30f3\u30b9",
+	0x330A:  "\u30aa\u30fc\u30e0",
+	0x330B:  "\u30ab\u30a4\u30ea",
+	0x330C:  "\u30ab\u30e9\u30c3\u30c8",
+	0x330D:  "\u30ab\u30ed\u30ea\u30fc",
+	0x330E:  "\u30ac\u30ed\u30f3",
+	0x330F:  "\u30ac\u30f3\u30de",
+	0x3310:  "\u30ae\u30ac",
+	0x3311:  "\u30ae\u30cb\u30fc",
+	0x3312:  "\u30ad\u30e5\u30ea\u30fc",
+	0x3313:  "\u30ae\u30eb\u30c0\u30fc",
+	0x3314:  "\u30ad\u30ed",
+	0x3315:  "\u30ad\u30ed\u30b0\u30e9\u30e0",
+	0x3316:  "\u30ad\u30ed\u30e1\u30fc\u30c8\u30eb",
+	0x3317:  "\u30ad\u30ed\u30ef\u30c3\u30c8",
+	0x3318:  "\u30b0\u30e9\u30e0",
+	0x3319:  "\u30b0\u30e9\u30e0\u30c8\u30f3",
+	0x331A:  "\u30af\u30eb\u30bc\u30a4\u30ed",
+	0x331B:  "\u30af\u30ed\u30fc\u30cd",
+	0x331C:  "\u30b1\u30fc\u30b9",
+	0x331D:  "\u30b3\u30eb\u30ca",
+	0x331E:  "\u30b3\u30fc\u30dd",
+	0x331F:  "\u30b5\u30a4\u30af\u30eb",
+	0x3320:  "\u30b5\u30f3\u30c1\u30fc\u30e0",
+	0x3321:  "\u30b7\u30ea\u30f3\u30b0",
+	0x3322:  "\u30bb\u30f3\u30c1",
+	0x3323:  "\u30bb\u30f3\u30c8",
+	0x3324:  "\u30c0\u30fc\u30b9",
+	0x3325:  "\u30c7\u30b7",
+	0x3326:  "\u30c9\u30eb",
+	0x3327:  "\u30c8\u30f3",
+	0x3328:  "\u30ca\u30ce",
+	0x3329:  "\u30ce\u30c3\u30c8",
+	0x332A:  "\u30cf\u30a4\u30c4",
+	0x332B:  "\u30d1\u30fc\u30bb\u30f3\u30c8",
+	0x332C:  "\u30d1\u30fc\u30c4",
+	0x332D:  "\u30d0\u30fc\u30ec\u30eb",
+	0x332E:  "\u30d4\u30a2\u30b9\u30c8\u30eb",
+	0x332F:  "\u30d4\u30af\u30eb",
+	0x3330:  "\u30d4\u30b3",
+	0x3331:  "\u30d3\u30eb",
+	0x3332:  "\u30d5\u30a1\u30e9\u30c3\u30c9",
+	0x3333:  "\u30d5\u30a3\u30fc\u30c8",
+	0x3334:  "\u30d6\u30c3\u30b7\u30a7\u30eb",
+	0x3335:  "\u30d5\u30e9\u30f3",
+	0x3336:  "\u30d8\u30af\u30bf\u30fc\u30eb",
+	0x3337:  "\u30da\u30bd",
+	0x3338:  "\u30da\u30cb\u30d2",
+	0x3339:  "\u30d8\u30eb\u30c4",
+	0x333A:  "\u30da\u30f3\u30b9",
+	0x333B:  "\u30da\u30fc\u30b8",
+	0x333C:  "\u30d9\u30fc\u30bf",
+	0x333D:  "\u30dd\u30a4\u30f3\u30c8",
+	0x333E:  "\u30dc\u30eb\u30c8",
+	0x333F:  "\u30db\u30f3",
+	0x3340:  "\u30dd\u30f3\u30c9",
+	0x3341:  "\u30db\u30fc\u30eb",
+	0x3342:  "\u30db\u30fc\u30f3",
+	0x3343:  "\u30de\u30a4\u30af\u30ed",
+	0x3344:  "\u30de\u30a4\u30eb",
+	0x3345:  "\u30de\u30c3\u30cf",
+	0x3346:  "\u30de\u30eb\u30af",
+	0x3347:  "\u30de\u30f3\u30b7\u30e7\u30f3",
+	0x3348:  "\u30df\u30af\u30ed\u30f3",
+	0x3349:  "\u30df\u30ea",
+	0x334A:  "\u30df\u30ea\u30d0\u30fc\u30eb",
+	0x334B:  "\u30e1\u30ac",
+	0x334C:  "\u30e1\u30ac\u30c8\u30f3",
+	0x334D:  "\u30e1\u30fc\u30c8\u30eb",
+	0x334E:  "\u30e4\u30fc\u30c9",
+	0x334F:  "\u30e4\u30fc\u30eb",
+	0x3350:  "\u30e6\u30a2\u30f3",
+	0x3351:  "\u30ea\u30c3\u30c8\u30eb",
+	0x3352:  "\u30ea\u30e9",
+	0x3353:  "\u30eb\u30d4\u30fc",
+	0x3354:  "\u30eb\u30fc\u30d6\u30eb",
+	0x3355:  "\u30ec\u30e0",
+	0x3356:  "\u30ec\u30f3\u30c8\u30b2\u30f3",
+	0x3357:  "\u30ef\u30c3\u30c8",
+	0x3358:  "\u0030\u70b9",
+	0x3359:  "\u0031\u70b9",
+	0x335A:  "\u0032\u70b9",
+	0x335B:  "\u0033\u70b9",
+	0x335C:  "\u0034\u70b9",
+	0x335D:  "\u0035\u70b9",
+	0x335E:  "\u0036\u70b9",
+	0x335F:  "\u0037\u70b9",
+	0x3360:  "\u0038\u70b9",
+	0x3361:  "\u0039\u70b9",
+	0x3362:  "\u0031\u0030\u70b9",
+	0x3363:  "\u0031\u0031\u70b9",
+	0x3364:  "\u0031\u0032\u70b9",
+	0x3365:  "\u0031\u0033\u70b9",
+	0x3366:  "\u0031\u0034\u70b9",
+	0x3367:  "\u0031\u0035\u70b9",
+	0x3368:  "\u0031\u0036\u70b9",
+	0x3369:  "\u0031\u0037\u70b9",
+	0x336A:  "\u0031\u0038\u70b9",
+	0x336B:  "\u0031\u0039\u70b9",
+	0x336C:  "\u0032\u0030\u70b9",
+	0x336D:  "\u0032\u0031\u70b9",
+	0x336E:  "\u0032\u0032\u70b9",
+	0x336F:  "\u0032\u0033\u70b9",
+	0x3370:  "\u0032\u0034\u70b9",
+	0x3371:  "\u0068\u0050\u0061",
+	0x3372:  "\u0064\u0061",
+	0x3373:  "\u0041\u0055",
+	0x3374:  "\u0062\u0061\u0072",
+	0x3375:  "\u006f\u0056",
+	0x3376:  "\u0070\u0063",
+	0x3377:  "\u0064\u006d",
+	0x3378:  "\u0064\u006d\u00b2",
+	0x3379:  "\u0064\u006d\u00b3",
+	0x337A:  "\u0049\u0055",
+	0x337B:  "\u5e73\u6210",
+	0x337C:  "\u662d\u548c",
+	0x337D:  "\u5927\u6b63",
+	0x337E:  "\u660e\u6cbb",
+	0x337F:  "\u682a\u5f0f\u4f1a\u793e",
+	0x3380:  "\u0070\u0041",
+	0x3381:  "\u006e\u0041",
+	0x3382:  "\u03bc\u0041",
+	0x3383:  "\u006d\u0041",
+	0x3384:  "\u006b\u0041",
+	0x3385:  "\u004b\u0042",
+	0x3386:  "\u004d\u0042",
+	0x3387:  "\u0047\u0042",
+	0x3388:  "\u0063\u0061\u006c",
+	0x3389:  "\u006b\u0063\u0061\u006c",
+	0x338A:  "\u0070\u0046",
+	0x338B:  "\u006e\u0046",
+	0x338C:  "\u03bc\u0046",
+	0x338D:  "\u03bc\u0067",
+	0x338E:  "\u006d\u0067",
+	0x338F:  "\u006b\u0067",
+	0x3390:  "\u0048\u007a",
+	0x3391:  "\u006b\u0048\u007a",
+	0x3392:  "\u004d\u0048\u007a",
+	0x3393:  "\u0047\u0048\u007a",
+	0x3394:  "\u0054\u0048\u007a",
+	0x3395:  "\u03bc\u2113",
+	0x3396:  "\u006d\u2113",
+	0x3397:  "\u0064\u2113",
+	0x3398:  "\u006b\u2113",
+	0x3399:  "\u0066\u006d",
+	0x339A:  "\u006e\u006d",
+	0x339B:  "\u03bc\u006d",
+	0x339C:  "\u006d\u006d",
+	0x339D:  "\u0063\u006d",
+	0x339E:  "\u006b\u006d",
+	0x339F:  "\u006d\u006d\u00b2",
+	0x33A0:  "\u0063\u006d\u00b2",
+	0x33A1:  "\u006d\u00b2",
+	0x33A2:  "\u006b\u006d\u00b2",
+	0x33A3:  "\u006d\u006d\u00b3",
+	0x33A4:  "\u0063\u006d\u00b3",
+	0x33A5:  "\u006d\u00b3",
+	0x33A6:  "\u006b\u006d\u00b3",
+	0x33A7:  "\u006d\u2215\u0073",
+	0x33A8:  "\u006d\u2215\u0073\u00b2",
+	0x33A9:  "\u0050\u0061",
+	0x33AA:  "\u006b\u0050\u0061",
+	0x33AB:  "\u004d\u0050\u0061",
+	0x33AC:  "\u0047\u0050\u0061",
+	0x33AD:  "\u0072\u0061\u0064",
+	0x33AE:  "\u0072\u0061\u0064\u2215\u0073",
+	0x33AF:  "\u0072\u0061\u0064\u2215\u0073\u00b2",
+	0x33B0:  "\u0070\u0073",
+	0x33B1:  "\u006e\u0073",
+	0x33B2:  "\u03bc\u0073",
+	0x33B3:  "\u006d\u0073",
+	0x33B4:  "\u0070\u0056",
+	0x33B5:  "\u006e\u0056",
+	0x33B6:  "\u03bc\u0056",
+	0x33B7:  "\u006d\u0056",
+	0x33B8:  "\u006b\u0056",
+	0x33B9:  "\u004d\u0056",
+	0x33BA:  "\u0070\u0057",
+	0x33BB:  "\u006e\u0057",
+	0x33BC:  "\u03bc\u0057",
+	0x33BD:  "\u006d\u0057",
+	0x33BE:  "\u006b\u0057",
+	0x33BF:  "\u004d\u0057",
+	0x33C0:  "\u006b\u03a9",
+	0x33C1:  "\u004d\u03a9",
+	0x33C2:  "\u0061\u002e\u006d\u002e",
+	0x33C3:  "\u0042\u0071",
+	0x33C4:  "\u0063\u0063",
+	0x33C5:  "\u0063\u0064",
+	0x33C6:  "\u0043\u2215\u006b\u0067",
+	0x33C7:  "\u0043\u006f\u002e",
+	0x33C8:  "\u0064\u0042",
+	0x33C9:  "\u0047\u0079",
+	0x33CA:  "\u0068\u0061",
+	0x33CB:  "\u0048\u0050",
+	0x33CC:  "\u0069\u006e",
+	0x33CD:  "\u004b\u004b",
+	0x33CE:  "\u004b\u004d",
+	0x33CF:  "\u006b\u0074",
+	0x33D0:  "\u006c\u006d",
+	0x33D1:  "\u006c\u006e",
+	0x33D2:  "\u006c\u006f\u0067",
+	0x33D3:  "\u006c\u0078",
+	0x33D4:  "\u006d\u0062",
+	0x33D5:  "\u006d\u0069\u006c",
+	0x33D6:  "\u006d\u006f\u006c",
+	0x33D7:  "\u0050\u0048",
+	0x33D8:  "\u0070\u002e\u006d\u002e",
+	0x33D9:  "\u0050\u0050\u004d",
+	0x33DA:  "\u0050\u0052",
+	0x33DB:  "\u0073\u0072",
+	0x33DC:  "\u0053\u0076",
+	0x33DD:  "\u0057\u0062",
+	0x33DE:  "\u0056\u2215\u006d",
+	0x33DF:  "\u0041\u2215\u006d",
+	0x33E0:  "\u0031\u65e5",
+	0x33E1:  "\u0032\u65e5",
+	0x33E2:  "\u0033\u65e5",
+	0x33E3:  "\u0034\u65e5",
+	0x33E4:  "\u0035\u65e5",
+	0x33E5:  "\u0036\u65e5",
+	0x33E6:  "\u0037\u65e5",
+	0x33E7:  "\u0038\u65e5",
+	0x33E8:  "\u0039\u65e5",
+	0x33E9:  "\u0031\u0030\u65e5",
+	0x33EA:  "\u0031\u0031\u65e5",
+	0x33EB:  "\u0031\u0032\u65e5",
+	0x33EC:  "\u0031\u0033\u65e5",
+	0x33ED:  "\u0031\u0034\u65e5",
+	0x33EE:  "\u0031\u0035\u65e5",
+	0x33EF:  "\u0031\u0036\u65e5",
+	0x33F0:  "\u0031\u0037\u65e5",
+	0x33F1:  "\u0031\u0038\u65e5",
+	0x33F2:  "\u0031\u0039\u65e5",
+	0x33F3:  "\u0032\u0030\u65e5",
+	0x33F4:  "\u0032\u0031\u65e5",
+	0x33F5:  "\u0032\u0032\u65e5",
+	0x33F6:  "\u0032\u0033\u65e5",
+	0x33F7:  "\u0032\u0034\u65e5",
+	0x33F8:  "\u0032\u0035\u65e5",
+	0x33F9:  "\u0032\u0036\u65e5",
+	0x33FA:  "\u0032\u0037\u65e5",
+	0x33FB:  "\u0032\u0038\u65e5",
+	0x33FC:  "\u0032\u0039\u65e5",
+	0x33FD:  "\u0033\u0030\u65e5",
+	0x33FE:  "\u0033\u0031\u65e5",
+	0x33FF:  "\u0067\u0061\u006c",
+	0xA69C:  "\u044a",
+	0xA69D:  "\u044c",
+	0xA770:  "\ua76f",
+	0xA7F2:  "\u0043",
+	0xA7F3:  "\u0046",
+	0xA7F4:  "\u0051",
+	0xA7F8:  "\u0126",
+	0xA7F9:  "\u0153",
+	0xAB5C:  "\ua727",
+	0xAB5D:  "\uab37",
+	0xAB5E:  "\u026b",
+	0xAB5F:  "\uab52",
+	0xAB69:  "\u028d",
+	0xFB00:  "\u0066\u0066",
+	0xFB01:  "\u0066\u0069",
+	0xFB02:  "\u0066\u006c",
+	0xFB03:  "\u0066\u0066\u0069",
+	0xFB04:  "\u0066\u0066\u006c",
+	0xFB05:  "\u017f\u0074",
+	0xFB06:  "\u0073\u0074",
+	0xFB13:  "\u0574\u0576",
+	0xFB14:  "\u0574\u0565",
+	0xFB15:  "\u0574\u056b",
+	0xFB16:  "\u057e\u0576",
+	0xFB17:  "\u0574\u056d",
+	0xFB20:  "\u05e2",
+	0xFB21:  "\u05d0",
+	0xFB22:  "\u05d3",
+	0xFB23:  "\u05d4",
+	0xFB24:  "\u05db",
+	0xFB25:  "\u05dc",
+	0xFB26:  "\u05dd",
+	0xFB27:  "\u05e8",
+	0xFB28:  "\u05ea",
+	0xFB29:  "\u002b",
+	0xFB4F:  "\u05d0\u05dc",
+	0xFB50:  "\u0671",
+	0xFB51:  "\u0671",
+	0xFB52:  "\u067b",
+	0xFB53:  "\u067b",
+	0xFB54:  "\u067b",
+	0xFB55:  "\u067b",
+	0xFB56:  "\u067e",
+	0xFB57:  "\u067e",
+	0xFB58:  "\u067e",
+	0xFB59:  "\u067e",
+	0xFB5A:  "\u0680",
+	0xFB5B:  "\u0680",
+	0xFB5C:  "\u0680",
+	0xFB5D:  "\u0680",
+	0xFB5E:  "\u067a",
+	0xFB5F:  "\u067a",
+	0xFB60:  "\u067a",
+	0xFB61:  "\u067a",
+	0xFB62:  "\u067f",
+	0xFB63:  "\u067f",
+	0xFB64:  "\u067f",
+	0xFB65:  "\u067f",
+	0xFB66:  "\u0679",
+	0xFB67:  "\u0679",
+	0xFB68:  "\u0679",
+	0xFB69:  "\u0679",
+	0xFB6A:  "\u06a4",
+	0xFB6B:  "\u06a4",
+	0xFB6C:  "\u06a4",
+	0xFB6D:  "\u06a4",
+	0xFB6E:  "\u06a6",
+	0xFB6F:  "\u06a6",
+	0xFB70:  "\u06a6",
+	0xFB71:  "\u06a6",
+	0xFB72:  "\u0684",
+	0xFB73:  "\u0684",
+	0xFB74:  "\u0684",
+	0xFB75:  "\u0684",
+	0xFB76:  "\u0683",
+	0xFB77:  "\u0683",
+	0xFB78:  "\u0683",
+	0xFB79:  "\u0683",
+	0xFB7A:  "\u0686",
+	0xFB7B:  "\u0686",
+	0xFB7C:  "\u0686",
+	0xFB7D:  "\u0686",
+	0xFB7E:  "\u0687",
+	0xFB7F:  "\u0687",
+	0xFB80:  "\u0687",
+	0xFB81:  "\u0687",
+	0xFB82:  "\u068d",
+	0xFB83:  "\u068d",
+	0xFB84:  "\u068c",
+	0xFB85:  "\u068c",
+	0xFB86:  "\u068e",
+	0xFB87:  "\u068e",
+	0xFB88:  "\u0688",
+	0xFB89:  "\u0688",
+	0xFB8A:  "\u0698",
+	0xFB8B:  "\u0698",
+	0xFB8C:  "\u0691",
+	0xFB8D:  "\u0691",
+	0xFB8E:  "\u06a9",
+	0xFB8F:  "\u06a9",
+	0xFB90:  "\u06a9",
+	0xFB91:  "\u06a9",
+	0xFB92:  "\u06af",
+	0xFB93:  "\u06af",
+	0xFB94:  "\u06af",
+	0xFB95:  "\u06af",
+	0xFB96:  "\u06b3",
+	0xFB97:  "\u06b3",
+	0xFB98:  "\u06b3",
+	0xFB99:  "\u06b3",
+	0xFB9A:  "\u06b1",
+	0xFB9B:  "\u06b1",
+	0xFB9C:  "\u06b1",
+	0xFB9D:  "\u06b1",
+	0xFB9E:  "\u06ba",
+	0xFB9F:  "\u06ba",
+	0xFBA0:  "\u06bb",
+	0xFBA1:  "\u06bb",
+	0xFBA2:  "\u06bb",
+	0xFBA3:  "\u06bb",
+	0xFBA4:  "\u06c0",
+	0xFBA5:  "\u06c0",
+	0xFBA6:  "\u06c1",
+	0xFBA7:  "\u06c1",
+	0xFBA8:  "\u06c1",
+	0xFBA9:  "\u06c1",
+	0xFBAA:  "\u06be",
+	0xFBAB:  "\u06be",
+	0xFBAC:  "\u06be",
+	0xFBAD:  "\u06be",
+	0xFBAE:  "\u06d2",
+	0xFBAF:  "\u06d2",
+	0xFBB0:  "\u06d3",
+	0xFBB1:  "\u06d3",
+	0xFBD3:  "\u06ad",
+	0xFBD4:  "\u06ad",
+	0xFBD5:  "\u06ad",
+	0xFBD6:  "\u06ad",
+	0xFBD7:  "\u06c7",
+	0xFBD8:  "\u06c7",
+	0xFBD9:  "\u06c6",
+	0xFBDA:  "\u06c6",
+	0xFBDB:  "\u06c8",
+	0xFBDC:  "\u06c8",
+	0xFBDD:  "\u0677",
+	0xFBDE:  "\u06cb",
+	0xFBDF:  "\u06cb",
+	0xFBE0:  "\u06c5",
+	0xFBE1:  "\u06c5",
+	0xFBE2:  "\u06c9",
+	0xFBE3:  "\u06c9",
+	0xFBE4:  "\u06d0",
+	0xFBE5:  "\u06d0",
+	0xFBE6:  "\u06d0",
+	0xFBE7:  "\u06d0",
+	0xFBE8:  "\u0649",
+	0xFBE9:  "\u0649",
+	0xFBEA:  "\u0626\u0627",
+	0xFBEB:  "\u0626\u0627",
+	0xFBEC:  "\u0626\u06d5",
+	0xFBED:  "\u0626\u06d5",
+	0xFBEE:  "\u0626\u0648",
+	0xFBEF:  "\u0626\u0648",
+	0xFBF0:  "\u0626\u06c7",
+	0xFBF1:  "\u0626\u06c7",
+	0xFBF2:  "\u0626\u06c6",
+	0xFBF3:  "\u0626\u06c6",
+	0xFBF4:  "\u0626\u06c8",
+	0xFBF5:  "\u0626\u06c8",
+	0xFBF6:  "\u0626\u06d0",
+	0xFBF7:  "\u0626\u06d0",
+	0xFBF8:  "\u0626\u06d0",
+	0xFBF9:  "\u0626\u0649",
+	0xFBFA:  "\u0626\u0649",
+	0xFBFB:  "\u0626\u0649",
+	0xFBFC:  "\u06cc",
+	0xFBFD:  "\u06cc",
+	0xFBFE:  "\u06cc",
+	0xFBFF:  "\u06cc",
+	0xFC00:  "\u0626\u062c",
+	0xFC01:  "\u0626\u062d",
+	0xFC02:  "\u0626\u0645",
+	0xFC03:  "\u0626\u0649",
+	0xFC04:  "\u0626\u064a",
+	0xFC05:  "\u0628\u062c",
+	0xFC06:  "\u0628\u062d",
+	0xFC07:  "\u0628\u062e",
+	0xFC08:  "\u0628\u0645",
+	0xFC09:  "\u0628\u0649",
+	0xFC0A:  "\u0628\u064a",
+	0xFC0B:  "\u062a\u062c",
+	0xFC0C:  "\u062a\u062d",
+	0xFC0D:  "\u062a\u062e",
+	0xFC0E:  "\u062a\u0645",
+	0xFC0F:  "\u062a\u0649",
+	0xFC10:  "\u062a\u064a",
+	0xFC11:  "\u062b\u062c",
+	0xFC12:  "\u062b\u0645",
+	0xFC13:  "\u062b\u0649",
+	0xFC14:  "\u062b\u064a",
+	0xFC15:  "\u062c\u062d",
+	0xFC16:  "\u062c\u0645",
+	0xFC17:  "\u062d\u062c",
+	0xFC18:  "\u062d\u0645",
+	0xFC19:  "\u062e\u062c",
+	0xFC1A:  "\u062e\u062d",
+	0xFC1B:  "\u062e\u0645",
+	0xFC1C:  "\u0633\u062c",
+	0xFC1D:  "\u0633\u062d",
+	0xFC1E:  "\u0633\u062e",
+	0xFC1F:  "\u0633\u0645",
+	0xFC20:  "\u0635\u062d",
+	0xFC21:  "\u0635\u0645",
+	0xFC22:  "\u0636\u062c",
+	0xFC23:  "\u0636\u062d",
+	0xFC24:  "\u0636\u062e",
+	0xFC25:  "\u0636\u0645",
+	0xFC26:  "\u0637\u062d",
+	0xFC27:  "\u0637\u0645",
+	0xFC28:  "\u0638\u0645",
+	0xFC29:  "\u0639\u062c",
+	0xFC2A:  "\u0639\u0645",
+	0xFC2B:  "\u063a\u062c",
+	0xFC2C:  "\u063a\u0645",
+	0xFC2D:  "\u0641\u062c",
+	0xFC2E:  "\u0641\u062d",
+	0xFC2F:  "\u0641\u062e",
+	0xFC30:  "\u0641\u0645",
+	0xFC31:  "\u0641\u0649",
+	0xFC32:  "\u0641\u064a",
+	0xFC33:  "\u0642\u062d",
+	0xFC34:  "\u0642\u0645",
+	0xFC35:  "\u0642\u0649",
+	0xFC36:  "\u0642\u064a",
+	0xFC37:  "\u0643\u0627",
+	0xFC38:  "\u0643\u062c",
+	0xFC39:  "\u0643\u062d",
+	0xFC3A:  "\u0643\u062e",
+	0xFC3B:  "\u0643\u0644",
+	0xFC3C:  "\u0643\u0645",
+	0xFC3D:  "\u0643\u0649",
+	0xFC3E:  "\u0643\u064a",
+	0xFC3F:  "\u0644\u062c",
+	0xFC40:  "\u0644\u062d",
+	0xFC41:  "\u0644\u062e",
+	0xFC42:  "\u0644\u0645",
+	0xFC43:  "\u0644\u0649",
+	0xFC44:  "\u0644\u064a",
+	0xFC45:  "\u0645\u062c",
+	0xFC46:  "\u0645\u062d",
+	0xFC47:  "\u0645\u062e",
+	0xFC48:  "\u0645\u0645",
+	0xFC49:  "\u0645\u0649",
+	0xFC4A:  "\u0645\u064a",
+	0xFC4B:  "\u0646\u062c",
+	0xFC4C:  "\u0646\u062d",
+	0xFC4D:  "\u0646\u062e",
+	0xFC4E:  "\u0646\u0645",
+	0xFC4F:  "\u0646\u0649",
+	0xFC50:  "\u0646\u064a",
+	0xFC51:  "\u0647\u062c",
+	0xFC52:  "\u0647\u0645",
+	0xFC53:  "\u0647\u0649",
+	0xFC54:  "\u0647\u064a",
+	0xFC55:  "\u064a\u062c",
+	0xFC56:  "\u064a\u062d",
+	0xFC57:  "\u064a\u062e",
+	0xFC58:  "\u064a\u0645",
+	0xFC59:  "\u064a\u0649",
+	0xFC5A:  "\u064a\u064a",
+	0xFC5B:  "\u0630\u0670",
+	0xFC5C:  "\u0631\u0670",
+	0xFC5D:  "\u0649\u0670",
+	0xFC5E:  "\u0020\u064c\u0651",
+	0xFC5F:  "\u0020\u064d\u0651",
+	0xFC60:  "\u0020\u064e\u0651",
+	0xFC61:  "\u0020\u064f\u0651",
+	0xFC62:  "\u0020\u0650\u0651",
+	0xFC63:  "\u0020\u0651\u0670",
+	0xFC64:  "\u0626\u0631",
+	0xFC65:  "\u0626\u0632",
+	0xFC66:  "\u0626\u0645",
+	0xFC67:  "\u0626\u0646",
+	0xFC68:  "\u0626\u0649",
+	0xFC69:  "\u0626\u064a",
+	0xFC6A:  "\u0628\u0631",
+	0xFC6B:  "\u0628\u0632",
+	0xFC6C:  "\u0628\u0645",
+	0xFC6D:  "\u0628\u0646",
+	0xFC6E:  "\u0628\u0649",
+	0xFC6F:  "\u0628\u064a",
+	0xFC70:  "\u062a\u0631",
+	0xFC71:  "\u062a\u0632",
+	0xFC72:  "\u062a\u0645",
+	0xFC73:  "\u062a\u0646",
+	0xFC74:  "\u062a\u0649",
+	0xFC75:  "\u062a\u064a",
+	0xFC76:  "\u062b\u0631",
+	0xFC77:  "\u062b\u0632",
+	0xFC78:  "\u062b\u0645",
+	0xFC79:  "\u062b\u0646",
+	0xFC7A:  "\u062b\u0649",
+	0xFC7B:  "\u062b\u064a",
+	0xFC7C:  "\u0641\u0649",
+	0xFC7D:  "\u0641\u064a",
+	0xFC7E:  "\u0642\u0649",
+	0xFC7F:  "\u0642\u064a",
+	0xFC80:  "\u0643\u0627",
+	0xFC81:  "\u0643\u0644",
+	0xFC82:  "\u0643\u0645",
+	0xFC83:  "\u0643\u0649",
+	0xFC84:  "\u0643\u064a",
+	0xFC85:  "\u0644\u0645",
+	0xFC86:  "\u0644\u0649",
+	0xFC87:  "\u0644\u064a",
+	0xFC88:  "\u0645\u0627",
+	0xFC89:  "\u0645\u0645",
+	0xFC8A:  "\u0646\u0631",
+	0xFC8B:  "\u0646\u0632",
+	0xFC8C:  "\u0646\u0645",
+	0xFC8D:  "\u0646\u0646",
+	0xFC8E:  "\u0646\u0649",
+	0xFC8F:  "\u0646\u064a",
+	0xFC90:  "\u0649\u0670",
+	0xFC91:  "\u064a\u0631",
+	0xFC92:  "\u064a\u0632",
+	0xFC93:  "\u064a\u0645",
+	0xFC94:  "\u064a\u0646",
+	0xFC95:  "\u064a\u0649",
+	0xFC96:  "\u064a\u064a",
+	0xFC97:  "\u0626\u062c",
+	0xFC98:  "\u0626\u062d",
+	0xFC99:  "\u0626\u062e",
+	0xFC9A:  "\u0626\u0645",
+	0xFC9B:  "\u0626\u0647",
+	0xFC9C:  "\u0628\u062c",
+	0xFC9D:  "\u0628\u062d",
+	0xFC9E:  "\u0628\u062e",
+	0xFC9F:  "\u0628\u0645",
+	0xFCA0:  "\u0628\u0647",
+	0xFCA1:  "\u062a\u062c",
+	0xFCA2:  "\u062a\u062d",
+	0xFCA3:  "\u062a\u062e",
+	0xFCA4:  "\u062a\u0645",
+	0xFCA5:  "\u062a\u0647",
+	0xFCA6:  "\u062b\u0645",
+	0xFCA7:  "\u062c\u062d",
+	0xFCA8:  "\u062c\u0645",
+	0xFCA9:  "\u062d\u062c",
+	0xFCAA:  "\u062d\u0645",
+	0xFCAB:  "\u062e\u062c",
+	0xFCAC:  "\u062e\u0645",
+	0xFCAD:  "\u0633\u062c",
+	0xFCAE:  "\u0633\u062d",
+	0xFCAF:  "\u0633\u062e",
+	0xFCB0:  "\u0633\u0645",
+	0xFCB1:  "\u0635\u062d",
+	0xFCB2:  "\u0635\u062e",
+	0xFCB3:  "\u0635\u0645",
+	0xFCB4:  "\u0636\u062c",
+	0xFCB5:  "\u0636\u062d",
+	0xFCB6:  "\u0636\u062e",
+	0xFCB7:  "\u0636\u0645",
+	0xFCB8:  "\u0637\u062d",
+	0xFCB9:  "\u0638\u0645",
+	0xFCBA:  "\u0639\u062c",
+	0xFCBB:  "\u0639\u0645",
+	0xFCBC:  "\u063a\u062c",
+	0xFCBD:  "\u063a\u0645",
+	0xFCBE:  "\u0641\u062c",
+	0xFCBF:  "\u0641\u062d",
+	0xFCC0:  "\u0641\u062e",
+	0xFCC1:  "\u0641\u0645",
+	0xFCC2:  "\u0642\u062d",
+	0xFCC3:  "\u0642\u0645",
+	0xFCC4:  "\u0643\u062c",
+	0xFCC5:  "\u0643\u062d",
+	0xFCC6:  "\u0643\u062e",
+	0xFCC7:  "\u0643\u0644",
+	0xFCC8:  "\u0643\u0645",
+	0xFCC9:  "\u0644\u062c",
+	0xFCCA:  "\u0644\u062d",
+	0xFCCB:  "\u0644\u062e",
+	0xFCCC:  "\u0644\u0645",
+	0xFCCD:  "\u0644\u0647",
+	0xFCCE:  "\u0645\u062c",
+	0xFCCF:  "\u0645\u062d",
+	0xFCD0:  "\u0645\u062e",
+	0xFCD1:  "\u0645\u0645",
+	0xFCD2:  "\u0646\u062c",
+	0xFCD3:  "\u0646\u062d",
+	0xFCD4:  "\u0646\u062e",
+	0xFCD5:  "\u0646\u0645",
+	0xFCD6:  "\u0646\u0647",
+	0xFCD7:  "\u0647\u062c",
+	0xFCD8:  "\u0647\u0645",
+	0xFCD9:  "\u0647\u0670",
+	0xFCDA:  "\u064a\u062c",
+	0xFCDB:  "\u064a\u062d",
+	0xFCDC:  "\u064a\u062e",
+	0xFCDD:  "\u064a\u0645",
+	0xFCDE:  "\u064a\u0647",
+	0xFCDF:  "\u0626\u0645",
+	0xFCE0:  "\u0626\u0647",
+	0xFCE1:  "\u0628\u0645",
+	0xFCE2:  "\u0628\u0647",
+	0xFCE3:  "\u062a\u0645",
+	0xFCE4:  "\u062a\u0647",
+	0xFCE5:  "\u062b\u0645",
+	0xFCE6:  "\u062b\u0647",
+	0xFCE7:  "\u0633\u0645",
+	0xFCE8:  "\u0633\u0647",
+	0xFCE9:  "\u0634\u0645",
+	0xFCEA:  "\u0634\u0647",
+	0xFCEB:  "\u0643\u0644",
+	0xFCEC:  "\u0643\u0645",
+	0xFCED:  "\u0644\u0645",
+	0xFCEE:  "\u0646\u0645",
+	0xFCEF:  "\u0646\u0647",
+	0xFCF0:  "\u064a\u0645",
+	0xFCF1:  "\u064a\u0647",
+	0xFCF2:  "\u0640\u064e\u0651",
+	0xFCF3:  "\u0640\u064f\u0651",
+	0xFCF4:  "\u0640\u0650\u0651",
+	0xFCF5:  "\u0637\u0649",
+	0xFCF6:  "\u0637\u064a",
+	0xFCF7:  "\u0639\u0649",
+	0xFCF8:  "\u0639\u064a",
+	0xFCF9:  "\u063a\u0649",
+	0xFCFA:  "\u063a\u064a",
+	0xFCFB:  "\u0633\u0649",
+	0xFCFC:  "\u0633\u064a",
+	0xFCFD:  "\u0634\u0649",
+	0xFCFE:  "\u0634\u064a",
+	0xFCFF:  "\u062d\u0649",
+	0xFD00:  "\u062d\u064a",
+	0xFD01:  "\u062c\u0649",
+	0xFD02:  "\u062c\u064a",
+	0xFD03:  "\u062e\u0649",
+	0xFD04:  "\u062e\u064a",
+	0xFD05:  "\u0635\u0649",
+	0xFD06:  "\u0635\u064a",
+	0xFD07:  "\u0636\u0649",
+	0xFD08:  "\u0636\u064a",
+	0xFD09:  "\u0634\u062c",
+	0xFD0A:  "\u0634\u062d",
+	0xFD0B:  "\u0634\u062e",
+	0xFD0C:  "\u0634\u0645",
+	0xFD0D:  "\u0634\u0631",
+	0xFD0E:  "\u0633\u0631",
+	0xFD0F:  "\u0635\u0631",
+	0xFD10:  "\u0636\u0631",
+	0xFD11:  "\u0637\u0649",
+	0xFD12:  "\u0637\u064a",
+	0xFD13:  "\u0639\u0649",
+	0xFD14:  "\u0639\u064a",
+	0xFD15:  "\u063a\u0649",
+	0xFD16:  "\u063a\u064a",
+	0xFD17:  "\u0633\u0649",
+	0xFD18:  "\u0633\u064a",
+	0xFD19:  "\u0634\u0649",
+	0xFD1A:  "\u0634\u064a",
+	0xFD1B:  "\u062d\u0649",
+	0xFD1C:  "\u062d\u064a",
+	0xFD1D:  "\u062c\u0649",
+	0xFD1E:  "\u062c\u064a",
+	0xFD1F:  "\u062e\u0649",
+	0xFD20:  "\u062e\u064a",
+	0xFD21:  "\u0635\u0649",
+	0xFD22:  "\u0635\u064a",
+	0xFD23:  "\u0636\u0649",
+	0xFD24:  "\u0636\u064a",
+	0xFD25:  "\u0634\u062c",
+	0xFD26:  "\u0634\u062d",
+	0xFD27:  "\u0634\u062e",
+	0xFD28:  "\u0634\u0645",
+	0xFD29:  "\u0634\u0631",
+	0xFD2A:  "\u0633\u0631",
+	0xFD2B:  "\u0635\u0631",
+	0xFD2C:  "\u0636\u0631",
+	0xFD2D:  "\u0634\u062c",
+	0xFD2E:  "\u0634\u062d",
+	0xFD2F:  "\u0634\u062e",
+	0xFD30:  "\u0634\u0645",
+	0xFD31:  "\u0633\u0647",
+	0xFD32:  "\u0634\u0647",
+	0xFD33:  "\u0637\u0645",
+	0xFD34:  "\u0633\u062c",
+	0xFD35:  "\u0633\u062d",
+	0xFD36:  "\u0633\u062e",
+	0xFD37:  "\u0634\u062c",
+	0xFD38:  "\u0634\u062d",
+	0xFD39:  "\u0634\u062e",
+	0xFD3A:  "\u0637\u0645",
+	0xFD3B:  "\u0638\u0645",
+	0xFD3C:  "\u0627\u064b",
+	0xFD3D:  "\u0627\u064b",
+	0xFD50:  "\u062a\u062c\u0645",
+	0xFD51:  "\u062a\u062d\u062c",
+	0xFD52:  "\u062a\u062d\u062c",
+	0xFD53:  "\u062a\u062d\u0645",
+	0xFD54:  "\u062a\u062e\u0645",
+	0xFD55:  "\u062a\u0645\u062c",
+	0xFD56:  "\u062a\u0645\u062d",
+	0xFD57:  "\u062a\u0645\u062e",
+	0xFD58:  "\u062c\u0645\u062d",
+	0xFD59:  "\u062c\u0645\u062d",
+	0xFD5A:  "\u062d\u0645\u064a",
+	0xFD5B:  "\u062d\u0645\u0649",
+	0xFD5C:  "\u0633\u062d\u062c",
+	0xFD5D:  "\u0633\u062c\u062d",
+	0xFD5E:  "\u0633\u062c\u0649",
+	0xFD5F:  "\u0633\u0645\u062d",
+	0xFD60:  "\u0633\u0645\u062d",
+	0xFD61:  "\u0633\u0645\u062c",
+	0xFD62:  "\u0633\u0645\u0645",
+	0xFD63:  "\u0633\u0645\u0645",
+	0xFD64:  "\u0635\u062d\u062d",
+	0xFD65:  "\u0635\u062d\u062d",
+	0xFD66:  "\u0635\u0645\u0645",
+	0xFD67:  "\u0634\u062d\u0645",
+	0xFD68:  "\u0634\u062d\u0645",
+	0xFD69:  "\u0634\u062c\u064a",
+	0xFD6A:  "\u0634\u0645\u062e",
+	0xFD6B:  "\u0634\u0645\u062e",
+	0xFD6C:  "\u0634\u0645\u0645",
+	0xFD6D:  "\u0634\u0645\u0645",
+	0xFD6E:  "\u0636\u062d\u0649",
+	0xFD6F:  "\u0636\u062e\u0645",
+	0xFD70:  "\u0636\u062e\u0645",
+	0xFD71:  "\u0637\u0645\u062d",
+	0xFD72:  "\u0637\u0645\u062d",
+	0xFD73:  "\u0637\u0645\u0645",
+	0xFD74:  "\u0637\u0645\u064a",
+	0xFD75:  "\u0639\u062c\u0645",
+	0xFD76:  "\u0639\u0645\u0645",
+	0xFD77:  "\u0639\u0645\u0645",
+	0xFD78:  "\u0639\u0645\u0649",
+	0xFD79:  "\u063a\u0645\u0645",
+	0xFD7A:  "\u063a\u0645\u064a",
+	0xFD7B:  "\u063a\u0645\u0649",
+	0xFD7C:  "\u0641\u062e\u0645",
+	0xFD7D:  "\u0641\u062e\u0645",
+	0xFD7E:  "\u0642\u0645\u062d",
+	0xFD7F:  "\u0642\u0645\u0645",
+	0xFD80:  "\u0644\u062d\u0645",
+	0xFD81:  "\u0644\u062d\u064a",
+	0xFD82:  "\u0644\u062d\u0649",
+	0xFD83:  "\u0644\u062c\u062c",
+	0xFD84:  "\u0644\u062c\u062c",
+	0xFD85:  "\u0644\u062e\u0645",
+	0xFD86:  "\u0644\u062e\u0645",
+	0xFD87:  "\u0644\u0645\u062d",
+	0xFD88:  "\u0644\u0645\u062d",
+	0xFD89:  "\u0645\u062d\u062c",
+	0xFD8A:  "\u0645\u062d\u0645",
+	0xFD8B:  "\u0645\u062d\u064a",
+	0xFD8C:  "\u0645\u062c\u062d",
+	0xFD8D:  "\u0645\u062c\u0645",
+	0xFD8E:  "\u0645\u062e\u062c",
+	0xFD8F:  "\u0645\u062e\u0645",
+	0xFD92:  "\u0645\u062c\u062e",
+	0xFD93:  "\u0647\u0645\u062c",
+	0xFD94:  "\u0647\u0645\u0645",
+	0xFD95:  "\u0646\u062d\u0645",
+	0xFD96:  "\u0646\u062d\u0649",
+	0xFD97:  "\u0646\u062c\u0645",
+	0xFD98:  "\u0646\u062c\u0645",
+	0xFD99:  "\u0646\u062c\u0649",
+	0xFD9A:  "\u0646\u0645\u064a",
+	0xFD9B:  "\u0646\u0645\u0649",
+	0xFD9C:  "\u064a\u0645\u0645",
+	0xFD9D:  "\u064a\u0645\u0645",
+	0xFD9E:  "\u0628\u062e\u064a",
+	0xFD9F:  "\u062a\u062c\u064a",
+	0xFDA0:  "\u062a\u062c\u0649",
+	0xFDA1:  "\u062a\u062e\u064a",
+	0xFDA2:  "\u062a\u062e\u0649",
+	0xFDA3:  "\u062a\u0645\u064a",
+	0xFDA4:  "\u062a\u0645\u0649",
+	0xFDA5:  "\u062c\u0645\u064a",
+	0xFDA6:  "\u062c\u062d\u0649",
+	0xFDA7:  "\u062c\u0645\u0649",
+	0xFDA8:  "\u0633\u062e\u0649",
+	0xFDA9:  "\u0635\u062d\u064a",
+	0xFDAA:  "\u0634\u062d\u064a",
+	0xFDAB:  "\u0636\u062d\u064a",
+	0xFDAC:  "\u0644\u062c\u064a",
+	0xFDAD:  "\u0644\u0645\u064a",
+	0xFDAE:  "\u064a\u062d\u064a",
+	0xFDAF:  "\u064a\u062c\u064a",
+	0xFDB0:  "\u064a\u0645\u064a",
+	0xFDB1:  "\u0645\u0645\u064a",
+	0xFDB2:  "\u0642\u0645\u064a",
+	0xFDB3:  "\u0646\u062d\u064a",
+	0xFDB4:  "\u0642\u0645\u062d",
+	0xFDB5:  "\u0644\u062d\u0645",
+	0xFDB6:  "\u0639\u0645\u064a",
+	0xFDB7:  "\u0643\u0645\u064a",
+	0xFDB8:  "\u0646\u062c\u062d",
+	0xFDB9:  "\u0645\u062e\u064a",
+	0xFDBA:  "\u0644\u062c\u0645",
+	0xFDBB:  "\u0643\u0645\u0645",
+	0xFDBC:  "\u0644\u062c\u0645",
+	0xFDBD:  "\u0646\u062c\u062d",
+	0xFDBE:  "\u062c\u062d\u064a",
+	0xFDBF:  "\u062d\u062c\u064a",
+	0xFDC0:  "\u0645\u062c\u064a",
+	0xFDC1:  "\u0641\u0645\u064a",
+	0xFDC2:  "\u0628\u062d\u064a",
+	0xFDC3:  "\u0643\u0645\u0645",
+	0xFDC4:  "\u0639\u062c\u0645",
+	0xFDC5:  "\u0635\u0645\u0645",
+	0xFDC6:  "\u0633\u062e\u064a",
+	0xFDC7:  "\u0646\u062c\u064a",
+	0xFDF0:  "\u0635\u0644\u06d2",
+	0xFDF1:  "\u0642\u0644\u06d2",
+	0xFDF2:  "\u0627\u0644\u0644\u0647",
+	0xFDF3:  "\u0627\u0643\u0628\u0631",
+	0xFDF4:  "\u0645\u062d\u0645\u062f",
+	0xFDF5:  "\u0635\u0644\u0639\u0645",
+	0xFDF6:  "\u0631\u0633\u0648\u0644",
+	0xFDF7:  "\u0639\u0644\u064a\u0647",
+	0xFDF8:  "\u0648\u0633\u0644\u0645",
+	0xFDF9:  "\u0635\u0644\u0649",
+	0xFDFA:  "\u0635\u0644\u0649\u0020\u0627\u0644\u0644\u0647\u0020\u0639\u0644\u064a\u0647\u0020\u0648\u0633\u0644\u0645",
+	0xFDFB:  "\u062c\u0644\u0020\u062c\u0644\u0627\u0644\u0647",
+	0xFDFC:  "\u0631\u06cc\u0627\u0644",
+	0xFE10:  "\u002c",
+	0xFE11:  "\u3001",
+	0xFE12:  "\u3002",
+	0xFE13:  "\u003a",
+	0xFE14:  "\u003b",
+	0xFE15:  "\u0021",
+	0xFE16:  "\u003f",
+	0xFE17:  "\u3016",
+	0xFE18:  "\u3017",
+	0xFE19:  "\u2026",
+	0xFE30:  "\u2025",
+	0xFE31:  "\u2014",
+	0xFE32:  "\u2013",
+	0xFE33:  "\u005f",
+	0xFE34:  "\u005f",
+	0xFE35:  "\u0028",
+	0xFE36:  "\u0029",
+	0xFE37:  "\u007b",
+	0xFE38:  "\u007d",
+	0xFE39:  "\u3014",
+	0xFE3A:  "\u3015",
+	0xFE3B:  "\u3010",
+	0xFE3C:  "\u3011",
+	0xFE3D:  "\u300a",
+	0xFE3E:  "\u300b",
+	0xFE3F:  "\u3008",
+	0xFE40:  "\u3009",
+	0xFE41:  "\u300c",
+	0xFE42:  "\u300d",
+	0xFE43:  "\u300e",
+	0xFE44:  "\u300f",
+	0xFE47:  "\u005b",
+	0xFE48:  "\u005d",
+	0xFE49:  "\u203e",
+	0xFE4A:  "\u203e",
+	0xFE4B:  "\u203e",
+	0xFE4C:  "\u203e",
+	0xFE4D:  "\u005f",
+	0xFE4E:  "\u005f",
+	0xFE4F:  "\u005f",
+	0xFE50:  "\u002c",
+	0xFE51:  "\u3001",
+	0xFE52:  "\u002e",
+	0xFE54:  "\u003b",
+	0xFE55:  "\u003a",
+	0xFE56:  "\u003f",
+	0xFE57:  "\u0021",
+	0xFE58:  "\u2014",
+	0xFE59:  "\u0028",
+	0xFE5A:  "\u0029",
+	0xFE5B:  "\u007b",
+	0xFE5C:  "\u007d",
+	0xFE5D:  "\u3014",
+	0xFE5E:  "\u3015",
+	0xFE5F:  "\u0023",
+	0xFE60:  "\u0026",
+	0xFE61:  "\u002a",
+	0xFE62:  "\u002b",
+	0xFE63:  "\u002d",
+	0xFE64:  "\u003c",
+	0xFE65:  "\u003e",
+	0xFE66:  "\u003d",
+	0xFE68:  "\u005c",
+	0xFE69:  "\u0024",
+	0xFE6A:  "\u0025",
+	0xFE6B:  "\u0040",
+	0xFE70:  "\u0020\u064b",
+	0xFE71:  "\u0640\u064b",
+	0xFE72:  "\u0020\u064c",
+	0xFE74:  "\u0020\u064d",
+	0xFE76:  "\u0020\u064e",
+	0xFE77:  "\u0640\u064e",
+	0xFE78:  "\u0020\u064f",
+	0xFE79:  "\u0640\u064f",
+	0xFE7A:  "\u0020\u0650",
+	0xFE7B:  "\u0640\u0650",
+	0xFE7C:  "\u0020\u0651",
+	0xFE7D:  "\u0640\u0651",
+	0xFE7E:  "\u0020\u0652",
+	0xFE7F:  "\u0640\u0652",
+	0xFE80:  "\u0621",
+	0xFE81:  "\u0622",
+	0xFE82:  "\u0622",
+	0xFE83:  "\u0623",
+	0xFE84:  "\u0623",
+	0xFE85:  "\u0624",
+	0xFE86:  "\u0624",
+	0xFE87:  "\u0625",
+	0xFE88:  "\u0625",
+	0xFE89:  "\u0626",
+	0xFE8A:  "\u0626",
+	0xFE8B:  "\u0626",
+	0xFE8C:  "\u0626",
+	0xFE8D:  "\u0627",
+	0xFE8E:  "\u0627",
+	0xFE8F:  "\u0628",
+	0xFE90:  "\u0628",
+	0xFE91:  "\u0628",
+	0xFE92:  "\u0628",
+	0xFE93:  "\u0629",
+	0xFE94:  "\u0629",
+	0xFE95:  "\u062a",
+	0xFE96:  "\u062a",
+	0xFE97:  "\u062a",
+	0xFE98:  "\u062a",
+	0xFE99:  "\u062b",
+	0xFE9A:  "\u062b",
+	0xFE9B:  "\u062b",
+	0xFE9C:  "\u062b",
+	0xFE9D:  "\u062c",
+	0xFE9E:  "\u062c",
+	0xFE9F:  "\u062c",
+	0xFEA0:  "\u062c",
+	0xFEA1:  "\u062d",
+	0xFEA2:  "\u062d",
+	0xFEA3:  "\u062d",
+	0xFEA4:  "\u062d",
+	0xFEA5:  "\u062e",
+	0xFEA6:  "\u062e",
+	0xFEA7:  "\u062e",
+	0xFEA8:  "\u062e",
+	0xFEA9:  "\u062f",
+	0xFEAA:  "\u062f",
+	0xFEAB:  "\u0630",
+	0xFEAC:  "\u0630",
+	0xFEAD:  "\u0631",
+	0xFEAE:  "\u0631",
+	0xFEAF:  "\u0632",
+	0xFEB0:  "\u0632",
+	0xFEB1:  "\u0633",
+	0xFEB2:  "\u0633",
+	0xFEB3:  "\u0633",
+	0xFEB4:  "\u0633",
+	0xFEB5:  "\u0634",
+	0xFEB6:  "\u0634",
+	0xFEB7:  "\u0634",
+	0xFEB8:  "\u0634",
+	0xFEB9:  "\u0635",
+	0xFEBA:  "\u0635",
+	0xFEBB:  "\u0635",
+	0xFEBC:  "\u0635",
+	0xFEBD:  "\u0636",
+	0xFEBE:  "\u0636",
+	0xFEBF:  "\u0636",
+	0xFEC0:  "\u0636",
+	0xFEC1:  "\u0637",
+	0xFEC2:  "\u0637",
+	0xFEC3:  "\u0637",
+	0xFEC4:  "\u0637",
+	0xFEC5:  "\u0638",
+	0xFEC6:  "\u0638",
+	0xFEC7:  "\u0638",
+	0xFEC8:  "\u0638",
+	0xFEC9:  "\u0639",
+	0xFECA:  "\u0639",
+	0xFECB:  "\u0639",
+	0xFECC:  "\u0639",
+	0xFECD:  "\u063a",
+	0xFECE:  "\u063a",
+	0xFECF:  "\u063a",
+	0xFED0:  "\u063a",
+	0xFED1:  "\u0641",
+	0xFED2:  "\u0641",
+	0xFED3:  "\u0641",
+	0xFED4:  "\u0641",
+	0xFED5:  "\u0642",
+	0xFED6:  "\u0642",
+	0xFED7:  "\u0642",
+	0xFED8:  "\u0642",
+	0xFED9:  "\u0643",
+	0xFEDA:  "\u0643",
+	0xFEDB:  "\u0643",
+	0xFEDC:  "\u0643",
+	0xFEDD:  "\u0644",
+	0xFEDE:  "\u0644",
+	0xFEDF:  "\u0644",
+	0xFEE0:  "\u0644",
+	0xFEE1:  "\u0645",
+	0xFEE2:  "\u0645",
+	0xFEE3:  "\u0645",
+	0xFEE4:  "\u0645",
+	0xFEE5:  "\u0646",
+	0xFEE6:  "\u0646",
+	0xFEE7:  "\u0646",
+	0xFEE8:  "\u0646",
+	0xFEE9:  "\u0647",
+	0xFEEA:  "\u0647",
+	0xFEEB:  "\u0647",
+	0xFEEC:  "\u0647",
+	0xFEED:  "\u0648",
+	0xFEEE:  "\u0648",
+	0xFEEF:  "\u0649",
+	0xFEF0:  "\u0649",
+	0xFEF1:  "\u064a",
+	0xFEF2:  "\u064a",
+	0xFEF3:  "\u064a",
+	0xFEF4:  "\u064a",
+	0xFEF5:  "\u0644\u0622",
+	0xFEF6:  "\u0644\u0622",
+	0xFEF7:  "\u0644\u0623",
+	0xFEF8:  "\u0644\u0623",
+	0xFEF9:  "\u0644\u0625",
+	0xFEFA:  "\u0644\u0625",
+	0xFEFB:  "\u0644\u0627",
+	0xFEFC:  "\u0644\u0627",
+	0xFF01:  "\u0021",
+	0xFF02:  "\u0022",
+	0xFF03:  "\u0023",
+	0xFF04:  "\u0024",
+	0xFF05:  "\u0025",
+	0xFF06:  "\u0026",
+	0xFF07:  "\u0027",
+	0xFF08:  "\u0028",
+	0xFF09:  "\u0029",
+	0xFF0A:  "\u002a",
+	0xFF0B:  "\u002b",
+	0xFF0C:  "\u002c",
+	0xFF0D:  "\u002d",
+	0xFF0E:  "\u002e",
+	0xFF0F:  "\u002f",
+	0xFF10:  "\u0030",
+	0xFF11:  "\u0031",
+	0xFF12:  "\u0032",
+	0xFF13:  "\u0033",
+	0xFF14:  "\u0034",
+	0xFF15:  "\u0035",
+	0xFF16:  "\u0036",
+	0xFF17:  "\u0037",
+	0xFF18:  "\u0038",
+	0xFF19:  "\u0039",
+	0xFF1A:  "\u003a",
+	0xFF1B:  "\u003b",
+	0xFF1C:  "\u003c",
+	0xFF1D:  "\u003d",
+	0xFF1E:  "\u003e",
+	0xFF1F:  "\u003f",
+	0xFF20:  "\u0040",
+	0xFF21:  "\u0041",
+	0xFF22:  "\u0042",
+	0xFF23:  "\u0043",
+	0xFF24:  "\u0044",
+	0xFF25:  "\u0045",
+	0xFF26:  "\u0046",
+	0xFF27:  "\u0047",
+	0xFF28:  "\u0048",
+	0xFF29:  "\u0049",
+	0xFF2A:  "\u004a",
+	0xFF2B:  "\u004b",
+	0xFF2C:  "\u004c",
+	0xFF2D:  "\u004d",
+	0xFF2E:  "\u004e",
+	0xFF2F:  "\u004f",
+	0xFF30:  "\u0050",
+	0xFF31:  "\u0051",
+	0xFF32:  "\u0052",
+	0xFF33:  "\u0053",
+	0xFF34:  "\u0054",
+	0xFF35:  "\u0055",
+	0xFF36:  "\u0056",
+	0xFF37:  "\u0057",
+	0xFF38:  "\u0058",
+	0xFF39:  "\u0059",
+	0xFF3A:  "\u005a",
+	0xFF3B:  "\u005b",
+	0xFF3C:  "\u005c",
+	0xFF3D:  "\u005d",
+	0xFF3E:  "\u005e",
+	0xFF3F:  "\u005f",
+	0xFF40:  "\u0060",
+	0xFF41:  "\u0061",
+	0xFF42:  "\u0062",
+	0xFF43:  "\u0063",
+	0xFF44:  "\u0064",
+	0xFF45:  "\u0065",
+	0xFF46:  "\u0066",
+	0xFF47:  "\u0067",
+	0xFF48:  "\u0068",
+	0xFF49:  "\u0069",
+	0xFF4A:  "\u006a",
+	0xFF4B:  "\u006b",
+	0xFF4C:  "\u006c",
+	0xFF4D:  "\u006d",
+	0xFF4E:  "\u006e",
+	0xFF4F:  "\u006f",
+	0xFF50:  "\u0070",
+	0xFF51:  "\u0071",
+	0xFF52:  "\u0072",
+	0xFF53:  "\u0073",
+	0xFF54:  "\u0074",
+	0xFF55:  "\u0075",
+	0xFF56:  "\u0076",
+	0xFF57:  "\u0077",
+	0xFF58:  "\u0078",
+	0xFF59:  "\u0079",
+	0xFF5A:  "\u007a",
+	0xFF5B:  "\u007b",
+	0xFF5C:  "\u007c",
+	0xFF5D:  "\u007d",
+	0xFF5E:  "\u007e",
+	0xFF5F:  "\u2985",
+	0xFF60:  "\u2986",
+	0xFF61:  "\u3002",
+	0xFF62:  "\u300c",
+	0xFF63:  "\u300d",
+	0xFF64:  "\u3001",
+	0xFF65:  "\u30fb",
+	0xFF66:  "\u30f2",
+	0xFF67:  "\u30a1",
+	0xFF68:  "\u30a3",
+	0xFF69:  "\u30a5",
+	0xFF6A:  "\u30a7",
+	0xFF6B:  "\u30a9",
+	0xFF6C:  "\u30e3",
+	0xFF6D:  "\u30e5",
+	0xFF6E:  "\u30e7",
+	0xFF6F:  "\u30c3",
+	0xFF70:  "\u30fc",
+	0xFF71:  "\u30a2",
+	0xFF72:  "\u30a4",
+	0xFF73:  "\u30a6",
+	0xFF74:  "\u30a8",
+	0xFF75:  "\u30aa",
+	0xFF76:  "\u30ab",
+	0xFF77:  "\u30ad",
+	0xFF78:  "\u30af",
+	0xFF79:  "\u30b1",
+	0xFF7A:  "\u30b3",
+	0xFF7B:  "\u30b5",
+	0xFF7C:  "\u30b7",
+	0xFF7D:  "\u30b9",
+	0xFF7E:  "\u30bb",
+	0xFF7F:  "\u30bd",
+	0xFF80:  "\u30bf",
+	0xFF81:  "\u30c1",
+	0xFF82:  "\u30c4",
+	0xFF83:  "\u30c6",
+	0xFF84:  "\u30c8",
+	0xFF85:  "\u30ca",
+	0xFF86:  "\u30cb",
+	0xFF87:  "\u30cc",
+	0xFF88:  "\u30cd",
+	0xFF89:  "\u30ce",
+	0xFF8A:  "\u30cf",
+	0xFF8B:  "\u30d2",
+	0xFF8C:  "\u30d5",
+	0xFF8D:  "\u30d8",
+	0xFF8E:  "\u30db",
+	0xFF8F:  "\u30de",
+	0xFF90:  "\u30df",
+	0xFF91:  "\u30e0",
+	0xFF92:  "\u30e1",
+	0xFF93:  "\u30e2",
+	0xFF94:  "\u30e4",
+	0xFF95:  "\u30e6",
+	0xFF96:  "\u30e8",
+	0xFF97:  "\u30e9",
+	0xFF98:  "\u30ea",
+	0xFF99:  "\u30eb",
+	0xFF9A:  "\u30ec",
+	0xFF9B:  "\u30ed",
+	0xFF9C:  "\u30ef",
+	0xFF9D:  "\u30f3",
+	0xFF9E:  "\u3099",
+	0xFF9F:  "\u309a",
+	0xFFA0:  "\u3164",
+	0xFFA1:  "\u3131",
+	0xFFA2:  "\u3132",
+	0xFFA3:  "\u3133",
+	0xFFA4:  "\u3134",
+	0xFFA5:  "\u3135",
+	0xFFA6:  "\u3136",
+	0xFFA7:  "\u3137",
+	0xFFA8:  "\u3138",
+	0xFFA9:  "\u3139",
+	0xFFAA:  "\u313a",
+	0xFFAB:  "\u313b",
+	0xFFAC:  "\u313c",
+	0xFFAD:  "\u313d",
+	0xFFAE:  "\u313e",
+	0xFFAF:  "\u313f",
+	0xFFB0:  "\u3140",
+	0xFFB1:  "\u3141",
+	0xFFB2:  "\u3142",
+	0xFFB3:  "\u3143",
+	0xFFB4:  "\u3144",
+	0xFFB5:  "\u3145",
+	0xFFB6:  "\u3146",
+	0xFFB7:  "\u3147",
+	0xFFB8:  "\u3148",
+	0xFFB9:  "\u3149",
+	0xFFBA:  "\u314a",
+	0xFFBB:  "\u314b",
+	0xFFBC:  "\u314c",
+	0xFFBD:  "\u314d",
+	0xFFBE:  "\u314e",
+	0xFFC2:  "\u314f",
+	0xFFC3:  "\u3150",
+	0xFFC4:  "\u3151",
+	0xFFC5:  "\u3152",
+	0xFFC6:  "\u3153",
+	0xFFC7:  "\u3154",
+	0xFFCA:  "\u3155",
+	0xFFCB:  "\u3156",
+	0xFFCC:  "\u3157",
+	0xFFCD:  "\u3158",
+	0xFFCE:  "\u3159",
+	0xFFCF:  "\u315a",
+	0xFFD2:  "\u315b",
+	0xFFD3:  "\u315c",
+	0xFFD4:  "\u315d",
+	0xFFD5:  "\u315e",
+	0xFFD6:  "\u315f",
+	0xFFD7:  "\u3160",
+	0xFFDA:  "\u3161",
+	0xFFDB:  "\u3162",
+	0xFFDC:  "\u3163",
+	0xFFE0:  "\u00a2",
+	0xFFE1:  "\u00a3",
+	0xFFE2:  "\u00ac",
+	0xFFE3:  "\u00af",
+	0xFFE4:  "\u00a6",
+	0xFFE5:  "\u00a5",
+	0xFFE6:  "\u20a9",
+	0xFFE8:  "\u2502",
+	0xFFE9:  "\u2190",
+	0xFFEA:  "\u2191",
+	0xFFEB:  "\u2192",
+	0xFFEC:  "\u2193",
+	0xFFED:  "\u25a0",
+	0xFFEE:  "\u25cb",
+	0x10781: "\u02d0",
+	0x10782: "\u02d1",
+	0x10783: "\u00e6",
+	0x10784: "\u0299",
+	0x10785: "\u0253",
+	0x10787: "\u02a3",
+	0x10788: "\uab66",
+	0x10789: "\u02a5",
+	0x1078A: "\u02a4",
+	0x1078B: "\u0256",
+	0x1078C: "\u0257",
+	0x1078D: "\u1d91",
+	0x1078E: "\u0258",
+	0x1078F: "\u025e",
+	0x10790: "\u02a9",
+	0x10791: "\u0264",
+	0x10792: "\u0262",
+	0x10793: "\u0260",
+	0x10794: "\u029b",
+	0x10795: "\u0127",
+	0x10796: "\u029c",
+	0x10797: "\u0267",
+	0x10798: "\u0284",
+	0x10799: "\u02aa",
+	0x1079A: "\u02ab",
+	0x1079B: "\u026c",
+	0x1079C: "\U0001df04",
+	0x1079D: "\ua78e",
+	0x1079E: "\u026e",
+	0x1079F: "\U0001df05",
+	0x107A0: "\u028e",
+	0x107A1: "\U0001df06",
+	0x107A2: "\u00f8",
+	0x107A3: "\u0276",
+	0x107A4: "\u0277",
+	0x107A5: "\u0071",
+	0x107A6: "\u027a",
+	0x107A7: "\U0001df08",
+	0x107A8: "\u027d",
+	0x107A9: "\u027e",
+	0x107AA: "\u0280",
+	0x107AB: "\u02a8",
+	0x107AC: "\u02a6",
+	0x107AD: "\uab67",
+	0x107AE: "\u02a7",
+	0x107AF: "\u0288",
+	0x107B0: "\u2c71",
+	0x107B2: "\u028f",
+	0x107B3: "\u02a1",
+	0x107B4: "\u02a2",
+	0x107B5: "\u0298",
+	0x107B6: "\u01c0",
+	0x107B7: "\u01c1",
+	0x107B8: "\u01c2",
+	0x107B9: "\U0001df0a",
+	0x107BA: "\U0001df1e",
+	0x1D400: "\u0041",
+	0x1D401: "\u0042",
+	0x1D402: "\u0043",
+	0x1D403: "\u0044",
+	0x1D404: "\u0045",
+	0x1D405: "\u0046",
+	0x1D406: "\u0047",
+	0x1D407: "\u0048",
+	0x1D408: "\u0049",
+	0x1D409: "\u004a",
+	0x1D40A: "\u004b",
+	0x1D40B: "\u004c",
+	0x1D40C: "\u004d",
+	0x1D40D: "\u004e",
+	0x1D40E: "\u004f",
+	0x1D40F: "\u0050",
+	0x1D410: "\u0051",
+	0x1D411: "\u0052",
+	0x1D412: "\u0053",
+	0x1D413: "\u0054",
+	0x1D414: "\u0055",
+	0x1D415: "\u0056",
+	0x1D416: "\u0057",
+	0x1D417: "\u0058",
+	0x1D418: "\u0059",
+	0x1D419: "\u005a",
+	0x1D41A: "\u0061",
+	0x1D41B: "\u0062",
+	0x1D41C: "\u0063",
+	0x1D41D: "\u0064",
+	0x1D41E: "\u0065",
+	0x1D41F: "\u0066",
+	0x1D420: "\u0067",
+	0x1D421: "\u0068",
+	0x1D422: "\u0069",
+	0x1D423: "\u006a",
+	0x1D424: "\u006b",
+	0x1D425: "\u006c",
+	0x1D426: "\u006d",
+	0x1D427: "\u006e",
+	0x1D428: "\u006f",
+	0x1D429: "\u0070",
+	0x1D42A: "\u0071",
+	0x1D42B: "\u0072",
+	0x1D42C: "\u0073",
+	0x1D42D: "\u0074",
+	0x1D42E: "\u0075",
+	0x1D42F: "\u0076",
+	0x1D430: "\u0077",
+	0x1D431: "\u0078",
+	0x1D432: "\u0079",
+	0x1D433: "\u007a",
+	0x1D434: "\u0041",
+	0x1D435: "\u0042",
+	0x1D436: "\u0043",
+	0x1D437: "\u0044",
+	0x1D438: "\u0045",
+	0x1D439: "\u0046",
+	0x1D43A: "\u0047",
+	0x1D43B: "\u0048",
+	0x1D43C: "\u0049",
+	0x1D43D: "\u004a",
+	0x1D43E: "\u004b",
+	0x1D43F: "\u004c",
+	0x1D440: "\u004d",
+	0x1D441: "\u004e",
+	0x1D442: "\u004f",
+	0x1D443: "\u0050",
+	0x1D444: "\u0051",
+	0x1D445: "\u0052",
+	0x1D446: "\u0053",
+	0x1D447: "\u0054",
+	0x1D448: "\u0055",
+	0x1D449: "\u0056",
+	0x1D44A: "\u0057",
+	0x1D44B: "\u0058",
+	0x1D44C: "\u0059",
+	0x1D44D: "\u005a",
+	0x1D44E: "\u0061",
+	0x1D44F: "\u0062",
+	0x1D450: "\u0063",
+	0x1D451: "\u0064",
+	0x1D452: "\u0065",
+	0x1D453: "\u0066",
+	0x1D454: "\u0067",
+	0x1D456: "\u0069",
+	0x1D457: "\u006a",
+	0x1D458: "\u006b",
+	0x1D459: "\u006c",
+	0x1D45A: "\u006d",
+	0x1D45B: "\u006e",
+	0x1D45C: "\u006f",
+	0x1D45D: "\u0070",
+	0x1D45E: "\u0071",
+	0x1D45F: "\u0072",
+	0x1D460: "\u0073",
+	0x1D461: "\u0074",
+	0x1D462: "\u0075",
+	0x1D463: "\u0076",
+	0x1D464: "\u0077",
+	0x1D465: "\u0078",
+	0x1D466: "\u0079",
+	0x1D467: "\u007a",
+	0x1D468: "\u0041",
+	0x1D469: "\u0042",
+	0x1D46A: "\u0043",
+	0x1D46B: "\u0044",
+	0x1D46C: "\u0045",
+	0x1D46D: "\u0046",
+	0x1D46E: "\u0047",
+	0x1D46F: "\u0048",
+	0x1D470: "\u0049",
+	0x1D471: "\u004a",
+	0x1D472: "\u004b",
+	0x1D473: "\u004c",
+	0x1D474: "\u004d",
+	0x1D475: "\u004e",
+	0x1D476: "\u004f",
+	0x1D477: "\u0050",
+	0x1D478: "\u0051",
+	0x1D479: "\u0052",
+	0x1D47A: "\u0053",
+	0x1D47B: "\u0054",
+	0x1D47C: "\u0055",
+	0x1D47D: "\u0056",
+	0x1D47E: "\u0057",
+	0x1D47F: "\u0058",
+	0x1D480: "\u0059",
+	0x1D481: "\u005a",
+	0x1D482: "\u0061",
+	0x1D483: "\u0062",
+	0x1D484: "\u0063",
+	0x1D485: "\u0064",
+	0x1D486: "\u0065",
+	0x1D487: "\u0066",
+	0x1D488: "\u0067",
+	0x1D489: "\u0068",
+	0x1D48A: "\u0069",
+	0x1D48B: "\u006a",
+	0x1D48C: "\u006b",
+	0x1D48D: "\u006c",
+	0x1D48E: "\u006d",
+	0x1D48F: "\u006e",
+	0x1D490: "\u006f",
+	0x1D491: "\u0070",
+	0x1D492: "\u0071",
+	0x1D493: "\u0072",
+	0x1D494: "\u0073",
+	0x1D495: "\u0074",
+	0x1D496: "\u0075",
+	0x1D497: "\u0076",
+	0x1D498: "\u0077",
+	0x1D499: "\u0078",
+	0x1D49A: "\u0079",
+	0x1D49B: "\u007a",
+	0x1D49C: "\u0041",
+	0x1D49E: "\u0043",
+	0x1D49F: "\u0044",
+	0x1D4A2: "\u0047",
+	0x1D4A5: "\u004a",
+	0x1D4A6: "\u004b",
+	0x1D4A9: "\u004e",
+	0x1D4AA: "\u004f",
+	0x1D4AB: "\u0050",
+	0x1D4AC: "\u0051",
+	0x1D4AE: "\u0053",
+	0x1D4AF: "\u0054",
+	0x1D4B0: "\u0055",
+	0x1D4B1: "\u0056",
+	0x1D4B2: "\u0057",
+	0x1D4B3: "\u0058",
+	0x1D4B4: "\u0059",
+	0x1D4B5: "\u005a",
+	0x1D4B6: "\u0061",
+	0x1D4B7: "\u0062",
+	0x1D4B8: "\u0063",
+	0x1D4B9: "\u0064",
+	0x1D4BB: "\u0066",
+	0x1D4BD: "\u0068",
+	0x1D4BE: "\u0069",
+	0x1D4BF: "\u006a",
+	0x1D4C0: "\u006b",
+	0x1D4C1: "\u006c",
+	0x1D4C2: "\u006d",
+	0x1D4C3: "\u006e",
+	0x1D4C5: "\u0070",
+	0x1D4C6: "\u0071",
+	0x1D4C7: "\u0072",
+	0x1D4C8: "\u0073",
+	0x1D4C9: "\u0074",
+	0x1D4CA: "\u0075",
+	0x1D4CB: "\u0076",
+	0x1D4CC: "\u0077",
+	0x1D4CD: "\u0078",
+	0x1D4CE: "\u0079",
+	0x1D4CF: "\u007a",
+	0x1D4D0: "\u0041",
+	0x1D4D1: "\u0042",
+	0x1D4D2: "\u0043",
+	0x1D4D3: "\u0044",
+	0x1D4D4: "\u0045",
+	0x1D4D5: "\u0046",
+	0x1D4D6: "\u0047",
+	0x1D4D7: "\u0048",
+	0x1D4D8: "\u0049",
+	0x1D4D9: "\u004a",
+	0x1D4DA: "\u004b",
+	0x1D4DB: "\u004c",
+	0x1D4DC: "\u004d",
+	0x1D4DD: "\u004e",
+	0x1D4DE: "\u004f",
+	0x1D4DF: "\u0050",
+	0x1D4E0: "\u0051",
+	0x1D4E1: "\u0052",
+	0x1D4E2: "\u0053",
+	0x1D4E3: "\u0054",
+	0x1D4E4: "\u0055",
+	0x1D4E5: "\u0056",
+	0x1D4E6: "\u0057",
+	0x1D4E7: "\u0058",
+	0x1D4E8: "\u0059",
+	0x1D4E9: "\u005a",
+	0x1D4EA: "\u0061",
+	0x1D4EB: "\u0062",
+	0x1D4EC: "\u0063",
+	0x1D4ED: "\u0064",
+	0x1D4EE: "\u0065",
+	0x1D4EF: "\u0066",
+	0x1D4F0: "\u0067",
+	0x1D4F1: "\u0068",
+	0x1D4F2: "\u0069",
+	0x1D4F3: "\u006a",
+	0x1D4F4: "\u006b",
+	0x1D4F5: "\u006c",
+	0x1D4F6: "\u006d",
+	0x1D4F7: "\u006e",
+	0x1D4F8: "\u006f",
+	0x1D4F9: "\u0070",
+	0x1D4FA: "\u0071",
+	0x1D4FB: "\u0072",
+	0x1D4FC: "\u0073",
+	0x1D4FD: "\u0074",
+	0x1D4FE: "\u0075",
+	0x1D4FF: "\u0076",
+	0x1D500: "\u0077",
+	0x1D501: "\u0078",
+	0x1D502: "\u0079",
+	0x1D503: "\u007a",
+	0x1D504: "\u0041",
+	0x1D505: "\u0042",
+	0x1D507: "\u0044",
+	0x1D508: "\u0045",
+	0x1D509: "\u0046",
+	0x1D50A: "\u0047",
+	0x1D50D: "\u004a",
+	0x1D50E: "\u004b",
+	0x1D50F: "\u004c",
+	0x1D510: "\u004d",
+	0x1D511: "\u004e",
+	0x1D512: "\u004f",
+	0x1D513: "\u0050",
+	0x1D514: "\u0051",
+	0x1D516: "\u0053",
+	0x1D517: "\u0054",
+	0x1D518: "\u0055",
+	0x1D519: "\u0056",
+	0x1D51A: "\u0057",
+	0x1D51B: "\u0058",
+	0x1D51C: "\u0059",
+	0x1D51E: "\u0061",
+	0x1D51F: "\u0062",
+	0x1D520: "\u0063",
+	0x1D521: "\u0064",
+	0x1D522: "\u0065",
+	0x1D523: "\u0066",
+	0x1D524: "\u0067",
+	0x1D525: "\u0068",
+	0x1D526: "\u0069",
+	0x1D527: "\u006a",
+	0x1D528: "\u006b",
+	0x1D529: "\u006c",
+	0x1D52A: "\u006d",
+	0x1D52B: "\u006e",
+	0x1D52C: "\u006f",
+	0x1D52D: "\u0070",
+	0x1D52E: "\u0071",
+	0x1D52F: "\u0072",
+	0x1D530: "\u0073",
+	0x1D531: "\u0074",
+	0x1D532: "\u0075",
+	0x1D533: "\u0076",
+	0x1D534: "\u0077",
+	0x1D535: "\u0078",
+	0x1D536: "\u0079",
+	0x1D537: "\u007a",
+	0x1D538: "\u0041",
+	0x1D539: "\u0042",
+	0x1D53B: "\u0044",
+	0x1D53C: "\u0045",
+	0x1D53D: "\u0046",
+	0x1D53E: "\u0047",
+	0x1D540: "\u0049",
+	0x1D541: "\u004a",
+	0x1D542: "\u004b",
+	0x1D543: "\u004c",
+	0x1D544: "\u004d",
+	0x1D546: "\u004f",
+	0x1D54A: "\u0053",
+	0x1D54B: "\u0054",
+	0x1D54C: "\u0055",
+	0x1D54D: "\u0056",
+	0x1D54E: "\u0057",
+	0x1D54F: "\u0058",
+	0x1D550: "\u0059",
+	0x1D552: "\u0061",
+	0x1D553: "\u0062",
+	0x1D554: "\u0063",
+	0x1D555: "\u0064",
+	0x1D556: "\u0065",
+	0x1D557: "\u0066",
+	0x1D558: "\u0067",
+	0x1D559: "\u0068",
+	0x1D55A: "\u0069",
+	0x1D55B: "\u006a",
+	0x1D55C: "\u006b",
+	0x1D55D: "\u006c",
+	0x1D55E: "\u006d",
+	0x1D55F: "\u006e",
+	0x1D560: "\u006f",
+	0x1D561: "\u0070",
+	0x1D562: "\u0071",
+	0x1D563: "\u0072",
+	0x1D564: "\u0073",
+	0x1D565: "\u0074",
+	0x1D566: "\u0075",
+	0x1D567: "\u0076",
+	0x1D568: "\u0077",
+	0x1D569: "\u0078",
+	0x1D56A: "\u0079",
+	0x1D56B: "\u007a",
+	0x1D56C: "\u0041",
+	0x1D56D: "\u0042",
+	0x1D56E: "\u0043",
+	0x1D56F: "\u0044",
+	0x1D570: "\u0045",
+	0x1D571: "\u0046",
+	0x1D572: "\u0047",
+	0x1D573: "\u0048",
+	0x1D574: "\u0049",
+	0x1D575: "\u004a",
+	0x1D576: "\u004b",
+	0x1D577: "\u004c",
+	0x1D578: "\u004d",
+	0x1D579: "\u004e",
+	0x1D57A: "\u004f",
+	0x1D57B: "\u0050",
+	0x1D57C: "\u0051",
+	0x1D57D: "\u0052",
+	0x1D57E: "\u0053",
+	0x1D57F: "\u0054",
+	0x1D580: "\u0055",
+	0x1D581: "\u0056",
+	0x1D582: "\u0057",
+	0x1D583: "\u0058",
+	0x1D584: "\u0059",
+	0x1D585: "\u005a",
+	0x1D586: "\u0061",
+	0x1D587: "\u0062",
+	0x1D588: "\u0063",
+	0x1D589: "\u0064",
+	0x1D58A: "\u0065",
+	0x1D58B: "\u0066",
+	0x1D58C: "\u0067",
+	0x1D58D: "\u0068",
+	0x1D58E: "\u0069",
+	0x1D58F: "\u006a",
+	0x1D590: "\u006b",
+	0x1D591: "\u006c",
+	0x1D592: "\u006d",
+	0x1D593: "\u006e",
+	0x1D594: "\u006f",
+	0x1D595: "\u0070",
+	0x1D596: "\u0071",
+	0x1D597: "\u0072",
+	0x1D598: "\u0073",
+	0x1D599: "\u0074",
+	0x1D59A: "\u0075",
+	0x1D59B: "\u0076",
+	0x1D59C: "\u0077",
+	0x1D59D: "\u0078",
+	0x1D59E: "\u0079",
+	0x1D59F: "\u007a",
+	0x1D5A0: "\u0041",
+	0x1D5A1: "\u0042",
+	0x1D5A2: "\u0043",
+	0x1D5A3: "\u0044",
+	0x1D5A4: "\u0045",
+	0x1D5A5: "\u0046",
+	0x1D5A6: "\u0047",
+	0x1D5A7: "\u0048",
+	0x1D5A8: "\u0049",
+	0x1D5A9: "\u004a",
+	0x1D5AA: "\u004b",
+	0x1D5AB: "\u004c",
+	0x1D5AC: "\u004d",
+	0x1D5AD: "\u004e",
+	0x1D5AE: "\u004f",
+	0x1D5AF: "\u0050",
+	0x1D5B0: "\u0051",
+	0x1D5B1: "\u0052",
+	0x1D5B2: "\u0053",
+	0x1D5B3: "\u0054",
+	0x1D5B4: "\u0055",
+	0x1D5B5: "\u0056",
+	0x1D5B6: "\u0057",
+	0x1D5B7: "\u0058",
+	0x1D5B8: "\u0059",
+	0x1D5B9: "\u005a",
+	0x1D5BA: "\u0061",
+	0x1D5BB: "\u0062",
+	0x1D5BC: "\u0063",
+	0x1D5BD: "\u0064",
+	0x1D5BE: "\u0065",
+	0x1D5BF: "\u0066",
+	0x1D5C0: "\u0067",
+	0x1D5C1: "\u0068",
+	0x1D5C2: "\u0069",
+	0x1D5C3: "\u006a",
+	0x1D5C4: "\u006b",
+	0x1D5C5: "\u006c",
+	0x1D5C6: "\u006d",
+	0x1D5C7: "\u006e",
+	0x1D5C8: "\u006f",
+	0x1D5C9: "\u0070",
+	0x1D5CA: "\u0071",
+	0x1D5CB: "\u0072",
+	0x1D5CC: "\u0073",
+	0x1D5CD: "\u0074",
+	0x1D5CE: "\u0075",
+	0x1D5CF: "\u0076",
+	0x1D5D0: "\u0077",
+	0x1D5D1: "\u0078",
+	0x1D5D2: "\u0079",
+	0x1D5D3: "\u007a",
+	0x1D5D4: "\u0041",
+	0x1D5D5: "\u0042",
+	0x1D5D6: "\u0043",
+	0x1D5D7: "\u0044",
+	0x1D5D8: "\u0045",
+	0x1D5D9: "\u0046",
+	0x1D5DA: "\u0047",
+	0x1D5DB: "\u0048",
+	0x1D5DC: "\u0049",
+	0x1D5DD: "\u004a",
+	0x1D5DE: "\u004b",
+	0x1D5DF: "\u004c",
+	0x1D5E0: "\u004d",
+	0x1D5E1: "\u004e",
+	0x1D5E2: "\u004f",
+	0x1D5E3: "\u0050",
+	0x1D5E4: "\u0051",
+	0x1D5E5: "\u0052",
+	0x1D5E6: "\u0053",
+	0x1D5E7: "\u0054",
+	0x1D5E8: "\u0055",
+	0x1D5E9: "\u0056",
+	0x1D5EA: "\u0057",
+	0x1D5EB: "\u0058",
+	0x1D5EC: "\u0059",
+	0x1D5ED: "\u005a",
+	0x1D5EE: "\u0061",
+	0x1D5EF: "\u0062",
+	0x1D5F0: "\u0063",
+	0x1D5F1: "\u0064",
+	0x1D5F2: "\u0065",
+	0x1D5F3: "\u0066",
+	0x1D5F4: "\u0067",
+	0x1D5F5: "\u0068",
+	0x1D5F6: "\u0069",
+	0x1D5F7: "\u006a",
+	0x1D5F8: "\u006b",
+	0x1D5F9: "\u006c",
+	0x1D5FA: "\u006d",
+	0x1D5FB: "\u006e",
+	0x1D5FC: "\u006f",
+	0x1D5FD: "\u0070",
+	0x1D5FE: "\u0071",
+	0x1D5FF: "\u0072",
+	0x1D600: "\u0073",
+	0x1D601: "\u0074",
+	0x1D602: "\u0075",
+	0x1D603: "\u0076",
+	0x1D604: "\u0077",
+	0x1D605: "\u0078",
+	0x1D606: "\u0079",
+	0x1D607: "\u007a",
+	0x1D608: "\u0041",
+	0x1D609: "\u0042",
+	0x1D60A: "\u0043",
+	0x1D60B: "\u0044",
+	0x1D60C: "\u0045",
+	0x1D60D: "\u0046",
+	0x1D60E: "\u0047",
+	0x1D60F: "\u0048",
+	0x1D610: "\u0049",
+	0x1D611: "\u004a",
+	0x1D612: "\u004b",
+	0x1D613: "\u004c",
+	0x1D614: "\u004d",
+	0x1D615: "\u004e",
+	0x1D616: "\u004f",
+	0x1D617: "\u0050",
+	0x1D618: "\u0051",
+	0x1D619: "\u0052",
+	0x1D61A: "\u0053",
+	0x1D61B: "\u0054",
+	0x1D61C: "\u0055",
+	0x1D61D: "\u0056",
+	0x1D61E: "\u0057",
+	0x1D61F: "\u0058",
+	0x1D620: "\u0059",
+	0x1D621: "\u005a",
+	0x1D622: "\u0061",
+	0x1D623: "\u0062",
+	0x1D624: "\u0063",
+	0x1D625: "\u0064",
+	0x1D626: "\u0065",
+	0x1D627: "\u0066",
+	0x1D628: "\u0067",
+	0x1D629: "\u0068",
+	0x1D62A: "\u0069",
+	0x1D62B: "\u006a",
+	0x1D62C: "\u006b",
+	0x1D62D: "\u006c",
+	0x1D62E: "\u006d",
+	0x1D62F: "\u006e",
+	0x1D630: "\u006f",
+	0x1D631: "\u0070",
+	0x1D632: "\u0071",
+	0x1D633: "\u0072",
+	0x1D634: "\u0073",
+	0x1D635: "\u0074",
+	0x1D636: "\u0075",
+	0x1D637: "\u0076",
+	0x1D638: "\u0077",
+	0x1D639: "\u0078",
+	0x1D63A: "\u0079",
+	0x1D63B: "\u007a",
+	0x1D63C: "\u0041",
+	0x1D63D: "\u0042",
+	0x1D63E: "\u0043",
+	0x1D63F: "\u0044",
+	0x1D640: "\u0045",
+	0x1D641: "\u0046",
+	0x1D642: "\u0047",
+	0x1D643: "\u0048",
+	0x1D644: "\u0049",
+	0x1D645: "\u004a",
+	0x1D646: "\u004b",
+	0x1D647: "\u004c",
+	0x1D648: "\u004d",
+	0x1D649: "\u004e",
+	0x1D64A: "\u004f",
+	0x1D64B: "\u0050",
+	0x1D64C: "\u0051",
+	0x1D64D: "\u0052",
+	0x1D64E: "\u0053",
+	0x1D64F: "\u0054",
+	0x1D650: "\u0055",
+	0x1D651: "\u0056",
+	0x1D652: "\u0057",
+	0x1D653: "\u0058",
+	0x1D654: "\u0059",
+	0x1D655: "\u005a",
+	0x1D656: "\u0061",
+	0x1D657: "\u0062",
+	0x1D658: "\u0063",
+	0x1D659: "\u0064",
+	0x1D65A: "\u0065",
+	0x1D65B: "\u0066",
+	0x1D65C: "\u0067",
+	0x1D65D: "\u0068",
+	0x1D65E: "\u0069",
+	0x1D65F: "\u006a",
+	0x1D660: "\u006b",
+	0x1D661: "\u006c",
+	0x1D662: "\u006d",
+	0x1D663: "\u006e",
+	0x1D664: "\u006f",
+	0x1D665: "\u0070",
+	0x1D666: "\u0071",
+	0x1D667: "\u0072",
+	0x1D668: "\u0073",
+	0x1D669: "\u0074",
+	0x1D66A: "\u0075",
+	0x1D66B: "\u0076",
+	0x1D66C: "\u0077",
+	0x1D66D: "\u0078",
+	0x1D66E: "\u0079",
+	0x1D66F: "\u007a",
+	0x1D670: "\u0041",
+	0x1D671: "\u0042",
+	0x1D672: "\u0043",
+	0x1D673: "\u0044",
+	0x1D674: "\u0045",
+	0x1D675: "\u0046",
+	0x1D676: "\u0047",
+	0x1D677: "\u0048",
+	0x1D678: "\u0049",
+	0x1D679: "\u004a",
+	0x1D67A: "\u004b",
+	0x1D67B: "\u004c",
+	0x1D67C: "\u004d",
+	0x1D67D: "\u004e",
+	0x1D67E: "\u004f",
+	0x1D67F: "\u0050",
+	0x1D680: "\u0051",
+	0x1D681: "\u0052",
+	0x1D682: "\u0053",
+	0x1D683: "\u0054",
+	0x1D684: "\u0055",
+	0x1D685: "\u0056",
+	0x1D686: "\u0057",
+	0x1D687: "\u0058",
+	0x1D688: "\u0059",
+	0x1D689: "\u005a",
+	0x1D68A: "\u0061",
+	0x1D68B: "\u0062",
+	0x1D68C: "\u0063",
+	0x1D68D: "\u0064",
+	0x1D68E: "\u0065",
+	0x1D68F: "\u0066",
+	0x1D690: "\u0067",
+	0x1D691: "\u0068",
+	0x1D692: "\u0069",
+	0x1D693: "\u006a",
+	0x1D694: "\u006b",
+	0x1D695: "\u006c",
+	0x1D696: "\u006d",
+	0x1D697: "\u006e",
+	0x1D698: "\u006f",
+	0x1D699: "\u0070",
+	0x1D69A: "\u0071",
+	0x1D69B: "\u0072",
+	0x1D69C: "\u0073",
+	0x1D69D: "\u0074",
+	0x1D69E: "\u0075",
+	0x1D69F: "\u0076",
+	0x1D6A0: "\u0077",
+	0x1D6A1: "\u0078",
+	0x1D6A2: "\u0079",
+	0x1D6A3: "\u007a",
+	0x1D6A4: "\u0131",
+	0x1D6A5: "\u0237",
+	0x1D6A8: "\u0391",
+	0x1D6A9: "\u0392",
+	0x1D6AA: "\u0393",
+	0x1D6AB: "\u0394",
+	0x1D6AC: "\u0395",
+	0x1D6AD: "\u0396",
+	0x1D6AE: "\u0397",
+	0x1D6AF: "\u0398",
+	0x1D6B0: "\u0399",
+	0x1D6B1: "\u039a",
+	0x1D6B2: "\u039b",
+	0x1D6B3: "\u039c",
+	0x1D6B4: "\u039d",
+	0x1D6B5: "\u039e",
+	0x1D6B6: "\u039f",
+	0x1D6B7: "\u03a0",
+	0x1D6B8: "\u03a1",
+	0x1D6B9: "\u03f4",
+	0x1D6BA: "\u03a3",
+	0x1D6BB: "\u03a4",
+	0x1D6BC: "\u03a5",
+	0x1D6BD: "\u03a6",
+	0x1D6BE: "\u03a7",
+	0x1D6BF: "\u03a8",
+	0x1D6C0: "\u03a9",
+	0x1D6C1: "\u2207",
+	0x1D6C2: "\u03b1",
+	0x1D6C3: "\u03b2",
+	0x1D6C4: "\u03b3",
+	0x1D6C5: "\u03b4",
+	0x1D6C6: "\u03b5",
+	0x1D6C7: "\u03b6",
+	0x1D6C8: "\u03b7",
+	0x1D6C9: "\u03b8",
+	0x1D6CA: "\u03b9",
+	0x1D6CB: "\u03ba",
+	0x1D6CC: "\u03bb",
+	0x1D6CD: "\u03bc",
+	0x1D6CE: "\u03bd",
+	0x1D6CF: "\u03be",
+	0x1D6D0: "\u03bf",
+	0x1D6D1: "\u03c0",
+	0x1D6D2: "\u03c1",
+	0x1D6D3: "\u03c2",
+	0x1D6D4: "\u03c3",
+	0x1D6D5: "\u03c4",
+	0x1D6D6: "\u03c5",
+	0x1D6D7: "\u03c6",
+	0x1D6D8: "\u03c7",
+	0x1D6D9: "\u03c8",
+	0x1D6DA: "\u03c9",
+	0x1D6DB: "\u2202",
+	0x1D6DC: "\u03f5",
+	0x1D6DD: "\u03d1",
+	0x1D6DE: "\u03f0",
+	0x1D6DF: "\u03d5",
+	0x1D6E0: "\u03f1",
+	0x1D6E1: "\u03d6",
+	0x1D6E2: "\u0391",
+	0x1D6E3: "\u0392",
+	0x1D6E4: "\u0393",
+	0x1D6E5: "\u0394",
+	0x1D6E6: "\u0395",
+	0x1D6E7: "\u0396",
+	0x1D6E8: "\u0397",
+	0x1D6E9: "\u0398",
+	0x1D6EA: "\u0399",
+	0x1D6EB: "\u039a",
+	0x1D6EC: "\u039b",
+	0x1D6ED: "\u039c",
+	0x1D6EE: "\u039d",
+	0x1D6EF: "\u039e",
+	0x1D6F0: "\u039f",
+	0x1D6F1: "\u03a0",
+	0x1D6F2: "\u03a1",
+	0x1D6F3: "\u03f4",
+	0x1D6F4: "\u03a3",
+	0x1D6F5: "\u03a4",
+	0x1D6F6: "\u03a5",
+	0x1D6F7: "\u03a6",
+	0x1D6F8: "\u03a7",
+	0x1D6F9: "\u03a8",
+	0x1D6FA: "\u03a9",
+	0x1D6FB: "\u2207",
+	0x1D6FC: "\u03b1",
+	0x1D6FD: "\u03b2",
+	0x1D6FE: "\u03b3",
+	0x1D6FF: "\u03b4",
+	0x1D700: "\u03b5",
+	0x1D701: "\u03b6",
+	0x1D702: "\u03b7",
+	0x1D703: "\u03b8",
+	0x1D704: "\u03b9",
+	0x1D705: "\u03ba",
+	0x1D706: "\u03bb",
+	0x1D707: "\u03bc",
+	0x1D708: "\u03bd",
+	0x1D709: "\u03be",
+	0x1D70A: "\u03bf",
+	0x1D70B: "\u03c0",
+	0x1D70C: "\u03c1",
+	0x1D70D: "\u03c2",
+	0x1D70E: "\u03c3",
+	0x1D70F: "\u03c4",
+	0x1D710: "\u03c5",
+	0x1D711: "\u03c6",
+	0x1D712: "\u03c7",
+	0x1D713: "\u03c8",
+	0x1D714: "\u03c9",
+	0x1D715: "\u2202",
+	0x1D716: "\u03f5",
+	0x1D717: "\u03d1",
+	0x1D718: "\u03f0",
+	0x1D719: "\u03d5",
+	0x1D71A: "\u03f1",
+	0x1D71B: "\u03d6",
+	0x1D71C: "\u0391",
+	0x1D71D: "\u0392",
+	0x1D71E: "\u0393",
+	0x1D71F: "\u0394",
+	0x1D720: "\u0395",
+	0x1D721: "\u0396",
+	0x1D722: "\u0397",
+	0x1D723: "\u0398",
+	0x1D724: "\u0399",
+	0x1D725: "\u039a",
+	0x1D726: "\u039b",
+	0x1D727: "\u039c",
+	0x1D728: "\u039d",
+	0x1D729: "\u039e",
+	0x1D72A: "\u039f",
+	0x1D72B: "\u03a0",
+	0x1D72C: "\u03a1",
+	0x1D72D: "\u03f4",
+	0x1D72E: "\u03a3",
+	0x1D72F: "\u03a4",
+	0x1D730: "\u03a5",
+	0x1D731: "\u03a6",
+	0x1D732: "\u03a7",
+	0x1D733: "\u03a8",
+	0x1D734: "\u03a9",
+	0x1D735: "\u2207",
+	0x1D736: "\u03b1",
+	0x1D737: "\u03b2",
+	0x1D738: "\u03b3",
+	0x1D739: "\u03b4",
+	0x1D73A: "\u03b5",
+	0x1D73B: "\u03b6",
+	0x1D73C: "\u03b7",
+	0x1D73D: "\u03b8",
+	0x1D73E: "\u03b9",
+	0x1D73F: "\u03ba",
+	0x1D740: "\u03bb",
+	0x1D741: "\u03bc",
+	0x1D742: "\u03bd",
+	0x1D743: "\u03be",
+	0x1D744: "\u03bf",
+	0x1D745: "\u03c0",
+	0x1D746: "\u03c1",
+	0x1D747: "\u03c2",
+	0x1D748: "\u03c3",
+	0x1D749: "\u03c4",
+	0x1D74A: "\u03c5",
+	0x1D74B: "\u03c6",
+	0x1D74C: "\u03c7",
+	0x1D74D: "\u03c8",
+	0x1D74E: "\u03c9",
+	0x1D74F: "\u2202",
+	0x1D750: "\u03f5",
+	0x1D751: "\u03d1",
+	0x1D752: "\u03f0",
+	0x1D753: "\u03d5",
+	0x1D754: "\u03f1",
+	0x1D755: "\u03d6",
+	0x1D756: "\u0391",
+	0x1D757: "\u0392",
+	0x1D758: "\u0393",
+	0x1D759: "\u0394",
+	0x1D75A: "\u0395",
+	0x1D75B: "\u0396",
+	0x1D75C: "\u0397",
+	0x1D75D: "\u0398",
+	0x1D75E: "\u0399",
+	0x1D75F: "\u039a",
+	0x1D760: "\u039b",
+	0x1D761: "\u039c",
+	0x1D762: "\u039d",
+	0x1D763: "\u039e",
+	0x1D764: "\u039f",
+	0x1D765: "\u03a0",
+	0x1D766: "\u03a1",
+	0x1D767: "\u03f4",
+	0x1D768: "\u03a3",
+	0x1D769: "\u03a4",
+	0x1D76A: "\u03a5",
+	0x1D76B: "\u03a6",
+	0x1D76C: "\u03a7",
+	0x1D76D: "\u03a8",
+	0x1D76E: "\u03a9",
+	0x1D76F: "\u2207",
+	0x1D770: "\u03b1",
+	0x1D771: "\u03b2",
+	0x1D772: "\u03b3",
+	0x1D773: "\u03b4",
+	0x1D774: "\u03b5",
+	0x1D775: "\u03b6",
+	0x1D776: "\u03b7",
+	0x1D777: "\u03b8",
+	0x1D778: "\u03b9",
+	0x1D779: "\u03ba",
+	0x1D77A: "\u03bb",
+	0x1D77B: "\u03bc",
+	0x1D77C: "\u03bd",
+	0x1D77D: "\u03be",
+	0x1D77E: "\u03bf",
+	0x1D77F: "\u03c0",
+	0x1D780: "\u03c1",
+	0x1D781: "\u03c2",
+	0x1D782: "\u03c3",
+	0x1D783: "\u03c4",
+	0x1D784: "\u03c5",
+	0x1D785: "\u03c6",
+	0x1D786: "\u03c7",
+	0x1D787: "\u03c8",
+	0x1D788: "\u03c9",
+	0x1D789: "\u2202",
+	0x1D78A: "\u03f5",
+	0x1D78B: "\u03d1",
+	0x1D78C: "\u03f0",
+	0x1D78D: "\u03d5",
+	0x1D78E: "\u03f1",
+	0x1D78F: "\u03d6",
+	0x1D790: "\u0391",
+	0x1D791: "\u0392",
+	0x1D792: "\u0393",
+	0x1D793: "\u0394",
+	0x1D794: "\u0395",
+	0x1D795: "\u0396",
+	0x1D796: "\u0397",
+	0x1D797: "\u0398",
+	0x1D798: "\u0399",
+	0x1D799: "\u039a",
+	0x1D79A: "\u039b",
+	0x1D79B: "\u039c",
+	0x1D79C: "\u039d",
+	0x1D79D: "\u039e",
+	0x1D79E: "\u039f",
+	0x1D79F: "\u03a0",
+	0x1D7A0: "\u03a1",
+	0x1D7A1: "\u03f4",
+	0x1D7A2: "\u03a3",
+	0x1D7A3: "\u03a4",
+	0x1D7A4: "\u03a5",
+	0x1D7A5: "\u03a6",
+	0x1D7A6: "\u03a7",
+	0x1D7A7: "\u03a8",
+	0x1D7A8: "\u03a9",
+	0x1D7A9: "\u2207",
+	0x1D7AA: "\u03b1",
+	0x1D7AB: "\u03b2",
+	0x1D7AC: "\u03b3",
+	0x1D7AD: "\u03b4",
+	0x1D7AE: "\u03b5",
+	0x1D7AF: "\u03b6",
+	0x1D7B0: "\u03b7",
+	0x1D7B1: "\u03b8",
+	0x1D7B2: "\u03b9",
+	0x1D7B3: "\u03ba",
+	0x1D7B4: "\u03bb",
+	0x1D7B5: "\u03bc",
+	0x1D7B6: "\u03bd",
+	0x1D7B7: "\u03be",
+	0x1D7B8: "\u03bf",
+	0x1D7B9: "\u03c0",
+	0x1D7BA: "\u03c1",
+	0x1D7BB: "\u03c2",
+	0x1D7BC: "\u03c3",
+	0x1D7BD: "\u03c4",
+	0x1D7BE: "\u03c5",
+	0x1D7BF: "\u03c6",
+	0x1D7C0: "\u03c7",
+	0x1D7C1: "\u03c8",
+	0x1D7C2: "\u03c9",
+	0x1D7C3: "\u2202",
+	0x1D7C4: "\u03f5",
+	0x1D7C5: "\u03d1",
+	0x1D7C6: "\u03f0",
+	0x1D7C7: "\u03d5",
+	0x1D7C8: "\u03f1",
+	0x1D7C9: "\u03d6",
+	0x1D7CA: "\u03dc",
+	0x1D7CB: "\u03dd",
+	0x1D7CE: "\u0030",
+	0x1D7CF: "\u0031",
+	0x1D7D0: "\u0032",
+	0x1D7D1: "\u0033",
+	0x1D7D2: "\u0034",
+	0x1D7D3: "\u0035",
+	0x1D7D4: "\u0036",
+	0x1D7D5: "\u0037",
+	0x1D7D6: "\u0038",
+	0x1D7D7: "\u0039",
+	0x1D7D8: "\u0030",
+	0x1D7D9: "\u0031",
+	0x1D7DA: "\u0032",
+	0x1D7DB: "\u0033",
+	0x1D7DC: "\u0034",
+	0x1D7DD: "\u0035",
+	0x1D7DE: "\u0036",
+	0x1D7DF: "\u0037",
+	0x1D7E0: "\u0038",
+	0x1D7E1: "\u0039",
+	0x1D7E2: "\u0030",
+	0x1D7E3: "\u0031",
+	0x1D7E4: "\u0032",
+	0x1D7E5: "\u0033",
+	0x1D7E6: "\u0034",
+	0x1D7E7: "\u0035",
+	0x1D7E8: "\u0036",
+	0x1D7E9: "\u0037",
+	0x1D7EA: "\u0038",
+	0x1D7EB: "\u0039",
+	0x1D7EC: "\u0030",
+	0x1D7ED: "\u0031",
+	0x1D7EE: "\u0032",
+	0x1D7EF: "\u0033",
+	0x1D7F0: "\u0034",
+	0x1D7F1: "\u0035",
+	0x1D7F2: "\u0036",
+	0x1D7F3: "\u0037",
+	0x1D7F4: "\u0038",
+	0x1D7F5: "\u0039",
+	0x1D7F6: "\u0030",
+	0x1D7F7: "\u0031",
+	0x1D7F8: "\u0032",
+	0x1D7F9: "\u0033",
+	0x1D7FA: "\u0034",
+	0x1D7FB: "\u0035",
+	0x1D7FC: "\u0036",
+	0x1D7FD: "\u0037",
+	0x1D7FE: "\u0038",
+	0x1D7FF: "\u0039",
+	0x1EE00: "\u0627",
+	0x1EE01: "\u0628",
+	0x1EE02: "\u062c",
+	0x1EE03: "\u062f",
+	0x1EE05: "\u0648",
+	0x1EE06: "\u0632",
+	0x1EE07: "\u062d",
+	0x1EE08: "\u0637",
+	0x1EE09: "\u064a",
+	0x1EE0A: "\u0643",
+	0x1EE0B: "\u0644",
+	0x1EE0C: "\u0645",
+	0x1EE0D: "\u0646",
+	0x1EE0E: "\u0633",
+	0x1EE0F: "\u0639",
+	0x1EE10: "\u0641",
+	0x1EE11: "\u0635",
+	0x1EE12: "\u0642",
+	0x1EE13: "\u0631",
+	0x1EE14: "\u0634",
+	0x1EE15: "\u062a",
+	0x1EE16: "\u062b",
+	0x1EE17: "\u062e",
+	0x1EE18: "\u0630",
+	0x1EE19: "\u0636",
+	0x1EE1A: "\u0638",
+	0x1EE1B: "\u063a",
+	0x1EE1C: "\u066e",
+	0x1EE1D: "\u06ba",
+	0x1EE1E: "\u06a1",
+	0x1EE1F: "\u066f",
+	0x1EE21: "\u0628",
+	0x1EE22: "\u062c",
+	0x1EE24: "\u0647",
+	0x1EE27: "\u062d",
+	0x1EE29: "\u064a",
+	0x1EE2A: "\u0643",
+	0x1EE2B: "\u0644",
+	0x1EE2C: "\u0645",
+	0x1EE2D: "\u0646",
+	0x1EE2E: "\u0633",
+	0x1EE2F: "\u0639",
+	0x1EE30: "\u0641",
+	0x1EE31: "\u0635",
+	0x1EE32: "\u0642",
+	0x1EE34: "\u0634",
+	0x1EE35: "\u062a",
+	0x1EE36: "\u062b",
+	0x1EE37: "\u062e",
+	0x1EE39: "\u0636",
+	0x1EE3B: "\u063a",
+	0x1EE42: "\u062c",
+	0x1EE47: "\u062d",
+	0x1EE49: "\u064a",
+	0x1EE4B: "\u0644",
+	0x1EE4D: "\u0646",
+	0x1EE4E: "\u0633",
+	0x1EE4F: "\u0639",
+	0x1EE51: "\u0635",
+	0x1EE52: "\u0642",
+	0x1EE54: "\u0634",
+	0x1EE57: "\u062e",
+	0x1EE59: "\u0636",
+	0x1EE5B: "\u063a",
+	0x1EE5D: "\u06ba",
+	0x1EE5F: "\u066f",
+	0x1EE61: "\u0628",
+	0x1EE62: "\u062c",
+	0x1EE64: "\u0647",
+	0x1EE67: "\u062d",
+	0x1EE68: "\u0637",
+	0x1EE69: "\u064a",
+	0x1EE6A: "\u0643",
+	0x1EE6C: "\u0645",
+	0x1EE6D: "\u0646",
+	0x1EE6E: "\u0633",
+	0x1EE6F: "\u0639",
+	0x1EE70: "\u0641",
+	0x1EE71: "\u0635",
+	0x1EE72: "\u0642",
+	0x1EE74: "\u0634",
+	0x1EE75: "\u062a",
+	0x1EE76: "\u062b",
+	0x1EE77: "\u062e",
+	0x1EE79: "\u0636",
+	0x1EE7A: "\u0638",
+	0x1EE7B: "\u063a",
+	0x1EE7C: "\u066e",
+	0x1EE7E: "\u06a1",
+	0x1EE80: "\u0627",
+	0x1EE81: "\u0628",
+	0x1EE82: "\u062c",
+	0x1EE83: "\u062f",
+	0x1EE84: "\u0647",
+	0x1EE85: "\u0648",
+	0x1EE86: "\u0632",
+	0x1EE87: "\u062d",
+	0x1EE88: "\u0637",
+	0x1EE89: "\u064a",
+	0x1EE8B: "\u0644",
+	0x1EE8C: "\u0645",
+	0x1EE8D: "\u0646",
+	0x1EE8E: "\u0633",
+	0x1EE8F: "\u0639",
+	0x1EE90: "\u0641",
+	0x1EE91: "\u0635",
+	0x1EE92: "\u0642",
+	0x1EE93: "\u0631",
+	0x1EE94: "\u0634",
+	0x1EE95: "\u062a",
+	0x1EE96: "\u062b",
+	0x1EE97: "\u062e",
+	0x1EE98: "\u0630",
+	0x1EE99: "\u0636",
+	0x1EE9A: "\u0638",
+	0x1EE9B: "\u063a",
+	0x1EEA1: "\u0628",
+	0x1EEA2: "\u062c",
+	0x1EEA3: "\u062f",
+	0x1EEA5: "\u0648",
+	0x1EEA6: "\u0632",
+	0x1EEA7: "\u062d",
+	0x1EEA8: "\u0637",
+	0x1EEA9: "\u064a",
+	0x1EEAB: "\u0644",
+	0x1EEAC: "\u0645",
+	0x1EEAD: "\u0646",
+	0x1EEAE: "\u0633",
+	0x1EEAF: "\u0639",
+	0x1EEB0: "\u0641",
+	0x1EEB1: "\u0635",
+	0x1EEB2: "\u0642",
+	0x1EEB3: "\u0631",
+	0x1EEB4: "\u0634",
+	0x1EEB5: "\u062a",
+	0x1EEB6: "\u062b",
+	0x1EEB7: "\u062e",
+	0x1EEB8: "\u0630",
+	0x1EEB9: "\u0636",
+	0x1EEBA: "\u0638",
+	0x1EEBB: "\u063a",
+	0x1F100: "\u0030\u002e",
+	0x1F101: "\u0030\u002c",
+	0x1F102: "\u0031\u002c",
+	0x1F103: "\u0032\u002c",
+	0x1F104: "\u0033\u002c",
+	0x1F105: "\u0034\u002c",
+	0x1F106: "\u0035\u002c",
+	0x1F107: "\u0036\u002c",
+	0x1F108: "\u0037\u002c",
+	0x1F109: "\u0038\u002c",
+	0x1F10A: "\u0039\u002c",
+	0x1F110: "\u0028\u0041\u0029",
+	0x1F111: "\u0028\u0042\u0029",
+	0x1F112: "\u0028\u0043\u0029",
+	0x1F113: "\u0028\u0044\u0029",
+	0x1F114: "\u0028\u0045\u0029",
+	0x1F115: "\u0028\u0046\u0029",
+	0x1F116: "\u0028\u0047\u0029",
+	0x1F117: "\u0028\u0048\u0029",
+	0x1F118: "\u0028\u0049\u0029",
+	0x1F119: "\u0028\u004a\u0029",
+	0x1F11A: "\u0028\u004b\u0029",
+	0x1F11B: "\u0028\u004c\u0029",
+	0x1F11C: "\u0028\u004d\u0029",
+	0x1F11D: "\u0028\u004e\u0029",
+	0x1F11E: "\u0028\u004f\u0029",
+	0x1F11F: "\u0028\u0050\u0029",
+	0x1F120: "\u0028\u0051\u0029",
+	0x1F121: "\u0028\u0052\u0029",
+	0x1F122: "\u0028\u0053\u0029",
+	0x1F123: "\u0028\u0054\u0029",
+	0x1F124: "\u0028\u0055\u0029",
+	0x1F125: "\u0028\u0056\u0029",
+	0x1F126: "\u0028\u0057\u0029",
+	0x1F127: "\u0028\u0058\u0029",
+	0x1F128: "\u0028\u0059\u0029",
+	0x1F129: "\u0028\u005a\u0029",
+	0x1F12A: "\u3014\u0053\u3015",
+	0x1F12B: "\u0043",
+	0x1F12C: "\u0052",
+	0x1F12D: "\u0043\u0044",
+	0x1F12E: "\u0057\u005a",
+	0x1F130: "\u0041",
+	0x1F131: "\u0042",
+	0x1F132: "\u0043",
+	0x1F133: "\u0044",
+	0x1F134: "\u0045",
+	0x1F135: "\u0046",
+	0x1F136: "\u0047",
+	0x1F137: "\u0048",
+	0x1F138: "\u0049",
+	0x1F139: "\u004a",
+	0x1F13A: "\u004b",
+	0x1F13B: "\u004c",
+	0x1F13C: "\u004d",
+	0x1F13D: "\u004e",
+	0x1F13E: "\u004f",
+	0x1F13F: "\u0050",
+	0x1F140: "\u0051",
+	0x1F141: "\u0052",
+	0x1F142: "\u0053",
+	0x1F143: "\u0054",
+	0x1F144: "\u0055",
+	0x1F145: "\u0056",
+	0x1F146: "\u0057",
+	0x1F147: "\u0058",
+	0x1F148: "\u0059",
+	0x1F149: "\u005a",
+	0x1F14A: "\u0048\u0056",
+	0x1F14B: "\u004d\u0056",
+	0x1F14C: "\u0053\u0044",
+	0x1F14D: "\u0053\u0053",
+	0x1F14E: "\u0050\u0050\u0056",
+	0x1F14F: "\u0057\u0043",
+	0x1F16A: "\u004d\u0043",
+	0x1F16B: "\u004d\u0044",
+	0x1F16C: "\u004d\u0052",
+	0x1F190: "\u0044\u004a",
+	0x1F200: "\u307b\u304b",
+	0x1F201: "\u30b3\u30b3",
+	0x1F202: "\u30b5",
+	0x1F210: "\u624b",
+	0x1F211: "\u5b57",
+	0x1F212: "\u53cc",
+	0x1F213: "\u30c7",
+	0x1F214: "\u4e8c",
+	0x1F215: "\u591a",
+	0x1F216: "\u89e3",
+	0x1F217: "\u5929",
+	0x1F218: "\u4ea4",
+	0x1F219: "\u6620",
+	0x1F21A: "\u7121",
+	0x1F21B: "\u6599",
+	0x1F21C: "\u524d",
+	0x1F21D: "\u5f8c",
+	0x1F21E: "\u518d",
+	0x1F21F: "\u65b0",
+	0x1F220: "\u521d",
+	0x1F221: "\u7d42",
+	0x1F222: "\u751f",
+	0x1F223: "\u8ca9",
+	0x1F224: "\u58f0",
+	0x1F225: "\u5439",
+	0x1F226: "\u6f14",
+	0x1F227: "\u6295",
+	0x1F228: "\u6355",
+	0x1F229: "\u4e00",
+	0x1F22A: "\u4e09",
+	0x1F22B: "\u904a",
+	0x1F22C: "\u5de6",
+	0x1F22D: "\u4e2d",
+	0x1F22E: "\u53f3",
+	0x1F22F: "\u6307",
+	0x1F230: "\u8d70",
+	0x1F231: "\u6253",
+	0x1F232: "\u7981",
+	0x1F233: "\u7a7a",
+	0x1F234: "\u5408",
+	0x1F235: "\u6e80",
+	0x1F236: "\u6709",
+	0x1F237: "\u6708",
+	0x1F238: "\u7533",
+	0x1F239: "\u5272",
+	0x1F23A: "\u55b6",
+	0x1F23B: "\u914d",
+	0x1F240: "\u3014\u672c\u3015",
+	0x1F241: "\u3014\u4e09\u3015",
+	0x1F242: "\u3014\u4e8c\u3015",
+	0x1F243: "\u3014\u5b89\u3015",
+	0x1F244: "\u3014\u70b9\u3015",
+	0x1F245: "\u3014\u6253\u3015",
+	0x1F246: "\u3014\u76d7\u3015",
+	0x1F247: "\u3014\u52dd\u3015",
+	0x1F248: "\u3014\u6557\u3015",
+	0x1F250: "\u5f97",
+	0x1F251: "\u53ef",
+	0x1FBF0: "\u0030",
+	0x1FBF1: "\u0031",
+	0x1FBF2: "\u0032",
+	0x1FBF3: "\u0033",
+	0x1FBF4: "\u0034",
+	0x1FBF5: "\u0035",
+	0x1FBF6: "\u0036",
+	0x1FBF7: "\u0037",
+	0x1FBF8: "\u0038",
+	0x1FBF9: "\u0039",
+}
+
+// compositions maps the pairs composed by NFC to their composite
+var compositions = map[[2]rune]rune{
+	{0x003C, 0x0338}:   0x226E,
+	{0x003D, 0x0338}:   0x2260,
+	{0x003E, 0x0338}:   0x226F,
+	{0x0041, 0x0300}:   0x00C0,
+	{0x0041, 0x0301}:   0x00C1,
+	{0x0041, 0x0302}:   0x00C2,
+	{0x0041, 0x0303}:   0x00C3,
+	{0x0041, 0x0304}:   0x0100,
+	{0x0041, 0x0306}:   0x0102,
+	{0x0041, 0x0307}:   0x0226,
+	{0x0041, 0x0308}:   0x00C4,
+	{0x0041, 0x0309}:   0x1EA2,
+	{0x0041, 0x030A}:   0x00C5,
+	{0x0041, 0x030C}:   0x01CD,
+	{0x0041, 0x030F}:   0x0200,
+	{0x0041, 0x0311}:   0x0202,
+	{0x0041, 0x0323}:   0x1EA0,
+	{0x0041, 0x0325}:   0x1E00,
+	{0x0041, 0x0328}:   0x0104,
+	{0x0042, 0x0307}:   0x1E02,
+	{0x0042, 0x0323}:   0x1E04,
+	{0x0042, 0x0331}:   0x1E06,
+	{0x0043, 0x0301}:   0x0106,
+	{0x0043, 0x0302}:   0x0108,
+	{0x0043, 0x0307}:   0x010A,
+	{0x0043, 0x030C}:   0x010C,
+	{0x0043, 0x0327}:   0x00C7,
+	{0x0044, 0x0307}:   0x1E0A,
+	{0x0044, 0x030C}:   0x010E,
+	{0x0044, 0x0323}:   0x1E0C,
+	{0x0044, 0x0327}:   0x1E10,
+	{0x0044, 0x032D}:   0x1E12,
+	{0x0044, 0x0331}:   0x1E0E,
+	{0x0045, 0x0300}:   0x00C8,
+	{0x0045, 0x0301}:   0x00C9,
+	{0x0045, 0x0302}:   0x00CA,
+	{0x0045, 0x0303}:   0x1EBC,
+	{0x0045, 0x0304}:   0x0112,
+	{0x0045, 0x0306}:   0x0114,
+	{0x0045, 0x0307}:   0x0116,
+	{0x0045, 0x0308}:   0x00CB,
+	{0x0045, 0x0309}:   0x1EBA,
+	{0x0045, 0x030C}:   0x011A,
+	{0x0045, 0x030F}:   0x0204,
+	{0x0045, 0x0311}:   0x0206,
+	{0x0045, 0x0323}:   0x1EB8,
+	{0x0045, 0x0327}:   0x0228,
+	{0x0045, 0x0328}:   0x0118,
+	{0x0045, 0x032D}:   0x1E18,
+	{0x0045, 0x0330}:   0x1E1A,
+	{0x0046, 0x0307}:   0x1E1E,
+	{0x0047, 0x0301}:   0x01F4,
+	{0x0047, 0x0302}:   0x011C,
+	{0x0047, 0x0304}:   0x1E20,
+	{0x0047, 0x0306}:   0x011E,
+	{0x0047, 0x0307}:   0x0120,
+	{0x0047, 0x030C}:   0x01E6,
+	{0x0047, 0x0327}:   0x0122,
+	{0x0048, 0x0302}:   0x0124,
+	{0x0048, 0x0307}:   0x1E22,
+	{0x0048, 0x0308}:   0x1E26,
+	{0x0048, 0x030C}:   0x021E,
+	{0x0048, 0x0323}:   0x1E24,
+	{0x0048, 0x0327}:   0x1E28,
+	{0x0048, 0x032E}:   0x1E2A,
+	{0x0049, 0x0300}:   0x00CC,
+	{0x0049, 0x0301}:   0x00CD,
+	{0x0049, 0x0302}:   0x00CE,
+	{0x0049, 0x0303}:   0x0128,
+	{0x0049, 0x0304}:   0x012A,
+	{0x0049, 0x0306}:   0x012C,
+	{0x0049, 0x0307}:   0x0130,
+	{0x0049, 0x0308}:   0x00CF,
+	{0x0049, 0x0309}:   0x1EC8,
+	{0x0049, 0x030C}:   0x01CF,
+	{0x0049, 0x030F}:   0x0208,
+	{0x0049, 0x0311}:   0x020A,
+	{0x0049, 0x0323}:   0x1ECA,
+	{0x0049, 0x0328}:   0x012E,
+	{0x0049, 0x0330}:   0x1E2C,
+	{0x004A, 0x0302}:   0x0134,
+	{0x004B, 0x0301}:   0x1E30,
+	{0x004B, 0x030C}:   0x01E8,
+	{0x004B, 0x0323}:   0x1E32,
+	{0x004B, 0x0327}:   0x0136,
+	{0x004B, 0x0331}:   0x1E34,
+	{0x004C, 0x0301}:   0x0139,
+	{0x004C, 0x030C}:   0x013D,
+	{0x004C, 0x0323}:   0x1E36,
+	{0x004C, 0x0327}:   0x013B,
+	{0x004C, 0x032D}:   0x1E3C,
+	{0x004C, 0x0331}:   0x1E3A,
+	{0x004D, 0x0301}:   0x1E3E,
+	{0x004D, 0x0307}:   0x1E40,
+	{0x004D, 0x0323}:   0x1E42,
+	{0x004E, 0x0300}:   0x01F8,
+	{0x004E, 0x0301}:   0x0143,
+	{0x004E, 0x0303}:   0x00D1,
+	{0x004E, 0x0307}:   0x1E44,
+	{0x004E, 0x030C}:   0x0147,
+	{0x004E, 0x0323}:   0x1E46,
+	{0x004E, 0x0327}:   0x0145,
+	{0x004E, 0x032D}:   0x1E4A,
+	{0x004E, 0x0331}:   0x1E48,
+	{0x004F, 0x0300}:   0x00D2,
+	{0x004F, 0x0301}:   0x00D3,
+	{0x004F, 0x0302}:   0x00D4,
+	{0x004F, 0x0303}:   0x00D5,
+	{0x004F, 0x0304}:   0x014C,
+	{0x004F, 0x0306}:   0x014E,
+	{0x004F, 0x0307}:   0x022E,
+	{0x004F, 0x0308}:   0x00D6,
+	{0x004F, 0x0309}:   0x1ECE,
+	{0x004F, 0x030B}:   0x0150,
+	{0x004F, 0x030C}:   0x01D1,
+	{0x004F, 0x030F}:   0x020C,
+	{0x004F, 0x0311}:   0x020E,
+	{0x004F, 0x031B}:   0x01A0,
+	{0x004F, 0x0323}:   0x1ECC,
+	{0x004F, 0x0328}:   0x01EA,
+	{0x0050, 0x0301}:   0x1E54,
+	{0x0050, 0x0307}:   0x1E56,
+	{0x0052, 0x0301}:   0x0154,
+	{0x0052, 0x0307}:   0x1E58,
+	{0x0052, 0x030C}:   0x0158,
+	{0x0052, 0x030F}:   0x0210,
+	{0x0052, 0x0311}:   0x0212,
+	{0x0052, 0x0323}:   0x1E5A,
+	{0x0052, 0x0327}:   0x0156,
+	{0x0052, 0x0331}:   0x1E5E,
+	{0x0053, 0x0301}:   0x015A,
+	{0x0053, 0x0302}:   0x015C,
+	{0x0053, 0x0307}:   0x1E60,
+	{0x0053, 0x030C}:   0x0160,
+	{0x0053, 0x0323}:   0x1E62,
+	{0x0053, 0x0326}:   0x0218,
+	{0x0053, 0x0327}:   0x015E,
+	{0x0054, 0x0307}:   0x1E6A,
+	{0x0054, 0x030C}:   0x0164,
+	{0x0054, 0x0323}:   0x1E6C,
+	{0x0054, 0x0326}:   0x021A,
+	{0x0054, 0x0327}:   0x0162,
+	{0x0054, 0x032D}:   0x1E70,
+	{0x0054, 0x0331}:   0x1E6E,
+	{0x0055, 0x0300}:   0x00D9,
+	{0x0055, 0x0301}:   0x00DA,
+	{0x0055, 0x0302}:   0x00DB,
+	{0x0055, 0x0303}:   0x0168,
+	{0x0055, 0x0304}:   0x016A,
+	{0x0055, 0x0306}:   0x016C,
+	{0x0055, 0x0308}:   0x00DC,
+	{0x0055, 0x0309}:   0x1EE6,
+	{0x0055, 0x030A}:   0x016E,
+	{0x0055, 0x030B}:   0x0170,
+	{0x0055, 0x030C}:   0x01D3,
+	{0x0055, 0x030F}:   0x0214,
+	{0x0055, 0x0311}:   0x0216,
+	{0x0055, 0x031B}:   0x01AF,
+	{0x0055, 0x0323}:   0x1EE4,
+	{0x0055, 0x0324}:   0x1E72,
+	{0x0055, 0x0328}:   0x0172,
+	{0x0055, 0x032D}:   0x1E76,
+	{0x0055, 0x0330}:   0x1E74,
+	{0x0056, 0x0303}:   0x1E7C,
+	{0x0056, 0x0323}:   0x1E7E,
+	{0x0057, 0x0300}:   0x1E80,
+	{0x0057, 0x0301}:   0x1E82,
+	{0x0057, 0x0302}:   0x0174,
+	{0x0057, 0x0307}:   0x1E86,
+	{0x0057, 0x0308}:   0x1E84,
+	{0x0057, 0x0323}:   0x1E88,
+	{0x0058, 0x0307}:   0x1E8A,
+	{0x0058, 0x0308}:   0x1E8C,
+	{0x0059, 0x0300}:   0x1EF2,
+	{0x0059, 0x0301}:   0x00DD,
+	{0x0059, 0x0302}:   0x0176,
+	{0x0059, 0x0303}:   0x1EF8,
+	{0x0059, 0x0304}:   0x0232,
+	{0x0059, 0x0307}:   0x1E8E,
+	{0x0059, 0x0308}:   0x0178,
+	{0x0059, 0x0309}:   0x1EF6,
+	{0x0059, 0x0323}:   0x1EF4,
+	{0x005A, 0x0301}:   0x0179,
+	{0x005A, 0x0302}:   0x1E90,
+	{0x005A, 0x0307}:   0x017B,
+	{0x005A, 0x030C}:   0x017D,
+	{0x005A, 0x0323}:   0x1E92,
+	{0x005A, 0x0331}:   0x1E94,
+	{0x0061, 0x0300}:   0x00E0,
+	{0x0061, 0x0301}:   0x00E1,
+	{0x0061, 0x0302}:   0x00E2,
+	{0x0061, 0x0303}:   0x00E3,
+	{0x0061, 0x0304}:   0x0101,
+	{0x0061, 0x0306}:   0x0103,
+	{0x0061, 0x0307}:   0x0227,
+	{0x0061, 0x0308}:   0x00E4,
+	{0x0061, 0x0309}:   0x1EA3,
+	{0x0061, 0x030A}:   0x00E5,
+	{0x0061, 0x030C}:   0x01CE,
+	{0x0061, 0x030F}:   0x0201,
+	{0x0061, 0x0311}:   0x0203,
+	{0x0061, 0x0323}:   0x1EA1,
+	{0x0061, 0x0325}:   0x1E01,
+	{0x0061, 0x0328}:   0x0105,
+	{0x0062, 0x0307}:   0x1E03,
+	{0x0062, 0x0323}:   0x1E05,
+	{0x0062, 0x0331}:   0x1E07,
+	{0x0063, 0x0301}:   0x0107,
+	{0x0063, 0x0302}:   0x0109,
+	{0x0063, 0x0307}:   0x010B,
+	{0x0063, 0x030C}:   0x010D,
+	{0x0063, 0x0327}:   0x00E7,
+	{0x0064, 0x0307}:   0x1E0B,
+	{0x0064, 0x030C}:   0x010F,
+	{0x0064, 0x0323}:   0x1E0D,
+	{0x0064, 0x0327}:   0x1E11,
+	{0x0064, 0x032D}:   0x1E13,
+	{0x0064, 0x0331}:   0x1E0F,
+	{0x0065, 0x0300}:   0x00E8,
+	{0x0065, 0x0301}:   0x00E9,
+	{0x0065, 0x0302}:   0x00EA,
+	{0x0065, 0x0303}:   0x1EBD,
+	{0x0065, 0x0304}:   0x0113,
+	{0x0065, 0x0306}:   0x0115,
+	{0x0065, 0x0307}:   0x0117,
+	{0x0065, 0x0308}:   0x00EB,
+	{0x0065, 0x0309}:   0x1EBB,
+	{0x0065, 0x030C}:   0x011B,
+	{0x0065, 0x030F}:   0x0205,
+	{0x0065, 0x0311}:   0x0207,
+	{0x0065, 0x0323}:   0x1EB9,
+	{0x0065, 0x0327}:   0x0229,
+	{0x0065, 0x0328}:   0x0119,
+	{0x0065, 0x032D}:   0x1E19,
+	{0x0065, 0x0330}:   0x1E1B,
+	{0x0066, 0x0307}:   0x1E1F,
+	{0x0067, 0x0301}:   0x01F5,
+	{0x0067, 0x0302}:   0x011D,
+	{0x0067, 0x0304}:   0x1E21,
+	{0x0067, 0x0306}:   0x011F,
+	{0x0067, 0x0307}:   0x0121,
+	{0x0067, 0x030C}:   0x01E7,
+	{0x0067, 0x0327}:   0x0123,
+	{0x0068, 0x0302}:   0x0125,
+	{0x0068, 0x0307}:   0x1E23,
+	{0x0068, 0x0308}:   0x1E27,
+	{0x0068, 0x030C}:   0x021F,
+	{0x0068, 0x0323}:   0x1E25,
+	{0x0068, 0x0327}:   0x1E29,
+	{0x0068, 0x032E}:   0x1E2B,
+	{0x0068, 0x0331}:   0x1E96,
+	{0x0069, 0x0300}:   0x00EC,
+	{0x0069, 0x0301}:   0x00ED,
+	{0x0069, 0x0302}:   0x00EE,
+	{0x0069, 0x0303}:   0x0129,
+	{0x0069, 0x0304}:   0x012B,
+	{0x0069, 0x0306}:   0x012D,
+	{0x0069, 0x0308}:   0x00EF,
+	{0x0069, 0x0309}:   0x1EC9,
+	{0x0069, 0x030C}:   0x01D0,
+	{0x0069, 0x030F}:   0x0209,
+	{0x0069, 0x0311}:   0x020B,
+	{0x0069, 0x0323}:   0x1ECB,
+	{0x0069, 0x0328}:   0x012F,
+	{0x0069, 0x0330}:   0x1E2D,
+	{0x006A, 0x0302}:   0x0135,
+	{0x006A, 0x030C}:   0x01F0,
+	{0x006B, 0x0301}:   0x1E31,
+	{0x006B, 0x030C}:   0x01E9,
+	{0x006B, 0x0323}:   0x1E33,
+	{0x006B, 0x0327}:   0x0137,
+	{0x006B, 0x0331}:   0x1E35,
+	{0x006C, 0x0301}:   0x013A,
+	{0x006C, 0x030C}:   0x013E,
+	{0x006C, 0x0323}:   0x1E37,
+	{0x006C, 0x0327}:   0x013C,
+	{0x006C, 0x032D}:   0x1E3D,
+	{0x006C, 0x0331}:   0x1E3B,
+	{0x006D, 0x0301}:   0x1E3F,
+	{0x006D, 0x0307}:   0x1E41,
+	{0x006D, 0x0323}:   0x1E43,
+	{0x006E, 0x0300}:   0x01F9,
+	{0x006E, 0x0301}:   0x0144,
+	{0x006E, 0x0303}:   0x00F1,
+	{0x006E, 0x0307}:   0x1E45,
+	{0x006E, 0x030C}:   0x0148,
+	{0x006E, 0x0323}:   0x1E47,
+	{0x006E, 0x0327}:   0x0146,
+	{0x006E, 0x032D}:   0x1E4B,
+	{0x006E, 0x0331}:   0x1E49,
+	{0x006F, 0x0300}:   0x00F2,
+	{0x006F, 0x0301}:   0x00F3,
+	{0x006F, 0x0302}:   0x00F4,
+	{0x006F, 0x0303}:   0x00F5,
+	{0x006F, 0x0304}:   0x014D,
+	{0x006F, 0x0306}:   0x014F,
+	{0x006F, 0x0307}:   0x022F,
+	{0x006F, 0x0308}:   0x00F6,
+	{0x006F, 0x0309}:   0x1ECF,
+	{0x006F, 0x030B}:   0x0151,
+	{0x006F, 0x030C}:   0x01D2,
+	{0x006F, 0x030F}:   0x020D,
+	{0x006F, 0x0311}:   0x020F,
+	{0x006F, 0x031B}:   0x01A1,
+	{0x006F, 0x0323}:   0x1ECD,
+	{0x006F, 0x0328}:   0x01EB,
+	{0x0070, 0x0301}:   0x1E55,
+	{0x0070, 0x0307}:   0x1E57,
+	{0x0072, 0x0301}:   0x0155,
+	{0x0072, 0x0307}:   0x1E59,
+	{0x0072, 0x030C}:   0x0159,
+	{0x0072, 0x030F}:   0x0211,
+	{0x0072, 0x0311}:   0x0213,
+	{0x0072, 0x0323}:   0x1E5B,
+	{0x0072, 0x0327}:   0x0157,
+	{0x0072, 0x0331}:   0x1E5F,
+	{0x0073, 0x0301}:   0x015B,
+	{0x0073, 0x0302}:   0x015D,
+	{0x0073, 0x0307}:   0x1E61,
+	{0x0073, 0x030C}:   0x0161,
+	{0x0073, 0x0323}:   0x1E63,
+	{0x0073, 0x0326}:   0x0219,
+	{0x0073, 0x0327}:   0x015F,
+	{0x0074, 0x0307}:   0x1E6B,
+	{0x0074, 0x0308}:   0x1E97,
+	{0x0074, 0x030C}:   0x0165,
+	{0x0074, 0x0323}:   0x1E6D,
+	{0x0074, 0x0326}:   0x021B,
+	{0x0074, 0x0327}:   0x0163,
+	{0x0074, 0x032D}:   0x1E71,
+	{0x0074, 0x0331}:   0x1E6F,
+	{0x0075, 0x0300}:   0x00F9,
+	{0x0075, 0x0301}:   0x00FA,
+	{0x0075, 0x0302}:   0x00FB,
+	{0x0075, 0x0303}:   0x0169,
+	{0x0075, 0x0304}:   0x016B,
+	{0x0075, 0x0306}:   0x016D,
+	{0x0075, 0x0308}:   0x00FC,
+	{0x0075, 0x0309}:   0x1EE7,
+	{0x0075, 0x030A}:   0x016F,
+	{0x0075, 0x030B}:   0x0171,
+	{0x0075, 0x030C}:   0x01D4,
+	{0x0075, 0x030F}:   0x0215,
+	{0x0075, 0x0311}:   0x0217,
+	{0x0075, 0x031B}:   0x01B0,
+	{0x0075, 0x0323}:   0x1EE5,
+	{0x0075, 0x0324}:   0x1E73,
+	{0x0075, 0x0328}:   0x0173,
+	{0x0075, 0x032D}:   0x1E77,
+	{0x0075, 0x0330}:   0x1E75,
+	{0x0076, 0x0303}:   0x1E7D,
+	{0x0076, 0x0323}:   0x1E7F,
+	{0x0077, 0x0300}:   0x1E81,
+	{0x0077, 0x0301}:   0x1E83,
+	{0x0077, 0x0302}:   0x0175,
+	{0x0077, 0x0307}:   0x1E87,
+	{0x0077, 0x0308}:   0x1E85,
+	{0x0077, 0x030A}:   0x1E98,
+	{0x0077, 0x0323}:   0x1E89,
+	{0x0078, 0x0307}:   0x1E8B,
+	{0x0078, 0x0308}:   0x1E8D,
+	{0x0079, 0x0300}:   0x1EF3,
+	{0x0079, 0x0301}:   0x00FD,
+	{0x0079, 0x0302}:   0x0177,
+	{0x0079, 0x0303}:   0x1EF9,
+	{0x0079, 0x0304}:   0x0233,
+	{0x0079, 0x0307}:   0x1E8F,
+	{0x0079, 0x0308}:   0x00FF,
+	{0x0079, 0x0309}:   0x1EF7,
+	{0x0079, 0x030A}:   0x1E99,
+	{0x0079, 0x0323}:   0x1EF5,
+	{0x007A, 0x0301}:   0x017A,
+	{0x007A, 0x0302}:   0x1E91,
+	{0x007A, 0x0307}:   0x017C,
+	{0x007A, 0x030C}:   0x017E,
+	{0x007A, 0x0323}:   0x1E93,
+	{0x007A, 0x0331}:   0x1E95,
+	{0x00A8, 0x0300}:   0x1FED,
+	{0x00A8, 0x0301}:   0x0385,
+	{0x00A8, 0x0342}:   0x1FC1,
+	{0x00C2, 0x0300}:   0x1EA6,
+	{0x00C2, 0x0301}:   0x1EA4,
+	{0x00C2, 0x0303}:   0x1EAA,
+	{0x00C2, 0x0309}:   0x1EA8,
+	{0x00C4, 0x0304}:   0x01DE,
+	{0x00C5, 0x0301}:   0x01FA,
+	{0x00C6, 0x0301}:   0x01FC,
+	{0x00C6, 0x0304}:   0x01E2,
+	{0x00C7, 0x0301}:   0x1E08,
+	{0x00CA, 0x0300}:   0x1EC0,
+	{0x00CA, 0x0301}:   0x1EBE,
+	{0x00CA, 0x0303}:   0x1EC4,
+	{0x00CA, 0x0309}:   0x1EC2,
+	{0x00CF, 0x0301}:   0x1E2E,
+	{0x00D4, 0x0300}:   0x1ED2,
+	{0x00D4, 0x0301}:   0x1ED0,
+	{0x00D4, 0x0303}:   0x1ED6,
+	{0x00D4, 0x0309}:   0x1ED4,
+	{0x00D5, 0x0301}:   0x1E4C,
+	{0x00D5, 0x0304}:   0x022C,
+	{0x00D5, 0x0308}:   0x1E4E,
+	{0x00D6, 0x0304}:   0x022A,
+	{0x00D8, 0x0301}:   0x01FE,
+	{0x00DC, 0x0300}:   0x01DB,
+	{0x00DC, 0x0301}:   0x01D7,
+	{0x00DC, 0x0304}:   0x01D5,
+	{0x00DC, 0x030C}:   0x01D9,
+	{0x00E2, 0x0300}:   0x1EA7,
+	{0x00E2, 0x0301}:   0x1EA5,
+	{0x00E2, 0x0303}:   0x1EAB,
+	{0x00E2, 0x0309}:   0x1EA9,
+	{0x00E4, 0x0304}:   0x01DF,
+	{0x00E5, 0x0301}:   0x01FB,
+	{0x00E6, 0x0301}:   0x01FD,
+	{0x00E6, 0x0304}:   0x01E3,
+	{0x00E7, 0x0301}:   0x1E09,
+	{0x00EA, 0x0300}:   0x1EC1,
+	{0x00EA, 0x0301}:   0x1EBF,
+	{0x00EA, 0x0303}:   0x1EC5,
+	{0x00EA, 0x0309}:   0x1EC3,
+	{0x00EF, 0x0301}:   0x1E2F,
+	{0x00F4, 0x0300}:   0x1ED3,
+	{0x00F4, 0x0301}:   0x1ED1,
+	{0x00F4, 0x0303}:   0x1ED7,
+	{0x00F4, 0x0309}:   0x1ED5,
+	{0x00F5, 0x0301}:   0x1E4D,
+	{0x00F5, 0x0304}:   0x022D,
+	{0x00F5, 0x0308}:   0x1E4F,
+	{0x00F6, 0x0304}:   0x022B,
+	{0x00F8, 0x0301}:   0x01FF,
+	{0x00FC, 0x0300}:   0x01DC,
+	{0x00FC, 0x0301}:   0x01D8,
+	{0x00FC, 0x0304}:   0x01D6,
+	{0x00FC, 0x030C}:   0x01DA,
+	{0x0102, 0x0300}:   0x1EB0,
+	{0x0102, 0x0301}:   0x1EAE,
+	{0x0102, 0x0303}:   0x1EB4,
+	{0x0102, 0x0309}:   0x1EB2,
+	{0x0103, 0x0300}:   0x1EB1,
+	{0x0103, 0x0301}:   0x1EAF,
+	{0x0103, 0x0303}:   0x1EB5,
+	{0x0103, 0x0309}:   0x1EB3,
+	{0x0112, 0x0300}:   0x1E14,
+	{0x0112, 0x0301}:   0x1E16,
+	{0x0113, 0x0300}:   0x1E15,
+	{0x0113, 0x0301}:   0x1E17,
+	{0x014C, 0x0300}:   0x1E50,
+	{0x014C, 0x0301}:   0x1E52,
+	{0x014D, 0x0300}:   0x1E51,
+	{0x014D, 0x0301}:   0x1E53,
+	{0x015A, 0x0307}:   0x1E64,
+	{0x015B, 0x0307}:   0x1E65,
+	{0x0160, 0x0307}:   0x1E66,
+	{0x0161, 0x0307}:   0x1E67,
+	{0x0168, 0x0301}:   0x1E78,
+	{0x0169, 0x0301}:   0x1E79,
+	{0x016A, 0x0308}:   0x1E7A,
+	{0x016B, 0x0308}:   0x1E7B,
+	{0x017F, 0x0307}:   0x1E9B,
+	{0x01A0, 0x0300}:   0x1EDC,
+	{0x01A0, 0x0301}:   0x1EDA,
+	{0x01A0, 0x0303}:   0x1EE0,
+	{0x01A0, 0x0309}:   0x1EDE,
+	{0x01A0, 0x0323}:   0x1EE2,
+	{0x01A1, 0x0300}:   0x1EDD,
+	{0x01A1, 0x0301}:   0x1EDB,
+	{0x01A1, 0x0303}:   0x1EE1,
+	{0x01A1, 0x0309}:   0x1EDF,
+	{0x01A1, 0x0323}:   0x1EE3,
+	{0x01AF, 0x0300}:   0x1EEA,
+	{0x01AF, 0x0301}:   0x1EE8,
+	{0x01AF, 0x0303}:   0x1EEE,
+	{0x01AF, 0x0309}:   0x1EEC,
+	{0x01AF, 0x0323}:   0x1EF0,
+	{0x01B0, 0x0300}:   0x1EEB,
+	{0x01B0, 0x0301}:   0x1EE9,
+	{0x01B0, 0x0303}:   0x1EEF,
+	{0x01B0, 0x0309}:   0x1EED,
+	{0x01B0, 0x0323}:   0x1EF1,
+	{0x01B7, 0x030C}:   0x01EE,
+	{0x01EA, 0x0304}:   0x01EC,
+	{0x01EB, 0x0304}:   0x01ED,
+	{0x0226, 0x0304}:   0x01E0,
+	{0x0227, 0x0304}:   0x01E1,
+	{0x0228, 0x0306}:   0x1E1C,
+	{0x0229, 0x0306}:   0x1E1D,
+	{0x022E, 0x0304}:   0x0230,
+	{0x022F, 0x0304}:   0x0231,
+	{0x0292, 0x030C}:   0x01EF,
+	{0x0391, 0x0300}:   0x1FBA,
+	{0x0391, 0x0301}:   0x0386,
+	{0x0391, 0x0304}:   0x1FB9,
+	{0x0391, 0x0306}:   0x1FB8,
+	{0x0391, 0x0313}:   0x1F08,
+	{0x0391, 0x0314}:   0x1F09,
+	{0x0391, 0x0345}:   0x1FBC,
+	{0x0395, 0x0300}:   0x1FC8,
+	{0x0395, 0x0301}:   0x0388,
+	{0x0395, 0x0313}:   0x1F18,
+	{0x0395, 0x0314}:   0x1F19,
+	{0x0397, 0x0300}:   0x1FCA,
+	{0x0397, 0x0301}:   0x0389,
+	{0x0397, 0x0313}:   0x1F28,
+	{0x0397, 0x0314}:   0x1F29,
+	{0x0397, 0x0345}:   0x1FCC,
+	{0x0399, 0x0300}:   0x1FDA,
+	{0x0399, 0x0301}:   0x038A,
+	{0x0399, 0x0304}:   0x1FD9,
+	{0x0399, 0x0306}:   0x1FD8,
+	{0x0399, 0x0308}:   0x03AA,
+	{0x0399, 0x0313}:   0x1F38,
+	{0x0399, 0x0314}:   0x1F39,
+	{0x039F, 0x0300}:   0x1FF8,
+	{0x039F, 0x0301}:   0x038C,
+	{0x039F, 0x0313}:   0x1F48,
+	{0x039F, 0x0314}:   0x1F49,
+	{0x03A1, 0x0314}:   0x1FEC,
+	{0x03A5, 0x0300}:   0x1FEA,
+	{0x03A5, 0x0301}:   0x038E,
+	{0x03A5, 0x0304}:   0x1FE9,
+	{0x03A5, 0x0306}:   0x1FE8,
+	{0x03A5, 0x0308}:   0x03AB,
+	{0x03A5, 0x0314}:   0x1F59,
+	{0x03A9, 0x0300}:   0x1FFA,
+	{0x03A9, 0x0301}:   0x038F,
+	{0x03A9, 0x0313}:   0x1F68,
+	{0x03A9, 0x0314}:   0x1F69,
+	{0x03A9, 0x0345}:   0x1FFC,
+	{0x03AC, 0x0345}:   0x1FB4,
+	{0x03AE, 0x0345}:   0x1FC4,
+	{0x03B1, 0x0300}:   0x1F70,
+	{0x03B1, 0x0301}:   0x03AC,
+	{0x03B1, 0x0304}:   0x1FB1,
+	{0x03B1, 0x0306}:   0x1FB0,
+	{0x03B1, 0x0313}:   0x1F00,
+	{0x03B1, 0x0314}:   0x1F01,
+	{0x03B1, 0x0342}:   0x1FB6,
+	{0x03B1, 0x0345}:   0x1FB3,
+	{0x03B5, 0x0300}:   0x1F72,
+	{0x03B5, 0x0301}:   0x03AD,
+	{0x03B5, 0x0313}:   0x1F10,
+	{0x03B5, 0x0314}:   0x1F11,
+	{0x03B7, 0x0300}:   0x1F74,
+	{0x03B7, 0x0301}:   0x03AE,
+	{0x03B7, 0x0313}:   0x1F20,
+	{0x03B7, 0x0314}:   0x1F21,
+	{0x03B7, 0x0342}:   0x1FC6,
+	{0x03B7, 0x0345}:   0x1FC3,
+	{0x03B9, 0x0300}:   0x1F76,
+	{0x03B9, 0x0301}:   0x03AF,
+	{0x03B9, 0x0304}:   0x1FD1,
+	{0x03B9, 0x0306}:   0x1FD0,
+	{0x03B9, 0x0308}:   0x03CA,
+	{0x03B9, 0x0313}:   0x1F30,
+	{0x03B9, 0x0314}:   0x1F31,
+	{0x03B9, 0x0342}:   0x1FD6,
+	{0x03BF, 0x0300}:   0x1F78,
+	{0x03BF, 0x0301}:   0x03CC,
+	{0x03BF, 0x0313}:   0x1F40,
+	{0x03BF, 0x0314}:   0x1F41,
+	{0x03C1, 0x0313}:   0x1FE4,
+	{0x03C1, 0x0314}:   0x1FE5,
+	{0x03C5, 0x0300}:   0x1F7A,
+	{0x03C5, 0x0301}:   0x03CD,
+	{0x03C5, 0x0304}:   0x1FE1,
+	{0x03C5, 0x0306}:   0x1FE0,
+	{0x03C5, 0x0308}:   0x03CB,
+	{0x03C5, 0x0313}:   0x1F50,
+	{0x03C5, 0x0314}:   0x1F51,
+	{0x03C5, 0x0342}:   0x1FE6,
+	{0x03C9, 0x0300}:   0x1F7C,
+	{0x03C9, 0x0301}:   0x03CE,
+	{0x03C9, 0x0313}:   0x1F60,
+	{0x03C9, 0x0314}:   0x1F61,
+	{0x03C9, 0x0342}:   0x1FF6,
+	{0x03C9, 0x0345}:   0x1FF3,
+	{0x03CA, 0x0300}:   0x1FD2,
+	{0x03CA, 0x0301}:   0x0390,
+	{0x03CA, 0x0342}:   0x1FD7,
+	{0x03CB, 0x0300}:   0x1FE2,
+	{0x03CB, 0x0301}:   0x03B0,
+	{0x03CB, 0x0342}:   0x1FE7,
+	{0x03CE, 0x0345}:   0x1FF4,
+	{0x03D2, 0x0301}:   0x03D3,
+	{0x03D2, 0x0308}:   0x03D4,
+	{0x0406, 0x0308}:   0x0407,
+	{0x0410, 0x0306}:   0x04D0,
+	{0x0410, 0x0308}:   0x04D2,
+	{0x0413, 0x0301}:   0x0403,
+	{0x0415, 0x0300}:   0x0400,
+	{0x0415, 0x0306}:   0x04D6,
+	{0x0415, 0x0308}:   0x0401,
+	{0x0416, 0x0306}:   0x04C1,
+	{0x0416, 0x0308}:   0x04DC,
+	{0x0417, 0x0308}:   0x04DE,
+	{0x0418, 0x0300}:   0x040D,
+	{0x0418, 0x0304}:   0x04E2,
+	{0x0418, 0x0306}:   0x0419,
+	{0x0418, 0x0308}:   0x04E4,
+	{0x041A, 0x0301}:   0x040C,
+	{0x041E, 0x0308}:   0x04E6,
+	{0x0423, 0x0304}:   0x04EE,
+	{0x0423, 0x0306}:   0x040E,
+	{0x0423, 0x0308}:   0x04F0,
+	{0x0423, 0x030B}:   0x04F2,
+	{0x0427, 0x0308}:   0x04F4,
+	{0x042B, 0x0308}:   0x04F8,
+	{0x042D, 0x0308}:   0x04EC,
+	{0x0430, 0x0306}:   0x04D1,
+	{0x0430, 0x0308}:   0x04D3,
+	{0x0433, 0x0301}:   0x0453,
+	{0x0435, 0x0300}:   0x0450,
+	{0x0435, 0x0306}:   0x04D7,
+	{0x0435, 0x0308}:   0x0451,
+	{0x0436, 0x0306}:   0x04C2,
+	{0x0436, 0x0308}:   0x04DD,
+	{0x0437, 0x0308}:   0x04DF,
+	{0x0438, 0x0300}:   0x045D,
+	{0x0438, 0x0304}:   0x04E3,
+	{0x0438, 0x0306}:   0x0439,
+	{0x0438, 0x0308}:   0x04E5,
+	{0x043A, 0x0301}:   0x045C,
+	{0x043E, 0x0308}:   0x04E7,
+	{0x0443, 0x0304}:   0x04EF,
+	{0x0443, 0x0306}:   0x045E,
+	{0x0443, 0x0308}:   0x04F1,
+	{0x0443, 0x030B}:   0x04F3,
+	{0x0447, 0x0308}:   0x04F5,
+	{0x044B, 0x0308}:   0x04F9,
+	{0x044D, 0x0308}:   0x04ED,
+	{0x0456, 0x0308}:   0x0457,
+	{0x0474, 0x030F}:   0x0476,
+	{0x0475, 0x030F}:   0x0477,
+	{0x04D8, 0x0308}:   0x04DA,
+	{0x04D9, 0x0308}:   0x04DB,
+	{0x04E8, 0x0308}:   0x04EA,
+	{0x04E9, 0x0308}:   0x04EB,
+	{0x0627, 0x0653}:   0x0622,
+	{0x0627, 0x0654}:   0x0623,
+	{0x0627, 0x0655}:   0x0625,
+	{0x0648, 0x0654}:   0x0624,
+	{0x064A, 0x0654}:   0x0626,
+	{0x06C1, 0x0654}:   0x06C2,
+	{0x06D2, 0x0654}:   0x06D3,
+	{0x06D5, 0x0654}:   0x06C0,
+	{0x0928, 0x093C}:   0x0929,
+	{0x0930, 0x093C}:   0x0931,
+	{0x0933, 0x093C}:   0x0934,
+	{0x09C7, 0x09BE}:   0x09CB,
+	{0x09C7, 0x09D7}:   0x09CC,
+	{0x0B47, 0x0B3E}:   0x0B4B,
+	{0x0B47, 0x0B56}:   0x0B48,
+	{0x0B47, 0x0B57}:   0x0B4C,
+	{0x0B92, 0x0BD7}:   0x0B94,
+	{0x0BC6, 0x0BBE}:   0x0BCA,
+	{0x0BC6, 0x0BD7}:   0x0BCC,
+	{0x0BC7, 0x0BBE}:   0x0BCB,
+	{0x0C46, 0x0C56}:   0x0C48,
+	{0x0CBF, 0x0CD5}:   0x0CC0,
+	{0x0CC6, 0x0CC2}:   0x0CCA,
+	{0x0CC6, 0x0CD5}:   0x0CC7,
+	{0x0CC6, 0x0CD6}:   0x0CC8,
+	{0x0CCA, 0x0CD5}:   0x0CCB,
+	{0x0D46, 0x0D3E}:   0x0D4A,
+	{0x0D46, 0x0D57}:   0x0D4C,
+	{0x0D47, 0x0D3E}:   0x0D4B,
+	{0x0DD9, 0x0DCA}:   0x0DDA,
+	{0x0DD9, 0x0DCF}:   0x0DDC,
+	{0x0DD9, 0x0DDF}:   0x0DDE,
+	{0x0DDC, 0x0DCA}:   0x0DDD,
+	{0x1025, 0x102E}:   0x1026,
+	{0x1B05, 0x1B35}:   0x1B06,
+	{0x1B07, 0x1B35}:   0x1B08,
+	{0x1B09, 0x1B35}:   0x1B0A,
+	{0x1B0B, 0x1B35}:   0x1B0C,
+	{0x1B0D, 0x1B35}:   0x1B0E,
+	{0x1B11, 0x1B35}:   0x1B12,
+	{0x1B3A, 0x1B35}:   0x1B3B,
+	{0x1B3C, 0x1B35}:   0x1B3D,
+	{0x1B3E, 0x1B35}:   0x1B40,
+	{0x1B3F, 0x1B35}:   0x1B41,
+	{0x1B42, 0x1B35}:   0x1B43,
+	{0x1E36, 0x0304}:   0x1E38,
+	{0x1E37, 0x0304}:   0x1E39,
+	{0x1E5A, 0x0304}:   0x1E5C,
+	{0x1E5B, 0x0304}:   0x1E5D,
+	{0x1E62, 0x0307}:   0x1E68,
+	{0x1E63, 0x0307}:   0x1E69,
+	{0x1EA0, 0x0302}:   0x1EAC,
+	{0x1EA0, 0x0306}:   0x1EB6,
+	{0x1EA1, 0x0302}:   0x1EAD,
+	{0x1EA1, 0x0306}:   0x1EB7,
+	{0x1EB8, 0x0302}:   0x1EC6,
+	{0x1EB9, 0x0302}:   0x1EC7,
+	{0x1ECC, 0x0302}:   0x1ED8,
+	{0x1ECD, 0x0302}:   0x1ED9,
+	{0x1F00, 0x0300}:   0x1F02,
+	{0x1F00, 0x0301}:   0x1F04,
+	{0x1F00, 0x0342}:   0x1F06,
+	{0x1F00, 0x0345}:   0x1F80,
+	{0x1F01, 0x0300}:   0x1F03,
+	{0x1F01, 0x0301}:   0x1F05,
+	{0x1F01, 0x0342}:   0x1F07,
+	{0x1F01, 0x0345}:   0x1F81,
+	{0x1F02, 0x0345}:   0x1F82,
+	{0x1F03, 0x0345}:   0x1F83,
+	{0x1F04, 0x0345}:   0x1F84,
+	{0x1F05, 0x0345}:   0x1F85,
+	{0x1F06, 0x0345}:   0x1F86,
+	{0x1F07, 0x0345}:   0x1F87,
+	{0x1F08, 0x0300}:   0x1F0A,
+	{0x1F08, 0x0301}:   0x1F0C,
+	{0x1F08, 0x0342}:   0x1F0E,
+	{0x1F08, 0x0345}:   0x1F88,
+	{0x1F09, 0x0300}:   0x1F0B,
+	{0x1F09, 0x0301}:   0x1F0D,
+	{0x1F09, 0x0342}:   0x1F0F,
+	{0x1F09, 0x0345}:   0x1F89,
+	{0x1F0A, 0x0345}:   0x1F8A,
+	{0x1F0B, 0x0345}:   0x1F8B,
+	{0x1F0C, 0x0345}:   0x1F8C,
+	{0x1F0D, 0x0345}:   0x1F8D,
+	{0x1F0E, 0x0345}:   0x1F8E,
+	{0x1F0F, 0x0345}:   0x1F8F,
+	{0x1F10, 0x0300}:   0x1F12,
+	{0x1F10, 0x0301}:   0x1F14,
+	{0x1F11, 0x0300}:   0x1F13,
+	{0x1F11, 0x0301}:   0x1F15,
+	{0x1F18, 0x0300}:   0x1F1A,
+	{0x1F18, 0x0301}:   0x1F1C,
+	{0x1F19, 0x0300}:   0x1F1B,
+	{0x1F19, 0x0301}:   0x1F1D,
+	{0x1F20, 0x0300}:   0x1F22,
+	{0x1F20, 0x0301}:   0x1F24,
+	{0x1F20, 0x0342}:   0x1F26,
+	{0x1F20, 0x0345}:   0x1F90,
+	{0x1F21, 0x0300}:   0x1F23,
+	{0x1F21, 0x0301}:   0x1F25,
+	{0x1F21, 0x0342}:   0x1F27,
+	{0x1F21, 0x0345}:   0x1F91,
+	{0x1F22, 0x0345}:   0x1F92,
+	{0x1F23, 0x0345}:   0x1F93,
+	{0x1F24, 0x0345}:   0x1F94,
+	{0x1F25, 0x0345}:   0x1F95,
+	{0x1F26, 0x0345}:   0x1F96,
+	{0x1F27, 0x0345}:   0x1F97,
+	{0x1F28, 0x0300}:   0x1F2A,
+	{0x1F28, 0x0301}:   0x1F2C,
+	{0x1F28, 0x0342}:   0x1F2E,
+	{0x1F28, 0x0345}:   0x1F98,
+	{0x1F29, 0x0300}:   0x1F2B,
+	{0x1F29, 0x0301}:   0x1F2D,
+	{0x1F29, 0x0342}:   0x1F2F,
+	{0x1F29, 0x0345}:   0x1F99,
+	{0x1F2A, 0x0345}:   0x1F9A,
+	{0x1F2B, 0x0345}:   0x1F9B,
+	{0x1F2C, 0x0345}:   0x1F9C,
+	{0x1F2D, 0x0345}:   0x1F9D,
+	{0x1F2E, 0x0345}:   0x1F9E,
+	{0x1F2F, 0x0345}:   0x1F9F,
+	{0x1F30, 0x0300}:   0x1F32,
+	{0x1F30, 0x0301}:   0x1F34,
+	{0x1F30, 0x0342}:   0x1F36,
+	{0x1F31, 0x0300}:   0x1F33,
+	{0x1F31, 0x0301}:   0x1F35,
+	{0x1F31, 0x0342}:   0x1F37,
+	{0x1F38, 0x0300}:   0x1F3A,
+	{0x1F38, 0x0301}:   0x1F3C,
+	{0x1F38, 0x0342}:   0x1F3E,
+	{0x1F39, 0x0300}:   0x1F3B,
+	{0x1F39, 0x0301}:   0x1F3D,
+	{0x1F39, 0x0342}:   0x1F3F,
+	{0x1F40, 0x0300}:   0x1F42,
+	{0x1F40, 0x0301}:   0x1F44,
+	{0x1F41, 0x0300}:   0x1F43,
+	{0x1F41, 0x0301}:   0x1F45,
+	{0x1F48, 0x0300}:   0x1F4A,
+	{0x1F48, 0x0301}:   0x1F4C,
+	{0x1F49, 0x0300}:   0x1F4B,
+	{0x1F49, 0x0301}:   0x1F4D,
+	{0x1F50, 0x0300}:   0x1F52,
+	{0x1F50, 0x0301}:   0x1F54,
+	{0x1F50, 0x0342}:   0x1F56,
+	{0x1F51, 0x0300}:   0x1F53,
+	{0x1F51, 0x0301}:   0x1F55,
+	{0x1F51, 0x0342}:   0x1F57,
+	{0x1F59, 0x0300}:   0x1F5B,
+	{0x1F59, 0x0301}:   0x1F5D,
+	{0x1F59, 0x0342}:   0x1F5F,
+	{0x1F60, 0x0300}:   0x1F62,
+	{0x1F60, 0x0301}:   0x1F64,
+	{0x1F60, 0x0342}:   0x1F66,
+	{0x1F60, 0x0345}:   0x1FA0,
+	{0x1F61, 0x0300}:   0x1F63,
+	{0x1F61, 0x0301}:   0x1F65,
+	{0x1F61, 0x0342}:   0x1F67,
+	{0x1F61, 0x0345}:   0x1FA1,
+	{0x1F62, 0x0345}:   0x1FA2,
+	{0x1F63, 0x0345}:   0x1FA3,
+	{0x1F64, 0x0345}:   0x1FA4,
+	{0x1F65, 0x0345}:   0x1FA5,
+	{0x1F66, 0x0345}:   0x1FA6,
+	{0x1F67, 0x0345}:   0x1FA7,
+	{0x1F68, 0x0300}:   0x1F6A,
+	{0x1F68, 0x0301}:   0x1F6C,
+	{0x1F68, 0x0342}:   0x1F6E,
+	{0x1F68, 0x0345}:   0x1FA8,
+	{0x1F69, 0x0300}:   0x1F6B,
+	{0x1F69, 0x0301}:   0x1F6D,
+	{0x1F69, 0x0342}:   0x1F6F,
+	{0x1F69, 0x0345}:   0x1FA9,
+	{0x1F6A, 0x0345}:   0x1FAA,
+	{0x1F6B, 0x0345}:   0x1FAB,
+	{0x1F6C, 0x0345}:   0x1FAC,
+	{0x1F6D, 0x0345}:   0x1FAD,
+	{0x1F6E, 0x0345}:   0x1FAE,
+	{0x1F6F, 0x0345}:   0x1FAF,
+	{0x1F70, 0x0345}:   0x1FB2,
+	{0x1F74, 0x0345}:   0x1FC2,
+	{0x1F7C, 0x0345}:   0x1FF2,
+	{0x1FB6, 0x0345}:   0x1FB7,
+	{0x1FBF, 0x0300}:   0x1FCD,
+	{0x1FBF, 0x0301}:   0x1FCE,
+	{0x1FBF, 0x0342}:   0x1FCF,
+	{0x1FC6, 0x0345}:   0x1FC7,
+	{0x1FF6, 0x0345}:   0x1FF7,
+	{0x1FFE, 0x0300}:   0x1FDD,
+	{0x1FFE, 0x0301}:   0x1FDE,
+	{0x1FFE, 0x0342}:   0x1FDF,
+	{0x2190, 0x0338}:   0x219A,
+	{0x2192, 0x0338}:   0x219B,
+	{0x2194, 0x0338}:   0x21AE,
+	{0x21D0, 0x0338}:   0x21CD,
+	{0x21D2, 0x0338}:   0x21CF,
+	{0x21D4, 0x0338}:   0x21CE,
+	{0x2203, 0x0338}:   0x2204,
+	{0x2208, 0x0338}:   0x2209,
+	{0x220B, 0x0338}:   0x220C,
+	{0x2223, 0x0338}:   0x2224,
+	{0x2225, 0x0338}:   0x2226,
+	{0x223C, 0x0338}:   0x2241,
+	{0x2243, 0x0338}:   0x2244,
+	{0x2245, 0x0338}:   0x2247,
+	{0x2248, 0x0338}:   0x2249,
+	{0x224D, 0x0338}:   0x226D,
+	{0x2261, 0x0338}:   0x2262,
+	{0x2264, 0x0338}:   0x2270,
+	{0x2265, 0x0338}:   0x2271,
+	{0x2272, 0x0338}:   0x2274,
+	{0x2273, 0x0338}:   0x2275,
+	{0x2276, 0x0338}:   0x2278,
+	{0x2277, 0x0338}:   0x2279,
+	{0x227A, 0x0338}:   0x2280,
+	{0x227B, 0x0338}:   0x2281,
+	{0x227C, 0x0338}:   0x22E0,
+	{0x227D, 0x0338}:   0x22E1,
+	{0x2282, 0x0338}:   0x2284,
+	{0x2283, 0x0338}:   0x2285,
+	{0x2286, 0x0338}:   0x2288,
+	{0x2287, 0x0338}:   0x2289,
+	{0x2291, 0x0338}:   0x22E2,
+	{0x2292, 0x0338}:   0x22E3,
+	{0x22A2, 0x0338}:   0x22AC,
+	{0x22A8, 0x0338}:   0x22AD,
+	{0x22A9, 0x0338}:   0x22AE,
+	{0x22AB, 0x0338}:   0x22AF,
+	{0x22B2, 0x0338}:   0x22EA,
+	{0x22B3, 0x0338}:   0x22EB,
+	{0x22B4, 0x0338}:   0x22EC,
+	{0x22B5, 0x0338}:   0x22ED,
+	{0x3046, 0x3099}:   0x3094,
+	{0x304B, 0x3099}:   0x304C,
+	{0x304D, 0x3099}:   0x304E,
+	{0x304F, 0x3099}:   0x3050,
+	{0x3051, 0x3099}:   0x3052,
+	{0x3053, 0x3099}:   0x3054,
+	{0x3055, 0x3099}:   0x3056,
+	{0x3057, 0x3099}:   0x3058,
+	{0x3059, 0x3099}:   0x305A,
+	{0x305B, 0x3099}:   0x305C,
+	{0x305D, 0x3099}:   0x305E,
+	{0x305F, 0x3099}:   0x3060,
+	{0x3061, 0x3099}:   0x3062,
+	{0x3064, 0x3099}:   0x3065,
+	{0x3066, 0x3099}:   0x3067,
+	{0x3068, 0x3099}:   0x3069,
+	{0x306F, 0x3099}:   0x3070,
+	{0x306F, 0x309A}:   0x3071,
+	{0x3072, 0x3099}:   0x3073,
+	{0x3072, 0x309A}:   0x3074,
+	{0x3075, 0x3099}:   0x3076,
+	{0x3075, 0x309A}:   0x3077,
+	{0x3078, 0x3099}:   0x3079,
+	{0x3078, 0x309A}:   0x307A,
+	{0x307B, 0x3099}:   0x307C,
+	{0x307B, 0x309A}:   0x307D,
+	{0x309D, 0x3099}:   0x309E,
+	{0x30A6, 0x3099}:   0x30F4,
+	{0x30AB, 0x3099}:   0x30AC,
+	{0x30AD, 0x3099}:   0x30AE,
+	{0x30AF, 0x3099}:   0x30B0,
+	{0x30B1, 0x3099}:   0x30B2,
+	{0x30B3, 0x3099}:   0x30B4,
+	{0x30B5, 0x3099}:   0x30B6,
+	{0x30B7, 0x3099}:   0x30B8,
+	{0x30B9, 0x3099}:   0x30BA,
+	{0x30BB, 0x3099}:   0x30BC,
+	{0x30BD, 0x3099}:   0x30BE,
+	{0x30BF, 0x3099}:   0x30C0,
+	{0x30C1, 0x3099}:   0x30C2,
+	{0x30C4, 0x3099}:   0x30C5,
+	{0x30C6, 0x3099}:   0x30C7,
+	{0x30C8, 0x3099}:   0x30C9,
+	{0x30CF, 0x3099}:   0x30D0,
+	{0x30CF, 0x309A}:   0x30D1,
+	{0x30D2, 0x3099}:   0x30D3,
+	{0x30D2, 0x309A}:   0x30D4,
+	{0x30D5, 0x3099}:   0x30D6,
+	{0x30D5, 0x309A}:   0x30D7,
+	{0x30D8, 0x3099}:   0x30D9,
+	{0x30D8, 0x309A}:   0x30DA,
+	{0x30DB, 0x3099}:   0x30DC,
+	{0x30DB, 0x309A}:   0x30DD,
+	{0x30EF, 0x3099}:   0x30F7,
+	{0x30F0, 0x3099}:   0x30F8,
+	{0x30F1, 0x3099}:   0x30F9,
+	{0x30F2, 0x3099}:   0x30FA,
+	{0x30FD, 0x3099}:   0x30FE,
+	{0x11099, 0x110BA}: 0x1109A,
+	{0x1109B, 0x110BA}: 0x1109C,
+	{0x110A5, 0x110BA}: 0x110AB,
+	{0x11131, 0x11127}: 0x1112E,
+	{0x11132, 0x11127}: 0x1112F,
+	{0x11347, 0x1133E}: 0x1134B,
+	{0x11347, 0x11357}: 0x1134C,
+	{0x114B9, 0x114B0}: 0x114BC,
+	{0x114B9, 0x114BA}: 0x114BB,
+	{0x114B9, 0x114BD}: 0x114BE,
+	{0x115B8, 0x115AF}: 0x115BA,
+	{0x115B9, 0x115AF}: 0x115BB,
+	{0x11935, 0x11930}: 0x11938,
+}
+
+// combiningClasses are the ranges of characters with a non-zero canonical combining class
+var combiningClasses = []struct {
+	lo, hi rune
+	class  uint8
+}{
+	{0x0300, 0x0314, 230},
+	{0x0315, 0x0315, 232},
+	{0x0316, 0x0319, 220},
+	{0x031A, 0x031A, 232},
+	{0x031B, 0x031B, 216},
+	{0x031C, 0x0320, 220},
+	{0x0321, 0x0322, 202},
+	{0x0323, 0x0326, 220},
+	{0x0327, 0x0328, 202},
+	{0x0329, 0x0333, 220},
+	{0x0334, 0x0338, 1},
+	{0x0339, 0x033C, 220},
+	{0x033D, 0x0344, 230},
+	{0x0345, 0x0345, 240},
+	{0x0346, 0x0346, 230},
+	{0x0347, 0x0349, 220},
+	{0x034A, 0x034C, 230},
+	{0x034D, 0x034E, 220},
+	{0x0350, 0x0352, 230},
+	{0x0353, 0x0356, 220},
+	{0x0357, 0x0357, 230},
+	{0x0358, 0x0358, 232},
+	{0x0359, 0x035A, 220},
+	{0x035B, 0x035B, 230},
+	{0x035C, 0x035C, 233},
+	{0x035D, 0x035E, 234},
+	{0x035F, 0x035F, 233},
+	{0x0360, 0x0361, 234},
+	{0x0362, 0x0362, 233},
+	{0x0363, 0x036F, 230},
+	{0x0483, 0x0487, 230},
+	{0x0591, 0x0591, 220},
+	{0x0592, 0x0595, 230},
+	{0x0596, 0x0596, 220},
+	{0x0597, 0x0599, 230},
+	{0x059A, 0x059A, 222},
+	{0x059B, 0x059B, 220},
+	{0x059C, 0x05A1, 230},
+	{0x05A2, 0x05A7, 220},
+	{0x05A8, 0x05A9, 230},
+	{0x05AA, 0x05AA, 220},
+	{0x05AB, 0x05AC, 230},
+	{0x05AD, 0x05AD, 222},
+	{0x05AE, 0x05AE, 228},
+	{0x05AF, 0x05AF, 230},
+	{0x05B0, 0x05B0, 10},
+	{0x05B1, 0x05B1, 11},
+	{0x05B2, 0x05B2, 12},
+	{0x05B3, 0x05B3, 13},
+	{0x05B4, 0x05B4, 14},
+	{0x05B5, 0x05B5, 15},
+	{0x05B6, 0x05B6, 16},
+	{0x05B7, 0x05B7, 17},
+	{0x05B8, 0x05B8, 18},
+	{0x05B9, 0x05BA, 19},
+	{0x05BB, 0x05BB, 20},
+	{0x05BC, 0x05BC, 21},
+	{0x05BD, 0x05BD, 22},
+	{0x05BF, 0x05BF, 23},
+	{0x05C1, 0x05C1, 24},
+	{0x05C2, 0x05C2, 25},
+	{0x05C4, 0x05C4, 230},
+	{0x05C5, 0x05C5, 220},
+	{0x05C7, 0x05C7, 18},
+	{0x0610, 0x0617, 230},
+	{0x0618, 0x0618, 30},
+	{0x0619, 0x0619, 31},
+	{0x061A, 0x061A, 32},
+	{0x064B, 0x064B, 27},
+	{0x064C, 0x064C, 28},
+	{0x064D, 0x064D, 29},
+	{0x064E, 0x064E, 30},
+	{0x064F, 0x064F, 31},
+	{0x0650, 0x0650, 32},
+	{0x0651, 0x0651, 33},
+	{0x0652, 0x0652, 34},
+	{0x0653, 0x0654, 230},
+	{0x0655, 0x0656, 220},
+	{0x0657, 0x065B, 230},
+	{0x065C, 0x065C, 220},
+	{0x065D, 0x065E, 230},
+	{0x065F, 0x065F, 220},
+	{0x0670, 0x0670, 35},
+	{0x06D6, 0x06DC, 230},
+	{0x06DF, 0x06E2, 230},
+	{0x06E3, 0x06E3, 220},
+	{0x06E4, 0x06E4, 230},
+	{0x06E7, 0x06E8, 230},
+	{0x06EA, 0x06EA, 220},
+	{0x06EB, 0x06EC, 230},
+	{0x06ED, 0x06ED, 220},
+	{0x0711, 0x0711, 36},
+	{0x0730, 0x0730, 230},
+	{0x0731, 0x0731, 220},
+	{0x0732, 0x0733, 230},
+	{0x0734, 0x0734, 220},
+	{0x0735, 0x0736, 230},
+	{0x0737, 0x0739, 220},
+	{0x073A, 0x073A, 230},
+	{0x073B, 0x073C, 220},
+	{0x073D, 0x073D, 230},
+	{0x073E, 0x073E, 220},
+	{0x073F, 0x0741, 230},
+	{0x0742, 0x0742, 220},
+	{0x0743, 0x0743, 230},
+	{0x0744, 0x0744, 220},
+	{0x0745, 0x0745, 230},
+	{0x0746, 0x0746, 220},
+	{0x0747, 0x0747, 230},
+	{0x0748, 0x0748, 220},
+	{0x0749, 0x074A, 230},
+	{0x07EB, 0x07F1, 230},
+	{0x07F2, 0x07F2, 220},
+	{0x07F3, 0x07F3, 230},
+	{0x07FD, 0x07FD, 220},
+	{0x0816, 0x0819, 230},
+	{0x081B, 0x0823, 230},
+	{0x0825, 0x0827, 230},
+	{0x0829, 0x082D, 230},
+	{0x0859, 0x085B, 220},
+	{0x0898, 0x0898, 230},
+	{0x0899, 0x089B, 220},
+	{0x089C, 0x089F, 230},
+	{0x08CA, 0x08CE, 230},
+	{0x08CF, 0x08D3, 220},
+	{0x08D4, 0x08E1, 230},
+	{0x08E3, 0x08E3, 220},
+	{0x08E4, 0x08E5, 230},
+	{0x08E6, 0x08E6, 220},
+	{0x08E7, 0x08E8, 230},
+	{0x08E9, 0x08E9, 220},
+	{0x08EA, 0x08EC, 230},
+	{0x08ED, 0x08EF, 220},
+	{0x08F0, 0x08F0, 27},
+	{0x08F1, 0x08F1, 28},
+	{0x08F2, 0x08F2, 29},
+	{0x08F3, 0x08F5, 230},
+	{0x08F6, 0x08F6, 220},
+	{0x08F7, 0x08F8, 230},
+	{0x08F9, 0x08FA, 220},
+	{0x08FB, 0x08FF, 230},
+	{0x093C, 0x093C, 7},
+	{0x094D, 0x094D, 9},
+	{0x0951, 0x0951, 230},
+	{0x0952, 0x0952, 220},
+	{0x0953, 0x0954, 230},
+	{0x09BC, 0x09BC, 7},
+	{0x09CD, 0x09CD, 9},
+	{0x09FE, 0x09FE, 230},
+	{0x0A3C, 0x0A3C, 7},
+	{0x0A4D, 0x0A4D, 9},
+	{0x0ABC, 0x0ABC, 7},
+	{0x0ACD, 0x0ACD, 9},
+	{0x0B3C, 0x0B3C, 7},
+	{0x0B4D, 0x0B4D, 9},
+	{0x0BCD, 0x0BCD, 9},
+	{0x0C3C, 0x0C3C, 7},
+	{0x0C4D, 0x0C4D, 9},
+	{0x0C55, 0x0C55, 84},
+	{0x0C56, 0x0C56, 91},
+	{0x0CBC, 0x0CBC, 7},
+	{0x0CCD, 0x0CCD, 9},
+	{0x0D3B, 0x0D3C, 9},
+	{0x0D4D, 0x0D4D, 9},
+	{0x0DCA, 0x0DCA, 9},
+	{0x0E38, 0x0E39, 103},
+	{0x0E3A, 0x0E3A, 9},
+	{0x0E48, 0x0E4B, 107},
+	{0x0EB8, 0x0EB9, 118},
+	{0x0EBA, 0x0EBA, 9},
+	{0x0EC8, 0x0ECB, 122},
+	{0x0F18, 0x0F19, 220},
+	{0x0F35, 0x0F35, 220},
+	{0x0F37, 0x0F37, 220},
+	{0x0F39, 0x0F39, 216},
+	{0x0F71, 0x0F71, 129},
+	{0x0F72, 0x0F72, 130},
+	{0x0F74, 0x0F74, 132},
+	{0x0F7A, 0x0F7D, 130},
+	{0x0F80, 0x0F80, 130},
+	{0x0F82, 0x0F83, 230},
+	{0x0F84, 0x0F84, 9},
+	{0x0F86, 0x0F87, 230},
+	{0x0FC6, 0x0FC6, 220},
+	{0x1037, 0x1037, 7},
+	{0x1039, 0x103A, 9},
+	{0x108D, 0x108D, 220},
+	{0x135D, 0x135F, 230},
+	{0x1714, 0x1715, 9},
+	{0x1734, 0x1734, 9},
+	{0x17D2, 0x17D2, 9},
+	{0x17DD, 0x17DD, 230},
+	{0x18A9, 0x18A9, 228},
+	{0x1939, 0x1939, 222},
+	{0x193A, 0x193A, 230},
+	{0x193B, 0x193B, 220},
+	{0x1A17, 0x1A17, 230},
+	{0x1A18, 0x1A18, 220},
+	{0x1A60, 0x1A60, 9},
+	{0x1A75, 0x1A7C, 230},
+	{0x1A7F, 0x1A7F, 220},
+	{0x1AB0, 0x1AB4, 230},
+	{0x1AB5, 0x1ABA, 220},
+	{0x1ABB, 0x1ABC, 230},
+	{0x1ABD, 0x1ABD, 220},
+	{0x1ABF, 0x1AC0, 220},
+	{0x1AC1, 0x1AC2, 230},
+	{0x1AC3, 0x1AC4, 220},
+	{0x1AC5, 0x1AC9, 230},
+	{0x1ACA, 0x1ACA, 220},
+	{0x1ACB, 0x1ACE, 230},
+	{0x1B34, 0x1B34, 7},
+	{0x1B44, 0x1B44, 9},
+	{0x1B6B, 0x1B6B, 230},
+	{0x1B6C, 0x1B6C, 220},
+	{0x1B6D, 0x1B73, 230},
+	{0x1BAA, 0x1BAB, 9},
+	{0x1BE6, 0x1BE6, 7},
+	{0x1BF2, 0x1BF3, 9},
+	{0x1C37, 0x1C37, 7},
+	{0x1CD0, 0x1CD2, 230},
+	{0x1CD4, 0x1CD4, 1},
+	{0x1CD5, 0x1CD9, 220},
+	{0x1CDA, 0x1CDB, 230},
+	{0x1CDC, 0x1CDF, 220},
+	{0x1CE0, 0x1CE0, 230},
+	{0x1CE2, 0x1CE8, 1},
+	{0x1CED, 0x1CED, 220},
+	{0x1CF4, 0x1CF4, 230},
+	{0x1CF8, 0x1CF9, 230},
+	{0x1DC0, 0x1DC1, 230},
+	{0x1DC2, 0x1DC2, 220},
+	{0x1DC3, 0x1DC9, 230},
+	{0x1DCA, 0x1DCA, 220},
+	{0x1DCB, 0x1DCC, 230},
+	{0x1DCD, 0x1DCD, 234},
+	{0x1DCE, 0x1DCE, 214},
+	{0x1DCF, 0x1DCF, 220},
+	{0x1DD0, 0x1DD0, 202},
+	{0x1DD1, 0x1DF5, 230},
+	{0x1DF6, 0x1DF6, 232},
+	{0x1DF7, 0x1DF8, 228},
+	{0x1DF9, 0x1DF9, 220},
+	{0x1DFA, 0x1DFA, 218},
+	{0x1DFB, 0x1DFB, 230},
+	{0x1DFC, 0x1DFC, 233},
+	{0x1DFD, 0x1DFD, 220},
+	{0x1DFE, 0x1DFE, 230},
+	{0x1DFF, 0x1DFF, 220},
+	{0x20D0, 0x20D1, 230},
+	{0x20D2, 0x20D3, 1},
+	{0x20D4, 0x20D7, 230},
+	{0x20D8, 0x20DA, 1},
+	{0x20DB, 0x20DC, 230},
+	{0x20E1, 0x20E1, 230},
+	{0x20E5, 0x20E6, 1},
+	{0x20E7, 0x20E7, 230},
+	{0x20E8, 0x20E8, 220},
+	{0x20E9, 0x20E9, 230},
+	{0x20EA, 0x20EB, 1},
+	{0x20EC, 0x20EF, 220},
+	{0x20F0, 0x20F0, 230},
+	{0x2CEF, 0x2CF1, 230},
+	{0x2D7F, 0x2D7F, 9},
+	{0x2DE0, 0x2DFF, 230},
+	{0x302A, 0x302A, 218},
+	{0x302B, 0x302B, 228},
+	{0x302C, 0x302C, 232},
+	{0x302D, 0x302D, 222},
+	{0x302E, 0x302F, 224},
+	{0x3099, 0x309A, 8},
+	{0xA66F, 0xA66F, 230},
+	{0xA674, 0xA67D, 230},
+	{0xA69E, 0xA69F, 230},
+	{0xA6F0, 0xA6F1, 230},
+	{0xA806, 0xA806, 9},
+	{0xA82C, 0xA82C, 9},
+	{0xA8C4, 0xA8C4, 9},
+	{0xA8E0, 0xA8F1, 230},
+	{0xA92B, 0xA92D, 220},
+	{0xA953, 0xA953, 9},
+	{0xA9B3, 0xA9B3, 7},
+	{0xA9C0, 0xA9C0, 9},
+	{0xAAB0, 0xAAB0, 230},
+	{0xAAB2, 0xAAB3, 230},
+	{0xAAB4, 0xAAB4, 220},
+	{0xAAB7, 0xAAB8, 230},
+	{0xAABE, 0xAABF, 230},
+	{0xAAC1, 0xAAC1, 230},
+	{0xAAF6, 0xAAF6, 9},
+	{0xABED, 0xABED, 9},
+	{0xFB1E, 0xFB1E, 26},
+	{0xFE20, 0xFE26, 230},
+	{0xFE27, 0xFE2D, 220},
+	{0xFE2E, 0xFE2F, 230},
+	{0x101FD, 0x101FD, 220},
+	{0x102E0, 0x102E0, 220},
+	{0x10376, 0x1037A, 230},
+	{0x10A0D, 0x10A0D, 220},
+	{0x10A0F, 0x10A0F, 230},
+	{0x10A38, 0x10A38, 230},
+	{0x10A39, 0x10A39, 1},
+	{0x10A3A, 0x10A3A, 220},
+	{0x10A3F, 0x10A3F, 9},
+	{0x10AE5, 0x10AE5, 230},
+	{0x10AE6, 0x10AE6, 220},
+	{0x10D24, 0x10D27, 230},
+	{0x10EAB, 0x10EAC, 230},
+	{0x10F46, 0x10F47, 220},
+	{0x10F48, 0x10F4A, 230},
+	{0x10F4B, 0x10F4B, 220},
+	{0x10F4C, 0x10F4C, 230},
+	{0x10F4D, 0x10F50, 220},
+	{0x10F82, 0x10F82, 230},
+	{0x10F83, 0x10F83, 220},
+	{0x10F84, 0x10F84, 230},
+	{0x10F85, 0x10F85, 220},
+	{0x11046, 0x11046, 9},
+	{0x11070, 0x11070, 9},
+	{0x1107F, 0x1107F, 9},
+	{0x110B9, 0x110B9, 9},
+	{0x110BA, 0x110BA, 7},
+	{0x11100, 0x11102, 230},
+	{0x11133, 0x11134, 9},
+	{0x11173, 0x11173, 7},
+	{0x111C0, 0x111C0, 9},
+	{0x111CA, 0x111CA, 7},
+	{0x11235, 0x11235, 9},
+	{0x11236, 0x11236, 7},
+	{0x112E9, 0x112E9, 7},
+	{0x112EA, 0x112EA, 9},
+	{0x1133B, 0x1133C, 7},
+	{0x1134D, 0x1134D, 9},
+	{0x11366, 0x1136C, 230},
+	{0x11370, 0x11374, 230},
+	{0x11442, 0x11442, 9},
+	{0x11446, 0x11446, 7},
+	{0x1145E, 0x1145E, 230},
+	{0x114C2, 0x114C2, 9},
+	{0x114C3, 0x114C3, 7},
+	{0x115BF, 0x115BF, 9},
+	{0x115C0, 0x115C0, 7},
+	{0x1163F, 0x1163F, 9},
+	{0x116B6, 0x116B6, 9},
+	{0x116B7, 0x116B7, 7},
+	{0x1172B, 0x1172B, 9},
+	{0x11839, 0x11839, 9},
+	{0x1183A, 0x1183A, 7},
+	{0x1193D, 0x1193E, 9},
+	{0x11943, 0x11943, 7},
+	{0x119E0, 0x119E0, 9},
+	{0x11A34, 0x11A34, 9},
+	{0x11A47, 0x11A47, 9},
+	{0x11A99, 0x11A99, 9},
+	{0x11C3F, 0x11C3F, 9},
+	{0x11D42, 0x11D42, 7},
+	{0x11D44, 0x11D45, 9},
+	{0x11D97, 0x11D97, 9},
+	{0x16AF0, 0x16AF4, 1},
+	{0x16B30, 0x16B36, 230},
+	{0x16FF0, 0x16FF1, 6},
+	{0x1BC9E, 0x1BC9E, 1},
+	{0x1D165, 0x1D166, 216},
+	{0x1D167, 0x1D169, 1},
+	{0x1D16D, 0x1D16D, 226},
+	{0x1D16E, 0x1D172, 216},
+	{0x1D17B, 0x1D182, 220},
+	{0x1D185, 0x1D189, 230},
+	{0x1D18A, 0x1D18B, 220},
+	{0x1D1AA, 0x1D1AD, 230},
+	{0x1D242, 0x1D244, 230},
+	{0x1E000, 0x1E006, 230},
+	{0x1E008, 0x1E018, 230},
+	{0x1E01B, 0x1E021, 230},
+	{0x1E023, 0x1E024, 230},
+	{0x1E026, 0x1E02A, 230},
+	{0x1E130, 0x1E136, 230},
+	{0x1E2AE, 0x1E2AE, 230},
+	{0x1E2EC, 0x1E2EF, 230},
+	{0x1E8D0, 0x1E8D6, 220},
+	{0x1E944, 0x1E949, 230},
+	{0x1E94A, 0x1E94A, 7},
+}
+
+// caseFolds maps the characters that full case folding changes
+var caseFolds = map[rune]string{
+	0x0041:  "\u0061",
+	0x0042:  "\u0062",
+	0x0043:  "\u0063",
+	0x0044:  "\u0064",
+	0x0045:  "\u0065",
+	0x0046:  "\u0066",
+	0x0047:  "\u0067",
+	0x0048:  "\u0068",
+	0x0049:  "\u0069",
+	0x004A:  "\u006a",
+	0x004B:  "\u006b",
+	0x004C:  "\u006c",
+	0x004D:  "\u006d",
+	0x004E:  "\u006e",
+	0x004F:  "\u006f",
+	0x0050:  "\u0070",
+	0x0051:  "\u0071",
+	0x0052:  "\u0072",
+	0x0053:  "\u0073",
+	0x0054:  "\u0074",
+	0x0055:  "\u0075",
+	0x0056:  "\u0076",
+	0x0057:  "\u0077",
+	0x0058:  "\u0078",
+	0x0059:  "\u0079",
+	0x005A:  "\u007a",
+	0x00B5:  "\u03bc",
+	0x00C0:  "\u00e0",
+	0x00C1:  "\u00e1",
+	0x00C2:  "\u00e2",
+	0x00C3:  "\u00e3",
+	0x00C4:  "\u00e4",
+	0x00C5:  "\u00e5",
+	0x00C6:  "\u00e6",
+	0x00C7:  "\u00e7",
+	0x00C8:  "\u00e8",
+	0x00C9:  "\u00e9",
+	0x00CA:  "\u00ea",
+	0x00CB:  "\u00eb",
+	0x00CC:  "\u00ec",
+	0x00CD:  "\u00ed",
+	0x00CE:  "\u00ee",
+	0x00CF:  "\u00ef",
+	0x00D0:  "\u00f0",
+	0x00D1:  "\u00f1",
+	0x00D2:  "\u00f2",
+	0x00D3:  "\u00f3",
+	0x00D4:  "\u00f4",
+	0x00D5:  "\u00f5",
+	0x00D6:  "\u00f6",
+	0x00D8:  "\u00f8",
+	0x00D9:  "\u00f9",
+	0x00DA:  "\u00fa",
+	0x00DB:  "\u00fb",
+	0x00DC:  "\u00fc",
+	0x00DD:  "\u00fd",
+	0x00DE:  "\u00fe",
+	0x00DF:  "\u0073\u0073",
+	0x0100:  "\u0101",
+	0x0102:  "\u0103",
+	0x0104:  "\u0105",
+	0x0106:  "\u0107",
+	0x0108:  "\u0109",
+	0x010A:  "\u010b",
+	0x010C:  "\u010d",
+	0x010E:  "\u010f",
+	0x0110:  "\u0111",
+	0x0112:  "\u0113",
+	0x0114:  "\u0115",
+	0x0116:  "\u0117",
+	0x0118:  "\u0119",
+	0x011A:  "\u011b",
+	0x011C:  "\u011d",
+	0x011E:  "\u011f",
+	0x0120:  "\u0121",
+	0x0122:  "\u0123",
+	0x0124:  "\u0125",
+	0x0126:  "\u0127",
+	0x0128:  "\u0129",
+	0x012A:  "\u012b",
+	0x012C:  "\u012d",
+	0x012E:  "\u012f",
+	0x0130:  "\u0069\u0307",
+	0x0132:  "\u0133",
+	0x0134:  "\u0135",
+	0x0136:  "\u0137",
+	0x0139:  "\u013a",
+	0x013B:  "\u013c",
+	0x013D:  "\u013e",
+	0x013F:  "\u0140",
+	0x0141:  "\u0142",
+	0x0143:  "\u0144",
+	0x0145:  "\u0146",
+	0x0147:  "\u0148",
+	0x0149:  "\u02bc\u006e",
+	0x014A:  "\u014b",
+	0x014C:  "\u014d",
+	0x014E:  "\u014f",
+	0x0150:  "\u0151",
+	0x0152:  "\u0153",
+	0x0154:  "\u0155",
+	0x0156:  "\u0157",
+	0x0158:  "\u0159",
+	0x015A:  "\u015b",
+	0x015C:  "\u015d",
+	0x015E:  "\u015f",
+	0x0160:  "\u0161",
+	0x0162:  "\u0163",
+	0x0164:  "\u0165",
+	0x0166:  "\u0167",
+	0x0168:  "\u0169",
+	0x016A:  "\u016b",
+	0x016C:  "\u016d",
+	0x016E:  "\u016f",
+	0x0170:  "\u0171",
+	0x0172:  "\u0173",
+	0x0174:  "\u0175",
+	0x0176:  "\u0177",
+	0x0178:  "\u00ff",
+	0x0179:  "\u017a",
+	0x017B:  "\u017c",
+	0x017D:  "\u017e",
+	0x017F:  "\u0073",
+	0x0181:  "\u0253",
+	0x0182:  "\u0183",
+	0x0184:  "\u0185",
+	0x0186:  "\u0254",
+	0x0187:  "\u0188",
+	0x0189:  "\u0256",
+	0x018A:  "\u0257",
+	0x018B:  "\u018c",
+	0x018E:  "\u01dd",
+	0x018F:  "\u0259",
+	0x0190:  "\u025b",
+	0x0191:  "\u0192",
+	0x0193:  "\u0260",
+	0x0194:  "\u0263",
+	0x0196:  "\u0269",
+	0x0197:  "\u0268",
+	0x0198:  "\u0199",
+	0x019C:  "\u026f",
+	0x019D:  "\u0272",
+	0x019F:  "\u0275",
+	0x01A0:  "\u01a1",
+	0x01A2:  "\u01a3",
+	0x01A4:  "\u01a5",
+	0x01A6:  "\u0280",
+	0x01A7:  "\u01a8",
+	0x01A9:  "\u0283",
+	0x01AC:  "\u01ad",
+	0x01AE:  "\u0288",
+	0x01AF:  "\u01b0",
+	0x01B1:  "\u028a",
+	0x01B2:  "\u028b",
+	0x01B3:  "\u01b4",
+	0x01B5:  "\u01b6",
+	0x01B7:  "\u0292",
+	0x01B8:  "\u01b9",
+	0x01BC:  "\u01bd",
+	0x01C4:  "\u01c6",
+	0x01C5:  "\u01c6",
+	0x01C7:  "\u01c9",
+	0x01C8:  "\u01c9",
+	0x01CA:  "\u01cc",
+	0x01CB:  "\u01cc",
+	0x01CD:  "\u01ce",
+	0x01CF:  "\u01d0",
+	0x01D1:  "\u01d2",
+	0x01D3:  "\u01d4",
+	0x01D5:  "\u01d6",
+	0x01D7:  "\u01d8",
+	0x01D9:  "\u01da",
+	0x01DB:  "\u01dc",
+	0x01DE:  "\u01df",
+	0x01E0:  "\u01e1",
+	0x01E2:  "\u01e3",
+	0x01E4:  "\u01e5",
+	0x01E6:  "\u01e7",
+	0x01E8:  "\u01e9",
+	0x01EA:  "\u01eb",
+	0x01EC:  "\u01ed",
+	0x01EE:  "\u01ef",
+	0x01F0:  "\u006a\u030c",
+	0x01F1:  "\u01f3",
+	0x01F2:  "\u01f3",
+	0x01F4:  "\u01f5",
+	0x01F6:  "\u0195",
+	0x01F7:  "\u01bf",
+	0x01F8:  "\u01f9",
+	0x01FA:  "\u01fb",
+	0x01FC:  "\u01fd",
+	0x01FE:  "\u01ff",
+	0x0200:  "\u0201",
+	0x0202:  "\u0203",
+	0x0204:  "\u0205",
+	0x0206:  "\u0207",
+	0x0208:  "\u0209",
+	0x020A:  "\u020b",
+	0x020C:  "\u020d",
+	0x020E:  "\u020f",
+	0x0210:  "\u0211",
+	0x0212:  "\u0213",
+	0x0214:  "\u0215",
+	0x0216:  "\u0217",
+	0x0218:  "\u0219",
+	0x021A:  "\u021b",
+	0x021C:  "\u021d",
+	0x021E:  "\u021f",
+	0x0220:  "\u019e",
+	0x0222:  "\u0223",
+	0x0224:  "\u0225",
+	0x0226:  "\u0227",
+	0x0228:  "\u0229",
+	0x022A:  "\u022b",
+	0x022C:  "\u022d",
+	0x022E:  "\u022f",
+	0x0230:  "\u0231",
+	0x0232:  "\u0233",
+	0x023A:  "\u2c65",
+	0x023B:  "\u023c",
+	0x023D:  "\u019a",
+	0x023E:  "\u2c66",
+	0x0241:  "\u0242",
+	0x0243:  "\u0180",
+	0x0244:  "\u0289",
+	0x0245:  "\u028c",
+	0x0246:  "\u0247",
+	0x0248:  "\u0249",
+	0x024A:  "\u024b",
+	0x024C:  "\u024d",
+	0x024E:  "\u024f",
+	0x0345:  "\u03b9",
+	0x0370:  "\u0371",
+	0x0372:  "\u0373",
+	0x0376:  "\u0377",
+	0x037F:  "\u03f3",
+	0x0386:  "\u03ac",
+	0x0388:  "\u03ad",
+	0x0389:  "\u03ae",
+	0x038A:  "\u03af",
+	0x038C:  "\u03cc",
+	0x038E:  "\u03cd",
+	0x038F:  "\u03ce",
+	0x0390:  "\u03b9\u0308\u0301",
+	0x0391:  "\u03b1",
+	0x0392:  "\u03b2",
+	0x0393:  "\u03b3",
+	0x0394:  "\u03b4",
+	0x0395:  "\u03b5",
+	0x0396:  "\u03b6",
+	0x0397:  "\u03b7",
+	0x0398:  "\u03b8",
+	0x0399:  "\u03b9",
+	0x039A:  "\u03ba",
+	0x039B:  "\u03bb",
+	0x039C:  "\u03bc",
+	0x039D:  "\u03bd",
+	0x039E:  "\u03be",
+	0x039F:  "\u03bf",
+	0x03A0:  "\u03c0",
+	0x03A1:  "\u03c1",
+	0x03A3:  "\u03c3",
+	0x03A4:  "\u03c4",
+	0x03A5:  "\u03c5",
+	0x03A6:  "\u03c6",
+	0x03A7:  "\u03c7",
+	0x03A8:  "\u03c8",
+	0x03A9:  "\u03c9",
+	0x03AA:  "\u03ca",
+	0x03AB:  "\u03cb",
+	0x03B0:  "\u03c5\u0308\u0301",
+	0x03C2:  "\u03c3",
+	0x03CF:  "\u03d7",
+	0x03D0:  "\u03b2",
+	0x03D1:  "\u03b8",
+	0x03D5:  "\u03c6",
+	0x03D6:  "\u03c0",
+	0x03D8:  "\u03d9",
+	0x03DA:  "\u03db",
+	0x03DC:  "\u03dd",
+	0x03DE:  "\u03df",
+	0x03E0:  "\u03e1",
+	0x03E2:  "\u03e3",
+	0x03E4:  "\u03e5",
+	0x03E6:  "\u03e7",
+	0x03E8:  "\u03e9",
+	0x03EA:  "\u03eb",
+	0x03EC:  "\u03ed",
+	0x03EE:  "\u03ef",
+	0x03F0:  "\u03ba",
+	0x03F1:  "\u03c1",
+	0x03F4:  "\u03b8",
+	0x03F5:  "\u03b5",
+	0x03F7:  "\u03f8",
+	0x03F9:  "\u03f2",
+	0x03FA:  "\u03fb",
+	0x03FD:  "\u037b",
+	0x03FE:  "\u037c",
+	0x03FF:  "\u037d",
+	0x0400:  "\u0450",
+	0x0401:  "\u0451",
+	0x0402:  "\u0452",
+	0x0403:  "\u0453",
+	0x0404:  "\u0454",
+	0x0405:  "\u0455",
+	0x0406:  "\u0456",
+	0x0407:  "\u0457",
+	0x0408:  "\u0458",
+	0x0409:  "\u0459",
+	0x040A:  "\u045a",
+	0x040B:  "\u045b",
+	0x040C:  "\u045c",
+	0x040D:  "\u045d",
+	0x040E:  "\u045e",
+	0x040F:  "\u045f",
+	0x0410:  "\u0430",
+	0x0411:  "\u0431",
+	0x0412:  "\u0432",
+	0x0413:  "\u0433",
+	0x0414:  "\u0434",
+	0x0415:  "\u0435",
+	0x0416:  "\u0436",
+	0x0417:  "\u0437",
+	0x0418:  "\u0438",
+	0x0419:  "\u0439",
+	0x041A:  "\u043a",
+	0x041B:  "\u043b",
+	0x041C:  "\u043c",
+	0x041D:  "\u043d",
+	0x041E:  "\u043e",
+	0x041F:  "\u043f",
+	0x0420:  "\u0440",
+	0x0421:  "\u0441",
+	0x0422:  "\u0442",
+	0x0423:  "\u0443",
+	0x0424:  "\u0444",
+	0x0425:  "\u0445",
+	0x0426:  "\u0446",
+	0x0427:  "\u0447",
+	0x0428:  "\u0448",
+	0x0429:  "\u0449",
+	0x042A:  "\u044a",
+	0x042B:  "\u044b",
+	0x042C:  "\u044c",
+	0x042D:  "\u044d",
+	0x042E:  "\u044e",
+	0x042F:  "\u044f",
+	0x0460:  "\u0461",
+	0x0462:  "\u0463",
+	0x0464:  "\u0465",
+	0x0466:  "\u0467",
+	0x0468:  "\u0469",
+	0x046A:  "\u046b",
+	0x046C:  "\u046d",
+	0x046E:  "\u046f",
+	0x0470:  "\u0471",
+	0x0472:  "\u0473",
+	0x0474:  "\u0475",
+	0x0476:  "\u0477",
+	0x0478:  "\u0479",
+	0x047A:  "\u047b",
+	0x047C:  "\u047d",
+	0x047E:  "\u047f",
+	0x0480:  "\u0481",
+	0x048A:  "\u048b",
+	0x048C:  "\u048d",
+	0x048E:  "\u048f",
+	0x0490:  "\u0491",
+	0x0492:  "\u0493",
+	0x0494:  "\u0495",
+	0x0496:  "\u0497",
+	0x0498:  "\u0499",
+	0x049A:  "\u049b",
+	0x049C:  "\u049d",
+	0x049E:  "\u049f",
+	0x04A0:  "\u04a1",
+	0x04A2:  "\u04a3",
+	0x04A4:  "\u04a5",
+	0x04A6:  "\u04a7",
+	0x04A8:  "\u04a9",
+	0x04AA:  "\u04ab",
+	0x04AC:  "\u04ad",
+	0x04AE:  "\u04af",
+	0x04B0:  "\u04b1",
+	0x04B2:  "\u04b3",
+	0x04B4:  "\u04b5",
+	0x04B6:  "\u04b7",
+	0x04B8:  "\u04b9",
+	0x04BA:  "\u04bb",
+	0x04BC:  "\u04bd",
+	0x04BE:  "\u04bf",
+	0x04C0:  "\u04cf",
+	0x04C1:  "\u04c2",
+	0x04C3:  "\u04c4",
+	0x04C5:  "\u04c6",
+	0x04C7:  "\u04c8",
+	0x04C9:  "\u04ca",
+	0x04CB:  "\u04cc",
+	0x04CD:  "\u04ce",
+	0x04D0:  "\u04d1",
+	0x04D2:  "\u04d3",
+	0x04D4:  "\u04d5",
+	0x04D6:  "\u04d7",
+	0x04D8:  "\u04d9",
+	0x04DA:  "\u04db",
+	0x04DC:  "\u04dd",
+	0x04DE:  "\u04df",
+	0x04E0:  "\u04e1",
+	0x04E2:  "\u04e3",
+	0x04E4:  "\u04e5",
+	0x04E6:  "\u04e7",
+	0x04E8:  "\u04e9",
+	0x04EA:  "\u04eb",
+	0x04EC:  "\u04ed",
+	0x04EE:  "\u04ef",
+	0x04F0:  "\u04f1",
+	0x04F2:  "\u04f3",
+	0x04F4:  "\u04f5",
+	0x04F6:  "\u04f7",
+	0x04F8:  "\u04f9",
+	0x04FA:  "\u04fb",
+	0x04FC:  "\u04fd",
+	0x04FE:  "\u04ff",
+	0x0500:  "\u0501",
+	0x0502:  "\u0503",
+	0x0504:  "\u0505",
+	0x0506:  "\u0507",
+	0x0508:  "\u0509",
+	0x050A:  "\u050b",
+	0x050C:  "\u050d",
+	0x050E:  "\u050f",
+	0x0510:  "\u0511",
+	0x0512:  "\u0513",
+	0x0514:  "\u0515",
+	0x0516:  "\u0517",
+	0x0518:  "\u0519",
+	0x051A:  "\u051b",
+	0x051C:  "\u051d",
+	0x051E:  "\u051f",
+	0x0520:  "\u0521",
+	0x0522:  "\u0523",
+	0x0524:  "\u0525",
+	0x0526:  "\u0527",
+	0x0528:  "\u0529",
+	0x052A:  "\u052b",
+	0x052C:  "\u052d",
+	0x052E:  "\u052f",
+	0x0531:  "\u0561",
+	0x0532:  "\u0562",
+	0x0533:  "\u0563",
+	0x0534:  "\u0564",
+	0x0535:  "\u0565",
+	0x0536:  "\u0566",
+	0x0537:  "\u0567",
+	0x0538:  "\u0568",
+	0x0539:  "\u0569",
+	0x053A:  "\u056a",
+	0x053B:  "\u056b",
+	0x053C:  "\u056c",
+	0x053D:  "\u056d",
+	0x053E:  "\u056e",
+	0x053F:  "\u056f",
+	0x0540:  "\u0570",
+	0x0541:  "\u0571",
+	0x0542:  "\u0572",
+	0x0543:  "\u0573",
+	0x0544:  "\u0574",
+	0x0545:  "\u0575",
+	0x0546:  "\u0576",
+	0x0547:  "\u0577",
+	0x0548:  "\u0578",
+	0x0549:  "\u0579",
+	0x054A:  "\u057a",
+	0x054B:  "\u057b",
+	0x054C:  "\u057c",
+	0x054D:  "\u057d",
+	0x054E:  "\u057e",
+	0x054F:  "\u057f",
+	0x0550:  "\u0580",
+	0x0551:  "\u0581",
+	0x0552:  "\u0582",
+	0x0553:  "\u0583",
+	0x0554:  "\u0584",
+	0x0555:  "\u0585",
+	0x0556:  "\u0586",
+	0x0587:  "\u0565\u0582",
+	0x10A0:  "\u2d00",
+	0x10A1:  "\u2d01",
+	0x10A2:  "\u2d02",
+	0x10A3:  "\u2d03",
+	0x10A4:  "\u2d04",
+	0x10A5:  "\u2d05",
+	0x10A6:  "\u2d06",
+	0x10A7:  "\u2d07",
+	0x10A8:  "\u2d08",
+	0x10A9:  "\u2d09",
+	0x10AA:  "\u2d0a",
+	0x10AB:  "\u2d0b",
+	0x10AC:  "\u2d0c",
+	0x10AD:  "\u2d0d",
+	0x10AE:  "\u2d0e",
+	0x10AF:  "\u2d0f",
+	0x10B0:  "\u2d10",
+	0x10B1:  "\u2d11",
+	0x10B2:  "\u2d12",
+	0x10B3:  "\u2d13",
+	0x10B4:  "\u2d14",
+	0x10B5:  "\u2d15",
+	0x10B6:  "\u2d16",
+	0x10B7:  "\u2d17",
+	0x10B8:  "\u2d18",
+	0x10B9:  "\u2d19",
+	0x10BA:  "\u2d1a",
+	0x10BB:  "\u2d1b",
+	0x10BC:  "\u2d1c",
+	0x10BD:  "\u2d1d",
+	0x10BE:  "\u2d1e",
+	0x10BF:  "\u2d1f",
+	0x10C0:  "\u2d20",
+	0x10C1:  "\u2d21",
+	0x10C2:  "\u2d22",
+	0x10C3:  "\u2d23",
+	0x10C4:  "\u2d24",
+	0x10C5:  "\u2d25",
+	0x10C7:  "\u2d27",
+	0x10CD:  "\u2d2d",
+	0x13F8:  "\u13f0",
+	0x13F9:  "\u13f1",
+	0x13FA:  "\u13f2",
+	0x13FB:  "\u13f3",
+	0x13FC:  "\u13f4",
+	0x13FD:  "\u13f5",
+	0x1C80:  "\u0432",
+	0x1C81:  "\u0434",
+	0x1C82:  "\u043e",
+	0x1C83:  "\u0441",
+	0x1C84:  "\u0442",
+	0x1C85:  "\u0442",
+	0x1C86:  "\u044a",
+	0x1C87:  "\u0463",
+	0x1C88:  "\ua64b",
+	0x1C90:  "\u10d0",
+	0x1C91:  "\u10d1",
+	0x1C92:  "\u10d2",
+	0x1C93:  "\u10d3",
+	0x1C94:  "\u10d4",
+	0x1C95:  "\u10d5",
+	0x1C96:  "\u10d6",
+	0x1C97:  "\u10d7",
+	0x1C98:  "\u10d8",
+	0x1C99:  "\u10d9",
+	0x1C9A:  "\u10da",
+	0x1C9B:  "\u10db",
+	0x1C9C:  "\u10dc",
+	0x1C9D:  "\u10dd",
+	0x1C9E:  "\u10de",
+	0x1C9F:  "\u10df",
+	0x1CA0:  "\u10e0",
+	0x1CA1:  "\u10e1",
+	0x1CA2:  "\u10e2",
+	0x1CA3:  "\u10e3",
+	0x1CA4:  "\u10e4",
+	0x1CA5:  "\u10e5",
+	0x1CA6:  "\u10e6",
+	0x1CA7:  "\u10e7",
+	0x1CA8:  "\u10e8",
+	0x1CA9:  "\u10e9",
+	0x1CAA:  "\u10ea",
+	0x1CAB:  "\u10eb",
+	0x1CAC:  "\u10ec",
+	0x1CAD:  "\u10ed",
+	0x1CAE:  "\u10ee",
+	0x1CAF:  "\u10ef",
+	0x1CB0:  "\u10f0",
+	0x1CB1:  "\u10f1",
+	0x1CB2:  "\u10f2",
+	0x1CB3:  "\u10f3",
+	0x1CB4:  "\u10f4",
+	0x1CB5:  "\u10f5",
+	0x1CB6:  "\u10f6",
+	0x1CB7:  "\u10f7",
+	0x1CB8:  "\u10f8",
+	0x1CB9:  "\u10f9",
+	0x1CBA:  "\u10fa",
+	0x1CBD:  "\u10fd",
+	0x1CBE:  "\u10fe",
+	0x1CBF:  "\u10ff",
+	0x1E00:  "\u1e01",
+	0x1E02:  "\u1e03",
+	0x1E04:  "\u1e05",
+	0x1E06:  "\u1e07",
+	0x1E08:  "\u1e09",
+	0x1E0A:  "\u1e0b",
+	0x1E0C:  "\u1e0d",
+	0x1E0E:  "\u1e0f",
+	0x1E10:  "\u1e11",
+	0x1E12:  "\u1e13",
+	0x1E14:  "\u1e15",
+	0x1E16:  "\u1e17",
+	0x1E18:  "\u1e19",
+	0x1E1A:  "\u1e1b",
+	0x1E1C:  "\u1e1d",
+	0x1E1E:  "\u1e1f",
+	0x1E20:  "\u1e21",
+	0x1E22:  "\u1e23",
+	0x1E24:  "\u1e25",
+	0x1E26:  "\u1e27",
+	0x1E28:  "\u1e29",
+	0x1E2A:  "\u1e2b",
+	0x1E2C:  "\u1e2d",
+	0x1E2E:  "\u1e2f",
+	0x1E30:  "\u1e31",
+	0x1E32:  "\u1e33",
+	0x1E34:  "\u1e35",
+	0x1E36:  "\u1e37",
+	0x1E38:  "\u1e39",
+	0x1E3A:  "\u1e3b",
+	0x1E3C:  "\u1e3d",
+	0x1E3E:  "\u1e3f",
+	0x1E40:  "\u1e41",
+	0x1E42:  "\u1e43",
+	0x1E44:  "\u1e45",
+	0x1E46:  "\u1e47",
+	0x1E48:  "\u1e49",
+	0x1E4A:  "\u1e4b",
+	0x1E4C:  "\u1e4d",
+	0x1E4E:  "\u1e4f",
+	0x1E50:  "\u1e51",
+	0x1E52:  "\u1e53",
+	0x1E54:  "\u1e55",
+	0x1E56:  "\u1e57",
+	0x1E58:  "\u1e59",
+	0x1E5A:  "\u1e5b",
+	0x1E5C:  "\u1e5d",
+	0x1E5E:  "\u1e5f",
+	0x1E60:  "\u1e61",
+	0x1E62:  "\u1e63",
+	0x1E64:  "\u1e65",
+	0x1E66:  "\u1e67",
+	0x1E68:  "\u1e69",
+	0x1E6A:  "\u1e6b",
+	0x1E6C:  "\u1e6d",
+	0x1E6E:  "\u1e6f",
+	0x1E70:  "\u1e71",
+	0x1E72:  "\u1e73",
+	0x1E74:  "\u1e75",
+	0x1E76:  "\u1e77",
+	0x1E78:  "\u1e79",
+	0x1E7A:  "\u1e7b",
+	0x1E7C:  "\u1e7d",
+	0x1E7E:  "\u1e7f",
+	0x1E80:  "\u1e81",
+	0x1E82:  "\u1e83",
+	0x1E84:  "\u1e85",
+	0x1E86:  "\u1e87",
+	0x1E88:  "\u1e89",
+	0x1E8A:  "\u1e8b",
+	0x1E8C:  "\u1e8d",
+	0x1E8E:  "\u1e8f",
+	0x1E90:  "\u1e91",
+	0x1E92:  "\u1e93",
+	0x1E94:  "\u1e95",
+	0x1E96:  "\u0068\u0331",
+	0x1E97:  "\u0074\u0308",
+	0x1E98:  "\u0077\u030a",
+	0x1E99:  "\u0079\u030a",
+	0x1E9A:  "\u0061\u02be",
+	0x1E9B:  "\u1e61",
+	0x1E9E:  "\u0073\u0073",
+	0x1EA0:  "\u1ea1",
+	0x1EA2:  "\u1ea3",
+	0x1EA4:  "\u1ea5",
+	0x1EA6:  "\u1ea7",
+	0x1EA8:  "\u1ea9",
+	0x1EAA:  "\u1eab",
+	0x1EAC:  "\u1ead",
+	0x1EAE:  "\u1eaf",
+	0x1EB0:  "\u1eb1",
+	0x1EB2:  "\u1eb3",
+	0x1EB4:  "\u1eb5",
+	0x1EB6:  "\u1eb7",
+	0x1EB8:  "\u1eb9",
+	0x1EBA:  "\u1ebb",
+	0x1EBC:  "\u1ebd",
+	0x1EBE:  "\u1ebf",
+	0x1EC0:  "\u1ec1",
+	0x1EC2:  "\u1ec3",
+	0x1EC4:  "\u1ec5",
+	0x1EC6:  "\u1ec7",
+	0x1EC8:  "\u1ec9",
+	0x1ECA:  "\u1ecb",
+	0x1ECC:  "\u1ecd",
+	0x1ECE:  "\u1ecf",
+	0x1ED0:  "\u1ed1",
+	0x1ED2:  "\u1ed3",
+	0x1ED4:  "\u1ed5",
+	0x1ED6:  "\u1ed7",
+	0x1ED8:  "\u1ed9",
+	0x1EDA:  "\u1edb",
+	0x1EDC:  "\u1edd",
+	0x1EDE:  "\u1edf",
+	0x1EE0:  "\u1ee1",
+	0x1EE2:  "\u1ee3",
+	0x1EE4:  "\u1ee5",
+	0x1EE6:  "\u1ee7",
+	0x1EE8:  "\u1ee9",
+	0x1EEA:  "\u1eeb",
+	0x1EEC:  "\u1eed",
+	0x1EEE:  "\u1eef",
+	0x1EF0:  "\u1ef1",
+	0x1EF2:  "\u1ef3",
+	0x1EF4:  "\u1ef5",
+	0x1EF6:  "\u1ef7",
+	0x1EF8:  "\u1ef9",
+	0x1EFA:  "\u1efb",
+	0x1EFC:  "\u1efd",
+	0x1EFE:  "\u1eff",
+	0x1F08:  "\u1f00",
+	0x1F09:  "\u1f01",
+	0x1F0A:  "\u1f02",
+	0x1F0B:  "\u1f03",
+	0x1F0C:  "\u1f04",
+	0x1F0D:  "\u1f05",
+	0x1F0E:  "\u1f06",
+	0x1F0F:  "\u1f07",
+	0x1F18:  "\u1f10",
+	0x1F19:  "\u1f11",
+	0x1F1A:  "\u1f12",
+	0x1F1B:  "\u1f13",
+	0x1F1C:  "\u1f14",
+	0x1F1D:  "\u1f15",
+	0x1F28:  "\u1f20",
+	0x1F29:  "\u1f21",
+	0x1F2A:  "\u1f22",
+	0x1F2B:  "\u1f23",
+	0x1F2C:  "\u1f24",
+	0x1F2D:  "\u1f25",
+	0x1F2E:  "\u1f26",
+	0x1F2F:  "\u1f27",
+	0x1F38:  "\u1f30",
+	0x1F39:  "\u1f31",
+	0x1F3A:  "\u1f32",
+	0x1F3B:  "\u1f33",
+	0x1F3C:  "\u1f34",
+	0x1F3D:  "\u1f35",
+	0x1F3E:  "\u1f36",
+	0x1F3F:  "\u1f37",
+	0x1F48:  "\u1f40",
+	0x1F49:  "\u1f41",
+	0x1F4A:  "\u1f42",
+	0x1F4B:  "\u1f43",
+	0x1F4C:  "\u1f44",
+	0x1F4D:  "\u1f45",
+	0x1F50:  "\u03c5\u0313",
+	0x1F52:  "\u03c5\u0313\u0300",
+	0x1F54:  "\u03c5\u0313\u0301",
+	0x1F56:  "\u03c5\u0313\u0342",
+	0x1F59:  "\u1f51",
+	0x1F5B:  "\u1f53",
+	0x1F5D:  "\u1f55",
+	0x1F5F:  "\u1f57",
+	0x1F68:  "\u1f60",
+	0x1F69:  "\u1f61",
+	0x1F6A:  "\u1f62",
+	0x1F6B:  "\u1f63",
+	0x1F6C:  "\u1f64",
+	0x1F6D:  "\u1f65",
+	0x1F6E:  "\u1f66",
+	0x1F6F:  "\u1f67",
+	0x1F80:  "\u1f00\u03b9",
+	0x1F81:  "\u1f01\u03b9",
+	0x1F82:  "\u1f02\u03b9",
+	0x1F83:  "\u1f03\u03b9",
+	0x1F84:  "\u1f04\u03b9",
+	0x1F85:  "\u1f05\u03b9",
+	0x1F86:  "\u1f06\u03b9",
+	0x1F87:  "\u1f07\u03b9",
+	0x1F88:  "\u1f00\u03b9",
+	0x1F89:  "\u1f01\u03b9",
+	0x1F8A:  "\u1f02\u03b9",
+	0x1F8B:  "\u1f03\u03b9",
+	0x1F8C:  "\u1f04\u03b9",
+	0x1F8D:  "\u1f05\u03b9",
+	0x1F8E:  "\u1f06\u03b9",
+	0x1F8F:  "\u1f07\u03b9",
+	0x1F90:  "\u1f20\u03b9",
+	0x1F91:  "\u1f21\u03b9",
+	0x1F92:  "\u1f22\u03b9",
+	0x1F93:  "\u1f23\u03b9",
+	0x1F94:  "\u1f24\u03b9",
+	0x1F95:  "\u1f25\u03b9",
+	0x1F96:  "\u1f26\u03b9",
+	0x1F97:  "\u1f27\u03b9",
+	0x1F98:  "\u1f20\u03b9",
+	0x1F99:  "\u1f21\u03b9",
+	0x1F9A:  "\u1f22\u03b9",
+	0x1F9B:  "\u1f23\u03b9",
+	0x1F9C:  "\u1f24\u03b9",
+	0x1F9D:  "\u1f25\u03b9",
+	0x1F9E:  "\u1f26\u03b9",
+	0x1F9F:  "\u1f27\u03b9",
+	0x1FA0:  "\u1f60\u03b9",
+	0x1FA1:  "\u1f61\u03b9",
+	0x1FA2:  "\u1f62\u03b9",
+	0x1FA3:  "\u1f63\u03b9",
+	0x1FA4:  "\u1f64\u03b9",
+	0x1FA5:  "\u1f65\u03b9",
+	0x1FA6:  "\u1f66\u03b9",
+	0x1FA7:  "\u1f67\u03b9",
+	0x1FA8:  "\u1f60\u03b9",
+	0x1FA9:  "\u1f61\u03b9",
+	0x1FAA:  "\u1f62\u03b9",
+	0x1FAB:  "\u1f63\u03b9",
+	0x1FAC:  "\u1f64\u03b9",
+	0x1FAD:  "\u1f65\u03b9",
+	0x1FAE:  "\u1f66\u03b9",
+	0x1FAF:  "\u1f67\u03b9",
+	0x1FB2:  "\u1f70\u03b9",
+	0x1FB3:  "\u03b1\u03b9",
+	0x1FB4:  "\u03ac\u03b9",
+	0x1FB6:  "\u03b1\u0342",
+	0x1FB7:  "\u03b1\u0342\u03b9",
+	0x1FB8:  "\u1fb0",
+	0x1FB9:  "\u1fb1",
+	0x1FBA:  "\u1f70",
+	0x1FBB:  "\u1f71",
+	0x1FBC:  "\u03b1\u03b9",
+	0x1FBE:  "\u03b9",
+	0x1FC2:  "\u1f74\u03b9",
+	0x1FC3:  "\u03b7\u03b9",
+	0x1FC4:  "\u03ae\u03b9",
+	0x1FC6:  "\u03b7\u0342",
+	0x1FC7:  "\u03b7\u0342\u03b9",
+	0x1FC8:  "\u1f72",
+	0x1FC9:  "\u1f73",
+	0x1FCA:  "\u1f74",
+	0x1FCB:  "\u1f75",
+	0x1FCC:  "\u03b7\u03b9",
+	0x1FD2:  "\u03b9\u0308\u0300",
+	0x1FD3:  "\u03b9\u0308\u0301",
+	0x1FD6:  "\u03b9\u0342",
+	0x1FD7:  "\u03b9\u0308\u0342",
+	0x1FD8:  "\u1fd0",
+	0x1FD9:  "\u1fd1",
+	0x1FDA:  "\u1f76",
+	0x1FDB:  "\u1f77",
+	0x1FE2:  "\u03c5\u0308\u0300",
+	0x1FE3:  "\u03c5\u0308\u0301",
+	0x1FE4:  "\u03c1\u0313",
+	0x1FE6:  "\u03c5\u0342",
+	0x1FE7:  "\u03c5\u0308\u0342",
+	0x1FE8:  "\u1fe0",
+	0x1FE9:  "\u1fe1",
+	0x1FEA:  "\u1f7a",
+	0x1FEB:  "\u1f7b",
+	0x1FEC:  "\u1fe5",
+	0x1FF2:  "\u1f7c\u03b9",
+	0x1FF3:  "\u03c9\u03b9",
+	0x1FF4:  "\u03ce\u03b9",
+	0x1FF6:  "\u03c9\u0342",
+	0x1FF7:  "\u03c9\u0342\u03b9",
+	0x1FF8:  "\u1f78",
+	0x1FF9:  "\u1f79",
+	0x1FFA:  "\u1f7c",
+	0x1FFB:  "\u1f7d",
+	0x1FFC:  "\u03c9\u03b9",
+	0x2126:  "\u03c9",
+	0x212A:  "\u006b",
+	0x212B:  "\u00e5",
+	0x2132:  "\u214e",
+	0x2160:  "\u2170",
+	0x2161:  "\u2171",
+	0x2162:  "\u2172",
+	0x2163:  "\u2173",
+	0x2164:  "\u2174",
+	0x2165:  "\u2175",
+	0x2166:  "\u2176",
+	0x2167:  "\u2177",
+	0x2168:  "\u2178",
+	0x2169:  "\u2179",
+	0x216A:  "\u217a",
+	0x216B:  "\u217b",
+	0x216C:  "\u217c",
+	0x216D:  "\u217d",
+	0x216E:  "\u217e",
+	0x216F:  "\u217f",
+	0x2183:  "\u2184",
+	0x24B6:  "\u24d0",
+	0x24B7:  "\u24d1",
+	0x24B8:  "\u24d2",
+	0x24B9:  "\u24d3",
+	0x24BA:  "\u24d4",
+	0x24BB:  "\u24d5",
+	0x24BC:  "\u24d6",
+	0x24BD:  "\u24d7",
+	0x24BE:  "\u24d8",
+	0x24BF:  "\u24d9",
+	0x24C0:  "\u24da",
+	0x24C1:  "\u24db",
+	0x24C2:  "\u24dc",
+	0x24C3:  "\u24dd",
+	0x24C4:  "\u24de",
+	0x24C5:  "\u24df",
+	0x24C6:  "\u24e0",
+	0x24C7:  "\u24e1",
+	0x24C8:  "\u24e2",
+	0x24C9:  "\u24e3",
+	0x24CA:  "\u24e4",
+	0x24CB:  "\u24e5",
+	0x24CC:  "\u24e6",
+	0x24CD:  "\u24e7",
+	0x24CE:  "\u24e8",
+	0x24CF:  "\u24e9",
+	0x2C00:  "\u2c30",
+	0x2C01:  "\u2c31",
+	0x2C02:  "\u2c32",
+	0x2C03:  "\u2c33",
+	0x2C04:  "\u2c34",
+	0x2C05:  "\u2c35",
+	0x2C06:  "\u2c36",
+	0x2C07:  "\u2c37",
+	0x2C08:  "\u2c38",
+	0x2C09:  "\u2c39",
+	0x2C0A:  "\u2c3a",
+	0x2C0B:  "\u2c3b",
+	0x2C0C:  "\u2c3c",
+	0x2C0D:  "\u2c3d",
+	0x2C0E:  "\u2c3e",
+	0x2C0F:  "\u2c3f",
+	0x2C10:  "\u2c40",
+	0x2C11:  "\u2c41",
+	0x2C12:  "\u2c42",
+	0x2C13:  "\u2c43",
+	0x2C14:  "\u2c44",
+	0x2C15:  "\u2c45",
+	0x2C16:  "\u2c46",
+	0x2C17:  "\u2c47",
+	0x2C18:  "\u2c48",
+	0x2C19:  "\u2c49",
+	0x2C1A:  "\u2c4a",
+	0x2C1B:  "\u2c4b",
+	0x2C1C:  "\u2c4c",
+	0x2C1D:  "\u2c4d",
+	0x2C1E:  "\u2c4e",
+	0x2C1F:  "\u2c4f",
+	0x2C20:  "\u2c50",
+	0x2C21:  "\u2c51",
+	0x2C22:  "\u2c52",
+	0x2C23:  "\u2c53",
+	0x2C24:  "\u2c54",
+	0x2C25:  "\u2c55",
+	0x2C26:  "\u2c56",
+	0x2C27:  "\u2c57",
+	0x2C28:  "\u2c58",
+	0x2C29:  "\u2c59",
+	0x2C2A:  "\u2c5a",
+	0x2C2B:  "\u2c5b",
+	0x2C2C:  "\u2c5c",
+	0x2C2D:  "\u2c5d",
+	0x2C2E:  "\u2c5e",
+	0x2C2F:  "\u2c5f",
+	0x2C60:  "\u2c61",
+	0x2C62:  "\u026b",
+	0x2C63:  "\u1d7d",
+	0x2C64:  "\u027d",
+	0x2C67:  "\u2c68",
+	0x2C69:  "\u2c6a",
+	0x2C6B:  "\u2c6c",
+	0x2C6D:  "\u0251",
+	0x2C6E:  "\u0271",
+	0x2C6F:  "\u0250",
+	0x2C70:  "\u0252",
+	0x2C72:  "\u2c73",
+	0x2C75:  "\u2c76",
+	0x2C7E:  "\u023f",
+	0x2C7F:  "\u0240",
+	0x2C80:  "\u2c81",
+	0x2C82:  "\u2c83",
+	0x2C84:  "\u2c85",
+	0x2C86:  "\u2c87",
+	0x2C88:  "\u2c89",
+	0x2C8A:  "\u2c8b",
+	0x2C8C:  "\u2c8d",
+	0x2C8E:  "\u2c8f",
+	0x2C90:  "\u2c91",
+	0x2C92:  "\u2c93",
+	0x2C94:  "\u2c95",
+	0x2C96:  "\u2c97",
+	0x2C98:  "\u2c99",
+	0x2C9A:  "\u2c9b",
+	0x2C9C:  "\u2c9d",
+	0x2C9E:  "\u2c9f",
+	0x2CA0:  "\u2ca1",
+	0x2CA2:  "\u2ca3",
+	0x2CA4:  "\u2ca5",
+	0x2CA6:  "\u2ca7",
+	0x2CA8:  "\u2ca9",
+	0x2CAA:  "\u2cab",
+	0x2CAC:  "\u2cad",
+	0x2CAE:  "\u2caf",
+	0x2CB0:  "\u2cb1",
+	0x2CB2:  "\u2cb3",
+	0x2CB4:  "\u2cb5",
+	0x2CB6:  "\u2cb7",
+	0x2CB8:  "\u2cb9",
+	0x2CBA:  "\u2cbb",
+	0x2CBC:  "\u2cbd",
+	0x2CBE:  "\u2cbf",
+	0x2CC0:  "\u2cc1",
+	0x2CC2:  "\u2cc3",
+	0x2CC4:  "\u2cc5",
+	0x2CC6:  "\u2cc7",
+	0x2CC8:  "\u2cc9",
+	0x2CCA:  "\u2ccb",
+	0x2CCC:  "\u2ccd",
+	0x2CCE:  "\u2ccf",
+	0x2CD0:  "\u2cd1",
+	0x2CD2:  "\u2cd3",
+	0x2CD4:  "\u2cd5",
+	0x2CD6:  "\u2cd7",
+	0x2CD8:  "\u2cd9",
+	0x2CDA:  "\u2cdb",
+	0x2CDC:  "\u2cdd",
+	0x2CDE:  "\u2cdf",
+	0x2CE0:  "\u2ce1",
+	0x2CE2:  "\u2ce3",
+	0x2CEB:  "\u2cec",
+	0x2CED:  "\u2cee",
+	0x2CF2:  "\u2cf3",
+	0xA640:  "\ua641",
+	0xA642:  "\ua643",
+	0xA644:  "\ua645",
+	0xA646:  "\ua647",
+	0xA648:  "\ua649",
+	0xA64A:  "\ua64b",
+	0xA64C:  "\ua64d",
+	0xA64E:  "\ua64f",
+	0xA650:  "\ua651",
+	0xA652:  "\ua653",
+	0xA654:  "\ua655",
+	0xA656:  "\ua657",
+	0xA658:  "\ua659",
+	0xA65A:  "\ua65b",
+	0xA65C:  "\ua65d",
+	0xA65E:  "\ua65f",
+	0xA660:  "\ua661",
+	0xA662:  "\ua663",
+	0xA664:  "\ua665",
+	0xA666:  "\ua667",
+	0xA668:  "\ua669",
+	0xA66A:  "\ua66b",
+	0xA66C:  "\ua66d",
+	0xA680:  "\ua681",
+	0xA682:  "\ua683",
+	0xA684:  "\ua685",
+	0xA686:  "\ua687",
+	0xA688:  "\ua689",
+	0xA68A:  "\ua68b",
+	0xA68C:  "\ua68d",
+	0xA68E:  "\ua68f",
+	0xA690:  "\ua691",
+	0xA692:  "\ua693",
+	0xA694:  "\ua695",
+	0xA696:  "\ua697",
+	0xA698:  "\ua699",
+	0xA69A:  "\ua69b",
+	0xA722:  "\ua723",
+	0xA724:  "\ua725",
+	0xA726:  "\ua727",
+	0xA728:  "\ua729",
+	0xA72A:  "\ua72b",
+	0xA72C:  "\ua72d",
+	0xA72E:  "\ua72f",
+	0xA732:  "\ua733",
+	0xA734:  "\ua735",
+	0xA736:  "\ua737",
+	0xA738:  "\ua739",
+	0xA73A:  "\ua73b",
+	0xA73C:  "\ua73d",
+	0xA73E:  "\ua73f",
+	0xA740:  "\ua741",
+	0xA742:  "\ua743",
+	0xA744:  "\ua745",
+	0xA746:  "\ua747",
+	0xA748:  "\ua749",
+	0xA74A:  "\ua74b",
+	0xA74C:  "\ua74d",
+	0xA74E:  "\ua74f",
+	0xA750:  "\ua751",
+	0xA752:  "\ua753",
+	0xA754:  "\ua755",
+	0xA756:  "\ua757",
+	0xA758:  "\ua759",
+	0xA75A:  "\ua75b",
+	0xA75C:  "\ua75d",
+	0xA75E:  "\ua75f",
+	0xA760:  "\ua761",
+	0xA762:  "\ua763",
+	0xA764:  "\ua765",
+	0xA766:  "\ua767",
+	0xA768:  "\ua769",
+	0xA76A:  "\ua76b",
+	0xA76C:  "\ua76d",
+	0xA76E:  "\ua76f",
+	0xA779:  "\ua77a",
+	0xA77B:  "\ua77c",
+	0xA77D:  "\u1d79",
+	0xA77E:  "\ua77f",
+	0xA780:  "\ua781",
+	0xA782:  "\ua783",
+	0xA784:  "\ua785",
+	0xA786:  "\ua787",
+	0xA78B:  "\ua78c",
+	0xA78D:  "\u0265",
+	0xA790:  "\ua791",
+	0xA792:  "\ua793",
+	0xA796:  "\ua797",
+	0xA798:  "\ua799",
+	0xA79A:  "\ua79b",
+	0xA79C:  "\ua79d",
+	0xA79E:  "\ua79f",
+	0xA7A0:  "\ua7a1",
+	0xA7A2:  "\ua7a3",
+	0xA7A4:  "\ua7a5",
+	0xA7A6:  "\ua7a7",
+	0xA7A8:  "\ua7a9",
+	0xA7AA:  "\u0266",
+	0xA7AB:  "\u025c",
+	0xA7AC:  "\u0261",
+	0xA7AD:  "\u026c",
+	0xA7AE:  "\u026a",
+	0xA7B0:  "\u029e",
+	0xA7B1:  "\u0287",
+	0xA7B2:  "\u029d",
+	0xA7B3:  "\uab53",
+	0xA7B4:  "\ua7b5",
+	0xA7B6:  "\ua7b7",
+	0xA7B8:  "\ua7b9",
+	0xA7BA:  "\ua7bb",
+	0xA7BC:  "\ua7bd",
+	0xA7BE:  "\ua7bf",
+	0xA7C0:  "\ua7c1",
+	0xA7C2:  "\ua7c3",
+	0xA7C4:  "\ua794",
+	0xA7C5:  "\u0282",
+	0xA7C6:  "\u1d8e",
+	0xA7C7:  "\ua7c8",
+	0xA7C9:  "\ua7ca",
+	0xA7D0:  "\ua7d1",
+	0xA7D6:  "\ua7d7",
+	0xA7D8:  "\ua7d9",
+	0xA7F5:  "\ua7f6",
+	0xAB70:  "\u13a0",
+	0xAB71:  "\u13a1",
+	0xAB72:  "\u13a2",
+	0xAB73:  "\u13a3",
+	0xAB74:  "\u13a4",
+	0xAB75:  "\u13a5",
+	0xAB76:  "\u13a6",
+	0xAB77:  "\u13a7",
+	0xAB78:  "\u13a8",
+	0xAB79:  "\u13a9",
+	0xAB7A:  "\u13aa",
+	0xAB7B:  "\u13ab",
+	0xAB7C:  "\u13ac",
+	0xAB7D:  "\u13ad",
+	0xAB7E:  "\u13ae",
+	0xAB7F:  "\u13af",
+	0xAB80:  "\u13b0",
+	0xAB81:  "\u13b1",
+	0xAB82:  "\u13b2",
+	0xAB83:  "\u13b3",
+	0xAB84:  "\u13b4",
+	0xAB85:  "\u13b5",
+	0xAB86:  "\u13b6",
+	0xAB87:  "\u13b7",
+	0xAB88:  "\u13b8",
+	0xAB89:  "\u13b9",
+	0xAB8A:  "\u13ba",
+	0xAB8B:  "\u13bb",
+	0xAB8C:  "\u13bc",
+	0xAB8D:  "\u13bd",
+	0xAB8E:  "\u13be",
+	0xAB8F:  "\u13bf",
+	0xAB90:  "\u13c0",
+	0xAB91:  "\u13c1",
+	0xAB92:  "\u13c2",
+	0xAB93:  "\u13c3",
+	0xAB94:  "\u13c4",
+	0xAB95:  "\u13c5",
+	0xAB96:  "\u13c6",
+	0xAB97:  "\u13c7",
+	0xAB98:  "\u13c8",
+	0xAB99:  "\u13c9",
+	0xAB9A:  "\u13ca",
+	0xAB9B:  "\u13cb",
+	0xAB9C:  "\u13cc",
+	0xAB9D:  "\u13cd",
+	0xAB9E:  "\u13ce",
+	0xAB9F:  "\u13cf",
+	0xABA0:  "\u13d0",
+	0xABA1:  "\u13d1",
+	0xABA2:  "\u13d2",
+	0xABA3:  "\u13d3",
+	0xABA4:  "\u13d4",
+	0xABA5:  "\u13d5",
+	0xABA6:  "\u13d6",
+	0xABA7:  "\u13d7",
+	0xABA8:  "\u13d8",
+	0xABA9:  "\u13d9",
+	0xABAA:  "\u13da",
+	0xABAB:  "\u13db",
+	0xABAC:  "\u13dc",
+	0xABAD:  "\u13dd",
+	0xABAE:  "\u13de",
+	0xABAF:  "\u13df",
+	0xABB0:  "\u13e0",
+	0xABB1:  "\u13e1",
+	0xABB2:  "\u13e2",
+	0xABB3:  "\u13e3",
+	0xABB4:  "\u13e4",
+	0xABB5:  "\u13e5",
+	0xABB6:  "\u13e6",
+	0xABB7:  "\u13e7",
+	0xABB8:  "\u13e8",
+	0xABB9:  "\u13e9",
+	0xABBA:  "\u13ea",
+	0xABBB:  "\u13eb",
+	0xABBC:  "\u13ec",
+	0xABBD:  "\u13ed",
+	0xABBE:  "\u13ee",
+	0xABBF:  "\u13ef",
+	0xFB00:  "\u0066\u0066",
+	0xFB01:  "\u0066\u0069",
+	0xFB02:  "\u0066\u006c",
+	0xFB03:  "\u0066\u0066\u0069",
+	0xFB04:  "\u0066\u0066\u006c",
+	0xFB05:  "\u0073\u0074",
+	0xFB06:  "\u0073\u0074",
+	0xFB13:  "\u0574\u0576",
+	0xFB14:  "\u0574\u0565",
+	0xFB15:  "\u0574\u056b",
+	0xFB16:  "\u057e\u0576",
+	0xFB17:  "\u0574\u056d",
+	0xFF21:  "\uff41",
+	0xFF22:  "\uff42",
+	0xFF23:  "\uff43",
+	0xFF24:  "\uff44",
+	0xFF25:  "\uff45",
+	0xFF26:  "\uff46",
+	0xFF27:  "\uff47",
+	0xFF28:  "\uff48",
+	0xFF29:  "\uff49",
+	0xFF2A:  "\uff4a",
+	0xFF2B:  "\uff4b",
+	0xFF2C:  "\uff4c",
+	0xFF2D:  "\uff4d",
+	0xFF2E:  "\uff4e",
+	0xFF2F:  "\uff4f",
+	0xFF30:  "\uff50",
+	0xFF31:  "\uff51",
+	0xFF32:  "\uff52",
+	0xFF33:  "\uff53",
+	0xFF34:  "\uff54",
+	0xFF35:  "\uff55",
+	0xFF36:  "\uff56",
+	0xFF37:  "\uff57",
+	0xFF38:  "\uff58",
+	0xFF39:  "\uff59",
+	0xFF3A:  "\uff5a",
+	0x10400: "\U00010428",
+	0x10401: "\U00010429",
+	0x10402: "\U0001042a",
+	0x10403: "\U0001042b",
+	0x10404: "\U0001042c",
+	0x10405: "\U0001042d",
+	0x10406: "\U0001042e",
+	0x10407: "\U0001042f",
+	0x10408: "\U00010430",
+	0x10409: "\U00010431",
+	0x1040A: "\U00010432",
+	0x1040B: "\U00010433",
+	0x1040C: "\U00010434",
+	0x1040D: "\U00010435",
+	0x1040E: "\U00010436",
+	0x1040F: "\U00010437",
+	0x10410: "\U00010438",
+	0x10411: "\U00010439",
+	0x10412: "\U0001043a",
+	0x10413: "\U0001043b",
+	0x10414: "\U0001043c",
+	0x10415: "\U0001043d",
+	0x10416: "\U0001043e",
+	0x10417: "\U0001043f",
+	0x10418: "\U00010440",
+	0x10419: "\U00010441",
+	0x1041A: "\U00010442",
+	0x1041B: "\U00010443",
+	0x1041C: "\U00010444",
+	0x1041D: "\U00010445",
+	0x1041E: "\U00010446",
+	0x1041F: "\U00010447",
+	0x10420: "\U00010448",
+	0x10421: "\U00010449",
+	0x10422: "\U0001044a",
+	0x10423: "\U0001044b",
+	0x10424: "\U0001044c",
+	0x10425: "\U0001044d",
+	0x10426: "\U0001044e",
+	0x10427: "\U0001044f",
+	0x104B0: "\U000104d8",
+	0x104B1: "\U000104d9",
+	0x104B2: "\U000104da",
+	0x104B3: "\U000104db",
+	0x104B4: "\U000104dc",
+	0x104B5: "\U000104dd",
+	0x104B6: "\U000104de",
+	0x104B7: "\U000104df",
+	0x104B8: "\U000104e0",
+	0x104B9: "\U000104e1",
+	0x104BA: "\U000104e2",
+	0x104BB: "\U000104e3",
+	0x104BC: "\U000104e4",
+	0x104BD: "\U000104e5",
+	0x104BE: "\U000104e6",
+	0x104BF: "\U000104e7",
+	0x104C0: "\U000104e8",
+	0x104C1: "\U000104e9",
+	0x104C2: "\U000104ea",
+	0x104C3: "\U000104eb",
+	0x104C4: "\U000104ec",
+	0x104C5: "\U000104ed",
+	0x104C6: "\U000104ee",
+	0x104C7: "\U000104ef",
+	0x104C8: "\U000104f0",
+	0x104C9: "\U000104f1",
+	0x104CA: "\U000104f2",
+	0x104CB: "\U000104f3",
+	0x104CC: "\U000104f4",
+	0x104CD: "\U000104f5",
+	0x104CE: "\U000104f6",
+	0x104CF: "\U000104f7",
+	0x104D0: "\U000104f8",
+	0x104D1: "\U000104f9",
+	0x104D2: "\U000104fa",
+	0x104D3: "\U000104fb",
+	0x10570: "\U00010597",
+	0x10571: "\U00010598",
+	0x10572: "\U00010599",
+	0x10573: "\U0001059a",
+	0x10574: "\U0001059b",
+	0x10575: "\U0001059c",
+	0x10576: "\U0001059d",
+	0x10577: "\U0001059e",
+	0x10578: "\U0001059f",
+	0x10579: "\U000105a0",
+	0x1057A: "\U000105a1",
+	0x1057C: "\U000105a3",
+	0x1057D: "\U000105a4",
+	0x1057E: "\U000105a5",
+	0x1057F: "\U000105a6",
+	0x10580: "\U000105a7",
+	0x10581: "\U000105a8",
+	0x10582: "\U000105a9",
+	0x10583: "\U000105aa",
+	0x10584: "\U000105ab",
+	0x10585: "\U000105ac",
+	0x10586: "\U000105ad",
+	0x10587: "\U000105ae",
+	0x10588: "\U000105af",
+	0x10589: "\U000105b0",
+	0x1058A: "\U000105b1",
+	0x1058C: "\U000105b3",
+	0x1058D: "\U000105b4",
+	0x1058E: "\U000105b5",
+	0x1058F: "\U000105b6",
+	0x10590: "\U000105b7",
+	0x10591: "\U000105b8",
+	0x10592: "\U000105b9",
+	0x10594: "\U000105bb",
+	0x10595: "\U000105bc",
+	0x10C80: "\U00010cc0",
+	0x10C81: "\U00010cc1",
+	0x10C82: "\U00010cc2",
+	0x10C83: "\U00010cc3",
+	0x10C84: "\U00010cc4",
+	0x10C85: "\U00010cc5",
+	0x10C86: "\U00010cc6",
+	0x10C87: "\U00010cc7",
+	0x10C88: "\U00010cc8",
+	0x10C89: "\U00010cc9",
+	0x10C8A: "\U00010cca",
+	0x10C8B: "\U00010ccb",
+	0x10C8C: "\U00010ccc",
+	0x10C8D: "\U00010ccd",
+	0x10C8E: "\U00010cce",
+	0x10C8F: "\U00010ccf",
+	0x10C90: "\U00010cd0",
+	0x10C91: "\U00010cd1",
+	0x10C92: "\U00010cd2",
+	0x10C93: "\U00010cd3",
+	0x10C94: "\U00010cd4",
+	0x10C95: "\U00010cd5",
+	0x10C96: "\U00010cd6",
+	0x10C97: "\U00010cd7",
+	0x10C98: "\U00010cd8",
+	0x10C99: "\U00010cd9",
+	0x10C9A: "\U00010cda",
+	0x10C9B: "\U00010cdb",
+	0x10C9C: "\U00010cdc",
+	0x10C9D: "\U00010cdd",
+	0x10C9E: "\U00010cde",
+	0x10C9F: "\U00010cdf",
+	0x10CA0: "\U00010ce0",
+	0x10CA1: "\U00010ce1",
+	0x10CA2: "\U00010ce2",
+	0x10CA3: "\U00010ce3",
+	0x10CA4: "\U00010ce4",
+	0x10CA5: "\U00010ce5",
+	0x10CA6: "\U00010ce6",
+	0x10CA7: "\U00010ce7",
+	0x10CA8: "\U00010ce8",
+	0x10CA9: "\U00010ce9",
+	0x10CAA: "\U00010cea",
+	0x10CAB: "\U00010ceb",
+	0x10CAC: "\U00010cec",
+	0x10CAD: "\U00010ced",
+	0x10CAE: "\U00010cee",
+	0x10CAF: "\U00010cef",
+	0x10CB0: "\U00010cf0",
+	0x10CB1: "\U00010cf1",
+	0x10CB2: "\U00010cf2",
+	0x118A0: "\U000118c0",
+	0x118A1: "\U000118c1",
+	0x118A2: "\U000118c2",
+	0x118A3: "\U000118c3",
+	0x118A4: "\U000118c4",
+	0x118A5: "\U000118c5",
+	0x118A6: "\U000118c6",
+	0x118A7: "\U000118c7",
+	0x118A8: "\U000118c8",
+	0x118A9: "\U000118c9",
+	0x118AA: "\U000118ca",
+	0x118AB: "\U000118cb",
+	0x118AC: "\U000118cc",
+	0x118AD: "\U000118cd",
+	0x118AE: "\U000118ce",
+	0x118AF: "\U000118cf",
+	0x118B0: "\U000118d0",
+	0x118B1: "\U000118d1",
+	0x118B2: "\U000118d2",
+	0x118B3: "\U000118d3",
+	0x118B4: "\U000118d4",
+	0x118B5: "\U000118d5",
+	0x118B6: "\U000118d6",
+	0x118B7: "\U000118d7",
+	0x118B8: "\U000118d8",
+	0x118B9: "\U000118d9",
+	0x118BA: "\U000118da",
+	0x118BB: "\U000118db",
+	0x118BC: "\U000118dc",
+	0x118BD: "\U000118dd",
+	0x118BE: "\U000118de",
+	0x118BF: "\U000118df",
+	0x16E40: "\U00016e60",
+	0x16E41: "\U00016e61",
+	0x16E42: "\U00016e62",
+	0x16E43: "\U00016e63",
+	0x16E44: "\U00016e64",
+	0x16E45: "\U00016e65",
+	0x16E46: "\U00016e66",
+	0x16E47: "\U00016e67",
+	0x16E48: "\U00016e68",
+	0x16E49: "\U00016e69",
+	0x16E4A: "\U00016e6a",
+	0x16E4B: "\U00016e6b",
+	0x16E4C: "\U00016e6c",
+	0x16E4D: "\U00016e6d",
+	0x16E4E: "\U00016e6e",
+	0x16E4F: "\U00016e6f",
+	0x16E50: "\U00016e70",
+	0x16E51: "\U00016e71",
+	0x16E52: "\U00016e72",
+	0x16E53: "\U00016e73",
+	0x16E54: "\U00016e74",
+	0x16E55: "\U00016e75",
+	0x16E56: "\U00016e76",
+	0x16E57: "\U00016e77",
+	0x16E58: "\U00016e78",
+	0x16E59: "\U00016e79",
+	0x16E5A: "\U00016e7a",
+	0x16E5B: "\U00016e7b",
+	0x16E5C: "\U00016e7c",
+	0x16E5D: "\U00016e7d",
+	0x16E5E: "\U00016e7e",
+	0x16E5F: "\U00016e7f",
+	0x1E900: "\U0001e922",
+	0x1E901: "\U0001e923",
+	0x1E902: "\U0001e924",
+	0x1E903: "\U0001e925",
+	0x1E904: "\U0001e926",
+	0x1E905: "\U0001e927",
+	0x1E906: "\U0001e928",
+	0x1E907: "\U0001e929",
+	0x1E908: "\U0001e92a",
+	0x1E909: "\U0001e92b",
+	0x1E90A: "\U0001e92c",
+	0x1E90B: "\U0001e92d",
+	0x1E90C: "\U0001e92e",
+	0x1E90D: "\U0001e92f",
+	0x1E90E: "\U0001e930",
+	0x1E90F: "\U0001e931",
+	0x1E910: "\U0001e932",
+	0x1E911: "\U0001e933",
+	0x1E912: "\U0001e934",
+	0x1E913: "\U0001e935",
+	0x1E914: "\U0001e936",
+	0x1E915: "\U0001e937",
+	0x1E916: "\U0001e938",
+	0x1E917: "\U0001e939",
+	0x1E918: "\U0001e93a",
+	0x1E919: "\U0001e93b",
+	0x1E91A: "\U0001e93c",
+	0x1E91B: "\U0001e93d",
+	0x1E91C: "\U0001e93e",
+	0x1E91D: "\U0001e93f",
+	0x1E91E: "\U0001e940",
+	0x1E91F: "\U0001e941",
+	0x1E920: "\U0001e942",
+	0x1E921: "\U0001e943",
+}
@@ -0,0 +1,669 @@
+package interp
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type ValueType string
+
+// enterPath adds the array or object value to path, the containers being
+// walked around it, reporting false when it is on path already: it contains itself
+func enterPath(path []RuntimeValue, value RuntimeValue) ([]RuntimeValue, bool) {
+	if slices.Contains(path, value) {
+		return path, false
+	}
+	return append(path, value), true
+}
+
+// maxStringDepth is how many arrays, objects and maps deep String goes
+const maxStringDepth = 1000
+
+// nestedString is the String of value inside path, the arrays, objects and
+// maps around it: one of them again is <circular>, and past maxStringDepth
+// of them the rest is …
+func nestedString(value RuntimeValue, path []RuntimeValue) string {
+	switch value.(type) {
+	case *ArrayValue, *ObjectValue, *MapValue:
+	default:
+		return value.String()
+	}
+	if len(path) >= maxStringDepth {
+		return "…"
+	}
+	path, entered := enterPath(path, value)
+	if !entered {
+		return "<circular>"
+	}
+
+	switch v := value.(type) {
+	case *ArrayValue:
+		var elements []string
+		for _, elem := range v.Elements {
+			elements = append(elements, nestedString(elem, path))
+		}
+		return "[" + strings.Join(elements, ", ") + "]"
+	case *ObjectValue:
+		var props []string
+		for _, key := range v.Keys() {
+			props = append(props, fmt.Sprintf("%s: %s", key, nestedString(v.Properties[key], path)))
+		}
+		return "{" + strings.Join(props, ", ") + "}"
+	default:
+		m := value.(*MapValue)
+		entries := make([]string, len(m.entries))
+		for i, entry := range m.entries {
+			entries[i] = entry.key.String() + " => " + nestedString(entry.value, path)
+		}
+		return "map {" + strings.Join(entries, ", ") + "}"
+	}
+}
+
+const (
+	NULL_TYPE      ValueType = "null"
+	UNDEF_TYPE     ValueType = "undef"
+	VOID_TYPE      ValueType = "void"
+	NUMBER_TYPE    ValueType = "number"
+	INT_TYPE       ValueType = "int"
+	BIGINT_TYPE    ValueType = "bigint"
+	BOOLEAN_TYPE   ValueType = "boolean"
+	STRING_TYPE    ValueType = "string"
+	FUNCTION_TYPE  ValueType = "function"
+	NATIVE_FN_TYPE ValueType = "native-fn"
+	ARRAY_TYPE     ValueType = "array"
+	OBJECT_TYPE    ValueType = "object"
+	MAP_TYPE       ValueType = "map"
+	SET_TYPE       ValueType = "set"
+	RANGE_TYPE     ValueType = "range"
+	CHANNEL_TYPE   ValueType = "channel"
+	TASK_TYPE      ValueType = "task"
+	SECRET_TYPE    ValueType = "secret"
+	RETURN_TYPE    ValueType = "return"
+)
+
+type RuntimeValue interface {
+	Type() ValueType
+	String() string
+	IsTruthy() bool
+	Prototypes() *[]RuntimeValue
+}
+
+// Null Value
+type NullValue struct{}
+
+func (n *NullValue) Type() ValueType { return NULL_TYPE }
+func (n *NullValue) String() string  { return "null" }
+func (n *NullValue) IsTruthy() bool  { return false }
+func (n *NullValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Undefined Value
+type UndefinedValue struct{}
+
+func (u *UndefinedValue) Type() ValueType { return UNDEF_TYPE }
+func (u *UndefinedValue) String() string  { return "undef" }
+func (u *UndefinedValue) IsTruthy() bool  { return false }
+func (u *UndefinedValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Void Value
+type VoidValue struct{}
+
+func (v *VoidValue) Type() ValueType { return VOID_TYPE }
+func (v *VoidValue) String() string  { return "" }
+func (v *VoidValue) IsTruthy() bool  { return false }
+func (v *VoidValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Number Value
+type NumberValue struct {
+	Value float64
+}
+
+func (n *NumberValue) Type() ValueType { return NUMBER_TYPE }
+func (n *NumberValue) String() string {
+	// Whole floats print like ints while both signs fit an int, so -2**63 does not
+	if n.Value == math.Trunc(n.Value) && math.Abs(n.Value) < 1<<63 {
+		return strconv.FormatInt(int64(n.Value), 10)
+	}
+	return strconv.FormatFloat(n.Value, 'g', -1, 64)
+}
+func (n *NumberValue) IsTruthy() bool { return n.Value != 0 && !math.IsNaN(n.Value) }
+
+// Prototypes returns an empty slice for NumberValue
+func (n *NumberValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+
+	prototypes = append(prototypes, MakeNativeFunction("string", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("string() requires 1 argument")
+		}
+		return MakeString(args[0].String()), nil
+	})) // NaN prototype
+
+	return &prototypes
+}
+
+// Int Value, a whole number kept exact up to 64 bits
+type IntValue struct {
+	Value int64
+}
+
+func (i *IntValue) Type() ValueType { return INT_TYPE }
+func (i *IntValue) String() string  { return strconv.FormatInt(i.Value, 10) }
+func (i *IntValue) IsTruthy() bool  { return i.Value != 0 }
+func (i *IntValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// BigInt Value, a whole number of any size, written 123n. The value is
+// never changed in place, operations make a new one.
+type BigIntValue struct {
+	Value *big.Int
+}
+
+func (b *BigIntValue) Type() ValueType { return BIGINT_TYPE }
+func (b *BigIntValue) String() string  { return b.Value.String() }
+func (b *BigIntValue) IsTruthy() bool  { return b.Value.Sign() != 0 }
+func (b *BigIntValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Boolean Value
+type BooleanValue struct {
+	Value bool
+}
+
+func (b *BooleanValue) Type() ValueType { return BOOLEAN_TYPE }
+func (b *BooleanValue) String() string {
+	if b.Value {
+		return "true"
+	}
+	return "false"
+}
+func (b *BooleanValue) IsTruthy() bool { return b.Value }
+func (b *BooleanValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+
+	prototypes = append(prototypes, MakeNativeFunction("string", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if b.Value {
+			return MakeString("true"), nil
+		}
+		return MakeString("false"), nil
+	})) // Boolean prototype
+
+	return &prototypes
+}
+
+// String Value
+type StringValue struct {
+	Value string
+}
+
+func (s *StringValue) Type() ValueType { return STRING_TYPE }
+func (s *StringValue) String() string  { return fmt.Sprintf("'%s'", s.Value) }
+func (s *StringValue) IsTruthy() bool  { return s.Value != "" }
+func (s *StringValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, f := range StringPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			val, err := f(s, args, env)
+			if err != nil {
+				return nil, err
+			}
+			return val, nil
+		}))
+	}
+
+	return &prototypes
+}
+
+// Array Value
+type ArrayValue struct {
+	Elements []RuntimeValue
+}
+
+func (a *ArrayValue) Type() ValueType { return ARRAY_TYPE }
+func (a *ArrayValue) String() string  { return nestedString(a, nil) }
+func (a *ArrayValue) IsTruthy() bool  { return len(a.Elements) > 0 }
+func (a *ArrayValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+
+	// arrayPrototype contains methods for ArrayValue
+	for name, fn := range ArrayPrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			val, err := fn(a, args, env)
+			if err != nil {
+				return nil, err
+			}
+			return val, nil
+		}))
+	}
+
+	return &prototypes
+}
+
+// Object Value, its properties are listed in the order they were first set.
+// Set and Delete keep that order, a property written to the map directly
+// is listed after the others, in sorted order.
+type ObjectValue struct {
+	Properties map[string]RuntimeValue
+	Frozen     bool     // set by freeze(), properties can no longer be assigned or deleted
+	keys       []string // the keys of Properties in insertion order
+}
+
+// Keys lists the properties in insertion order, the slice must not be changed
+func (o *ObjectValue) Keys() []string {
+	if len(o.keys) != len(o.Properties) {
+		o.syncKeys()
+	}
+	return o.keys
+}
+
+// syncKeys drops deleted keys and appends the ones set around Set
+func (o *ObjectValue) syncKeys() {
+	keys := make([]string, 0, len(o.Properties))
+	listed := make(map[string]bool, len(o.keys))
+	for _, key := range o.keys {
+		if _, exists := o.Properties[key]; exists && !listed[key] {
+			keys = append(keys, key)
+			listed[key] = true
+		}
+	}
+	var added []string
+	for key := range o.Properties {
+		if !listed[key] {
+			added = append(added, key)
+		}
+	}
+	sort.Strings(added)
+	o.keys = append(keys, added...)
+}
+
+// Set assigns a property, a new key goes after the existing ones
+func (o *ObjectValue) Set(key string, value RuntimeValue) {
+	if _, exists := o.Properties[key]; !exists {
+		o.keys = append(o.Keys(), key)
+	}
+	o.Properties[key] = value
+}
+
+// Delete removes a property, reporting whether it existed
+func (o *ObjectValue) Delete(key string) bool {
+	if _, exists := o.Properties[key]; !exists {
+		return false
+	}
+	keys := o.Keys()
+	delete(o.Properties, key)
+	if i := slices.Index(keys, key); i >= 0 {
+		// A new array, slices handed out by Keys stay as they were
+		o.keys = append(keys[:i:i], keys[i+1:]...)
+	}
+	return true
+}
+
+func (o *ObjectValue) Type() ValueType { return OBJECT_TYPE }
+func (o *ObjectValue) String() string  { return nestedString(o, nil) }
+func (o *ObjectValue) IsTruthy() bool  { return len(o.Properties) > 0 }
+func (o *ObjectValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+
+	prototypes = append(prototypes, MakeNativeFunction("keys", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		keys := make([]RuntimeValue, 0, len(o.Properties))
+		for _, key := range o.Keys() {
+			keys = append(keys, MakeString(key))
+		}
+		return MakeArray(keys), nil
+	}))
+
+	prototypes = append(prototypes, MakeNativeFunction("values", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		values := make([]RuntimeValue, 0, len(o.Properties))
+		for _, key := range o.Keys() {
+			values = append(values, o.Properties[key])
+		}
+		return MakeArray(values), nil
+	}))
+
+	prototypes = append(prototypes, MakeNativeFunction("entries", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		entries := make([]RuntimeValue, 0, len(o.Properties))
+		for _, key := range o.Keys() {
+			entries = append(entries, MakeArray([]RuntimeValue{MakeString(key), o.Properties[key]}))
+		}
+		return MakeArray(entries), nil
+	}))
+
+	prototypes = append(prototypes, MakeNativeFunction("has", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("object.has requires a key string")
+		}
+		_, exists := o.Properties[args[0].(*StringValue).Value]
+		return MakeBool(exists), nil
+	}))
+
+	prototypes = append(prototypes, MakeNativeFunction("delete", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("object.delete requires a key string")
+		}
+		if o.Frozen {
+			return nil, fmt.Errorf("cannot delete from a frozen object")
+		}
+		return MakeBool(o.Delete(args[0].(*StringValue).Value)), nil
+	}))
+
+	// get(key, default) reads a property, default (or undef) when it is missing
+	prototypes = append(prototypes, MakeNativeFunction("get", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < 1 || len(args) > 2 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("object.get requires a key string and an optional default")
+		}
+		if value, exists := o.Properties[args[0].(*StringValue).Value]; exists {
+			return value, nil
+		}
+		if len(args) == 2 {
+			return args[1], nil
+		}
+		return MakeUndefined(), nil
+	}))
+
+	// setdefault(key, default) sets a missing property to default, and returns the property
+	prototypes = append(prototypes, MakeNativeFunction("setdefault", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 2 || args[0].Type() != STRING_TYPE {
+			return nil, fmt.Errorf("object.setdefault requires a key string and a default")
+		}
+		key := args[0].(*StringValue).Value
+		if value, exists := o.Properties[key]; exists {
+			return value, nil
+		}
+		if o.Frozen {
+			return nil, fmt.Errorf("cannot assign to property '%s' of a frozen object", key)
+		}
+		o.Set(key, args[1])
+		return args[1], nil
+	}))
+
+	// merge returns a new object, properties of the argument win
+	prototypes = append(prototypes, MakeNativeFunction("merge", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) != 1 || args[0].Type() != OBJECT_TYPE {
+			return nil, fmt.Errorf("object.merge requires an object")
+		}
+		merged := newObject()
+		for _, key := range o.Keys() {
+			merged.Set(key, o.Properties[key])
+		}
+		other := args[0].(*ObjectValue)
+		for _, key := range other.Keys() {
+			merged.Set(key, other.Properties[key])
+		}
+		return merged, nil
+	}))
+
+	prototypes = append(prototypes, MakeNativeFunction("freeze", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		o.Frozen = true
+		return o, nil
+	}))
+
+	return &prototypes
+}
+
+// Range Value, a lazy sequence of numbers from Start towards End by Step
+type RangeValue struct {
+	Start     float64
+	End       float64
+	Step      float64
+	Inclusive bool // End itself is part of the range
+}
+
+// Len returns how many numbers the range yields
+func (r *RangeValue) Len() int {
+	steps := (r.End - r.Start) / r.Step
+	if steps < 0 || math.IsNaN(steps) {
+		return 0
+	}
+	if r.Inclusive {
+		// Tolerate rounding, 0..=0.3 by 0.1 includes 0.3
+		return int(math.Floor(steps+1e-9)) + 1
+	}
+	return int(math.Ceil(steps - 1e-9))
+}
+
+// At returns the i-th number of the range
+func (r *RangeValue) At(i int) float64 {
+	return r.Start + float64(i)*r.Step
+}
+
+// Item returns the i-th number of the range as a value, an int when the range counts in whole numbers
+func (r *RangeValue) Item(i int) RuntimeValue {
+	if isInteger(r.Start) && isInteger(r.Step) {
+		return wholeNumber(r.At(i))
+	}
+	return MakeNumber(r.At(i))
+}
+
+func (r *RangeValue) Type() ValueType { return RANGE_TYPE }
+func (r *RangeValue) String() string {
+	start, end := MakeNumber(r.Start).String(), MakeNumber(r.End).String()
+	if r.Step != 1 && r.Step != -1 {
+		return fmt.Sprintf("range(%s, %s, %s)", start, end, MakeNumber(r.Step).String())
+	}
+	if r.Inclusive {
+		return start + "..=" + end
+	}
+	return start + ".." + end
+}
+func (r *RangeValue) IsTruthy() bool { return r.Len() > 0 }
+func (r *RangeValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	for name, fn := range RangePrototype {
+		prototypes = append(prototypes, MakeNativeFunction(name, func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+			return fn(r, args, env)
+		}))
+	}
+
+	return &prototypes
+}
+
+// Function Value
+type FunctionValue struct {
+	Name           string
+	Parameters     []Parameter
+	Body           []Statement
+	DeclarationEnv *Environment
+	Export         bool
+	Anonymous      bool
+	Async          bool   // calls return a task running the body
+	Doc            string // the ## comment of the declaration, fn.doc
+}
+
+func (f *FunctionValue) String() string {
+	var paramStrs []string
+	for _, param := range f.Parameters {
+		if param.Rest {
+			paramStrs = append(paramStrs, "..."+param.Name)
+		} else if param.DefaultValue != nil {
+			paramStrs = append(paramStrs, fmt.Sprintf("%s=(...)", param.Label()))
+		} else {
+			paramStrs = append(paramStrs, param.Label())
+		}
+	}
+
+	prefix := ""
+	if f.Async {
+		prefix = "async "
+	}
+	if f.IsAnonymous() {
+		return fmt.Sprintf("%slambda %s { ... }", prefix, strings.Join(paramStrs, " "))
+	}
+	return fmt.Sprintf("%sfn %s %s { ... }", prefix, f.Name, strings.Join(paramStrs, " "))
+}
+func (f *FunctionValue) IsTruthy() bool    { return true }
+func (f *FunctionValue) IsAnonymous() bool { return f.Anonymous }
+func (f *FunctionValue) Type() ValueType   { return FUNCTION_TYPE }
+func (f *FunctionValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+
+	// Add a prototype for calling the function
+	prototypes = append(prototypes, MakeNativeFunction("call", func(args []RuntimeValue, env *Environment) (RuntimeValue, error) {
+		if len(args) < len(f.Parameters) {
+			return nil, fmt.Errorf("not enough arguments to call function %s", f.Name)
+		}
+		if len(args) > len(f.Parameters) {
+			return nil, fmt.Errorf("too many arguments to call function %s", f.Name)
+		}
+
+		// Create a new environment for the function call
+		callEnv := NewEnvironment(f.DeclarationEnv)
+
+		for i, param := range f.Parameters {
+			if param.Pattern != nil {
+				err := bindPattern(param.Pattern, args[i], callEnv, func(name string, value RuntimeValue) {
+					callEnv.DeclareVar(name, value, false)
+				})
+				if err != nil {
+					return nil, err
+				}
+				continue
+			}
+			callEnv.DeclareVar(param.Name, args[i], false)
+		}
+
+		// Execute the function body
+		var returnValue RuntimeValue
+		for _, stmt := range f.Body {
+			result, err := evaluateStatement(stmt, callEnv)
+			if err != nil {
+				return nil, err
+			}
+			if result != nil && result.Type() == RETURN_TYPE {
+				returnValue = result.(*ReturnValue).Value
+				break
+			}
+		}
+
+		if returnValue == nil {
+			return MakeVoid(), nil
+		}
+		return returnValue, nil
+	}))
+
+	return &prototypes
+}
+
+// Native Function Value
+type NativeFunctionCall func(args []RuntimeValue, env *Environment) (RuntimeValue, error)
+
+type NativeFunctionValue struct {
+	Name string
+	Call NativeFunctionCall
+	Doc  string // description shown by help()
+}
+
+func (n *NativeFunctionValue) Type() ValueType { return NATIVE_FN_TYPE }
+func (n *NativeFunctionValue) String() string {
+	return fmt.Sprintf("fn %s", n.Name)
+}
+func (n *NativeFunctionValue) IsTruthy() bool { return true }
+func (n *NativeFunctionValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Return Value (for control flow)
+type ReturnValue struct {
+	Value RuntimeValue
+}
+
+func (r *ReturnValue) Type() ValueType { return RETURN_TYPE }
+func (r *ReturnValue) String() string  { return r.Value.String() }
+func (r *ReturnValue) IsTruthy() bool  { return r.Value.IsTruthy() }
+func (r *ReturnValue) Prototypes() *[]RuntimeValue {
+	var prototypes []RuntimeValue
+	return &prototypes
+}
+
+// Helper functions to create values
+func MakeNull() RuntimeValue {
+	return &NullValue{}
+}
+
+func MakeUndefined() RuntimeValue {
+	return &UndefinedValue{}
+}
+
+func MakeVoid() RuntimeValue {
+	return &VoidValue{}
+}
+
+func MakeNumber(value float64) RuntimeValue {
+	return &NumberValue{Value: value}
+}
+
+func MakeInt(value int64) RuntimeValue {
+	return &IntValue{Value: value}
+}
+
+func MakeBigInt(value *big.Int) RuntimeValue {
+	return &BigIntValue{Value: value}
+}
+
+func MakeBool(value bool) RuntimeValue {
+	return &BooleanValue{Value: value}
+}
+
+func MakeString(value string) RuntimeValue {
+	return &StringValue{Value: value}
+}
+
+func MakeArray(elements []RuntimeValue) RuntimeValue {
+	return &ArrayValue{Elements: elements}
+}
+
+// MakeObject wraps a map, its keys are listed in sorted order
+func MakeObject(properties map[string]RuntimeValue) RuntimeValue {
+	return &ObjectValue{Properties: properties, keys: sortedKeys(properties)}
+}
+
+// newObject is an empty object to fill with Set, keeping the order of the calls
+func newObject() *ObjectValue {
+	return &ObjectValue{Properties: make(map[string]RuntimeValue)}
+}
+
+// MakeRange creates the range start..end, counting down when end is below start
+func MakeRange(start, end float64, inclusive bool) RuntimeValue {
+	step := 1.0
+	if end < start {
+		step = -1
+	}
+	return &RangeValue{Start: start, End: end, Step: step, Inclusive: inclusive}
+}
+
+// Update MakeFunction to use Parameter struct
+func MakeFunction(name string, parameters []Parameter, body []Statement, env *Environment, export bool, anonymous bool) RuntimeValue {
+	return &FunctionValue{
+		Name:           name,
+		Parameters:     parameters,
+		Body:           body,
+		DeclarationEnv: env,
+		Export:         export,
+		Anonymous:      anonymous,
+	}
+}
+
+func MakeNativeFunction(name string, call NativeFunctionCall) RuntimeValue {
+	return &NativeFunctionValue{Name: name, Call: call}
+}
+
+func MakeReturn(value RuntimeValue) RuntimeValue {
+	return &ReturnValue{Value: value}
+}
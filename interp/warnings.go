@@ -0,0 +1,93 @@
+package interp
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Warnings point at code that runs but is probably not what was meant. They
+// never stop a program: they are collected while it runs and printed once it
+// is done, or as JSON diagnostics with --json-errors. --no-warn turns them off.
+//
+//	fn add x y { x }      W105 y is never used
+//	for count in list { } W104 the loop variable hides the global count
+//	fn reset { cout = 0 } W103 the assignment makes a name nobody declared
+//
+// Assigning a new name at the top level is how scripts make their globals
+// and is not warned about.
+//
+// A name starting with _ is never warned about. Go code reaches the channel
+// through env.Warn, which keeps each warning once.
+
+// warningList is shared by every scope of an environment, spawned tasks included
+type warningList struct {
+	mu       sync.Mutex
+	warnings []Diagnostic
+	seen     map[string]bool
+}
+
+// Warn records a warning to print after the program, once however often it is given
+func (env *Environment) Warn(warning Diagnostic) {
+	list := env.warnings
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	key := warning.File + ":" + warning.String()
+	if list.seen[key] {
+		return
+	}
+	list.seen[key] = true
+	list.warnings = append(list.warnings, warning)
+}
+
+// Warnings takes the warnings recorded so far, leaving none behind
+func (env *Environment) Warnings() []Diagnostic {
+	list := env.warnings
+	list.mu.Lock()
+	defer list.mu.Unlock()
+	warnings := list.warnings
+	list.warnings = nil
+	return warnings
+}
+
+// warningsEnabled reports whether warnings are shown, --no-warn hides them
+func warningsEnabled() bool {
+	return !hasFlag("--no-warn")
+}
+
+// collectWarnings looks over the code of file for warnings before it runs.
+// Code that does not parse has none, running it reports the syntax error.
+func collectWarnings(env *Environment, code string, file string) {
+	if !warningsEnabled() {
+		return
+	}
+	tokens, err := NewTokenizer(code).Tokenize()
+	if err != nil {
+		return
+	}
+	ast, err := NewParser(tokens, code).ProduceAST()
+	if err != nil {
+		return
+	}
+	for _, warning := range NewChecker().Warnings(ast.(*Program)) {
+		warning.File = file
+		env.Warn(warning)
+	}
+}
+
+// printWarnings prints the warnings recorded in env, in yellow or as JSON
+func printWarnings(env *Environment) {
+	if !warningsEnabled() {
+		return
+	}
+	for _, warning := range env.Warnings() {
+		if jsonErrors() {
+			printDiagnostic(warning)
+			continue
+		}
+		message := fmt.Sprintf("%s: %s:%s", yellow(bold("Warning")), warning.File, yellow(warning.String()))
+		if env.Pragmas().NoColor {
+			message = stripColor(message)
+		}
+		fmt.Println(message)
+	}
+}
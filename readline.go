@@ -1,53 +0,0 @@
-package main
-
-import (
-	"bufio"
-	"fmt"
-	"os"
-)
-
-// Simple readline implementation with cursor movement
-type Readline struct {
-	prompt  string
-	line    []rune
-	cursor  int
-	history []string
-	histPos int
-}
-
-func NewReadline(prompt string) *Readline {
-	return &Readline{
-		prompt:  prompt,
-		line:    make([]rune, 0),
-		cursor:  0,
-		history: make([]string, 0),
-		histPos: -1,
-	}
-}
-
-func (r *Readline) ReadLine(printPrompt ...any) (string, error) {
-	if len(printPrompt) == 0 {
-		fmt.Print(r.prompt)
-	}
-
-	// For now, use simple input until we implement full terminal control
-	scanner := bufio.NewScanner(os.Stdin)
-	if scanner.Scan() {
-		input := scanner.Text()
-		if input != "" {
-			r.history = append(r.history, input)
-		}
-		return input, nil
-	}
-
-	return "", scanner.Err()
-}
-
-// TODO: Implement proper terminal control for cursor movement
-// This would require platform-specific terminal handling
-func (r *Readline) MoveCursorLeft() {
-	if r.cursor > 0 {
-		r.cursor--
-		fmt.Print("\033[1D") // Move cursor left
-	}
-}
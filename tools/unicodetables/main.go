@@ -0,0 +1,250 @@
+// Unicodetables generates interp/unicode_tables.go, the normalization and
+// case folding data of interp/unicode.go, from the Unicode Character
+// Database. Go's unicode package has neither decompositions nor full case
+// folding, so they are read from UnicodeData.txt, CompositionExclusions.txt
+// and CaseFolding.txt:
+//
+//	go generate ./interp
+//
+// runs it. -ucd reads the files from another URL or from a directory.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const unicodeVersion = "14.0.0"
+
+var (
+	ucd    = flag.String("ucd", "https://www.unicode.org/Public/"+unicodeVersion+"/ucd", "URL or directory of the Unicode Character Database")
+	output = flag.String("o", "unicode_tables.go", "file to write")
+)
+
+// combiningRange is a run of characters with the same combining class
+type combiningRange struct {
+	lo, hi rune
+	class  int
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("unicodetables: ")
+	flag.Parse()
+
+	canonical := map[rune][]rune{}
+	compatibility := map[rune][]rune{}
+	classes := map[rune]int{}
+	var ranges []combiningRange
+
+	err := readUCD("UnicodeData.txt", func(fields []string) error {
+		code, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		class, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("combining class of %04X: %v", code, err)
+		}
+		if class != 0 {
+			classes[code] = class
+			if last := len(ranges) - 1; last >= 0 && ranges[last].hi == code-1 && ranges[last].class == class {
+				ranges[last].hi = code
+			} else {
+				ranges = append(ranges, combiningRange{code, code, class})
+			}
+		}
+
+		decomposition := strings.Fields(fields[5])
+		if len(decomposition) == 0 {
+			return nil
+		}
+		table := canonical
+		if strings.HasPrefix(decomposition[0], "<") {
+			table, decomposition = compatibility, decomposition[1:]
+		}
+		runes, err := parseRunes(decomposition)
+		if err != nil {
+			return err
+		}
+		table[code] = runes
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	excluded := map[rune]bool{}
+	err = readUCD("CompositionExclusions.txt", func(fields []string) error {
+		code, err := parseRune(fields[0])
+		excluded[code] = true
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Full case folding is the common and the full mappings, C and F
+	folds := map[rune][]rune{}
+	err = readUCD("CaseFolding.txt", func(fields []string) error {
+		if status := fields[1]; status != "C" && status != "F" {
+			return nil
+		}
+		code, err := parseRune(fields[0])
+		if err != nil {
+			return err
+		}
+		folds[code], err = parseRunes(strings.Fields(fields[2]))
+		return err
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// NFC puts back together the pairs of the canonical decompositions, but
+	// for the excluded characters and those starting with or being a mark
+	compositions := map[[2]rune]rune{}
+	for code, runes := range canonical {
+		if len(runes) == 2 && !excluded[code] && classes[code] == 0 && classes[runes[0]] == 0 {
+			compositions[[2]rune{runes[0], runes[1]}] = code
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by tools/unicodetables from Unicode %s. DO NOT EDIT.\n\n", unicodeVersion)
+	fmt.Fprintf(&out, "package interp\n\n")
+
+	writeMap(&out, "canonicalDecompositions", "maps a character to its canonical decomposition, one level deep", canonical)
+	writeMap(&out, "compatibilityDecompositions", "maps a character to its compatibility decomposition, one level deep", compatibility)
+
+	pairs := make([][2]rune, 0, len(compositions))
+	for pair := range compositions {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	fmt.Fprintf(&out, "// compositions maps the pairs composed by NFC to their composite\n")
+	fmt.Fprintf(&out, "var compositions = map[[2]rune]rune{\n")
+	for _, pair := range pairs {
+		fmt.Fprintf(&out, "\t{0x%04X, 0x%04X}: 0x%04X,\n", pair[0], pair[1], compositions[pair])
+	}
+	fmt.Fprintf(&out, "}\n\n")
+
+	fmt.Fprintf(&out, "// combiningClasses are the ranges of characters with a non-zero canonical combining class\n")
+	fmt.Fprintf(&out, "var combiningClasses = []struct {\n\tlo, hi rune\n\tclass  uint8\n}{\n")
+	for _, r := range ranges {
+		fmt.Fprintf(&out, "\t{0x%04X, 0x%04X, %d},\n", r.lo, r.hi, r.class)
+	}
+	fmt.Fprintf(&out, "}\n\n")
+
+	writeMap(&out, "caseFolds", "maps the characters that full case folding changes", folds)
+
+	source, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// readUCD calls line with the fields of each data line of a UCD file
+func readUCD(name string, line func(fields []string) error) error {
+	var reader io.Reader
+	if strings.HasPrefix(*ucd, "http://") || strings.HasPrefix(*ucd, "https://") {
+		response, err := http.Get(*ucd + "/" + name)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s: %s", name, response.Status)
+		}
+		reader = response.Body
+	} else {
+		file, err := os.Open(path.Join(*ucd, name))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		text, _, _ := strings.Cut(scanner.Text(), "#")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		fields := strings.Split(text, ";")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if err := line(fields); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return scanner.Err()
+}
+
+func parseRune(field string) (rune, error) {
+	code, err := strconv.ParseUint(field, 16, 32)
+	return rune(code), err
+}
+
+func parseRunes(fields []string) ([]rune, error) {
+	runes := make([]rune, len(fields))
+	for i, field := range fields {
+		var err error
+		if runes[i], err = parseRune(field); err != nil {
+			return nil, err
+		}
+	}
+	return runes, nil
+}
+
+// writeMap writes a table from characters to strings, sorted by character
+func writeMap(out *bytes.Buffer, name, doc string, table map[rune][]rune) {
+	codes := make([]rune, 0, len(table))
+	for code := range table {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	fmt.Fprintf(out, "// %s %s\n", name, doc)
+	fmt.Fprintf(out, "var %s = map[rune]string{\n", name)
+	for _, code := range codes {
+		fmt.Fprintf(out, "\t0x%04X: %s,\n", code, literal(table[code]))
+	}
+	fmt.Fprintf(out, "}\n\n")
+}
+
+// literal is a Go string of runes, each written as an escape
+func literal(runes []rune) string {
+	var text strings.Builder
+	text.WriteByte('"')
+	for _, r := range runes {
+		if r > 0xFFFF {
+			fmt.Fprintf(&text, "\\U%08x", r)
+		} else {
+			fmt.Fprintf(&text, "\\u%04x", r)
+		}
+	}
+	text.WriteByte('"')
+	return text.String()
+}